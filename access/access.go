@@ -15,6 +15,17 @@
 //	Write: user@domain.com, joe@domain.com
 //	Delete: user@domain.com # This is a comment.
 //
+// The user name "self" stands for the owner of the path being checked.
+// A Group file listing it can be shared by many users' Access files,
+// granting each of them rights in their own tree:
+//	Write,Create,Delete: ann@domain.com/Group/self-and-joe
+//
+// A line beginning with '!' denies the listed users and groups the rights,
+// overriding any grant of the same rights elsewhere in the file:
+//	Read: *@domain.com
+//	!Read: badguy@domain.com
+// Denials do not apply to the rights the owner always holds.
+//
 // Each line of a Group file specifies a user or group
 // to be included in the group:
 // 	<user/group>
@@ -66,12 +77,31 @@ const (
 	// details. Its appearance in a user list grants access to everyone who
 	// can authenticate to the Upspin system.
 	AllUsers upspin.UserName = "all@upspin.io"
+
+	// Self is a shorthand for SelfUser. Its appearance in a user list
+	// grants access to the owner of the path being checked, that is, the
+	// user whose tree holds it. Since it is resolved for each path, not
+	// for each Access or Group file, a Group file listing Self can be
+	// shared by many users, each of whom it grants rights in their own
+	// tree. It is expanded to the full name ("self@upspin.io") when
+	// returned from Access.List and such.
+	Self = "self" // Case is ignored, as for All.
+
+	// SelfUser is a reserved Upspin name and is not valid in the text of
+	// an Access or Group file. It is the user name that is substituted
+	// for the shorthand "self" in a user list. See the comment about Self
+	// for more details.
+	SelfUser upspin.UserName = "self@upspin.io"
 )
 
 var (
 	allBytes       = []byte(All)
 	allUsersBytes  = []byte(AllUsers)
 	allUsersParsed path.Parsed
+
+	selfBytes      = []byte(Self)
+	selfUserBytes  = []byte(SelfUser)
+	selfUserParsed path.Parsed
 )
 
 func init() {
@@ -80,6 +110,10 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	selfUserParsed, err = path.Parse(upspin.PathName(SelfUser))
+	if err != nil {
+		panic(err)
+	}
 }
 
 // ErrPermissionDenied is a predeclared error reporting that a permission check has failed.
@@ -156,6 +190,17 @@ type Access struct {
 	// "Any" right. That is, the lists above are all subslices of this list.
 	// Note that this list will be neither sorted nor deduplicated.
 	allUsers []path.Parsed
+
+	// deny holds the lists of parsed user and group names denied
+	// a right. It is indexed by a right. Each list is stored in
+	// sorted order.
+	deny [numRights][]path.Parsed
+
+	// The indexes of the lists above, or nil for lists too short to
+	// be worth indexing.
+	listIndex     [numRights]*memberIndex
+	allUsersIndex *memberIndex
+	denyIndex     [numRights]*memberIndex
 }
 
 // Path returns the full path name of the file that was parsed.
@@ -182,6 +227,12 @@ func Parse(pathName upspin.PathName, data []byte) (*Access, error) {
 			continue
 		}
 
+		// A leading '!' marks a line of denials.
+		deny := line[0] == '!'
+		if deny {
+			line = bytes.TrimSpace(line[1:])
+		}
+
 		// A line is two non-empty comma-separated lists, separated by a colon.
 		colon := bytes.IndexByte(line, ':')
 		if colon < 0 {
@@ -202,6 +253,27 @@ func Parse(pathName upspin.PathName, data []byte) (*Access, error) {
 
 		var err error
 		var all []byte
+		if deny {
+			for _, right := range rights {
+				switch r := which(right); r {
+				case AllRights:
+					for r := Right(0); r < numRights && err == nil; r++ {
+						all, err = a.addDeny(r, parsed.User(), users)
+					}
+				case Read, Write, List, Create, Delete:
+					all, err = a.addDeny(r, parsed.User(), users)
+				case Invalid:
+					err = errors.Errorf("invalid access rights on line %d: %q", lineNum, right)
+				}
+				if err == nil && all != nil {
+					err = errors.Errorf("%q cannot be denied on line %d", all, lineNum)
+				}
+				if err != nil {
+					return nil, errors.E(op, pathName, errors.Invalid, err)
+				}
+			}
+			continue
+		}
 		for _, right := range rights {
 			switch r := which(right); r {
 			case AllRights:
@@ -246,6 +318,14 @@ func Parse(pathName upspin.PathName, data []byte) (*Access, error) {
 		a.list[i] = a.allUsers[len(a.allUsers) : len(a.allUsers)+len(r)]
 		a.allUsers = append(a.allUsers, r...)
 	}
+	for _, r := range a.deny {
+		sort.Sort(sliceOfParsed(r))
+	}
+	for i := range a.list {
+		a.listIndex[i] = newMemberIndex(a.list[i])
+		a.denyIndex[i] = newMemberIndex(a.deny[i])
+	}
+	a.allUsersIndex = newMemberIndex(a.allUsers)
 	if numReaders > 1 && a.worldReadable {
 		return nil, errors.E(op, pathName, errors.Invalid, errors.Errorf("%q cannot appear with other users", userAll))
 	}
@@ -263,6 +343,23 @@ func (a *Access) addRight(r Right, owner upspin.UserName, users [][]byte) ([]byt
 	return all, err
 }
 
+func (a *Access) addDeny(r Right, owner upspin.UserName, users [][]byte) ([]byte, error) {
+	var err error
+	var all []byte
+	a.deny[r], all, err = parsedAppend(a.deny[r], owner, users...)
+	return all, err
+}
+
+// hasDenials reports whether the Access file denies any rights.
+func (a *Access) hasDenials() bool {
+	for _, r := range a.deny {
+		if len(r) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // New returns a new Access granting the owner of pathName all rights.
 // It represents rights equivalent to the those granted to the owner if no Access
 // files are present in the owner's tree.
@@ -344,6 +441,16 @@ func isAllUsers(user []byte) bool {
 	return len(user) == len(allUsersBytes) && bytes.EqualFold(user, allUsersBytes)
 }
 
+// isSelf is a case-insensitive check for "self".
+func isSelf(user []byte) bool {
+	return len(user) == len(selfBytes) && bytes.EqualFold(user, selfBytes)
+}
+
+// isSelfUser is a case-insensitive check for "self@upspin.io".
+func isSelfUser(user []byte) bool {
+	return len(user) == len(selfUserBytes) && bytes.EqualFold(user, selfUserBytes)
+}
+
 // parsedAppend parses the users (as path.Parse values) and appends them to the list.
 // The returned byte slice is empty unless "all" is present, in which case the text of
 // the provided user name is returned, for use in error messages.
@@ -351,10 +458,14 @@ func isAllUsers(user []byte) bool {
 func parsedAppend(list []path.Parsed, owner upspin.UserName, users ...[]byte) ([]path.Parsed, []byte, error) {
 	var all []byte
 	for _, user := range users {
-		// Reject "all@upspin.io" as user input.
-		if isAllUsers(user) {
+		// Reject "all@upspin.io" and "self@upspin.io" as user input.
+		if isAllUsers(user) || isSelfUser(user) {
 			return nil, nil, errors.Errorf("reserved user name %q", user)
 		}
+		// Case-insensitive check for "self" which we canonicalize to "self@upspin.io".
+		if isSelf(user) {
+			user = selfUserBytes
+		}
 		// Case-insensitive check for "all" which we canonicalize to "all@upspin.io".
 		// We require it to be the only item on the line.
 		if isAll(user) {
@@ -572,6 +683,58 @@ func RemoveGroup(pathName upspin.PathName) error {
 	return nil
 }
 
+// UpdateGroup installs a new definition of the group with the specified name
+// and textual contents, as AddGroup does, and returns the names of the users
+// whose membership of the group changed as a result. It allows the caller to
+// discard only those cached permission decisions that the change affects.
+//
+// Membership is computed as in Users: nested groups are expanded, loading
+// them as needed by calling the provided function, and wildcards such as
+// *@domain.com are reported as is, as is SelfUser. If the group was not previously installed,
+// its old membership is unknown and all the members of the new group are
+// returned.
+func UpdateGroup(pathName upspin.PathName, contents []byte, load func(upspin.PathName) ([]byte, error)) ([]upspin.UserName, error) {
+	const op errors.Op = "access.UpdateGroup"
+	parsed, err := path.Parse(pathName)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	group, err := ParseGroup(parsed, contents)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	old, found := groups[parsed.Path()]
+	groups[parsed.Path()] = group
+	mu.Unlock()
+
+	before := make(map[upspin.UserName]struct{})
+	if found {
+		before, err = expandUsers(old, "", load)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	after, err := expandUsers(group, "", load)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	var changed []upspin.UserName
+	for u := range before {
+		if _, ok := after[u]; !ok {
+			changed = append(changed, u)
+		}
+	}
+	for u := range after {
+		if _, ok := before[u]; !ok {
+			changed = append(changed, u)
+		}
+	}
+	sort.Sort(sliceOfUserName(changed))
+	return changed, nil
+}
+
 // ParseGroup parses a group file but does not call AddGroup to install it.
 func ParseGroup(parsed path.Parsed, contents []byte) (group []path.Parsed, err error) {
 	const op errors.Op = "access.ParseGroup"
@@ -625,14 +788,14 @@ func preallocSize(n int) int {
 // rightGranted returns whether the requester is granted the
 // right for the path given the rules of the Access file, and if the answer
 // isn't immediately known, the access list to traverse.
-func (a *Access) rightGranted(requester upspin.UserName, right Right, pathName upspin.PathName) (bool, []path.Parsed, error) {
+func (a *Access) rightGranted(requester upspin.UserName, right Right, pathName upspin.PathName) (bool, []path.Parsed, *memberIndex, error) {
 	isOwner := requester == a.owner
 	// If user is the owner and the request is for read, list, or any access, access is granted.
 	if isOwner {
 		switch right {
 		case Read, List, AnyRight:
 			// Owner can always read or list anything in the owner's tree.
-			return true, nil, nil
+			return true, nil, nil, nil
 		}
 	}
 	// If the file is an Access or Group file, the owner has full rights always; no one else
@@ -640,11 +803,11 @@ func (a *Access) rightGranted(requester upspin.UserName, right Right, pathName u
 	if IsAccessControlFile(pathName) {
 		switch right {
 		case Write, Create, Delete:
-			return isOwner, nil, nil
+			return isOwner, nil, nil, nil
 		}
 	}
-	group, err := a.getListFor(right)
-	return false, group, err
+	group, index, err := a.getListFor(right)
+	return false, group, index, err
 }
 
 // Can reports whether the requesting user can access the file
@@ -669,10 +832,45 @@ func (a *Access) rightGranted(requester upspin.UserName, right Right, pathName u
 // reported only if the requester does not match any names that
 // can be found in the Access file or other Group files.
 func (a *Access) Can(requester upspin.UserName, right Right, pathName upspin.PathName, load func(upspin.PathName) ([]byte, error)) (bool, error) {
+	_, ok, err := a.whyCan(requester, right, pathName, load)
+	return ok, err
+}
+
+// Grant describes how a right was granted by an Access file.
+type Grant struct {
+	// Owner reports that the right was granted implicitly because
+	// the requester owns the tree holding the Access file.
+	// If Owner is set, the other fields are empty.
+	Owner bool
+
+	// Match is the item that matched the requester. If it is a root, it is
+	// a user name, a wildcard such as *@example.com, or one of the special
+	// users "all" and "self". Otherwise it is a group that the requester owns.
+	Match path.Parsed
+
+	// Group is the name of the Group file whose list held Match.
+	// If Match appeared directly in the Access file, Group is empty.
+	Group upspin.PathName
+}
+
+// WhyCan is like Can but, if the right is granted, it reports how. It
+// returns nil if the right is not granted. It is intended for diagnosing
+// permission problems.
+func (a *Access) WhyCan(requester upspin.UserName, right Right, pathName upspin.PathName, load func(upspin.PathName) ([]byte, error)) (*Grant, error) {
+	grant, ok, err := a.whyCan(requester, right, pathName, load)
+	if !ok {
+		return nil, err
+	}
+	return &grant, err
+}
+
+// whyCan implements WhyCan, reporting with ok whether the right is
+// granted. Returning the Grant by value saves Can an allocation.
+func (a *Access) whyCan(requester upspin.UserName, right Right, pathName upspin.PathName, load func(upspin.PathName) ([]byte, error)) (grant Grant, ok bool, err error) {
 
 	parsedRequester, err := path.Parse(upspin.PathName(requester + "/"))
 	if err != nil {
-		return false, err
+		return Grant{}, false, err
 	}
 
 	requesterUserName := parsedRequester.User()
@@ -680,29 +878,88 @@ func (a *Access) Can(requester upspin.UserName, right Right, pathName upspin.Pat
 	_, _, domain, err := user.Parse(requesterUserName)
 	// We don't expect an error since it's been parsed, but check anyway.
 	if err != nil {
-		return false, err
+		return Grant{}, false, err
 	}
 
-	granted, group, err := a.rightGranted(requester, right, pathName)
-	if granted || err != nil {
-		return granted, err
+	granted, group, index, err := a.rightGranted(requester, right, pathName)
+	if err != nil {
+		return Grant{}, false, err
+	}
+	if granted {
+		return Grant{Owner: true}, true, nil
 	}
 
+	// The owner of the path, which is matched by "self".
+	parsedPath, err := path.Parse(pathName)
+	if err != nil {
+		return Grant{}, false, err
+	}
+	self := parsedPath.User()
+
+	if a.hasDenials() {
+		if right == AnyRight {
+			// Denials apply per right, so the requester holds
+			// some right only if one of them survives its denials.
+			var firstErr error
+			for r := Right(0); r < numRights; r++ {
+				grant, ok, err := a.whyCan(requester, r, pathName, load)
+				if ok {
+					return grant, true, nil
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			return Grant{}, false, firstErr
+		}
+		// Denials are checked first and override any grant.
+		// A Group file in the deny list that cannot be loaded
+		// might have named the requester, so fail closed.
+		var loadErr error
+		denyLoad := func(name upspin.PathName) ([]byte, error) {
+			data, err := load(name)
+			if err != nil && loadErr == nil {
+				loadErr = err
+			}
+			return data, err
+		}
+		_, denied, err := findInGroups(requesterUserName, domain, self, a.deny[right], a.denyIndex[right], denyLoad)
+		if err == nil {
+			err = loadErr
+		}
+		if denied || err != nil {
+			return Grant{}, false, err
+		}
+	}
+
+	return findInGroups(requesterUserName, domain, self, group, index, load)
+}
+
+// findInGroups reports whether the requester is in the list of users and
+// groups, loading nested groups as needed, and if so returns a Grant
+// describing the match. Self is the owner of the path being checked.
+// The index, if not nil, is that of the list.
+//
+// If a Group file cannot be loaded or parsed that failure is
+// reported only if the requester does not match any names that
+// can be found in the list or other Group files.
+func findInGroups(requesterUserName upspin.UserName, domain string, self upspin.UserName, group []path.Parsed, index *memberIndex, load func(upspin.PathName) ([]byte, error)) (Grant, bool, error) {
 	// The groups graph is traversed depth-first, always preferring to check
 	// loaded groups first.
 
 	var groupsToCheck iter
 	var missing []path.Parsed
 	var groupErr error
+	var groupName upspin.PathName // Name of the group being searched; empty for the Access file.
 
 	for len(group) > 0 {
 		// The loop searches lists to find whether the requester is represented
 		// in the group graph.
 
-		granted = inGroup(requesterUserName, domain, group, &groupsToCheck)
-		if granted {
-			return true, nil
+		if match, ok := inGroup(requesterUserName, domain, self, group, index, &groupsToCheck); ok {
+			return Grant{Match: match, Group: groupName}, true, nil
 		}
+		index = nil // Group files are not indexed.
 
 		// Until a non-empty group is found, iterate through groupsToCheck,
 		// checking groups already loaded and deferring the rest.
@@ -720,6 +977,7 @@ func (a *Access) Can(requester upspin.UserName, right Right, pathName upspin.Pat
 				// Defer check.
 				missing = append(missing, parsed)
 			}
+			groupName = parsed.Path()
 		}
 
 		// If necessary and possible, load another group.
@@ -727,6 +985,7 @@ func (a *Access) Can(requester upspin.UserName, right Right, pathName upspin.Pat
 			var parsed path.Parsed
 			parsed, missing = missing[len(missing)-1], missing[:len(missing)-1]
 
+			var err error
 			group, err = loadAndAdd(parsed, load)
 			// TODO issue #489, change to groupErr == nil, so we actually
 			// return an error. Leaving like this for now, to mimic the
@@ -736,43 +995,140 @@ func (a *Access) Can(requester upspin.UserName, right Right, pathName upspin.Pat
 				// Remember first load or parse error.
 				groupErr = err
 			}
+			groupName = parsed.Path()
 		}
 	}
-	return false, groupErr
+	return Grant{}, false, groupErr
 }
 
 // inGroup reports whether the requester is present in the group, either
 // directly, by wildcard, by being the owner of a nested group, or virtually by
-// finding the allUsersParsed id in the list. Any nested groups encountered
-// before ascertaining an answer get included in the set of groupsToCheck.
-func inGroup(requesterUserName upspin.UserName, domain string, group []path.Parsed, groupsToCheck *iter) bool {
+// finding the allUsersParsed id in the list, or the selfUserParsed id when the
+// requester is self, the owner of the path being checked. If so it returns the
+// member that matched. Any nested groups encountered before ascertaining an
+// answer get included in the set of groupsToCheck. If index is not nil, it is
+// the index of the group and is used instead of scanning the group.
+func inGroup(requesterUserName upspin.UserName, domain string, self upspin.UserName, group []path.Parsed, index *memberIndex, groupsToCheck *iter) (path.Parsed, bool) {
+	if index != nil {
+		if i := index.first(requesterUserName, domain, self); i >= 0 {
+			return group[i], true
+		}
+		for _, g := range index.groups {
+			groupsToCheck.add(g)
+		}
+		return path.Parsed{}, false
+	}
 	for _, member := range group {
 		memberUserName := member.User()
 		if member.IsRoot() {
 			// A user id
 			// Simple test for AllUsers, granting universal access.
 			if member == allUsersParsed {
-				return true
+				return member, true
+			}
+			// SelfUser stands for the owner of the path.
+			if member == selfUserParsed {
+				if requesterUserName == self {
+					return member, true
+				}
+				continue
 			}
 
 			if memberUserName == requesterUserName {
-				return true
+				return member, true
 			}
 			// Wildcard: The path name *@domain.com matches anyone in domain.
 			if strings.HasPrefix(string(memberUserName), "*@") && string(memberUserName[2:]) == domain {
-				return true
+				return member, true
 			}
 		} else {
 			// A nested group
 			if memberUserName == requesterUserName {
 				// The owner of a group is automatically a member of the group.
 				// No need to see that the group can even be loaded.
-				return true
+				return member, true
 			}
 			groupsToCheck.add(member)
 		}
 	}
-	return false
+	return path.Parsed{}, false
+}
+
+// minIndexed is the shortest list of users and groups that is indexed.
+// Scanning a shorter list is about as fast and saves building the index.
+const minIndexed = 16
+
+// A memberIndex records, for a list of users and groups, the position of
+// the first member matching each requester, so that checking a requester
+// against a long list, such as one with many wildcards, does not require
+// scanning it. It is built when an Access file is parsed and not changed
+// afterwards.
+type memberIndex struct {
+	users     map[upspin.UserName]int // Position of the first user, or group owned by the user, by user name.
+	wildcards map[string]int          // Position of the first wildcard *@domain, by domain.
+	all       int                     // Position of the first all@upspin.io, or -1.
+	self      int                     // Position of the first self@upspin.io, or -1.
+	groups    []path.Parsed           // The groups in the list, in order.
+}
+
+// newMemberIndex returns the index of the list, or nil if the list is
+// shorter than minIndexed.
+func newMemberIndex(list []path.Parsed) *memberIndex {
+	if len(list) < minIndexed {
+		return nil
+	}
+	x := &memberIndex{
+		users: make(map[upspin.UserName]int),
+		all:   -1,
+		self:  -1,
+	}
+	for i, member := range list {
+		name := member.User()
+		switch {
+		case !member.IsRoot():
+			x.groups = append(x.groups, member)
+		case member == allUsersParsed:
+			if x.all < 0 {
+				x.all = i
+			}
+			continue
+		case member == selfUserParsed:
+			if x.self < 0 {
+				x.self = i
+			}
+			continue
+		case strings.HasPrefix(string(name), "*@"):
+			if x.wildcards == nil {
+				x.wildcards = make(map[string]int)
+			}
+			if _, ok := x.wildcards[string(name[2:])]; !ok {
+				x.wildcards[string(name[2:])] = i
+			}
+			continue
+		}
+		if _, ok := x.users[name]; !ok {
+			x.users[name] = i
+		}
+	}
+	return x
+}
+
+// first returns the position of the first member of the indexed list that
+// matches the requester, whose domain is given, as inGroup would find it,
+// or -1 if there is none. Self is the owner of the path being checked.
+func (x *memberIndex) first(requester upspin.UserName, domain string, self upspin.UserName) int {
+	pos := x.all
+	earliest := func(i int, ok bool) {
+		if ok && (pos < 0 || i < pos) {
+			pos = i
+		}
+	}
+	earliest(x.self, x.self >= 0 && requester == self)
+	i, ok := x.users[requester]
+	earliest(i, ok)
+	i, ok = x.wildcards[domain]
+	earliest(i, ok)
+	return pos
 }
 
 // loadAndAdd returns the group having loaded the file and calling AddGroup on the result.
@@ -790,14 +1146,16 @@ func loadAndAdd(parsed path.Parsed, load func(upspin.PathName) ([]byte, error))
 	return
 }
 
-func (a *Access) getListFor(right Right) ([]path.Parsed, error) {
+// getListFor returns the list of users and groups granted the right
+// and its index, if any.
+func (a *Access) getListFor(right Right) ([]path.Parsed, *memberIndex, error) {
 	switch right {
 	case Read, Write, List, Create, Delete:
-		return a.list[right], nil
+		return a.list[right], a.listIndex[right], nil
 	case AnyRight:
-		return a.allUsers, nil
+		return a.allUsers, a.allUsersIndex, nil
 	default:
-		return nil, errors.Errorf("unrecognized right value %d", right)
+		return nil, nil, errors.Errorf("unrecognized right value %d", right)
 	}
 }
 
@@ -831,23 +1189,207 @@ func (a *Access) List(right Right) []path.Parsed {
 	return out
 }
 
+// DenyList returns the list of users and groups denied the specified right,
+// in the same form as List. For AnyRight, it returns the concatenation of
+// the lists for all rights.
+func (a *Access) DenyList(right Right) []path.Parsed {
+	var out []path.Parsed
+	if right == AnyRight {
+		for _, list := range a.deny {
+			out = append(out, list...)
+		}
+		return out
+	}
+	if right < 0 || numRights <= right || len(a.deny[right]) == 0 {
+		return nil
+	}
+	return append(out, a.deny[right]...)
+}
+
 // Users returns the user names granted a given right according to the rules of
 // the Access file. It also interprets the rule that the owner can always Read
 // and List. Users loads group files as needed by calling the provided function
-// to read each file's contents.
+// to read each file's contents. Users denied the right are omitted, although a
+// denial cannot remove individual users from a wildcard such as *@domain.com.
+// The special user "self" is reported as the owner of the Access file, since
+// every path the file governs is in that user's tree.
 func (a *Access) Users(right Right, load func(upspin.PathName) ([]byte, error)) ([]upspin.UserName, error) {
-	group, err := a.getListFor(right)
+	var userNameSet map[upspin.UserName]struct{}
+	if right == AnyRight && a.hasDenials() {
+		// Denials apply per right, so gather the users right by right.
+		userNameSet = make(map[upspin.UserName]struct{})
+		for r := Right(0); r < numRights; r++ {
+			set, err := a.userSet(r, load)
+			if err != nil {
+				return nil, err
+			}
+			for u := range set {
+				userNameSet[u] = struct{}{}
+			}
+		}
+	} else {
+		var err error
+		userNameSet, err = a.userSet(right, load)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(userNameSet) == 0 {
+		return nil, nil
+	}
+
+	// Build a slice and then sort it.
+	userNames := make([]upspin.UserName, 0, len(userNameSet))
+	for k := range userNameSet {
+		userNames = append(userNames, k)
+	}
+
+	sort.Sort(sliceOfUserName(userNames))
+
+	return userNames, nil
+}
+
+// UsersByRight returns, for each right granted by the Access file, the sorted
+// user names holding that right, as computed by Users. Rights held by no one
+// are omitted from the map. Group files are loaded as needed by calling the
+// provided function. If the groups refer to one another in a cycle,
+// UsersByRight returns an error describing the cycle.
+func (a *Access) UsersByRight(load func(upspin.PathName) ([]byte, error)) (map[Right][]upspin.UserName, error) {
+	const op errors.Op = "access.UsersByRight"
+	if err := findCycle(a.allUsers, load); err != nil {
+		return nil, errors.E(op, a.Path(), err)
+	}
+	for _, list := range a.deny {
+		if err := findCycle(list, load); err != nil {
+			return nil, errors.E(op, a.Path(), err)
+		}
+	}
+	byRight := make(map[Right][]upspin.UserName)
+	for r := Right(0); r < numRights; r++ {
+		users, err := a.Users(r, load)
+		if err != nil {
+			return nil, errors.E(op, a.Path(), err)
+		}
+		if len(users) > 0 {
+			byRight[r] = users
+		}
+	}
+	return byRight, nil
+}
+
+// findCycle returns an error describing a cycle in the graph of groups
+// reachable from the list, if there is one. Groups are loaded as needed
+// by calling the provided function.
+func findCycle(list []path.Parsed, load func(upspin.PathName) ([]byte, error)) error {
+	const (
+		visiting = 1 + iota
+		visited
+	)
+	state := make(map[upspin.PathName]int)
+	var stack []upspin.PathName // Groups being visited, outermost first.
+
+	var visit func(p path.Parsed) error
+	visit = func(p path.Parsed) error {
+		name := p.Path()
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			var cycle []string
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					for _, n := range stack[i:] {
+						cycle = append(cycle, string(n))
+					}
+					break
+				}
+			}
+			cycle = append(cycle, string(name))
+			return errors.E(errors.Invalid, errors.Str("group cycle: "+strings.Join(cycle, " -> ")))
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+
+		mu.RLock()
+		group, found := groups[name]
+		mu.RUnlock()
+		if !found {
+			var err error
+			group, err = loadAndAdd(p, load)
+			if err != nil {
+				return err
+			}
+		}
+		for _, member := range group {
+			if member.IsRoot() {
+				continue
+			}
+			if err := visit(member); err != nil {
+				return err
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, p := range list {
+		if p.IsRoot() {
+			continue
+		}
+		if err := visit(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userSet returns the set of user names granted the right, less those denied it.
+func (a *Access) userSet(right Right, load func(upspin.PathName) ([]byte, error)) (map[upspin.UserName]struct{}, error) {
+	group, _, err := a.getListFor(right)
+	if err != nil {
+		return nil, err
+	}
+	userNameSet, err := expandUsers(group, a.owner, load)
 	if err != nil {
 		return nil, err
 	}
 
-	userNameSet := make(map[upspin.UserName]struct{})
-	var groupsToCheck iter
+	if right != AnyRight && len(a.deny[right]) > 0 {
+		denied, err := expandUsers(a.deny[right], a.owner, load)
+		if err != nil {
+			return nil, err
+		}
+		for u := range userNameSet {
+			_, _, domain, err := user.Parse(u)
+			if err != nil {
+				// Wildcards do not parse; they are only removed by an identical denial.
+				domain = ""
+			}
+			_, isDenied := denied[u]
+			_, isDomainDenied := denied["*@"+upspin.UserName(domain)]
+			if isDenied || domain != "" && isDomainDenied {
+				delete(userNameSet, u)
+			}
+		}
+	}
 
 	switch right {
 	case Read, List:
 		userNameSet[a.owner] = struct{}{}
 	}
+	return userNameSet, nil
+}
+
+// expandUsers returns the set of user names in the list, including those
+// reachable through groups, which are loaded as needed by calling the
+// provided function. The owner of a group is included as a member.
+// SelfUser is replaced by self, unless self is empty.
+func expandUsers(group []path.Parsed, self upspin.UserName, load func(upspin.PathName) ([]byte, error)) (map[upspin.UserName]struct{}, error) {
+	userNameSet := make(map[upspin.UserName]struct{})
+	var groupsToCheck iter
 
 	// Loop over all the group lists reachable by traversing the graph rooted
 	// with the access right given. Every group list can include parsed user
@@ -856,7 +1398,11 @@ func (a *Access) Users(right Right, load func(upspin.PathName) ([]byte, error))
 	for {
 		for _, parsed := range group {
 			// Be it a user or a nested group owner, the group member user is granted the right.
-			userNameSet[parsed.User()] = struct{}{}
+			if parsed == selfUserParsed && self != "" {
+				userNameSet[self] = struct{}{}
+			} else {
+				userNameSet[parsed.User()] = struct{}{}
+			}
 
 			// A nested group bears traversal too.
 			if !parsed.IsRoot() {
@@ -878,26 +1424,22 @@ func (a *Access) Users(right Right, load func(upspin.PathName) ([]byte, error))
 		mu.RUnlock()
 
 		if !found {
+			var err error
 			group, err = loadAndAdd(parsed, load)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
+	return userNameSet, nil
+}
 
-	if len(userNameSet) == 0 {
-		return nil, nil
-	}
-
-	// Build a slice and then sort it.
-	userNames := make([]upspin.UserName, 0, len(userNameSet))
-	for k := range userNameSet {
-		userNames = append(userNames, k)
-	}
-
-	sort.Sort(sliceOfUserName(userNames))
-
-	return userNames, nil
+// accessJSON is the JSON encoding of an Access that denies rights.
+// An Access that denies no rights is encoded as just its list of grants,
+// as it was before denials existed.
+type accessJSON struct {
+	List [numRights][]path.Parsed
+	Deny [numRights][]path.Parsed
 }
 
 // MarshalJSON returns a JSON-encoded representation of this Access struct.
@@ -907,7 +1449,11 @@ func (a *Access) MarshalJSON() ([]byte, error) {
 	// so we encode it separately.
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
-	if err := enc.Encode(a.list); err != nil {
+	var v interface{} = a.list
+	if a.hasDenials() {
+		v = accessJSON{List: a.list, Deny: a.deny}
+	}
+	if err := enc.Encode(v); err != nil {
 		return nil, errors.E(op, err)
 	}
 	return buf.Bytes(), nil
@@ -916,13 +1462,19 @@ func (a *Access) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON returns an Access given its path name and its JSON encoding.
 func UnmarshalJSON(name upspin.PathName, jsonAccess []byte) (*Access, error) {
 	const op errors.Op = "access.UnmarshalJSON"
-	var list [numRights][]path.Parsed
-	err := json.Unmarshal(jsonAccess, &list)
+	var v accessJSON
+	var err error
+	if trimmed := bytes.TrimSpace(jsonAccess); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(jsonAccess, &v)
+	} else {
+		err = json.Unmarshal(jsonAccess, &v.List)
+	}
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
 	access := &Access{
-		list: list,
+		list: v.List,
+		deny: v.Deny,
 	}
 	access.parsed, err = path.Parse(name)
 	if err != nil {
@@ -937,8 +1489,9 @@ func UnmarshalJSON(name upspin.PathName, jsonAccess []byte) (*Access, error) {
 }
 
 // IsReadableByAll reports whether the Access file has read:all or read:all@upspin.io
+// and denies Read to no one.
 func (a *Access) IsReadableByAll() bool {
-	return a.worldReadable
+	return a.worldReadable && len(a.deny[Read]) == 0
 }
 
 // iter implements an iterator over path.Parsed items.
@@ -5,8 +5,11 @@
 package access
 
 import (
+	"bytes"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"upspin.io/errors"
@@ -219,6 +222,122 @@ func TestParseGroup(t *testing.T) {
 	match(t, group, []string{"fred@me.com", "ann@me.com", "joe@me.com"})
 }
 
+func TestUpdateGroup(t *testing.T) {
+	resetGroupsCache()
+
+	loadTest := func(name upspin.PathName) ([]byte, error) {
+		switch name {
+		case "me@here.com/Group/kids":
+			return []byte("son@me.com, daughter@me.com\n"), nil
+		default:
+			return nil, errors.Errorf("%s not found", name)
+		}
+	}
+
+	// A group that was not installed reports all its members.
+	changed, err := UpdateGroup(testGroupFile, []byte("fred@me.com, ann@me.com"), loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"ann@me.com", "fred@me.com"}, listFromUserName(changed))
+
+	// Adding and removing users reports just them.
+	changed, err = UpdateGroup(testGroupFile, []byte("ann@me.com, joe@me.com, *@you.com"), loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"*@you.com", "fred@me.com", "joe@me.com"}, listFromUserName(changed))
+
+	// Adding a nested group reports its members and its owner.
+	changed, err = UpdateGroup(testGroupFile, []byte("ann@me.com, joe@me.com, *@you.com, kids"), loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"daughter@me.com", "me@here.com", "son@me.com"}, listFromUserName(changed))
+
+	// A user who is already a member through a nested group is unaffected.
+	changed, err = UpdateGroup(testGroupFile, []byte("ann@me.com, joe@me.com, *@you.com, kids, son@me.com"), loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{}, listFromUserName(changed))
+
+	// Removing the nested group reports only those no longer members.
+	changed, err = UpdateGroup(testGroupFile, []byte("ann@me.com, joe@me.com, *@you.com, son@me.com"), loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"daughter@me.com", "me@here.com"}, listFromUserName(changed))
+
+	// The new definition is installed.
+	a, err := Parse(testFile, []byte("r: family"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := a.Can("son@me.com", Read, "me@here.com/foo", loadTest)
+	if err != nil || !ok {
+		t.Errorf("son@me.com cannot read: %v", err)
+	}
+	ok, err = a.Can("fred@me.com", Read, "me@here.com/foo", loadTest)
+	if err != nil || ok {
+		t.Errorf("fred@me.com can read: %v", err)
+	}
+}
+
+func TestUsersByRight(t *testing.T) {
+	resetGroupsCache()
+
+	loadTest := func(name upspin.PathName) ([]byte, error) {
+		switch name {
+		case "me@here.com/Group/family":
+			return []byte("sister@me.com, kids\n"), nil
+		case "me@here.com/Group/kids":
+			return []byte("son@me.com, sister@me.com\n"), nil
+		case "me@here.com/Group/a":
+			return []byte("a@me.com, b\n"), nil
+		case "me@here.com/Group/b":
+			return []byte("b@me.com, a\n"), nil
+		default:
+			return nil, errors.Errorf("%s not found", name)
+		}
+	}
+
+	a, err := Parse(testFile, []byte("r: family, friend@you.com\nw: kids\nd: friend@you.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byRight, err := a.UsersByRight(loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[Right][]string{
+		Read:   {"friend@you.com", "me@here.com", "sister@me.com", "son@me.com"},
+		Write:  {"me@here.com", "sister@me.com", "son@me.com"},
+		List:   {"me@here.com"},
+		Delete: {"friend@you.com"},
+	}
+	if len(byRight) != len(want) {
+		t.Errorf("got %d rights, want %d: %v", len(byRight), len(want), byRight)
+	}
+	for r, users := range want {
+		expectEqual(t, users, listFromUserName(byRight[r]))
+	}
+
+	// A cycle between groups is reported.
+	a, err = Parse(testFile, []byte("r: a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.UsersByRight(loadTest)
+	if err == nil {
+		t.Fatal("expected error for group cycle")
+	}
+	const cycle = "group cycle: me@here.com/Group/a -> me@here.com/Group/b -> me@here.com/Group/a"
+	if !strings.Contains(err.Error(), cycle) {
+		t.Errorf("err = %q, want %q", err, cycle)
+	}
+}
+
 func TestParseAllocs(t *testing.T) {
 	allocs := testing.AllocsPerRun(100, func() {
 		Parse(testFile, accessText)
@@ -438,6 +557,187 @@ func TestHasAccessWithGroups(t *testing.T) {
 	}
 }
 
+func TestWhyCan(t *testing.T) {
+	resetGroupsCache()
+
+	const (
+		accessText = "r: reader@r.com, *@nsa.gov, family\n" +
+			"w: friends\n" +
+			"l: all\n"
+		file = upspin.PathName("me@here.com/foo/bar")
+	)
+
+	loadTest := func(name upspin.PathName) ([]byte, error) {
+		switch name {
+		case "me@here.com/Group/family":
+			return []byte("sister@me.com, brother@me.com\n"), nil
+		case "me@here.com/Group/friends":
+			return []byte("pal@me.com, family, *@club.org\n"), nil
+		default:
+			return nil, errors.Errorf("%s not found", name)
+		}
+	}
+
+	a, err := Parse(testFile, []byte(accessText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user  upspin.UserName
+		right Right
+		file  upspin.PathName
+		owner bool
+		match string
+		group upspin.PathName
+	}{
+		{"reader@r.com", Read, file, false, "reader@r.com/", ""},
+		{"spy@nsa.gov", Read, file, false, "*@nsa.gov/", ""},
+		{"sister@me.com", Read, file, false, "sister@me.com/", "me@here.com/Group/family"},
+		{"pal@me.com", Write, file, false, "pal@me.com/", "me@here.com/Group/friends"},
+		{"brother@me.com", Write, file, false, "brother@me.com/", "me@here.com/Group/family"},
+		{"member@club.org", Write, file, false, "*@club.org/", "me@here.com/Group/friends"},
+		{"anyone@any.com", List, file, false, "all@upspin.io/", ""},
+		{"me@here.com", Read, file, true, "", ""},
+		{"me@here.com", Write, testFile, true, "", ""},
+		// The owner of a group is a member of the group.
+		{"me@here.com", Write, file, false, "me@here.com/Group/friends", ""},
+	}
+	for _, test := range tests {
+		grant, err := a.WhyCan(test.user, test.right, test.file, loadTest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if grant == nil {
+			t.Errorf("%s cannot %s %s", test.user, test.right, test.file)
+			continue
+		}
+		if grant.Owner != test.owner {
+			t.Errorf("%s %s %s: Owner = %v, want %v", test.user, test.right, test.file, grant.Owner, test.owner)
+		}
+		if test.owner {
+			continue
+		}
+		if got := string(grant.Match.Path()); got != test.match {
+			t.Errorf("%s %s %s: Match = %q, want %q", test.user, test.right, test.file, got, test.match)
+		}
+		if grant.Group != test.group {
+			t.Errorf("%s %s %s: Group = %q, want %q", test.user, test.right, test.file, grant.Group, test.group)
+		}
+	}
+
+	// Denied access reports no grant.
+	grant, err := a.WhyCan("stranger@else.com", Read, file, loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grant != nil {
+		t.Errorf("stranger@else.com granted Read: %+v", grant)
+	}
+}
+
+func TestDeny(t *testing.T) {
+	resetGroupsCache()
+
+	const (
+		accessText = "r, l: *@ourcorp.com, family\n" +
+			"w: family\n" +
+			"!r: bad@ourcorp.com, badfamily\n" +
+			"!*: worse@ourcorp.com\n"
+		file = upspin.PathName("me@here.com/foo/bar")
+	)
+
+	loadTest := func(name upspin.PathName) ([]byte, error) {
+		switch name {
+		case "me@here.com/Group/family":
+			return []byte("sister@me.com, cousin@me.com, worse@ourcorp.com\n"), nil
+		case "me@here.com/Group/badfamily":
+			return []byte("cousin@me.com\n"), nil
+		default:
+			return nil, errors.Errorf("%s not found", name)
+		}
+	}
+
+	a, err := Parse(testFile, []byte(accessText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(user upspin.UserName, right Right, truth bool) {
+		t.Helper()
+		ok, err := a.Can(user, right, file, loadTest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != truth {
+			t.Errorf("Can(%s, %s) = %v, want %v", user, right, ok, truth)
+		}
+	}
+
+	// Wildcard grants, but individual denial overrides.
+	check("good@ourcorp.com", Read, true)
+	check("bad@ourcorp.com", Read, false)
+	check("bad@ourcorp.com", List, true)
+	check("bad@ourcorp.com", AnyRight, true)
+
+	// Denial of all rights covers every right.
+	check("worse@ourcorp.com", Read, false)
+	check("worse@ourcorp.com", List, false)
+	check("worse@ourcorp.com", Write, false)
+	check("worse@ourcorp.com", AnyRight, false)
+
+	// Group members are denied through a group.
+	check("sister@me.com", Read, true)
+	check("cousin@me.com", Read, false)
+	check("cousin@me.com", Write, true)
+
+	// The owner's implicit rights cannot be denied.
+	b, err := Parse(testFile, []byte("!*: me@here.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := b.Can("me@here.com", Read, file, loadTest); !ok || err != nil {
+		t.Errorf("owner cannot read: %v", err)
+	}
+
+	// Users respects denials.
+	users, err := a.Users(Read, loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"*@ourcorp.com", "me@here.com", "sister@me.com"}, listFromUserName(users))
+	users, err = a.Users(Write, loadTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"cousin@me.com", "me@here.com", "sister@me.com"}, listFromUserName(users))
+
+	// A deny Group that cannot be loaded fails closed.
+	c, err := Parse(testFile, []byte("r: *@ourcorp.com\n!r: missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.Can("good@ourcorp.com", Read, file, loadTest); ok {
+		t.Error("read granted despite unloadable deny group")
+	}
+
+	// A file readable by all but with a Read denial is not readable by all.
+	d, err := Parse(testFile, []byte("r: all\n!r: bad@ourcorp.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.IsReadableByAll() {
+		t.Error("IsReadableByAll despite denial")
+	}
+}
+
+func TestParseDenyAll(t *testing.T) {
+	_, err := Parse(testFile, []byte("!r: all"))
+	if err == nil {
+		t.Fatal("expected error denying all")
+	}
+}
+
 func TestAccessAllUsers(t *testing.T) {
 	const (
 		owner = upspin.UserName("me@here.com")
@@ -486,6 +786,95 @@ func TestAccessAllUsers(t *testing.T) {
 	check("someone@obscure.com", Write, "me@here.com/foo/bar", false)
 }
 
+func TestAccessSelf(t *testing.T) {
+	resetGroupsCache()
+
+	const (
+		ann = upspin.UserName("ann@example.com")
+		bob = upspin.UserName("bob@example.com")
+		joe = upspin.UserName("joe@example.com")
+		sam = upspin.UserName("sam@example.com")
+
+		// A group in Ann's tree that grants rights to the owner of
+		// whatever path it is applied to, and to Joe.
+		template = upspin.PathName("ann@example.com/Group/editors")
+		text     = "w, c: ann@example.com/Group/editors\nd: SELF\n"
+	)
+	load := func(name upspin.PathName) ([]byte, error) {
+		if name == template {
+			return []byte("self\njoe@example.com\n"), nil
+		}
+		return nil, errors.Errorf("%s not found", name)
+	}
+
+	annAccess, err := Parse("ann@example.com/Access", []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobAccess, err := Parse("bob@example.com/Access", []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := bobAccess.List(Delete); len(list) != 1 || list[0].User() != SelfUser {
+		t.Errorf("delete list is %v, want [%s]", list, SelfUser)
+	}
+
+	tests := []struct {
+		access *Access
+		user   upspin.UserName
+		right  Right
+		file   upspin.PathName
+		truth  bool
+	}{
+		// The owner of the path is granted the rights, whichever
+		// tree holds the Group file.
+		{annAccess, ann, Write, "ann@example.com/file", true},
+		{bobAccess, bob, Write, "bob@example.com/file", true},
+		{bobAccess, bob, Delete, "bob@example.com/file", true},
+		{bobAccess, joe, Write, "bob@example.com/file", true},
+
+		// Other users are not. The owner of the Group file is
+		// a member of it, but is not self in another's tree.
+		{bobAccess, sam, Write, "bob@example.com/file", false},
+		{bobAccess, sam, Delete, "bob@example.com/file", false},
+		{bobAccess, ann, Delete, "bob@example.com/file", false},
+		{bobAccess, joe, Delete, "bob@example.com/file", false},
+		{annAccess, bob, Delete, "ann@example.com/file", false},
+	}
+	for _, test := range tests {
+		ok, err := test.access.Can(test.user, test.right, test.file, load)
+		if err != nil {
+			t.Errorf("%s %s %s: %v", test.user, test.right, test.file, err)
+			continue
+		}
+		if ok != test.truth {
+			t.Errorf("%s %s %s: got %t, want %t", test.user, test.right, test.file, ok, test.truth)
+		}
+	}
+
+	grant, err := bobAccess.WhyCan(bob, Write, "bob@example.com/file", load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grant == nil || grant.Match != selfUserParsed || grant.Group != template {
+		t.Errorf("WhyCan = %+v, want match %s in %s", grant, SelfUser, template)
+	}
+
+	// Users reports self as the owner of the Access file.
+	users, err := bobAccess.Users(Write, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []upspin.UserName{ann, bob, joe}; !reflect.DeepEqual(users, want) {
+		t.Errorf("Users(Write) = %v, want %v", users, want)
+	}
+
+	// The full name cannot be used.
+	if _, err := Parse(testFile, []byte("r: self@upspin.io")); err == nil {
+		t.Errorf("Parse accepted self@upspin.io")
+	}
+}
+
 func TestGroupDisallowsAll(t *testing.T) {
 	parsed, err := path.Parse("me@here.com/Group/meAndAllElse")
 	if err != nil {
@@ -673,6 +1062,24 @@ func TestMarshal(t *testing.T) {
 	}
 }
 
+func TestMarshalDeny(t *testing.T) {
+	a, err := Parse(testFile, []byte("r,w: *@ourcorp.com, family\n!r: bad@ourcorp.com\n!*: worse@ourcorp.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := a.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := UnmarshalJSON(testFile, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.equal(b) {
+		t.Errorf("Marshal/Unmarshal failed to recover denials: got %v, want %v", b.deny, a.deny)
+	}
+}
+
 func TestNew(t *testing.T) {
 	const path = upspin.PathName("bob@foo.com/my/private/sub/dir/Access")
 	a, err := New(path)
@@ -1291,11 +1698,12 @@ func (a *Access) equal(b *Access) bool {
 	if a.parsed.Compare(b.parsed) != 0 {
 		return false
 	}
-	if len(a.list) != len(b.list) {
-		return false
-	}
-	for i, al := range a.list {
-		bl := b.list[i]
+	return equalLists(a.list, b.list) && equalLists(a.deny, b.deny)
+}
+
+func equalLists(a, b [numRights][]path.Parsed) bool {
+	for i, al := range a {
+		bl := b[i]
 		if len(al) != len(bl) {
 			return false
 		}
@@ -1346,6 +1754,78 @@ func usersWithMissing(a *Access, right Right) ([]upspin.UserName, []upspin.PathN
 	return users, missing, err
 }
 
+// wildcardAccess returns the text of an Access file granting Read to
+// n wildcards and n users, and Write and List to some of each.
+func wildcardAccess(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("r: ")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "*@domain%d.com, user%d@example.com, ", i, i)
+	}
+	b.WriteString("friends, joe@domain3.com\n")
+	b.WriteString("w: self, ann@group.com/Group/writers, *@domain3.com, joe@domain3.com\n")
+	b.WriteString("l: all\n")
+	b.WriteString("!r: *@domain7.com, user8@example.com\n")
+	return b.Bytes()
+}
+
+func TestIndexedLists(t *testing.T) {
+	defer resetGroupsCache()
+	const owner = "me@here.com"
+	a, err := Parse(owner+"/Access", wildcardAccess(minIndexed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.listIndex[Read] == nil || a.allUsersIndex == nil {
+		t.Fatal("long lists are not indexed")
+	}
+	if a.listIndex[Write] != nil {
+		t.Fatal("short list is indexed")
+	}
+	// A copy that scans every list.
+	scan := *a
+	scan.listIndex = [numRights]*memberIndex{}
+	scan.denyIndex = [numRights]*memberIndex{}
+	scan.allUsersIndex = nil
+
+	load := func(name upspin.PathName) ([]byte, error) {
+		return []byte("friend@elsewhere.com\n"), nil
+	}
+	requesters := []upspin.UserName{
+		owner, "ann@group.com", "friend@elsewhere.com", "joe@domain3.com",
+		"bob@domain3.com", "bob@domain7.com", "user5@example.com", "user8@example.com",
+		"nobody@nowhere.com",
+	}
+	for _, requester := range requesters {
+		for _, right := range []Right{Read, Write, List, Create, Delete, AnyRight} {
+			const file = owner + "/file"
+			want, wantErr := scan.WhyCan(requester, right, file, load)
+			got, err := a.WhyCan(requester, right, file, load)
+			if !reflect.DeepEqual(got, want) || err != wantErr {
+				t.Errorf("WhyCan(%q, %v) = %+v, %v; scanning gives %+v, %v", requester, right, got, err, want, wantErr)
+			}
+		}
+	}
+}
+
+func BenchmarkCanWildcards(b *testing.B) {
+	a, err := Parse("me@here.com/Access", wildcardAccess(100))
+	if err != nil {
+		b.Fatal(err)
+	}
+	load := func(name upspin.PathName) ([]byte, error) {
+		return nil, errors.Str("no groups")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ok, err := a.Can("joe@domain99.com", Read, "me@here.com/file", load)
+		if !ok || err != nil {
+			b.Fatalf("Can = %t, %v", ok, err)
+		}
+	}
+}
+
 // resetGroupsCache sets the global groups variable back to its starting point.
 func resetGroupsCache() {
 	groups = make(map[upspin.PathName][]path.Parsed) // Forget any existing groups in the cache.
@@ -7,6 +7,7 @@ package bind // import "upspin.io/bind"
 
 import (
 	"sync"
+	"time"
 
 	"upspin.io/errors"
 	"upspin.io/upspin"
@@ -20,7 +21,28 @@ type dialKey struct {
 }
 
 type dialers map[upspin.Transport]upspin.Dialer
-type services map[dialKey]upspin.Service
+type services map[dialKey]*cachedService
+
+// cachedService is a dialed service and the last time it was known to
+// be reachable.
+type cachedService struct {
+	service upspin.Service
+	checked time.Time
+}
+
+// Pinger is implemented by services that can report whether they are
+// still reachable. Before returning a cached service that implements
+// Pinger and has not been checked for PingInterval, bind calls Ping.
+// If it fails, bind closes the service, evicts it from the cache,
+// and dials the endpoint afresh.
+type Pinger interface {
+	// Ping reports whether the service is reachable.
+	Ping() bool
+}
+
+// PingInterval is how long a cached service is trusted to be reachable
+// before being checked again with Ping.
+const PingInterval = 1 * time.Minute
 
 // The servers struct tracks upspin.Dialers that have been registered for
 // various transports, and upspin.Services that have already been
@@ -157,26 +179,94 @@ func (s *servers) register(transport upspin.Transport, key upspin.Dialer) error
 // fresh one and saves it in the cache.
 func (s *servers) reachableService(cc upspin.Config, e upspin.Endpoint) (upspin.Service, error) {
 	key := dialKey{user: cc.UserName(), endpoint: e, cacheserver: cc.CacheEndpoint()}
+	s.mu.Lock()
+	c, cached := s.services[key]
+	ping := cached && needsPing(c)
+	s.mu.Unlock()
+	// Ping without holding the lock, as it may take a long time.
+	if cached && (!ping || s.ping(c)) {
+		return c.service, nil
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	service, cached := s.services[key]
-	if !cached {
-		var err error
-		dialer, ok := s.dialers[e.Transport]
-		if !ok {
-			return nil, errors.E(s.serverOp(), errors.Invalid, errors.Errorf("service with transport %q not registered", e.Transport))
-		}
-		service, err = dialer.Dial(cc, e)
-		if err != nil {
-			return nil, errors.E(s.serverOp(), err)
-		}
-		if !noCache {
-			s.services[key] = service
-		}
+	if cached && s.services[key] == c {
+		// Unreachable; forget it and dial again.
+		delete(s.services, key)
+		c.service.Close()
+	}
+	if c, ok := s.services[key]; ok {
+		// Dialed by another caller while we pinged.
+		return c.service, nil
+	}
+	dialer, ok := s.dialers[e.Transport]
+	if !ok {
+		return nil, errors.E(s.serverOp(), errors.Invalid, errors.Errorf("service with transport %q not registered", e.Transport))
+	}
+	service, err := dialer.Dial(cc, e)
+	if err != nil {
+		return nil, errors.E(s.serverOp(), err)
+	}
+	if !noCache {
+		s.services[key] = &cachedService{service: service, checked: time.Now()}
 	}
 	return service, nil
 }
 
+// needsPing reports whether the cached service is a Pinger
+// that has not been checked recently.
+// s.mu must be held.
+func needsPing(c *cachedService) bool {
+	_, ok := c.service.(Pinger)
+	return ok && time.Since(c.checked) >= PingInterval
+}
+
+// ping reports whether the cached service, a Pinger, is reachable,
+// recording when it was found to be.
+// s.mu must not be held.
+func (s *servers) ping(c *cachedService) bool {
+	if !c.service.(Pinger).Ping() {
+		return false
+	}
+	s.mu.Lock()
+	c.checked = time.Now()
+	s.mu.Unlock()
+	return true
+}
+
+// Release closes and evicts from the cache all the services dialed
+// on behalf of the given config, that is, for its user and cache server.
+// Services previously returned for the config must not be used afterwards;
+// later calls to KeyServer, DirServer, and StoreServer dial afresh.
+func Release(cc upspin.Config) {
+	match := func(k dialKey) bool {
+		return k.user == cc.UserName() && k.cacheserver == cc.CacheEndpoint()
+	}
+	for _, s := range []*servers{&keyServers, &dirServers, &storeServers} {
+		s.release(match)
+	}
+}
+
+// ReleaseAll closes and evicts from the cache all dialed services,
+// whatever their config.
+func ReleaseAll() {
+	for _, s := range []*servers{&keyServers, &dirServers, &storeServers} {
+		s.release(func(dialKey) bool { return true })
+	}
+}
+
+// release closes and evicts the cached services whose keys match.
+func (s *servers) release(match func(dialKey) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, c := range s.services {
+		if match(k) {
+			delete(s.services, k)
+			c.service.Close()
+		}
+	}
+}
+
 func (s *servers) registerOp() errors.Op {
 	return errors.Op("bind.Register" + s.kind + "Server") // "bind.RegisterKeyServer"
 }
@@ -78,6 +78,122 @@ func TestSwitch(t *testing.T) {
 	}
 }
 
+func TestRelease(t *testing.T) {
+	cfg := testfixtures.NewSimpleConfig("ann@example.com")
+	cfg2 := testfixtures.NewSimpleConfig("bob@example.com")
+	e := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "release"}
+	dp := &dummyPinger{}
+	if err := RegisterKeyServer(e.Transport, dp); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		keyServers.mu.Lock()
+		delete(keyServers.dialers, e.Transport)
+		keyServers.mu.Unlock()
+		ReleaseAll()
+	}()
+
+	k1, err := KeyServer(cfg, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := KeyServer(cfg2, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Releasing ann's config closes only her service.
+	Release(cfg)
+	if k1.(*dummyPinger).closed != 1 || k2.(*dummyPinger).closed != 0 {
+		t.Fatalf("after Release: closed %d and %d times, want 1 and 0", k1.(*dummyPinger).closed, k2.(*dummyPinger).closed)
+	}
+	k3, err := KeyServer(cfg, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k3 == k1 || dp.dialed != 3 {
+		t.Errorf("after Release: got cached service, %d dials; want new service, 3 dials", dp.dialed)
+	}
+
+	// A service that fails its Ping is replaced, but only
+	// once PingInterval has passed since it was last checked.
+	k3.(*dummyPinger).dead = true
+	if k, _ := KeyServer(cfg, e); k != k3 {
+		t.Errorf("dead service replaced before PingInterval")
+	}
+	keyServers.mu.Lock()
+	for _, c := range keyServers.services {
+		c.checked = c.checked.Add(-PingInterval)
+	}
+	keyServers.mu.Unlock()
+	k4, err := KeyServer(cfg, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k4 == k3 || k3.(*dummyPinger).closed != 1 {
+		t.Errorf("dead service not closed and replaced")
+	}
+	if k, _ := KeyServer(cfg2, e); k != k2 {
+		t.Errorf("live service replaced")
+	}
+
+	ReleaseAll()
+	if k2.(*dummyPinger).closed != 1 || k4.(*dummyPinger).closed != 1 {
+		t.Errorf("after ReleaseAll: services not closed")
+	}
+}
+
+func TestPingWithoutLock(t *testing.T) {
+	cfg := testfixtures.NewSimpleConfig("ann@example.com")
+	slow := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "slow"}
+	fast := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "fast"}
+	dp := &dummyPinger{}
+	if err := RegisterKeyServer(slow.Transport, dp); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		keyServers.mu.Lock()
+		delete(keyServers.dialers, slow.Transport)
+		keyServers.mu.Unlock()
+		ReleaseAll()
+	}()
+	k, err := KeyServer(cfg, slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := KeyServer(cfg, fast); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the slow service's next Ping block until released.
+	pinging := make(chan bool)
+	unblock := make(chan bool)
+	k.(*dummyPinger).block = func() {
+		pinging <- true
+		<-unblock
+	}
+	keyServers.mu.Lock()
+	for _, c := range keyServers.services {
+		c.checked = c.checked.Add(-PingInterval)
+	}
+	keyServers.mu.Unlock()
+	go KeyServer(cfg, slow)
+	<-pinging
+
+	// Another service can be bound meanwhile.
+	done := make(chan bool)
+	go func() {
+		KeyServer(cfg, fast)
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Error("KeyServer blocked by Ping of another service")
+	}
+	close(unblock)
+}
+
 // Some dummy interfaces.
 type dummyKey struct {
 	testfixtures.DummyKey
@@ -127,3 +243,27 @@ func (d *dummyDirServer) Dial(cc upspin.Config, e upspin.Endpoint) (upspin.Servi
 func (d *dummyDirServer) Endpoint() upspin.Endpoint {
 	return d.endpoint
 }
+
+// dummyPinger is a KeyServer that implements Pinger.
+type dummyPinger struct {
+	dummyKey
+	dead   bool
+	closed int
+	block  func() // If not nil, called by Ping.
+}
+
+func (d *dummyPinger) Dial(cc upspin.Config, e upspin.Endpoint) (upspin.Service, error) {
+	d.dialed++
+	return &dummyPinger{dummyKey: dummyKey{endpoint: e}}, nil
+}
+
+func (d *dummyPinger) Ping() bool {
+	if d.block != nil {
+		d.block()
+	}
+	return !d.dead
+}
+
+func (d *dummyPinger) Close() {
+	d.closed++
+}
@@ -17,8 +17,10 @@ import (
 	"upspin.io/config"
 	"upspin.io/factotum"
 	"upspin.io/flags"
+	"upspin.io/key/sha256key"
 	"upspin.io/log"
 	"upspin.io/pack"
+	"upspin.io/path"
 	"upspin.io/test/testutil"
 	"upspin.io/upspin"
 
@@ -134,6 +136,143 @@ func TestPutGetTopLevelFile(t *testing.T) {
 	}
 }
 
+func TestGetReader(t *testing.T) {
+	const (
+		user     = "user1@google.com"
+		fileName = user + "/getreader"
+	)
+	oldBlockSize := flags.BlockSize
+	flags.BlockSize = 1023
+	defer func() {
+		flags.BlockSize = oldBlockSize
+	}()
+	cfg := setup(baseCfg, user)
+	client := New(cfg).(upspin.ReaderGetter)
+	data := make([]byte, 3*flags.BlockSize+100)
+	rand.Read(data)
+	entry, err := New(cfg).Put(fileName, data)
+	if err != nil {
+		t.Fatal("put file:", err)
+	}
+	if len(entry.Blocks) != 4 {
+		t.Fatalf("file has %d blocks, want 4", len(entry.Blocks))
+	}
+
+	r, err := client.GetReader(fileName)
+	if err != nil {
+		t.Fatal("get reader:", err)
+	}
+	got, err := io.ReadAll(iotest.OneByteReader(r))
+	if err != nil {
+		t.Fatal("read:", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal("close:", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes that differ from the %d written", len(got), len(data))
+	}
+
+	// Remove the third block; reading should deliver the first
+	// two and then fail.
+	store, err := bind.StoreServer(cfg, entry.Blocks[2].Location.Endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(entry.Blocks[2].Location.Reference); err != nil {
+		t.Fatal(err)
+	}
+	r, err = client.GetReader(fileName)
+	if err != nil {
+		t.Fatal("get reader:", err)
+	}
+	defer r.Close()
+	got, err = io.ReadAll(r)
+	if !errors.Is(errors.NotExist, err) {
+		t.Fatalf("read of file with missing block: err = %v, want NotExist", err)
+	}
+	if want := data[:entry.Blocks[2].Offset]; !bytes.Equal(got, want) {
+		t.Fatalf("read %d bytes before the missing block, want %d", len(got), len(want))
+	}
+}
+
+func TestPutReader(t *testing.T) {
+	const (
+		user     = "user1@google.com"
+		fileName = user + "/putreader"
+	)
+	oldBlockSize := flags.BlockSize
+	flags.BlockSize = 1023
+	defer func() {
+		flags.BlockSize = oldBlockSize
+	}()
+	client := New(setup(baseCfg, user))
+	data := make([]byte, 2*flags.BlockSize+100)
+	rand.Read(data)
+	entry, err := client.(upspin.ReaderPutter).PutReader(fileName, iotest.HalfReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal("put reader:", err)
+	}
+	if len(entry.Blocks) != 3 {
+		t.Fatalf("file has %d blocks, want 3", len(entry.Blocks))
+	}
+	got, err := client.Get(fileName)
+	if err != nil {
+		t.Fatal("get file:", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes that differ from the %d written", len(got), len(data))
+	}
+}
+
+func TestPutReaderError(t *testing.T) {
+	const (
+		user     = "user1@google.com"
+		fileName = user + "/putreadererror"
+	)
+	oldBlockSize := flags.BlockSize
+	flags.BlockSize = 1023
+	defer func() {
+		flags.BlockSize = oldBlockSize
+	}()
+	// Use plain packing so we know the references of the blocks.
+	cfg := config.SetPacking(setup(baseCfg, user), upspin.PlainPack)
+	client := New(cfg)
+	data := make([]byte, 2*flags.BlockSize)
+	rand.Read(data)
+	// Another file shares the first block.
+	other := data[:flags.BlockSize]
+	if _, err := client.Put(user+"/putreadererror-other", other); err != nil {
+		t.Fatal(err)
+	}
+	readErr := errors.Str("mid-stream failure")
+	r := io.MultiReader(bytes.NewReader(data), iotest.ErrReader(readErr))
+	_, err := client.(upspin.ReaderPutter).PutReader(fileName, r)
+	if !errors.Is(errors.IO, err) {
+		t.Fatalf("put reader: err = %v, want IO error", err)
+	}
+	if _, err := client.Lookup(fileName, false); !errors.Is(errors.NotExist, err) {
+		t.Fatalf("lookup after failed put: err = %v, want NotExist", err)
+	}
+	// The new block stored before the failure should have been deleted,
+	// but not the one shared with the other file.
+	store, err := bind.StoreServer(cfg, cfg.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := upspin.Reference(sha256key.Of(data[flags.BlockSize:]).String())
+	if _, _, _, err := store.Get(ref); !errors.Is(errors.NotExist, err) {
+		t.Errorf("new block: store.Get: err = %v, want NotExist", err)
+	}
+	got, err := client.Get(user + "/putreadererror-other")
+	if err != nil {
+		t.Fatalf("get file sharing a block: %v", err)
+	}
+	if !bytes.Equal(got, other) {
+		t.Errorf("file sharing a block was corrupted")
+	}
+}
+
 func TestPutSequencedGetTopLevelFile(t *testing.T) {
 	const (
 		user = "user1@google.com"
@@ -738,6 +877,74 @@ func testPutDuplicateDifferentUser(t *testing.T, packing upspin.Packing) {
 	}
 }
 
+func TestCopy(t *testing.T) {
+	t.Run(fmt.Sprintf("packing=ee"), func(t *testing.T) {
+		testCopy(t, upspin.EEPack)
+	})
+	t.Run(fmt.Sprintf("packing=eeintegrity"), func(t *testing.T) {
+		testCopy(t, upspin.EEIntegrityPack)
+	})
+}
+
+func testCopy(t *testing.T, packing upspin.Packing) {
+	user := upspin.UserName(fmt.Sprintf("copy-%s@example.com", packing))
+	client := New(setup(config.SetPacking(baseCfg, packing), user)).(*Client)
+	root := upspin.PathName(user)
+	for name, data := range map[upspin.PathName]string{
+		root + "/Access":         "*: " + string(user) + "\nread: ann@example.com",
+		root + "/private/Access": "*: " + string(user),
+	} {
+		if _, err := client.MakeDirectory(path.DropPath(name, 1)); err != nil && !errors.Is(errors.Exist, err) {
+			t.Fatal(err)
+		}
+		if _, err := client.Put(name, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const text = "the rain in spain"
+	original := root + "/original"
+	origEntry, err := client.Put(original, []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check copies original to name and reports whether the
+	// references were duplicated.
+	check := func(name upspin.PathName) bool {
+		t.Helper()
+		entry, err := client.Copy(original, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name != name {
+			t.Errorf("entry.Name = %q, want %q", entry.Name, name)
+		}
+		got, err := client.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != text {
+			t.Errorf("contents of %q are %q, want %q", name, got, text)
+		}
+		return entry.Blocks[0].Location == origEntry.Blocks[0].Location
+	}
+
+	if !check(root + "/dup") {
+		t.Errorf("copy within directory did not duplicate references")
+	}
+	// The readers of private differ, which matters only if the data is encrypted.
+	if dup, want := check(root+"/private/dup"), packing != upspin.EEPack; dup != want {
+		t.Errorf("copy to directory with other readers duplicated references: %t, want %t", dup, want)
+	}
+
+	if _, err := client.Copy(original, root+"/dup"); !errors.Is(errors.Exist, err) {
+		t.Errorf("Copy to existing file: err = %v, want Exist", err)
+	}
+	if _, err := client.Copy(root+"/private", root+"/dir"); !errors.Is(errors.IsDir, err) {
+		t.Errorf("Copy of directory: err = %v, want IsDir", err)
+	}
+}
+
 func TestRenames(t *testing.T) {
 	testRenames(t, upspin.EEPack)
 	testRenames(t, upspin.EEIntegrityPack)
@@ -7,7 +7,9 @@
 package client // import "upspin.io/client"
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"strings"
 
 	"upspin.io/access"
@@ -16,6 +18,7 @@ import (
 	"upspin.io/client/file"
 	"upspin.io/errors"
 	"upspin.io/flags"
+	"upspin.io/key/sha256key"
 	"upspin.io/metric"
 	"upspin.io/pack"
 	"upspin.io/path"
@@ -23,6 +26,7 @@ import (
 
 	_ "upspin.io/pack/eeintegrity"
 	_ "upspin.io/pack/plain"
+	_ "upspin.io/pack/plainsum"
 )
 
 // Client implements upspin.Client.
@@ -105,7 +109,19 @@ func (c *Client) Put(name upspin.PathName, data []byte) (*upspin.DirEntry, error
 
 // PutSequenced implements upspin.Client.
 func (c *Client) PutSequenced(name upspin.PathName, seq int64, data []byte) (*upspin.DirEntry, error) {
-	const op errors.Op = "client.Put"
+	return c.put("client.Put", name, seq, data, nil)
+}
+
+var _ upspin.ReaderPutter = (*Client)(nil)
+
+// PutReader implements upspin.ReaderPutter.
+func (c *Client) PutReader(name upspin.PathName, r io.Reader) (*upspin.DirEntry, error) {
+	return c.put("client.PutReader", name, upspin.SeqIgnore, nil, r)
+}
+
+// put implements PutSequenced and PutReader. The contents of the file are
+// read from r if it is non-nil, and otherwise are data.
+func (c *Client) put(op errors.Op, name upspin.PathName, seq int64, data []byte, r io.Reader) (*upspin.DirEntry, error) {
 	m, s := newMetric(op)
 	defer m.Done()
 
@@ -132,6 +148,19 @@ func (c *Client) PutSequenced(name upspin.PathName, seq int64, data []byte) (*up
 		return nil, errors.E(op, name, errors.Errorf("unrecognized Packing %d", c.config.Packing()))
 	}
 
+	// Access and Group files are small and must be validated
+	// before they are stored, so read them in full.
+	if r != nil && access.IsAccessControlFile(name) {
+		data, err = io.ReadAll(r)
+		if err != nil {
+			return nil, errors.E(op, name, errors.IO, err)
+		}
+		r = nil
+	}
+	if r == nil {
+		r = bytes.NewReader(data)
+	}
+
 	// Ensure Access file is valid.
 	if access.IsAccessFile(name) {
 		_, err := access.Parse(name, data)
@@ -159,7 +188,7 @@ func (c *Client) PutSequenced(name upspin.PathName, seq int64, data []byte) (*up
 	}
 
 	ss := s.StartSpan("pack")
-	if err := c.pack(entry, data, packer, ss); err != nil {
+	if err := c.pack(entry, r, packer, ss); err != nil {
 		return nil, errors.E(op, err)
 	}
 	ss.End()
@@ -241,7 +270,25 @@ func (c *Client) access(path upspin.PathName, dir upspin.DirServer) (*access.Acc
 	return access.Parse(whichAccess.Name, accessData)
 }
 
-func (c *Client) pack(entry *upspin.DirEntry, data []byte, packer upspin.Packer, s *metric.Span) error {
+// newBlockRef returns the reference under which store will hold the
+// block and reports whether store is known not to hold it already.
+// If store cannot say, newBlockRef reports false.
+func newBlockRef(store upspin.StoreServer, block []byte) (upspin.Reference, bool) {
+	ref := upspin.Reference(sha256key.Of(block).String())
+	exister, ok := store.(upspin.StoreExister)
+	if !ok {
+		return ref, false
+	}
+	exists, err := exister.Exists(ref)
+	return ref, err == nil && !exists
+}
+
+// pack packs the data read from r into blocks and stores them, recording
+// their locations in the entry. Each block is stored before the next is
+// read. If packing fails, pack tries to delete the blocks it stored that
+// the store server reported were not already present. Blocks are named
+// by their contents, so one that was present may back another file.
+func (c *Client) pack(entry *upspin.DirEntry, r io.Reader, packer upspin.Packer, s *metric.Span) (err error) {
 	// Verify the blocks aren't too big. This can't happen unless someone's modified
 	// flags.BlockSize underfoot, but protect anyway.
 	if flags.BlockSize > upspin.MaxBlockSize {
@@ -256,24 +303,41 @@ func (c *Client) pack(entry *upspin.DirEntry, data []byte, packer upspin.Packer,
 	if err != nil {
 		return err
 	}
-	for len(data) > 0 {
-		n := len(data)
-		if n > flags.BlockSize {
-			n = flags.BlockSize
+	var stored []upspin.Reference
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Best effort: the store server may not permit deletion.
+		for _, ref := range stored {
+			store.Delete(ref)
+		}
+	}()
+	buf := make([]byte, flags.BlockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return errors.E(errors.IO, err)
 		}
 		ss := s.StartSpan("bp.pack")
-		cipher, err := bp.Pack(data[:n])
+		cipher, err := bp.Pack(buf[:n])
 		ss.End()
 		if err != nil {
 			return err
 		}
-		data = data[n:]
+		ref, isNew := newBlockRef(store, cipher)
 		ss = s.StartSpan("store.Put")
 		refdata, err := store.Put(cipher)
 		ss.End()
 		if err != nil {
 			return err
 		}
+		if isNew && refdata.Reference == ref {
+			stored = append(stored, ref)
+		}
 		bp.SetLocation(
 			upspin.Location{
 				Endpoint:  c.config.StoreEndpoint(),
@@ -476,6 +540,32 @@ func (c *Client) Get(name upspin.PathName) ([]byte, error) {
 	return data, nil
 }
 
+var _ upspin.ReaderGetter = (*Client)(nil)
+
+// GetReader implements upspin.ReaderGetter.
+func (c *Client) GetReader(name upspin.PathName) (io.ReadCloser, error) {
+	const op errors.Op = "client.GetReader"
+	m, s := newMetric(op)
+	defer m.Done()
+
+	entry, _, err := c.lookup(op, &upspin.DirEntry{Name: name}, lookupLookupFn, followFinalLink, s)
+	if err != nil {
+		return nil, errors.E(op, name, err)
+	}
+
+	if entry.IsDir() {
+		return nil, errors.E(op, name, errors.IsDir)
+	}
+	if err = c.validSigner(entry); err != nil {
+		return nil, errors.E(op, name, err)
+	}
+	r, err := clientutil.NewReader(c.config, entry)
+	if err != nil {
+		return nil, errors.E(op, name, err)
+	}
+	return r, nil
+}
+
 func lookupLookupFn(dir upspin.DirServer, entry *upspin.DirEntry, s *metric.Span) (*upspin.DirEntry, error) {
 	defer s.StartSpan("dir.Lookup").End()
 	return dir.Lookup(entry.Name)
@@ -733,6 +823,131 @@ func (c *Client) PutDuplicate(oldName, newName upspin.PathName) (*upspin.DirEntr
 	return c.dupOrRename(op, oldName, newName, false, s)
 }
 
+var _ upspin.Copier = (*Client)(nil)
+
+// Copy implements upspin.Copier.
+func (c *Client) Copy(src, dst upspin.PathName) (*upspin.DirEntry, error) {
+	const op errors.Op = "client.Copy"
+	m, s := newMetric(op)
+	defer m.Done()
+
+	entry, _, err := c.lookup(op, &upspin.DirEntry{Name: src}, lookupLookupFn, followFinalLink, s)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if entry.IsDir() {
+		return nil, errors.E(op, src, errors.IsDir, "cannot copy directories")
+	}
+	dup, err := c.canDuplicate(op, entry, dst, s)
+	if err != nil {
+		return nil, err
+	}
+	if dup {
+		return c.dupOrRename(op, entry.Name, dst, false, s)
+	}
+
+	// Copy the data, packing it as if it were new.
+	if err = c.validSigner(entry); err != nil {
+		return nil, errors.E(op, src, err)
+	}
+	r, err := clientutil.NewReader(c.config, entry)
+	if err != nil {
+		return nil, errors.E(op, src, err)
+	}
+	defer r.Close()
+	return c.put(op, dst, upspin.SeqNotExist, nil, r)
+}
+
+// canDuplicate reports whether Copy may copy entry to dst by duplicating
+// its references. The two names must be served by the same DirServer and
+// the blocks must be held by our StoreServer, so the copy does not depend
+// on another user's store. For EE packing, the readers of dst must also be
+// those of entry, since the wrapped keys are copied along with the blocks.
+func (c *Client) canDuplicate(op errors.Op, entry *upspin.DirEntry, dst upspin.PathName, s *metric.Span) (bool, error) {
+	if access.IsAccessControlFile(dst) {
+		// These must be validated as they are written.
+		return false, nil
+	}
+	// Evaluate any links in dst to find where it will be.
+	dstAccess, dstEntry, err := c.lookup(op, &upspin.DirEntry{Name: dst}, whichAccessLookupFn, followFinalLink, s)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	dst = dstEntry.Name
+
+	srcDir, err := c.DirServer(entry.Name)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	dstDir, err := c.DirServer(dst)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	if srcDir.Endpoint() != dstDir.Endpoint() {
+		return false, nil
+	}
+	for _, b := range entry.Blocks {
+		if b.Location.Endpoint != c.config.StoreEndpoint() {
+			return false, nil
+		}
+	}
+	if entry.Packing != upspin.EEPack {
+		return true, nil
+	}
+
+	srcParsed, err := path.Parse(entry.Name)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	dstParsed, err := path.Parse(dst)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	if srcParsed.Drop(1).Equal(dstParsed.Drop(1)) {
+		// Same directory, same Access file.
+		return true, nil
+	}
+	srcAccess, _, err := c.lookup(op, entry, whichAccessLookupFn, doNotFollowFinalLink, s)
+	if err != nil {
+		return false, errors.E(op, entry.Name, err)
+	}
+	srcReaders, err := c.readerSet(op, entry.Name, srcAccess)
+	if err != nil {
+		return false, errors.E(op, entry.Name, err)
+	}
+	dstReaders, err := c.readerSet(op, dst, dstAccess)
+	if err != nil {
+		return false, errors.E(op, dst, err)
+	}
+	if len(srcReaders) != len(dstReaders) {
+		return false, nil
+	}
+	for r := range srcReaders {
+		if !dstReaders[r] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readerSet returns the users who may read name according to the
+// given Access file, always including the owner of name.
+func (c *Client) readerSet(op errors.Op, name upspin.PathName, accessEntry *upspin.DirEntry) (map[upspin.UserName]bool, error) {
+	readers, err := c.getReaders(op, name, accessEntry)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := path.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	set := map[upspin.UserName]bool{parsed.User(): true}
+	for _, r := range readers {
+		set[r] = true
+	}
+	return set, nil
+}
+
 // Rename implements upspin.Client.
 func (c *Client) Rename(oldName, newName upspin.PathName) (*upspin.DirEntry, error) {
 	const op errors.Op = "client.Rename"
@@ -5,8 +5,11 @@
 package clientutil
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"math/big"
 	"strconv"
 	"testing"
@@ -15,6 +18,7 @@ import (
 	"upspin.io/config"
 	"upspin.io/errors"
 	"upspin.io/factotum"
+	"upspin.io/pack"
 	"upspin.io/pack/packutil"
 	"upspin.io/test/testfixtures"
 	"upspin.io/test/testutil"
@@ -98,6 +102,67 @@ func TestReadAll(t *testing.T) {
 	}
 }
 
+func TestGetBatch(t *testing.T) {
+	cfg := setupTestConfig(t)
+	// TestReadAll registers its store for InProcess.
+	store := &batchStore{data: make(map[upspin.Reference][]byte)}
+	if err := bind.RegisterStoreServer(upspin.Remote, store); err != nil {
+		t.Fatal(err)
+	}
+	ep := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "batch"}
+
+	// Make a file with more blocks than fit in one batch.
+	entry := &upspin.DirEntry{
+		Name:       userName + "/batchfile",
+		SignedName: userName + "/batchfile",
+		Packing:    upspin.PlainPack,
+		Time:       12345,
+		Writer:     userName,
+	}
+	bp, err := pack.Lookup(upspin.PlainPack).Pack(cfg, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for i := 0; i < upspin.MaxGetBatchRefs+6; i++ {
+		cipher, err := bp.Pack([]byte(fmt.Sprintf("block %d;", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := upspin.Reference(fmt.Sprint("batchref", i))
+		store.data[ref] = cipher
+		bp.SetLocation(upspin.Location{Endpoint: ep, Reference: ref})
+		want = append(want, cipher...)
+	}
+	if err := bp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A block the batch fails to get is got on its own.
+	store.failBatch = entry.Blocks[3].Location.Reference
+
+	check := func(got []byte, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if store.batches != 2 || store.gets != 1 {
+			t.Errorf("got %d batches and %d gets, want 2 and 1", store.batches, store.gets)
+		}
+		store.batches, store.gets = 0, 0
+	}
+	check(ReadAll(cfg, entry))
+	r, err := NewReader(cfg, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	check(got, err)
+}
+
 func pdMarshal(dst *[]byte, sig, sig2 upspin.Signature) error {
 	// sig2 is a signature with another owner key, to enable smoother key rotation.
 	n := packdataLen()
@@ -184,3 +249,44 @@ func (s *mockStore) Get(ref upspin.Reference) ([]byte, *upspin.Refdata, []upspin
 func (s *mockStore) Dial(upspin.Config, upspin.Endpoint) (upspin.Service, error) {
 	return s, nil
 }
+
+// batchStore is a StoreServer that implements upspin.StoreGetBatcher
+// and counts the requests it serves.
+type batchStore struct {
+	testfixtures.DummyStoreServer
+	data      map[upspin.Reference][]byte
+	failBatch upspin.Reference // GetBatch fails for this reference.
+	gets      int
+	batches   int
+}
+
+func (s *batchStore) Get(ref upspin.Reference) ([]byte, *upspin.Refdata, []upspin.Location, error) {
+	s.gets++
+	data, ok := s.data[ref]
+	if !ok {
+		return nil, nil, nil, errors.E(errors.NotExist)
+	}
+	return data, &upspin.Refdata{Reference: ref}, nil, nil
+}
+
+func (s *batchStore) GetBatch(refs []upspin.Reference) []upspin.StoreGetResult {
+	s.batches++
+	results := make([]upspin.StoreGetResult, len(refs))
+	for i, ref := range refs {
+		if ref == s.failBatch {
+			results[i].Error = errors.E(errors.IO)
+			continue
+		}
+		data, ok := s.data[ref]
+		if !ok {
+			results[i].Error = errors.E(errors.NotExist)
+			continue
+		}
+		results[i].Data, results[i].Refdata = data, &upspin.Refdata{Reference: ref}
+	}
+	return results
+}
+
+func (s *batchStore) Dial(upspin.Config, upspin.Endpoint) (upspin.Service, error) {
+	return s, nil
+}
@@ -7,6 +7,8 @@
 package clientutil // import "upspin.io/client/clientutil"
 
 import (
+	"io"
+
 	"upspin.io/access"
 	"upspin.io/bind"
 	"upspin.io/errors"
@@ -19,6 +21,101 @@ import (
 // the necessary keys loaded in the config to unpack the cipher if the entry
 // is encrypted.
 func ReadAll(cfg upspin.Config, entry *upspin.DirEntry) ([]byte, error) {
+	bu, err := unpack(cfg, entry)
+	if err != nil {
+		return nil, err
+	}
+	defer bu.Close()
+	f := newFetcher(cfg, entry)
+	var data []byte
+	for {
+		block, ok := bu.NextBlock()
+		if !ok {
+			break // EOF
+		}
+		// block is known valid as per valid.DirEntry above.
+
+		cipher, err := f.read(block.Location)
+		if err != nil {
+			return nil, errors.E(err)
+		}
+		clear, err := bu.Unpack(cipher)
+		if err != nil {
+			return nil, errors.E(entry.Name, err)
+		}
+		data = append(data, clear...) // TODO: Could avoid a copy if only one block.
+	}
+	return data, nil
+}
+
+// NewReader returns a ReadCloser that reads the contents of a DirEntry.
+// Unlike ReadAll, it fetches and unpacks each block only when the reader
+// reaches it, so the whole file is never held in memory. As with ReadAll,
+// the config must hold the keys needed to unpack the entry. The caller
+// must call Close when done.
+func NewReader(cfg upspin.Config, entry *upspin.DirEntry) (io.ReadCloser, error) {
+	bu, err := unpack(cfg, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{f: newFetcher(cfg, entry), name: entry.Name, bu: bu}, nil
+}
+
+// reader is the ReadCloser returned by NewReader.
+type reader struct {
+	f    *fetcher
+	name upspin.PathName
+	bu   upspin.BlockUnpacker
+	buf  []byte // Unread clear text of the current block.
+	err  error  // Sticky error, io.EOF after the last block.
+}
+
+// Read implements io.Reader.
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.buf, r.err = r.nextBlock()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// nextBlock fetches and unpacks the next block, returning io.EOF after
+// the last one.
+func (r *reader) nextBlock() ([]byte, error) {
+	block, ok := r.bu.NextBlock()
+	if !ok {
+		return nil, io.EOF
+	}
+	cipher, err := r.f.read(block.Location)
+	if err != nil {
+		return nil, errors.E(r.name, err)
+	}
+	clear, err := r.bu.Unpack(cipher)
+	if err != nil {
+		return nil, errors.E(r.name, err)
+	}
+	return clear, nil
+}
+
+// Close implements io.Closer.
+func (r *reader) Close() error {
+	if r.err == errClosed {
+		return errors.E(r.name, errClosed)
+	}
+	r.buf = nil
+	r.f = nil
+	r.err = errClosed
+	return r.bu.Close()
+}
+
+var errClosed = errors.Str("reader is closed")
+
+// unpack checks that the entry may be read and returns a BlockUnpacker for it.
+func unpack(cfg upspin.Config, entry *upspin.DirEntry) (upspin.BlockUnpacker, error) {
 	if entry.IsLink() {
 		return nil, errors.E(entry.Name, errors.Invalid, "can't read a link entry")
 	}
@@ -33,7 +130,6 @@ func ReadAll(cfg upspin.Config, entry *upspin.DirEntry) ([]byte, error) {
 		}
 	}
 
-	var data []byte
 	packer := pack.Lookup(entry.Packing)
 	if packer == nil {
 		return nil, errors.E(entry.Name, errors.Errorf("unrecognized Packing %d", entry.Packing))
@@ -42,24 +138,79 @@ func ReadAll(cfg upspin.Config, entry *upspin.DirEntry) ([]byte, error) {
 	if err != nil {
 		return nil, errors.E(entry.Name, err) // Showstopper.
 	}
-	for {
-		block, ok := bu.NextBlock()
-		if !ok {
-			break // EOF
-		}
-		// block is known valid as per valid.DirEntry above.
+	return bu, nil
+}
 
-		cipher, err := ReadLocation(cfg, block.Location)
-		if err != nil {
-			return nil, errors.E(err)
+// A fetcher reads the blocks of a file in order. If the StoreServer
+// holding a block is an upspin.StoreGetBatcher, the fetcher gets the
+// block and those that follow it on the same server in one request,
+// keeping the data until it is read.
+type fetcher struct {
+	cfg     upspin.Config
+	blocks  []upspin.DirBlock // The blocks not yet fetched.
+	fetched map[upspin.Location][]byte
+}
+
+func newFetcher(cfg upspin.Config, entry *upspin.DirEntry) *fetcher {
+	return &fetcher{
+		cfg:     cfg,
+		blocks:  entry.Blocks,
+		fetched: make(map[upspin.Location][]byte),
+	}
+}
+
+// read returns the data held at the location of one of the blocks.
+func (f *fetcher) read(loc upspin.Location) ([]byte, error) {
+	if _, ok := f.fetched[loc]; !ok {
+		f.prefetch(loc)
+	}
+	if data, ok := f.fetched[loc]; ok {
+		delete(f.fetched, loc)
+		return data, nil
+	}
+	return ReadLocation(f.cfg, loc)
+}
+
+// prefetch gets the data for loc, and for the blocks after it on the same
+// StoreServer, if that server can get them in one request. Failures are
+// ignored, leaving read to try those blocks one at a time.
+func (f *fetcher) prefetch(loc upspin.Location) {
+	i := 0
+	for i < len(f.blocks) && f.blocks[i].Location != loc {
+		i++
+	}
+	if len(f.blocks)-i < 2 {
+		// Nothing to gain.
+		return
+	}
+	f.blocks = f.blocks[i:]
+	store, err := bind.StoreServer(f.cfg, loc.Endpoint)
+	if err != nil {
+		return
+	}
+	b, ok := store.(upspin.StoreGetBatcher)
+	if !ok {
+		return
+	}
+	var refs []upspin.Reference
+	for _, block := range f.blocks {
+		if len(refs) == upspin.MaxGetBatchRefs || block.Location.Endpoint != loc.Endpoint {
+			break
 		}
-		clear, err := bu.Unpack(cipher)
-		if err != nil {
-			return nil, errors.E(entry.Name, err)
+		refs = append(refs, block.Location.Reference)
+	}
+	results := b.GetBatch(refs)
+	if len(results) > len(refs) {
+		return
+	}
+	for i, r := range results {
+		if r.Error != nil || r.Locations != nil {
+			// Let ReadLocation report the error or follow the redirection.
+			continue
 		}
-		data = append(data, clear...) // TODO: Could avoid a copy if only one block.
+		f.fetched[upspin.Location{Endpoint: loc.Endpoint, Reference: refs[i]}] = r.Data
 	}
-	return data, nil
+	f.blocks = f.blocks[len(results):]
 }
 
 // ReadLocation uses the provided Config to fetch the contents of the given
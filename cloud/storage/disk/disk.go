@@ -8,6 +8,8 @@ package disk // import "upspin.io/cloud/storage/disk"
 import (
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"upspin.io/cloud/storage"
@@ -17,8 +19,11 @@ import (
 )
 
 // New initializes and returns a disk-backed storage.Storage with the given
-// options. The single, required option is "basePath" that must be an absolute
-// path under which all objects should be stored.
+// options. The required option "basePath" must be an absolute path under
+// which all objects should be stored. The option "fsync", if true, makes
+// Put flush each object and the directories leading to it to stable
+// storage before returning; it is off by default as it makes Put much
+// slower.
 func New(opts *storage.Opts) (storage.Storage, error) {
 	const op errors.Op = "cloud/storage/disk.New"
 
@@ -26,6 +31,14 @@ func New(opts *storage.Opts) (storage.Storage, error) {
 	if !ok {
 		return nil, errors.E(op, "the basePath option must be specified")
 	}
+	var fsync bool
+	if v, ok := opts.Opts["fsync"]; ok {
+		var err error
+		fsync, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.E(op, errors.Invalid, errors.Errorf("bad fsync option %q", v))
+		}
+	}
 	if err := os.MkdirAll(base, 0700); err != nil {
 		return nil, errors.E(op, errors.IO, err)
 	}
@@ -34,7 +47,7 @@ func New(opts *storage.Opts) (storage.Storage, error) {
 		return nil, errors.E(op, errors.IO, err)
 	}
 
-	return &storageImpl{base: base}, nil
+	return &storageImpl{base: base, fsync: fsync}, nil
 }
 
 // guaranteeNewEncoding makes sure we are using the new, safe path encoding.
@@ -91,12 +104,19 @@ func init() {
 }
 
 type storageImpl struct {
-	base string
+	base  string
+	fsync bool // Flush objects to stable storage in Put.
 }
 
+// tmpPrefix begins the names of the temporary files written by Put.
+// The names of stored objects are base64-encoded so never begin with it.
+const tmpPrefix = "."
+
 var (
-	_ storage.Storage = (*storageImpl)(nil)
-	_ storage.Lister  = (*storageImpl)(nil)
+	_ storage.Storage      = (*storageImpl)(nil)
+	_ storage.Lister       = (*storageImpl)(nil)
+	_ storage.Exister      = (*storageImpl)(nil)
+	_ storage.PrefixLister = (*storageImpl)(nil)
 )
 
 // LinkBase implements storage.Storage.
@@ -117,18 +137,97 @@ func (s *storageImpl) Download(ref string) ([]byte, error) {
 }
 
 // Put implements storage.Storage.
+// The contents are written to a temporary file that is then renamed into
+// place, so an object is never seen partially written, even after a crash.
 func (s *storageImpl) Put(ref string, contents []byte) error {
 	const op errors.Op = "cloud/storage/disk.Put"
 	p := s.path(ref)
-	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+	if err := s.mkdirAll(filepath.Dir(p)); err != nil {
 		return errors.E(op, errors.IO, err)
 	}
-	if err := os.WriteFile(p, contents, 0600); err != nil {
+	if err := s.writeFile(p, contents); err != nil {
 		return errors.E(op, errors.IO, err)
 	}
 	return nil
 }
 
+// writeFile atomically replaces the named file with one holding contents.
+func (s *storageImpl) writeFile(name string, contents []byte) (err error) {
+	dir, file := filepath.Split(name)
+	f, err := os.CreateTemp(dir, tmpPrefix+file+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(contents); err != nil {
+		return err
+	}
+	if s.fsync {
+		if err = f.Sync(); err != nil {
+			return err
+		}
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	if s.fsync {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// mkdirAll is like os.MkdirAll but, if s.fsync is set, it also flushes
+// the directories holding those it creates, so they survive a crash.
+func (s *storageImpl) mkdirAll(dir string) error {
+	if !s.fsync {
+		return os.MkdirAll(dir, 0700)
+	}
+	// Find the nearest directory that exists already.
+	exists := dir
+	for exists != s.base {
+		if _, err := os.Stat(exists); err == nil {
+			break
+		}
+		exists = filepath.Dir(exists)
+	}
+	if exists == dir {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// The new directory itself is flushed once the file is in it.
+	for d := filepath.Dir(dir); ; d = filepath.Dir(d) {
+		if err := syncDir(d); err != nil {
+			return err
+		}
+		if d == exists {
+			return nil
+		}
+	}
+}
+
+// syncDir flushes the directory's entries to stable storage.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 // Delete implements storage.Storage.
 func (s *storageImpl) Delete(ref string) error {
 	const op errors.Op = "cloud/storage/disk.Delete"
@@ -140,6 +239,17 @@ func (s *storageImpl) Delete(ref string) error {
 	return nil
 }
 
+// Exists implements storage.Exister.
+func (s *storageImpl) Exists(ref string) (bool, error) {
+	const op errors.Op = "cloud/storage/disk.Exists"
+	if _, err := os.Stat(s.path(ref)); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.E(op, errors.IO, err)
+	}
+	return true, nil
+}
+
 var maxRefsPerCall = 1000 // A variable so that it may be overridden by tests.
 
 // List implements storage.Lister.
@@ -158,6 +268,11 @@ func (s *storageImpl) List(token string) (refs []upspin.ListRefsItem, next strin
 			return nil
 		}
 
+		// Ignore temporary files written by Put.
+		if strings.HasPrefix(fi.Name(), tmpPrefix) {
+			return nil
+		}
+
 		// Stop walking when we've gathered enough refs.
 		if len(refs) >= maxRefsPerCall {
 			if next == "" {
@@ -198,6 +313,77 @@ func (s *storageImpl) List(token string) (refs []upspin.ListRefsItem, next strin
 	return refs, next, nil
 }
 
+// ListPrefix implements storage.PrefixLister.
+func (s *storageImpl) ListPrefix(prefix string, depth int) ([]string, error) {
+	const op errors.Op = "cloud/storage/disk.ListPrefix"
+	refs, err := s.listPrefix(prefix, depth)
+	if err != nil {
+		return nil, errors.E(op, errors.Str(prefix), err)
+	}
+	return refs, nil
+}
+
+// ListDir implements storage.PrefixLister.
+func (s *storageImpl) ListDir(dir string) ([]string, error) {
+	const op errors.Op = "cloud/storage/disk.ListDir"
+	refs, err := s.listPrefix(dir, 0)
+	if err != nil {
+		return nil, errors.E(op, errors.Str(dir), err)
+	}
+	return refs, nil
+}
+
+// listPrefix returns, sorted, the references that begin with prefix and
+// contain at most depth '/' characters after it.
+func (s *storageImpl) listPrefix(prefix string, depth int) ([]string, error) {
+	// The files for the references are stored under directories named
+	// for the leading bytes of their encoding, so only the directories
+	// that agree with the encoding of the prefix need be walked.
+	encPrefix := local.EncodedPrefix(prefix)
+	var refs []string
+	err := filepath.Walk(s.base, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Convert path into its base path.
+		path = strings.TrimPrefix(strings.TrimPrefix(path, s.base), string(filepath.Separator))
+
+		// Ignore the root.
+		if path == "" {
+			return nil
+		}
+
+		// Ignore temporary files written by Put.
+		if strings.HasPrefix(fi.Name(), tmpPrefix) {
+			return nil
+		}
+
+		if fi.IsDir() {
+			// Don't descend into irrelevant directories.
+			enc := strings.Replace(strings.Replace(path, string(filepath.Separator), "", -1), "+", "", -1)
+			if !strings.HasPrefix(enc, encPrefix) && !strings.HasPrefix(encPrefix, enc) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ref, err := local.Ref(path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(ref, prefix) && strings.Count(ref[len(prefix):], "/") <= depth {
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
+
 // path returns the absolute path that should contain ref.
 func (s *storageImpl) path(ref string) string {
 	return local.Path(s.base, ref)
@@ -8,9 +8,12 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"upspin.io/cloud/storage"
+	"upspin.io/errors"
 	"upspin.io/upspin"
 )
 
@@ -90,6 +93,165 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListPrefix(t *testing.T) {
+	base, err := os.MkdirTemp("", "upspin-storage-disk-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	store, err := New(&storage.Opts{Opts: map[string]string{"basePath": base}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, ok := store.(storage.PrefixLister)
+	if !ok {
+		t.Fatalf("%T does not implement storage.PrefixLister", store)
+	}
+
+	const (
+		file1 = "ann@example.com/file1"
+		file2 = "ann@example.com/dir1/file2"
+		file3 = "ann@example.com/dir1/sub/file3"
+		file4 = "ann@example.com/dir2/file4"
+		file5 = "bob@example.com/file5"
+		file6 = "ann@example.commune/file6"
+	)
+	for _, ref := range []string{file1, file2, file3, file4, file5, file6} {
+		if err := store.Put(ref, []byte(ref)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		prefix string
+		depth  int
+		want   []string
+	}{
+		{"ann@example.com/", 0, []string{file1}},
+		{"ann@example.com/", 1, []string{file2, file4, file1}},
+		{"ann@example.com/", 10, []string{file2, file3, file4, file1}},
+		{"ann@example.com/dir1/", 0, []string{file2}},
+		{"ann@example.com/dir", 1, []string{file2, file4}},
+		{"ann@example.com", 1, []string{file1, file6}},
+		{"ann@example.com", 2, []string{file2, file4, file1, file6}},
+		{"ann@example.com/nothing/", 10, nil},
+		{"", 0, nil},
+		{"", 1, []string{file1, file6, file5}},
+		{"", 10, []string{file2, file3, file4, file1, file6, file5}},
+	} {
+		got, err := ls.ListPrefix(test.prefix, test.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ListPrefix(%q, %d) = %q, want %q", test.prefix, test.depth, got, test.want)
+		}
+	}
+
+	got, err := ls.ListDir("ann@example.com/dir1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{file2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDir = %q, want %q", got, want)
+	}
+}
+
+func TestExists(t *testing.T) {
+	base, err := os.MkdirTemp("", "upspin-storage-disk-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	opts := &storage.Opts{Opts: map[string]string{"basePath": base}}
+	store, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ex, ok := store.(storage.Exister)
+	if !ok {
+		t.Fatalf("%T does not implement storage.Exister", store)
+	}
+
+	const ref = "some-ref"
+	check := func(want bool) {
+		t.Helper()
+		exists, err := ex.Exists(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != want {
+			t.Errorf("Exists(%q) = %v, want %v", ref, exists, want)
+		}
+	}
+	check(false)
+	if err := store.Put(ref, []byte("some file content")); err != nil {
+		t.Fatal(err)
+	}
+	check(true)
+	if err := store.Delete(ref); err != nil {
+		t.Fatal(err)
+	}
+	check(false)
+}
+
+func TestPutFsync(t *testing.T) {
+	base, err := os.MkdirTemp("", "upspin-storage-disk-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	opts := &storage.Opts{Opts: map[string]string{"basePath": base, "fsync": "bad"}}
+	if _, err := New(opts); !errors.Is(errors.Invalid, err) {
+		t.Fatalf("New with bad fsync option: err = %v, want Invalid", err)
+	}
+	opts.Opts["fsync"] = "true"
+	store, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ref = "some-ref"
+	for _, want := range []string{"first contents", "second"} {
+		if err := store.Put(ref, []byte(want)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Download(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Download(%q) = %q, want %q", ref, got, want)
+		}
+	}
+
+	// A temporary file left by an interrupted Put is not listed,
+	// and Put leaves none behind.
+	p := store.(*storageImpl).path(ref)
+	stray := filepath.Join(filepath.Dir(p), tmpPrefix+filepath.Base(p)+"-123")
+	if err := os.WriteFile(stray, []byte("sec"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	refs, _, err := store.(storage.Lister).List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 1 || refs[0].Ref != ref {
+		t.Errorf("List returned %v, want only %q", refs, ref)
+	}
+	files, err := os.ReadDir(filepath.Dir(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("directory holds %d files, want 2", len(files))
+	}
+}
+
 func randomBytes(n int) []byte {
 	b := make([]byte, n)
 	rand.Read(b)
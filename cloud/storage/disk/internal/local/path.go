@@ -89,6 +89,16 @@ func Ref(path string) (string, error) {
 	return string(ref), nil
 }
 
+// EncodedPrefix returns a prefix of the encoding, as used by Path, of every
+// reference that begins with prefix. The path of such a reference, with
+// its separators and padding removed, begins with the returned string.
+func EncodedPrefix(prefix string) string {
+	// Each 3 bytes of the reference encode as 4 bytes independently
+	// of the bytes that follow them.
+	n := len(prefix) - len(prefix)%3
+	return enc.EncodeToString([]byte(prefix[:n]))
+}
+
 // OldPath returns the file path to hold the contents of the blob with the
 // specified reference. The returned path is rooted in the provided base
 // directory.
@@ -60,3 +60,18 @@ func TestRef(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodedPrefix(t *testing.T) {
+	for _, test := range pathTests {
+		file := strings.Replace(strings.TrimPrefix(test.file, "B/"), "/", "", -1)
+		if i := strings.Index(file, "+"); i >= 0 {
+			file = file[:i]
+		}
+		for n := 0; n <= len(test.ref); n++ {
+			prefix := test.ref[:n]
+			if got := EncodedPrefix(prefix); !strings.HasPrefix(file, got) {
+				t.Errorf("EncodedPrefix(%q) = %q, not a prefix of %q, the encoding of %q", prefix, got, file, test.ref)
+			}
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package s3 provides a storage.Storage that stores data in Amazon S3
+// or any object store that speaks its protocol.
+package s3 // import "upspin.io/cloud/storage/s3"
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"upspin.io/cloud/storage"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// Keys used for storing dial options.
+const (
+	bucketName      = "s3BucketName"
+	region          = "s3Region"
+	endpoint        = "s3Endpoint"
+	accessKeyID     = "s3AccessKeyID"
+	secretAccessKey = "s3SecretAccessKey"
+	defaultACL      = "defaultACL"
+)
+
+// publicRead is the canned ACL that makes objects readable by anyone.
+const publicRead = "public-read"
+
+// requestTimeout bounds each request to the object store, so that a
+// server that stops responding cannot hang its caller.
+const requestTimeout = 1 * time.Minute
+
+// New initializes and returns an S3-backed storage.Storage with the given
+// options. The required option "s3BucketName" names the bucket in which
+// all objects are stored. The other options are:
+//
+//	s3Region: the region of the bucket, "us-east-1" by default.
+//	s3Endpoint: the base URL of the object store, by default that of
+//		Amazon S3 in the region. Set it to use another store that
+//		speaks the S3 protocol.
+//	s3AccessKeyID, s3SecretAccessKey: the credentials used to sign
+//		requests. If not set, they are taken from the environment
+//		variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, and
+//		AWS_SESSION_TOKEN, if set, supplies a session token.
+//	defaultACL: the canned ACL given to stored objects. If it is
+//		"public-read", LinkBase reports the URL of the bucket.
+//
+// Objects are addressed by path, as endpoint/bucket/ref.
+func New(opts *storage.Opts) (storage.Storage, error) {
+	const op errors.Op = "cloud/storage/s3.New"
+
+	bucket, ok := opts.Opts[bucketName]
+	if !ok || bucket == "" {
+		return nil, errors.E(op, errors.Invalid, errors.Errorf("%q option is required", bucketName))
+	}
+	s := &storageImpl{
+		client: &http.Client{Timeout: requestTimeout},
+		bucket: bucket,
+		region: opts.Opts[region],
+		acl:    opts.Opts[defaultACL],
+		keyID:  opts.Opts[accessKeyID],
+		secret: opts.Opts[secretAccessKey],
+		now:    time.Now,
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	base := opts.Opts[endpoint]
+	if base == "" {
+		base = "https://s3." + s.region + ".amazonaws.com"
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.E(op, errors.Invalid, errors.Errorf("bad %s option %q", endpoint, base))
+	}
+	s.endpoint = u
+	if s.keyID == "" && s.secret == "" {
+		s.keyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		s.secret = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		s.token = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if s.keyID == "" || s.secret == "" {
+		return nil, errors.E(op, errors.Invalid, errors.Str("no S3 credentials"))
+	}
+	return s, nil
+}
+
+func init() {
+	storage.Register("S3", New)
+}
+
+type storageImpl struct {
+	client   *http.Client
+	endpoint *url.URL // Base URL of the object store.
+	bucket   string
+	region   string
+	acl      string // Canned ACL for stored objects; may be empty.
+
+	// Credentials for signing requests. The token may be empty.
+	keyID  string
+	secret string
+	token  string
+
+	now func() time.Time // Replaced in tests.
+}
+
+var (
+	_ storage.Storage      = (*storageImpl)(nil)
+	_ storage.Lister       = (*storageImpl)(nil)
+	_ storage.PrefixLister = (*storageImpl)(nil)
+	_ storage.Exister      = (*storageImpl)(nil)
+)
+
+// LinkBase implements storage.Storage.
+func (s *storageImpl) LinkBase() (base string, err error) {
+	if s.acl != publicRead {
+		return "", upspin.ErrNotSupported
+	}
+	return s.objectURL("").String(), nil
+}
+
+// Download implements storage.Storage.
+func (s *storageImpl) Download(ref string) ([]byte, error) {
+	const op errors.Op = "cloud/storage/s3.Download"
+	resp, err := s.do("GET", s.objectURL(ref), nil, nil)
+	if err != nil {
+		return nil, errors.E(op, errors.Str(ref), err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.E(op, errors.IO, err)
+	}
+	return b, nil
+}
+
+// Put implements storage.Storage.
+func (s *storageImpl) Put(ref string, contents []byte) error {
+	const op errors.Op = "cloud/storage/s3.Put"
+	hdr := make(http.Header)
+	if s.acl != "" {
+		hdr.Set("X-Amz-Acl", s.acl)
+	}
+	resp, err := s.do("PUT", s.objectURL(ref), hdr, contents)
+	if err != nil {
+		return errors.E(op, errors.Str(ref), err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete implements storage.Storage.
+// As S3 does not report whether the object existed, neither does Delete.
+func (s *storageImpl) Delete(ref string) error {
+	const op errors.Op = "cloud/storage/s3.Delete"
+	resp, err := s.do("DELETE", s.objectURL(ref), nil, nil)
+	if err != nil {
+		return errors.E(op, errors.Str(ref), err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exists implements storage.Exister.
+func (s *storageImpl) Exists(ref string) (bool, error) {
+	const op errors.Op = "cloud/storage/s3.Exists"
+	resp, err := s.do("HEAD", s.objectURL(ref), nil, nil)
+	if errors.Is(errors.NotExist, err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.E(op, errors.Str(ref), err)
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+var maxRefsPerCall = 1000 // A variable so that it may be overridden by tests.
+
+// listResult is the part of the response to a ListObjectsV2 request
+// that List and ListPrefix use.
+type listResult struct {
+	Contents []struct {
+		Key  string
+		Size int64
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List implements storage.Lister.
+func (s *storageImpl) List(token string) (refs []upspin.ListRefsItem, next string, err error) {
+	const op errors.Op = "cloud/storage/s3.List"
+	result, err := s.list(url.Values{}, token)
+	if err != nil {
+		return nil, "", errors.E(op, err)
+	}
+	for _, c := range result.Contents {
+		refs = append(refs, upspin.ListRefsItem{
+			Ref:  upspin.Reference(c.Key),
+			Size: c.Size,
+		})
+	}
+	if result.IsTruncated {
+		next = result.NextContinuationToken
+	}
+	return refs, next, nil
+}
+
+// ListPrefix implements storage.PrefixLister.
+func (s *storageImpl) ListPrefix(prefix string, depth int) ([]string, error) {
+	const op errors.Op = "cloud/storage/s3.ListPrefix"
+	refs, err := s.listPrefix(prefix, depth)
+	if err != nil {
+		return nil, errors.E(op, errors.Str(prefix), err)
+	}
+	return refs, nil
+}
+
+// ListDir implements storage.PrefixLister.
+func (s *storageImpl) ListDir(dir string) ([]string, error) {
+	const op errors.Op = "cloud/storage/s3.ListDir"
+	refs, err := s.listPrefix(dir, 0)
+	if err != nil {
+		return nil, errors.E(op, errors.Str(dir), err)
+	}
+	return refs, nil
+}
+
+// listPrefix returns, sorted, the references that begin with prefix and
+// contain at most depth '/' characters after it. It lists a level at a
+// time with '/' as the delimiter, so the store does not send the keys
+// below depth.
+func (s *storageImpl) listPrefix(prefix string, depth int) ([]string, error) {
+	if depth < 0 {
+		return nil, nil
+	}
+	q := url.Values{"delimiter": {"/"}}
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	var refs []string
+	token := ""
+	for {
+		result, err := s.list(q, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			refs = append(refs, c.Key)
+		}
+		if depth > 0 {
+			for _, p := range result.CommonPrefixes {
+				sub, err := s.listPrefix(p.Prefix, depth-1)
+				if err != nil {
+					return nil, err
+				}
+				refs = append(refs, sub...)
+			}
+		}
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
+
+// list sends a ListObjectsV2 request with the query parameters q,
+// continuing from token if it is not empty, and returns one page of
+// the result.
+func (s *storageImpl) list(q url.Values, token string) (*listResult, error) {
+	u := s.objectURL("")
+	q.Set("list-type", "2")
+	q.Set("max-keys", strconv.Itoa(maxRefsPerCall))
+	q.Del("continuation-token")
+	if token != "" {
+		q.Set("continuation-token", token)
+	}
+	u.RawQuery = q.Encode()
+	resp, err := s.do("GET", u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result listResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.E(errors.IO, err)
+	}
+	return &result, nil
+}
+
+// objectURL returns the URL of the object holding ref,
+// or of the bucket if ref is empty.
+func (s *storageImpl) objectURL(ref string) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + ref
+	// Escape the path as the signature does, so it is sent as signed.
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+// s3Error is the body of an error response.
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// do signs and sends a request and returns the response if it succeeded.
+// Otherwise it returns an error, whose Kind is derived from the status.
+func (s *storageImpl) do(method string, u *url.URL, hdr http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.E(errors.Invalid, err)
+	}
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+	s.sign(req, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.E(errors.IO, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var kind errors.Kind
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		kind = errors.NotExist
+	case http.StatusForbidden:
+		kind = errors.Permission
+	default:
+		kind = errors.IO
+	}
+	var e s3Error
+	// A HEAD response has no body, and others may not be XML.
+	if xml.NewDecoder(resp.Body).Decode(&e) != nil || e.Code == "" {
+		return nil, errors.E(kind, errors.Errorf("%s", resp.Status))
+	}
+	return nil, errors.E(kind, errors.Errorf("%s: %s: %s", resp.Status, e.Code, e.Message))
+}
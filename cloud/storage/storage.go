@@ -45,6 +45,32 @@ type Lister interface {
 	List(token string) (refs []upspin.ListRefsItem, nextToken string, err error)
 }
 
+// Exister provides a mechanism to check whether a reference is held in
+// storage without downloading it. Clients can use a type assertion to
+// verify whether the Storage implements this interface.
+type Exister interface {
+	// Exists reports whether the storage backend holds the reference.
+	Exists(ref string) (bool, error)
+}
+
+// PrefixLister provides a mechanism to list the references held in storage
+// that begin with a prefix, treating '/' as a separator that delimits
+// levels, as with the hierarchical listing of a cloud object store.
+// Clients can use a type assertion to verify whether the Storage
+// implements this interface.
+type PrefixLister interface {
+	// ListPrefix returns, in lexical order, the references that begin
+	// with prefix and whose remainder after the prefix contains at most
+	// depth '/' characters. The prefix is a plain string prefix; it need
+	// not end with '/'.
+	ListPrefix(prefix string, depth int) ([]string, error)
+
+	// ListDir returns, in lexical order, the references that begin with
+	// dir and whose remainder contains no '/': the immediate contents of
+	// dir if it ends with '/'. It is equivalent to ListPrefix(dir, 0).
+	ListDir(dir string) ([]string, error)
+}
+
 // StorageConstructor is a function that initializes and returns a Storage
 // implementation with the given options.
 type StorageConstructor func(*Opts) (Storage, error)
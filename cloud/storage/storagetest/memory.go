@@ -59,3 +59,11 @@ func (m *mem) Delete(ref string) error {
 	delete(m.m, ref)
 	return nil
 }
+
+func (m *mem) Exists(ref string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.m[ref]
+	return ok, nil
+}
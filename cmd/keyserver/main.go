@@ -22,6 +22,7 @@ import (
 	// Possible storage backends.
 	"upspin.io/cloud/https"
 	_ "upspin.io/cloud/storage/disk"
+	_ "upspin.io/cloud/storage/s3"
 )
 
 var (
@@ -10,8 +10,9 @@ import (
 	"upspin.io/cloud/https"
 	"upspin.io/serverutil/storeserver"
 
-	// Storage implementation.
+	// Storage implementations.
 	_ "upspin.io/cloud/storage/disk"
+	_ "upspin.io/cloud/storage/s3"
 )
 
 func main() {
@@ -8,6 +8,10 @@ provides an upspin shell acting as the first user specified by the schema.
 Such clusters of Upspin servers are usually ephemeral in nature, making them
 useful for testing, developing Upspin clients and servers, and experiments.
 
+If the schema specifies a directory with its "dir" field, the generated keys,
+config files, and server data are kept there and reused by later runs, so that
+a cluster may be stopped and restarted without losing its contents.
+
 For information on defining a schema, see the documentation for package
 upspin.io/upbox.
 */
@@ -22,6 +26,14 @@ import (
 
 	"upspin.io/log"
 	"upspin.io/upbox"
+
+	// Transports and packings used to write the users' files.
+	_ "upspin.io/dir/remote"
+	_ "upspin.io/key/remote"
+	_ "upspin.io/pack/ee"
+	_ "upspin.io/pack/eeintegrity"
+	_ "upspin.io/pack/plain"
+	_ "upspin.io/store/remote"
 )
 
 var (
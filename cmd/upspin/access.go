@@ -0,0 +1,121 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+
+	"upspin.io/access"
+	"upspin.io/path"
+	"upspin.io/upspin"
+	"upspin.io/user"
+)
+
+func (s *State) access(args ...string) {
+	const help = `
+Access examines the effect of Access and Group files without changing
+them. At the moment its only subcommand is test.
+
+Access test reports whether the named user would have the named right
+to the path: read, write, list, create, delete, or any (meaning any of
+them). It evaluates the Access file that governs the path, loading any
+Group files it refers to, just as the directory server would, and
+prints yes or no. The path need not exist. It must be run by a user
+who can read the Access and Group files, typically their owner.
+
+With the -explain flag, access test also reports the Access file that
+was evaluated and, if the right is granted, how: because the user owns
+the tree, or through which entry or Group file.
+`
+	fs := flag.NewFlagSet("access", flag.ExitOnError)
+	explain := fs.Bool("explain", false, "explain the result")
+	s.ParseFlags(fs, args, help, "access test [-explain] user right path")
+	if fs.NArg() < 1 || fs.Arg(0) != "test" {
+		usageAndExit(fs)
+	}
+	// Allow the flags to follow the subcommand name.
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		usageAndExit(fs)
+	}
+	if fs.NArg() != 3 {
+		usageAndExit(fs)
+	}
+	userName, err := user.Clean(upspin.UserName(fs.Arg(0)))
+	if err != nil {
+		s.Exit(err)
+	}
+	right := access.AnyRight
+	if fs.Arg(1) != right.String() {
+		right = parseRight(fs.Arg(1))
+	}
+	if right == access.Invalid {
+		s.Exitf("unknown right %q", fs.Arg(1))
+	}
+	// The path need not exist, so do no glob processing.
+	name := s.AtSign(fs.Arg(2))
+	s.accessTest(userName, right, name, *explain)
+}
+
+// accessTest reports whether user would have the right to name.
+func (s *State) accessTest(user upspin.UserName, right access.Right, name upspin.PathName, explain bool) {
+	parsed, err := path.Parse(name)
+	if err != nil {
+		s.Exit(err)
+	}
+	entry, err := s.whichAccessFollowLinks(name)
+	if err != nil {
+		s.Exit(err)
+	}
+	if entry == nil {
+		// Without an Access file, only the owner has rights.
+		if user == parsed.User() {
+			s.Printf("yes\n")
+		} else {
+			s.Printf("no\n")
+		}
+		if explain {
+			s.Printf("no Access file applies; only the owner, %s, has rights\n", parsed.User())
+		}
+		return
+	}
+	data, err := s.Client.Get(entry.Name)
+	if err != nil {
+		s.Exit(err)
+	}
+	acc, err := access.Parse(entry.Name, data)
+	if err != nil {
+		s.Exit(err)
+	}
+	grant, err := acc.WhyCan(user, right, name, s.Client.Get)
+	if err != nil {
+		s.Exit(err)
+	}
+	if grant == nil {
+		s.Printf("no\n")
+		if explain {
+			s.Printf("%s does not grant %s the %s right\n", entry.Name, user, right)
+		}
+		return
+	}
+	s.Printf("yes\n")
+	if !explain {
+		return
+	}
+	if grant.Owner {
+		s.Printf("%s owns the tree governed by %s\n", user, entry.Name)
+		return
+	}
+	// A match that is a root, such as a user name or "all",
+	// is best shown without its trailing slash.
+	match := string(grant.Match.Path())
+	if grant.Match.IsRoot() {
+		match = string(grant.Match.User())
+	}
+	if grant.Group != "" {
+		s.Printf("%s grants the %s right to group %s, which lists %s\n", entry.Name, right, grant.Group, match)
+	} else {
+		s.Printf("%s grants the %s right to %s\n", entry.Name, right, match)
+	}
+}
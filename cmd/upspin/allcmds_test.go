@@ -5,6 +5,8 @@
 package main
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"upspin.io/upspin"
@@ -68,6 +70,22 @@ var basicCmdTests = []cmdTest{
 			"ee", "2", "28", "remote", "\tann@example.com/foo",
 		),
 	},
+	{
+		"config -check",
+		ann,
+		do(
+			"config -check",
+			"ls @/", // The shell still works after the check.
+		),
+		"",
+		expect(
+			"ok: user ann@example.com is registered",
+			"ok: local public key matches the key server",
+			"ok: root ann@example.com/ exists",
+			"ok: storage server",
+			"No problems found.",
+		),
+	},
 	{
 		"list nonexistent file",
 		ann,
@@ -223,6 +241,15 @@ var basicCmdTests = []cmdTest{
 			"some stuff to save",
 		),
 	},
+	{
+		"put with unknown packing",
+		ann,
+		do(
+			"put -packing rot13 @/justtext",
+		),
+		"some stuff to save",
+		fail(`no such packing "rot13"; available packings: plain, plainsum, ee, eeintegrity`),
+	},
 	{
 		"whichaccess",
 		ann,
@@ -263,6 +290,53 @@ var basicCmdTests = []cmdTest{
 		"",
 		expect("ann+snapshot@example.com/2"), // "/2" for "/2017" - or maybe later.
 	},
+	{
+		"snapshot diff",
+		ann,
+		do(
+			"snapshot -diff @+snapshot/*/*/*/* live",
+			"put @/snapdiff.txt",
+			"snapshot -diff @+snapshot/*/*/*/* live",
+			"snapshot -diff live @+snapshot/*/*/*/*",
+			"rm @/snapdiff.txt",
+		),
+		"new file\n",
+		expect(
+			"added", "snapdiff.txt",
+			"removed", "snapdiff.txt",
+		),
+	},
+	{
+		"snapshot diff of a file",
+		ann,
+		do(
+			"snapshot -diff live @/Public/Photo/public.jpg",
+		),
+		"",
+		fail("is not a directory"),
+	},
+	{
+		"snapshot of a subtree",
+		ann,
+		do(
+			"snapshot @/Friends",
+			"snapshot -list",
+		),
+		"",
+		expect(
+			"ann+snapshot@example.com/2", "ann@example.com/",
+			"ann+snapshot@example.com/2", "-Friends", "ann@example.com/Friends",
+		),
+	},
+	{
+		"snapshot of a file",
+		ann,
+		do(
+			"snapshot @/Public/Photo/public.jpg",
+		),
+		"",
+		fail("not a directory"),
+	},
 	{
 		"info on public file",
 		ann,
@@ -325,6 +399,205 @@ var basicCmdTests = []cmdTest{
 	},
 }
 
+// duTests tests the du command.
+var duTests = []cmdTest{
+	{
+		"du setup",
+		ann,
+		do(
+			"mkdir @/du",
+			"mkdir @/du/sub",
+			"mkdir @/du/sub/empty",
+			"link @/du @/du/sub/loop",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/du/ten", "0123456789"),
+	putFile(ann, "@/du/sub/five", "01234"),
+	{
+		"du tree",
+		ann,
+		do(
+			"du @/du",
+		),
+		"",
+		expect(
+			"0\tann@example.com/du/sub/empty\n",
+			"5\tann@example.com/du/sub\n",
+			"15\tann@example.com/du\n",
+		),
+	},
+	{
+		"du summary of file and directory",
+		ann,
+		do(
+			"du -s @/du/ten @/du/sub",
+		),
+		"",
+		expect(
+			"10\tann@example.com/du/ten\n",
+			"5\tann@example.com/du/sub\n",
+		),
+	},
+	{
+		"du follows looping link once",
+		ann,
+		do(
+			"du -s -L @/du",
+		),
+		"",
+		expect("15\tann@example.com/du\n"),
+	},
+	{
+		"du let chris list",
+		ann,
+		do(
+			"put @/du/Access",
+		),
+		"l:chris@example.com\n*:ann@example.com\n",
+		expectNoOutput(),
+	},
+	{
+		"du hide subdirectory from chris",
+		ann,
+		do(
+			"put @/du/sub/Access",
+		),
+		"*:ann@example.com\n",
+		expectNoOutput(),
+	},
+	{
+		"du of tree without read rights",
+		chris,
+		do(
+			"du ann@example.com/du",
+		),
+		"",
+		fail("skipping files in ann@example.com/du: sizes are hidden without read rights"),
+	},
+	{
+		"du of tree without list rights",
+		chris,
+		do(
+			"du ann@example.com/du",
+		),
+		"",
+		fail("skipping ann@example.com/du/sub: "),
+	},
+}
+
+// findTests tests the find command.
+var findTests = []cmdTest{
+	{
+		"find setup",
+		ann,
+		do(
+			"mkdir @/find",
+			"mkdir @/find/sub",
+			"link @/find @/find/sub/loop",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/find/a.jpg", "0123456789"),
+	putFile(ann, "@/find/sub/b.jpg", "01234"),
+	putFile(ann, "@/find/sub/c.txt", "0"),
+	{
+		"find everything",
+		ann,
+		do(
+			"find @/find",
+		),
+		"",
+		expectExactly(
+			"ann@example.com/find\n",
+			"ann@example.com/find/a.jpg\n",
+			"ann@example.com/find/sub\n",
+			"ann@example.com/find/sub/b.jpg\n",
+			"ann@example.com/find/sub/c.txt\n",
+			"ann@example.com/find/sub/loop\n",
+		),
+	},
+	{
+		"find by name, following looping link",
+		ann,
+		do(
+			"find -L -name=*.jpg @/find",
+		),
+		"",
+		expectExactly(
+			"ann@example.com/find/a.jpg\n",
+			"ann@example.com/find/sub/b.jpg\n",
+		),
+	},
+	{
+		"find larger files",
+		ann,
+		do(
+			"find -size=+5 @/find",
+		),
+		"",
+		expectExactly("ann@example.com/find/a.jpg\n"),
+	},
+	{
+		"find smaller files",
+		ann,
+		do(
+			"find -size=-5 @/find",
+		),
+		"",
+		expectExactly("ann@example.com/find/sub/c.txt\n"),
+	},
+	{
+		"find files of exact size",
+		ann,
+		do(
+			"find -size=5 @/find",
+		),
+		"",
+		expectExactly("ann@example.com/find/sub/b.jpg\n"),
+	},
+	{
+		"find newer files",
+		ann,
+		do(
+			"find -newer=1h -name=c.* @/find",
+			"find -newer=1ns @/find",
+		),
+		"",
+		expectExactly("ann@example.com/find/sub/c.txt\n"),
+	},
+	{
+		"find with format",
+		ann,
+		do(
+			"find -size=+1K @/find",
+			"find -format={{.Name}}:{{.Size}} -name=a.jpg @/find",
+		),
+		"",
+		expectExactly("ann@example.com/find/a.jpg:10\n"),
+	},
+	{
+		"find with bad size",
+		ann,
+		do(
+			"find -size=+1X @/find",
+		),
+		"",
+		fail("invalid -size"),
+	},
+	{
+		"find with bad pattern",
+		ann,
+		do(
+			"find -name=[ @/find",
+		),
+		"",
+		fail("invalid -name pattern"),
+	},
+}
+
 // globTests tests glob processing, and the ability to disable it.
 // TODO: Test lots more.
 var globTests = []cmdTest{
@@ -346,7 +619,7 @@ var globTests = []cmdTest{
 			"mkdir -glob=false @/a[1]b/c**d",
 			"put -glob=false @/a[1]b/c**d/file1",
 			"get @/a?1?b/c??d/file1", // Note: globbing enabled here.
-			"rm -R -glob=false @/a[1]b",
+			"rm -R -f -glob=false @/a[1]b",
 		),
 		"text of file1",
 		expect("text of file1"),
@@ -429,6 +702,182 @@ var cpTests = []cmdTest{
 		"this is @/cp/file new content",
 		expect("this is @/cp/file new content", "this is @/cp/subdir/file"),
 	},
+	{
+		"cp with bandwidth limit",
+		ann,
+		do(
+			"put -bwlimit=1000 @/cp/limited",
+			"cp -bwlimit=1000 @/cp/limited "+testTempDir("cplimit", deleteOld),
+			"cp -bwlimit=1000 "+testTempDir("cplimit", keepOld)+"/limited @/cp/limited2",
+			"get -bwlimit=1000 @/cp/limited2",
+		),
+		"slow and steady",
+		expect("slow and steady"),
+	},
+	{
+		"build tree to cp in parallel",
+		ann,
+		do(
+			"mkdir @/cppar",
+			"mkdir @/cppar/a",
+			"mkdir @/cppar/a/b",
+			"mkdir @/cppar2",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/cppar/1", "one"),
+	putFile(ann, "@/cppar/a/2", "two"),
+	putFile(ann, "@/cppar/a/b/3", "three"),
+	putFile(ann, "@/cppar/a/b/4", "four"),
+	{
+		"cp tree in parallel",
+		ann,
+		do(
+			"cp -R -parallel=3 @/cppar/* "+testTempDir("cppar", deleteOld),
+			"cp -R -parallel=3 "+testTempGlob("cppar")+" @/cppar2",
+			"ls -R @/cppar2",
+			"get @/cppar2/a/b/4",
+		),
+		"",
+		expect(
+			"ann@example.com/cppar2/1\n",
+			"ann@example.com/cppar2/a/2\n",
+			"ann@example.com/cppar2/a/b/3\n",
+			"ann@example.com/cppar2/a/b/4\n",
+			"four",
+		),
+	},
+	{
+		"cp in parallel reports failure",
+		ann,
+		do(
+			"mkdir @/cppar3",
+			"mkdir @/cppar3/1",
+			"cp -R -parallel=3 "+testTempGlob("cppar")+" @/cppar3",
+		),
+		"",
+		fail("ann@example.com/cppar3/1"),
+	},
+	{
+		"cp in parallel continues after failure",
+		ann,
+		do(
+			"ls -R @/cppar3",
+		),
+		"",
+		expect(
+			"ann@example.com/cppar3/1/\n",
+			"ann@example.com/cppar3/a/2\n",
+			"ann@example.com/cppar3/a/b/3\n",
+			"ann@example.com/cppar3/a/b/4\n",
+		),
+	},
+	{
+		"build tree to cp with dedup",
+		ann,
+		do(
+			"mkdir @/cpdedup",
+			"mkdir @/cpdedup/sub",
+			"mkdir @/cpdedup2",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/cpdedup/a", "same"),
+	putFile(ann, "@/cpdedup/b", "same"),
+	putFile(ann, "@/cpdedup/sub/c", "same"),
+	putFile(ann, "@/cpdedup/d", "different"),
+	{
+		"cp -dedup-local",
+		ann,
+		do(
+			"cp -R @/cpdedup/* "+testTempDir("cpdedup", deleteOld),
+			"cp -R -dedup-local -packing=plain "+testTempGlob("cpdedup")+" @/cpdedup2",
+		),
+		"",
+		expectSharedBlocks("ann@example.com/cpdedup2/a", "ann@example.com/cpdedup2/b", "ann@example.com/cpdedup2/sub/c"),
+	},
+	{
+		"cp -dedup-local copies contents",
+		ann,
+		do(
+			"get @/cpdedup2/b",
+			"get @/cpdedup2/sub/c",
+			"get @/cpdedup2/d",
+		),
+		"",
+		expect("same", "same", "different"),
+	},
+	{
+		"cp -dedup-local requires unencrypted packing",
+		ann,
+		do(
+			"cp -R -dedup-local " + testTempGlob("cpdedup") + " @/cpdedup2",
+		),
+		"",
+		fail("-dedup-local requires plain, plainsum, or eeintegrity packing, not ee"),
+	},
+}
+
+var convertArchive = filepath.Join(testTempDir("convert", deleteOld), "tree.archive")
+
+var convertTests = []cmdTest{
+	{
+		"build tree to convert",
+		ann,
+		do(
+			"mkdir @/convert",
+			"mkdir @/convert/sub",
+			"put -packing plain @/convert/plain",
+			"link @/convert/plain @/convert/sub/link",
+			"link @/Public/Photo @/convert/outside",
+		),
+		"this is @/convert/plain",
+		expectNoOutput(),
+	},
+	putFile(
+		ann,
+		"@/convert/Access",
+		"*: ann@example.com chris@example.com\n",
+	),
+	putFile(
+		chris,
+		"ann@example.com/convert/sub/file",
+		"chris wrote this",
+	),
+	{
+		"convert to archive",
+		ann,
+		do("convert -to archive @/convert " + convertArchive),
+		"",
+		expectArchiveWriters(convertArchive, map[upspin.PathName]upspin.UserName{
+			"ann@example.com/convert/plain":    ann,
+			"ann@example.com/convert/sub/file": chris,
+			"ann@example.com/convert/sub/link": ann,
+		}),
+	},
+	{
+		"convert from archive",
+		ann,
+		do("convert -from archive -prefix @/converted " + convertArchive),
+		"",
+		expectSameTree("ann@example.com/convert", "ann@example.com/converted"),
+	},
+	{
+		"convert from non-archive",
+		ann,
+		do("convert -from archive -prefix @/converted2 doc.go"),
+		"",
+		fail("not an Upspin archive"),
+	},
+	{
+		"convert unknown format",
+		ann,
+		do("convert -to zip @/convert " + convertArchive),
+		"",
+		fail(`unknown format "zip"`),
+	},
 }
 
 // lsTests tests the ls command, in particular its handling of links.
@@ -490,10 +939,275 @@ var lsTests = []cmdTest{
 			"ann@example.com/linktest/file",
 		),
 	},
-}
-
-// shareTests tests share processing,.
-// TODO: Test lots more.
+	{
+		"ls -format",
+		ann,
+		do(
+			"ls -format={{.Name}}:{{.Size}}:{{.Link}} @/linktest",
+			"info -format={{.Writer}},{{.Attr}} @/linktest/file",
+		),
+		"",
+		expect(
+			"ann@example.com/linktest/file:16:\n",
+			"ann@example.com/linktest/link:0:ann@example.com/linktest/file\n",
+			"ann@example.com,none (plain file)\n",
+		),
+	},
+	{
+		"ls -format with bad template",
+		ann,
+		do(
+			"ls -format={{.Nonexistent}} @/linktest",
+		),
+		"",
+		fail("invalid -format template"),
+	},
+	{
+		"ls -json",
+		ann,
+		do(
+			"ls -json @/linktest",
+			"info -json @/linktest/file",
+		),
+		"",
+		expect(
+			`{"Name":"ann@example.com/linktest/file","Attr":"none (plain file)",`,
+			`"Size":16,"Blocks":[{"Location":{"Endpoint":"remote,`, `"Offset":0,"Size":16}],"Writer":"ann@example.com","Packing":"ee"}`,
+			`{"Name":"ann@example.com/linktest/link","Attr":"link",`,
+			`"Size":0,"Blocks":[],"Writer":"ann@example.com","Packing":"plain","Link":"ann@example.com/linktest/file"}`,
+			`{"Name":"ann@example.com/linktest/file",`,
+			`"Packing":"ee","Access":"owner only","Packer":"ee"}`,
+		),
+	},
+	{
+		"ls -json with -format",
+		ann,
+		do(
+			"ls -json -format={{.Name}} @/linktest",
+		),
+		"",
+		fail("Usage: upspin ls"),
+	},
+	{
+		"create mixed directory",
+		ann,
+		do(
+			"mkdir @/grouptest",
+			"put @/grouptest/a",
+			"mkdir @/grouptest/b",
+			"put @/grouptest/c",
+			"mkdir @/grouptest/d",
+			"link @/grouptest/b @/grouptest/e",
+		),
+		"some data",
+		expectNoOutput(),
+	},
+	{
+		"ls ungrouped",
+		ann,
+		do(
+			"ls @/grouptest",
+		),
+		"",
+		expect(
+			"ann@example.com/grouptest/a\n",
+			"ann@example.com/grouptest/b/\n",
+			"ann@example.com/grouptest/c\n",
+			"ann@example.com/grouptest/d/\n",
+			"ann@example.com/grouptest/e\n",
+		),
+	},
+	{
+		"ls -dirs-first",
+		ann,
+		do(
+			"ls -dirs-first @/grouptest",
+		),
+		"",
+		expect(
+			"ann@example.com/grouptest/b/\n",
+			"ann@example.com/grouptest/d/\n",
+			"ann@example.com/grouptest/a\n",
+			"ann@example.com/grouptest/c\n",
+			"ann@example.com/grouptest/e\n",
+		),
+	},
+	{
+		"ls -files-first",
+		ann,
+		do(
+			"ls -files-first @/grouptest",
+		),
+		"",
+		expect(
+			"ann@example.com/grouptest/a\n",
+			"ann@example.com/grouptest/c\n",
+			"ann@example.com/grouptest/e\n",
+			"ann@example.com/grouptest/b/\n",
+			"ann@example.com/grouptest/d/\n",
+		),
+	},
+	{
+		"ls -dirs-first -L",
+		ann,
+		do(
+			"ls -dirs-first -L @/grouptest",
+		),
+		"",
+		expect(
+			// The link is replaced by its target, a directory.
+			"ann@example.com/grouptest/b/\n",
+			"ann@example.com/grouptest/d/\n",
+			"ann@example.com/grouptest/b/\n",
+			"ann@example.com/grouptest/a\n",
+			"ann@example.com/grouptest/c\n",
+		),
+	},
+	{
+		"ls -dirs-first with -files-first",
+		ann,
+		do(
+			"ls -dirs-first -files-first @/grouptest",
+		),
+		"",
+		fail("Usage: upspin ls"),
+	},
+}
+
+// mkdirTests tests creating directories with an initial Access file.
+var mkdirTests = []cmdTest{
+	{
+		"mkdir with access file",
+		ann,
+		do(
+			`mkdir -access r:chris@example.com\n*:ann@example.com @/Shared`,
+			"get @/Shared/Access",
+		),
+		"",
+		expect("r:chris@example.com\n*:ann@example.com\n"),
+	},
+	putFile(
+		ann,
+		"@/Shared/notes",
+		"shared notes",
+	),
+	{
+		"chris can read the shared directory",
+		chris,
+		do(
+			"get ann@example.com/Shared/notes",
+		),
+		"",
+		expect("shared notes"),
+	},
+	{
+		"mkdir with invalid access file",
+		ann,
+		do(
+			"mkdir -access=x:chris@example.com @/BadShared",
+		),
+		"",
+		fail("invalid access rights"),
+	},
+	{
+		"directory with invalid access file was not created",
+		ann,
+		do(
+			"ls @/BadShared",
+		),
+		"",
+		fail("item does not exist"),
+	},
+}
+
+// mvTests tests the mv command.
+var mvTests = []cmdTest{
+	{
+		"mv setup",
+		ann,
+		do(
+			"mkdir @/mv",
+			"mkdir @/mv/dir",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/mv/a", "file a"),
+	putFile(ann, "@/mv/b", "file b"),
+	putFile(ann, "@/mv/c", "file c"),
+	{
+		"mv rename file",
+		ann,
+		do(
+			"mv @/mv/a @/mv/a2",
+			"get @/mv/a2",
+			"ls @/mv",
+		),
+		"",
+		expect("file a", "mv/a2", "mv/b", "mv/c", "mv/dir"),
+	},
+	{
+		"mv old name is gone",
+		ann,
+		do(
+			"get @/mv/a",
+		),
+		"",
+		fail("item does not exist"),
+	},
+	{
+		"mv will not replace a file",
+		ann,
+		do(
+			"mv @/mv/a2 @/mv/b",
+		),
+		"",
+		fail("already exists"),
+	},
+	{
+		"mv -f replaces a file",
+		ann,
+		do(
+			"mv -f @/mv/a2 @/mv/b",
+			"get @/mv/b",
+			"ls @/mv",
+		),
+		"",
+		expect("file a", "mv/b", "mv/c", "mv/dir"),
+	},
+	{
+		"mv multiple files into directory",
+		ann,
+		do(
+			"mv @/mv/[bc] @/mv/dir",
+			"ls @/mv/dir",
+			"get @/mv/dir/c",
+		),
+		"",
+		expect("mv/dir/b", "mv/dir/c", "file c"),
+	},
+	{
+		"mv multiple files to non-directory",
+		ann,
+		do(
+			"mv @/mv/dir/b @/mv/dir/c @/mv/nonesuch",
+		),
+		"",
+		fail("is not a directory"),
+	},
+	{
+		"mv directory",
+		ann,
+		do(
+			"mv @/mv/dir @/mv/dir2",
+		),
+		"",
+		fail("cannot link or rename directories"),
+	},
+}
+
+// shareTests tests share processing,.
+// TODO: Test lots more.
 var shareTests = []cmdTest{
 	// Make sure that kelly@ cannot read the Friends directory.
 	{
@@ -521,6 +1235,20 @@ var shareTests = []cmdTest{
 		"",
 		fail("no wrapped key for user"),
 	},
+	// An audit reports the problem without fixing it.
+	{
+		"ann audits @/Friends",
+		ann,
+		do(
+			"share -audit @/Friends",
+		),
+		"",
+		expect(
+			"friends.jpg:",
+			"cannot decrypt: kelly@example.com",
+			"1 of 2 encrypted files have problems",
+		),
+	},
 	// Do the share; that should fix it.
 	{
 		"ann shares @/Friends",
@@ -588,70 +1316,705 @@ var shareTests = []cmdTest{
 		"",
 		expect("this is friends.jpg"),
 	},
+	// With the keys fixed, the audit finds nothing.
+	{
+		"ann audits @/Friends again",
+		ann,
+		do(
+			"share -audit -q @/Friends",
+		),
+		"",
+		expectExactly("0 of 2 encrypted files have problems\n"),
+	},
 }
 
-// The keygen tests update the keys for the user. Since the command test reloads the
-// environment for each cmdTest, we can also test that the new keys work.
-var keygenTests = []cmdTest{
+// shareGroupTests tests sharing through a Group file with share -via-group.
+var shareGroupTests = []cmdTest{
 	{
-		"create a temporary key",
+		"make team directory",
 		ann,
+		do("mkdir @/Team"),
+		"",
+		expectNoOutput(),
+	},
+	putFile(
+		ann,
+		"@/Team/plan",
+		"this is the plan",
+	),
+	{
+		"chris can't read the plan yet",
+		chris,
 		do(
-			"keygen -secretseed disis-valid-fosoh-matij.disis-valid-fosoh-matij " + testTempDir("key", deleteOld),
+			"get ann@example.com/Team/plan",
 		),
 		"",
-		keygenVerify(testTempDir("key", keepOld), "p256\n24759220094", "3291910954783938", "", keepOld),
+		fail("information withheld"),
 	},
+	// Create the team group with chris@ as its member.
 	{
-		"keygen again will fail",
+		"ann shares @/Team via a group",
 		ann,
 		do(
-			"keygen -secretseed bonus-favor-panat-fakir.kolor-kivil-koral-hovit " + testTempDir("key", keepOld),
+			"share -q -via-group=@/Group/team -add=chris@example.com -r @/Team",
 		),
 		"",
-		fail("prior keys exist"),
+		expectNoOutput(),
 	},
 	{
-		"keygen rotate",
+		"team group and access file",
 		ann,
 		do(
-			"keygen -rotate -secretseed bonus-favor-panat-fakir.kolor-kivil-koral-hovit " + testTempDir("key", keepOld),
+			"get @/Group/team",
+			"get @/Team/Access",
 		),
 		"",
-		keygenVerify(testTempDir("key", keepOld), "p256\n33850756267", "1135817957601671", "3291910954783938", deleteOld),
+		expect("chris@example.com", "*: ann@example.com", "read: ann@example.com/Group/team"),
 	},
 	{
-		"use new keys",
+		"chris can read the plan now",
+		chris,
+		do(
+			"get ann@example.com/Team/plan",
+		),
+		"",
+		expect("this is the plan"),
+	},
+	{
+		"kelly can't read the plan yet",
+		kelly,
+		do(
+			"get ann@example.com/Team/plan",
+		),
+		"",
+		fail("information withheld"),
+	},
+	// Add kelly@ to the group; the Access file is already correct.
+	{
+		"ann adds kelly to the team",
 		ann,
 		do(
-			"mkdir @/keytest",
-			"ls @/keytest",
-			"rm @/keytest",
+			"share -q -via-group=@/Group/team -add=kelly@example.com -r @/Team",
 		),
 		"",
 		expectNoOutput(),
 	},
+	{
+		"kelly can read the plan now",
+		kelly,
+		do(
+			"get ann@example.com/Team/plan",
+		),
+		"",
+		expect("this is the plan"),
+	},
+	{
+		"access file is unchanged",
+		ann,
+		do(
+			"get @/Team/Access",
+		),
+		"",
+		expect("*: ann@example.com\nread: ann@example.com/Group/team\n"),
+	},
 }
 
-// The suffixed user tests create a new suffixed user confirming that the
-// config and key files for that user are created and that the user is known
-// to the key server. They also confirm that a suffixed user can not create
-// a suffixed user.
-var suffixedUserTests = []cmdTest{
+// The history tests modify a file as two different users, delete and
+// recreate it, and verify that info -history reports each change in order.
+var historyTests = []cmdTest{
 	{
-		"create a suffixed user",
+		"make history directory",
+		ann,
+		do("mkdir @/History"),
+		"",
+		expectNoOutput(),
+	},
+	putFile(
+		ann,
+		"@/History/Access",
+		"*: ann@example.com chris@example.com\n",
+	),
+	putFile(
+		ann,
+		"@/History/file",
+		"version one",
+	),
+	putFile(
+		chris,
+		"ann@example.com/History/file",
+		"version two",
+	),
+	{
+		"delete and recreate file",
 		ann,
 		do(
-			"createsuffixeduser -secrets=" + testTempDir("key", deleteOld) + " ann+quux@example.com",
+			"rm @/History/file",
+			"put @/History/file",
 		),
+		"version three",
+		expectNoOutput(),
+	},
+	{
+		"info -history",
+		ann,
+		do("info -history @/History/file"),
 		"",
-		suffixedUserExists("ann", "quux"),
+		expect(
+			"ann@example.com/History/file:",
+			"put", "ann@example.com", "11",
+			"put", "chris@example.com", "11",
+			"delete",
+			"put", "ann@example.com", "13",
+		),
 	},
 	{
-		"user ann+quux",
+		"history",
 		ann,
-		do("user ann+quux@example.com"),
+		do("history @/History/file"),
 		"",
-		expect("name: ann+quux@example.com", "dirs", "- remote,localhost", "stores", "- remote,localhost", "publickey"),
+		expect(
+			"ann@example.com/History/file:",
+			"put", "ann@example.com", "11",
+			"put", "chris@example.com", "11",
+			"delete",
+			"put", "ann@example.com", "13",
+		),
+	},
+	{
+		"history nonexistent file",
+		chris,
+		do("history ann@example.com/History/nothing"),
+		"",
+		fail("no history"),
+	},
+	{
+		"info -history nonexistent file",
+		ann,
+		do("info -history @/History/nothing"),
+		"",
+		fail("no history"),
+	},
+}
+
+// The keygen tests update the keys for the user. Since the command test reloads the
+// environment for each cmdTest, we can also test that the new keys work.
+var keygenTests = []cmdTest{
+	{
+		"create a temporary key",
+		ann,
+		do(
+			"keygen -secretseed disis-valid-fosoh-matij.disis-valid-fosoh-matij " + testTempDir("key", deleteOld),
+		),
+		"",
+		keygenVerify(testTempDir("key", keepOld), "p256\n24759220094", "3291910954783938", "", keepOld),
+	},
+	{
+		"keygen again will fail",
+		ann,
+		do(
+			"keygen -secretseed bonus-favor-panat-fakir.kolor-kivil-koral-hovit " + testTempDir("key", keepOld),
+		),
+		"",
+		fail("prior keys exist"),
+	},
+	{
+		"keygen rotate",
+		ann,
+		do(
+			"keygen -rotate -secretseed bonus-favor-panat-fakir.kolor-kivil-koral-hovit " + testTempDir("key", keepOld),
+		),
+		"",
+		keygenVerify(testTempDir("key", keepOld), "p256\n33850756267", "1135817957601671", "3291910954783938", deleteOld),
+	},
+	{
+		"use new keys",
+		ann,
+		do(
+			"mkdir @/keytest",
+			"ls @/keytest",
+			"rm @/keytest",
+		),
+		"",
+		expectNoOutput(),
+	},
+	{
+		"keygen print public key",
+		ann,
+		do("keygen -print-public"),
+		"",
+		keygenPrintVerify(false),
+	},
+	putFile(
+		ann,
+		"@/fingerprint",
+		"fingerprint me",
+	),
+	{
+		"keygen fingerprint",
+		ann,
+		do("keygen -fingerprint"),
+		"",
+		keygenPrintVerify(true),
+	},
+	{
+		"keygen quietly prints new public key",
+		ann,
+		do(
+			"keygen -quiet -print-new-public -curve p384 -secretseed disis-valid-fosoh-matij.disis-valid-fosoh-matij -where " + testTempDir("key-p384", deleteOld),
+		),
+		"",
+		keygenNewPublicVerify(testTempDir("key-p384", keepOld), "p384\n"),
+	},
+	{
+		"keygen unsupported curve",
+		ann,
+		do("keygen -curve p257 " + testTempDir("key-p257", deleteOld)),
+		"",
+		fail(`unsupported curve "p257"; must be one of p256, p384, p521`),
+	},
+}
+
+// The suffixed user tests create a new suffixed user confirming that the
+// config and key files for that user are created and that the user is known
+// to the key server. They also confirm that a suffixed user can not create
+// a suffixed user.
+var suffixedUserTests = []cmdTest{
+	{
+		"create a suffixed user",
+		ann,
+		do(
+			"createsuffixeduser -secrets=" + testTempDir("key", deleteOld) + " ann+quux@example.com",
+		),
+		"",
+		suffixedUserExists("ann", "quux"),
+	},
+	{
+		"user ann+quux",
+		ann,
+		do("user ann+quux@example.com"),
+		"",
+		expect("name: ann+quux@example.com", "dirs", "- remote,localhost", "stores", "- remote,localhost", "publickey"),
+	},
+}
+
+var infoTests = []cmdTest{
+	{
+		"info -raw-store setup",
+		ann,
+		do(
+			"mkdir @/rawstore",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/rawstore/file", rawStoreText),
+	{
+		"info -raw-store",
+		ann,
+		do(
+			"info -raw-store @/rawstore/file",
+		),
+		"",
+		expectRawStore("ann@example.com/rawstore/file", rawStoreText),
+	},
+	{
+		"info -raw-store -R",
+		ann,
+		do(
+			"info -R -raw-store @/rawstore",
+		),
+		"",
+		expectRawStore("ann@example.com/rawstore/file", rawStoreText),
+	},
+	{
+		"info -raw-store with -json",
+		ann,
+		do(
+			"info -raw-store -json @/rawstore/file",
+		),
+		"",
+		fail("Usage: upspin info"),
+	},
+}
+
+const rawStoreText = "The wren\nEarns his living\nNoiselessly.\n"
+
+var verifyTests = []cmdTest{
+	{
+		"verify setup",
+		ann,
+		do(
+			"mkdir @/verify",
+			"mkdir @/verify/sub",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/verify/one", "one"),
+	putFile(ann, "@/verify/sub/two", "two"),
+	{
+		"verify files",
+		ann,
+		do(
+			"verify @/verify/one @/verify/sub/two",
+		),
+		"",
+		expectNoOutput(),
+	},
+	{
+		"verify tree",
+		ann,
+		do(
+			"verify -R -v @/verify",
+		),
+		"",
+		expect(
+			"ann@example.com/verify/one: ok\n",
+			"ann@example.com/verify/sub/two: ok\n",
+		),
+	},
+	{
+		"verify remove blocks",
+		ann,
+		do(
+			"ls @/verify/sub/two",
+		),
+		"",
+		deleteBlocks("ann@example.com/verify/sub/two"),
+	},
+	{
+		"verify missing block",
+		ann,
+		do(
+			"verify -R @/verify",
+		),
+		"",
+		fail("ann@example.com/verify/sub/two: block 0: "),
+	},
+}
+
+var accessTests = []cmdTest{
+	{
+		"access test setup",
+		ann,
+		do(
+			"mkdir @/acctest",
+			"put @/Group/acctest",
+		),
+		"chris@example.com",
+		expectNoOutput(),
+	},
+	{
+		"access test add Access file",
+		ann,
+		do(
+			"put @/acctest/Access",
+		),
+		"read: ann@example.com/Group/acctest\nlist: lee@example.com\n",
+		expectNoOutput(),
+	},
+	{
+		"access test group member",
+		ann,
+		do(
+			"access test chris@example.com read @/acctest/file",
+			"access test chris@example.com write @/acctest/file",
+		),
+		"",
+		expect("yes\n", "no\n"),
+	},
+	{
+		"access test non-member",
+		ann,
+		do(
+			"access test kelly@example.com read @/acctest/file",
+			"access test kelly@example.com any @/acctest",
+		),
+		"",
+		expect("no\n", "no\n"),
+	},
+	{
+		"access test explain",
+		ann,
+		do(
+			"access test -explain chris@example.com read @/acctest/file",
+			"access test -explain lee@example.com any @/acctest",
+			"access test -explain ann@example.com read @/acctest/file",
+			"access test -explain kelly@example.com read @/acctest/file",
+		),
+		"",
+		expect(
+			"yes\nann@example.com/acctest/Access grants the read right to group ann@example.com/Group/acctest, which lists chris@example.com\n",
+			"yes\nann@example.com/acctest/Access grants the any right to lee@example.com\n",
+			"yes\nann@example.com owns the tree governed by ann@example.com/acctest/Access\n",
+			"no\nann@example.com/acctest/Access does not grant kelly@example.com the read right\n",
+		),
+	},
+	{
+		"access test unknown right",
+		ann,
+		do(
+			"access test chris@example.com fly @/acctest",
+		),
+		"",
+		fail(`unknown right "fly"`),
+	},
+	{
+		"access test user name is cleaned",
+		ann,
+		do(
+			"access test chris@EXAMPLE.com read @/acctest/file",
+		),
+		"",
+		expect("yes\n"),
+	},
+	{
+		"access test bad user name",
+		ann,
+		do(
+			"access test chris read @/acctest",
+		),
+		"",
+		fail("user name must contain one @ symbol"),
+	},
+}
+
+// completeTests tests the completion of command and path names in the shell.
+var completeTests = []cmdTest{
+	{
+		"complete setup",
+		ann,
+		do(
+			"mkdir @/complete",
+			"mkdir @/complete/sub",
+			"mkdir @/complete/subtle",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/complete/a.txt", "a"),
+	{
+		"complete command name",
+		ann,
+		do(),
+		"",
+		expectCompletions("get @/x | sha", "sha", "share"),
+	},
+	{
+		"complete directory",
+		ann,
+		do(),
+		"",
+		expectCompletions("ls @/complete/", "@/complete/", "@/complete/a.txt", "@/complete/sub/", "@/complete/subtle/"),
+	},
+	{
+		"complete partial name",
+		ann,
+		do(),
+		"",
+		expectCompletions("ls ann@example.com/complete/su", "ann@example.com/complete/su", "ann@example.com/complete/sub/", "ann@example.com/complete/subtle/"),
+	},
+	{
+		"complete without rights",
+		chris,
+		do(),
+		"",
+		expectCompletions("ls ann@example.com/complete/", "ann@example.com/complete/"),
+	},
+	{
+		"complete local name",
+		ann,
+		do(),
+		"",
+		expectCompletions("cp a.t", "a.t"),
+	},
+}
+
+// expectCompletions is a post function that verifies that the shell
+// completes the last word of line with the given names.
+func expectCompletions(line, word string, want ...string) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		got := r.state.completions(line, word)
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("%q: completions(%q) = %q, want %q", cmd.name, line, got, want)
+		}
+	}
+}
+
+// dryRunTests tests the -n flag of rm, mv, and cp.
+var dryRunTests = []cmdTest{
+	{
+		"dry run setup",
+		ann,
+		do(
+			"mkdir @/dryrun",
+			"mkdir @/dryrun/dir",
+			"mkdir @/dryrun/dst",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/dryrun/a", "file a"),
+	putFile(ann, "@/dryrun/b", "file b"),
+	putFile(ann, "@/dryrun/dir/c", "file c"),
+	{
+		"rm -n",
+		ann,
+		do(
+			"rm -n -R @/dryrun/[ad]*",
+		),
+		"",
+		expectExactly(
+			"rm ann@example.com/dryrun/a\n",
+			"rm ann@example.com/dryrun/dir/c\n",
+			"rm ann@example.com/dryrun/dir\n",
+			"rm ann@example.com/dryrun/dst\n",
+		),
+	},
+	{
+		"mv -n",
+		ann,
+		do(
+			"mv -dry-run @/dryrun/a @/dryrun/a2",
+			"mv -n -f @/dryrun/a @/dryrun/b",
+			"mv -n @/dryrun/[ab] @/dryrun/dst",
+		),
+		"",
+		expectExactly(
+			"mv ann@example.com/dryrun/a ann@example.com/dryrun/a2\n",
+			"rm ann@example.com/dryrun/b\n",
+			"mv ann@example.com/dryrun/a ann@example.com/dryrun/b\n",
+			"mv ann@example.com/dryrun/a ann@example.com/dryrun/dst/a\n",
+			"mv ann@example.com/dryrun/b ann@example.com/dryrun/dst/b\n",
+		),
+	},
+	{
+		"mv -n will not replace a file",
+		ann,
+		do(
+			"mv -n @/dryrun/a @/dryrun/b",
+		),
+		"",
+		fail("already exists"),
+	},
+	{
+		"cp -n",
+		ann,
+		do(
+			"cp -n -R @/dryrun/a @/dryrun/dir @/dryrun/dst",
+		),
+		"",
+		expectExactly(
+			"cp ann@example.com/dryrun/a ann@example.com/dryrun/dst/a\n",
+			"mkdir ann@example.com/dryrun/dst/dir\n",
+			"cp ann@example.com/dryrun/dir/c ann@example.com/dryrun/dst/dir/c\n",
+		),
+	},
+	{
+		"dry runs changed nothing",
+		ann,
+		do(
+			"find @/dryrun",
+		),
+		"",
+		expectExactly(
+			"ann@example.com/dryrun\n",
+			"ann@example.com/dryrun/a\n",
+			"ann@example.com/dryrun/b\n",
+			"ann@example.com/dryrun/dir\n",
+			"ann@example.com/dryrun/dir/c\n",
+			"ann@example.com/dryrun/dst\n",
+		),
+	},
+}
+
+// rmTests tests recursive removal by the rm command.
+var rmTests = []cmdTest{
+	{
+		"rm setup",
+		ann,
+		do(
+			"mkdir @/rm",
+			"mkdir @/rm/dir",
+			"mkdir @/rm/dir/sub",
+			"mkdir @/rm/perm",
+			"mkdir @/rm/perm/keep",
+		),
+		"",
+		expectNoOutput(),
+	},
+	putFile(ann, "@/rm/target", "target"),
+	putFile(ann, "@/rm/dir/a", "a"),
+	putFile(ann, "@/rm/dir/sub/b", "b"),
+	putFile(ann, "@/rm/perm/Access", "*: ann@example.com\nr,l,d: chris@example.com\n"),
+	putFile(ann, "@/rm/perm/gone", "gone"),
+	putFile(ann, "@/rm/perm/keep/Access", "*: ann@example.com\nr,l: chris@example.com\n"),
+	putFile(ann, "@/rm/perm/keep/kept", "kept"),
+	{
+		"rm -R link in tree",
+		ann,
+		do(
+			"link @/rm/target @/rm/dir/link",
+		),
+		"",
+		expectNoOutput(),
+	},
+	{
+		"rm -R needs -f without a terminal",
+		ann,
+		do(
+			"rm -R @/rm/dir",
+		),
+		"y\n",
+		fail("would remove 5 items; use -f"),
+	},
+	{
+		"rm -r -f",
+		ann,
+		do(
+			"rm -r -f @/rm/dir",
+			"find @/rm",
+		),
+		"",
+		expectExactly(
+			"removed 5 items\n",
+			"ann@example.com/rm\n",
+			"ann@example.com/rm/perm\n",
+			"ann@example.com/rm/perm/Access\n",
+			"ann@example.com/rm/perm/gone\n",
+			"ann@example.com/rm/perm/keep\n",
+			"ann@example.com/rm/perm/keep/Access\n",
+			"ann@example.com/rm/perm/keep/kept\n",
+			"ann@example.com/rm/target\n",
+		),
+	},
+	{
+		"rm -R -f continues after permission error",
+		chris,
+		do(
+			"rm -R -f ann@example.com/rm/perm/gone ann@example.com/rm/perm/keep",
+		),
+		"",
+		fail("permission denied"),
+	},
+	{
+		"rm -R -f removed what it could",
+		ann,
+		do(
+			"find @/rm",
+		),
+		"",
+		expectExactly(
+			"ann@example.com/rm\n",
+			"ann@example.com/rm/perm\n",
+			"ann@example.com/rm/perm/Access\n",
+			"ann@example.com/rm/perm/keep\n",
+			"ann@example.com/rm/perm/keep/Access\n",
+			"ann@example.com/rm/perm/keep/kept\n",
+			"ann@example.com/rm/target\n",
+		),
 	},
 }
@@ -0,0 +1,97 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"io"
+	"sync"
+	"time"
+)
+
+// bwlimitFlag sets a "-bwlimit=0" flag in the FlagSet.
+func bwlimitFlag(fs *flag.FlagSet) *int64 {
+	return fs.Int64("bwlimit", 0, "limit data transfer to `bytes` per second (0 means no limit)")
+}
+
+// rateLimiter is a token bucket that paces data transfers to a fixed number
+// of bytes per second. A single rateLimiter may be shared by concurrent
+// transfers, in which case the limit applies to their total.
+// A nil *rateLimiter imposes no limit.
+type rateLimiter struct {
+	rate int64 // Bytes per second; also the size of the bucket.
+
+	// For testing.
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	mu     sync.Mutex
+	tokens float64   // Bytes that may be transferred without waiting; may be negative.
+	last   time.Time // When tokens was last updated.
+}
+
+// newRateLimiter returns a rateLimiter for the given number of bytes per
+// second, or nil if bytesPerSec is not positive. The bucket starts empty,
+// so even the first bytes transferred are paced.
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:  bytesPerSec,
+		now:   time.Now,
+		sleep: time.Sleep,
+		last:  time.Now(),
+	}
+}
+
+// wait blocks until n more bytes may be transferred.
+func (l *rateLimiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if max := float64(l.rate); l.tokens > max {
+		l.tokens = max
+	}
+	l.last = now
+	// Take the tokens now, even if that leaves a deficit, so that
+	// concurrent callers queue up behind us rather than racing.
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+	if deficit > 0 {
+		l.sleep(time.Duration(deficit / float64(l.rate) * float64(time.Second)))
+	}
+}
+
+// Reader returns a Reader that reads from r no faster than the limit.
+// If l is nil, it returns r.
+func (l *rateLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{r: r, l: l}
+}
+
+// limitedReader is an io.Reader whose reads are paced by a rateLimiter.
+type limitedReader struct {
+	r io.Reader
+	l *rateLimiter
+}
+
+// Read implements io.Reader.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	// Read no more than a second's worth at a time,
+	// so the transfer proceeds smoothly.
+	if int64(len(p)) > r.l.rate {
+		p = p[:r.l.rate]
+	}
+	n, err := r.r.Read(p)
+	r.l.wait(n)
+	return n, err
+}
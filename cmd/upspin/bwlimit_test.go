@@ -0,0 +1,169 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"upspin.io/subcmd"
+	"upspin.io/upspin"
+)
+
+func TestRateLimiterPacing(t *testing.T) {
+	start := time.Now()
+	now := start
+	var slept time.Duration
+	l := newRateLimiter(1000)
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+	l.last = start
+
+	l.wait(500)
+	if slept != 500*time.Millisecond {
+		t.Fatalf("after 500 bytes slept %v, want 500ms", slept)
+	}
+	l.wait(1500)
+	if slept != 2*time.Second {
+		t.Fatalf("after 2000 bytes slept %v, want 2s", slept)
+	}
+	// An idle period refills the bucket, but only up to a second's worth.
+	now = now.Add(10 * time.Second)
+	slept = 0
+	l.wait(1000)
+	if slept != 0 {
+		t.Fatalf("after idle period slept %v, want 0", slept)
+	}
+	l.wait(100)
+	if slept != 100*time.Millisecond {
+		t.Fatalf("after draining bucket slept %v, want 100ms", slept)
+	}
+}
+
+func TestRateLimiterNil(t *testing.T) {
+	if l := newRateLimiter(0); l != nil {
+		t.Fatalf("newRateLimiter(0) = %v, want nil", l)
+	}
+	var l *rateLimiter
+	r := bytes.NewReader([]byte("data"))
+	if got := l.Reader(r); got != io.Reader(r) {
+		t.Fatalf("nil limiter wrapped the reader")
+	}
+	l.wait(100) // Must not block or panic.
+}
+
+func TestLimitedCopy(t *testing.T) {
+	const (
+		rate = 10000
+		size = 3000
+	)
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	l := newRateLimiter(rate)
+	var b bytes.Buffer
+	start := time.Now()
+	if _, err := io.Copy(&b, l.Reader(bytes.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed, want := time.Since(start), size*time.Second/rate; elapsed < want {
+		t.Errorf("copy took %v, want at least %v", elapsed, want)
+	}
+	if !bytes.Equal(b.Bytes(), data) {
+		t.Errorf("copied data differs from original")
+	}
+}
+
+func TestLimitedCopyShared(t *testing.T) {
+	const (
+		rate    = 10000
+		size    = 1000
+		copiers = 3
+	)
+	data := bytes.Repeat([]byte("x"), size)
+	l := newRateLimiter(rate)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < copiers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var b bytes.Buffer
+			if _, err := io.Copy(&b, l.Reader(bytes.NewReader(data))); err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(b.Bytes(), data) {
+				t.Error("copied data differs from original")
+			}
+		}()
+	}
+	wg.Wait()
+	// The limit applies to the total.
+	if elapsed, want := time.Since(start), copiers*size*time.Second/rate; elapsed < want {
+		t.Errorf("copies took %v, want at least %v", elapsed, want)
+	}
+}
+
+// uploadClient is an upspin.Client that records when the last of the data
+// given to PutReader arrives.
+type uploadClient struct {
+	upspin.Client
+	now  func() time.Time
+	data []byte
+	last time.Time
+}
+
+func (c *uploadClient) PutReader(name upspin.PathName, r io.Reader) (*upspin.DirEntry, error) {
+	buf := make([]byte, 100)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			c.data = append(c.data, buf[:n]...)
+			c.last = c.now()
+		}
+		if err == io.EOF {
+			return &upspin.DirEntry{Name: name}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func TestLimitedUpload(t *testing.T) {
+	const (
+		rate = 1000
+		size = 3000
+	)
+	start := time.Now()
+	now := start
+	l := newRateLimiter(rate)
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) { now = now.Add(d) }
+	l.last = start
+
+	c := &uploadClient{now: l.now}
+	s := &State{State: &subcmd.State{Client: c}}
+	cs := &copyState{state: s, overwrite: true, limit: l}
+	data := bytes.Repeat([]byte("x"), size)
+	src := cpFile{path: "/local/file"}
+	dst := cpFile{path: "ann@example.com/file", isUpspin: true}
+	s.copyToFile(cs, io.NopCloser(bytes.NewReader(data)), src, dst)
+
+	if !bytes.Equal(c.data, data) {
+		t.Fatalf("uploaded %d bytes that differ from the %d copied", len(c.data), len(data))
+	}
+	// The data reaches the client as it is read, not all at once.
+	if got, want := c.last.Sub(start), size*time.Second/rate; got < want-time.Millisecond {
+		t.Errorf("upload finished after %v, want at least %v", got, want)
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"flag"
 	"fmt"
 	"io"
@@ -14,18 +15,37 @@ import (
 	"strings"
 	"testing"
 
+	"upspin.io/bind"
+	"upspin.io/client"
+	"upspin.io/client/clientutil"
+	"upspin.io/config"
+	"upspin.io/factotum"
+	"upspin.io/pack"
 	"upspin.io/upbox"
 	"upspin.io/upspin"
 )
 
 var allCmdTests = []*[]cmdTest{
 	&basicCmdTests,
+	&completeTests,
+	&accessTests,
+	&convertTests,
 	&cpTests,
+	&dryRunTests,
+	&duTests,
+	&findTests,
 	&globTests,
+	&historyTests,
+	&infoTests,
 	&keygenTests,
 	&lsTests,
+	&mkdirTests,
+	&mvTests,
+	&rmTests,
 	&shareTests,
+	&shareGroupTests,
 	&suffixedUserTests,
+	&verifyTests,
 }
 
 // TestCommands runs the tests defined in cmdTests as subtests.
@@ -197,6 +217,19 @@ func expectNoOutput() func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr
 	}
 }
 
+// expectExactly is a post function that verifies that standard output
+// consists of the given lines, in order, and nothing else.
+func expectExactly(lines ...string) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		if want := strings.Join(lines, ""); stdout != want {
+			t.Fatalf("%q: output:\n%s\nwant:\n%s", cmd.name, stdout, want)
+		}
+	}
+}
+
 // fail is a post function that verifies that standard error contains the text of errStr.
 func fail(errStr string) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
 	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
@@ -242,6 +275,114 @@ func putFile(user upspin.UserName, name, contents string) cmdTest {
 	}
 }
 
+// deleteBlocks is a post function. It returns a function that deletes from
+// the store server all the blocks of the named file, which must be visible to
+// the user running the test. The deletions are made as the store server's own
+// user, since only it may delete blocks.
+func deleteBlocks(name upspin.PathName) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		cfg, err := config.FromFile(r.config(cmd.user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := client.New(cfg).Lookup(name, true)
+		if err != nil {
+			t.Fatalf("%q: %v", cmd.name, err)
+		}
+		storeCfg, err := config.FromFile(r.config("storeserver@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range entry.Blocks {
+			store, err := bind.StoreServer(storeCfg, b.Location.Endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Delete(b.Location.Reference); err != nil {
+				t.Fatalf("%q: %v", cmd.name, err)
+			}
+		}
+	}
+}
+
+// expectRawStore is a post function that verifies that the output of info
+// -raw-store reports the length and hash of the stored data for each block
+// of the named file, and that for an ee-packed file these describe the
+// ciphertext, not the cleartext.
+func expectRawStore(name upspin.PathName, cleartext string) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		cfg, err := config.FromFile(r.config(cmd.user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := client.New(cfg).Lookup(name, true)
+		if err != nil {
+			t.Fatalf("%q: %v", cmd.name, err)
+		}
+		if entry.Packing != upspin.EEPack {
+			t.Fatalf("%q: %s has packing %s, want ee", cmd.name, name, entry.Packing)
+		}
+		if !strings.HasPrefix(stdout, string(name)+"\n") && !strings.Contains(stdout, "\n"+string(name)+"\n") {
+			t.Fatalf("%q: output does not list %q:\n%s", cmd.name, name, stdout)
+		}
+		clearSum := fmt.Sprintf("%x", sha256.Sum256([]byte(cleartext)))
+		if strings.Contains(stdout, clearSum) {
+			t.Errorf("%q: output contains hash of cleartext:\n%s", cmd.name, stdout)
+		}
+		for i, b := range entry.Blocks {
+			data, err := clientutil.ReadLocation(cfg, b.Location)
+			if err != nil {
+				t.Fatalf("%q: block %d: %v", cmd.name, i, err)
+			}
+			if string(data) == cleartext {
+				t.Errorf("%q: block %d is stored as cleartext", cmd.name, i)
+			}
+			// The ee packing uses a stream cipher, so the stored
+			// length equals the cleartext length.
+			want := fmt.Sprintf(" %d %d %d %x ", i, b.Size, len(data), sha256.Sum256(data))
+			if !strings.Contains(strings.Join(strings.Fields(stdout), " ")+" ", want) {
+				t.Errorf("%q: block %d: output does not contain %q:\n%s", cmd.name, i, want, stdout)
+			}
+		}
+	}
+}
+
+// expectSharedBlocks is a post function that verifies that the command
+// succeeded and that the named files are stored in the same blocks.
+func expectSharedBlocks(names ...upspin.PathName) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		cfg, err := config.FromFile(r.config(cmd.user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var first []upspin.DirBlock
+		for i, name := range names {
+			entry, err := client.New(cfg).Lookup(name, true)
+			if err != nil {
+				t.Fatalf("%q: %v", cmd.name, err)
+			}
+			if i == 0 {
+				first = entry.Blocks
+				continue
+			}
+			if len(entry.Blocks) != len(first) {
+				t.Fatalf("%q: %s has %d blocks, %s has %d", cmd.name, name, len(entry.Blocks), names[0], len(first))
+			}
+			for j, b := range entry.Blocks {
+				if b.Location != first[j].Location {
+					t.Errorf("%q: %s block %d is at %v, %s block is at %v", cmd.name, name, j, b.Location, names[0], first[j].Location)
+				}
+			}
+		}
+	}
+}
+
 // testTempDir creates, if not already present, a temporary directory
 // with basename dir. It panics if it does not exist and cannot be created.
 func testTempDir(dir string, keepOld bool) string {
@@ -282,6 +423,175 @@ func keygenVerify(dir, public, secret, secret2 string, persist bool) func(t *tes
 	}
 }
 
+// keygenNewPublicVerify is a post function for keygen -quiet -print-new-public.
+// It verifies that nothing but the public key stored in dir, which must
+// begin with prefix, was printed.
+func keygenNewPublicVerify(dir, prefix string) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		defer os.RemoveAll(dir)
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		public, err := os.ReadFile(filepath.Join(dir, "public.upspinkey"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stdout != string(public) {
+			t.Fatalf("%q: printed key %q, want %q", cmd.name, stdout, public)
+		}
+		if !strings.HasPrefix(stdout, prefix) {
+			t.Fatalf("%q: printed key %q, want prefix %q", cmd.name, stdout, prefix)
+		}
+	}
+}
+
+// keygenPrintVerify is a post function that verifies the output of
+// keygen -print-public or, if fingerprint is set, keygen -fingerprint.
+// The fingerprint must also be among the reader hashes that the ee
+// packer recorded for @/fingerprint.
+func keygenPrintVerify(fingerprint bool) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		pub := r.state.Config.Factotum().PublicKey()
+		if !fingerprint {
+			if stdout != string(pub) {
+				t.Fatalf("%q: printed key %q, want %q", cmd.name, stdout, pub)
+			}
+			return
+		}
+		hash := factotum.KeyHash(pub)
+		if want := fmt.Sprintf("%x\n", hash); stdout != want {
+			t.Fatalf("%q: printed fingerprint %q, want %q", cmd.name, stdout, want)
+		}
+		entry, err := r.state.Client.Lookup(upspin.PathName(r.state.Config.UserName())+"/fingerprint", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes, err := pack.Lookup(upspin.EEPack).ReaderHashes(entry.Packdata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hashes {
+			if bytes.Equal(h, hash) {
+				return
+			}
+		}
+		t.Fatalf("%q: fingerprint %x not among reader hashes %x", cmd.name, hash, hashes)
+	}
+}
+
+// expectArchiveWriters is a post function that verifies that the command
+// succeeded and that the archive written by convert to the local file
+// records the given writer for each named entry.
+func expectArchiveWriters(file string, writers map[upspin.PathName]upspin.UserName) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		ar, err := newArchiveReader(f)
+		if err != nil {
+			t.Fatalf("%q: %v", cmd.name, err)
+		}
+		found := 0
+		for {
+			entry, err := ar.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%q: %v", cmd.name, err)
+			}
+			if !entry.IsDir() && !entry.IsLink() {
+				r, err := ar.contents()
+				if err != nil {
+					t.Fatalf("%q: %v", cmd.name, err)
+				}
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					t.Fatalf("%q: %s: %v", cmd.name, entry.Name, err)
+				}
+			}
+			want, ok := writers[entry.Name]
+			if !ok {
+				continue
+			}
+			found++
+			if entry.Writer != want {
+				t.Errorf("%q: %s has writer %s, want %s", cmd.name, entry.Name, entry.Writer, want)
+			}
+		}
+		if found != len(writers) {
+			t.Errorf("%q: found %d of the %d entries in the archive", cmd.name, found, len(writers))
+		}
+	}
+}
+
+// expectSameTree is a post function that verifies that the command
+// succeeded and that the tree under copy has the same entries as the tree
+// under orig, with the same packing, modification times, link targets,
+// and contents.
+func expectSameTree(orig, copy upspin.PathName) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+		if stderr != "" {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		c := r.state.Client
+		origEntries, err := c.Glob(upspin.AllFilesGlob(orig))
+		if err != nil {
+			t.Fatal(err)
+		}
+		copyEntries, err := c.Glob(upspin.AllFilesGlob(copy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(origEntries) != len(copyEntries) {
+			t.Fatalf("%q: %s has %d entries, %s has %d", cmd.name, orig, len(origEntries), copy, len(copyEntries))
+		}
+		for i, o := range origEntries {
+			e := copyEntries[i]
+			if want := copy + o.Name[len(orig):]; e.Name != want {
+				t.Fatalf("%q: got entry %s, want %s", cmd.name, e.Name, want)
+			}
+			switch {
+			case o.IsDir():
+				if !e.IsDir() {
+					t.Fatalf("%q: %s is not a directory", cmd.name, e.Name)
+				}
+				expectSameTree(o.Name, e.Name)(t, r, cmd, "", "")
+				continue
+			case o.IsLink():
+				if !e.IsLink() || e.Link != o.Link {
+					t.Errorf("%q: %s links to %q, want %q", cmd.name, e.Name, e.Link, o.Link)
+				}
+			default:
+				if e.Packing != o.Packing {
+					t.Errorf("%q: %s has packing %s, want %s", cmd.name, e.Name, e.Packing, o.Packing)
+				}
+				oData, err := c.Get(o.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				eData, err := c.Get(e.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(oData, eData) {
+					t.Errorf("%q: %s contains %q, want %q", cmd.name, e.Name, eData, oData)
+				}
+			}
+			if e.Time != o.Time {
+				t.Errorf("%q: %s has time %v, want %v", cmd.name, e.Name, e.Time, o.Time)
+			}
+		}
+	}
+}
+
 func keyVerify(t *testing.T, name, prefix string) {
 	key, err := os.ReadFile(name)
 	if err != nil {
@@ -13,10 +13,26 @@ Config prints to standard output the contents of the current config file.
 It works by saving the file at initialization time, so if the actual
 file has changed since the command started, it will still show the
 configuration being used.
+
+With the -check flag, config instead checks the configuration being
+used, as the doctor command does: that the keys it names exist and match
+those registered with the key server, and that the servers it names can
+be reached. It reports the result of each check and changes nothing.
 `
 	fs := flag.NewFlagSet("config", flag.ExitOnError)
 	outFile := fs.String("out", "", "output file (default standard output)")
-	s.ParseFlags(fs, args, help, "config [-out=outputfile]")
+	check := fs.Bool("check", false, "check the configuration instead of printing it")
+	s.ParseFlags(fs, args, help, "config [-out=outputfile] [-check]")
+	if fs.NArg() != 0 {
+		usageAndExit(fs)
+	}
 
+	if *check {
+		if *outFile != "" {
+			s.Exitf("cannot use -out with -check")
+		}
+		s.diagnose(s.configFile, nil)
+		return
+	}
 	s.writeOut(*outFile, s.configFile)
 }
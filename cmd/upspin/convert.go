@@ -0,0 +1,358 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+
+	"upspin.io/access"
+	"upspin.io/client"
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/pack"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+func (s *State) convert(args ...string) {
+	const help = `
+Convert copies an Upspin tree to or from a local file in another format.
+The only format at present is "archive", a single file holding the
+directory entries of the tree, including links, together with the
+cleartext contents of its files.
+
+With -to archive, convert writes the tree rooted at the Upspin path
+to the local file. Unlike tar, the archive records each entry's
+metadata as stored by the directory server, including its Writer,
+Packing, and modification time.
+
+With -from archive, convert recreates the tree held in the local file
+under the Upspin path given by the -prefix flag. Files are packed
+again with their original packing, and files and links are given their
+original modification times. Everything is written by the current user,
+whatever its original Writer; use -v to see the recorded writers. Link
+targets are restored unchanged. As with tar, Access files are written
+last.
+`
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "", "convert the Upspin tree to the local file in the given `format`")
+	from := fs.String("from", "", "convert the local file in the given `format` to an Upspin tree")
+	prefix := fs.String("prefix", "", "Upspin `path` under which to recreate the tree with -from")
+	verbose := fs.Bool("v", false, "verbose output")
+	s.ParseFlags(fs, args, help, "convert -to archive upspin_path local_file\n       upspin convert -from archive -prefix upspin_path local_file")
+	switch {
+	case *to != "" && *from == "":
+		if fs.NArg() != 2 || *prefix != "" {
+			usageAndExit(fs)
+		}
+		s.checkConvertFormat(*to)
+		root := s.GlobOneUpspinPath(fs.Arg(0))
+		file := s.GlobOneLocal(fs.Arg(1))
+		if err := s.exportArchive(root, s.CreateLocal(file), *verbose); err != nil {
+			s.Exit(err)
+		}
+	case *from != "" && *to == "":
+		if fs.NArg() != 1 || *prefix == "" {
+			usageAndExit(fs)
+		}
+		s.checkConvertFormat(*from)
+		dest, err := path.Parse(s.AtSign(*prefix))
+		if err != nil {
+			s.Exit(err)
+		}
+		if err := s.importArchive(s.OpenLocal(s.GlobOneLocal(fs.Arg(0))), dest.Path(), *verbose); err != nil {
+			s.Exit(err)
+		}
+	default:
+		usageAndExit(fs)
+	}
+}
+
+// archiveFormat is the name of the format written and read by
+// exportArchive and importArchive.
+const archiveFormat = "archive"
+
+// archiveMagic begins every archive file. The version number
+// is to be incremented if the layout of the archive changes.
+//
+// After the magic string, the archive holds one record for each entry
+// in the tree, starting with the root. A record is the marshaled
+// DirEntry, preceded by its length as an unsigned varint. The record
+// for a plain file is followed by the length of its cleartext contents,
+// also as an unsigned varint, and the contents themselves.
+const archiveMagic = "upspin archive 1\n"
+
+func (s *State) checkConvertFormat(format string) {
+	if format != archiveFormat {
+		s.Exitf("unknown format %q; available formats: %s", format, archiveFormat)
+	}
+}
+
+// exportArchive writes the tree rooted at root to dst in archive format.
+func (s *State) exportArchive(root upspin.PathName, dst io.WriteCloser, verbose bool) error {
+	entry, err := s.Client.Lookup(root, false)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	w := bufio.NewWriter(dst)
+	_, err = w.WriteString(archiveMagic)
+	if err == nil {
+		err = s.exportEntry(w, entry, verbose)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// exportEntry writes the record for entry, and for a directory the
+// records for its contents, to w.
+func (s *State) exportEntry(w *bufio.Writer, entry *upspin.DirEntry, verbose bool) error {
+	if verbose {
+		fmt.Fprintf(s.Stderr, "Archiving %q\n", entry.Name)
+	}
+	b, err := entry.Marshal()
+	if err != nil {
+		return err
+	}
+	writeUvarint(w, uint64(len(b)))
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	switch {
+	case entry.IsDir():
+		entries, err := s.Client.Glob(upspin.AllFilesGlob(entry.Name))
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := s.exportEntry(w, e, verbose); err != nil {
+				return err
+			}
+		}
+	case entry.IsLink():
+		// The target is in the DirEntry.
+	default:
+		size, err := entry.Size()
+		if err != nil {
+			return err
+		}
+		f, err := s.Client.Open(entry.Name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		writeUvarint(w, uint64(size))
+		n, err := io.Copy(w, f)
+		if err != nil {
+			return err
+		}
+		if n != size {
+			return errors.E(entry.Name, errors.IO, errors.Errorf("read %d bytes, expected %d", n, size))
+		}
+	}
+	return nil
+}
+
+func writeUvarint(w *bufio.Writer, x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], x)])
+}
+
+// archiveReader reads the records of an archive.
+type archiveReader struct {
+	r *bufio.Reader
+}
+
+// newArchiveReader checks that src holds an archive and returns
+// a reader for its records.
+func newArchiveReader(src io.Reader) (*archiveReader, error) {
+	r := bufio.NewReader(src)
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != archiveMagic {
+		return nil, errors.E(errors.Invalid, errors.Str("not an Upspin archive"))
+	}
+	return &archiveReader{r: r}, nil
+}
+
+// next returns the next entry in the archive, or io.EOF at the end.
+// If the entry is a plain file, its contents must be read with
+// contents before next is called again.
+func (a *archiveReader) next() (*upspin.DirEntry, error) {
+	n, err := binary.ReadUvarint(a.r)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, errArchiveTruncated
+	}
+	if n > upspin.MaxBlockSize {
+		return nil, errors.E(errors.Invalid, errors.Str("archive entry too large"))
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(a.r, b); err != nil {
+		return nil, errArchiveTruncated
+	}
+	entry := new(upspin.DirEntry)
+	if _, err := entry.Unmarshal(b); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// contents returns a reader for the contents of the plain file
+// whose entry was just returned by next.
+func (a *archiveReader) contents() (io.Reader, error) {
+	n, err := binary.ReadUvarint(a.r)
+	if err != nil {
+		return nil, errArchiveTruncated
+	}
+	return &exactReader{r: io.LimitReader(a.r, int64(n)), n: int64(n)}, nil
+}
+
+var errArchiveTruncated = errors.E(errors.Invalid, errors.Str("archive is truncated"))
+
+// exactReader reads exactly n bytes from r and reports
+// errArchiveTruncated if r ends early.
+type exactReader struct {
+	r io.Reader
+	n int64
+}
+
+func (e *exactReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	e.n -= int64(n)
+	if err == io.EOF && e.n > 0 {
+		err = errArchiveTruncated
+	}
+	return n, err
+}
+
+// importArchive recreates under dest the tree archived in src.
+func (s *State) importArchive(src io.ReadCloser, dest upspin.PathName, verbose bool) error {
+	defer src.Close()
+	ar, err := newArchiveReader(src)
+	if err != nil {
+		return err
+	}
+
+	// Access files are written last, to prevent being locked out
+	// from restoring the rest of the tree.
+	type accessFile struct {
+		name     upspin.PathName
+		packing  upspin.Packing
+		contents []byte
+	}
+	var acc []accessFile
+
+	// clients holds a client for each packing in the archive.
+	clients := make(map[upspin.Packing]upspin.Client)
+	clientFor := func(p upspin.Packing) upspin.Client {
+		if pack.Lookup(p) == nil {
+			// Not known to this binary; use the default.
+			return s.Client
+		}
+		c, ok := clients[p]
+		if !ok {
+			c = client.New(config.SetPacking(s.Config, p))
+			clients[p] = c
+		}
+		return c
+	}
+
+	var root upspin.PathName
+	for {
+		entry, err := ar.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if root == "" {
+			root = entry.Name
+		}
+		name, err := rebaseArchiveName(root, entry.Name, dest)
+		if err != nil {
+			return err
+		}
+		if verbose {
+			fmt.Fprintf(s.Stderr, "Extracting %q into %q (writer %s, packing %s)\n", entry.Name, name, entry.Writer, entry.Packing)
+		}
+
+		switch {
+		case entry.IsDir():
+			_, err = s.Client.MakeDirectory(name)
+			if err != nil && !errors.Is(errors.Exist, err) {
+				return err
+			}
+		case entry.IsLink():
+			if _, err := s.Client.PutLink(entry.Link, name); err != nil {
+				return err
+			}
+			if err := s.Client.SetTime(name, entry.Time); err != nil {
+				return err
+			}
+		default:
+			r, err := ar.contents()
+			if err != nil {
+				return err
+			}
+			if access.IsAccessFile(name) {
+				buf, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				acc = append(acc, accessFile{name: name, packing: entry.Packing, contents: buf})
+				continue
+			}
+			c := clientFor(entry.Packing)
+			f, err := c.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, r); err != nil {
+				f.Close()
+				c.Delete(name)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			if err := c.SetTime(name, entry.Time); err != nil {
+				return err
+			}
+		}
+	}
+	if root == "" {
+		return errors.E(errors.Invalid, errors.Str("archive is empty"))
+	}
+
+	for _, af := range acc {
+		c := clientFor(af.packing)
+		if _, err := c.Put(af.name, af.contents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebaseArchiveName returns the name that the archived entry called name,
+// which must be root or lie beneath it, has when the tree is recreated under dest.
+func rebaseArchiveName(root, name, dest upspin.PathName) (upspin.PathName, error) {
+	rel, err := path.Rel(root, name)
+	if err != nil {
+		return "", errors.E(name, errors.Invalid, errors.Errorf("archived entry is not within %s", root))
+	}
+	return path.Join(dest, rel), nil
+}
@@ -5,13 +5,16 @@
 package main
 
 import (
+	"crypto/sha256"
 	"flag"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"upspin.io/client"
 	"upspin.io/config"
 	"upspin.io/errors"
 	"upspin.io/path"
@@ -38,12 +41,60 @@ they must be absolute paths or start with '.', '..',  or '~'.
 When copying from one Upspin path to another Upspin path, cp can be
 very efficient, copying only the references to the data rather than
 the data itself.
+
+The -parallel flag sets the number of files copied concurrently.
+Directories are always created before the files within them. A
+failure to copy one file is reported but does not stop the others.
+
+The -bwlimit flag limits the rate at which file contents are copied,
+in bytes per second. Fast copies within Upspin, which move no data,
+are not affected. The limit applies to the total of all concurrent
+copies. Data copied into Upspin is stored a block at a time as it is
+read, so uploads are paced too, though each block is sent at full speed.
+
+The -packing flag sets the packing used for data copied into Upspin,
+overriding the user's config. Fast copies keep their original packing.
+
+The -dedup-local flag makes cp hash each local file copied into Upspin
+and, when a file's contents are identical to those of one already copied,
+point the new Upspin name at the stored data rather than uploading it
+again. Since duplicates share the original's packing metadata, including
+any wrapped keys, it requires a packing that does not encrypt the data:
+plain, plainsum, or eeintegrity.
+
+The -n (or -dry-run) flag makes cp print, after expanding its arguments,
+the copies it would make and the directories it would create, without
+writing anything. Existing files that would be skipped because
+-overwrite=false are not listed.
 `
 	fs := flag.NewFlagSet("cp", flag.ExitOnError)
 	verbose := fs.Bool("v", false, "log each file as it is copied")
 	recur := fs.Bool("R", false, "recursively copy directories")
 	overwrite := fs.Bool("overwrite", true, "overwrite existing files")
+	bwlimit := bwlimitFlag(fs)
+	parallel := fs.Int("parallel", 4, "copy up to `n` files concurrently")
+	packing := fs.String("packing", "", "packing to use, one of "+packingNames()+" (default from user's config)")
+	dedup := fs.Bool("dedup-local", false, "upload identical local files only once")
+	dryRun := dryRunFlag(fs)
 	s.ParseFlags(fs, args, help, "cp [opts] file... file or cp [opts] file... directory")
+	if *parallel < 1 {
+		usageAndExit(fs)
+	}
+
+	cfg := s.Config
+	if *packing != "" {
+		cfg = config.SetPacking(cfg, s.packerByName(*packing).Packing())
+		prevClient := s.Client
+		s.Client = client.New(cfg)
+		defer func() { s.Client = prevClient }()
+	}
+	if *dedup {
+		switch cfg.Packing() {
+		case upspin.PlainPack, upspin.PlainSumPack, upspin.EEIntegrityPack:
+		default:
+			s.Exitf("-dedup-local requires plain, plainsum, or eeintegrity packing, not %s", cfg.Packing())
+		}
+	}
 
 	var err error
 	if home == "" {
@@ -59,6 +110,16 @@ the data itself.
 		overwrite: *overwrite,
 		recur:     *recur,
 		verbose:   *verbose,
+		dryRun:    *dryRun,
+		limit:     newRateLimiter(*bwlimit),
+		workers:   make(chan struct{}, *parallel),
+	}
+	if *dedup {
+		cs.dedup = &dedupState{files: make(map[[sha256.Size]byte]*dedupFile)}
+	}
+	if *dryRun {
+		// One at a time, so the copies are listed in order.
+		cs.workers = make(chan struct{}, 1)
 	}
 
 	// Do all the glob processing here.
@@ -75,6 +136,7 @@ the data itself.
 	nSrc := len(files) - 1
 	src, dest := files[:nSrc], files[nSrc]
 	s.copyCommand(cs, src, dest)
+	cs.wg.Wait()
 }
 
 type copyState struct {
@@ -83,6 +145,103 @@ type copyState struct {
 	overwrite bool
 	recur     bool
 	verbose   bool
+	dryRun    bool         // Print the copies but do not make them.
+	limit     *rateLimiter // Nil if there is no bandwidth limit.
+	dedup     *dedupState  // Nil unless -dedup-local is set.
+
+	// workers holds a token for each file being copied,
+	// bounding the number of concurrent copies.
+	workers chan struct{}
+	wg      sync.WaitGroup
+
+	mu sync.Mutex // Serializes reports of failures and printed output.
+}
+
+// fail reports the error and sets the exit code.
+// It is safe to call from concurrent copies.
+func (c *copyState) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Fail(err)
+}
+
+// printf prints to standard output.
+// It is safe to call from concurrent copies.
+func (c *copyState) printf(format string, args ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Printf(format, args...)
+}
+
+// goCopy runs fn, a copy of a single file, once fewer than the
+// maximum number of copies are in progress. It does not wait
+// for fn to complete; copyState.wg does.
+func (c *copyState) goCopy(fn func()) {
+	c.workers <- struct{}{}
+	c.wg.Add(1)
+	go func() {
+		defer func() {
+			<-c.workers
+			c.wg.Done()
+		}()
+		fn()
+	}()
+}
+
+// dedupState records, by the hash of their contents, the local files
+// copied into Upspin, so later copies of identical files can share them.
+type dedupState struct {
+	mu    sync.Mutex
+	files map[[sha256.Size]byte]*dedupFile
+}
+
+// A dedupFile is a local file being copied, or already copied, into Upspin.
+type dedupFile struct {
+	name upspin.PathName // The Upspin file holding the copy.
+	done chan struct{}   // Closed when the copy completes.
+	ok   bool            // Whether the copy succeeded. Set before done is closed.
+}
+
+// claim hashes the contents of the local file, which it then rewinds.
+// If no file with the same contents has been claimed, it records that the
+// contents are being copied to name and returns the new dedupFile with
+// first set; the caller must call its finish method once the copy is done.
+// Otherwise it returns the dedupFile holding the earlier copy.
+func (d *dedupState) claim(file io.ReadSeeker, name upspin.PathName) (f *dedupFile, first bool, err error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.files[sum]; ok {
+		return f, false, nil
+	}
+	f = &dedupFile{
+		name: name,
+		done: make(chan struct{}),
+	}
+	d.files[sum] = f
+	return f, true, nil
+}
+
+// finish records whether the copy succeeded, releasing any copies of
+// identical files waiting for it.
+func (f *dedupFile) finish(ok bool) {
+	f.ok = ok
+	close(f.done)
+}
+
+// wait waits for the copy to complete and reports whether it succeeded.
+func (f *dedupFile) wait() bool {
+	<-f.done
+	return f.ok
 }
 
 func (c *copyState) logf(format string, args ...interface{}) {
@@ -183,8 +342,9 @@ func (s *State) create(file cpFile) (io.WriteCloser, error) {
 func (s *State) copyToDir(cs *copyState, src []cpFile, dir cpFile) {
 	for _, from := range src {
 		dstPath := path.Join(upspin.PathName(dir.path), filepath.Base(from.path))
-		if dir.isUpspin && from.isUpspin {
+		if dir.isUpspin && from.isUpspin && cs.copier() == nil && !cs.dryRun {
 			// Try a fast copy. It can fail but that's OK.
+			// A Copier is instead used by copyToFile, concurrently.
 			cs.logf("try fast copy to %s", dstPath)
 			if s.fastCopy(upspin.PathName(from.path), dstPath) == nil {
 				continue
@@ -204,62 +364,143 @@ func (s *State) copyToDir(cs *copyState, src []cpFile, dir cpFile) {
 			if dir.isUpspin {
 				// Rather than use the libraries and a lot of casting, it's easiest just to cat the strings here.
 				subDir.path = subDir.path + "/" + filepath.Base(from.path) // TODO: is filepath.Base OK?
-				_, err := s.Client.MakeDirectory(upspin.PathName(subDir.path))
-				if err != nil && !errors.Is(errors.Exist, err) {
-					s.Fail(err)
-					continue
-				}
 			} else {
 				subDir.path = filepath.Join(subDir.path, filepath.Base(from.path))
-				err := os.Mkdir(subDir.path, 0755) // TODO: Mode.
-				if err != nil && !os.IsExist(err) {
-					s.Fail(err)
-					continue
-				}
+			}
+			if err := s.makeDir(cs, subDir); err != nil {
+				cs.fail(err)
+				continue
 			}
 			s.copyToDir(cs, newFiles, subDir)
 			continue
 		}
 		if err != nil {
-			s.Fail(err)
+			cs.fail(err)
 			continue
 		}
 		dst := cpFile{
 			path:     string(dstPath),
 			isUpspin: dir.isUpspin,
 		}
-		s.copyToFile(cs, reader, from, dst)
+		from := from
+		cs.goCopy(func() {
+			s.copyToFile(cs, reader, from, dst)
+		})
 	}
 }
 
+// makeDir creates the directory if it does not already exist,
+// or, with -n, reports that it would.
+func (s *State) makeDir(cs *copyState, dir cpFile) error {
+	if cs.dryRun {
+		ok, err := s.exists(dir)
+		if err == nil && !ok {
+			cs.printf("mkdir %s\n", dir.path)
+		}
+		return err
+	}
+	if dir.isUpspin {
+		_, err := s.Client.MakeDirectory(upspin.PathName(dir.path))
+		if errors.Is(errors.Exist, err) {
+			return nil
+		}
+		return err
+	}
+	err := os.Mkdir(dir.path, 0755) // TODO: Mode.
+	if os.IsExist(err) {
+		return nil
+	}
+	return err
+}
+
 // copyToFile copies the source to the destination. The source file has already been opened.
+// It may be called concurrently for different files.
 func (s *State) copyToFile(cs *copyState, reader io.ReadCloser, src, dst cpFile) {
 	if !cs.overwrite {
 		if ok, err := s.exists(dst); err != nil {
-			s.Exit(err)
+			cs.fail(err)
+			reader.Close()
+			return
 		} else if ok {
+			reader.Close()
 			return
 		}
 	}
+	if cs.dryRun {
+		cs.printf("cp %s %s\n", src.path, dst.path)
+		reader.Close()
+		return
+	}
 	cs.logf("start cp %s %s", src.path, dst.path)
 	defer cs.logf("end cp %s %s", src.path, dst.path)
 	// If both are in Upspin, we can avoid touching the data by copying
 	// just the references.
-	if src.isUpspin && dst.isUpspin {
+	if c := cs.copier(); c != nil && src.isUpspin && dst.isUpspin {
+		cs.logf("copy within Upspin to %v", dst)
+		_, err := c.Copy(upspin.PathName(src.path), upspin.PathName(dst.path))
+		if !errors.Is(errors.Exist, err) {
+			reader.Close()
+			if err != nil {
+				cs.fail(err)
+			}
+			return
+		}
+		// Copy does not overwrite, so copy the data here.
+	} else if src.isUpspin && dst.isUpspin {
 		cs.logf("try fast copy to %v", dst)
 		err := s.fastCopy(upspin.PathName(src.path), upspin.PathName(dst.path))
 		if err == nil {
+			reader.Close()
 			return
 		}
-		s.Fail(err) // Failed at fastCopy; but try normal copy.
+		cs.fail(err) // Failed at fastCopy; but try normal copy.
+	}
+	// With -dedup-local, a local file identical to one already
+	// copied into Upspin can share its data.
+	ok := false
+	if rs, isSeeker := reader.(io.ReadSeeker); cs.dedup != nil && !src.isUpspin && dst.isUpspin && isSeeker {
+		f, first, err := cs.dedup.claim(rs, upspin.PathName(dst.path))
+		switch {
+		case err != nil:
+			cs.fail(err)
+			reader.Close()
+			return
+		case first:
+			defer func() { f.finish(ok) }()
+		case f.wait():
+			_, err := s.Client.PutDuplicate(f.name, upspin.PathName(dst.path))
+			if err == nil {
+				cs.logf("%s duplicates %s", dst.path, f.name)
+				reader.Close()
+				return
+			}
+			// Copy the data after all, perhaps because dst exists.
+		}
+	}
+	if dst.isUpspin && cs.limit != nil {
+		// A file made by Create would hold the data until Close and
+		// then store it all unpaced, so stream it instead.
+		ok = cs.doPut(reader, upspin.PathName(dst.path))
+		return
 	}
 	writer, err := s.create(dst)
 	if err != nil {
-		s.Fail(err)
+		cs.fail(err)
 		reader.Close()
 		return
 	}
-	cs.doCopy(reader, writer)
+	ok = cs.doCopy(reader, writer)
+}
+
+// copier returns the client as an upspin.Copier, or nil if it is not one
+// or if there is a bandwidth limit, which Copy would not observe when it
+// must copy the data.
+func (cs *copyState) copier() upspin.Copier {
+	if cs.limit != nil {
+		return nil
+	}
+	c, _ := cs.state.Client.(upspin.Copier)
+	return c
 }
 
 // fastCopy copies the source to the destination using the references rather than the data.
@@ -284,18 +525,36 @@ func (s *State) fastCopy(src, dst upspin.PathName) error {
 	return nil
 }
 
-func (cs *copyState) doCopy(reader io.ReadCloser, writer io.WriteCloser) {
+// doCopy copies the data from reader to writer and closes them both.
+// It reports whether the copy succeeded.
+func (cs *copyState) doCopy(reader io.ReadCloser, writer io.WriteCloser) (ok bool) {
 	defer func() {
 		reader.Close()
 		err := writer.Close()
 		if err != nil {
-			cs.state.Fail(err)
+			cs.fail(err)
+			ok = false
 		}
 	}()
-	_, err := io.Copy(writer, reader)
+	_, err := io.Copy(writer, cs.limit.Reader(reader))
 	if err != nil {
-		cs.state.Fail(err)
+		cs.fail(err)
+		return false
 	}
+	return true
+}
+
+// doPut stores the data from reader, read no faster than the limit, in the
+// named Upspin file, and closes the reader. Each block is stored as soon as
+// it has been read, so the upload is paced too. It reports whether the
+// copy succeeded.
+func (cs *copyState) doPut(reader io.ReadCloser, name upspin.PathName) bool {
+	defer reader.Close()
+	if _, err := putReader(cs.state.Client, name, cs.limit.Reader(reader)); err != nil {
+		cs.fail(err)
+		return false
+	}
+	return true
 }
 
 // isLocal reports whether the argument names a fully-qualified local file.
@@ -361,7 +620,7 @@ func (s *State) contents(cs *copyState, dir cpFile) ([]cpFile, error) {
 	if dir.isUpspin {
 		entries, err := s.Client.Glob(upspin.AllFilesGlob(upspin.PathName(dir.path)))
 		if err != nil {
-			s.Fail(err)
+			cs.fail(err)
 			// OK to continue; there may still be files.
 		}
 		files := make([]cpFile, len(entries))
@@ -376,13 +635,13 @@ func (s *State) contents(cs *copyState, dir cpFile) ([]cpFile, error) {
 	// Local directory. We're descending into a directory here, so there can be no ~.
 	fd, err := os.Open(dir.path)
 	if err != nil {
-		s.Fail(err)
+		cs.fail(err)
 		return nil, err
 	}
 	defer fd.Close()
 	names, err := fd.Readdirnames(0)
 	if err != nil {
-		s.Fail(err)
+		cs.fail(err)
 		// OK to continue; there may still be files.
 	}
 	files := make([]cpFile, len(names))
@@ -0,0 +1,59 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDedupClaim(t *testing.T) {
+	d := &dedupState{files: make(map[[sha256.Size]byte]*dedupFile)}
+
+	orig, first, err := d.claim(strings.NewReader("same"), "ann@example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first {
+		t.Fatal("first claim: first = false")
+	}
+
+	// A file with the same contents finds the first copy.
+	r := strings.NewReader("same")
+	dup, first, err := d.claim(r, "ann@example.com/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first || dup != orig {
+		t.Fatalf("duplicate claim: got %v, first = %t; want original", dup, first)
+	}
+	// The file is rewound so it can still be copied.
+	if b, _ := io.ReadAll(r); string(b) != "same" {
+		t.Errorf("after claim, read %q, want %q", b, "same")
+	}
+
+	// A file with different contents does not.
+	other, first, err := d.claim(strings.NewReader("different"), "ann@example.com/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first || other == orig {
+		t.Fatal("claim of different contents found the original")
+	}
+
+	// Waiters see whether the original copy succeeded.
+	done := make(chan bool)
+	go func() { done <- dup.wait() }()
+	orig.finish(true)
+	if !<-done {
+		t.Error("wait = false after successful copy")
+	}
+	other.finish(false)
+	if other.wait() {
+		t.Error("wait = true after failed copy")
+	}
+}
@@ -48,18 +48,25 @@ Usage of upspin:
 	upspin [globalflags] <command> [flags] <path>
 Upspin commands:
 	shell (Interactive mode)
+	access
 	config
+	convert
 	countersign
 	cp
 	createsuffixeduser
 	deletestorage
+	doctor
+	du
+	find
 	get
 	getref
+	history
 	info
 	keygen
 	link
 	ls
 	mkdir
+	mv
 	put
 	repack
 	rm
@@ -73,6 +80,7 @@ Upspin commands:
 	snapshot
 	tar
 	user
+	verify
 	watch
 	whichaccess
 Global flags:
@@ -90,6 +98,32 @@ Global flags:
     	make storage cache writethrough
 
 
+Sub-command access
+
+Usage: upspin access test [-explain] user right path
+
+Access examines the effect of Access and Group files without changing
+them. At the moment its only subcommand is test.
+
+Access test reports whether the named user would have the named right
+to the path: read, write, list, create, delete, or any (meaning any of
+them). It evaluates the Access file that governs the path, loading any
+Group files it refers to, just as the directory server would, and
+prints yes or no. The path need not exist. It must be run by a user
+who can read the Access and Group files, typically their owner.
+
+With the -explain flag, access test also reports the Access file that
+was evaluated and, if the right is granted, how: because the user owns
+the tree, or through which entry or Group file.
+
+Flags:
+  -explain
+    	explain the result
+  -help
+    	print more information about the command
+
+
+
 Sub-command audit
 
 Audit provides subcommands for auditing storage consumption.
@@ -131,7 +165,7 @@ Commands: scan-dir, scan-store, find-garbage, delete-garbage
 
 Sub-command config
 
-Usage: upspin config [-out=outputfile]
+Usage: upspin config [-out=outputfile] [-check]
 
 Config prints to standard output the contents of the current config file.
 
@@ -139,7 +173,14 @@ It works by saving the file at initialization time, so if the actual
 file has changed since the command started, it will still show the
 configuration being used.
 
+With the -check flag, config instead checks the configuration being
+used, as the doctor command does: that the keys it names exist and match
+those registered with the key server, and that the servers it names can
+be reached. It reports the result of each check and changes nothing.
+
 Flags:
+  -check
+    	check the configuration instead of printing it
   -help
     	print more information about the command
   -out string
@@ -147,6 +188,42 @@ Flags:
 
 
 
+Sub-command convert
+
+Usage: upspin convert -to archive upspin_path local_file
+       upspin convert -from archive -prefix upspin_path local_file
+
+Convert copies an Upspin tree to or from a local file in another format.
+The only format at present is "archive", a single file holding the
+directory entries of the tree, including links, together with the
+cleartext contents of its files.
+
+With -to archive, convert writes the tree rooted at the Upspin path
+to the local file. Unlike tar, the archive records each entry's
+metadata as stored by the directory server, including its Writer,
+Packing, and modification time.
+
+With -from archive, convert recreates the tree held in the local file
+under the Upspin path given by the -prefix flag. Files are packed
+again with their original packing, and files and links are given their
+original modification times. Everything is written by the current user,
+whatever its original Writer; use -v to see the recorded writers. Link
+targets are restored unchanged. As with tar, Access files are written
+last.
+
+Flags:
+  -from format
+    	convert the local file in the given format to an Upspin tree
+  -help
+    	print more information about the command
+  -prefix path
+    	Upspin path under which to recreate the tree with -from
+  -to format
+    	convert the Upspin tree to the local file in the given format
+  -v	verbose output
+
+
+
 Sub-command countersign
 
 Usage: upspin countersign
@@ -183,12 +260,48 @@ When copying from one Upspin path to another Upspin path, cp can be
 very efficient, copying only the references to the data rather than
 the data itself.
 
+The -parallel flag sets the number of files copied concurrently.
+Directories are always created before the files within them. A
+failure to copy one file is reported but does not stop the others.
+
+The -bwlimit flag limits the rate at which file contents are copied,
+in bytes per second. Fast copies within Upspin, which move no data,
+are not affected. The limit applies to the total of all concurrent
+copies. Data copied into Upspin is stored a block at a time as it is
+read, so uploads are paced too, though each block is sent at full speed.
+
+The -packing flag sets the packing used for data copied into Upspin,
+overriding the user's config. Fast copies keep their original packing.
+
+The -dedup-local flag makes cp hash each local file copied into Upspin
+and, when a file's contents are identical to those of one already copied,
+point the new Upspin name at the stored data rather than uploading it
+again. Since duplicates share the original's packing metadata, including
+any wrapped keys, it requires a packing that does not encrypt the data:
+plain, plainsum, or eeintegrity.
+
+The -n (or -dry-run) flag makes cp print, after expanding its arguments,
+the copies it would make and the directories it would create, without
+writing anything. Existing files that would be skipped because
+-overwrite=false are not listed.
+
 Flags:
   -R	recursively copy directories
+  -bwlimit bytes
+    	limit data transfer to bytes per second (0 means no limit)
+  -dedup-local
+    	upload identical local files only once
+  -dry-run
+    	same as -n
   -help
     	print more information about the command
+  -n	print what would be done but do not do it
   -overwrite
     	overwrite existing files (default true)
+  -packing string
+    	packing to use, one of plain, plainsum, ee, eeintegrity (default from user's config)
+  -parallel n
+    	copy up to n files concurrently (default 4)
   -v	log each file as it is copied
 
 
@@ -264,6 +377,114 @@ Flags:
 
 
 
+Sub-command doctor
+
+Usage: upspin doctor
+
+Doctor checks the setup of the current user and suggests how to fix any
+problems it finds. It checks that the config file can be read and parsed,
+that the user's keys are present and match those registered with the key
+server, that the key, directory, and storage servers can be reached, and
+that the local clock agrees with the servers' clocks closely enough for
+authentication to succeed.
+
+Doctor talks to the servers directly, bypassing any cache server named
+in the config.
+
+Flags:
+  -help
+    	print more information about the command
+
+
+
+Sub-command du
+
+Usage: upspin du [-h] [-s] [-L] [path...]
+
+Du reports the space used by the named Upspin files and directory
+trees, by default the user's root. Sizes are computed from the blocks
+recorded in the directory entries; no data is read from the storage
+servers. Du prints the total for each directory, in bytes, after those
+for the directories within it, followed by the directory name. For
+a named file, it prints the size of the file.
+
+Directories that cannot be listed are skipped with a warning, as are
+files whose sizes are hidden because the user does not have read
+rights to them.
+
+Du does not follow links unless the -L flag is set, and even then it
+visits each directory once, so links that form loops are harmless.
+Links named on the command line are always followed.
+
+Flags:
+  -L	follow links
+  -h	print sizes in human-readable form, such as 1.5K or 23M
+  -help
+    	print more information about the command
+  -s	print only the total for each argument
+
+
+
+Sub-command find
+
+Usage: upspin find [-name=pattern] [-newer=duration] [-size=[+-]size] [-L] [-format=template] [path...]
+
+Find walks the named Upspin directory trees, by default the user's root,
+and prints the names of the entries, including the roots themselves,
+that satisfy all the conditions given by its flags. With no flags it
+prints every entry in the trees.
+
+The -name flag selects entries whose final path element matches the
+pattern, which has the syntax of path.Match; quote it to protect it
+from the shell, as in -name '*.jpg'.
+
+The -newer flag selects entries modified within the given duration
+before now, such as 36h or 10m.
+
+The -size flag selects files by their size, computed from the blocks
+recorded in their directory entries. The size is a number of bytes,
+optionally followed by K, M, G, or T for powers of 1024. With a leading
++ it selects larger files, with a leading - smaller files, and otherwise
+files of exactly that size. Directories and links never satisfy -size,
+nor do files whose sizes are hidden because the user does not have read
+rights to them.
+
+Directories that cannot be listed are skipped with a warning.
+
+Find does not follow links unless the -L flag is set, in which case a
+link is treated as its target. Even then it visits each directory once,
+so links that form loops are harmless. Links named on the command line
+are always followed.
+
+The -format flag takes a Go template (see https://golang.org/pkg/text/template)
+that is executed for each entry in place of the usual output, with a newline
+printed after each one. The template is applied to a structure with fields
+	Name     upspin.PathName // The full path name of the entry.
+	Size     int64           // The size of the file; zero if unknown.
+	Time     upspin.Time     // The time of the last change.
+	Writer   upspin.UserName // The user who last changed the entry.
+	Attr     string          // "none (plain file)", "directory", or "link".
+	Sequence int64           // The sequence number of the entry.
+	Packing  upspin.Packing  // The packing, such as "ee" or "plain".
+	Link     upspin.PathName // The target of a link; empty otherwise.
+For example, -format='{{.Name}} {{.Size}}' prints the name and size of
+each entry.
+
+Flags:
+  -L	follow links
+  -format template
+    	Go template for printing each entry
+  -help
+    	print more information about the command
+  -name pattern
+    	select entries whose final element matches the pattern
+  -newer duration
+    	select entries modified within the duration before now
+  -size size
+    	select files of size bytes; +size for larger, -size for smaller
+
+
+
 Sub-command get
 
 Usage: upspin get [-out=outputfile] path
@@ -274,7 +495,12 @@ The -glob flag can be set to false to have get skip Glob processing,
 treating its argument as literal text even if it contains special
 characters. (A leading @ sign is always expanded.)
 
+The -bwlimit flag limits the rate at which the contents are fetched,
+in bytes per second.
+
 Flags:
+  -bwlimit bytes
+    	limit data transfer to bytes per second (0 means no limit)
   -glob
     	apply glob processing to the arguments (default true)
   -help
@@ -302,9 +528,30 @@ Flags:
 
 
 
+Sub-command history
+
+Usage: upspin history path...
+
+History prints, oldest first, the recorded changes to each named path,
+including deletions, as reported by the directory server. For each
+change it shows the time, sequence number, operation, writer, and size
+of the file. It is the same as info -history.
+
+Since a deleted path may have a history, the arguments are not
+expanded as glob patterns. (Leading @ signs are always expanded.)
+
+The user must have some access right to the path. Not all directory
+servers record history; for those that do not, history says so.
+
+Flags:
+  -help
+    	print more information about the command
+
+
+
 Sub-command info
 
-Usage: upspin info path...
+Usage: upspin info [-R] [-history] [-format=template] [-json] [-raw-store] path...
 
 Info prints to standard output a thorough description of all the
 information about named paths, including information provided by
@@ -314,36 +561,112 @@ If the path names an Access or Group file, it is also checked for
 validity. If it is a link, the command attempts to access the target
 of the link.
 
+The -history flag instead prints, oldest first, the recorded changes
+to each named path, including deletions, as reported by the directory
+server. For each change it shows the time, sequence number, operation,
+writer, and size of the file. Not all directory servers support this.
+
+With -format, info prints only the fields selected by the template and
+does not check Access and Group files or follow links. The same is true
+of -json, which adds to the fields printed by ls -json the Access file
+that applies to the entry, as Access, and the name of the packer for its
+packing, as Packer.
+
+The -raw-store flag instead prints, for each block of each entry, its
+cleartext size recorded in the directory entry and the length and SHA-256
+hash of the data held by the store server. The stored data is not
+unpacked, so this works for any packing and allows the store's contents
+to be checked independently of the packer.
+
+The -format flag takes a Go template (see https://golang.org/pkg/text/template)
+that is executed for each entry in place of the usual output, with a newline
+printed after each one. The template is applied to a structure with fields
+	Name     upspin.PathName // The full path name of the entry.
+	Size     int64           // The size of the file; zero if unknown.
+	Time     upspin.Time     // The time of the last change.
+	Writer   upspin.UserName // The user who last changed the entry.
+	Attr     string          // "none (plain file)", "directory", or "link".
+	Sequence int64           // The sequence number of the entry.
+	Packing  upspin.Packing  // The packing, such as "ee" or "plain".
+	Link     upspin.PathName // The target of a link; empty otherwise.
+For example, -format='{{.Name}} {{.Size}}' prints the name and size of
+each entry.
+
+The -json flag prints each entry as a JSON object on a line of its own,
+suitable for processing by tools such as jq. The object holds the
+fields of the directory entry: Name, Attr, Sequence, Time, Size,
+Blocks (each with its Location, Offset, and Size), Writer, Packing,
+and, for links, Link.
+
 Flags:
   -R	recur into subdirectories
+  -format template
+    	Go template for printing each entry
   -help
     	print more information about the command
+  -history
+    	print the history of changes to each path
+  -json
+    	print each entry as JSON
+  -raw-store
+    	print the length and hash of each block as stored
 
 
 
 Sub-command keygen
 
-Usage: upspin keygen [-curve=256] [-secretseed=seed] <directory>
+Usage: upspin keygen [-curve=p256] [-secretseed=seed] [-print-new-public] [-quiet] -where=<directory> | <directory>
+       upspin keygen -print-public | -fingerprint
 
 Keygen creates a new Upspin key pair and stores the pair in local files
-secret.upspinkey and public.upspinkey in the specified directory.
-Existing key pairs are appended to secret2.upspinkey.
+secret.upspinkey and public.upspinkey in the specified directory, given
+either as the argument or with the -where flag.
+Existing key pairs are appended to secret2.upspinkey. With -rotate,
+the existing key files are first copied to a new subdirectory of
+backup in the same directory, named for the current time, and the new
+files are written in full before any existing file is replaced.
 Keygen does not update the information in the key server;
 use the "user -put" command for that.
 
+The -curve flag selects the elliptic curve of the new key: p256 (the
+default), p384, or p521.
+
+Keygen never prompts, so it may be run from scripts. The -print-new-public
+flag prints the new public key on standard output, ready to be used in a
+user record, and the -quiet flag suppresses the report on standard error,
+including the secret seed from which the keys can be re-created.
+
 New users should instead use the "signup" command to create their first key.
 
+The -print-public and -fingerprint flags instead report on the current
+key, the one held by the configured factotum, and take no directory.
+The -print-public flag prints the public key in the form stored in
+public.upspinkey and the key server. The -fingerprint flag prints in
+hexadecimal the SHA-256 hash of the public key, the same hash the ee
+packing uses to identify a file's readers, so that users exchanging
+keys can compare them easily.
+
 See the description for rotate for information about updating keys.
 
 Flags:
   -curve name
     	cryptographic curve name: p256, p384, or p521 (default "p256")
+  -fingerprint
+    	print the SHA-256 hash of the current public key and exit
   -help
     	print more information about the command
+  -print-new-public
+    	print the new public key on standard output
+  -print-public
+    	print the current public key and exit
+  -quiet
+    	do not report the new keys on standard error
   -rotate
     	back up the existing keys and replace them with new ones
   -secretseed string
     	the seed containing a 128-bit secret in proquint format or a file that contains it
+  -where directory
+    	directory to store the key pair
 
 
 
@@ -363,36 +686,78 @@ Flags:
 
 Sub-command ls
 
-Usage: upspin ls [-l] [path...]
+Usage: upspin ls [-l] [-format=template] [-json] [-dirs-first | -files-first] [path...]
 
 Ls lists the names and, if requested, other properties of Upspin
 files and directories. If given no path arguments, it lists the
 user's root. By default ls does not follow links; use the -L flag
 to learn about the targets of links.
 
+The -dirs-first and -files-first flags group the entries of each
+listing, printing directories before or after everything else.
+Links are grouped with files, or, with -L, according to their targets.
+Entries remain sorted by name within each group.
+
+The -format flag takes a Go template (see https://golang.org/pkg/text/template)
+that is executed for each entry in place of the usual output, with a newline
+printed after each one. The template is applied to a structure with fields
+	Name     upspin.PathName // The full path name of the entry.
+	Size     int64           // The size of the file; zero if unknown.
+	Time     upspin.Time     // The time of the last change.
+	Writer   upspin.UserName // The user who last changed the entry.
+	Attr     string          // "none (plain file)", "directory", or "link".
+	Sequence int64           // The sequence number of the entry.
+	Packing  upspin.Packing  // The packing, such as "ee" or "plain".
+	Link     upspin.PathName // The target of a link; empty otherwise.
+For example, -format='{{.Name}} {{.Size}}' prints the name and size of
+each entry.
+
+The -json flag prints each entry as a JSON object on a line of its own,
+suitable for processing by tools such as jq. The object holds the
+fields of the directory entry: Name, Attr, Sequence, Time, Size,
+Blocks (each with its Location, Offset, and Size), Writer, Packing,
+and, for links, Link.
+
 Flags:
   -L	follow links
   -R	recur into subdirectories
+  -dirs-first
+    	list directories before files
+  -files-first
+    	list files before directories
+  -format template
+    	Go template for printing each entry
   -help
     	print more information about the command
+  -json
+    	print each entry as JSON
   -l	long format
 
 
 
 Sub-command mkdir
 
-Usage: upspin mkdir [-p] directory...
+Usage: upspin mkdir [-p] [-access=contents] directory...
 
 Mkdir creates Upspin directories.
 
 The -p flag can be set to have mkdir create any missing parent directories of
 each argument.
 
+The -access flag can be set to the contents of an Access file to write
+into each new directory, with lines separated by newlines or by the two
+characters \n, as in
+	upspin mkdir -access 'r: friends\n*: ann@example.com' @/Shared
+The contents are checked before any directory is created. If the Access
+file cannot be written, the new directory is removed.
+
 The -glob flag can be set to false to have mkdir skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 
 Flags:
+  -access file
+    	contents of an Access file to write into each new directory
   -glob
     	apply glob processing to the arguments (default true)
   -help
@@ -401,6 +766,37 @@ Flags:
 
 
 
+Sub-command mv
+
+Usage: upspin mv [-f] [-n] path... path or mv [-f] [-n] path... directory
+
+Mv renames Upspin files. If the final argument is an existing
+directory, the other arguments are moved into it, keeping their final
+path elements as their names. Otherwise mv requires exactly two path
+names and renames the first to the second.
+
+Mv will not replace an existing file unless the -f flag is set.
+Directories cannot be moved, and Access and Group files cannot be
+renamed.
+
+Like cp, mv copies only the references to the data, not the data
+itself, re-wrapping keys for the readers of the new location if the
+file moves to a different directory.
+
+The -n (or -dry-run) flag makes mv print, after expanding its arguments,
+the renames it would do, without doing them. An existing file that -f
+would replace is reported as being removed first.
+
+Flags:
+  -dry-run
+    	same as -n
+  -f	replace existing files
+  -help
+    	print more information about the command
+  -n	print what would be done but do not do it
+
+
+
 Sub-command put
 
 Usage: upspin put [-in=inputfile] path
@@ -412,7 +808,12 @@ The -glob flag can be set to false to have put skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 
+The -bwlimit flag limits the rate at which the input is read, and
+thus transferred, in bytes per second.
+
 Flags:
+  -bwlimit bytes
+    	limit data transfer to bytes per second (0 means no limit)
   -glob
     	apply glob processing to the arguments (default true)
   -help
@@ -420,7 +821,7 @@ Flags:
   -in string
     	input file (default standard input)
   -packing string
-    	packing to use (default from user's config)
+    	packing to use, one of plain, plainsum, ee, eeintegrity (default from user's config)
 
 
 
@@ -442,7 +843,7 @@ Flags:
   -help
     	print more information about the command
   -pack string
-    	packing to use when rewriting (default "ee")
+    	packing to use when rewriting, one of plain, plainsum, ee, eeintegrity (default "ee")
   -r	recur into subdirectories
   -v	verbose: log progress
 
@@ -450,7 +851,7 @@ Flags:
 
 Sub-command rm
 
-Usage: upspin rm path...
+Usage: upspin rm [-R] [-f] [-n] path...
 
 Rm removes Upspin files and directories from the name space.
 
@@ -458,6 +859,19 @@ The -glob flag can be set to false to have rm skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 
+The -R (or -r) flag removes directories and their contents, deleting
+the contents of each directory before the directory itself. Unless the
+-f flag is set, rm first counts the items it would remove and asks for
+confirmation; if standard input is not a terminal, it exits with an
+error instead, so scripts must use -f. Once done, rm reports the number
+of items removed. If an item cannot be removed, perhaps for lack of
+rights, rm reports it and carries on with the rest, but leaves the
+directories holding it.
+
+The -f flag also makes rm continue past an argument that cannot be
+looked up rather than exiting. Either way, rm exits with a non-zero
+status if anything could not be removed.
+
 Rm does not delete the associated storage, which is rarely necessary
 or wise: storage can be shared between items and unused storage is
 better recovered by automatic means.
@@ -467,19 +881,27 @@ Rm does not delete the targets of links, only the links themselves.
 See the deletestorage command for more information about deleting
 storage.
 
+The -n (or -dry-run) flag makes rm print, after expanding its arguments,
+the names of the items it would remove, in the order it would remove
+them, without removing anything.
+
 Flags:
   -R	recur into subdirectories
-  -f	continue if errors occur
+  -dry-run
+    	same as -n
+  -f	do not ask for confirmation; continue if errors occur
   -glob
     	apply glob processing to the arguments (default true)
   -help
     	print more information about the command
+  -n	print what would be done but do not do it
+  -r	same as -R
 
 
 
 Sub-command rotate
 
-Usage: upspin rotate
+Usage: upspin rotate [-check]
 
 Rotate pushes an updated key to the key server.
 
@@ -492,16 +914,29 @@ To update an Upspin key, the sequence is:
 
 Keygen creates a new key and saves the old one. Countersign walks
 the file tree and adds signatures with the new key alongside those
-for the old. Rotate pushes the new key to the KeyServer. Share walks
-the file tree, re-wrapping the encryption keys that were encrypted
-with the old key to use the new key.
+for the old. Rotate pushes the new key to the KeyServer, together
+with a signature by the old key authorizing the change; the key
+server records the rotation so others can verify the chain of keys.
+Share walks the file tree, re-wrapping the encryption keys that were
+encrypted with the old key to use the new key.
 
 Some of these steps could be folded together but the full sequence
-makes it easier to recover if a step fails.
+makes it easier to recover if a step fails. Keygen -rotate backs up the
+existing key files before replacing them, and rotate itself changes no
+local files, so if the key server refuses the new key the local keys
+are left as they were and rotate may simply be run again.
+
+Before pushing the new key, rotate checks that the key server holds the
+previous key, the one the rotation is signed with. Afterwards it looks
+the user up again to confirm that the key server now returns the new
+key. The -check flag makes rotate stop after the first check, reporting
+what it would do without changing anything.
 
 TODO: Rotate and countersign are terms of art, not clear to users.
 
 Flags:
+  -check
+    	check the keys but do not push the new key to the key server
   -help
     	print more information about the command
 
@@ -651,9 +1086,32 @@ The -glob flag can be set to false to have share skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 
+The -via-group flag grants access through a Group file instead of by
+listing users in Access files. Share adds the users named by -add to the
+Group file, creating it if necessary, and makes sure the Access file
+governing each argument path grants the -right (default read) to the
+group, creating an Access file in the path's directory if there is none.
+It then updates the keys as with -fix so the members of the group can
+read encrypted files. To share with more users later, run the command
+again with the new users in -add.
+
+The -audit flag reviews the arguments, and everything below those that
+are directories, without changing anything. For each encrypted file it
+lists the users granted read access by the governing Access file and the
+users whose keys are wrapped in the file's metadata, and flags readers
+who cannot decrypt the file, keys wrapped for users no longer granted
+access, and keys whose owners cannot be determined. Keys are matched to
+users by looking up, in the key server, the users named in the Access
+files and the file's writer. With -q, only files with problems are
+listed. The exit status is non-zero if there are any.
+
 See the description for rotate for information about updating keys.
 
 Flags:
+  -add users
+    	comma-separated users to add to the -via-group Group file
+  -audit
+    	report mismatches between Access files and wrapped keys without changing anything; implies -r
   -d	do all files in directory; path must be a directory
   -fix
     	repair incorrect share settings
@@ -665,8 +1123,12 @@ Flags:
     	print more information about the command
   -q	suppress output. Default is to show state for every file
   -r	recur into subdirectories; path must be a directory. assumes -d
+  -right right
+    	right to grant to the -via-group group (default "read")
   -unencryptforall
     	for currently encrypted read:all files only, rewrite using EEIntegrity; requires -fix or -force
+  -via-group file
+    	grant access through the Group file; implies -fix
 
 
 
@@ -677,17 +1139,39 @@ Usage: upspin shell [-v] [-prompt=<prompt_string>]
 Shell runs an interactive session for Upspin subcommands.
 When running the shell, the leading "upspin" is assumed on each command.
 
-The shell has a simple interface, free of quoting or other features usually
-associated with interactive shells. It is intended only for testing and is kept
-simple for reasons of comprehensibility, portability, and maintainability.
-Those who need quoting or line editing or other such features should use their
-regular shell and run upspinfs or invoke the upspin command line-by-line.
+The shell is kept simple for reasons of comprehensibility, portability, and
+maintainability, and is intended mainly for testing. It splits each command
+into words at white space, with no quoting, treats the rest of a line after
+a # as a comment, and has no variables or command history. Those who need
+such features should use their regular shell and run upspinfs or invoke the
+upspin command line-by-line.
+
+When reading from a terminal, the shell edits each line as it is typed.
+Backspace erases a character, ^U erases the line, ^C abandons it, and ^D
+on an empty line ends the session. Other control characters and escape
+sequences, such as those sent by the arrow keys, are ignored.
+
+Tab completes the word being typed. The first word of a command completes
+to the names of upspin commands, and a later word holding an @ sign
+completes to the names of the Upspin files and directories that begin with
+it. If there are several, tab extends the word as far as they agree or, if
+it already does, lists them. If the directory cannot be listed promptly,
+tab does nothing.
 
-The shell does have one convenience feature, though, in the handling of path
-names. A path beginning with a plain @ refers to the current user's root
-(ann@example.com), while one starting @+suffix is the same with the suffix
-included (ann+suffix@example.com). This feature works in all upspin commands
-but is particularly handy inside the shell.
+In path names, as in all upspin commands, a path beginning with a plain @
+refers to the current user's root (ann@example.com), while one starting
+@+suffix is the same with the suffix included (ann+suffix@example.com).
+
+The shell can also connect commands with a vertical bar, as in
+
+	get @/notes | grep upspin
+	!date | put @/today
+
+The first word of each command names an Upspin command if there is one
+by that name; otherwise, or if the name is prefixed with !, it names a
+local program. At most one Upspin command may appear in a pipeline. The first
+command of a pipeline reads nothing and the last writes to the shell's
+standard output.
 
 Flags:
   -help
@@ -758,15 +1242,35 @@ Flags:
 
 Sub-command snapshot
 
-Usage: upspin snapshot
+Usage: upspin snapshot [-list | -diff old new | path]
 
 Snapshot requests the system to take a snapshot of the user's
 directory tree as soon as possible. Snapshots are created only if
-the directory server for the user's root supports them.
+the directory server for the user's root supports them. Given a path
+to a directory in the user's tree, snapshot takes a snapshot of just
+that directory. A snapshot is named for the date and time it was
+taken, such as @+snapshot/2017/06/01/12:30, followed for a snapshot
+of a single directory by a hyphen and the last element of the
+directory's name.
+
+With the -list flag, snapshot instead lists the user's snapshots and,
+for each, the directory it is a snapshot of.
+
+With the -diff flag, snapshot instead compares two directory trees,
+typically two snapshots such as @+snapshot/2017/06/01/12:30, and
+reports the items added, removed, or modified in the second tree
+relative to the first. Either argument may be the word "live", which
+stands for the user's root. Files are compared by their block
+references and modification times, not their contents, and
+directories whose blocks are identical are not descended into.
 
 Flags:
+  -diff
+    	compare two snapshots instead of taking one
   -help
     	print more information about the command
+  -list
+    	list snapshots instead of taking one
 
 
 
@@ -799,6 +1303,7 @@ Sub-command user
 
 Usage: upspin user [username...]
               user -put [-in=inputfile] [-force] [username]
+              user -watch [-interval=period] [-baseline=file] [username]
 
 User prints in YAML format the user record stored in the key server
 for the specified user, by default the current user.
@@ -818,17 +1323,60 @@ user whose domain is administered by the current user.
 A handy way to use the command is to edit the config file and run
 	upspin user | upspin user -put
 
+With the -watch flag, user monitors the key server record for the
+specified user, by default the current user, polling it at the period
+set by the -interval flag. Each time the public key, directory servers,
+or store servers in the record change, it prints a line describing the
+change, which may be a sign that the user's account has been
+compromised. The first record read is compared with the record in the
+file named by the -baseline flag, if set, which must be in the YAML
+format printed by the command; each later one is compared with the
+record before it, so each change is reported once. It runs until
+interrupted.
+
 To install new users see the signup command.
 
 Flags:
+  -baseline file
+    	file holding the expected user record for -watch
   -force
     	force writing user record even if key is empty
   -help
     	print more information about the command
   -in string
     	input file (default standard input)
+  -interval period
+    	period between checks with -watch (default 5m0s)
   -put
     	write new user record
+  -watch
+    	monitor the user record for changes
+
+
+
+Sub-command verify
+
+Usage: upspin verify [-R] [-v] path...
+
+Verify checks that the named Upspin files are intact. For each file it
+fetches every block from the storage server and unpacks it as a read
+would, which for packings such as ee and eeintegrity verifies the
+signature of the directory entry and the checksum of each block, and
+checks that each block has the size recorded in the directory entry.
+No data is written.
+
+Each failure is reported, identifying the file and block, and the
+command exits with a non-zero status if any occurred. With the -R flag,
+verify checks all files in the named directories and their
+subdirectories; otherwise directories are skipped. Links are not
+followed. With the -v flag, verify also reports each file that is
+intact.
+
+Flags:
+  -R	recur into subdirectories
+  -help
+    	print more information about the command
+  -v	report files that are intact
 
 
 
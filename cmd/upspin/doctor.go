@@ -0,0 +1,280 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"upspin.io/bind"
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/flags"
+	"upspin.io/rpc"
+	"upspin.io/transports"
+	"upspin.io/upspin"
+)
+
+func (s *State) doctor(args ...string) {
+	const help = `
+Doctor checks the setup of the current user and suggests how to fix any
+problems it finds. It checks that the config file can be read and parsed,
+that the user's keys are present and match those registered with the key
+server, that the key, directory, and storage servers can be reached, and
+that the local clock agrees with the servers' clocks closely enough for
+authentication to succeed.
+
+Doctor talks to the servers directly, bypassing any cache server named
+in the config.
+`
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	s.ParseFlags(fs, args, help, "doctor")
+	if fs.NArg() != 0 {
+		usageAndExit(fs)
+	}
+
+	s.diagnose(readConfigFile())
+}
+
+// diagnose runs the doctor's checks of the config file, whose contents are
+// data or, if it could not be read, readErr. It exits if there are problems.
+func (s *State) diagnose(data []byte, readErr error) {
+	d := &doctor{
+		out: s.Stdout,
+		now: time.Now,
+	}
+	d.run(flags.Config, data, readErr)
+	if d.problems > 0 {
+		s.Exitf("found %d problem(s)", d.problems)
+	}
+	s.Printf("No problems found.\n")
+}
+
+// maxClockSkew is the largest difference between the local clock and a
+// server's clock that is tolerated. Servers reject authentication requests
+// that are timestamped more than 30 seconds ahead of their own clock.
+const maxClockSkew = 30 * time.Second
+
+// doctor holds the state of a run of the doctor command.
+type doctor struct {
+	out      io.Writer
+	now      func() time.Time
+	problems int
+}
+
+// ok reports a check that passed.
+func (d *doctor) ok(format string, args ...interface{}) {
+	fmt.Fprintf(d.out, "ok: %s\n", fmt.Sprintf(format, args...))
+}
+
+// problem reports a failed check and the remedy for it.
+func (d *doctor) problem(fix, format string, args ...interface{}) {
+	d.problems++
+	fmt.Fprintf(d.out, "PROBLEM: %s\n\t%s\n", fmt.Sprintf(format, args...), fix)
+}
+
+// run checks the setup described by the named config file, whose contents
+// are data or, if it could not be read, readErr.
+func (d *doctor) run(configFile string, data []byte, readErr error) {
+	if readErr != nil {
+		d.problem("Run 'upspin signup' to create a config file, or use -config to name an existing one.",
+			"cannot read config file %q: %v", configFile, readErr)
+		return
+	}
+	cfg, err := config.InitClientConfig(bytes.NewReader(data))
+	switch {
+	case err == config.ErrNoFactotum:
+		d.problem("Set secrets in the config file to the directory holding your keys.",
+			"config file %q says there are no keys", configFile)
+	case errors.Is(errors.NotExist, err):
+		d.problem("Run 'upspin keygen' to create keys, or 'upspin keygen -secretseed' to restore them.",
+			"cannot find keys: %v", err)
+		return
+	case err != nil:
+		d.problem("Correct the config file; see https://upspin.io/doc/config.md for its format.",
+			"config file %q is invalid: %v", configFile, err)
+		return
+	default:
+		d.ok("config file %q is valid", configFile)
+	}
+	// Talk to the servers directly.
+	cfg = config.SetCacheEndpoint(cfg, upspin.Endpoint{})
+	transports.Init(cfg)
+
+	d.checkKeyServer(cfg)
+	d.checkDirServer(cfg)
+	d.checkStoreServer(cfg)
+}
+
+// checkKeyServer checks that the user is registered with the key server
+// and that the registered public key matches the user's local keys.
+// It also checks that the key server's record names the config's
+// directory server.
+func (d *doctor) checkKeyServer(cfg upspin.Config) {
+	e := cfg.KeyEndpoint()
+	if !d.checkEndpoint(cfg, "key", e) {
+		return
+	}
+	// Dial without keys, as otherwise the key server cache would
+	// answer for the current user with the values in the config.
+	// Release the connection afterwards so that it is not reused by
+	// those, such as later commands in a shell, that need the keys.
+	noKeys := config.SetFactotum(cfg, nil)
+	key, err := bind.KeyServer(noKeys, e)
+	if err != nil {
+		d.unreachable("key", e, err)
+		return
+	}
+	u, err := key.Lookup(cfg.UserName())
+	bind.Release(noKeys)
+	if errors.Is(errors.NotExist, err) {
+		d.problem("Run 'upspin signup' to register, or check username in the config file.",
+			"user %s is not registered with the key server %s", cfg.UserName(), e)
+		return
+	}
+	if err != nil {
+		d.unreachable("key", e, err)
+		return
+	}
+	d.ok("user %s is registered with the key server %s", cfg.UserName(), e)
+
+	if f := cfg.Factotum(); f != nil {
+		if f.PublicKey() != u.PublicKey {
+			d.problem("Restore your registered keys with 'upspin keygen -secretseed', or register new ones with 'upspin rotate'.",
+				"local public key does not match the one registered with the key server")
+		} else {
+			d.ok("local public key matches the key server")
+		}
+	}
+
+	dir := cfg.DirEndpoint()
+	if dir.Unassigned() {
+		return
+	}
+	for _, e := range u.Dirs {
+		if e == dir {
+			return
+		}
+	}
+	d.problem("Correct dirserver in the config file, or run 'upspin user' to update the key server record.",
+		"key server record lists directory server %v but the config file names %v", u.Dirs, dir)
+}
+
+// checkDirServer checks that the directory server can be reached and that
+// the user's root exists.
+func (d *doctor) checkDirServer(cfg upspin.Config) {
+	e := cfg.DirEndpoint()
+	if !d.checkEndpoint(cfg, "directory", e) {
+		return
+	}
+	dir, err := bind.DirServer(cfg, e)
+	if err != nil {
+		d.unreachable("directory", e, err)
+		return
+	}
+	root := upspin.PathName(cfg.UserName() + "/")
+	_, err = dir.Lookup(root)
+	switch {
+	case err == nil:
+		d.ok("root %s exists on the directory server %s", root, e)
+	case errors.Is(errors.NotExist, err):
+		d.problem(fmt.Sprintf("Run 'upspin mkdir %s' to create it.", root),
+			"root %s does not exist on the directory server %s", root, e)
+	case errors.Is(errors.Permission, err), errors.Is(errors.Private, err):
+		d.problem("Check that the directory server accepts this user; see 'upspin setupserver' and 'upspin setupwriters'.",
+			"directory server %s refused to look up %s: %v", e, root, err)
+	default:
+		d.unreachable("directory", e, err)
+	}
+}
+
+// checkStoreServer checks that the storage server can be reached.
+func (d *doctor) checkStoreServer(cfg upspin.Config) {
+	e := cfg.StoreEndpoint()
+	if !d.checkEndpoint(cfg, "storage", e) {
+		return
+	}
+	store, err := bind.StoreServer(cfg, e)
+	if err != nil {
+		d.unreachable("storage", e, err)
+		return
+	}
+	// Servers need not support the health reference,
+	// but any reply from the server shows it is up.
+	_, _, _, err = store.Get(upspin.HealthMetadata)
+	if err != nil && !errors.Is(errors.NotExist, err) {
+		d.unreachable("storage", e, err)
+		return
+	}
+	d.ok("storage server %s is reachable", e)
+}
+
+// checkEndpoint checks that the endpoint for the named kind of server is
+// set and, for remote servers, that its clock agrees with the local one.
+// It reports whether the server should be checked further.
+func (d *doctor) checkEndpoint(cfg upspin.Config, kind string, e upspin.Endpoint) bool {
+	if e.Unassigned() {
+		d.problem(fmt.Sprintf("Set %sserver in the config file.", kindKey[kind]),
+			"no %s server in the config file", kind)
+		return false
+	}
+	if e.Transport != upspin.Remote {
+		return true
+	}
+	skew, err := d.clockSkew(cfg, e.NetAddr)
+	if err != nil {
+		d.unreachable(kind, e, err)
+		return false
+	}
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		d.problem("Synchronize the local clock, for instance with NTP.",
+			"local clock differs from the %s server's clock by %v", kind, skew.Round(time.Second))
+	}
+	return true
+}
+
+// kindKey maps the kind of a server to its config key, less the "server" suffix.
+var kindKey = map[string]string{
+	"key":       "key",
+	"directory": "dir",
+	"storage":   "store",
+}
+
+// unreachable reports that a server cannot be reached.
+func (d *doctor) unreachable(kind string, e upspin.Endpoint, err error) {
+	d.problem(fmt.Sprintf("Check the network connection and %sserver in the config file.", kindKey[kind]),
+		"cannot reach %s server %s: %v", kind, e, err)
+}
+
+// clockSkew returns the difference between the local clock and the clock
+// of the server at the given address, as reported in its HTTP Date header.
+// The result has a precision of one second.
+func (d *doctor) clockSkew(cfg upspin.Config, addr upspin.NetAddr) (time.Duration, error) {
+	pool, err := rpc.CertPoolFromConfig(cfg)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{
+		Timeout: 15 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+	resp, err := client.Head("https://" + string(addr) + "/")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, errors.Errorf("bad Date header: %v", err)
+	}
+	return d.now().Sub(date), nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"upspin.io/bind"
+	"upspin.io/config"
+	"upspin.io/factotum"
+	"upspin.io/test/testutil"
+	"upspin.io/upspin"
+)
+
+// diagnose runs the doctor on a config file with the given contents and
+// returns its output and the number of problems it found.
+func diagnose(t *testing.T, data string, readErr error) (string, int) {
+	t.Helper()
+	var out bytes.Buffer
+	d := &doctor{
+		out: &out,
+		now: time.Now,
+	}
+	d.run("testconfig", []byte(data), readErr)
+	return out.String(), d.problems
+}
+
+// inProcessConfig returns the text of a config file for the named user with
+// the keys in key/testdata/joe, talking to in-process servers.
+func inProcessConfig(user upspin.UserName, servers string) string {
+	return fmt.Sprintf("username: %s\nsecrets: %s\nkeyserver: inprocess\n%s",
+		user, testutil.Repo("key", "testdata", "joe"), servers)
+}
+
+// register registers the user with the in-process key server, with the
+// public key from the named directory in key/testdata.
+func register(t *testing.T, user upspin.UserName, keyDir string) {
+	t.Helper()
+	f, err := factotum.NewFromDir(testutil.Repo("key", "testdata", keyDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inProcess := upspin.Endpoint{Transport: upspin.InProcess}
+	cfg := config.SetUserName(config.New(), user)
+	key, err := bind.KeyServer(cfg, inProcess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = key.Put(&upspin.User{
+		Name:      user,
+		Dirs:      []upspin.Endpoint{inProcess},
+		Stores:    []upspin.Endpoint{inProcess},
+		PublicKey: f.PublicKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	const (
+		unregistered = "nobody@doctor.com"
+		mismatched   = "mismatched@doctor.com"
+	)
+	register(t, mismatched, "bob")
+
+	tests := []struct {
+		name    string
+		data    string
+		readErr error
+		want    string
+	}{
+		{
+			name:    "missing config",
+			readErr: os.ErrNotExist,
+			want:    "cannot read config file",
+		},
+		{
+			name: "invalid config",
+			data: "username: [",
+			want: "is invalid",
+		},
+		{
+			name: "missing keys",
+			data: "username: nokeys@doctor.com\nsecrets: " + testutil.Repo("key", "testdata", "nonexistent"),
+			want: "cannot find keys",
+		},
+		{
+			name: "no dir server",
+			data: inProcessConfig(mismatched, ""),
+			want: "no directory server",
+		},
+		{
+			name: "unregistered user",
+			data: inProcessConfig(unregistered, ""),
+			want: "user nobody@doctor.com is not registered",
+		},
+		{
+			name: "mismatched keys",
+			data: inProcessConfig(mismatched, ""),
+			want: "local public key does not match",
+		},
+		{
+			name: "wrong dir server",
+			data: inProcessConfig(mismatched, "dirserver: remote,dir.doctor.com\n"),
+			want: "key server record lists directory server",
+		},
+	}
+	for _, test := range tests {
+		out, problems := diagnose(t, test.data, test.readErr)
+		if problems == 0 {
+			t.Errorf("%s: no problems found", test.name)
+		}
+		if !strings.Contains(out, "PROBLEM: ") || !strings.Contains(out, test.want) {
+			t.Errorf("%s: output does not report %q:\n%s", test.name, test.want, out)
+		}
+	}
+}
+
+func TestDoctorHealthy(t *testing.T) {
+	const user = "healthy@doctor.com"
+	register(t, user, "joe")
+
+	data := inProcessConfig(user, "dirserver: inprocess\nstoreserver: inprocess\n")
+	cfg, err := config.InitConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the root is created, that is the only problem.
+	out, problems := diagnose(t, data, nil)
+	if problems != 1 || !strings.Contains(out, "root healthy@doctor.com/ does not exist") {
+		t.Fatalf("found %d problems, want just a missing root:\n%s", problems, out)
+	}
+
+	dir, err := bind.DirServer(cfg, cfg.DirEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dir.Put(&upspin.DirEntry{
+		Name:       user + "/",
+		SignedName: user + "/",
+		Attr:       upspin.AttrDirectory,
+		Writer:     user,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	out, problems = diagnose(t, data, nil)
+	if problems != 0 {
+		t.Errorf("found %d problems, want none:\n%s", problems, out)
+	}
+}
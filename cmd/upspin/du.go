@@ -0,0 +1,147 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"upspin.io/upspin"
+)
+
+func (s *State) du(args ...string) {
+	const help = `
+Du reports the space used by the named Upspin files and directory
+trees, by default the user's root. Sizes are computed from the blocks
+recorded in the directory entries; no data is read from the storage
+servers. Du prints the total for each directory, in bytes, after those
+for the directories within it, followed by the directory name. For
+a named file, it prints the size of the file.
+
+Directories that cannot be listed are skipped with a warning, as are
+files whose sizes are hidden because the user does not have read
+rights to them.
+
+Du does not follow links unless the -L flag is set, and even then it
+visits each directory once, so links that form loops are harmless.
+Links named on the command line are always followed.
+`
+	fs := flag.NewFlagSet("du", flag.ExitOnError)
+	human := fs.Bool("h", false, "print sizes in human-readable form, such as 1.5K or 23M")
+	summary := fs.Bool("s", false, "print only the total for each argument")
+	followLinks := fs.Bool("L", false, "follow links")
+	s.ParseFlags(fs, args, help, "du [-h] [-s] [-L] [path...]")
+
+	d := &duState{
+		state:       s,
+		human:       *human,
+		summary:     *summary,
+		followLinks: *followLinks,
+		visited:     make(map[upspin.PathName]bool),
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"@"}
+	}
+	for _, name := range names {
+		for _, entry := range s.GlobUpspin(name) {
+			if entry.IsLink() {
+				e, err := s.Client.Lookup(entry.Name, true)
+				if err != nil {
+					s.Fail(err)
+					continue
+				}
+				entry = e
+			}
+			total := d.walk(entry)
+			if d.summary || !entry.IsDir() {
+				d.print(total, entry.Name)
+			}
+		}
+	}
+}
+
+// duState holds the state of a du command.
+type duState struct {
+	state       *State
+	human       bool
+	summary     bool
+	followLinks bool
+	// visited records the directories already counted.
+	visited map[upspin.PathName]bool
+}
+
+// walk returns the total size of the files in the tree rooted at entry,
+// printing the totals for each directory unless d.summary is set.
+func (d *duState) walk(entry *upspin.DirEntry) int64 {
+	s := d.state
+	if entry.IsLink() {
+		if !d.followLinks {
+			return 0
+		}
+		e, err := s.Client.Lookup(entry.Link, true)
+		if err != nil {
+			s.Fail(err)
+			return 0
+		}
+		entry = e
+	}
+	if !entry.IsDir() {
+		return s.sizeOf(entry)
+	}
+	if d.visited[entry.Name] {
+		return 0
+	}
+	d.visited[entry.Name] = true
+
+	contents, err := s.Client.Glob(upspin.AllFilesGlob(entry.Name))
+	if err != nil {
+		s.Failf("skipping %s: %v", entry.Name, err)
+		return 0
+	}
+	var total int64
+	hidden := false
+	for _, e := range contents {
+		if e.IsIncomplete() && !e.IsDir() && !e.IsLink() {
+			hidden = true
+			continue
+		}
+		total += d.walk(e)
+	}
+	if hidden {
+		s.Failf("skipping files in %s: sizes are hidden without read rights", entry.Name)
+	}
+	if !d.summary {
+		d.print(total, entry.Name)
+	}
+	return total
+}
+
+func (d *duState) print(size int64, name upspin.PathName) {
+	if d.human {
+		d.state.Printf("%s\t%s\n", humanSize(size), name)
+		return
+	}
+	d.state.Printf("%d\t%s\n", size, name)
+}
+
+// humanSize formats a size in bytes using binary prefixes,
+// with one decimal place for values less than 10.
+func humanSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	f := float64(n)
+	i := -1
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if f < 10 {
+		return fmt.Sprintf("%.1f%c", f, units[i])
+	}
+	return fmt.Sprintf("%.0f%c", f, units[i])
+}
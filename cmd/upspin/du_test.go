@@ -0,0 +1,28 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{10 * 1024, "10K"},
+		{23<<20 + 1, "23M"},
+		{5 << 30, "5.0G"},
+		{1 << 60, "1.0E"},
+	}
+	for _, test := range tests {
+		if got := humanSize(test.size); got != test.want {
+			t.Errorf("humanSize(%d) = %q, want %q", test.size, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"upspin.io/upspin"
+)
+
+func (s *State) find(args ...string) {
+	const help = `
+Find walks the named Upspin directory trees, by default the user's root,
+and prints the names of the entries, including the roots themselves,
+that satisfy all the conditions given by its flags. With no flags it
+prints every entry in the trees.
+
+The -name flag selects entries whose final path element matches the
+pattern, which has the syntax of path.Match; quote it to protect it
+from the shell, as in -name '*.jpg'.
+
+The -newer flag selects entries modified within the given duration
+before now, such as 36h or 10m.
+
+The -size flag selects files by their size, computed from the blocks
+recorded in their directory entries. The size is a number of bytes,
+optionally followed by K, M, G, or T for powers of 1024. With a leading
++ it selects larger files, with a leading - smaller files, and otherwise
+files of exactly that size. Directories and links never satisfy -size,
+nor do files whose sizes are hidden because the user does not have read
+rights to them.
+
+Directories that cannot be listed are skipped with a warning.
+
+Find does not follow links unless the -L flag is set, in which case a
+link is treated as its target. Even then it visits each directory once,
+so links that form loops are harmless. Links named on the command line
+are always followed.
+` + formatHelp
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	name := fs.String("name", "", "select entries whose final element matches the `pattern`")
+	newer := fs.Duration("newer", 0, "select entries modified within the `duration` before now")
+	size := fs.String("size", "", "select files of `size` bytes; +size for larger, -size for smaller")
+	followLinks := fs.Bool("L", false, "follow links")
+	format := fs.String("format", "", "Go `template` for printing each entry")
+	s.ParseFlags(fs, args, help, "find [-name=pattern] [-newer=duration] [-size=[+-]size] [-L] [-format=template] [path...]")
+
+	f := &findState{
+		state:       s,
+		followLinks: *followLinks,
+		visited:     make(map[upspin.PathName]bool),
+		tmpl:        s.formatFlag(*format),
+	}
+	if *name != "" {
+		if _, err := path.Match(*name, ""); err != nil {
+			s.Exitf("invalid -name pattern %q: %v", *name, err)
+		}
+		f.preds = append(f.preds, func(e *upspin.DirEntry) bool {
+			ok, _ := path.Match(*name, path.Base(string(e.Name)))
+			return ok
+		})
+	}
+	if *newer != 0 {
+		since := time.Now().Add(-*newer)
+		f.preds = append(f.preds, func(e *upspin.DirEntry) bool {
+			return e.Time.Go().After(since)
+		})
+	}
+	if *size != "" {
+		cmp, n, err := parseFindSize(*size)
+		if err != nil {
+			s.Exitf("invalid -size %q: %v", *size, err)
+		}
+		f.preds = append(f.preds, func(e *upspin.DirEntry) bool {
+			if e.IsDir() || e.IsLink() || e.IsIncomplete() {
+				return false
+			}
+			size, err := e.Size()
+			if err != nil {
+				return false
+			}
+			switch cmp {
+			case '+':
+				return size > n
+			case '-':
+				return size < n
+			}
+			return size == n
+		})
+	}
+
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"@"}
+	}
+	for _, name := range names {
+		for _, entry := range s.GlobUpspin(name) {
+			if entry.IsLink() {
+				e, err := s.Client.Lookup(entry.Name, true)
+				if err != nil {
+					s.Fail(err)
+					continue
+				}
+				entry = e
+			}
+			f.walk(entry)
+		}
+	}
+}
+
+// findState holds the state of a find command.
+type findState struct {
+	state       *State
+	followLinks bool
+	tmpl        *template.Template
+	// preds holds the conditions an entry must satisfy to be printed.
+	preds []func(*upspin.DirEntry) bool
+	// visited records the directories already walked.
+	visited map[upspin.PathName]bool
+}
+
+// walk prints the entries in the tree rooted at entry that satisfy
+// the conditions.
+func (f *findState) walk(entry *upspin.DirEntry) {
+	s := f.state
+	if entry.IsLink() && f.followLinks {
+		e, err := s.Client.Lookup(entry.Link, true)
+		if err != nil {
+			s.Fail(err)
+			return
+		}
+		entry = e
+	}
+	if entry.IsDir() {
+		if f.visited[entry.Name] {
+			return
+		}
+		f.visited[entry.Name] = true
+	}
+	if f.match(entry) {
+		f.print(entry)
+	}
+	if !entry.IsDir() {
+		return
+	}
+	contents, err := s.Client.Glob(upspin.AllFilesGlob(entry.Name))
+	if err != nil {
+		s.Failf("skipping %s: %v", entry.Name, err)
+		return
+	}
+	for _, e := range contents {
+		f.walk(e)
+	}
+}
+
+// match reports whether entry satisfies all the conditions.
+func (f *findState) match(entry *upspin.DirEntry) bool {
+	for _, pred := range f.preds {
+		if !pred(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *findState) print(entry *upspin.DirEntry) {
+	s := f.state
+	if f.tmpl != nil {
+		s.printFormatted(f.tmpl, []*upspin.DirEntry{entry})
+		return
+	}
+	s.Printf("%s\n", entry.Name)
+}
+
+// parseFindSize parses the argument of the -size flag, returning the leading
+// '+' or '-', if any, and the size in bytes.
+func parseFindSize(arg string) (cmp byte, size int64, err error) {
+	if arg != "" && (arg[0] == '+' || arg[0] == '-') {
+		cmp, arg = arg[0], arg[1:]
+	}
+	shift := uint(0)
+	if n := len(arg); n > 0 {
+		if i := strings.Index("KMGT", strings.ToUpper(arg[n-1:])); i >= 0 {
+			shift = 10 * uint(i+1)
+			arg = arg[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < 0 || n > (1<<63-1)>>shift {
+		return 0, 0, strconv.ErrRange
+	}
+	return cmp, n << shift, nil
+}
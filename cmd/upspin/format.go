@@ -0,0 +1,102 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"text/template"
+
+	"upspin.io/upspin"
+)
+
+// formatHelp documents the -format flag shared by commands that print
+// directory entries. It is appended to their help text.
+const formatHelp = `
+The -format flag takes a Go template (see https://golang.org/pkg/text/template)
+that is executed for each entry in place of the usual output, with a newline
+printed after each one. The template is applied to a structure with fields
+	Name     upspin.PathName // The full path name of the entry.
+	Size     int64           // The size of the file; zero if unknown.
+	Time     upspin.Time     // The time of the last change.
+	Writer   upspin.UserName // The user who last changed the entry.
+	Attr     string          // "none (plain file)", "directory", or "link".
+	Sequence int64           // The sequence number of the entry.
+	Packing  upspin.Packing  // The packing, such as "ee" or "plain".
+	Link     upspin.PathName // The target of a link; empty otherwise.
+For example, -format='{{.Name}} {{.Size}}' prints the name and size of
+each entry.
+`
+
+// formatEntry holds the fields of a DirEntry available to -format templates.
+// Keep formatHelp in sync with its fields.
+type formatEntry struct {
+	Name     upspin.PathName
+	Size     int64
+	Time     upspin.Time
+	Writer   upspin.UserName
+	Attr     string
+	Sequence int64
+	Packing  upspin.Packing
+	Link     upspin.PathName
+}
+
+// parseFormat parses the template given to a -format flag. To report errors
+// before any output is produced, it also executes the template once on an
+// empty entry, which catches references to fields that do not exist.
+func parseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, &formatEntry{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// writeFormatted writes the entry to w using the template, followed by a newline.
+func writeFormatted(w io.Writer, tmpl *template.Template, e *upspin.DirEntry) error {
+	size, err := e.Size()
+	if err != nil {
+		size = 0
+	}
+	f := &formatEntry{
+		Name:     e.Name,
+		Size:     size,
+		Time:     e.Time,
+		Writer:   e.Writer,
+		Attr:     attrFormat(e.Attr),
+		Sequence: e.Sequence,
+		Packing:  e.Packing,
+		Link:     e.Link,
+	}
+	if err := tmpl.Execute(w, f); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// formatFlag parses the value of a -format flag, exiting if it is invalid.
+// It returns nil if the flag is empty.
+func (s *State) formatFlag(format string) *template.Template {
+	if format == "" {
+		return nil
+	}
+	tmpl, err := parseFormat(format)
+	if err != nil {
+		s.Exitf("invalid -format template: %v", err)
+	}
+	return tmpl
+}
+
+// printFormatted prints the entries using the template.
+func (s *State) printFormatted(tmpl *template.Template, entries []*upspin.DirEntry) {
+	for _, e := range entries {
+		if err := writeFormatted(s.Stdout, tmpl, e); err != nil {
+			s.Exitf("executing -format template: %v", err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"upspin.io/upspin"
+)
+
+func TestFormat(t *testing.T) {
+	entry := &upspin.DirEntry{
+		Name:       "ann@example.com/dir/file",
+		SignedName: "ann@example.com/dir/file",
+		Attr:       upspin.AttrNone,
+		Writer:     "chris@example.com",
+		Packing:    upspin.EEPack,
+		Sequence:   17,
+		Time:       upspin.TimeFromGo(time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC)),
+		Blocks: []upspin.DirBlock{
+			{Offset: 0, Size: 100},
+			{Offset: 100, Size: 23},
+		},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{{.Name}}", "ann@example.com/dir/file\n"},
+		{"{{.Name}} {{.Size}}", "ann@example.com/dir/file 123\n"},
+		{"{{.Writer}} seq={{.Sequence}} {{.Packing}}", "chris@example.com seq=17 ee\n"},
+		{"{{.Attr}}", "none (plain file)\n"},
+		{"{{.Time}}", "2017-06-01T12:30:00 UTC\n"},
+		{`{{.Time.Go.Format "2006-01-02"}}`, "2017-06-01\n"},
+		{"{{if .Link}}link{{else}}no link{{end}}", "no link\n"},
+		{"", "\n"},
+	}
+	for _, test := range tests {
+		tmpl, err := parseFormat(test.format)
+		if err != nil {
+			t.Errorf("parseFormat(%q): %v", test.format, err)
+			continue
+		}
+		var b bytes.Buffer
+		if err := writeFormatted(&b, tmpl, entry); err != nil {
+			t.Errorf("%q: %v", test.format, err)
+			continue
+		}
+		if got := b.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.format, got, test.want)
+		}
+	}
+}
+
+func TestFormatBadTemplate(t *testing.T) {
+	for _, format := range []string{
+		"{{.Name",      // Syntax error.
+		"{{.NoSuch}}",  // No such field.
+		"{{.Name.Go}}", // No such method.
+	} {
+		if _, err := parseFormat(format); err == nil {
+			t.Errorf("parseFormat(%q) succeeded, want error", format)
+		}
+	}
+}
@@ -6,6 +6,10 @@ package main
 
 import (
 	"flag"
+	"io"
+
+	"upspin.io/subcmd"
+	"upspin.io/upspin"
 )
 
 func (s *State) get(args ...string) {
@@ -15,10 +19,14 @@ Get writes to standard output the contents identified by the Upspin path.
 The -glob flag can be set to false to have get skip Glob processing,
 treating its argument as literal text even if it contains special
 characters. (A leading @ sign is always expanded.)
+
+The -bwlimit flag limits the rate at which the contents are fetched,
+in bytes per second.
 `
 	fs := flag.NewFlagSet("get", flag.ExitOnError)
 	outFile := fs.String("out", "", "output file (default standard output)")
 	glob := globFlag(fs)
+	bwlimit := bwlimitFlag(fs)
 	s.ParseFlags(fs, args, help, "get [-out=outputfile] path")
 
 	names := s.expandUpspin(fs.Args(), *glob)
@@ -26,9 +34,33 @@ characters. (A leading @ sign is always expanded.)
 		usageAndExit(fs)
 	}
 
-	data, err := s.Client.Get(names[0])
+	r, err := s.getReader(names[0])
 	if err != nil {
 		s.Exit(err)
 	}
-	s.writeOut(*outFile, data)
+	defer r.Close()
+	var out io.Writer = s.Stdout
+	if *outFile != "" {
+		f := s.CreateLocal(subcmd.Tilde(*outFile))
+		defer func() {
+			if err := f.Close(); err != nil {
+				s.Exitf("closing output failed: %v", err)
+			}
+		}()
+		out = f
+	}
+	// The file's blocks are fetched as they are read,
+	// so pacing the reads paces the transfer.
+	if _, err := io.Copy(out, newRateLimiter(*bwlimit).Reader(r)); err != nil {
+		s.Exit(err)
+	}
+}
+
+// getReader returns a reader for the contents of the named file that
+// fetches them as they are read, rather than all at once.
+func (s *State) getReader(name upspin.PathName) (io.ReadCloser, error) {
+	if rg, ok := s.Client.(upspin.ReaderGetter); ok {
+		return rg.GetReader(name)
+	}
+	return s.Client.Open(name)
 }
@@ -0,0 +1,32 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+)
+
+func (s *State) history(args ...string) {
+	const help = `
+History prints, oldest first, the recorded changes to each named path,
+including deletions, as reported by the directory server. For each
+change it shows the time, sequence number, operation, writer, and size
+of the file. It is the same as info -history.
+
+Since a deleted path may have a history, the arguments are not
+expanded as glob patterns. (Leading @ signs are always expanded.)
+
+The user must have some access right to the path. Not all directory
+servers record history; for those that do not, history says so.
+`
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	s.ParseFlags(fs, args, help, "history path...")
+	if fs.NArg() == 0 {
+		usageAndExit(fs)
+	}
+	for _, name := range fs.Args() {
+		s.printHistory(s.AtSign(name))
+	}
+}
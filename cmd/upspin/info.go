@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"flag"
 	"fmt"
 	"text/tabwriter"
@@ -13,6 +14,8 @@ import (
 	"time"
 
 	"upspin.io/access"
+	"upspin.io/client/clientutil"
+	"upspin.io/errors"
 	"upspin.io/pack"
 	"upspin.io/path"
 	"upspin.io/upspin"
@@ -27,21 +30,135 @@ ls but also storage references, sizes, and other metadata.
 If the path names an Access or Group file, it is also checked for
 validity. If it is a link, the command attempts to access the target
 of the link.
-`
+
+The -history flag instead prints, oldest first, the recorded changes
+to each named path, including deletions, as reported by the directory
+server. For each change it shows the time, sequence number, operation,
+writer, and size of the file. Not all directory servers support this.
+
+With -format, info prints only the fields selected by the template and
+does not check Access and Group files or follow links. The same is true
+of -json, which adds to the fields printed by ls -json the Access file
+that applies to the entry, as Access, and the name of the packer for its
+packing, as Packer.
+
+The -raw-store flag instead prints, for each block of each entry, its
+cleartext size recorded in the directory entry and the length and SHA-256
+hash of the data held by the store server. The stored data is not
+unpacked, so this works for any packing and allows the store's contents
+to be checked independently of the packer.
+` + formatHelp + jsonHelp
 	fs := flag.NewFlagSet("info", flag.ExitOnError)
 	recur := fs.Bool("R", false, "recur into subdirectories")
-	s.ParseFlags(fs, args, help, "info path...")
+	history := fs.Bool("history", false, "print the history of changes to each path")
+	format := fs.String("format", "", "Go `template` for printing each entry")
+	jsonOut := fs.Bool("json", false, "print each entry as JSON")
+	rawStore := fs.Bool("raw-store", false, "print the length and hash of each block as stored")
+	s.ParseFlags(fs, args, help, "info [-R] [-history] [-format=template] [-json] [-raw-store] path...")
 
-	if fs.NArg() == 0 {
+	if fs.NArg() == 0 || (*recur && *history) {
 		usageAndExit(fs)
 	}
+	// The -history, -format, -json, and -raw-store flags are mutually exclusive.
+	modes := 0
+	for _, set := range []bool{*history, *format != "", *jsonOut, *rawStore} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		usageAndExit(fs)
+	}
+	tmpl := s.formatFlag(*format)
+	var printEntry func(*upspin.DirEntry)
+	switch {
+	case *jsonOut:
+		printEntry = s.printInfoJSON
+	case tmpl != nil:
+		printEntry = func(e *upspin.DirEntry) { s.printFormatted(tmpl, []*upspin.DirEntry{e}) }
+	case *rawStore:
+		printEntry = s.printRawStore
+	}
 
 	for _, name := range fs.Args() {
-		s.doInfo(string(s.AtSign(name)), *recur, true)
+		if *history {
+			s.printHistory(s.AtSign(name))
+			continue
+		}
+		s.doInfo(string(s.AtSign(name)), printEntry, *recur, true)
+	}
+}
+
+// printHistory prints, oldest first, the changes to the named path
+// recorded by its directory server.
+func (s *State) printHistory(name upspin.PathName) {
+	dir := s.DirServer(name)
+	h, ok := dir.(upspin.DirHistorian)
+	if !ok {
+		s.Exitf("%s: directory server does not support history", name)
+	}
+	events, err := h.History(name)
+	if err == upspin.ErrNotSupported {
+		s.Exitf("%s: directory server does not support history", name)
+	}
+	if err != nil {
+		s.Exit(err)
+	}
+	if len(events) == 0 {
+		s.Exitf("no history for %q", name)
+	}
+	s.Printf("%s:\n", name)
+	w := tabwriter.NewWriter(s.Stdout, 4, 4, 1, ' ', 0)
+	for _, e := range events {
+		de := e.Entry
+		if e.Delete {
+			// The log records the entry that was deleted,
+			// not who deleted it or when.
+			fmt.Fprintf(w, "\t\t%d\tdelete\t\t\n", de.Sequence)
+			continue
+		}
+		size := ""
+		if de.IsRegular() && !de.IsIncomplete() {
+			d, _ := de.Size()
+			size = fmt.Sprint(d)
+		}
+		fmt.Fprintf(w, "\t%s\t%d\tput\t%s\t%s\n", de.Time, de.Sequence, de.Writer, size)
+	}
+	if err := w.Flush(); err != nil {
+		s.Exitf("flushing output: %v", err)
 	}
 }
 
-func (s *State) doInfo(pattern string, recur, first bool) {
+// printRawStore prints, for each block of the entry, the length and SHA-256
+// hash of the data held by the store server. It does not unpack the data.
+func (s *State) printRawStore(entry *upspin.DirEntry) {
+	if len(entry.Blocks) == 0 {
+		return
+	}
+	s.Printf("%s\n", entry.Name)
+	w := tabwriter.NewWriter(s.Stdout, 4, 4, 1, ' ', 0)
+	fmt.Fprintf(w, "\tBlock#\tSize\tStored\tSHA-256\tLocation\n")
+	var errs []error
+	for i, b := range entry.Blocks {
+		data, err := clientutil.ReadLocation(s.Config, b.Location)
+		if err != nil {
+			fmt.Fprintf(w, "\t%d\t%d\t-\t-\t%v\n", i, b.Size, b.Location)
+			errs = append(errs, errors.Errorf("%s: block %d: %v", entry.Name, i, err))
+			continue
+		}
+		fmt.Fprintf(w, "\t%d\t%d\t%d\t%x\t%v\n", i, b.Size, len(data), sha256.Sum256(data), b.Location)
+	}
+	if err := w.Flush(); err != nil {
+		s.Exitf("flushing output: %v", err)
+	}
+	for _, err := range errs {
+		s.Fail(err)
+	}
+}
+
+// doInfo prints information about the entries matching pattern. If printEntry is
+// non-nil, it is used to print each entry instead of the full description.
+func (s *State) doInfo(pattern string, printEntry func(*upspin.DirEntry), recur, first bool) {
 	entries, err := s.DirServer(upspin.PathName(pattern)).Glob(pattern)
 	// ErrFollowLink is OK: we show the link itself.
 	if err != nil && err != upspin.ErrFollowLink {
@@ -51,6 +168,13 @@ func (s *State) doInfo(pattern string, recur, first bool) {
 		s.Exitf("no such file %q", pattern)
 	}
 	for _, entry := range entries {
+		if printEntry != nil {
+			printEntry(entry)
+			if recur && entry.IsDir() {
+				s.doInfo(upspin.AllFilesGlob(entry.Name), printEntry, recur, false)
+			}
+			continue
+		}
 		s.printInfo(entry)
 		switch {
 		case access.IsAccessFile(entry.Name):
@@ -59,7 +183,7 @@ func (s *State) doInfo(pattern string, recur, first bool) {
 			s.checkGroupFile(entry.Name)
 		case entry.IsDir():
 			if recur {
-				s.doInfo(upspin.AllFilesGlob(entry.Name), recur, false)
+				s.doInfo(upspin.AllFilesGlob(entry.Name), printEntry, recur, false)
 			}
 		}
 	}
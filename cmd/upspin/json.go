@@ -0,0 +1,116 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"upspin.io/pack"
+	"upspin.io/upspin"
+)
+
+// jsonHelp documents the -json flag shared by ls and info.
+// It is appended to their help text.
+const jsonHelp = `
+The -json flag prints each entry as a JSON object on a line of its own,
+suitable for processing by tools such as jq. The object holds the
+fields of the directory entry: Name, Attr, Sequence, Time, Size,
+Blocks (each with its Location, Offset, and Size), Writer, Packing,
+and, for links, Link.
+`
+
+// jsonEntry is the form of a DirEntry printed by the -json flag.
+type jsonEntry struct {
+	Name     upspin.PathName
+	Attr     string
+	Sequence int64
+	Time     time.Time
+	Size     int64
+	Blocks   []jsonBlock
+	Writer   upspin.UserName
+	Packing  string
+	Link     upspin.PathName `json:",omitempty"`
+}
+
+type jsonBlock struct {
+	Location jsonLocation
+	Offset   int64
+	Size     int64
+}
+
+type jsonLocation struct {
+	Endpoint  string
+	Reference upspin.Reference
+}
+
+// jsonInfo is the form of a DirEntry printed by info -json.
+type jsonInfo struct {
+	jsonEntry
+	// Access is the Access file that applies to the entry,
+	// or "owner only" if there is none.
+	Access string
+	// Packer is the name of the packer for the entry's packing,
+	// or empty if it is not available in this program.
+	Packer string
+}
+
+func newJSONEntry(e *upspin.DirEntry) jsonEntry {
+	size, err := e.Size()
+	if err != nil {
+		size = 0
+	}
+	j := jsonEntry{
+		Name:     e.Name,
+		Attr:     attrFormat(e.Attr),
+		Sequence: e.Sequence,
+		Time:     e.Time.Go().UTC(),
+		Size:     size,
+		Blocks:   []jsonBlock{}, // Print [], not null, if there are none.
+		Writer:   e.Writer,
+		Packing:  e.Packing.String(),
+		Link:     e.Link,
+	}
+	for _, b := range e.Blocks {
+		j.Blocks = append(j.Blocks, jsonBlock{
+			Location: jsonLocation{
+				Endpoint:  b.Location.Endpoint.String(),
+				Reference: b.Location.Reference,
+			},
+			Offset: b.Offset,
+			Size:   b.Size,
+		})
+	}
+	return j
+}
+
+// writeJSON writes v to w as JSON followed by a newline.
+func writeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// printJSON prints the entries, one JSON object per line.
+func (s *State) printJSON(entries []*upspin.DirEntry) {
+	for _, e := range entries {
+		if err := writeJSON(s.Stdout, newJSONEntry(e)); err != nil {
+			s.Exitf("writing JSON: %v", err)
+		}
+	}
+}
+
+// printInfoJSON prints the entry as info -json does.
+func (s *State) printInfoJSON(e *upspin.DirEntry) {
+	info := jsonInfo{
+		jsonEntry: newJSONEntry(e),
+		Access:    (&infoDirEntry{state: s, DirEntry: e}).WhichAccess(),
+	}
+	if packer := pack.Lookup(e.Packing); packer != nil {
+		info.Packer = packer.String()
+	}
+	if err := writeJSON(s.Stdout, info); err != nil {
+		s.Exitf("writing JSON: %v", err)
+	}
+}
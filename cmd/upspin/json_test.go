@@ -0,0 +1,41 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"upspin.io/upspin"
+)
+
+func TestJSONEntry(t *testing.T) {
+	store := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "store.example.com:443"}
+	entry := &upspin.DirEntry{
+		Name:     "ann@example.com/dir/file",
+		Attr:     upspin.AttrNone,
+		Writer:   "chris@example.com",
+		Packing:  upspin.EEPack,
+		Sequence: 17,
+		Time:     upspin.TimeFromGo(time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC)),
+		Blocks: []upspin.DirBlock{
+			{Location: upspin.Location{Endpoint: store, Reference: "ref1"}, Offset: 0, Size: 100},
+			{Location: upspin.Location{Endpoint: store, Reference: "ref2"}, Offset: 100, Size: 23},
+		},
+	}
+	const want = `{"Name":"ann@example.com/dir/file","Attr":"none (plain file)","Sequence":17,` +
+		`"Time":"2017-06-01T12:30:00Z","Size":123,"Blocks":[` +
+		`{"Location":{"Endpoint":"remote,store.example.com:443","Reference":"ref1"},"Offset":0,"Size":100},` +
+		`{"Location":{"Endpoint":"remote,store.example.com:443","Reference":"ref2"},"Offset":100,"Size":23}],` +
+		`"Writer":"chris@example.com","Packing":"ee"}` + "\n"
+	var b bytes.Buffer
+	if err := writeJSON(&b, newJSONEntry(entry)); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got\n\t%s\nwant\n\t%s", got, want)
+	}
+}
@@ -14,20 +14,42 @@ import (
 	"strings"
 
 	"upspin.io/errors"
+	"upspin.io/factotum"
 	"upspin.io/key/keygen"
+	"upspin.io/pack/ee"
 	"upspin.io/subcmd"
 )
 
 func (s *State) keygen(args ...string) {
 	const help = `
 Keygen creates a new Upspin key pair and stores the pair in local files
-secret.upspinkey and public.upspinkey in the specified directory.
-Existing key pairs are appended to secret2.upspinkey.
+secret.upspinkey and public.upspinkey in the specified directory, given
+either as the argument or with the -where flag.
+Existing key pairs are appended to secret2.upspinkey. With -rotate,
+the existing key files are first copied to a new subdirectory of
+backup in the same directory, named for the current time, and the new
+files are written in full before any existing file is replaced.
 Keygen does not update the information in the key server;
 use the "user -put" command for that.
 
+The -curve flag selects the elliptic curve of the new key: p256 (the
+default), p384, or p521.
+
+Keygen never prompts, so it may be run from scripts. The -print-new-public
+flag prints the new public key on standard output, ready to be used in a
+user record, and the -quiet flag suppresses the report on standard error,
+including the secret seed from which the keys can be re-created.
+
 New users should instead use the "signup" command to create their first key.
 
+The -print-public and -fingerprint flags instead report on the current
+key, the one held by the configured factotum, and take no directory.
+The -print-public flag prints the public key in the form stored in
+public.upspinkey and the key server. The -fingerprint flag prints in
+hexadecimal the SHA-256 hash of the public key, the same hash the ee
+packing uses to identify a file's readers, so that users exchanging
+keys can compare them easily.
+
 See the description for rotate for information about updating keys.
 `
 	// Keep flags in sync with signup.go. New flags here should appear
@@ -37,20 +59,38 @@ See the description for rotate for information about updating keys.
 		curve      = fs.String("curve", "p256", "cryptographic curve `name`: p256, p384, or p521")
 		secretSeed = fs.String("secretseed", "", "the seed containing a 128-bit secret in proquint format or a file that contains it")
 		rotate     = fs.Bool("rotate", false, "back up the existing keys and replace them with new ones")
+		where      = fs.String("where", "", "`directory` to store the key pair")
+		printNew   = fs.Bool("print-new-public", false, "print the new public key on standard output")
+		quiet      = fs.Bool("quiet", false, "do not report the new keys on standard error")
+		printPub   = fs.Bool("print-public", false, "print the current public key and exit")
+		fprint     = fs.Bool("fingerprint", false, "print the SHA-256 hash of the current public key and exit")
 	)
-	s.ParseFlags(fs, args, help, "keygen [-curve=256] [-secretseed=seed] <directory>")
-	if fs.NArg() != 1 {
+	s.ParseFlags(fs, args, help, "keygen [-curve=p256] [-secretseed=seed] [-print-new-public] [-quiet] -where=<directory> | <directory>\n       upspin keygen -print-public | -fingerprint")
+	if *printPub || *fprint {
+		if fs.NArg() != 0 || *printPub == *fprint {
+			usageAndExit(fs)
+		}
+		s.printKey(*fprint)
+		return
+	}
+	switch {
+	case *where == "" && fs.NArg() == 1:
+		*where = fs.Arg(0)
+	case *where == "" || fs.NArg() != 0:
 		usageAndExit(fs)
 	}
-	s.keygenCommand(fs.Arg(0), *curve, *secretSeed, *rotate)
+	public := s.keygenCommand(*where, *curve, *secretSeed, *rotate, *quiet)
+	if *printNew {
+		fmt.Fprint(s.Stdout, public)
+	}
 }
 
-func (s *State) keygenCommand(where, curve, secretseed string, rotate bool) {
-	switch curve {
-	case "p256", "p384", "p521":
-		// ok
-	default:
-		s.Exitf("no such curve %q", curve)
+// keygenCommand creates a key pair on the named curve, saves it in the
+// directory where, and returns the new public key. Unless quiet is set,
+// it reports what it did, and how to re-create the keys, on standard error.
+func (s *State) keygenCommand(where, curve, secretseed string, rotate, quiet bool) string {
+	if !validCurve(curve) {
+		s.Exitf("unsupported curve %q; must be one of %s", curve, strings.Join(ee.Curves, ", "))
 	}
 
 	public, private, secretStr, err := s.createKeys(curve, secretseed)
@@ -58,13 +98,24 @@ func (s *State) keygenCommand(where, curve, secretseed string, rotate bool) {
 		s.Exitf("creating keys: %v", err)
 	}
 
-	err = keygen.SaveKeys(where, rotate, public, private, secretStr)
+	var backup string
+	if rotate {
+		backup, err = keygen.RotateKeys(where, public, private, secretStr)
+	} else {
+		err = keygen.SaveKeys(where, false, public, private, secretStr)
+	}
 	if err != nil {
 		s.Exitf("keys not generated: %s", err)
 	}
+	if quiet {
+		return public
+	}
 	if rotate {
 		archiveFile := filepath.Join(where, "secret2.upspinkey")
 		fmt.Fprintf(s.Stderr, "Saved previous key pair to:\n\t%s\n", archiveFile)
+		if backup != "" {
+			fmt.Fprintf(s.Stderr, "Backed up previous key files to:\n\t%s\n", backup)
+		}
 	}
 
 	fmt.Fprintln(s.Stderr, "Upspin private/public key pair written to:")
@@ -81,6 +132,31 @@ func (s *State) keygenCommand(where, curve, secretseed string, rotate bool) {
 		fmt.Fprintln(s.Stderr, "\nTo install new keys in the key server, see 'upspin rotate -help'.")
 	}
 	fmt.Fprintln(s.Stderr)
+	return public
+}
+
+// validCurve reports whether the named curve is supported by the ee packing.
+func validCurve(name string) bool {
+	for _, c := range ee.Curves {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// printKey prints the public key held by the configured factotum,
+// or its hash if fingerprint is set.
+func (s *State) printKey(fingerprint bool) {
+	f := s.Config.Factotum()
+	if f == nil {
+		s.Exitf("no factotum available")
+	}
+	if fingerprint {
+		fmt.Fprintf(s.Stdout, "%x\n", factotum.KeyHash(f.PublicKey()))
+		return
+	}
+	fmt.Fprint(s.Stdout, f.PublicKey())
 }
 
 func (s *State) createKeys(curveName, secretFlag string) (public, private, secretStr string, err error) {
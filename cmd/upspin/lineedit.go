@@ -0,0 +1,226 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"upspin.io/upspin"
+)
+
+// A lineEditor reads lines typed at a terminal, echoing them and
+// completing the word before the cursor when the user types a tab.
+// Its editing is minimal: besides tab, it understands only backspace,
+// ^U to erase the line, ^C to abandon it, and ^D on an empty line
+// for end of file. Other control characters and escape sequences,
+// such as those sent by the arrow keys, are ignored.
+type lineEditor struct {
+	in     *bufio.Reader
+	out    io.Writer
+	prompt string
+	// raw, if not nil, puts the terminal into raw mode for the
+	// duration of a readLine and returns a function that restores it.
+	raw func() (restore func(), err error)
+	// complete returns the possible completions of word, the last
+	// word of line, each a full replacement for word.
+	complete func(line, word string) []string
+}
+
+// Control characters understood by the lineEditor.
+const (
+	ctrlC     = 0x03
+	ctrlD     = 0x04
+	backspace = 0x08
+	tab       = 0x09
+	ctrlU     = 0x15
+	esc       = 0x1b
+	del       = 0x7f
+)
+
+// readLine prints the prompt and returns the line typed in response.
+// It returns io.EOF if the user types ^D on an empty line.
+func (e *lineEditor) readLine() (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	fmt.Fprint(e.out, e.prompt)
+	var line []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				err = nil
+			}
+			fmt.Fprint(e.out, "\n")
+			return string(line), err
+		}
+		switch {
+		case c == '\r' || c == '\n':
+			fmt.Fprint(e.out, "\n")
+			return string(line), nil
+		case c == ctrlD:
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+		case c == ctrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", nil
+		case c == backspace || c == del:
+			if len(line) > 0 {
+				_, n := utf8.DecodeLastRune(line)
+				line = line[:len(line)-n]
+				fmt.Fprint(e.out, "\b \b")
+			}
+		case c == ctrlU:
+			fmt.Fprint(e.out, strings.Repeat("\b \b", utf8.RuneCount(line)))
+			line = line[:0]
+		case c == tab:
+			line = e.completeLine(line)
+		case c == esc:
+			e.skipEscape()
+		case c < ' ':
+			// Ignore other control characters.
+		default:
+			line = append(line, c)
+			e.out.Write([]byte{c})
+		}
+	}
+}
+
+// skipEscape discards the rest of an escape sequence, such as
+// "ESC [ A" for the up arrow.
+func (e *lineEditor) skipEscape() {
+	c, err := e.in.ReadByte()
+	if err != nil || (c != '[' && c != 'O') {
+		return
+	}
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil || ('@' <= c && c <= '~') {
+			return
+		}
+	}
+}
+
+// completeLine completes the last word of line. If there is just one
+// possible completion, it replaces the word, followed by a space unless
+// the completion is a directory. If there are several, it extends the
+// word to their longest common prefix, or, if that adds nothing, lists
+// them and prints the line again.
+func (e *lineEditor) completeLine(line []byte) []byte {
+	if e.complete == nil {
+		return line
+	}
+	start := strings.LastIndexAny(string(line), " \t|") + 1
+	word := string(line[start:])
+	completions := e.complete(string(line), word)
+	if len(completions) == 0 {
+		return line
+	}
+	add := ""
+	if len(completions) == 1 {
+		add = completions[0][len(word):]
+		if !strings.HasSuffix(add, "/") {
+			add += " "
+		}
+	} else if prefix := commonPrefix(completions); len(prefix) > len(word) {
+		add = prefix[len(word):]
+	} else {
+		// List the final elements only.
+		dir := strings.LastIndex(word, "/") + 1
+		fmt.Fprint(e.out, "\n")
+		for _, c := range completions {
+			fmt.Fprintf(e.out, "%s  ", c[dir:])
+		}
+		fmt.Fprintf(e.out, "\n%s%s", e.prompt, line)
+		return line
+	}
+	fmt.Fprint(e.out, add)
+	return append(line, add...)
+}
+
+// commonPrefix returns the longest common prefix of the strings.
+func commonPrefix(strs []string) string {
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// Don't split a character.
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// completionTimeout bounds the time spent listing a directory
+// to complete a path name.
+const completionTimeout = 2 * time.Second
+
+// completions returns the possible completions of word, the last word of
+// the shell command line. If word is the first of its command, it is
+// completed as a command name. Otherwise, if it holds an @ sign, it is
+// completed as an Upspin path name, preserving any leading @ shorthand
+// for the current user. Directories are completed with a trailing slash.
+// If the directory cannot be listed, perhaps for lack of rights, or
+// does not list within completionTimeout, there are no completions.
+func (s *State) completions(line, word string) []string {
+	segment := line[strings.LastIndex(line, "|")+1:]
+	if len(strings.Fields(strings.TrimSuffix(segment, word))) == 0 {
+		var names []string
+		for name := range commands {
+			if strings.HasPrefix(name, word) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	if !strings.Contains(word, "@") {
+		return nil
+	}
+	slash := strings.LastIndex(word, "/")
+	if slash < 0 {
+		return nil
+	}
+	dir, elem := word[:slash+1], word[slash+1:]
+	pattern := upspin.QuoteGlob(s.AtSign(dir)) + upspin.QuoteGlob(upspin.PathName(elem)) + "*"
+
+	// The goroutine is abandoned if it is too slow.
+	done := make(chan []*upspin.DirEntry, 1)
+	go func() {
+		entries, _ := s.Client.Glob(string(pattern))
+		done <- entries
+	}()
+	var entries []*upspin.DirEntry
+	select {
+	case entries = <-done:
+	case <-time.After(completionTimeout):
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		name := string(e.Name)
+		name = dir + name[strings.LastIndex(name, "/")+1:]
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
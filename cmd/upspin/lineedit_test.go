@@ -0,0 +1,84 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	words := []string{"ann@example.com/dir/", "ann@example.com/docs/", "ann@example.com/file"}
+	complete := func(line, word string) []string {
+		var c []string
+		for _, w := range words {
+			if strings.HasPrefix(w, word) {
+				c = append(c, w)
+			}
+		}
+		return c
+	}
+	tests := []struct {
+		in    string
+		lines []string
+	}{
+		{"ls\r", []string{"ls"}},
+		{"lx\x7fs\n", []string{"ls"}},
+		{"junk\x15ls\r", []string{"ls"}},
+		{"junk\x03ls\r", []string{"", "ls"}},
+		{"ls\x1b[Ax\x1bOB\r", []string{"lsx"}},
+		{"ls a\tf\t\r", []string{"ls ann@example.com/file "}},
+		{"ls ann@example.com/d\t\too\r", []string{"ls ann@example.com/doo"}},
+		{"ls ann@example.com/do\t\r", []string{"ls ann@example.com/docs/"}},
+		{"ls x\t\r", []string{"ls x"}},
+		{"ls\r\x04", []string{"ls"}},
+		{"ls", []string{"ls"}},
+	}
+	for _, test := range tests {
+		out := new(bytes.Buffer)
+		e := &lineEditor{
+			in:       bufio.NewReader(strings.NewReader(test.in)),
+			out:      out,
+			prompt:   "> ",
+			complete: complete,
+		}
+		var lines []string
+		for {
+			line, err := e.readLine()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		if strings.Join(lines, "|") != strings.Join(test.lines, "|") {
+			t.Errorf("%q: got lines %q, want %q", test.in, lines, test.lines)
+		}
+	}
+}
+
+func TestLineEditorListsCompletions(t *testing.T) {
+	out := new(bytes.Buffer)
+	e := &lineEditor{
+		in:     bufio.NewReader(strings.NewReader("cat @/d\t\r")),
+		out:    out,
+		prompt: "> ",
+		complete: func(line, word string) []string {
+			return []string{"@/dir/", "@/docs"}
+		},
+	}
+	if _, err := e.readLine(); err != nil {
+		t.Fatal(err)
+	}
+	const want = "> cat @/d\ndir/  docs  \n> cat @/d\n"
+	if got := out.String(); got != want {
+		t.Errorf("output is %q, want %q", got, want)
+	}
+}
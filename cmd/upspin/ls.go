@@ -18,12 +18,46 @@ Ls lists the names and, if requested, other properties of Upspin
 files and directories. If given no path arguments, it lists the
 user's root. By default ls does not follow links; use the -L flag
 to learn about the targets of links.
-`
+
+The -dirs-first and -files-first flags group the entries of each
+listing, printing directories before or after everything else.
+Links are grouped with files, or, with -L, according to their targets.
+Entries remain sorted by name within each group.
+` + formatHelp + jsonHelp
 	fs := flag.NewFlagSet("ls", flag.ExitOnError)
 	longFormat := fs.Bool("l", false, "long format")
 	followLinks := fs.Bool("L", false, "follow links")
 	recur := fs.Bool("R", false, "recur into subdirectories")
-	s.ParseFlags(fs, args, help, "ls [-l] [path...]")
+	format := fs.String("format", "", "Go `template` for printing each entry")
+	jsonOut := fs.Bool("json", false, "print each entry as JSON")
+	dirsFirst := fs.Bool("dirs-first", false, "list directories before files")
+	filesFirst := fs.Bool("files-first", false, "list files before directories")
+	s.ParseFlags(fs, args, help, "ls [-l] [-format=template] [-json] [-dirs-first | -files-first] [path...]")
+
+	if *jsonOut && *format != "" {
+		usageAndExit(fs)
+	}
+	if *dirsFirst && *filesFirst {
+		usageAndExit(fs)
+	}
+	tmpl := s.formatFlag(*format)
+	var printEntries func([]*upspin.DirEntry)
+	switch {
+	case *jsonOut:
+		printEntries = s.printJSON
+	case tmpl != nil:
+		printEntries = func(entries []*upspin.DirEntry) { s.printFormatted(tmpl, entries) }
+	case *longFormat:
+		printEntries = s.printLongDirEntries
+	default:
+		printEntries = s.printShortDirEntries
+	}
+	if *dirsFirst || *filesFirst {
+		ungrouped := printEntries
+		printEntries = func(entries []*upspin.DirEntry) { ungrouped(groupEntries(entries, *dirsFirst)) }
+	}
+	// Headers for subdirectories would break machine-readable output.
+	headers := !*jsonOut && tmpl == nil
 
 	done := map[upspin.PathName]bool{}
 	if fs.NArg() == 0 {
@@ -32,17 +66,20 @@ to learn about the targets of links.
 		if err != nil {
 			s.Exit(err)
 		}
-		s.list(rootEntry, done, *longFormat, *followLinks, *recur)
+		s.list(rootEntry, done, printEntries, headers, *followLinks, *recur)
 		return
 	}
 	// The done map marks a directory we have listed, so we don't recur endlessly
 	// when given a chain of links with -L.
 	for _, entry := range s.GlobAllUpspin(fs.Args()) {
-		s.list(entry, done, *longFormat, *followLinks, *recur)
+		s.list(entry, done, printEntries, headers, *followLinks, *recur)
 	}
 }
 
-func (s *State) list(entry *upspin.DirEntry, done map[upspin.PathName]bool, longFormat, followLinks, recur bool) {
+// list prints, using printEntries, the entry or, if it is a directory, its
+// contents. If headers is set, the contents of each subdirectory visited when
+// recurring are preceded by its name.
+func (s *State) list(entry *upspin.DirEntry, done map[upspin.PathName]bool, printEntries func([]*upspin.DirEntry), headers, followLinks, recur bool) {
 	done[entry.Name] = true
 
 	var dirContents []*upspin.DirEntry
@@ -70,21 +107,34 @@ func (s *State) list(entry *upspin.DirEntry, done map[upspin.PathName]bool, long
 		}
 	}
 
-	if longFormat {
-		s.printLongDirEntries(dirContents)
-	} else {
-		s.printShortDirEntries(dirContents)
-	}
+	printEntries(dirContents)
 
 	if !recur {
 		return
 	}
 	for _, entry := range dirContents {
 		if entry.IsDir() && !done[entry.Name] {
-			s.Printf("\n%s:\n", entry.Name)
-			s.list(entry, done, longFormat, followLinks, recur)
+			if headers {
+				s.Printf("\n%s:\n", entry.Name)
+			}
+			s.list(entry, done, printEntries, headers, followLinks, recur)
+		}
+	}
+}
+
+// groupEntries returns a copy of the entries with the directories moved
+// before the other entries if dirsFirst is set, or after them otherwise.
+// The order within each group is preserved.
+func groupEntries(entries []*upspin.DirEntry, dirsFirst bool) []*upspin.DirEntry {
+	grouped := make([]*upspin.DirEntry, 0, len(entries))
+	for _, wantDir := range []bool{dirsFirst, !dirsFirst} {
+		for _, e := range entries {
+			if e.IsDir() == wantDir {
+				grouped = append(grouped, e)
+			}
 		}
 	}
+	return grouped
 }
 
 func hasFinalSlash(name upspin.PathName) bool {
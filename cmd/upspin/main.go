@@ -76,18 +76,25 @@ For a list of available subcommands and global flags, run
 `
 
 var commands = map[string]func(*State, ...string){
+	"access":             (*State).access,
 	"countersign":        (*State).countersign,
 	"cp":                 (*State).cp,
+	"convert":            (*State).convert,
 	"config":             (*State).config,
 	"createsuffixeduser": (*State).createsuffixeduser,
 	"deletestorage":      (*State).deletestorage,
+	"doctor":             (*State).doctor,
+	"du":                 (*State).du,
+	"find":               (*State).find,
 	"get":                (*State).get,
 	"getref":             (*State).getref,
+	"history":            (*State).history,
 	"info":               (*State).info,
 	"keygen":             (*State).keygen,
 	"link":               (*State).link,
 	"ls":                 (*State).ls,
 	"mkdir":              (*State).mkdir,
+	"mv":                 (*State).mv,
 	"put":                (*State).put,
 	"repack":             (*State).repack,
 	"rotate":             (*State).rotate,
@@ -100,6 +107,7 @@ var commands = map[string]func(*State, ...string){
 	"snapshot":           (*State).snapshot,
 	"tar":                (*State).tar,
 	"user":               (*State).user,
+	"verify":             (*State).verify,
 	"watch":              (*State).watch,
 	"whichaccess":        (*State).whichAccess,
 }
@@ -245,6 +253,19 @@ func printCommands() {
 // If the command still can't be found, it exits after listing the
 // commands that do exist.
 func (s *State) getCommand(op string) func(*State, ...string) {
+	fn := lookupCommand(op)
+	if fn != nil {
+		return fn
+	}
+	printCommands()
+	s.Exitf("no such command %q", strings.ToLower(op))
+	return nil
+}
+
+// lookupCommand returns the function that runs the named subcommand,
+// either built in or provided by an upspin-op binary in the path.
+// It returns nil if there is no such command.
+func lookupCommand(op string) func(*State, ...string) {
 	op = strings.ToLower(op)
 	fn := commands[op]
 	if fn != nil {
@@ -256,16 +277,14 @@ func (s *State) getCommand(op string) func(*State, ...string) {
 			s.runCommand(path, append(flags.Args(), args...)...)
 		}
 	}
-	printCommands()
-	s.Exitf("no such command %q", op)
 	return nil
 }
 
 func (s *State) runCommand(path string, args ...string) {
 	cmd := exec.Command(path, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdin = s.Stdin
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = s.Stderr
 	err := cmd.Run()
 	if err != nil {
 		s.Exit(err)
@@ -286,28 +305,18 @@ func newState(name string) *State {
 func (s *State) init() {
 	// signup is special since there is no user yet.
 	// keygen simply does not require a config or anything else.
-	if s.Name != "signup" && s.Name != "keygen" {
-		// Read the config file and pass it to config.InitConfig
+	// doctor loads the config itself, to diagnose problems with it.
+	if s.Name != "signup" && s.Name != "keygen" && s.Name != "doctor" {
+		// Read the config file and pass it to config.InitClientConfig
 		// instead of calling config.FromFile, so that we can stash its
-		// contents away for later use by the "config" sub-command.
-		data, err := os.ReadFile(flags.Config)
-		// Duplicate the logic of config.FromFile that looks for the
-		// config in $HOME/upspin/config if it can't be found at its
-		// specified location.
-		if os.IsNotExist(err) {
-			home, err2 := config.Homedir()
-			if err2 == nil {
-				data, err2 = os.ReadFile(filepath.Join(home, "upspin", flags.Config))
-				if err2 == nil {
-					err = nil
-				}
-			}
-		}
+		// contents away for later use by the "config" sub-command and
+		// so that the environment may override it.
+		data, err := readConfigFile()
 		if err != nil {
 			s.Exit(err)
 		}
 
-		cfg, err := config.InitConfig(bytes.NewReader(data))
+		cfg, err := config.InitClientConfig(bytes.NewReader(data))
 		if err != nil && err != config.ErrNoFactotum {
 			s.Exit(err)
 		}
@@ -319,6 +328,25 @@ func (s *State) init() {
 	s.enableMetrics()
 }
 
+// readConfigFile returns the contents of the config file named by the
+// -config flag.
+func readConfigFile() ([]byte, error) {
+	data, err := os.ReadFile(flags.Config)
+	// Duplicate the logic of config.FromFile that looks for the
+	// config in $HOME/upspin/config if it can't be found at its
+	// specified location.
+	if os.IsNotExist(err) {
+		home, err2 := config.Homedir()
+		if err2 == nil {
+			data, err2 = os.ReadFile(filepath.Join(home, "upspin", flags.Config))
+			if err2 == nil {
+				err = nil
+			}
+		}
+	}
+	return data, err
+}
+
 func (s *State) Printf(format string, args ...interface{}) {
 	fmt.Fprintf(s.Stdout, format, args...)
 }
@@ -348,6 +376,14 @@ func globFlag(fs *flag.FlagSet) *bool {
 	return fs.Bool("glob", true, "apply glob processing to the arguments")
 }
 
+// dryRunFlag sets "-n=false" and its synonym "-dry-run=false" in the FlagSet.
+func dryRunFlag(fs *flag.FlagSet) *bool {
+	dryRun := new(bool)
+	fs.BoolVar(dryRun, "n", false, "print what would be done but do not do it")
+	fs.BoolVar(dryRun, "dry-run", false, "same as -n")
+	return dryRun
+}
+
 // expandUpspin turns the list of string arguments into Upspin path names.
 // If glob is true, it "globs" and @-expands the arguments.
 // Otherwise, it interprets leading @ symbols but does no other processing.
@@ -6,7 +6,9 @@ package main
 
 import (
 	"flag"
+	"strings"
 
+	"upspin.io/access"
 	"upspin.io/errors"
 	"upspin.io/path"
 	"upspin.io/upspin"
@@ -19,20 +21,55 @@ Mkdir creates Upspin directories.
 The -p flag can be set to have mkdir create any missing parent directories of
 each argument.
 
+The -access flag can be set to the contents of an Access file to write
+into each new directory, with lines separated by newlines or by the two
+characters \n, as in
+	upspin mkdir -access 'r: friends\n*: ann@example.com' @/Shared
+The contents are checked before any directory is created. If the Access
+file cannot be written, the new directory is removed.
+
 The -glob flag can be set to false to have mkdir skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 `
 	fs := flag.NewFlagSet("mkdir", flag.ExitOnError)
 	parent := fs.Bool("p", false, "make all parent directories")
+	accessText := fs.String("access", "", "contents of an Access `file` to write into each new directory")
 	glob := globFlag(fs)
-	s.ParseFlags(fs, args, help, "mkdir [-p] directory...")
+	s.ParseFlags(fs, args, help, "mkdir [-p] [-access=contents] directory...")
 	if fs.NArg() == 0 {
 		usageAndExit(fs)
 	}
-	for _, name := range s.expandUpspin(fs.Args(), *glob) {
+	names := s.expandUpspin(fs.Args(), *glob)
+	var data []byte
+	if *accessText != "" {
+		data = []byte(strings.Replace(*accessText, `\n`, "\n", -1) + "\n")
+		// Validate the Access file for every directory before creating any.
+		for _, name := range names {
+			if _, err := access.Parse(path.Join(name, access.AccessFile), data); err != nil {
+				s.Exit(err)
+			}
+		}
+	}
+	for _, name := range names {
 		s.doMkdir(name, *parent)
+		if data != nil {
+			s.putAccess(name, data)
+		}
+	}
+}
+
+// putAccess writes an Access file with the given contents into the newly
+// created directory. If that fails, it removes the directory and exits.
+func (s *State) putAccess(dir upspin.PathName, data []byte) {
+	name := path.Join(dir, access.AccessFile)
+	if _, err := s.Client.Put(name, data); err != nil {
+		if delErr := s.Client.Delete(dir); delErr != nil {
+			s.Exitf("writing %s: %v; removing %s: %v", name, err, dir, delErr)
+		}
+		s.Exit(err)
 	}
+	s.sharer = newSharer(s)
 }
 
 func (s *State) doMkdir(name upspin.PathName, parent bool) {
@@ -0,0 +1,152 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+
+	"upspin.io/access"
+	"upspin.io/errors"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+func (s *State) mv(args ...string) {
+	const help = `
+Mv renames Upspin files. If the final argument is an existing
+directory, the other arguments are moved into it, keeping their final
+path elements as their names. Otherwise mv requires exactly two path
+names and renames the first to the second.
+
+Mv will not replace an existing file unless the -f flag is set.
+Directories cannot be moved, and Access and Group files cannot be
+renamed.
+
+Like cp, mv copies only the references to the data, not the data
+itself, re-wrapping keys for the readers of the new location if the
+file moves to a different directory.
+
+The -n (or -dry-run) flag makes mv print, after expanding its arguments,
+the renames it would do, without doing them. An existing file that -f
+would replace is reported as being removed first.
+`
+	fs := flag.NewFlagSet("mv", flag.ExitOnError)
+	force := fs.Bool("f", false, "replace existing files")
+	dryRun := dryRunFlag(fs)
+	s.ParseFlags(fs, args, help, "mv [-f] [-n] path... path or mv [-f] [-n] path... directory")
+	if fs.NArg() < 2 {
+		usageAndExit(fs)
+	}
+
+	nSrc := fs.NArg() - 1
+	srcs := s.GlobAllUpspinPath(fs.Args()[:nSrc])
+	dsts := s.GlobUpspinPath(fs.Arg(nSrc))
+	if len(dsts) != 1 {
+		s.Exitf("more than one file matches %q", fs.Arg(nSrc))
+	}
+	dst := dsts[0]
+
+	// Follow links so a link to a directory is treated as the directory.
+	entry, err := s.Client.Lookup(dst, true)
+	switch {
+	case err == nil && entry.IsDir():
+		for _, src := range srcs {
+			p, err := path.Parse(src)
+			if err != nil {
+				s.Fail(err)
+				continue
+			}
+			if p.IsRoot() {
+				s.Fail(errors.E(src, errors.IsDir, "cannot move a root"))
+				continue
+			}
+			s.move(src, path.Join(entry.Name, p.Elem(p.NElem()-1)), *force, *dryRun)
+		}
+		return
+	case err != nil && !errors.Is(errors.NotExist, err):
+		s.Exit(err)
+	}
+	if len(srcs) != 1 {
+		s.Exitf("moving multiple files but %s is not a directory", dst)
+	}
+	s.move(srcs[0], dst, *force, *dryRun)
+}
+
+// move renames oldName to newName. If force is set, an existing
+// file named newName is replaced. If dryRun is set, it prints
+// what it would do instead of doing it.
+func (s *State) move(oldName, newName upspin.PathName, force, dryRun bool) {
+	if path.Clean(oldName) == path.Clean(newName) {
+		s.Fail(errors.E(oldName, errors.Invalid, "source and destination are the same"))
+		return
+	}
+	if dryRun {
+		s.previewMove(oldName, newName, force)
+		return
+	}
+	err := s.rename(oldName, newName)
+	if force && errors.Is(errors.Exist, err) {
+		// Delete the existing file, but never a directory, and try again.
+		var entry *upspin.DirEntry
+		entry, err = s.Client.Lookup(newName, false)
+		if err == nil {
+			if entry.IsDir() {
+				err = errors.E(newName, errors.IsDir, "cannot replace a directory")
+			} else if err = s.Client.Delete(newName); err == nil {
+				err = s.rename(oldName, newName)
+			}
+		}
+	}
+	if err != nil {
+		s.Fail(err)
+	}
+}
+
+// rename renames oldName to newName, falling back to PutDuplicate
+// and Delete if the client does not support Rename.
+func (s *State) rename(oldName, newName upspin.PathName) error {
+	_, err := s.Client.Rename(oldName, newName)
+	if err != upspin.ErrNotSupported {
+		return err
+	}
+	if _, err := s.Client.PutDuplicate(oldName, newName); err != nil {
+		return err
+	}
+	return s.Client.Delete(oldName)
+}
+
+// previewMove prints what move would do to rename oldName to newName,
+// reporting the errors it can foresee, but changes nothing.
+func (s *State) previewMove(oldName, newName upspin.PathName, force bool) {
+	entry, err := s.Client.Lookup(oldName, false)
+	switch {
+	case err != nil:
+		s.Fail(err)
+		return
+	case entry.IsDir():
+		s.Fail(errors.E(oldName, errors.IsDir, "cannot rename directories"))
+		return
+	case access.IsAccessControlFile(newName):
+		s.Fail(errors.E(newName, errors.Invalid, "Access or Group files cannot be renamed"))
+		return
+	}
+	entry, err = s.Client.Lookup(newName, false)
+	switch {
+	case errors.Is(errors.NotExist, err):
+		// Nothing to replace.
+	case err != nil:
+		s.Fail(err)
+		return
+	case entry.IsDir():
+		s.Fail(errors.E(newName, errors.IsDir, "cannot replace a directory"))
+		return
+	case !force:
+		s.Fail(errors.E(newName, errors.Exist))
+		return
+	default:
+		s.Printf("rm %s\n", newName)
+	}
+	s.Printf("mv %s %s\n", oldName, newName)
+}
@@ -6,13 +6,14 @@ package main
 
 import (
 	"flag"
+	"io"
 
 	"upspin.io/access"
 	"upspin.io/client"
 	"upspin.io/config"
-	"upspin.io/pack"
 	"upspin.io/path"
 	"upspin.io/subcmd"
+	"upspin.io/upspin"
 )
 
 func (s *State) put(args ...string) {
@@ -23,18 +24,23 @@ entry with the given path name to refer to the data.
 The -glob flag can be set to false to have put skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
+
+The -bwlimit flag limits the rate at which the input is read, and
+thus transferred, in bytes per second.
 `
 	fs := flag.NewFlagSet("put", flag.ExitOnError)
 	inFile := fs.String("in", "", "input file (default standard input)")
-	packing := fs.String("packing", "", "packing to use (default from user's config)")
+	packing := fs.String("packing", "", "packing to use, one of "+packingNames()+" (default from user's config)")
 	glob := globFlag(fs)
+	bwlimit := bwlimitFlag(fs)
 	s.ParseFlags(fs, args, help, "put [-in=inputfile] path")
 
 	if fs.NArg() != 1 {
 		usageAndExit(fs)
 	}
 
-	data := s.ReadAll(*inFile)
+	input := s.openInput(*inFile)
+	defer input.Close()
 	// Must be a valid Upspin name.
 	parsed, err := path.Parse(s.AtSign(fs.Arg(0)))
 	if err != nil {
@@ -54,13 +60,10 @@ characters. (Leading @ signs are always expanded.)
 	}
 	cl := s.Client
 	if *packing != "" {
-		p := pack.LookupByName(*packing)
-		if p == nil {
-			s.Exitf("no such packing %q", *packing)
-		}
+		p := s.packerByName(*packing)
 		cl = client.New(config.SetPacking(s.Config, p.Packing()))
 	}
-	_, err = cl.Put(name, data)
+	_, err = putReader(cl, name, newRateLimiter(*bwlimit).Reader(input))
 	if err != nil {
 		s.Exit(err)
 	}
@@ -74,3 +77,25 @@ characters. (Leading @ signs are always expanded.)
 		}
 	}
 }
+
+// openInput opens the named local file, or returns standard input if the
+// name is empty.
+func (s *State) openInput(fileName string) io.ReadCloser {
+	if fileName == "" {
+		return io.NopCloser(s.Stdin)
+	}
+	return s.OpenLocal(subcmd.Tilde(fileName))
+}
+
+// putReader stores the data read from r under the name. If the client
+// supports it, the data is streamed rather than read into memory first.
+func putReader(cl upspin.Client, name upspin.PathName, r io.Reader) (*upspin.DirEntry, error) {
+	if rp, ok := cl.(upspin.ReaderPutter); ok {
+		return rp.PutReader(name, r)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return cl.Put(name, data)
+}
@@ -0,0 +1,16 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import "upspin.io/errors"
+
+// makeRaw reports that raw terminal mode is not supported
+// on this system.
+func makeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errors.Str("raw terminal mode not supported")
+}
@@ -11,7 +11,6 @@ import (
 
 	"upspin.io/client"
 	"upspin.io/config"
-	"upspin.io/pack"
 	"upspin.io/subcmd"
 	"upspin.io/upspin"
 )
@@ -29,7 +28,7 @@ for more information.
 `
 	fs := flag.NewFlagSet("repack", flag.ExitOnError)
 	fs.Bool("f", false, "force repack even if the file is already packed as requested")
-	fs.String("pack", "ee", "packing to use when rewriting")
+	fs.String("pack", "ee", "packing to use when rewriting, one of "+packingNames())
 	fs.Bool("r", false, "recur into subdirectories")
 	fs.Bool("v", false, "verbose: log progress")
 	s.ParseFlags(fs, args, help, "repack [-pack ee] [flags] path...")
@@ -43,10 +42,7 @@ for more information.
 // repackCommand implements the repack command. It builds a temporary client
 // with the new packing and iterates over the files.
 func (s *State) repackCommand(fs *flag.FlagSet) {
-	packer := pack.LookupByName(subcmd.StringFlag(fs, "pack"))
-	if packer == nil {
-		s.Exitf("no such packing %q", subcmd.StringFlag(fs, "pack"))
-	}
+	packer := s.packerByName(subcmd.StringFlag(fs, "pack"))
 
 	prevClient := s.Client
 	s.Client = client.New(config.SetPacking(s.Config, packer.Packing()))
@@ -5,7 +5,12 @@
 package main
 
 import (
+	"bufio"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"upspin.io/upspin"
 )
@@ -18,6 +23,19 @@ The -glob flag can be set to false to have rm skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 
+The -R (or -r) flag removes directories and their contents, deleting
+the contents of each directory before the directory itself. Unless the
+-f flag is set, rm first counts the items it would remove and asks for
+confirmation; if standard input is not a terminal, it exits with an
+error instead, so scripts must use -f. Once done, rm reports the number
+of items removed. If an item cannot be removed, perhaps for lack of
+rights, rm reports it and carries on with the rest, but leaves the
+directories holding it.
+
+The -f flag also makes rm continue past an argument that cannot be
+looked up rather than exiting. Either way, rm exits with a non-zero
+status if anything could not be removed.
+
 Rm does not delete the associated storage, which is rarely necessary
 or wise: storage can be shared between items and unused storage is
 better recovered by automatic means.
@@ -26,48 +44,144 @@ Rm does not delete the targets of links, only the links themselves.
 
 See the deletestorage command for more information about deleting
 storage.
+
+The -n (or -dry-run) flag makes rm print, after expanding its arguments,
+the names of the items it would remove, in the order it would remove
+them, without removing anything.
 `
 	fs := flag.NewFlagSet("rm", flag.ExitOnError)
 	recur := fs.Bool("R", false, "recur into subdirectories")
-	continueOnError := fs.Bool("f", false, "continue if errors occur")
+	fs.BoolVar(recur, "r", false, "same as -R")
+	force := fs.Bool("f", false, "do not ask for confirmation; continue if errors occur")
 	glob := globFlag(fs)
-	s.ParseFlags(fs, args, help, "rm path...")
+	dryRun := dryRunFlag(fs)
+	s.ParseFlags(fs, args, help, "rm [-R] [-f] [-n] path...")
 	if fs.NArg() == 0 {
 		usageAndExit(fs)
 	}
 	exit := s.Exit
-	if *continueOnError {
+	if *force {
 		exit = s.Fail
 	}
+	var entries []*upspin.DirEntry
+	hasDir := false
 	for _, name := range s.expandUpspin(fs.Args(), *glob) {
 		entry, err := s.Client.Lookup(name, false)
 		if err != nil {
 			exit(err)
 			continue
 		}
-		s.remove(entry, *recur, exit)
+		entries = append(entries, entry)
+		hasDir = hasDir || entry.IsDir()
+	}
+	recursive := *recur && hasDir
+	if recursive && !*force && !*dryRun && !s.confirmRemove(entries) {
+		return
+	}
+	removed := 0
+	for _, entry := range entries {
+		n, _ := s.remove(entry, *recur, *dryRun)
+		removed += n
+	}
+	if recursive && !*dryRun {
+		s.Printf("removed %d %s\n", removed, plural(removed, "item"))
 	}
 }
 
-// remove deletes the entry. If recur is set and entry is a directory, it first
-// removes the contents of the directory.
-func (s *State) remove(entry *upspin.DirEntry, recur bool, exit func(error)) {
+// confirmRemove counts the items that removing the entries recursively
+// would delete and asks the user to confirm the removal. If standard input
+// is not a terminal there is no one to ask, so it exits with an error.
+func (s *State) confirmRemove(entries []*upspin.DirEntry) bool {
+	n := 0
+	for _, entry := range entries {
+		n += s.countTree(entry)
+	}
+	if !isTerminal(s.Stdin) {
+		s.Exitf("would remove %d %s; use -f to remove without confirmation", n, plural(n, "item"))
+	}
+	fmt.Fprintf(s.Stderr, "remove %d %s? [y/N] ", n, plural(n, "item"))
+	answer, _ := bufio.NewReader(s.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	fmt.Fprintf(s.Stderr, "nothing removed\n")
+	return false
+}
+
+// isTerminal reports whether r is a terminal, or at least a character
+// device, without changing its state. It is a variable so tests can
+// answer the confirmation prompt.
+var isTerminal = func(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// countTree returns the number of items in the tree rooted at entry,
+// including entry itself. Directories that cannot be listed are
+// counted as empty.
+func (s *State) countTree(entry *upspin.DirEntry) int {
+	n := 1
+	if entry.IsDir() {
+		dirContents, _ := s.Client.Glob(upspin.AllFilesGlob(entry.Name))
+		for _, e := range dirContents {
+			n += s.countTree(e)
+		}
+	}
+	return n
+}
+
+// remove deletes the entry and returns the number of items deleted. If recur
+// is set and entry is a directory, it first removes the contents of the
+// directory. An error deleting an item is reported and the rest of the
+// directory's contents are still removed, but not the directory; ok reports
+// whether there were no errors. If dryRun is set, it prints the names of the
+// entries instead of deleting them.
+func (s *State) remove(entry *upspin.DirEntry, recur, dryRun bool) (n int, ok bool) {
+	ok = true
 	if recur && entry.IsDir() {
 		// Delete the contents of the directory first. Dir is not a link so
-		// Client.Glob is fine.
+		// Client.Glob is fine. A link within it is returned as itself,
+		// so it is deleted rather than its target.
 		dirContents, err := s.Client.Glob(upspin.AllFilesGlob(entry.Name))
 		if err != nil {
-			exit(err)
-			return
+			s.Fail(err)
+			return 0, false
 		}
 		for _, e := range dirContents {
-			s.remove(e, recur, exit)
+			en, eok := s.remove(e, recur, dryRun)
+			n += en
+			ok = ok && eok
+		}
+		if !ok {
+			// The directory is not empty.
+			return n, false
 		}
 		// Now fall through to delete directory.
 	}
+	if dryRun {
+		s.Printf("rm %s\n", entry.Name)
+		return n + 1, true
+	}
 	err := s.Client.Delete(entry.Name)
 	if err != nil {
-		exit(err)
-		return
+		s.Fail(err)
+		return n, false
+	}
+	return n + 1, true
+}
+
+// plural returns noun, with an s appended unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return noun
 	}
+	return noun + "s"
 }
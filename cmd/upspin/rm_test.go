@@ -0,0 +1,70 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"upspin.io/subcmd"
+	"upspin.io/upspin"
+)
+
+func TestConfirmRemove(t *testing.T) {
+	defer func(f func(io.Reader) bool) { isTerminal = f }(isTerminal)
+	isTerminal = func(io.Reader) bool { return true }
+
+	// Files are counted without consulting the client.
+	entries := []*upspin.DirEntry{
+		{Name: "ann@example.com/a", Attr: upspin.AttrNone},
+		{Name: "ann@example.com/b", Attr: upspin.AttrNone},
+	}
+	for _, test := range []struct {
+		answer string
+		ok     bool
+		prompt string
+	}{
+		{"y\n", true, "remove 2 items? [y/N] "},
+		{"YES\n", true, "remove 2 items? [y/N] "},
+		{"n\n", false, "remove 2 items? [y/N] nothing removed\n"},
+		{"\n", false, "remove 2 items? [y/N] nothing removed\n"},
+		{"", false, "remove 2 items? [y/N] nothing removed\n"},
+	} {
+		var stdout, stderr bytes.Buffer
+		s := &State{State: &subcmd.State{}}
+		s.SetIO(strings.NewReader(test.answer), &stdout, &stderr)
+		if ok := s.confirmRemove(entries); ok != test.ok {
+			t.Errorf("answer %q: confirmRemove = %t, want %t", test.answer, ok, test.ok)
+		}
+		if got := stderr.String(); got != test.prompt {
+			t.Errorf("answer %q: stderr = %q, want %q", test.answer, got, test.prompt)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("answer %q: unexpected output %q", test.answer, stdout.String())
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	f, err := os.CreateTemp(t.TempDir(), "rm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, r := range []io.Reader{strings.NewReader("y\n"), r, f} {
+		if isTerminal(r) {
+			t.Errorf("isTerminal(%T) = true, want false", r)
+		}
+	}
+}
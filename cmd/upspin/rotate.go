@@ -8,6 +8,8 @@ import (
 	"flag"
 
 	"upspin.io/config"
+	"upspin.io/factotum"
+	"upspin.io/upspin"
 )
 
 func (s *State) rotate(args ...string) {
@@ -23,17 +25,32 @@ To update an Upspin key, the sequence is:
 
 Keygen creates a new key and saves the old one. Countersign walks
 the file tree and adds signatures with the new key alongside those
-for the old. Rotate pushes the new key to the KeyServer. Share walks
-the file tree, re-wrapping the encryption keys that were encrypted
-with the old key to use the new key.
+for the old. Rotate pushes the new key to the KeyServer, together
+with a signature by the old key authorizing the change; the key
+server records the rotation so others can verify the chain of keys.
+Share walks the file tree, re-wrapping the encryption keys that were
+encrypted with the old key to use the new key.
 
 Some of these steps could be folded together but the full sequence
-makes it easier to recover if a step fails.
+makes it easier to recover if a step fails. Keygen -rotate backs up the
+existing key files before replacing them, and rotate itself changes no
+local files, so if the key server refuses the new key the local keys
+are left as they were and rotate may simply be run again. If the key
+server already has the new key, rotate reports so and does nothing.
+Key servers that do not record rotations are sent the new key with a
+plain update instead.
+
+Before pushing the new key, rotate checks that the key server holds the
+previous key, the one the rotation is signed with. Afterwards it looks
+the user up again to confirm that the key server now returns the new
+key. The -check flag makes rotate stop after the first check, reporting
+what it would do without changing anything.
 
 TODO: Rotate and countersign are terms of art, not clear to users.
 `
 	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
-	s.ParseFlags(fs, args, help, "rotate")
+	check := fs.Bool("check", false, "check the keys but do not push the new key to the key server")
+	s.ParseFlags(fs, args, help, "rotate [-check]")
 	if fs.NArg() != 0 {
 		usageAndExit(fs)
 	}
@@ -57,9 +74,50 @@ TODO: Rotate and countersign are terms of art, not clear to users.
 	if err != nil {
 		s.Exit(err)
 	}
+	switch u.PublicKey {
+	case f.Pop().PublicKey():
+		// Expected.
+	case f.PublicKey():
+		// Already rotated, perhaps by an earlier run.
+		s.Printf("key server already has the new key for %s\n", u.Name)
+		return
+	default:
+		s.Exitf("key server has neither the previous nor the new key for %s; cannot rotate", u.Name)
+	}
+	if *check {
+		s.Printf("key server has the previous key for %s; rotate would replace it with key %x\n",
+			u.Name, factotum.KeyHash(f.PublicKey()))
+		return
+	}
 	u.PublicKey = f.PublicKey()
-	err = keyServer.Put(u)
-	if err != nil {
+	if err := s.pushKey(keyServer, u); err != nil {
 		s.Exit(err)
 	}
+
+	// Confirm the key server now has the new key.
+	u, err = keyServer.Lookup(u.Name)
+	if err != nil {
+		s.Exitf("new key pushed but could not be confirmed: %v", err)
+	}
+	if u.PublicKey != f.PublicKey() {
+		s.Exitf("key server did not accept the new key for %s", u.Name)
+	}
+}
+
+// pushKey stores the user record, holding the new key, in the key server,
+// recording the rotation if the key server supports it.
+func (s *State) pushKey(keyServer upspin.KeyServer, u *upspin.User) error {
+	f := s.Config.Factotum() // Holds the previous key.
+	if r, ok := keyServer.(upspin.KeyRotator); ok {
+		sig, err := f.Sign(factotum.RotationHash(u.Name, f.PublicKey(), u.PublicKey))
+		if err != nil {
+			return err
+		}
+		err = r.Rotate(u, sig)
+		if err != upspin.ErrNotSupported {
+			return err
+		}
+	}
+	// The key server does not record rotations; just store the new key.
+	return keyServer.Put(u)
 }
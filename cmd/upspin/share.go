@@ -20,6 +20,7 @@ import (
 	"upspin.io/factotum"
 	"upspin.io/log"
 	"upspin.io/pack"
+	"upspin.io/pack/ee"
 	"upspin.io/path"
 	"upspin.io/subcmd"
 	"upspin.io/upspin"
@@ -44,6 +45,25 @@ The -glob flag can be set to false to have share skip Glob processing,
 treating its arguments as literal text even if they contain special
 characters. (Leading @ signs are always expanded.)
 
+The -via-group flag grants access through a Group file instead of by
+listing users in Access files. Share adds the users named by -add to the
+Group file, creating it if necessary, and makes sure the Access file
+governing each argument path grants the -right (default read) to the
+group, creating an Access file in the path's directory if there is none.
+It then updates the keys as with -fix so the members of the group can
+read encrypted files. To share with more users later, run the command
+again with the new users in -add.
+
+The -audit flag reviews the arguments, and everything below those that
+are directories, without changing anything. For each encrypted file it
+lists the users granted read access by the governing Access file and the
+users whose keys are wrapped in the file's metadata, and flags readers
+who cannot decrypt the file, keys wrapped for users no longer granted
+access, and keys whose owners cannot be determined. Keys are matched to
+users by looking up, in the key server, the users named in the Access
+files and the file's writer. With -q, only files with problems are
+listed. The exit status is non-zero if there are any.
+
 See the description for rotate for information about updating keys.
 `
 	fs := flag.NewFlagSet("share", flag.ExitOnError)
@@ -54,11 +74,22 @@ See the description for rotate for information about updating keys.
 	recur := fs.Bool("r", false, "recur into subdirectories; path must be a directory. assumes -d")
 	unencryptForAll := fs.Bool("unencryptforall", false, "for currently encrypted read:all files only, rewrite using EEIntegrity; requires -fix or -force")
 	fs.Bool("q", false, "suppress output. Default is to show state for every file")
+	viaGroup := fs.String("via-group", "", "grant access through the Group `file`; implies -fix")
+	add := fs.String("add", "", "comma-separated `users` to add to the -via-group Group file")
+	right := fs.String("right", "read", "`right` to grant to the -via-group group")
+	audit := fs.Bool("audit", false, "report mismatches between Access files and wrapped keys without changing anything; implies -r")
 	s.ParseFlags(fs, args, help, "share path...")
 	if fs.NArg() == 0 {
 		usageAndExit(fs)
 	}
 
+	if *audit {
+		if *fix || *force || *unencryptForAll || *viaGroup != "" {
+			s.Exitf("-audit cannot be combined with -fix, -force, -unencryptforall, or -via-group")
+		}
+		*recur = true
+	}
+
 	if *recur {
 		*isDir = true
 	}
@@ -68,6 +99,16 @@ See the description for rotate for information about updating keys.
 	if *unencryptForAll && !*fix {
 		s.Exitf("-unencryptforall requires -fix or -force")
 	}
+	if *viaGroup != "" {
+		*fix = true
+		var users []string
+		if *add != "" {
+			users = strings.Split(*add, ",")
+		}
+		s.shareViaGroup(*viaGroup, users, *right, s.expandUpspin(fs.Args(), subcmd.BoolFlag(fs, "glob")))
+	} else if *add != "" {
+		s.Exitf("-add requires -via-group")
+	}
 	s.shareCommand(fs)
 }
 
@@ -83,6 +124,7 @@ type Sharer struct {
 	recur           bool
 	quiet           bool
 	unencryptForAll bool
+	audit           bool
 
 	// accessFiles contains the parsed Access files, keyed by directory to which it applies.
 	accessFiles map[upspin.PathName]*access.Access
@@ -116,6 +158,7 @@ func (s *State) shareCommand(fs *flag.FlagSet) {
 	s.sharer.recur = subcmd.BoolFlag(fs, "r")
 	s.sharer.quiet = subcmd.BoolFlag(fs, "q")
 	s.sharer.unencryptForAll = subcmd.BoolFlag(fs, "unencryptforall")
+	s.sharer.audit = subcmd.BoolFlag(fs, "audit")
 
 	// To change things, User must be the owner of every file.
 	if s.sharer.fix {
@@ -135,6 +178,11 @@ func (s *State) shareCommand(fs *flag.FlagSet) {
 		s.sharer.addAccess(e)
 	}
 
+	if s.sharer.audit {
+		s.sharer.auditEntries(entries)
+		return
+	}
+
 	// Now we're ready. First show the state if asked.
 	if !s.sharer.quiet {
 		uNames := make(map[string][]string)
@@ -172,7 +220,8 @@ func (s *State) shareCommand(fs *flag.FlagSet) {
 			continue
 		}
 		packer := s.lookupPacker(entry)
-		if packer.Packing() == upspin.PlainPack || packer.Packing() == upspin.EEIntegrityPack {
+		switch packer.Packing() {
+		case upspin.PlainPack, upspin.PlainSumPack, upspin.EEIntegrityPack:
 			continue
 		}
 		users, keyUsers, self, err := s.sharer.readers(entry)
@@ -207,6 +256,143 @@ func (s *State) shareCommand(fs *flag.FlagSet) {
 	}
 }
 
+// shareViaGroup adds the users to the named Group file, creating it if
+// necessary, and makes sure the Access file governing each of the names
+// grants the right to the group.
+func (s *State) shareViaGroup(group string, users []string, rightName string, names []upspin.PathName) {
+	right := parseRight(rightName)
+	if right == access.Invalid {
+		s.Exitf("invalid right %q", rightName)
+	}
+	groupName := upspin.PathName(s.AtSign(group))
+	if !access.IsGroupFile(groupName) {
+		s.Exitf("%q is not a Group file", groupName)
+	}
+	me := s.Config.UserName()
+	parsed, err := path.Parse(groupName)
+	if err != nil {
+		s.Exit(err)
+	}
+	if parsed.User() != me {
+		s.Exitf("%q: %q is not owner", groupName, me)
+	}
+
+	// Add any new members to the Group file.
+	data, err := read(s.Client, groupName)
+	if err != nil && !errors.Is(errors.NotExist, err) {
+		s.Exit(err)
+	}
+	members, err := access.ParseGroup(parsed, data)
+	if err != nil {
+		s.Exit(err)
+	}
+	var added []string
+	for _, user := range users {
+		user = strings.TrimSpace(user)
+		if user == "" || isMember(members, upspin.UserName(user)) {
+			continue
+		}
+		added = append(added, user)
+	}
+	if len(added) > 0 || data == nil {
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		data = append(data, strings.Join(added, " ")+"\n"...)
+		if _, err := access.ParseGroup(parsed, data); err != nil {
+			s.Exit(err)
+		}
+		s.putAccessControlFile(groupName, data)
+	}
+
+	// Make sure each name's Access file grants the right to the group.
+	for _, name := range names {
+		entry, err := s.Client.Lookup(name, true)
+		if err != nil {
+			s.Exit(err)
+		}
+		s.grantToGroup(entry, right, parsed)
+	}
+}
+
+// grantToGroup makes sure the Access file governing the entry grants the
+// right to the group, adding a line to that Access file if necessary or,
+// if there is none, creating one in the entry's directory.
+func (s *State) grantToGroup(entry *upspin.DirEntry, right access.Right, group path.Parsed) {
+	me := s.Config.UserName()
+	dir := entry.Name
+	if !entry.IsDir() {
+		dir = path.DropPath(dir, 1)
+	}
+	which, err := s.DirServer(entry.Name).WhichAccess(entry.Name)
+	if err != nil {
+		s.Exitf("looking up access file for %q: %s", entry.Name, err)
+	}
+	line := fmt.Sprintf("%s: %s\n", right, group)
+	var accessName upspin.PathName
+	var data []byte
+	if which == nil {
+		// With no Access file only the owner has rights; keep it that way.
+		accessName = path.Join(dir, access.AccessFile)
+		data = []byte(fmt.Sprintf("*: %s\n%s", me, line))
+	} else {
+		accessName = which.Name
+		data = s.readOrExit(s.Client, accessName)
+		a, err := access.Parse(accessName, data)
+		if err != nil {
+			s.Exitf("parsing access file %q: %s", accessName, err)
+		}
+		for _, p := range a.List(right) {
+			if p.Equal(group) {
+				return
+			}
+		}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		data = append(data, line...)
+	}
+	if p, _ := path.Parse(accessName); p.User() != me {
+		s.Exitf("%q: %q is not owner", accessName, me)
+	}
+	if _, err := access.Parse(accessName, data); err != nil {
+		s.Exitf("updating access file %q: %s", accessName, err)
+	}
+	s.putAccessControlFile(accessName, data)
+}
+
+// putAccessControlFile writes the Access or Group file and discards any
+// cached information about it.
+func (s *State) putAccessControlFile(name upspin.PathName, data []byte) {
+	if _, err := s.Client.Put(name, data); err != nil {
+		s.Exit(err)
+	}
+	s.sharer = newSharer(s)
+	if access.IsGroupFile(name) {
+		_ = access.RemoveGroup(name) // Ignore errors; file might not be cached.
+	}
+}
+
+// isMember reports whether the user is listed directly in the group.
+func isMember(group []path.Parsed, user upspin.UserName) bool {
+	for _, p := range group {
+		if p.IsRoot() && p.User() == user {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRight returns the right with the given name, or access.Invalid.
+func parseRight(name string) access.Right {
+	for r := access.Read; r <= access.Delete; r++ {
+		if r.String() == name {
+			return r
+		}
+	}
+	return access.Invalid
+}
+
 // readers returns two lists, the list of users with access according to the
 // access file, and the pretty-printed string of user names recovered from
 // looking at the list of hashed keys in the packdata.
@@ -218,9 +404,7 @@ func (s *Sharer) readers(entry *upspin.DirEntry) (userList, string, bool, error)
 		return nil, "", self, nil
 	}
 	users := userList(s.users[path.DropPath(entry.Name, 1)])
-	for _, user := range users {
-		s.lookupKey(user)
-	}
+	s.lookupKeys(users)
 	packer := s.state.lookupPacker(entry)
 	if packer == nil {
 		return users, "", self, errors.Errorf("no packer registered for packer %s", entry.Packing)
@@ -287,6 +471,135 @@ func (s *Sharer) readers(entry *upspin.DirEntry) (userList, string, bool, error)
 	return users, keyUsers.String(), self, nil
 }
 
+// auditEntries reports, for each encrypted entry, the users granted read
+// access by its Access file and the users whose keys are wrapped in its
+// packdata, and any differences between the two. It changes nothing.
+func (s *Sharer) auditEntries(entries []*upspin.DirEntry) {
+	// The hashes of wrapped keys can be matched only to keys we have
+	// looked up, so look up every user we know of.
+	var candidates userList
+	for _, users := range s.users {
+		candidates = append(candidates, users...)
+	}
+	for _, entry := range entries {
+		candidates = append(candidates, entry.Writer)
+	}
+	s.lookupKeys(candidates)
+
+	problems, encrypted := 0, 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		packer := s.state.lookupPacker(entry)
+		if packer == nil || packer.Packing() != upspin.EEPack {
+			continue
+		}
+		encrypted++
+		a := s.auditEntry(entry, packer)
+		if a.ok() && s.quiet {
+			continue
+		}
+		s.state.Printf("%s:\n", entry.Name)
+		s.state.Printf("\tAccess: %s\n", a.readers)
+		s.state.Printf("\tKeys:   %s\n", a.keyUsers)
+		if len(a.cannotRead) > 0 {
+			s.state.Printf("\tcannot decrypt: %s\n", a.cannotRead)
+		}
+		if len(a.notGranted) > 0 {
+			s.state.Printf("\tkeys wrapped for users without access: %s\n", a.notGranted)
+		}
+		if len(a.oldKeys) > 0 {
+			s.state.Printf("\tkeys wrapped for old keys of: %s\n", a.oldKeys)
+		}
+		for _, h := range a.unknown {
+			s.state.Printf("\tkey wrapped for unknown user: %x\n", h)
+		}
+		if !a.ok() {
+			problems++
+		}
+	}
+	s.state.Printf("%d of %d encrypted files have problems\n", problems, encrypted)
+	if problems > 0 {
+		s.state.ExitCode = 1
+	}
+}
+
+// entryAudit holds the result of auditing the wrapped keys of an entry.
+type entryAudit struct {
+	readers    userList // Users granted read access.
+	keyUsers   userList // Users whose keys are wrapped.
+	cannotRead userList // Readers whose keys are not wrapped.
+	notGranted userList // Users whose keys are wrapped but who are not readers.
+	oldKeys    userList // Users for whom a superseded key is wrapped.
+	unknown    [][]byte // Hashes of wrapped keys of unknown users.
+}
+
+// ok reports whether the wrapped keys match the readers exactly.
+func (a *entryAudit) ok() bool {
+	return len(a.cannotRead) == 0 && len(a.notGranted) == 0 && len(a.oldKeys) == 0 && len(a.unknown) == 0
+}
+
+// auditEntry compares the users granted read access to the entry with
+// the users whose keys are wrapped in its packdata.
+func (s *Sharer) auditEntry(entry *upspin.DirEntry, packer upspin.Packer) *entryAudit {
+	a := &entryAudit{
+		readers: s.users[path.DropPath(entry.Name, 1)],
+	}
+	if access.IsAccessControlFile(entry.Name) {
+		// Access and Group files are readable by anyone with any right,
+		// so their keys are wrapped for all users.
+		a.readers = append(userList{access.AllUsers}, a.readers...)
+	}
+	hashes, err := packer.ReaderHashes(entry.Packdata)
+	if err != nil {
+		fmt.Fprintf(s.state.Stderr, "%q: %s\n", entry.Name, err)
+		s.state.ExitCode = 1
+		return a
+	}
+	wrapped := make(map[upspin.UserName]bool)
+	for _, hash := range hashes {
+		if len(hash) == sha256.Size {
+			var h [sha256.Size]byte
+			copy(h[:], hash)
+			if user, ok := s.userByHash[h]; ok {
+				wrapped[user] = true
+				continue
+			}
+		}
+		if bytes.Equal(factotum.AllUsersKeyHash, hash) {
+			wrapped[access.AllUsers] = true
+			continue
+		}
+		if f := s.state.Config.Factotum(); f != nil {
+			if _, err := f.PublicKeyFromHash(hash); err == nil {
+				self := s.state.Config.UserName()
+				wrapped[self] = true
+				a.oldKeys = append(a.oldKeys, self)
+				continue
+			}
+		}
+		a.unknown = append(a.unknown, hash)
+	}
+
+	granted := make(map[upspin.UserName]bool)
+	for _, user := range a.readers {
+		granted[user] = true
+		if !wrapped[user] && !wrapped[access.AllUsers] {
+			a.cannotRead = append(a.cannotRead, user)
+		}
+	}
+	for user := range wrapped {
+		a.keyUsers = append(a.keyUsers, user)
+		if !granted[user] {
+			a.notGranted = append(a.notGranted, user)
+		}
+	}
+	sort.Sort(a.keyUsers)
+	sort.Sort(a.notGranted)
+	return a
+}
+
 // allEntries expands the arguments to find all the DirEntries identifying items to examine.
 // The returned slice contains no directories and no links, only plain files.
 func (s *Sharer) allEntries(names []upspin.PathName) []*upspin.DirEntry {
@@ -359,6 +672,26 @@ func (s *State) lookupPacker(entry *upspin.DirEntry) upspin.Packer {
 	return packer
 }
 
+// packerByName returns the Packer with the given name.
+// If there is none, it exits, listing the packings available.
+func (s *State) packerByName(name string) upspin.Packer {
+	packer := pack.LookupByName(name)
+	if packer == nil {
+		s.Exitf("no such packing %q; available packings: %s", name, packingNames())
+	}
+	return packer
+}
+
+// packingNames returns a comma-separated list of the names of
+// the packings registered in this binary.
+func packingNames() string {
+	var names []string
+	for _, p := range pack.Registered() {
+		names = append(names, pack.Lookup(p).String())
+	}
+	return strings.Join(names, ", ")
+}
+
 // addAccess loads an access file.
 func (s *Sharer) addAccess(entry *upspin.DirEntry) {
 	name := entry.Name
@@ -463,11 +796,22 @@ func (s *Sharer) fixShare(name upspin.PathName, users userList) {
 	if all {
 		keys = append(keys, upspin.AllUsersKey)
 	}
-	packer.Share(s.state.Config, keys, []*[]byte{&entry.Packdata})
-	if entry.Packdata == nil {
-		fmt.Fprintf(s.state.Stderr, "packing skipped for %q\n", entry.Name)
-		s.state.ExitCode = 1
-		return
+	if !s.force && wrapsOnly(packer, entry.Packdata, keys) {
+		// No reader has lost access, so just wrap the key for the new ones.
+		pd, err := ee.AddReaders(s.state.Config, entry.Packdata, keys)
+		if err != nil {
+			fmt.Fprintf(s.state.Stderr, "adding readers for %q: %s\n", entry.Name, err)
+			s.state.ExitCode = 1
+			return
+		}
+		entry.Packdata = pd
+	} else {
+		packer.Share(s.state.Config, keys, []*[]byte{&entry.Packdata})
+		if entry.Packdata == nil {
+			fmt.Fprintf(s.state.Stderr, "packing skipped for %q\n", entry.Name)
+			s.state.ExitCode = 1
+			return
+		}
 	}
 	_, err = directory.Put(entry)
 	if err != nil {
@@ -477,6 +821,29 @@ func (s *Sharer) fixShare(name upspin.PathName, users userList) {
 	}
 }
 
+// wrapsOnly reports whether every key wrapped in the packdata belongs
+// to one of the keys, so that no reader needs to lose access.
+func wrapsOnly(packer upspin.Packer, packdata []byte, keys []upspin.PublicKey) bool {
+	hashes, err := packer.ReaderHashes(packdata)
+	if err != nil {
+		return false
+	}
+	want := make(map[string]bool)
+	for _, k := range keys {
+		if k == upspin.AllUsersKey {
+			want[string(factotum.AllUsersKeyHash)] = true
+			continue
+		}
+		want[string(factotum.KeyHash(k))] = true
+	}
+	for _, h := range hashes {
+		if !want[string(h)] {
+			return false
+		}
+	}
+	return true
+}
+
 // lookupKey returns the public key for the user.
 // If the user does not exist, is the "all" user, or is a wildcard
 // (*@example.com), it returns the empty string.
@@ -497,14 +864,46 @@ func (s *Sharer) lookupKey(user upspin.UserName) upspin.PublicKey {
 		return ""
 	}
 	u, err := s.state.KeyServer().Lookup(user)
+	return s.recordKey(user, u, err)
+}
+
+// lookupKeys looks up the public keys of the users, as lookupKey does, but
+// if the key server supports it, it looks up all users not already known
+// in a single request.
+func (s *Sharer) lookupKeys(users userList) {
+	var names []upspin.UserName
+	for _, user := range users {
+		if _, ok := s.userKeys[user]; ok || user == access.AllUsers || isWildcardUser(user) {
+			continue
+		}
+		names = append(names, user)
+	}
+	if b, ok := s.state.KeyServer().(upspin.KeyLookupBatcher); ok && len(names) > 1 {
+		found, errs := b.LookupBatch(names)
+		for i, user := range names {
+			// Leave failures to lookupKey, which will retry
+			// in case the server does not support batches.
+			if errs[i] == nil {
+				s.recordKey(user, found[i], nil)
+			}
+		}
+	}
+	for _, user := range users {
+		s.lookupKey(user)
+	}
+}
+
+// recordKey remembers the result of looking up the user, failed or
+// otherwise, and returns the user's key, or the empty string if the
+// lookup failed.
+func (s *Sharer) recordKey(user upspin.UserName, u *upspin.User, err error) upspin.PublicKey {
 	if err != nil {
 		fmt.Fprintf(s.state.Stderr, "can't find key for %q: %s\n", user, err)
 		s.state.ExitCode = 1
 		s.userKeys[user] = ""
 		return ""
 	}
-	// Remember the lookup, failed or otherwise.
-	key = u.PublicKey
+	key := u.PublicKey
 	if len(key) == 0 {
 		fmt.Fprintf(s.state.Stderr, "no key for %q\n", user)
 		s.state.ExitCode = 1
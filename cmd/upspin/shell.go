@@ -8,7 +8,11 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
 	"strings"
+	"sync"
 )
 
 func (s *State) shell(args ...string) {
@@ -17,17 +21,39 @@ func (s *State) shell(args ...string) {
 Shell runs an interactive session for Upspin subcommands.
 When running the shell, the leading "upspin" is assumed on each command.
 
-The shell has a simple interface, free of quoting or other features usually
-associated with interactive shells. It is intended only for testing and is kept
-simple for reasons of comprehensibility, portability, and maintainability.
-Those who need quoting or line editing or other such features should use their
-regular shell and run upspinfs or invoke the upspin command line-by-line.
-
-The shell does have one convenience feature, though, in the handling of path
-names. A path beginning with a plain @ refers to the current user's root
-(ann@example.com), while one starting @+suffix is the same with the suffix
-included (ann+suffix@example.com). This feature works in all upspin commands
-but is particularly handy inside the shell.
+The shell is kept simple for reasons of comprehensibility, portability, and
+maintainability, and is intended mainly for testing. It splits each command
+into words at white space, with no quoting, treats the rest of a line after
+a # as a comment, and has no variables or command history. Those who need
+such features should use their regular shell and run upspinfs or invoke the
+upspin command line-by-line.
+
+When reading from a terminal, the shell edits each line as it is typed.
+Backspace erases a character, ^U erases the line, ^C abandons it, and ^D
+on an empty line ends the session. Other control characters and escape
+sequences, such as those sent by the arrow keys, are ignored.
+
+Tab completes the word being typed. The first word of a command completes
+to the names of upspin commands, and a later word holding an @ sign
+completes to the names of the Upspin files and directories that begin with
+it. If there are several, tab extends the word as far as they agree or, if
+it already does, lists them. If the directory cannot be listed promptly,
+tab does nothing.
+
+In path names, as in all upspin commands, a path beginning with a plain @
+refers to the current user's root (ann@example.com), while one starting
+@+suffix is the same with the suffix included (ann+suffix@example.com).
+
+The shell can also connect commands with a vertical bar, as in
+
+	get @/notes | grep upspin
+	!date | put @/today
+
+The first word of each command names an Upspin command if there is one
+by that name; otherwise, or if the name is prefixed with !, it names a
+local program. At most one Upspin command may appear in a pipeline. The first
+command of a pipeline reads nothing and the last writes to the shell's
+standard output.
 `
 	fs := flag.NewFlagSet("shell", flag.ExitOnError)
 	promptFlag := fs.String("prompt", promptPlaceholder, "interactive `prompt`")
@@ -46,6 +72,14 @@ but is particularly handy inside the shell.
 	}
 	s.Interactive = true
 	defer func() { s.Interactive = false }()
+	if f, ok := s.Stdin.(*os.File); ok {
+		if restore, err := makeRaw(f.Fd()); err == nil {
+			// It's a terminal.
+			restore()
+			s.editShell(f, *promptFlag, *verbose)
+			return
+		}
+	}
 	scanner := bufio.NewScanner(s.Stdin)
 	for prompt(); scanner.Scan(); prompt() {
 		s.exec(scanner.Text(), *verbose)
@@ -55,6 +89,28 @@ but is particularly handy inside the shell.
 	}
 }
 
+// editShell runs the shell reading commands from the terminal f
+// with a lineEditor.
+func (s *State) editShell(f *os.File, prompt string, verbose bool) {
+	e := &lineEditor{
+		in:       bufio.NewReader(f),
+		out:      s.Stderr,
+		prompt:   prompt,
+		raw:      func() (func(), error) { return makeRaw(f.Fd()) },
+		complete: s.completions,
+	}
+	for {
+		line, err := e.readLine()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.Exit(err)
+		}
+		s.exec(line, verbose)
+	}
+}
+
 func (s *State) exec(line string, verbose bool) {
 	defer func() {
 		err := recover()
@@ -75,6 +131,13 @@ func (s *State) exec(line string, verbose bool) {
 	if len(words) == 0 {
 		return
 	}
+	if strings.Contains(line, "|") {
+		if verbose {
+			fmt.Fprintln(s.Stderr, " + "+strings.Join(words, " "))
+		}
+		s.pipeline(strings.Split(line, "|"))
+		return
+	}
 	fn := s.getCommand(strings.ToLower(words[0]))
 	if fn == nil {
 		fmt.Fprintf(s.Stderr, "upspin: no such command %q\n", words[0])
@@ -86,3 +149,122 @@ func (s *State) exec(line string, verbose bool) {
 	s.Name = words[0]
 	fn(s, words[1:]...)
 }
+
+// pipeline runs the commands of a shell pipeline, connecting the standard
+// output of each to the standard input of the next. The Upspin command,
+// if any, runs in the shell itself with its I/O redirected; the others are
+// local programs.
+func (s *State) pipeline(segments []string) {
+	// All the commands share the shell's error output.
+	stderr := &lockedWriter{w: s.Stderr}
+	cmds := make([]*exec.Cmd, len(segments))
+	var (
+		fn    func(*State, ...string)
+		args  []string
+		which int
+	)
+	for i, seg := range segments {
+		words := strings.Fields(seg)
+		if len(words) == 0 {
+			fmt.Fprintf(s.Stderr, "upspin: empty command in pipeline\n")
+			return
+		}
+		if !strings.HasPrefix(words[0], "!") {
+			if f := lookupCommand(words[0]); f != nil {
+				if fn != nil {
+					fmt.Fprintf(s.Stderr, "upspin: more than one upspin command in pipeline\n")
+					return
+				}
+				fn, args, which = f, words[1:], i
+				s.Name = words[0]
+				continue
+			}
+		}
+		name := strings.TrimPrefix(words[0], "!")
+		if name == "" {
+			fmt.Fprintf(s.Stderr, "upspin: missing program name after !\n")
+			return
+		}
+		cmds[i] = exec.Command(name, words[1:]...)
+		cmds[i].Stderr = stderr
+	}
+
+	// Connect each command to the next with a pipe. The first
+	// command's input is left empty and the last writes to the
+	// shell's output. As each command finishes, it closes its end
+	// of both pipes so its neighbors see EOF or a write error.
+	stdin, stdout := s.Stdin, s.Stdout
+	in := make([]*io.PipeReader, len(segments))
+	out := make([]*io.PipeWriter, len(segments))
+	for i := 0; i < len(segments)-1; i++ {
+		in[i+1], out[i] = io.Pipe()
+	}
+	finish := func(i int) {
+		if in[i] != nil {
+			in[i].Close()
+		}
+		if out[i] != nil {
+			out[i].Close()
+		}
+	}
+	var wg sync.WaitGroup
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		if in[i] != nil {
+			cmd.Stdin = in[i]
+		}
+		if out[i] != nil {
+			cmd.Stdout = out[i]
+		} else {
+			cmd.Stdout = stdout
+		}
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(stderr, "upspin: %v\n", err)
+			finish(i)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, cmd *exec.Cmd) {
+			defer wg.Done()
+			err := cmd.Wait()
+			finish(i)
+			if _, ok := err.(*exec.ExitError); err != nil && !ok {
+				fmt.Fprintf(stderr, "upspin: %s: %v\n", cmd.Path, err)
+			}
+		}(i, cmd)
+	}
+	defer wg.Wait()
+
+	if fn == nil {
+		return
+	}
+	// Restore the shell's I/O and wake our neighbors even if
+	// the command exits.
+	defer func() {
+		s.Stdin, s.Stdout, s.Stderr = stdin, stdout, stderr.w
+		finish(which)
+	}()
+	s.Stderr = stderr
+	s.Stdin = strings.NewReader("")
+	if in[which] != nil {
+		s.Stdin = in[which]
+	}
+	if out[which] != nil {
+		s.Stdout = out[which]
+	}
+	fn(s, args...)
+}
+
+// lockedWriter serializes writes to w.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -177,7 +177,7 @@ file and keys and only send the signup request to the key server.
 			s.Exit(err)
 		}
 	}
-	s.keygenCommand(*secrets, *curve, *secretseed, false)
+	s.keygenCommand(*secrets, *curve, *secretseed, false, false)
 
 	// Send the signup request to the key server.
 	s.registerUser(*keyServer)
@@ -6,6 +6,8 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"strings"
 
 	"upspin.io/errors"
 	"upspin.io/path"
@@ -17,25 +19,75 @@ func (s *State) snapshot(args ...string) {
 	const help = `
 Snapshot requests the system to take a snapshot of the user's
 directory tree as soon as possible. Snapshots are created only if
-the directory server for the user's root supports them.
+the directory server for the user's root supports them. Given a path
+to a directory in the user's tree, snapshot takes a snapshot of just
+that directory. A snapshot is named for the date and time it was
+taken, such as @+snapshot/2017/06/01/12:30, followed for a snapshot
+of a single directory by a hyphen and the last element of the
+directory's name.
+
+With the -list flag, snapshot instead lists the user's snapshots and,
+for each, the directory it is a snapshot of.
+
+With the -diff flag, snapshot instead compares two directory trees,
+typically two snapshots such as @+snapshot/2017/06/01/12:30, and
+reports the items added, removed, or modified in the second tree
+relative to the first. Either argument may be the word "live", which
+stands for the user's root. Files are compared by their block
+references and modification times, not their contents, and
+directories whose blocks are identical are not descended into.
 `
 	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
-	s.ParseFlags(fs, args, help, "snapshot")
-	if fs.NArg() > 0 {
-		usageAndExit(fs)
-	}
+	diff := fs.Bool("diff", false, "compare two snapshots instead of taking one")
+	list := fs.Bool("list", false, "list snapshots instead of taking one")
+	s.ParseFlags(fs, args, help, "snapshot [-list | -diff old new | path]")
 
 	u, suffix, domain, err := user.Parse(s.Config.UserName())
 	if err != nil {
 		s.Exit(err)
 	}
 	var snapshotUser upspin.UserName
-	if suffix == "" {
+	switch suffix {
+	case "":
 		snapshotUser = upspin.UserName(u + "+snapshot@" + domain)
-	} else if suffix == "snapshot" {
+	case "snapshot":
 		// Okay -- snapshot user is allowed to trigger snapshots.
-	} else {
-		s.Exitf("Only the snapshot user or the canonical user %q can trigger a snapshot", u+"@"+domain)
+		snapshotUser = s.Config.UserName()
+		u = strings.TrimSuffix(u, "+snapshot")
+	default:
+		if !*diff {
+			s.Exitf("Only the snapshot user or the canonical user %q can trigger a snapshot", u+"@"+domain)
+		}
+	}
+	live := upspin.PathName(u + "@" + domain + "/")
+	switch {
+	case *diff:
+		if *list || fs.NArg() != 2 {
+			usageAndExit(fs)
+		}
+		s.snapshotDiff(s.snapshotTree(fs.Arg(0), live), s.snapshotTree(fs.Arg(1), live))
+		return
+	case *list:
+		if fs.NArg() != 0 {
+			usageAndExit(fs)
+		}
+		s.snapshotList(snapshotUser)
+		return
+	case fs.NArg() > 1:
+		usageAndExit(fs)
+	}
+
+	// The subtree to snapshot, relative to the user's root.
+	var subtree string
+	if fs.NArg() == 1 {
+		p, err := path.Parse(upspin.PathName(s.AtSign(fs.Arg(0))))
+		if err != nil {
+			s.Exit(err)
+		}
+		if p.User() != upspin.UserName(u+"@"+domain) {
+			s.Exitf("%s is not in the tree of %s", p, u+"@"+domain)
+		}
+		subtree = p.FilePath()
 	}
 
 	// Does the snapshot user exist? If not, create it.
@@ -75,15 +127,37 @@ the directory server for the user's root supports them.
 	// Note: This is a hack, but it works. See dir/server/snapshot.go for
 	// the mechanism.
 	// TODO: Find a cleaner mechanism?
-	name := path.Join(upspin.PathName(snapshotUser), "TakeSnapshot")
+	name := path.Join(upspin.PathName(snapshotUser), "TakeSnapshot", subtree)
 	entry := &upspin.DirEntry{
 		Name:       name,
 		SignedName: name,
 		Packing:    upspin.PlainPack,
 		Writer:     s.Config.UserName(),
 	}
-	_, err = s.DirServer(entry.Name).Put(entry)
+	dir := s.DirServer(entry.Name)
+	_, err = dir.Put(entry)
+	if err != nil {
+		s.Exit(err)
+	}
+	// A server that takes snapshots does not store the entry. One that
+	// does not take them stores it as an ordinary file; remove it.
+	if _, err := dir.Lookup(name); err == nil {
+		dir.Delete(name)
+		s.Exitf("the directory server for %s does not support snapshots", snapshotUser)
+	}
+}
+
+// snapshotList prints the names of the snapshot user's snapshots and the
+// directories they are snapshots of.
+func (s *State) snapshotList(snapshotUser upspin.UserName) {
+	entries, err := s.Client.Glob(string(snapshotUser) + "/*/*/*/*")
 	if err != nil {
 		s.Exit(err)
 	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		fmt.Fprintf(s.Stdout, "%s\t%s\n", e.Name, e.SignedName)
+	}
 }
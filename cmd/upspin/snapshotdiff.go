@@ -0,0 +1,141 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+// snapshotTree returns the root of the tree named by a snapshot -diff
+// argument, which is either "live" or a path, possibly a glob pattern,
+// naming a directory.
+func (s *State) snapshotTree(arg string, live upspin.PathName) upspin.PathName {
+	if arg == "live" {
+		return live
+	}
+	entries := s.GlobUpspin(arg)
+	if len(entries) != 1 {
+		s.Exitf("more than one file matches %q", arg)
+	}
+	if !entries[0].IsDir() {
+		s.Exitf("%s is not a directory", entries[0].Name)
+	}
+	return entries[0].Name
+}
+
+// snapshotDiff prints the differences between the trees rooted at old and new.
+func (s *State) snapshotDiff(old, new upspin.PathName) {
+	err := diffTrees(s.Client.Glob, old, new, func(kind, name string) {
+		fmt.Fprintf(s.Stdout, "%-8s %s\n", kind, name)
+	})
+	if err != nil {
+		s.Exit(err)
+	}
+}
+
+// diffTrees compares the trees rooted at the directories old and new,
+// calling report for each item that was "added", "removed", or "modified"
+// in new relative to old. The name passed to report is relative to the
+// roots of the trees. Items are reported in lexical order within each
+// directory, and a directory present in only one tree is reported
+// without its contents. The glob function is used to list directories.
+func diffTrees(glob func(string) ([]*upspin.DirEntry, error), old, new upspin.PathName, report func(kind, name string)) error {
+	return diffDir(glob, old, new, "", report)
+}
+
+func diffDir(glob func(string) ([]*upspin.DirEntry, error), old, new upspin.PathName, prefix string, report func(kind, name string)) error {
+	oldEntries, err := glob(upspin.AllFilesGlob(old))
+	if err != nil {
+		return err
+	}
+	newEntries, err := glob(upspin.AllFilesGlob(new))
+	if err != nil {
+		return err
+	}
+	oldByElem, err := entriesByElem(oldEntries)
+	if err != nil {
+		return err
+	}
+	newByElem, err := entriesByElem(newEntries)
+	if err != nil {
+		return err
+	}
+	var elems []string
+	for elem := range oldByElem {
+		elems = append(elems, elem)
+	}
+	for elem := range newByElem {
+		if oldByElem[elem] == nil {
+			elems = append(elems, elem)
+		}
+	}
+	sort.Strings(elems)
+
+	for _, elem := range elems {
+		o, n := oldByElem[elem], newByElem[elem]
+		name := prefix + elem
+		switch {
+		case n == nil:
+			report("removed", name)
+		case o == nil:
+			report("added", name)
+		case o.IsDir() && n.IsDir():
+			if sameBlocks(o, n) {
+				// Identical contents; nothing below can differ.
+				continue
+			}
+			if err := diffDir(glob, o.Name, n.Name, name+"/", report); err != nil {
+				return err
+			}
+		case !sameEntry(o, n):
+			report("modified", name)
+		}
+	}
+	return nil
+}
+
+// entriesByElem returns a map of the entries keyed by the final
+// element of their names.
+func entriesByElem(entries []*upspin.DirEntry) (map[string]*upspin.DirEntry, error) {
+	m := make(map[string]*upspin.DirEntry, len(entries))
+	for _, e := range entries {
+		p, err := path.Parse(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		m[p.Elem(p.NElem()-1)] = e
+	}
+	return m, nil
+}
+
+// sameEntry reports whether two entries, which need not have the same name,
+// describe the same item: the same kind of entry with the same blocks,
+// link target, packing and modification time.
+func sameEntry(a, b *upspin.DirEntry) bool {
+	return a.Attr == b.Attr &&
+		a.Link == b.Link &&
+		a.Packing == b.Packing &&
+		a.Time == b.Time &&
+		sameBlocks(a, b)
+}
+
+// sameBlocks reports whether two entries have blocks with the same
+// references, offsets and sizes.
+func sameBlocks(a, b *upspin.DirEntry) bool {
+	if len(a.Blocks) != len(b.Blocks) {
+		return false
+	}
+	for i := range a.Blocks {
+		ab, bb := &a.Blocks[i], &b.Blocks[i]
+		if ab.Location.Reference != bb.Location.Reference || ab.Offset != bb.Offset || ab.Size != bb.Size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+// fakeTree is a directory tree held in memory, keyed by directory name.
+type fakeTree map[upspin.PathName][]*upspin.DirEntry
+
+func (t fakeTree) glob(pattern string) ([]*upspin.DirEntry, error) {
+	dir := upspin.PathName(strings.TrimSuffix(pattern, "/*"))
+	entries, ok := t[dir]
+	if !ok {
+		return nil, errors.E(dir, errors.NotExist)
+	}
+	return entries, nil
+}
+
+// add adds an entry to the tree. Ref holds the reference of its only block;
+// for a directory, it stands for the reference of the directory's contents.
+func (t fakeTree) add(name upspin.PathName, attr upspin.Attribute, ref upspin.Reference, time upspin.Time) {
+	e := &upspin.DirEntry{
+		Name: name,
+		Attr: attr,
+		Time: time,
+		Blocks: []upspin.DirBlock{{
+			Location: upspin.Location{Reference: ref},
+			Size:     1,
+		}},
+	}
+	dir := path.DropPath(name, 1)
+	t[dir] = append(t[dir], e)
+	if attr == upspin.AttrDirectory {
+		if _, ok := t[name]; !ok {
+			t[name] = nil
+		}
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	// Two snapshots of ann's tree with some changes between them.
+	const (
+		snapA = "ann+snapshot@example.com/2017/06/01/12:00"
+		snapB = "ann+snapshot@example.com/2017/06/01/13:00"
+	)
+	older, newer := upspin.Time(100), upspin.Time(200)
+	tree := fakeTree{snapA: nil, snapB: nil}
+	for _, snap := range []upspin.PathName{snapA, snapB} {
+		// The same in both.
+		tree.add(snap+"/same", upspin.AttrNone, "same", older)
+		tree.add(snap+"/samedir", upspin.AttrDirectory, "samedir", older)
+		// Contents of the directory are only in the first snapshot;
+		// as its blocks are unchanged, it must not be listed.
+		tree.add(snap+"/dir", upspin.AttrDirectory, upspin.Reference("dir-"+snap), older)
+		tree.add(snap+"/dir/same", upspin.AttrNone, "dir/same", older)
+	}
+	tree.add(snapA+"/samedir/x", upspin.AttrNone, "x", older)
+
+	tree.add(snapA+"/gone", upspin.AttrNone, "gone", older)
+	tree.add(snapB+"/new", upspin.AttrNone, "new", newer)
+	tree.add(snapA+"/changed", upspin.AttrNone, "changed1", older)
+	tree.add(snapB+"/changed", upspin.AttrNone, "changed2", newer)
+	tree.add(snapA+"/touched", upspin.AttrNone, "touched", older)
+	tree.add(snapB+"/touched", upspin.AttrNone, "touched", newer)
+	tree.add(snapA+"/dir/gone", upspin.AttrNone, "dir/gone", older)
+	tree.add(snapB+"/dir/newdir", upspin.AttrDirectory, "newdir", newer)
+	tree.add(snapB+"/dir/newdir/file", upspin.AttrNone, "newdir/file", newer)
+	tree.add(snapA+"/kind", upspin.AttrNone, "kind", older)
+	tree.add(snapB+"/kind", upspin.AttrDirectory, "kind", older)
+
+	var got []string
+	report := func(kind, name string) {
+		got = append(got, fmt.Sprintf("%s %s", kind, name))
+	}
+	if err := diffTrees(tree.glob, snapA, snapB, report); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"modified changed",
+		"removed dir/gone",
+		"added dir/newdir",
+		"removed gone",
+		"modified kind",
+		"added new",
+		"modified touched",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diff:\n\tgot  %q\n\twant %q", got, want)
+	}
+
+	// A tree compared with itself has no differences.
+	got = nil
+	if err := diffTrees(tree.glob, snapB, snapB, report); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("diff of tree with itself: got %q", got)
+	}
+}
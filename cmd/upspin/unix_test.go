@@ -7,6 +7,7 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"sort"
@@ -66,3 +67,78 @@ func TestFindUpspinBinaries(t *testing.T) {
 		t.Fatalf("expected %q, got %q", wanted, got)
 	}
 }
+
+func init() {
+	allCmdTests = append(allCmdTests, &shellPipeTests)
+}
+
+// shellPipeTests exercise pipelines in the shell, using programs
+// found on Unix systems.
+var shellPipeTests = []cmdTest{
+	{
+		"make shell directory",
+		ann,
+		do("mkdir @/Shell"),
+		"",
+		expectNoOutput(),
+	},
+	putFile(
+		ann,
+		"@/Shell/file",
+		"one upspin\ntwo\nthree upspin\n",
+	),
+	{
+		"shell pipe get to local program",
+		ann,
+		do(),
+		"",
+		shellPipe("", "get @/Shell/file | grep upspin", "one upspin\nthree upspin\n"),
+	},
+	{
+		"shell pipe local program to put",
+		ann,
+		do(),
+		"",
+		shellPipe("", "!echo hello world | put @/Shell/echo", ""),
+	},
+	{
+		"shell pipe through two local programs",
+		ann,
+		do(),
+		"",
+		shellPipe("", "get @/Shell/echo | tr a-z A-Z | !cat", "HELLO WORLD\n"),
+	},
+	{
+		"shell pipe with two upspin commands",
+		ann,
+		do(),
+		"",
+		shellPipe("more than one upspin command in pipeline", "get @/Shell/file | put @/Shell/copy", ""),
+	},
+}
+
+// shellPipe is a post function that runs the line as the shell would and
+// verifies that its standard output is exactly want. If errStr is not
+// empty, standard error must contain it instead.
+func shellPipe(errStr, line, want string) func(t *testing.T, r *runner, cmd *cmdTest, stdout, stderr string) {
+	return func(t *testing.T, r *runner, cmd *cmdTest, _, _ string) {
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		r.state.SetIO(devNull{}, stdout, stderr)
+		defer r.state.DefaultIO()
+		r.state.Interactive = true
+		r.state.exec(line, false)
+		if errStr != "" {
+			if !strings.Contains(stderr.String(), errStr) {
+				t.Fatalf("%q: unexpected error (expected %q)\n\t%q", cmd.name, errStr, stderr)
+			}
+			return
+		}
+		if stderr.Len() != 0 {
+			t.Fatalf("%q: unexpected error:\n\t%q", cmd.name, stderr)
+		}
+		if got := stdout.String(); got != want {
+			t.Fatalf("%q: output is %q, want %q", cmd.name, got, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -37,14 +38,41 @@ user whose domain is administered by the current user.
 A handy way to use the command is to edit the config file and run
 	upspin user | upspin user -put
 
+With the -watch flag, user monitors the key server record for the
+specified user, by default the current user, polling it at the period
+set by the -interval flag. Each time the public key, directory servers,
+or store servers in the record change, it prints a line describing the
+change, which may be a sign that the user's account has been
+compromised. The first record read is compared with the record in the
+file named by the -baseline flag, if set, which must be in the YAML
+format printed by the command; each later one is compared with the
+record before it, so each change is reported once. It runs until
+interrupted.
+
 To install new users see the signup command.
 `
 	fs := flag.NewFlagSet("user", flag.ExitOnError)
 	put := fs.Bool("put", false, "write new user record")
 	inFile := fs.String("in", "", "input file (default standard input)")
 	force := fs.Bool("force", false, "force writing user record even if key is empty")
-	s.ParseFlags(fs, args, help, "user [username...]\n              user -put [-in=inputfile] [-force] [username]")
+	watch := fs.Bool("watch", false, "monitor the user record for changes")
+	interval := fs.Duration("interval", 5*time.Minute, "`period` between checks with -watch")
+	baseline := fs.String("baseline", "", "`file` holding the expected user record for -watch")
+	s.ParseFlags(fs, args, help, "user [username...]\n              user -put [-in=inputfile] [-force] [username]\n              user -watch [-interval=period] [-baseline=file] [username]")
 	keyServer := s.KeyServer()
+	if *watch {
+		if *put {
+			s.Exitf("-watch and -put are mutually exclusive")
+		}
+		if fs.NArg() > 1 {
+			usageAndExit(fs)
+		}
+		s.watchUser(fs.Arg(0), keyServer, *interval, *baseline)
+		return
+	}
+	if *baseline != "" {
+		s.Exitf("-baseline only available with -watch")
+	}
 	if *put {
 		s.putUser(fs, keyServer, s.GlobOneLocal(*inFile), *force)
 		return
@@ -116,6 +144,79 @@ To install new users see the signup command.
 	}
 }
 
+// watchUser implements user -watch. It never returns.
+func (s *State) watchUser(name string, keyServer upspin.KeyServer, interval time.Duration, baselineFile string) {
+	userName := s.Config.UserName()
+	if name != "" {
+		var err error
+		userName, err = user.Clean(upspin.UserName(name))
+		if err != nil {
+			s.Exit(err)
+		}
+	}
+	w := &userWatch{
+		name: userName,
+		lookup: func(name upspin.UserName) (*upspin.User, error) {
+			// Bypass the cache so each check asks the key server.
+			usercache.ResetGlobal()
+			return keyServer.Lookup(name)
+		},
+	}
+	if baselineFile != "" {
+		w.last = new(upspin.User)
+		if err := yaml.Unmarshal(s.ReadAll(s.GlobOneLocal(baselineFile)), w.last); err != nil {
+			s.Exit(err)
+		}
+		if w.last.Name != userName {
+			s.Exitf("baseline is for user %s, not %s", w.last.Name, userName)
+		}
+	}
+	for {
+		changes, err := w.check()
+		if err != nil {
+			s.Fail(err)
+		}
+		for _, c := range changes {
+			s.Printf("%s %s: %s\n", time.Now().UTC().Format(time.RFC3339), userName, c)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// userWatch holds the state of a user -watch command.
+type userWatch struct {
+	name   upspin.UserName
+	lookup func(upspin.UserName) (*upspin.User, error)
+	// last is the record most recently seen, or the baseline.
+	// If nil, the first check sets it.
+	last *upspin.User
+}
+
+// check looks up the user record and returns descriptions
+// of how it differs from the record seen previously.
+func (w *userWatch) check() ([]string, error) {
+	u, err := w.lookup(w.name)
+	if err != nil {
+		return nil, err
+	}
+	last := w.last
+	w.last = u
+	if last == nil {
+		return nil, nil
+	}
+	var changes []string
+	if u.PublicKey != last.PublicKey {
+		changes = append(changes, "public key changed")
+	}
+	if !equalEndpoints(u.Dirs, last.Dirs) {
+		changes = append(changes, fmt.Sprintf("dirs changed from %s to %s", last.Dirs, u.Dirs))
+	}
+	if !equalEndpoints(u.Stores, last.Stores) {
+		changes = append(changes, fmt.Sprintf("stores changed from %s to %s", last.Stores, u.Stores))
+	}
+	return changes, nil
+}
+
 func equalEndpoints(a, b []upspin.Endpoint) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,68 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"upspin.io/upspin"
+)
+
+func TestUserWatch(t *testing.T) {
+	dir := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "dir.example.com:443"}
+	store := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "store.example.com:443"}
+	record := &upspin.User{
+		Name:      "ann@example.com",
+		Dirs:      []upspin.Endpoint{dir},
+		Stores:    []upspin.Endpoint{store},
+		PublicKey: "key1",
+	}
+	var current upspin.User
+	w := &userWatch{
+		name: "ann@example.com",
+		lookup: func(name upspin.UserName) (*upspin.User, error) {
+			if name != "ann@example.com" {
+				t.Fatalf("lookup of %s", name)
+			}
+			u := current
+			return &u, nil
+		},
+	}
+	check := func(want ...string) {
+		t.Helper()
+		changes, err := w.check()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("got changes %q, want %q", changes, want)
+		}
+	}
+
+	current = *record
+	check() // First check sets the baseline.
+	check() // No change.
+
+	current.PublicKey = "key2"
+	check("public key changed")
+	check() // Reported once.
+
+	evil := upspin.Endpoint{Transport: upspin.Remote, NetAddr: "evil.example.com:443"}
+	current.Dirs = []upspin.Endpoint{evil}
+	current.Stores = []upspin.Endpoint{evil}
+	check(
+		"dirs changed from [remote,dir.example.com:443] to [remote,evil.example.com:443]",
+		"stores changed from [remote,store.example.com:443] to [remote,evil.example.com:443]",
+	)
+
+	// A baseline is compared with the first record read.
+	w.last = record
+	current = *record
+	check()
+	w.last = record
+	current.PublicKey = "key3"
+	check("public key changed")
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+
+	"upspin.io/client/clientutil"
+	"upspin.io/pack"
+	"upspin.io/upspin"
+)
+
+func (s *State) verify(args ...string) {
+	const help = `
+Verify checks that the named Upspin files are intact. For each file it
+fetches every block from the storage server and unpacks it as a read
+would, which for packings such as ee and eeintegrity verifies the
+signature of the directory entry and the checksum of each block, and
+checks that each block has the size recorded in the directory entry.
+No data is written.
+
+Each failure is reported, identifying the file and block, and the
+command exits with a non-zero status if any occurred. With the -R flag,
+verify checks all files in the named directories and their
+subdirectories; otherwise directories are skipped. Links are not
+followed. With the -v flag, verify also reports each file that is
+intact.
+`
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	recur := fs.Bool("R", false, "recur into subdirectories")
+	verbose := fs.Bool("v", false, "report files that are intact")
+	s.ParseFlags(fs, args, help, "verify [-R] [-v] path...")
+	if fs.NArg() == 0 {
+		usageAndExit(fs)
+	}
+	for _, entry := range s.GlobAllUpspin(fs.Args()) {
+		s.verifyEntry(entry, *recur, *verbose)
+	}
+}
+
+// verifyEntry verifies the file described by entry or, if recur is set and
+// the entry is a directory, the files in the tree rooted there.
+func (s *State) verifyEntry(entry *upspin.DirEntry, recur, verbose bool) {
+	switch {
+	case entry.IsLink():
+		return
+	case entry.IsDir():
+		if !recur {
+			return
+		}
+		entries, err := s.Client.Glob(upspin.AllFilesGlob(entry.Name))
+		if err != nil {
+			s.Fail(err)
+			return
+		}
+		for _, e := range entries {
+			s.verifyEntry(e, recur, verbose)
+		}
+		return
+	}
+	if s.verifyFile(entry) && verbose {
+		s.Printf("%s: ok\n", entry.Name)
+	}
+}
+
+// verifyFile fetches and unpacks each block of the file, reporting any
+// failures. It reports whether the file is intact.
+func (s *State) verifyFile(entry *upspin.DirEntry) bool {
+	if entry.IsIncomplete() {
+		s.Failf("%s: cannot verify without read rights", entry.Name)
+		return false
+	}
+	packer := pack.Lookup(entry.Packing)
+	if packer == nil {
+		s.Failf("%s: unrecognized packing %s", entry.Name, entry.Packing)
+		return false
+	}
+	bu, err := packer.Unpack(s.Config, entry)
+	if err != nil {
+		s.Failf("%s: %v", entry.Name, err)
+		return false
+	}
+	defer bu.Close()
+	ok := true
+	for i := 0; ; i++ {
+		block, more := bu.NextBlock()
+		if !more {
+			break
+		}
+		cipher, err := clientutil.ReadLocation(s.Config, block.Location)
+		if err != nil {
+			s.Failf("%s: block %d: %v", entry.Name, i, err)
+			ok = false
+			continue
+		}
+		clear, err := bu.Unpack(cipher)
+		if err != nil {
+			s.Failf("%s: block %d: %v", entry.Name, i, err)
+			ok = false
+			continue
+		}
+		if int64(len(clear)) != block.Size {
+			s.Failf("%s: block %d: size is %d, directory entry says %d", entry.Name, i, len(clear), block.Size)
+			ok = false
+		}
+	}
+	return ok
+}
@@ -367,6 +367,12 @@ func (cf *cachedFile) close() {
 	cf.n = nil
 	cf.c.Lock()
 	defer cf.c.Unlock()
+	// A file created after an earlier version was closed may still
+	// have that version in the LRU. Evict it so that neither its bytes
+	// nor its cache file are lost track of.
+	if old, ok := cf.c.lru.Remove(uname).(*cachedFile); ok && old != cf {
+		old.OnEviction(uname)
+	}
 	cf.c.lru.Add(uname, cf)
 	if cf.c.lruBytes < 0 {
 		log.Error.Print("lruBytes < 0")
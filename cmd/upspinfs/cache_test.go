@@ -0,0 +1,216 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+// These tests exercise the cache directly and need no FUSE mount.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/presotto/fuse"
+
+	"upspin.io/client"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+const cacheUser = "cachetester@google.com"
+
+// newTestFS returns a file system, with no mount, whose cache holds at
+// most maxBytes of closed files.
+func newTestFS(t *testing.T, maxBytes int64) *upspinFS {
+	cfg, err := testSetup(cacheUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(cfg)
+	if _, err := c.MakeDirectory(cacheUser + "/"); err != nil && !errors.Is(errors.Exist, err) {
+		t.Fatal(err)
+	}
+	return &upspinFS{
+		config: cfg,
+		client: c,
+		cache:  newCache(cfg, t.TempDir(), maxBytes),
+	}
+}
+
+// createFile creates the named file in f's cache, as a FUSE Create
+// followed by a Write would, and returns its node. Data is not changed.
+func createFile(t *testing.T, f *upspinFS, name upspin.PathName, data []byte) *node {
+	n := &node{
+		t:       otherNode,
+		f:       f,
+		uname:   name,
+		user:    cacheUser,
+		handles: make(map[*handle]bool),
+	}
+	if err := f.cache.create(&handle{n: n}); err != nil {
+		t.Fatal(err)
+	}
+	writeAt(t, n, data, 0)
+	n.attr.Size = uint64(len(data))
+	return n
+}
+
+// writeAt writes a copy of data to n's cached file, which encrypts what
+// it is given in place.
+func writeAt(t *testing.T, n *node, data []byte, offset int64) {
+	if _, err := n.cf.writeAt(append([]byte(nil), data...), offset); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// get returns the contents of the named file in Upspin.
+func get(t *testing.T, f *upspinFS, name upspin.PathName) []byte {
+	data, err := f.client.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCacheFsync(t *testing.T) {
+	f := newTestFS(t, maxBytes)
+	name := upspin.PathName(cacheUser + "/fsync")
+	data := []byte("written before fsync")
+	n := createFile(t, f, name, data)
+
+	if _, err := f.client.Lookup(name, false); !errors.Is(errors.NotExist, err) {
+		t.Fatalf("before Fsync, Lookup: err = %v, want NotExist", err)
+	}
+	if err := n.Fsync(context.Background(), &fuse.FsyncRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, f, name); !bytes.Equal(got, data) {
+		t.Errorf("after Fsync, Get = %q, want %q", got, data)
+	}
+	if n.cf.dirty {
+		t.Error("file is dirty after Fsync")
+	}
+
+	// A later change is written back by the next Fsync.
+	more := []byte(" and after")
+	writeAt(t, n, more, int64(len(data)))
+	if err := n.Fsync(context.Background(), &fuse.FsyncRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, more...)
+	if got := get(t, f, name); !bytes.Equal(got, data) {
+		t.Errorf("after second Fsync, Get = %q, want %q", got, data)
+	}
+
+	// An unchanged file is not written again.
+	seq := n.seq
+	if err := n.Fsync(context.Background(), &fuse.FsyncRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := f.client.Lookup(name, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Sequence != seq {
+		t.Errorf("Fsync of unchanged file: sequence %d, want %d", entry.Sequence, seq)
+	}
+}
+
+// closeFile closes n's cached file, as the release of its last handle
+// would, and returns the name of its cache file.
+func closeFile(n *node) string {
+	fname := n.cf.fname
+	n.cf.close()
+	return fname
+}
+
+// checkCached checks that the cache holds exactly the named closed files,
+// from least to most recently used, that their cache files exist, and
+// that it accounts for their bytes.
+func checkCached(t *testing.T, c *cache, want ...upspin.PathName) {
+	t.Helper()
+	var got []upspin.PathName
+	var bytes int64
+	for it := c.lru.NewReverseIterator(); ; {
+		k, v, ok := it.GetAndAdvance()
+		if !ok {
+			break
+		}
+		name, cf := k.(upspin.PathName), v.(*cachedFile)
+		got = append(got, name)
+		if _, err := os.Stat(cf.fname); err != nil {
+			t.Errorf("cache file of %s: %v", name, err)
+		}
+		bytes += cf.cachedSize
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("cache holds %v, want %v", got, want)
+	}
+	if c.lruBytes != bytes {
+		t.Errorf("cache counts %d bytes, want %d", c.lruBytes, bytes)
+	}
+	if c.lruBytes > c.lruMaxBytes {
+		t.Errorf("cache holds %d bytes, more than its limit of %d", c.lruBytes, c.lruMaxBytes)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	const size = 10
+	f := newTestFS(t, 3*size)
+	data := bytes.Repeat([]byte("x"), size)
+	names := []upspin.PathName{
+		cacheUser + "/a",
+		cacheUser + "/b",
+		cacheUser + "/c",
+		cacheUser + "/d",
+	}
+	fnames := make([]string, len(names))
+	for i, name := range names[:3] {
+		fnames[i] = closeFile(createFile(t, f, name, data))
+	}
+	checkCached(t, f.cache, names[:3]...)
+
+	// Using a makes b the least recently used.
+	f.cache.lru.Get(names[0])
+	checkCached(t, f.cache, names[1], names[2], names[0])
+
+	// Closing d evicts b, and only b, and removes its cache file.
+	fnames[3] = closeFile(createFile(t, f, names[3], data))
+	checkCached(t, f.cache, names[2], names[0], names[3])
+	if _, err := os.Stat(fnames[1]); !os.IsNotExist(err) {
+		t.Errorf("cache file of evicted %s: err = %v, want not exist", names[1], err)
+	}
+
+	// A file larger than the limit evicts everything, itself included.
+	closeFile(createFile(t, f, cacheUser+"/big", bytes.Repeat(data, 4)))
+	checkCached(t, f.cache)
+	for _, fname := range fnames {
+		if _, err := os.Stat(fname); !os.IsNotExist(err) {
+			t.Errorf("cache file %s: err = %v, want not exist", fname, err)
+		}
+	}
+}
+
+func TestCacheRecreate(t *testing.T) {
+	const size = 10
+	f := newTestFS(t, 3*size)
+	name := upspin.PathName(cacheUser + "/recreated")
+	old := closeFile(createFile(t, f, name, bytes.Repeat([]byte("o"), size)))
+
+	// Closing a new version replaces the old one, whose bytes are
+	// no longer counted.
+	closeFile(createFile(t, f, name, bytes.Repeat([]byte("n"), 2*size)))
+	checkCached(t, f.cache, name)
+	if f.cache.lruBytes != 2*size {
+		t.Errorf("cache counts %d bytes, want %d", f.cache.lruBytes, 2*size)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("cache file of old version: err = %v, want not exist", err)
+	}
+}
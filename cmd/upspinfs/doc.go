@@ -57,6 +57,14 @@ bits and the relevant Access file.
 - While random access will work, the first time a file is opened
 for read, it is read in its entirety and cached locally.
 
+- Closed files stay in the local cache, up to -cachesize bytes, and are
+evicted least recently used first. A changed file is written back to
+Upspin when it is closed or fsynced.
+
+- A file's Upspin metadata is visible as the read-only extended
+attributes user.upspin.packing, user.upspin.writer, user.upspin.sequence,
+and user.upspin.blocks, for example with getfattr -d -m user.upspin.
+
 - Hard links are really copy on write.
 The two names will refer to the original data until either file is changed.
 They will then diverge.
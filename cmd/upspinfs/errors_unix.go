@@ -62,6 +62,8 @@ var kindToErrno = map[errors.Kind]syscall.Errno{
 	errors.NotEmpty:      syscall.ENOTEMPTY,
 	errors.CannotDecrypt: syscall.EPERM,
 	errors.Private:       syscall.EACCES,
+	errors.Quota:         syscall.EDQUOT,
+	errors.Transient:     syscall.EAGAIN,
 }
 
 func notSupported(s string) *errnoError {
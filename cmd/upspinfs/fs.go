@@ -875,8 +875,16 @@ func (h *handle) Release(context gContext.Context, req *fuse.ReleaseRequest) err
 	return err
 }
 
-// Fsync implements fs.NodeFsyncer.Fsync.
+// Fsync implements fs.NodeFsyncer.Fsync. It writes the file back to
+// Upspin if it has been changed.
 func (n *node) Fsync(ctx gContext.Context, req *fuse.FsyncRequest) error {
+	const op errors.Op = "Fsync"
+
+	n.Lock()
+	defer n.Unlock()
+	if err := n.cf.writeback(n); err != nil {
+		return e2e(errors.E(op, n.uname, err))
+	}
 	return nil
 }
 
@@ -968,29 +976,6 @@ func (n *node) Rename(ctx gContext.Context, req *fuse.RenameRequest, newDir fs.N
 	return nil
 }
 
-// The following Xattr calls exist to short circuit any xattr calls.  Without them,
-// the macOS kernel will constantly look for ._ files.
-
-// Getxattr implements fs.NodeGetxattrer.Getxattr.
-func (n *node) Getxattr(ctx gContext.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
-	return notSupported("getxattr")
-}
-
-// Listxattr implements fs.NodeListxattrer.Listxattr.
-func (n *node) Listxattr(ctx gContext.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
-	return notSupported("listxattr")
-}
-
-// Setxattr implements fs.NodeSetxattrer.Setxattr.
-func (n *node) Setxattr(ctx gContext.Context, req *fuse.SetxattrRequest) error {
-	return notSupported("setxattr")
-}
-
-// Removexattr implements fs.NodeRemovexattrer.Removexattr.
-func (n *node) Removexattr(ctx gContext.Context, req *fuse.RemovexattrRequest) error {
-	return nil
-}
-
 // convertPath converts a host path separators into upspin ones.
 func convertPath(path string) upspin.PathName {
 	if filepath.Separator == '/' {
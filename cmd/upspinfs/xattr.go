@@ -0,0 +1,100 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	gContext "golang.org/x/net/context"
+
+	"github.com/presotto/fuse"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// xattrPrefix begins the names of the extended attributes with which
+// upspinfs exposes a file's Upspin metadata. They are read only.
+const xattrPrefix = "user.upspin."
+
+// xattrs maps the name of each extended attribute, less xattrPrefix,
+// to a function that returns its value for a DirEntry.
+var xattrs = map[string]func(de *upspin.DirEntry) string{
+	"packing":  func(de *upspin.DirEntry) string { return de.Packing.String() },
+	"writer":   func(de *upspin.DirEntry) string { return string(de.Writer) },
+	"sequence": func(de *upspin.DirEntry) string { return fmt.Sprint(de.Sequence) },
+	"blocks":   func(de *upspin.DirEntry) string { return fmt.Sprint(len(de.Blocks)) },
+}
+
+// xattrNames lists the extended attributes in the order Listxattr reports them.
+var xattrNames = []string{"packing", "writer", "sequence", "blocks"}
+
+var errReadOnlyXattr = &errnoError{syscall.EPERM, errors.Str("upspin attributes are read only")}
+
+// dirEntry returns the DirEntry for n, or nil if n is the root,
+// which has none.
+func (n *node) dirEntry() (*upspin.DirEntry, error) {
+	if n.t == rootNode {
+		return nil, nil
+	}
+	_, de, err := n.lookup(n.uname)
+	return de, err
+}
+
+// Getxattr implements fs.NodeGetxattrer.Getxattr. Attributes other than
+// the Upspin ones are reported as absent. Without this, the macOS kernel
+// will constantly look for ._ files.
+func (n *node) Getxattr(ctx gContext.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	const op errors.Op = "Getxattr"
+
+	name := strings.TrimPrefix(req.Name, xattrPrefix)
+	value, ok := xattrs[name]
+	if !ok || name == req.Name {
+		return fuse.ErrNoXattr
+	}
+	de, err := n.dirEntry()
+	if err != nil {
+		return e2e(errors.E(op, n.uname, err))
+	}
+	if de == nil {
+		return fuse.ErrNoXattr
+	}
+	resp.Xattr = []byte(value(de))
+	return nil
+}
+
+// Listxattr implements fs.NodeListxattrer.Listxattr.
+func (n *node) Listxattr(ctx gContext.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
+	if n.t == rootNode {
+		return nil
+	}
+	for _, name := range xattrNames {
+		resp.Append(xattrPrefix + name)
+	}
+	return nil
+}
+
+// Setxattr implements fs.NodeSetxattrer.Setxattr. The Upspin attributes
+// cannot be set.
+func (n *node) Setxattr(ctx gContext.Context, req *fuse.SetxattrRequest) error {
+	if strings.HasPrefix(req.Name, xattrPrefix) {
+		return errReadOnlyXattr
+	}
+	return notSupported("setxattr")
+}
+
+// Removexattr implements fs.NodeRemovexattrer.Removexattr. The Upspin
+// attributes cannot be removed.
+func (n *node) Removexattr(ctx gContext.Context, req *fuse.RemovexattrRequest) error {
+	if strings.HasPrefix(req.Name, xattrPrefix) {
+		return errReadOnlyXattr
+	}
+	return nil
+}
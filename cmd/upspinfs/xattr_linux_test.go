@@ -0,0 +1,98 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// getxattr returns the value of the named extended attribute of fn.
+func getxattr(fn, name string) (string, error) {
+	buf := make([]byte, 1024)
+	n, err := syscall.Getxattr(fn, name, buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
+
+func TestXattr(t *testing.T) {
+	testDir := mkTestDir(t, "testxattr")
+	fn := filepath.Join(testDir, "file")
+	mkFile(t, fn, randomBytes(t, 16*1024))
+
+	buf := make([]byte, 1024)
+	n, err := syscall.Listxattr(fn, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range xattrNames {
+		if !bytes.Contains(buf[:n], []byte(xattrPrefix+name+"\x00")) {
+			t.Errorf("Listxattr: %s missing from %q", xattrPrefix+name, buf[:n])
+		}
+	}
+
+	want := map[string]string{
+		"packing": testConfig.cfg.Packing().String(),
+		"writer":  testConfig.user,
+		"blocks":  "1",
+	}
+	for name, v := range want {
+		got, err := getxattr(fn, xattrPrefix+name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got != v {
+			t.Errorf("%s = %q, want %q", name, got, v)
+		}
+	}
+	seq, err := getxattr(fn, xattrPrefix+"sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sequence number increases when the file is rewritten.
+	mkFile(t, fn, randomBytes(t, 1024))
+	eventually(t, func() error {
+		seq, err := getxattr(fn, xattrPrefix+"sequence")
+		if err != nil {
+			return err
+		}
+		after, err := strconv.ParseInt(seq, 10, 64)
+		if err != nil {
+			return err
+		}
+		if after <= before {
+			return fmt.Errorf("sequence is %d after rewrite, was %d", after, before)
+		}
+		return nil
+	}, 5*time.Second)
+
+	if _, err := getxattr(fn, "user.other"); err != syscall.ENODATA {
+		t.Errorf("getxattr user.other: got %v, want %v", err, syscall.ENODATA)
+	}
+	if err := syscall.Setxattr(fn, xattrPrefix+"packing", []byte("plain"), 0); err != syscall.EPERM {
+		t.Errorf("setxattr: got %v, want %v", err, syscall.EPERM)
+	}
+	if err := syscall.Removexattr(fn, xattrPrefix+"writer"); err != syscall.EPERM {
+		t.Errorf("removexattr: got %v, want %v", err, syscall.EPERM)
+	}
+
+	remove(t, fn)
+	if err := os.RemoveAll(testDir); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -68,6 +68,11 @@ const (
 	cache       = "cache"
 )
 
+// envPrefix, followed by the upper-case form of a key, names the environment
+// variable that overrides that key. Not all keys may be overridden; see
+// InitClientConfig.
+const envPrefix = "UPSPIN_"
+
 // ErrNoFactotum indicates that the returned config contains no Factotum, and
 // that the user requested this by setting secrets=none in the configuration.
 var ErrNoFactotum = errors.Str("factotum not initialized: no secrets provided")
@@ -127,8 +132,35 @@ func FromFile(name string) (upspin.Config, error) {
 // Files without the suffix ".pem" are ignored.
 // The default value for tlscerts is the empty string,
 // in which case just the system roots are used.
+//
+// InitConfig ignores the environment; see InitClientConfig.
 func InitConfig(r io.Reader) (upspin.Config, error) {
 	const op errors.Op = "config.InitConfig"
+	return initConfig(op, r, false)
+}
+
+// InitClientConfig is like InitConfig but lets the environment override
+// the configuration. It is for the configuration of a user's client, such
+// as the upspin command, which may be run in several environments from one
+// configuration file. Servers, and the users that upbox sets up, use
+// InitConfig so that the environment they inherit does not change their
+// configuration.
+//
+// The values of username, keyserver, dirserver, storeserver, packing, and
+// cache may be overridden by the environment variables UPSPIN_USERNAME,
+// UPSPIN_KEYSERVER, UPSPIN_DIRSERVER, UPSPIN_STORESERVER, UPSPIN_PACKING,
+// and UPSPIN_CACHE. A variable with a non-empty value takes precedence over
+// the value in the configuration file, which takes precedence over the
+// default. Because the default secrets directory depends on the user name,
+// it too follows UPSPIN_USERNAME. An endpoint in the environment that
+// cannot be parsed is an error.
+func InitClientConfig(r io.Reader) (upspin.Config, error) {
+	const op errors.Op = "config.InitClientConfig"
+	return initConfig(op, r, true)
+}
+
+// initConfig implements InitConfig and, if env is set, InitClientConfig.
+func initConfig(op errors.Op, r io.Reader, env bool) (upspin.Config, error) {
 	vals := map[string]string{
 		username:    string(defaultUserName),
 		packing:     defaultPacking.String(),
@@ -161,6 +193,11 @@ func InitConfig(r io.Reader) (upspin.Config, error) {
 	if err := valsFromYAML(vals, other, data); err != nil {
 		return nil, errors.E(op, err)
 	}
+	if env {
+		if err := valsFromEnv(vals); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
 
 	// Construct a config from vals.
 	cfg := New()
@@ -250,6 +287,27 @@ func valsFromYAML(vals map[string]string, other map[string]interface{}, data []b
 	return nil
 }
 
+// valsFromEnv replaces the values in the provided map with those of the
+// corresponding environment variables that are not empty. It returns an error
+// if an endpoint set in the environment cannot be parsed.
+func valsFromEnv(vals map[string]string) error {
+	for key := range vals {
+		name := envPrefix + strings.ToUpper(key)
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		switch key {
+		case keyserver, dirserver, storeserver:
+			if _, err := parseEndpointText(v); err != nil {
+				return errors.E(errors.Invalid, errors.Errorf("$%s: cannot parse service %q: %v", name, v, err))
+			}
+		}
+		vals[key] = v
+	}
+	return nil
+}
+
 // asString tries to convert a value back into its original string. This will
 // not always be possible but should be for all our expected use cases.
 func asString(v interface{}) (string, error) {
@@ -268,6 +326,22 @@ func parseEndpoint(op errors.Op, vals map[string]string, key string, errorp *err
 		return upspin.Endpoint{}
 	}
 
+	ep, err := parseEndpointText(text)
+	if err != nil {
+		err = errors.E(op, errors.Errorf("cannot parse service %q: %v", text, err))
+		log.Error.Print(err)
+		if *errorp == nil {
+			*errorp = err
+		}
+		return upspin.Endpoint{}
+	}
+	return *ep
+}
+
+// parseEndpointText parses the text of an endpoint in a configuration,
+// which may omit the transport, implying remote, and the port of a
+// remote address, implying 443.
+func parseEndpointText(text string) (*upspin.Endpoint, error) {
 	ep, err := upspin.ParseEndpoint(text)
 	// If no transport is provided, assume remote transport.
 	if err != nil && !strings.Contains(text, ",") {
@@ -277,12 +351,7 @@ func parseEndpoint(op errors.Op, vals map[string]string, key string, errorp *err
 		}
 	}
 	if err != nil {
-		err = errors.E(op, errors.Errorf("cannot parse service %q: %v", text, err))
-		log.Error.Print(err)
-		if *errorp == nil {
-			*errorp = err
-		}
-		return upspin.Endpoint{}
+		return nil, err
 	}
 
 	// If it's a remote and the provided address does not include a port,
@@ -291,7 +360,7 @@ func parseEndpoint(op errors.Op, vals map[string]string, key string, errorp *err
 		ep.NetAddr += ":443"
 	}
 
-	return *ep
+	return ep, nil
 }
 
 // parseCacheValue parses the cache value and returns a config containing the cacheserver endpoint.
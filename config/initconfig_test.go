@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -227,7 +228,12 @@ func makeConfig(expect *expectations) string {
 }
 
 func testConfig(t *testing.T, expect *expectations, configuration string) {
-	config, err := InitConfig(strings.NewReader(configuration))
+	testConfigWith(t, InitConfig, expect, configuration)
+}
+
+// testConfigWith is like testConfig but parses the configuration with init.
+func testConfigWith(t *testing.T, init func(io.Reader) (upspin.Config, error), expect *expectations, configuration string) {
+	config, err := init(strings.NewReader(configuration))
 	if err != nil {
 		t.Fatalf("could not parse config %v: %v", configuration, err)
 	}
@@ -255,3 +261,39 @@ func testConfig(t *testing.T, expect *expectations, configuration string) {
 		t.Errorf("got cmdflags\n\t%#v\nexpected\n\t%#v", cmdflags, expect.cmdflags)
 	}
 }
+
+func TestEnvOverrides(t *testing.T) {
+	fileExpect := expectations{
+		username:    "p@google.com",
+		keyserver:   upspin.Endpoint{Transport: upspin.InProcess, NetAddr: ""},
+		dirserver:   upspin.Endpoint{Transport: upspin.Remote, NetAddr: "who.knows:1234"},
+		storeserver: upspin.Endpoint{Transport: upspin.Remote, NetAddr: "who.knows:1234"},
+		packing:     upspin.EEPack,
+		secrets:     secretsDir,
+	}
+	config := makeConfig(&fileExpect)
+
+	t.Setenv("UPSPIN_USERNAME", "Q@Google.com")
+	t.Setenv("UPSPIN_DIRSERVER", "dir.example.com")
+	t.Setenv("UPSPIN_STORESERVER", "remote,store.example.com:8080")
+	t.Setenv("UPSPIN_KEYSERVER", "") // Empty, so ignored.
+	t.Setenv("UPSPIN_PACKING", "plain")
+	expect := fileExpect
+	expect.username = "Q@google.com"
+	expect.dirserver = upspin.Endpoint{Transport: upspin.Remote, NetAddr: "dir.example.com:443"}
+	expect.storeserver = upspin.Endpoint{Transport: upspin.Remote, NetAddr: "store.example.com:8080"}
+	expect.packing = upspin.PlainPack
+	testConfigWith(t, InitClientConfig, &expect, config)
+
+	// Servers' configs are unaffected.
+	testConfig(t, &fileExpect, config)
+
+	t.Setenv("UPSPIN_DIRSERVER", "bogus,dir.example.com")
+	_, err := InitClientConfig(strings.NewReader(config))
+	if err == nil || !strings.Contains(err.Error(), "$UPSPIN_DIRSERVER") {
+		t.Errorf("InitClientConfig with bad UPSPIN_DIRSERVER: err = %v, want error naming the variable", err)
+	}
+	if _, err := InitConfig(strings.NewReader(config)); err != nil {
+		t.Errorf("InitConfig with bad UPSPIN_DIRSERVER: %v", err)
+	}
+}
@@ -31,7 +31,12 @@ type remote struct {
 	cfg        dialConfig
 }
 
-var _ upspin.DirServer = (*remote)(nil)
+var (
+	_ upspin.DirServer    = (*remote)(nil)
+	_ upspin.DirHistorian = (*remote)(nil)
+	_ upspin.DirExister   = (*remote)(nil)
+	_ upspin.DirPutAller  = (*remote)(nil)
+)
 
 // Glob implements upspin.DirServer.Glob.
 func (r *remote) Glob(pattern string) ([]*upspin.DirEntry, error) {
@@ -75,6 +80,35 @@ func (r *remote) Put(entry *upspin.DirEntry) (*upspin.DirEntry, error) {
 	})
 }
 
+// PutAll implements upspin.DirPutAller.
+func (r *remote) PutAll(entries []*upspin.DirEntry) ([]*upspin.DirEntry, error) {
+	op := r.opf("PutAll", "%d entries", len(entries))
+
+	b, err := proto.DirEntryBytes(entries)
+	if err != nil {
+		return nil, op.error(err)
+	}
+	req := &proto.DirPutAllRequest{
+		Entries: b,
+	}
+	resp := new(proto.EntriesError)
+	if err := r.Invoke("Dir/PutAll", req, resp, nil, nil); err != nil {
+		if err == upspin.ErrNotSupported {
+			return nil, err
+		}
+		return nil, op.error(errors.IO, err)
+	}
+	err = unmarshalError(resp.Error)
+	if err != nil && err != upspin.ErrFollowLink {
+		return nil, op.error(err)
+	}
+	put, pErr := proto.UpspinDirEntries(resp.Entries)
+	if pErr != nil {
+		return nil, op.error(errors.IO, pErr)
+	}
+	return put, op.error(err)
+}
+
 // WhichAccess implements upspin.DirServer.WhichAccess.
 func (r *remote) WhichAccess(pathName upspin.PathName) (*upspin.DirEntry, error) {
 	op := r.opf("WhichAccess", "%q", pathName)
@@ -153,6 +187,62 @@ func (r *remote) Watch(name upspin.PathName, sequence int64, done <-chan struct{
 	return events, nil
 }
 
+// History implements upspin.DirHistorian.
+func (r *remote) History(name upspin.PathName) ([]upspin.Event, error) {
+	op := r.opf("History", "%q", name)
+	req := &proto.DirLookupRequest{
+		Name: string(name),
+	}
+
+	stream := make(eventStream)
+	done := make(chan struct{})
+	defer close(done)
+	if err := r.Invoke("Dir/History", req, nil, stream, done); err != nil {
+		if err == upspin.ErrNotSupported {
+			return nil, err
+		}
+		return nil, op.error(err)
+	}
+	var events []upspin.Event
+	for ep := range stream {
+		e, err := proto.UpspinEvent(&ep)
+		if err != nil {
+			return nil, op.error(errors.IO, err)
+		}
+		if e.Error != nil {
+			return nil, op.error(e.Error)
+		}
+		events = append(events, *e)
+	}
+	return events, nil
+}
+
+// Exists implements upspin.DirExister.
+func (r *remote) Exists(name upspin.PathName) (bool, error) {
+	op := r.opf("Exists", "%q", name)
+	req := &proto.DirLookupRequest{
+		Name: string(name),
+	}
+
+	resp := new(proto.EntryError)
+	if err := r.Invoke("Dir/Exists", req, resp, nil, nil); err != nil {
+		if err == upspin.ErrNotSupported {
+			return false, err
+		}
+		return false, op.error(errors.IO, err)
+	}
+	err := unmarshalError(resp.Error)
+	switch {
+	case err == nil:
+		return true, nil
+	case err == upspin.ErrFollowLink:
+		return false, err
+	case errors.Is(errors.NotExist, err):
+		return false, nil
+	}
+	return false, op.error(err)
+}
+
 type eventStream chan proto.Event
 
 func (s eventStream) Send(b []byte, done <-chan struct{}) error {
@@ -85,12 +85,12 @@ func TestMakeRoot(t *testing.T) {
 	}
 
 	// Ensure log for user has been deleted.
-	hasLog, err := serverlog.HasLog(userName, s.logDir)
+	hasLog, err := serverlog.HasLog(userName, s.logDirFor(userName))
 	if err != nil {
 		t.Fatal(err)
 	}
 	if hasLog {
-		t.Fatalf("expected no log for user %q in %q", userName, s.logDir)
+		t.Fatalf("expected no log for user %q in %q", userName, s.logDirFor(userName))
 	}
 
 	// Create it again.
@@ -887,6 +887,38 @@ func TestAccessAndGroupFilesNotIncompleteFromWatch(t *testing.T) {
 	}
 }
 
+func TestWatchClosesWhenDone(t *testing.T) {
+	s, userCtx := newDirServerForTesting(t, userName)
+	_, err := putAccessOrGroupFile(t, s, userCtx, userName+"/Access", "*:"+userName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	events, err := s.Watch(userName+"/", upspin.WatchStart, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read one event, then disconnect without reading the rest.
+	select {
+	case <-events:
+	case <-time.After(time.Minute):
+		t.Fatal("timed out waiting for event")
+	}
+	close(done)
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("event channel not closed after done")
+		}
+	}
+}
+
 func TestOverwriteFileWithWrongSequence(t *testing.T) {
 	s, userCtx := newDirServerForTesting(t, userName)
 	_, err := putAccessOrGroupFile(t, s, userCtx, userName+"/Access", "*:"+userName)
@@ -973,9 +1005,22 @@ func makeDirectory(s *server, name upspin.PathName) (*upspin.DirEntry, error) {
 }
 
 func putAccessOrGroupFile(t testing.TB, s *server, userCtx upspin.Config, name upspin.PathName, contents string) (*upspin.DirEntry, error) {
+	de := newAccessOrGroupEntry(t, userCtx, name, contents)
+	_, err := s.Put(de)
+	return de, err
+}
+
+// newAccessOrGroupEntry packs the contents of an Access or Group file,
+// written by the owner of the tree it is in, writes them to the store and
+// returns the file's entry.
+func newAccessOrGroupEntry(t testing.TB, userCtx upspin.Config, name upspin.PathName, contents string) *upspin.DirEntry {
 	if !access.IsAccessControlFile(name) {
 		t.Fatalf("%s not an access file", name)
 	}
+	p, err := path.Parse(name)
+	if err != nil {
+		t.Fatal(err)
+	}
 	packer := pack.Lookup(upspin.EEIntegrityPack)
 	de := &upspin.DirEntry{
 		Name:       name,
@@ -984,7 +1029,7 @@ func putAccessOrGroupFile(t testing.TB, s *server, userCtx upspin.Config, name u
 		Time:       upspin.Now(),
 		Sequence:   upspin.SeqIgnore,
 		Attr:       upspin.AttrNone,
-		Writer:     userName,
+		Writer:     p.User(),
 		Packing:    upspin.EEIntegrityPack,
 	}
 	bp, err := packer.Pack(userCtx, de)
@@ -1006,8 +1051,7 @@ func putAccessOrGroupFile(t testing.TB, s *server, userCtx upspin.Config, name u
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = s.Put(de)
-	return de, err
+	return de
 }
 
 // checkDirEntry compares the main fields in dir entries got and want and
@@ -0,0 +1,75 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/dir/server/tree"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// Check checks the consistency of the trees of the named users, or of all
+// users with logs in the server's log directories if none are named, and
+// returns the problems found in each tree that has any. It is intended for
+// administrators and is not reachable through the DirServer interface.
+//
+// Check modifies nothing. A tree that the server has not loaded is opened
+// read-only: its log is neither recovered nor truncated, as loading it
+// would do after a crash, and a corrupt or incomplete log entry is
+// reported, with its offset, as a problem.
+func (s *server) Check(users ...upspin.UserName) (map[upspin.UserName][]error, error) {
+	const op errors.Op = "dir/server.Check"
+	if len(users) == 0 {
+		for _, dir := range s.logDirs {
+			u, err := serverlog.ListUsers(dir)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			users = append(users, u...)
+		}
+	}
+	report := make(map[upspin.UserName][]error)
+	for _, userName := range users {
+		problems, err := s.check(userName)
+		if err != nil {
+			return nil, errors.E(op, userName, err)
+		}
+		if len(problems) > 0 {
+			report[userName] = problems
+		}
+	}
+	return report, nil
+}
+
+// check checks the named user's tree, using the loaded tree if there is
+// one and otherwise opening it read-only from the user's logs.
+func (s *server) check(userName upspin.UserName) ([]error, error) {
+	if val, found := s.userTrees.Get(userName); found {
+		if t, ok := val.(*tree.Tree); ok {
+			return t.Check()
+		}
+	}
+	logDir := s.logDirFor(userName)
+	hasLog, err := serverlog.HasLog(userName, logDir)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLog {
+		return nil, errors.E(errors.NotExist)
+	}
+	// No storage, so nothing is backed up.
+	user, err := serverlog.Open(userName, logDir, s.serverConfig.Factotum(), nil)
+	if err != nil {
+		return nil, err
+	}
+	t, err := tree.New(s.serverConfig, user, tree.ReadOnly())
+	if err != nil {
+		user.Close()
+		return nil, err
+	}
+	defer t.Close()
+	return t.Check()
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestCheck(t *testing.T) {
+	const (
+		owner = "checker@flintstone.org"
+		link  = owner + "/link"
+	)
+	s, _ := newDirServerForTesting(t, owner)
+	if _, err := makeDirectory(s, owner+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := makeDirectory(s, owner+"/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	// All users in the log directory, which holds other tests' users too.
+	report, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems, ok := report[owner]; ok {
+		t.Fatalf("Check of consistent tree: %v", problems)
+	}
+
+	_, err = s.Put(&upspin.DirEntry{
+		Name:       link,
+		SignedName: link,
+		Attr:       upspin.AttrLink,
+		Writer:     owner,
+		Link:       owner + "/dir/missing",
+		Packing:    upspin.PlainPack,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = s.Check(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := report[owner]
+	if len(problems) != 1 || !errors.Is(errors.Internal, problems[0]) {
+		t.Fatalf("Check with dangling link: got %v, want one Internal error", problems)
+	}
+
+	if _, err := s.Check("nobody@flintstone.org"); !errors.Is(errors.NotExist, err) {
+		t.Errorf("Check of user with no tree: err = %v, want NotExist", err)
+	}
+}
+
+func TestCheckCorruptLog(t *testing.T) {
+	const owner = "corrupt@flintstone.org"
+	s, _ := newDirServerForTesting(t, owner)
+	if _, err := makeDirectory(s, owner+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := makeDirectory(s, owner+"/dir"); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := s.loadTreeFor(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := tree.User()
+	checkpoint, err := user.ReadOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := user.AppendOffset()
+	if checkpoint == end {
+		t.Fatal("log has no entries beyond the checkpoint")
+	}
+
+	// A crash part way through writing an entry leaves its start.
+	if _, err := user.Write([]byte{0x00, 0x20}); err != nil {
+		t.Fatal(err)
+	}
+	end += 2
+
+	// A server that has not loaded the tree, as after the crash.
+	fresh, err := New(s.serverConfig, "logDir="+s.logDirFor(owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := fresh.(*server).Check(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := report[owner]
+	want := fmt.Sprintf("corrupt log entry at offset %d", end-2)
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), want) {
+		t.Fatalf("Check of corrupt log: got %v, want one problem with %q", problems, want)
+	}
+
+	// Neither the log nor the checkpoint has changed.
+	if got := user.AppendOffset(); got != end {
+		t.Errorf("AppendOffset = %d, want %d", got, end)
+	}
+	if got, err := user.ReadOffset(); err != nil || got != checkpoint {
+		t.Errorf("ReadOffset = %d, %v; want %d", got, err, checkpoint)
+	}
+}
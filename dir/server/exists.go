@@ -0,0 +1,60 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"upspin.io/access"
+	"upspin.io/errors"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+var _ upspin.DirExister = (*server)(nil)
+
+// Exists implements upspin.DirExister.
+// Unlike Lookup, it does not flush the tree to clean the entry,
+// nor does it fetch or mask the entry's blocks.
+func (s *server) Exists(name upspin.PathName) (bool, error) {
+	const op errors.Op = "dir/server.Exists"
+	o, m := newOptMetric(op)
+	defer m.Done()
+
+	p, err := path.Parse(name)
+	if err != nil {
+		return false, errors.E(op, name, err)
+	}
+
+	// Check rights first so that nothing about the item,
+	// including whether it exists, is revealed to those without them.
+	hasAny, link, err := s.hasRight(access.AnyRight, p, o)
+	if err == upspin.ErrFollowLink {
+		_, err := s.errLink(op, link, o)
+		if errors.Is(errors.Private, err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if errors.Is(errors.NotExist, err) {
+		// The user's root does not exist.
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	if !hasAny {
+		return false, nil
+	}
+
+	_, err = s.lookup(p, !entryMustBeClean, o)
+	switch {
+	case err == nil, err == upspin.ErrFollowLink:
+		// Links in earlier elements were found by hasRight,
+		// so if the lookup found a link it is the item itself.
+		return true, nil
+	case errors.Is(errors.NotExist, err):
+		return false, nil
+	}
+	return false, errors.E(op, err)
+}
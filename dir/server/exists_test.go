@@ -0,0 +1,80 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"upspin.io/upspin"
+)
+
+func TestExists(t *testing.T) {
+	const (
+		owner   = "existentialist@flintstone.org"
+		file    = owner + "/file.txt"
+		missing = owner + "/missing.txt"
+		link    = owner + "/link"
+	)
+	s, _ := newDirServerForTesting(t, owner)
+	if _, err := makeDirectory(s, owner+"/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, de := range []*upspin.DirEntry{{
+		Name:       file,
+		SignedName: file,
+		Attr:       upspin.AttrNone,
+		Writer:     owner,
+		Packing:    upspin.PlainPack,
+	}, {
+		Name:       link,
+		SignedName: link,
+		Attr:       upspin.AttrLink,
+		Writer:     owner,
+		Link:       "linkerdude@linkatron.lnk/target",
+		Packing:    upspin.PlainPack,
+	}} {
+		if _, err := s.Put(de); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   upspin.PathName
+		exists bool
+		err    error
+	}{
+		{owner + "/", true, nil},
+		{file, true, nil},
+		{missing, false, nil},
+		{file + "/below", false, nil},
+		{link, true, nil},
+		{link + "/below", false, upspin.ErrFollowLink},
+		{"barney@rubble.org/", false, nil},
+	}
+	for _, test := range tests {
+		exists, err := s.Exists(test.name)
+		if err != test.err {
+			t.Errorf("Exists(%q): err = %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if exists != test.exists {
+			t.Errorf("Exists(%q) = %v, want %v", test.name, exists, test.exists)
+		}
+	}
+
+	// Another user with no rights cannot distinguish
+	// existing items from missing ones.
+	sOther, _ := newDirServerForTesting(t, otherUser)
+	for _, name := range []upspin.PathName{owner + "/", file, missing, link, link + "/below"} {
+		exists, err := sOther.Exists(name)
+		if err != nil {
+			t.Errorf("other user: Exists(%q): %v", name, err)
+			continue
+		}
+		if exists {
+			t.Errorf("other user: Exists(%q) = true, want false", name)
+		}
+	}
+}
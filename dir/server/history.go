@@ -0,0 +1,76 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"upspin.io/access"
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+var _ upspin.DirHistorian = (*server)(nil)
+
+// maxHistory is the maximum number of events returned by History.
+const maxHistory = 1000
+
+// History implements upspin.DirHistorian.
+// The history is read from the tree's log and access rights are those
+// currently in effect for the named item. At most maxHistory events,
+// the most recent, are returned.
+func (s *server) History(name upspin.PathName) ([]upspin.Event, error) {
+	const op errors.Op = "dir/server.History"
+	o, m := newOptMetric(op)
+	defer m.Done()
+
+	p, err := path.Parse(name)
+	if err != nil {
+		return nil, errors.E(op, name, err)
+	}
+
+	// Snapshot trees are built using PutDir and have
+	// no meaningful per-item history.
+	if isSnapshotUser(p.User()) {
+		return nil, upspin.ErrNotSupported
+	}
+
+	hasAny, link, err := s.hasRight(access.AnyRight, p, o)
+	if err == upspin.ErrFollowLink {
+		_, err := s.errLink(op, link, o)
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if !hasAny {
+		return nil, errors.E(op, name, errors.Private)
+	}
+	hasRead, _, err := s.hasRight(access.Read, p, o)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	tree, err := s.loadTreeFor(p.User(), o)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	logEntries, err := tree.History(p, maxHistory)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	events := make([]upspin.Event, len(logEntries))
+	for i := range logEntries {
+		entry := &logEntries[i].Entry
+		if !hasRead && !access.IsAccessControlFile(entry.SignedName) {
+			entry.MarkIncomplete()
+		}
+		events[i] = upspin.Event{
+			Entry:  entry,
+			Delete: logEntries[i].Op == serverlog.Delete,
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestHistory(t *testing.T) {
+	const (
+		owner = "historian@flintstone.org"
+		name  = owner + "/file.txt"
+	)
+	s, _ := newDirServerForTesting(t, owner)
+	if _, err := makeDirectory(s, owner+"/"); err != nil {
+		t.Fatal(err)
+	}
+	put := func(size int64) {
+		de := &upspin.DirEntry{
+			Name:       name,
+			SignedName: name,
+			Attr:       upspin.AttrNone,
+			Writer:     owner,
+			Sequence:   upspin.SeqIgnore,
+			Packing:    upspin.PlainPack,
+			Blocks: []upspin.DirBlock{{
+				Location: upspin.Location{Reference: "ref"},
+				Size:     size,
+			}},
+		}
+		if _, err := s.Put(de); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(1)
+	put(2)
+	if _, err := s.Delete(name); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.History(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		delete bool
+		size   int64
+	}{
+		{false, 1},
+		{false, 2},
+		{true, 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Entry.Name != name {
+			t.Errorf("%d: name = %q, want %q", i, e.Entry.Name, name)
+		}
+		if e.Delete != want[i].delete {
+			t.Errorf("%d: delete = %v, want %v", i, e.Delete, want[i].delete)
+		}
+		if e.Entry.IsIncomplete() {
+			t.Errorf("%d: entry is incomplete", i)
+			continue
+		}
+		if size, _ := e.Entry.Size(); size != want[i].size {
+			t.Errorf("%d: size = %d, want %d", i, size, want[i].size)
+		}
+	}
+
+	// Another user with no rights cannot learn anything.
+	sOther, _ := newDirServerForTesting(t, otherUser)
+	_, err = sOther.History(name)
+	if !errors.Is(errors.Private, err) {
+		t.Errorf("err = %v, want Private", err)
+	}
+}
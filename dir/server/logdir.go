@@ -0,0 +1,43 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"hash/fnv"
+
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// logDirFor returns the directory that holds, or will hold, the logs
+// for the named user.
+func (s *server) logDirFor(userName upspin.UserName) string {
+	if len(s.logDirs) == 1 {
+		return s.logDirs[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(userName))
+	return s.logDirs[h.Sum32()%uint32(len(s.logDirs))]
+}
+
+// checkNoLogElsewhere returns an error if the named user has logs in
+// any of the server's log directories other than dir, which happens
+// only if the set of directories was changed after the logs were written.
+func (s *server) checkNoLogElsewhere(userName upspin.UserName, dir string) error {
+	for _, other := range s.logDirs {
+		if other == dir {
+			continue
+		}
+		hasLog, err := serverlog.HasLog(userName, other)
+		if err != nil {
+			return err
+		}
+		if hasLog {
+			return errors.E(userName, errors.Internal, errors.Errorf("logs are in %s, expected in %s; were the log directories changed?", other, dir))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"upspin.io/cache"
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestLogDirs(t *testing.T) {
+	dirs := []string{t.TempDir(), t.TempDir()}
+	sharded := &server{logDirs: dirs}
+
+	// Find a user for each directory.
+	users := make([]upspin.UserName, len(dirs))
+	for i := 0; users[0] == "" || users[1] == ""; i++ {
+		u := upspin.UserName(fmt.Sprintf("shard%d@example.com", i))
+		for j, dir := range dirs {
+			if sharded.logDirFor(u) == dir && users[j] == "" {
+				users[j] = u
+			}
+		}
+	}
+
+	for i, u := range users {
+		s, _ := newDirServerForTesting(t, u)
+		s.logDirs = dirs
+		if _, err := makeDirectory(s, upspin.PathName(u)+"/"); err != nil {
+			t.Fatal(err)
+		}
+		name := upspin.PathName(u) + "/file"
+		if _, err := s.Put(&upspin.DirEntry{
+			Name:       name,
+			SignedName: name,
+			Attr:       upspin.AttrNone,
+			Writer:     u,
+			Packing:    upspin.PlainPack,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Lookup(name); err != nil {
+			t.Fatal(err)
+		}
+		for j, dir := range dirs {
+			hasLog, err := serverlog.HasLog(u, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := i == j; hasLog != want {
+				t.Errorf("user %s: log in %s is %v, want %v", u, dir, hasLog, want)
+			}
+		}
+	}
+
+	// A server with the directories reordered must refuse
+	// to start a second tree for an existing user.
+	s, _ := newDirServerForTesting(t, users[0])
+	s.logDirs = []string{dirs[1], dirs[0]}
+	s.userTrees = cache.NewLRU(10)
+	_, err := s.Lookup(upspin.PathName(users[0]) + "/file")
+	if !errors.Is(errors.Internal, err) {
+		t.Fatalf("Lookup with reordered logDirs: err = %v, want Internal error", err)
+	}
+}
+
+func TestLogDirsOption(t *testing.T) {
+	_, cfg := newDirServerForTesting(t, userName)
+	for _, opts := range [][]string{
+		{"logDirs=a,,b"},
+		{"logDir=a", "logDirs=b,c"},
+	} {
+		if _, err := New(cfg, opts...); !errors.Is(errors.Invalid, err) {
+			t.Errorf("New(%q): err = %v, want Invalid error", opts, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestPutAll(t *testing.T) {
+	const owner = "putall@flintstone.org"
+	s, userCtx := newDirServerForTesting(t, owner)
+	if _, err := makeDirectory(s, owner+"/"); err != nil {
+		t.Fatal(err)
+	}
+	file := func(name upspin.PathName) *upspin.DirEntry {
+		return &upspin.DirEntry{
+			Name:       name,
+			SignedName: name,
+			Writer:     owner,
+			Sequence:   upspin.SeqNotExist,
+			Packing:    upspin.PlainPack,
+		}
+	}
+	dir := &upspin.DirEntry{
+		Name:       owner + "/dir",
+		SignedName: owner + "/dir",
+		Attr:       upspin.AttrDirectory,
+		Sequence:   upspin.SeqIgnore,
+	}
+
+	// A directory, its Access file and a file protected by it.
+	entries := []*upspin.DirEntry{
+		dir,
+		newAccessOrGroupEntry(t, userCtx, owner+"/dir/Access", "*:"+owner),
+		file(owner + "/dir/file"),
+	}
+	put, err := s.PutAll(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(put) != len(entries) {
+		t.Fatalf("PutAll returned %d entries, want %d", len(put), len(entries))
+	}
+	for i, e := range entries {
+		if !put[i].IsIncomplete() {
+			t.Errorf("entry returned for %s is not incomplete", e.Name)
+		}
+		got, err := s.Lookup(e.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A directory's sequence number advances as entries are put in it.
+		if !got.IsDir() && got.Sequence != put[i].Sequence {
+			t.Errorf("Lookup(%s) has sequence %d, want %d", e.Name, got.Sequence, put[i].Sequence)
+		}
+	}
+
+	// If any entry cannot be put, none is.
+	for _, test := range []struct {
+		entries []*upspin.DirEntry
+		kind    errors.Kind
+	}{
+		{[]*upspin.DirEntry{file(owner + "/dir/new"), file(owner + "/dir/file")}, errors.Exist},
+		{[]*upspin.DirEntry{file(owner + "/dir/new"), file(owner + "/missing/file")}, errors.NotExist},
+		{[]*upspin.DirEntry{file(owner + "/dir/new"), file(userName + "/new")}, errors.Invalid},
+		{[]*upspin.DirEntry{file(owner + "/dir/new"), file(owner + "/dir/new")}, errors.Invalid},
+	} {
+		if _, err := s.PutAll(test.entries); !errors.Is(test.kind, err) {
+			t.Errorf("PutAll(%v): err = %v, want %v", test.entries, err, test.kind)
+		}
+		if _, err := s.Lookup(owner + "/dir/new"); !errors.Is(errors.NotExist, err) {
+			t.Fatalf("after failed PutAll(%v), Lookup: err = %v, want NotExist", test.entries, err)
+		}
+	}
+
+	// Only the owner may write the files.
+	sOther, _ := newDirServerForTesting(t, otherUser)
+	_, err = sOther.PutAll([]*upspin.DirEntry{file(owner + "/dir/other")})
+	if !errors.Is(errors.Private, err) {
+		t.Errorf("PutAll by other user: err = %v, want Private", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestQuota(t *testing.T) {
+	const user = "quota@example.com"
+	s, _ := newDirServerForTesting(t, user)
+	s.quotaEntries = 2
+	for _, name := range []upspin.PathName{user + "/", user + "/dir1", user + "/dir2"} {
+		if _, err := makeDirectory(s, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := makeDirectory(s, user+"/dir3")
+	if !errors.Is(errors.Quota, err) {
+		t.Fatalf("MakeDirectory over quota: err = %v, want Quota error", err)
+	}
+}
+
+func TestQuotaOptions(t *testing.T) {
+	_, cfg := newDirServerForTesting(t, userName)
+	for _, opt := range []string{"quotaEntries=0", "quotaBytes=-1", "quotaBytes=1G"} {
+		if _, err := New(cfg, opt); !errors.Is(errors.Invalid, err) {
+			t.Errorf("New(%q): err = %v, want Invalid error", opt, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package server
 
 import (
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -56,9 +57,25 @@ type server struct {
 	// by user.Parse.
 	userBase, userSuffix, userDomain string
 
-	// logDir is the directory path accessible through the local file system
-	// where user logs are stored.
-	logDir string
+	// logDirs are the directory paths accessible through the local file
+	// system where user logs are stored. Each user's logs are in just one
+	// of them, chosen by logDirFor.
+	logDirs []string
+
+	// logSync is the policy for flushing user logs to stable storage.
+	logSync serverlog.SyncPolicy
+
+	// maxLogSize, if positive, is the size at which a user's log file
+	// is sealed and a new one started.
+	maxLogSize int64
+
+	// truncateCorruptLogs reports whether to recover trees whose logs are
+	// corrupt by discarding the corrupt and later entries.
+	truncateCorruptLogs bool
+
+	// quotaEntries and quotaBytes, if positive, limit the number of
+	// entries in each user's tree and the total size of its files.
+	quotaEntries, quotaBytes int64
 
 	// userTrees keeps track of user trees in LRU fashion, where key
 	// is an upspin.UserName and value is the tree.Tree for that user name.
@@ -108,10 +125,14 @@ type server struct {
 // snapshotCreate is used to create a snapshot and report its success.
 type snapshotCreate struct {
 	userName upspin.UserName
+	subtree  string // Directory to snapshot, relative to the root; empty for the root.
 	created  chan error
 }
 
-var _ upspin.DirServer = (*server)(nil)
+var (
+	_ upspin.DirServer   = (*server)(nil)
+	_ upspin.DirPutAller = (*server)(nil)
+)
 
 // options are optional parameters to almost every inner method of directory
 // for doing optional, non-correctness-related work.
@@ -120,7 +141,29 @@ type options struct {
 	// Add other things below (for example, some health monitoring stats).
 }
 
-// New creates a new instance of DirServer with the given options
+// New creates a new instance of DirServer with the given options.
+//
+// The option "logDir=<dir>" names the directory holding the user logs.
+// Alternatively, "logDirs=<dir>,<dir>,..." spreads the users' logs across
+// several directories, perhaps on different volumes, each user being
+// assigned to one by a hash of the user name. The assignment depends on
+// the number and order of the directories, so they must not change once
+// logs have been written.
+// The option "logSync=<mode>" sets how eagerly the logs are flushed to
+// stable storage, trading durability for throughput; the mode is one of
+// "always" (the default), "interval", or "os", as described by
+// serverlog.SyncMode. In interval mode, "logSyncInterval=<duration>" and
+// "logSyncEntries=<n>" bound the batches of entries flushed together.
+// The option "maxLogSize=<bytes>" sets the size at which a log file is
+// sealed and a new one started; the default is serverlog.MaxLogSize.
+// The option "truncateCorruptLogs=true" allows a user's tree to be loaded
+// even if its log is corrupt, discarding the first corrupt entry and all
+// later ones; see tree.TruncateCorruptLog.
+// The options "quotaEntries=<n>" and "quotaBytes=<bytes>" limit each user's
+// tree to that many entries and that total size of the blocks of its files;
+// a Put that would exceed either limit fails with an errors.Quota error.
+// By default there is no limit. See tree.Quota.
+// Other options are passed to the storage backend.
 func New(cfg upspin.Config, options ...string) (upspin.DirServer, error) {
 	const op errors.Op = "dir/server.New"
 	if cfg == nil {
@@ -144,6 +187,12 @@ func New(cfg upspin.Config, options ...string) (upspin.DirServer, error) {
 	// Check which options are present and pick suitable defaults.
 	var (
 		logDir         string
+		logDirs        []string
+		logSync        serverlog.SyncPolicy
+		maxLogSize     int64
+		truncateLogs   bool
+		quotaEntries   int64
+		quotaBytes     int64
 		storageBackend string
 		storageOpts    []storage.DialOpts
 	)
@@ -153,6 +202,93 @@ func New(cfg upspin.Config, options ...string) (upspin.DirServer, error) {
 			logDir = opt[len(logDirPrefix):]
 			continue
 		}
+		const logDirsPrefix = "logDirs="
+		if strings.HasPrefix(opt, logDirsPrefix) {
+			logDirs = strings.Split(opt[len(logDirsPrefix):], ",")
+			for _, dir := range logDirs {
+				if dir == "" {
+					return nil, errors.E(op, errors.Invalid, errors.Errorf("empty directory in %q", opt))
+				}
+			}
+			continue
+		}
+		const logSyncPrefix = "logSync="
+		if strings.HasPrefix(opt, logSyncPrefix) {
+			switch mode := opt[len(logSyncPrefix):]; mode {
+			case "always":
+				logSync.Mode = serverlog.SyncAlways
+			case "interval":
+				logSync.Mode = serverlog.SyncInterval
+			case "os":
+				logSync.Mode = serverlog.SyncOS
+			default:
+				return nil, errors.E(op, errors.Invalid, errors.Errorf("unknown logSync mode %q", mode))
+			}
+			continue
+		}
+		const logSyncIntervalPrefix = "logSyncInterval="
+		if strings.HasPrefix(opt, logSyncIntervalPrefix) {
+			d, err := time.ParseDuration(opt[len(logSyncIntervalPrefix):])
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
+			logSync.Interval = d
+			continue
+		}
+		const logSyncEntriesPrefix = "logSyncEntries="
+		if strings.HasPrefix(opt, logSyncEntriesPrefix) {
+			n, err := strconv.Atoi(opt[len(logSyncEntriesPrefix):])
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
+			logSync.Entries = n
+			continue
+		}
+		const maxLogSizePrefix = "maxLogSize="
+		if strings.HasPrefix(opt, maxLogSizePrefix) {
+			n, err := strconv.ParseInt(opt[len(maxLogSizePrefix):], 10, 64)
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
+			if n <= 0 {
+				return nil, errors.E(op, errors.Invalid, errors.Errorf("maxLogSize must be positive, got %d", n))
+			}
+			maxLogSize = n
+			continue
+		}
+		const truncatePrefix = "truncateCorruptLogs="
+		if strings.HasPrefix(opt, truncatePrefix) {
+			b, err := strconv.ParseBool(opt[len(truncatePrefix):])
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
+			truncateLogs = b
+			continue
+		}
+		const quotaEntriesPrefix = "quotaEntries="
+		if strings.HasPrefix(opt, quotaEntriesPrefix) {
+			n, err := strconv.ParseInt(opt[len(quotaEntriesPrefix):], 10, 64)
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
+			if n <= 0 {
+				return nil, errors.E(op, errors.Invalid, errors.Errorf("quotaEntries must be positive, got %d", n))
+			}
+			quotaEntries = n
+			continue
+		}
+		const quotaBytesPrefix = "quotaBytes="
+		if strings.HasPrefix(opt, quotaBytesPrefix) {
+			n, err := strconv.ParseInt(opt[len(quotaBytesPrefix):], 10, 64)
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
+			if n <= 0 {
+				return nil, errors.E(op, errors.Invalid, errors.Errorf("quotaBytes must be positive, got %d", n))
+			}
+			quotaBytes = n
+			continue
+		}
 		const backendPrefix = "backend="
 		if strings.HasPrefix(opt, backendPrefix) {
 			storageBackend = opt[len(backendPrefix):]
@@ -160,7 +296,10 @@ func New(cfg upspin.Config, options ...string) (upspin.DirServer, error) {
 		}
 		storageOpts = append(storageOpts, storage.WithOptions(opt))
 	}
-	if logDir == "" {
+	if logDir != "" && logDirs != nil {
+		return nil, errors.E(op, errors.Invalid, "cannot set both logDir and logDirs")
+	}
+	if logDir == "" && logDirs == nil {
 		dir, err := os.MkdirTemp("", "DirServer")
 		if err != nil {
 			return nil, errors.E(op, errors.IO, err)
@@ -168,6 +307,9 @@ func New(cfg upspin.Config, options ...string) (upspin.DirServer, error) {
 		log.Error.Printf("%s: warning: writing important logs to a temporary directory (%q). A server restart will lose data.", op, dir)
 		logDir = dir
 	}
+	if logDirs == nil {
+		logDirs = []string{logDir}
+	}
 
 	var store storage.Storage
 	if storageBackend != "" {
@@ -185,16 +327,21 @@ func New(cfg upspin.Config, options ...string) (upspin.DirServer, error) {
 		groupCacheSize  = 100
 	)
 	s := &server{
-		serverConfig:  cfg,
-		userName:      cfg.UserName(),
-		logDir:        logDir,
-		userTrees:     cache.NewLRU(userCacheSize),
-		access:        cache.NewLRU(accessCacheSize),
-		defaultAccess: cache.NewLRU(accessCacheSize),
-		remoteGroups:  cache.NewLRU(groupCacheSize),
-		userLocks:     make([]sync.Mutex, numUserLocks),
-		now:           upspin.Now,
-		storage:       store,
+		serverConfig:        cfg,
+		userName:            cfg.UserName(),
+		logDirs:             logDirs,
+		logSync:             logSync,
+		maxLogSize:          maxLogSize,
+		truncateCorruptLogs: truncateLogs,
+		quotaEntries:        quotaEntries,
+		quotaBytes:          quotaBytes,
+		userTrees:           cache.NewLRU(userCacheSize),
+		access:              cache.NewLRU(accessCacheSize),
+		defaultAccess:       cache.NewLRU(accessCacheSize),
+		remoteGroups:        cache.NewLRU(groupCacheSize),
+		userLocks:           make([]sync.Mutex, numUserLocks),
+		now:                 upspin.Now,
+		storage:             store,
 	}
 	shutdown.Handle(s.shutdown)
 	// Start background services.
@@ -320,11 +467,107 @@ func (s *server) Put(entry *upspin.DirEntry) (*upspin.DirEntry, error) {
 		errorC := make(chan error)
 		s.snapshotControl <- snapshotCreate{
 			userName: p.User(),
+			subtree:  snapshotSubtree(p),
 			created:  errorC,
 		}
 		return entry, <-errorC // Returned error reports status of snapshot.
 	}
 
+	if link, err := s.checkPut(op, p, entry, o); err != nil {
+		return link, err
+	}
+
+	entry, err = s.put(op, p, entry, o)
+	if err != nil {
+		return entry, err
+	}
+	// Return Incomplete entry with Sequence number.
+	retEntry := &upspin.DirEntry{
+		Attr:     upspin.AttrIncomplete,
+		Sequence: entry.Sequence,
+	}
+	return retEntry, nil
+}
+
+// PutAll implements upspin.DirPutAller. It puts the entries, in order, as a
+// single transaction: either all of them are put, or none is. They are recorded together in the log, so that
+// a crash cannot leave, say, a file without the Access file that protects
+// it. The entries must all be in the same user's tree, and none may be a
+// root or a snapshot control file. Each entry is checked as by Put, against
+// the tree as it was before the transaction, except that an entry may be put
+// in a directory made by an earlier entry. As with Put, the returned entries
+// are incomplete and hold only their new sequence numbers.
+//
+// If the returned error is ErrFollowLink, the only entry returned is that
+// of the link found along the path of one of the entries.
+func (s *server) PutAll(entries []*upspin.DirEntry) ([]*upspin.DirEntry, error) {
+	const op errors.Op = "dir/server.PutAll"
+	o, m := newOptMetric(op)
+	defer m.Done()
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	var userName upspin.UserName
+	for _, entry := range entries {
+		err := valid.DirEntry(entry)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		p, err := path.Parse(entry.Name)
+		if err != nil {
+			return nil, errors.E(op, entry.Name, err)
+		}
+		if userName == "" {
+			userName = p.User()
+		} else if p.User() != userName {
+			return nil, errors.E(op, p.Path(), errors.Invalid, "entries must all be in the same tree")
+		}
+		if p.IsRoot() {
+			return nil, errors.E(op, p.Path(), errors.Invalid, "cannot put a root with other entries")
+		}
+		if isSnapshotUser(p.User()) && isSnapshotControlFile(p) {
+			return nil, errors.E(op, p.Path(), errors.Invalid, "cannot take a snapshot with other entries")
+		}
+		if link, err := s.checkPut(op, p, entry, o); err == upspin.ErrFollowLink {
+			return []*upspin.DirEntry{link}, err
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	tree, err := s.loadTreeFor(userName, o)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	put, err := tree.PutAll(entries)
+	if err == upspin.ErrFollowLink {
+		// A link was made along the path since it was checked.
+		link, err := s.errLink(op, put[0], o)
+		if link == nil {
+			return nil, err
+		}
+		return []*upspin.DirEntry{link}, err
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	// Return Incomplete entries with Sequence numbers.
+	ret := make([]*upspin.DirEntry, len(put))
+	for i, entry := range put {
+		ret[i] = &upspin.DirEntry{
+			Attr:     upspin.AttrIncomplete,
+			Sequence: entry.Sequence,
+		}
+	}
+	return ret, nil
+}
+
+// checkPut checks that entry, whose path is p, may be put by the current
+// user. If the path crosses a link, it returns the link and ErrFollowLink.
+// As the entry is about to replace them, it drops any cached copy of an
+// Access or Group file at p.
+func (s *server) checkPut(op errors.Op, p path.Parsed, entry *upspin.DirEntry, o options) (*upspin.DirEntry, error) {
 	isAccess := access.IsAccessFile(p.Path())
 	isGroup := access.IsGroupFile(p.Path())
 	isLink := entry.IsLink()
@@ -365,7 +608,7 @@ func (s *server) Put(entry *upspin.DirEntry) (*upspin.DirEntry, error) {
 	}
 	if isGroupFile {
 		// Validate group files at Put time to detect bad ones early.
-		err = s.loadGroup(p, entry)
+		err := s.loadGroup(p, entry)
 		if err != nil {
 			return nil, errors.E(op, err)
 		}
@@ -455,17 +698,7 @@ func (s *server) Put(entry *upspin.DirEntry) (*upspin.DirEntry, error) {
 			}
 		}
 	}
-
-	entry, err = s.put(op, p, entry, o)
-	if err != nil {
-		return entry, err
-	}
-	// Return Incomplete entry with Sequence number.
-	retEntry := &upspin.DirEntry{
-		Attr:     upspin.AttrIncomplete,
-		Sequence: entry.Sequence,
-	}
-	return retEntry, nil
+	return nil, nil
 }
 
 // put performs Put on the user's tree.
@@ -693,15 +926,16 @@ func (s *server) Watch(name upspin.PathName, sequence int64, done <-chan struct{
 	}
 	events := make(chan upspin.Event, 1)
 
-	go s.watch(op, treeEvents, events)
+	go s.watch(op, treeEvents, events, done)
 
 	return events, nil
 }
 
 // watcher runs in a goroutine reading events from the tree and passing them
 // along to the original caller, but first verifying whether the user has rights
-// to know about the event.
-func (s *server) watch(op errors.Op, treeEvents <-chan *upspin.Event, outEvents chan<- upspin.Event) {
+// to know about the event. It returns once the done channel is closed, so a
+// caller that disconnects does not leave it blocked.
+func (s *server) watch(op errors.Op, treeEvents <-chan *upspin.Event, outEvents chan<- upspin.Event, done <-chan struct{}) {
 	const sendTimeout = time.Minute
 
 	t := time.NewTimer(sendTimeout)
@@ -718,6 +952,9 @@ func (s *server) watch(op errors.Op, treeEvents <-chan *upspin.Event, outEvents
 		case outEvents <- *e:
 			// OK, sent.
 			return true
+		case <-done:
+			// Caller is no longer listening.
+			return false
 		case <-t.C:
 			// Timed out.
 			log.Printf("%s: timeout sending event for %s", op, s.userName)
@@ -850,19 +1087,31 @@ func (s *server) loadTreeFor(userName upspin.UserName, opts ...options) (*tree.T
 			errors.Errorf("userTrees contained value of unexpected type %T", val))
 	}
 	// User is not in the cache. Load a tree from the logs, if they exist.
-	hasLog, err := serverlog.HasLog(userName, s.logDir)
+	logDir := s.logDirFor(userName)
+	hasLog, err := serverlog.HasLog(userName, logDir)
 	if err != nil {
 		return nil, err
 	}
+	if !hasLog {
+		// Make sure we don't start a second log for a user whose
+		// existing log was assigned to a different directory.
+		if err := s.checkNoLogElsewhere(userName, logDir); err != nil {
+			return nil, err
+		}
+	}
 	if !hasLog && !s.canCreateRoot(userName) {
 		// Tree for user does not exist and the logged-in user is not
 		// allowed to create it.
 		return nil, errNotExist
 	}
-	user, err := serverlog.Open(userName, s.logDir, s.serverConfig.Factotum(), s.storage)
+	user, err := serverlog.Open(userName, logDir, s.serverConfig.Factotum(), s.storage)
 	if err != nil {
 		return nil, err
 	}
+	if err := user.SetSync(s.logSync); err != nil {
+		return nil, err
+	}
+	user.SetMaxLogSize(s.maxLogSize)
 	// If user has root, we can load the tree from it.
 	if _, err := user.Root(); err != nil {
 		// Likely the user has no root yet.
@@ -879,7 +1128,14 @@ func (s *server) loadTreeFor(userName upspin.UserName, opts ...options) (*tree.T
 		// Fall through and load a new tree.
 	}
 	// Create a new tree for the user.
-	tree, err := tree.New(s.serverConfig, user)
+	var treeOpts []tree.Option
+	if s.truncateCorruptLogs {
+		treeOpts = append(treeOpts, tree.TruncateCorruptLog())
+	}
+	if s.quotaEntries > 0 || s.quotaBytes > 0 {
+		treeOpts = append(treeOpts, tree.Quota(s.quotaEntries, s.quotaBytes))
+	}
+	tree, err := tree.New(s.serverConfig, user, treeOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +22,9 @@ tree.index.<username> - log checkpoint for username (historically named).
 d.tree.log.<username> - subdirectory for username, containing files named:
 <offset>.<version> - log greater than offset but less than the next offset file.
 The .version part is missing for old-format logs.
+<offset>.<version>.compacted - log written by User.Compact, holding only the
+entries live at the time of compaction. Only later log files follow it;
+earlier offsets are no longer valid.
 
 There may also be a legacy file tree.log.<username> which will be renamed
 (and set to offset 0) if found.
@@ -33,9 +36,21 @@ for historical reasons, to be the varint encoding of signed 0 or 1.
 
 A record looks like this in the logs:
 
-one byte: the Op, 0x00 for a Put, 0x02 for a Delete.
+one byte: 0x01, marking a record with a CRC-32 checksum.
+one byte: the Op, 0x00 for a Put, 0x02 for a Delete, plus 0x04 if the
+record is followed by another of the same transaction.
 N bytes: the result of calling DirEntry.Marshal for the entry.
-4 bytes: a simple checksum calculated by the checksum function.
+4 bytes: the big-endian CRC-32 (Castagnoli) checksum of the preceding bytes.
+
+A transaction is a run of records, each but the last marked with 0x04,
+that are applied together or not at all. Most records stand alone, as a
+transaction of one. A log that ends part way through a transaction was
+cut short by a crash, and the records of the unfinished transaction are
+discarded.
+
+Records written by older servers lack the leading 0x01 byte and end
+instead with a simple XOR checksum calculated by the checksum function.
+Such records can still be read, but are no longer written.
 
 To prevent problems with corrupted logs, a marshaled DirEntry is
 required to fit within 64MB.
@@ -11,6 +11,7 @@ import (
 	"encoding/binary"
 	"flag"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -47,7 +48,11 @@ func main() {
 			if file.version == 0 {
 				le.Entry.Sequence &= version0SeqMask
 			}
-			fmt.Printf("%d: %q: op %s seq %d off %d\n", i, le.Entry.Name, le.Op, le.Entry.Sequence, offset+file.offset)
+			more := ""
+			if le.more {
+				more = " (transaction continues)"
+			}
+			fmt.Printf("%d: %q: op %s seq %d off %d%s\n", i, le.Entry.Name, le.Op, le.Entry.Sequence, offset+file.offset, more)
 			offset += int64(count)
 		}
 	}
@@ -57,6 +62,7 @@ func main() {
 type Entry struct {
 	Op    Operation
 	Entry upspin.DirEntry
+	more  bool
 }
 
 const version0SeqMask = 1<<23 - 1
@@ -99,7 +105,8 @@ func findLogFiles(dir string) []*logFile {
 	for _, file := range files {
 		// Format of name is ..../*tree.log.ann@example.com/oooo.vvvv where o=offset, v=version.
 		// For old files, .vvvv will be missing, and version is 0.
-		elems := strings.Split(filepath.Base(file), ".")
+		// Compacted files have an extra .compacted suffix.
+		elems := strings.Split(strings.TrimSuffix(filepath.Base(file), ".compacted"), ".")
 		var ints []int64
 		fmt.Println(file, elems)
 		for _, elem := range elems {
@@ -144,16 +151,27 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 	if err != nil && err != io.EOF || nRead < 8 { // Sanity check.
 		return 0, errors.E(errors.IO, errors.Errorf("reading op: %s", err))
 	}
-	switch data[0] {
+	// hdr is the length of the record header before the entry size.
+	hdr := 1
+	crc := data[0] == crcRecord
+	if crc {
+		hdr = 2
+	}
+	op := data[hdr-1]
+	le.more = crc && op&moreRecord != 0
+	if le.more {
+		op &^= moreRecord
+	}
+	switch op {
 	case 0x00:
 		le.Op = Put
 	case 0x02:
 		le.Op = Delete
 	default:
-		return 0, errors.E(errors.Invalid, errors.Errorf("unknown Op %d", data[0]))
+		return 0, errors.E(errors.Invalid, errors.Errorf("unknown Op %d", op))
 	}
 
-	size, n := binary.Varint(data[1:])
+	size, n := binary.Varint(data[hdr:])
 	if n <= 0 {
 		return 0, errors.E(errors.IO, errors.Errorf("could not read entry"))
 	}
@@ -166,10 +184,10 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 		return 0, errors.E(errors.IO, errors.Errorf("entry size too large: %d", size))
 	}
 	entrySize := int(size) // Will not overflow.
-	// We need a total of 1 + n + entrySize bytes, plus 4 bytes for the checksum,
+	// We need a total of hdr + n + entrySize bytes, plus 4 bytes for the checksum,
 	// which will give us a header, a marshaled entry, and a checksum.
 	// Do we need to do another read?
-	totalSize := 1 + n + entrySize + 4
+	totalSize := hdr + n + entrySize + 4
 	if totalSize > cap(data) {
 		nData := make([]byte, totalSize)
 		copy(nData, data)
@@ -187,7 +205,7 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 	}
 
 	// Everything's loaded, so unpack it.
-	body := data[1+n : len(data)-4]
+	body := data[hdr+n : len(data)-4]
 	checksumData := data[len(data)-4:]
 	leftOver, err := le.Entry.Unmarshal(body)
 	if err != nil {
@@ -196,7 +214,12 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 	if len(leftOver) != 0 {
 		return 0, errors.E(errors.IO, errors.Errorf("%d bytes left; log misaligned for entry %+v", len(leftOver), le.Entry))
 	}
-	chksum := checksum(data[:len(data)-4]) // Everything but the checksum bytes.
+	var chksum [4]byte
+	if crc {
+		chksum = crcChecksum(data[:len(data)-4]) // Everything but the checksum bytes.
+	} else {
+		chksum = checksum(data[:len(data)-4])
+	}
 	for i, c := range chksum {
 		if c != checksumData[i] {
 			return 0, errors.E(errors.IO, errors.Errorf("invalid checksum: got %x, expected %x for entry %+v", chksum, checksumData, le.Entry))
@@ -205,6 +228,21 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 	return len(data), nil
 }
 
+// crcRecord is the first byte of a record whose checksum is a CRC-32.
+const crcRecord = 0x01
+
+// moreRecord is added to the Op byte of a CRC-32 record that is followed
+// by another record of the same transaction.
+const moreRecord = 0x04
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func crcChecksum(buf []byte) [4]byte {
+	var c [4]byte
+	binary.BigEndian.PutUint32(c[:], crc32.Checksum(buf, crcTable))
+	return c
+}
+
 var checksumSalt = [4]byte{0xde, 0xad, 0xbe, 0xef}
 
 func checksum(buf []byte) [4]byte {
@@ -7,6 +7,7 @@ package serverlog
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -14,6 +15,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"upspin.io/cloud/storage"
 	"upspin.io/errors"
@@ -32,6 +35,11 @@ type User struct {
 	// clones share the lock (see ReadOnlyClone).
 	mu *sync.Mutex
 
+	// tail records how much of the log file being written holds complete
+	// entries, so Readers can read up to that point without taking mu.
+	// It is only stored with mu held. A pointer so clones share it.
+	tail *atomic.Pointer[tailMark]
+
 	writer     *writer
 	root       *root
 	checkpoint *checkpoint
@@ -47,8 +55,57 @@ type User struct {
 	// from version 0 to version 1. If there are no version 0
 	// logs, it will be zero.
 	v1Transition upspin.Time
+
+	// sync is the policy for flushing appended entries to stable storage.
+	sync SyncPolicy
+
+	// maxLogSize, if positive, overrides MaxLogSize for this user.
+	maxLogSize int64
+}
+
+// SyncMode specifies how eagerly appended log entries are flushed
+// to stable storage.
+type SyncMode int
+
+// The supported SyncModes.
+const (
+	// SyncAlways flushes each entry before Append returns. Once Append
+	// succeeds the entry survives a crash of the process or machine.
+	SyncAlways SyncMode = iota
+
+	// SyncInterval flushes entries in batches, once SyncPolicy.Entries
+	// entries are pending or SyncPolicy.Interval has passed since the
+	// first of them was appended, whichever comes first. A crash of the
+	// machine may lose the entries appended since the last flush: fewer
+	// than Entries entries, written within the last Interval.
+	SyncInterval
+
+	// SyncOS never flushes explicitly and relies on the operating system
+	// to write data back in its own time. A crash of the machine may lose
+	// any entries not yet written back, typically those of the last 30
+	// seconds or so on Linux. A crash of the process alone loses nothing.
+	SyncOS
+)
+
+// SyncPolicy configures when a User flushes appended entries to stable
+// storage. The zero value is SyncAlways, the safest and slowest policy.
+// Whatever the policy, SaveOffset and SaveOffsetAndUsage flush pending
+// entries before saving the offset, so a saved offset never lies beyond
+// the end of the log that survives a crash.
+type SyncPolicy struct {
+	Mode SyncMode
+
+	// Interval and Entries bound the batches flushed in SyncInterval mode.
+	// If zero, they default to 100ms and 100 entries.
+	Interval time.Duration
+	Entries  int
 }
 
+const (
+	defaultSyncInterval = 100 * time.Millisecond
+	defaultSyncEntries  = 100
+)
+
 // Operation is the kind of operation performed on the DirEntry.
 type Operation int
 
@@ -58,14 +115,19 @@ const (
 	Delete
 )
 
-// MaxLogSize is the maximum size of a single log file.
-// It can be modified, such as for testing.
+// MaxLogSize is the default maximum size of a single log file.
+// It can be modified, such as for testing, or overridden for a
+// single user by SetMaxLogSize.
 var MaxLogSize int64 = 100 * 1024 * 1024 // 100 MB
 
 // Entry is the unit of logging.
 type Entry struct {
 	Op    Operation
 	Entry upspin.DirEntry
+
+	// more records that the entry is followed in the log by another
+	// of the same transaction; see AppendAll.
+	more bool
 }
 
 // writer is an append-only log of Entry.
@@ -74,13 +136,24 @@ type writer struct {
 
 	fd   *os.File // file descriptor for the log.
 	file *logFile // log this writer is writing to.
+
+	// unsynced counts the entries appended since the last flush.
+	unsynced int
+	// syncTimer, if non-nil, flushes pending entries in SyncInterval mode.
+	syncTimer *time.Timer
 }
 
 // Write implements io.Writer for the our User type.
 // It is the method clients use to append data to the set of log files.
+// Unlike Append, it makes whatever it writes visible to Readers at once,
+// as a crash part way through an Append would.
 // TODO: Used only in a test of corrupted data in ../tree - could be deleted.
 func (u *User) Write(b []byte) (int, error) {
-	return u.writer.fd.Write(b)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	n, err := u.writer.fd.Write(b)
+	u.setTail(u.writer.file.offset + size(u.writer.fd))
+	return n, err
 }
 
 // Reader reads LogEntries from the log.
@@ -226,6 +299,14 @@ func (r *root) readOnlyClone() (*root, error) {
 	}, nil
 }
 
+// A tailMark records the end of the last complete entry in the log file
+// being written. Entries before it never change, except by Truncate.
+// A tailMark is immutable; a new one is stored as the log grows.
+type tailMark struct {
+	file *logFile
+	end  int64 // Offset of the end of the last complete entry.
+}
+
 // offSeq remembers the correspondence between a global offset
 // for a user and the sequence number of the change at that offset.
 type offSeq struct {
@@ -235,10 +316,12 @@ type offSeq struct {
 
 // logFile gathers the information about a log file on disk.
 type logFile struct {
-	name    string // Full path name.
-	index   int    // Position in User.files.
-	version int    // Version number of the format used.
-	offset  int64  // Offset at start of file.
+	name      string      // Full path name.
+	index     int         // Position in User.files.
+	version   int         // Version number of the format used.
+	offset    int64       // Offset at start of file.
+	compacted bool        // Written by Compact; holds only live entries.
+	removed   atomic.Bool // Deleted by Compact or Truncate.
 }
 
 const (
@@ -246,6 +329,8 @@ const (
 	// in their name.
 	version               = 1
 	oldStyleLogFilePrefix = "tree.log."
+	// compactedSuffix marks log files written by Compact.
+	compactedSuffix = ".compacted"
 	// Version 0 logs had 23 low bits of actual sequence; the upper
 	// bits were random. When we read version 0 logs, we clear
 	// the random bits.
@@ -274,6 +359,7 @@ func Open(userName upspin.UserName, directory string, fac upspin.Factotum, store
 		name:      userName,
 		directory: directory,
 		mu:        new(sync.Mutex),
+		tail:      new(atomic.Pointer[tailMark]),
 	}
 	subdir := u.logSubDir()
 
@@ -299,6 +385,9 @@ func Open(userName upspin.UserName, directory string, fac upspin.Factotum, store
 	}
 
 	u.findLogFiles(subdir)
+	if err := u.removeCompactedHistory(); err != nil {
+		return nil, err
+	}
 	u.populateOffSeqs()
 	u.setV1Transition()
 
@@ -312,9 +401,10 @@ func Open(userName upspin.UserName, directory string, fac upspin.Factotum, store
 	case len(u.files) == 0:
 		// No files for this user yet.
 		_, fd, err = u.createLogFile(0)
-	case u.files[last].version != version:
+	case u.files[last].version != version || u.files[last].compacted:
 		// Must create new file with current version.
-		// We can only write to files with the latest version.
+		// We can only write to files with the latest version,
+		// and never to a compacted file.
 		file := u.files[last]
 		var size int64
 		size, err = sizeOfFile(file.name)
@@ -349,6 +439,7 @@ func Open(userName upspin.UserName, directory string, fac upspin.Factotum, store
 		file: u.files[len(u.files)-1],
 	}
 	u.writer = w
+	u.setTail(w.file.offset + size(fd))
 
 	return u, nil
 }
@@ -421,6 +512,7 @@ func (u *User) DeleteLogs() error {
 	for _, fn := range []string{
 		filepath.Join(u.directory, oldStyleLogFilePrefix+string(u.name)),
 		u.checkpointFile(),
+		u.compactTempFile(),
 	} {
 		err := os.Remove(fn)
 		if err != nil && !os.IsNotExist(err) {
@@ -463,11 +555,39 @@ func ListUsersWithSuffix(suffix, directory string) ([]upspin.UserName, error) {
 	return userGlob("*+"+suffix+"@*", directory)
 }
 
+// SetSync sets the policy for flushing appended entries to stable storage.
+// It flushes any pending entries before returning.
+func (u *User) SetSync(p SyncPolicy) error {
+	if p.Interval <= 0 {
+		p.Interval = defaultSyncInterval
+	}
+	if p.Entries <= 0 {
+		p.Entries = defaultSyncEntries
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.sync = p
+	return u.writer.flush()
+}
+
+// SetMaxLogSize sets the size a log file may reach before Append starts
+// a new one. Entries are never split between files, so a file may exceed
+// the limit by up to the size of one entry. If n is not positive, the
+// limit reverts to MaxLogSize.
+func (u *User) SetMaxLogSize(n int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.maxLogSize = n
+}
+
 func (u *User) Close() error {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 
-	err1 := u.writer.close()
+	err1 := u.writer.flush()
+	if err := u.writer.close(); err1 == nil {
+		err1 = err
+	}
 	err2 := u.checkpoint.close()
 	err3 := u.root.close()
 	if err1 != nil {
@@ -499,6 +619,7 @@ const (
 	rootFilePrefix = "tree.root."
 	// For historical reasons, the checkpoint file name is "index".
 	checkpointFilePrefix = "tree.index."
+	compactFilePrefix    = "tree.compact."
 )
 
 func (u *User) checkpointFile() string {
@@ -509,6 +630,12 @@ func (u *User) rootFile() string {
 	return filepath.Join(u.directory, rootFilePrefix+string(u.name))
 }
 
+// compactTempFile is where Compact writes the new log before moving it
+// into the log subdirectory.
+func (u *User) compactTempFile() string {
+	return filepath.Join(u.directory, compactFilePrefix+string(u.name))
+}
+
 // findLogFiles populates u.files with the log files available for this user.
 // They are stored in increasing offset order.
 func (u *User) findLogFiles(dir string) {
@@ -520,7 +647,10 @@ func (u *User) findLogFiles(dir string) {
 	for _, file := range files {
 		// Format of name is ..../*tree.log.ann@example.com/oooo.vvvv where o=offset, v=version.
 		// For old files, .vvvv will be missing, and version is 0.
-		elems := strings.Split(filepath.Base(file), ".")
+		// Compacted files have an extra .compacted suffix.
+		base := filepath.Base(file)
+		compacted := strings.HasSuffix(base, compactedSuffix)
+		elems := strings.Split(strings.TrimSuffix(base, compactedSuffix), ".")
 		var ints []int64
 		for _, elem := range elems {
 			x, err := strconv.ParseInt(elem, 10, 64)
@@ -531,8 +661,9 @@ func (u *User) findLogFiles(dir string) {
 			ints = append(ints, x)
 		}
 		lf := &logFile{
-			name:  file,
-			index: len(u.files),
+			name:      file,
+			index:     len(u.files),
+			compacted: compacted,
 		}
 		switch len(ints) {
 		case 2:
@@ -547,13 +678,44 @@ func (u *User) findLogFiles(dir string) {
 		u.files = append(u.files, lf)
 	}
 	sort.Slice(u.files, func(i, j int) bool { return u.files[i].offset < u.files[j].offset })
+	for i, file := range u.files {
+		file.index = i
+	}
+}
 
+// removeCompactedHistory deletes any log files that precede the most recent
+// compacted log file. They remain only if a Compact was interrupted.
+func (u *User) removeCompactedHistory() error {
+	last := -1
+	for i, file := range u.files {
+		if file.compacted {
+			last = i
+		}
+	}
+	if last <= 0 {
+		return nil
+	}
+	for _, file := range u.files[:last] {
+		if err := os.Remove(file.name); err != nil && !os.IsNotExist(err) {
+			return errors.E(errors.IO, err)
+		}
+	}
+	u.files = append([]*logFile{}, u.files[last:]...)
+	for i, file := range u.files {
+		file.index = i
+	}
+	return nil
 }
 
 // populateOffSeqs reads the entries in the logs and builds User.offSeqs.
+// Entries in a compacted log are not recorded, as their order is not
+// that of their sequence numbers; see Compact.
 func (u *User) populateOffSeqs() {
 	data := make([]byte, 4096)
 	for _, file := range u.files {
+		if file.compacted {
+			continue
+		}
 		fd, err := os.Open(file.name)
 		if err != nil {
 			log.Error.Printf("dir/server/serverlog.populateOffSeqs: user %s: %v", u.name, err)
@@ -643,15 +805,16 @@ func (u *User) createLogFile(offset int64) (*logFile, *os.File, error) {
 	return lf, fd, err
 }
 
-// isWriter reports whether the index is that of the most recent log file.
-// It's used to permit Reader to check whether it will interfere with writer.
-func (u *User) isWriter(file *logFile) bool {
-	u.mu.Lock()
-	defer u.mu.Unlock()
-	return file == u.writer.file
+// setTail records that the log file being written holds complete
+// entries up to offset end.
+// u.mu must be held.
+func (u *User) setTail(end int64) {
+	u.tail.Store(&tailMark{file: u.writer.file, end: end})
 }
 
 // whichLogFile returns the log file to use to read this offset.
+// Offsets before the start of the first file map to the first file;
+// callers should check with firstOffset.
 // u.mu must be held.
 func (u *User) whichLogFile(offset int64) *logFile {
 	for i := 1; i < len(u.files); i++ {
@@ -662,19 +825,28 @@ func (u *User) whichLogFile(offset int64) *logFile {
 	return u.files[len(u.files)-1]
 }
 
+// firstOffset returns the offset of the start of the logs, which is
+// zero unless the logs have been compacted.
+// u.mu must be held.
+func (u *User) firstOffset() int64 {
+	return u.files[0].offset
+}
+
 // OffsetOf returns the global offset in the user's logs for this sequence number.
-// It returns -1 if the sequence number does not appear in the logs.
+// It returns -1 if the sequence number does not appear in the logs, which
+// is the case for all sequence numbers written before the logs were
+// compacted.
 // ReadAt will return an error if asked to read at a negative offset.
 func (u *User) OffsetOf(seq int64) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
 	if seq == 0 {
 		// Start of file. There may be no data yet.
 		// TODO: How does this arise? (It does, but it shouldn't.)
-		return 0
+		return u.firstOffset()
 	}
 
-	u.mu.Lock()
-	defer u.mu.Unlock()
-
 	i := sort.Search(len(u.offSeqs), func(i int) bool { return u.offSeqs[i].sequence >= seq })
 	if i < len(u.offSeqs) && u.offSeqs[i].sequence == seq {
 		return u.offSeqs[i].offset
@@ -684,10 +856,31 @@ func (u *User) OffsetOf(seq int64) int64 {
 
 // Append appends a Entry to the end of the writer log.
 func (u *User) Append(e *Entry) error {
-	buf, err := e.marshal()
-	if err != nil {
-		return err
+	return u.AppendAll([]*Entry{e})
+}
+
+// AppendAll appends the entries to the end of the writer log as a single
+// transaction. The entries are written and flushed together, and become
+// visible to Readers together. If a crash leaves only some of them in
+// the log, Verify reports the transaction as incomplete, so that it is
+// discarded in its entirety by the Truncate that follows.
+func (u *User) AppendAll(entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
 	}
+	var buf []byte
+	ends := make([]int, len(entries)) // The end of each entry in buf.
+	for i, e := range entries {
+		rec := *e
+		rec.more = i < len(entries)-1
+		b, err := rec.marshal()
+		if err != nil {
+			return err
+		}
+		buf = append(buf, b...)
+		ends[i] = len(buf)
+	}
+	var err error
 
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -698,8 +891,16 @@ func (u *User) Append(e *Entry) error {
 	offset := w.file.offset + prevSize
 
 	// Is it time to move to a new log file?
-	if prevSize >= MaxLogSize {
-		// Close the current underlying log file.
+	maxSize := MaxLogSize
+	if u.maxLogSize > 0 {
+		maxSize = u.maxLogSize
+	}
+	if prevSize >= maxSize {
+		// Flush and close the current underlying log file.
+		err = w.flush()
+		if err != nil {
+			return errors.E(errors.IO, err)
+		}
 		err = w.close()
 		if err != nil {
 			return errors.E(errors.IO, err)
@@ -719,19 +920,168 @@ func (u *User) Append(e *Entry) error {
 	if err != nil {
 		return errors.E(errors.IO, err)
 	}
-	err = w.fd.Sync()
+	w.unsynced += len(entries)
+	switch u.sync.Mode {
+	case SyncAlways:
+		err = w.flush()
+	case SyncInterval:
+		if w.unsynced >= u.sync.Entries {
+			err = w.flush()
+		} else if w.syncTimer == nil {
+			w.syncTimer = time.AfterFunc(u.sync.Interval, u.flushPending)
+		}
+	}
 	if err != nil {
 		return errors.E(errors.IO, err)
 	}
-	// Sanity check: flush worked and the new offset relative to the
+	// Sanity check: the new offset relative to the
 	// beginning of this file is the expected one.
 	newOffs := prevSize + int64(n)
 	if newOffs != size(w.fd) {
 		// This might indicate a race somewhere, despite the locks.
-		return errors.E(errors.IO, errors.Errorf("file.Sync did not update offset: expected %d, got %d", newOffs, size(w.fd)))
+		return errors.E(errors.IO, errors.Errorf("write did not update offset: expected %d, got %d", newOffs, size(w.fd)))
+	}
+
+	start := 0
+	for i, e := range entries {
+		u.addOffSeq(offset+int64(start), e.Entry.Sequence)
+		start = ends[i]
+	}
+	u.setTail(offset + int64(n))
+	return nil
+}
+
+// Compact replaces the user's logs with a compacted log holding just the
+// given entries, followed by an empty log for new entries. The entries
+// should describe the live state of the tree, each directory preceding its
+// contents, so the tree can be rebuilt from them. The tree must be
+// quiescent and flushed, so that the checkpoint records that every entry
+// in the logs has been processed.
+//
+// The compacted log continues at the offset where the old logs ended.
+// Earlier offsets are no longer valid: ReadAt fails for them, even in a
+// Reader opened before the compaction, and OffsetOf returns -1 for any
+// sequence number written before the compaction, so watchers must start
+// again. The compacted entries keep their sequence numbers.
+//
+// If Compact is interrupted after the compacted log is in place, the next
+// Open discards the old logs and the tree is rebuilt from the compacted one.
+func (u *User) Compact(entries []*Entry) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	w := u.writer
+	if w == nil {
+		return errors.E(errors.Invalid, u.name, "cannot compact a read-only log")
+	}
+	start := w.file.offset + size(w.fd)
+	processed, usage, err := u.checkpoint.read()
+	if err != nil {
+		return err
+	}
+	if processed != start {
+		return errors.E(errors.Invalid, u.name, errors.Errorf("cannot compact log with unprocessed entries: checkpoint at %d, log ends at %d", processed, start))
+	}
+	if start == u.firstOffset() {
+		// The logs are empty.
+		return nil
+	}
+
+	// Write the new log to a temporary file and sync it
+	// before moving it into place.
+	tmp := u.compactTempFile()
+	fd, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.E(errors.IO, err)
+	}
+	n, err := writeEntries(fd, entries)
+	if err == nil {
+		err = syncFile(fd)
+	}
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return errors.E(errors.IO, err)
+	}
+	var compacted *logFile
+	if n > 0 {
+		compacted = &logFile{
+			name:      u.logFileName(start, version) + compactedSuffix,
+			version:   version,
+			offset:    start,
+			compacted: true,
+		}
+		if err := os.Rename(tmp, compacted.name); err != nil {
+			return errors.E(errors.IO, err)
+		}
+	} else {
+		os.Remove(tmp)
+	}
+
+	end := start + n
+	if err := u.replaceLogs(compacted, end); err != nil {
+		return err
+	}
+	// Compaction does not change the tree, so its usage is kept.
+	return u.checkpoint.write(end, usage)
+}
+
+// writeEntries writes the marshaled entries to f and returns the number
+// of bytes written.
+func writeEntries(f *os.File, entries []*Entry) (int64, error) {
+	var n int64
+	for _, e := range entries {
+		buf, err := e.marshal()
+		if err != nil {
+			return n, err
+		}
+		nw, err := f.Write(buf)
+		n += int64(nw)
+		if err != nil {
+			return n, err
+		}
 	}
+	return n, nil
+}
 
-	u.addOffSeq(offset, e.Entry.Sequence)
+// replaceLogs removes all the user's log files except keep, which may be
+// nil, and starts a new log file for writing at offset. Readers of the
+// removed files will reopen the logs on their next ReadAt.
+// u.mu must be held.
+func (u *User) replaceLogs(keep *logFile, offset int64) error {
+	w := u.writer
+	if err := w.flush(); err != nil {
+		return errors.E(errors.IO, err)
+	}
+	if err := w.close(); err != nil {
+		return errors.E(errors.IO, err)
+	}
+	old := u.files
+	u.files = nil
+	if keep != nil {
+		keep.index = 0
+		u.files = append(u.files, keep)
+	}
+	file, fd, err := u.createLogFile(offset)
+	if err != nil {
+		return errors.E(errors.IO, err)
+	}
+	w.file = file
+	w.fd = fd
+	u.setTail(offset)
+	u.offSeqs = nil
+	for _, f := range old {
+		f.removed.Store(true)
+		if f.name == file.name {
+			// An empty log file at the same offset; still in use.
+			continue
+		}
+		if err := os.Remove(f.name); err != nil && !os.IsNotExist(err) {
+			return errors.E(errors.IO, err)
+		}
+	}
 	return nil
 }
 
@@ -758,34 +1108,34 @@ func (u *User) addOffSeq(offset, sequence int64) {
 
 // ReadAt reads an entry from the log at offset. It returns the log entry and
 // the next offset. If offset is negative, which may correspond to an invalid
-// sequence number processed by OffsetOf, it returns an error.
+// sequence number processed by OffsetOf, or precedes the start of a compacted
+// log, it returns an error.
+//
+// ReadAt does not contend with Append: it reads the log file being written
+// only up to the end of the last entry appended in full, and so never sees
+// partially-written data. It should not be called concurrently with a
+// Truncate that discards the entry being read.
 func (r *Reader) ReadAt(offset int64) (le Entry, next int64, err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// The maximum offset we can satisfy with the current log file.
-	maxOff := r.file.offset + size(r.fd)
+	maxOff := r.endOffset()
 
-	// Is the requested offset outside the bounds of the current log file?
+	// Is the requested offset outside the bounds of the current log file,
+	// or has the file been removed by Compact since it was opened?
 	before := offset < r.file.offset
 	after := offset >= maxOff
-	if before || after {
+	if before || after || r.file.removed.Load() {
 		// Locate the file and open it.
 		r.user.mu.Lock()
 		err := r.openLogForOffset(offset)
 		r.user.mu.Unlock()
 		if err != nil {
-			return le, 0, errors.E(errors.IO, err)
+			return le, 0, err
 		}
 		// Recompute maxOff for the new file.
-		maxOff = r.file.offset + size(r.fd)
-	}
-
-	// If we're reading from the log file being written, then we
-	// must lock r.user.mu to avoid reading partially-written data.
-	if r.user.isWriter(r.file) {
-		r.user.mu.Lock()
-		defer r.user.mu.Unlock()
+		maxOff = r.endOffset()
 	}
 
 	// Are we past the end of the current file?
@@ -805,6 +1155,53 @@ func (r *Reader) ReadAt(offset int64) (le Entry, next int64, err error) {
 	return
 }
 
+// incompleteError reports a log entry cut short by the end of its file,
+// as left behind by a crash part way through a write.
+type incompleteError string
+
+func (e incompleteError) Error() string { return string(e) }
+
+// Verify reads the log from offset to its end, validating the structure and
+// checksum of each entry. If all the entries are valid, it returns the offset
+// of the end of the log. Otherwise it returns the offset of the first invalid
+// entry, whether that entry is incomplete, as happens when a crash interrupts
+// a write, and an error that reports the offset and the problem. The entries
+// before the returned offset are intact.
+//
+// An invalid entry invalidates the earlier entries of its transaction, so
+// if it is part of one the offset returned is that of the transaction's
+// first entry. A transaction that is not finished by the end of the log is
+// reported as incomplete.
+func (r *Reader) Verify(offset int64) (bad int64, incomplete bool, err error) {
+	txStart := int64(-1) // Offset of the first entry of an unfinished transaction.
+	for {
+		le, next, err := r.ReadAt(offset)
+		if err != nil {
+			if e, ok := err.(*errors.Error); ok {
+				_, incomplete = e.Err.(incompleteError)
+			}
+			bad := offset
+			if txStart >= 0 {
+				bad = txStart
+			}
+			return bad, incomplete, errors.E(errors.IO, r.user.name, errors.Errorf("corrupt log entry at offset %d: %v", offset, err))
+		}
+		if next == offset {
+			if txStart >= 0 {
+				return txStart, true, errors.E(errors.IO, r.user.name, errors.Errorf("corrupt log entry at offset %d: unfinished transaction", txStart))
+			}
+			return offset, false, nil
+		}
+		switch {
+		case !le.more:
+			txStart = -1
+		case txStart < 0:
+			txStart = offset
+		}
+		offset = next
+	}
+}
+
 // AppendOffset returns the offset of the end of the written log file or -1 on error.
 func (u *User) AppendOffset() int64 {
 	u.mu.Lock()
@@ -818,14 +1215,17 @@ func (u *User) AppendOffset() int64 {
 func (r *Reader) EndOffset() int64 {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.endOffset()
+}
 
-	// If we're reading from the same file as the current writer, lock it.
-	// Order is important.
-	if r.file.offset == r.user.files[len(r.user.files)-1].offset {
-		r.user.mu.Lock()
-		defer r.user.mu.Unlock()
+// endOffset returns the offset of the end of the reader's current file or
+// -1 on error. If it is the file being written, that is the end of the last
+// complete entry.
+// r.mu must be held.
+func (r *Reader) endOffset() int64 {
+	if tail := r.user.tail.Load(); tail != nil && tail.file == r.file {
+		return tail.end
 	}
-
 	return r.file.offset + size(r.fd)
 }
 
@@ -846,13 +1246,23 @@ func sizeOfFile(name string) (int64, error) {
 }
 
 // Truncate truncates the write log at offset.
+// If the logs have been compacted, the only offset before their start
+// that may be given is zero, which discards all the logs.
 func (u *User) Truncate(offset int64) error {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 
+	if first := u.firstOffset(); offset < first {
+		if offset != 0 {
+			return errors.E(errors.Invalid, errors.Errorf("cannot truncate log at offset %d before its start at %d", offset, first))
+		}
+		return u.replaceLogs(nil, 0)
+	}
+
 	// Delete any files after the one holding offset.
 	file := u.whichLogFile(offset)
 	for i := file.index + 1; i < len(u.files); i++ {
+		u.files[i].removed.Store(true)
 		err := os.Remove(u.files[i].name)
 		if err != nil {
 			return errors.E(errors.IO, err)
@@ -874,8 +1284,14 @@ func (u *User) Truncate(offset int64) error {
 		w.fd = fd
 	}
 
-	// Truncate the active file.
+	// Truncate the active file, first moving the tail back
+	// so readers stop reading the discarded entries.
 	pos := offset - w.file.offset
+	if end := w.file.offset + size(w.fd); offset < end {
+		u.setTail(offset)
+	} else {
+		u.setTail(end)
+	}
 	if pos < size(w.fd) {
 		err := w.fd.Truncate(pos)
 		if err != nil {
@@ -917,22 +1333,25 @@ func (u *User) NewReader() (*Reader, error) {
 	}
 	err := r.openLogForOffset(w.file.offset)
 	if err != nil {
-		return nil, errors.E(errors.IO, err)
+		return nil, err
 	}
 	return r, nil
 }
 
 // openLogForOffset opens the log file that holds the offset.
-// r.mu must be held.
+// r.mu and r.user.mu must be held.
 func (r *Reader) openLogForOffset(offset int64) error {
+	if first := r.user.firstOffset(); offset < first {
+		return errors.E(errors.Invalid, errors.Errorf("offset %d precedes start of compacted log at %d", offset, first))
+	}
 	logFile := r.user.whichLogFile(offset)
 	// Re-opening the same offset?
-	if r.fd != nil && r.fd.Name() == logFile.name {
+	if r.fd != nil && r.file == logFile {
 		return nil
 	}
 	f, err := os.Open(logFile.name)
 	if err != nil {
-		return err
+		return errors.E(errors.IO, err)
 	}
 	if r.fd != nil {
 		r.fd.Close()
@@ -942,6 +1361,39 @@ func (r *Reader) openLogForOffset(offset int64) error {
 	return nil
 }
 
+// flushPending flushes any entries pending in SyncInterval mode.
+// It is called by the writer's syncTimer.
+func (u *User) flushPending() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.writer.flush(); err != nil {
+		log.Error.Printf("dir/server/serverlog: flushing log for %s: %v", u.name, err)
+	}
+}
+
+// flush flushes any pending entries to stable storage. user.mu must be held.
+func (w *writer) flush() error {
+	if w == nil || w.fd == nil {
+		return nil
+	}
+	if w.syncTimer != nil {
+		w.syncTimer.Stop()
+		w.syncTimer = nil
+	}
+	if w.unsynced == 0 {
+		return nil
+	}
+	if err := syncFile(w.fd); err != nil {
+		return err
+	}
+	w.unsynced = 0
+	return nil
+}
+
+// syncFile flushes the file to stable storage.
+// It is replaced in tests to simulate crashes.
+var syncFile = (*os.File).Sync
+
 // close closes the writer. user.mu must be held.
 func (w *writer) close() error {
 	if w == nil || w.fd == nil {
@@ -1107,37 +1559,106 @@ func (u *User) ReadOffset() (int64, error) {
 func (cp *checkpoint) readOffset() (int64, error) {
 	cp.user.mu.Lock()
 	defer cp.user.mu.Unlock()
+	return cp.offset()
+}
+
+// offset is readOffset without the locking.
+// user.mu must be held.
+func (cp *checkpoint) offset() (int64, error) {
+	offset, _, err := cp.read()
+	return offset, err
+}
+
+// Usage records how much a user's tree references: the number of entries
+// in the tree, not counting the root, and the total size of the blocks of
+// the files among them.
+type Usage struct {
+	Entries int64
+	Bytes   int64
+}
 
+// ReadUsage reads from stable storage the usage saved by SaveOffsetAndUsage.
+// It returns nil if no usage was saved with the current offset, as is the
+// case if the offset was saved by SaveOffset or by an older server.
+func (u *User) ReadUsage() (*Usage, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	_, usage, err := u.checkpoint.read()
+	return usage, err
+}
+
+// read reads the offset and usage saved in the checkpoint. The offset is
+// stored as a varint and is optionally followed by the usage, stored as two
+// unsigned varints.
+// user.mu must be held.
+func (cp *checkpoint) read() (int64, *Usage, error) {
 	buf, err := readAllFromTop(cp.checkpointFile)
 	if err != nil {
-		return 0, errors.E(errors.IO, err)
+		return 0, nil, errors.E(errors.IO, err)
 	}
 	if len(buf) == 0 {
-		return 0, errors.E(errors.NotExist, cp.user.Name(), "no log offset for user")
+		return 0, nil, errors.E(errors.NotExist, cp.user.Name(), "no log offset for user")
 	}
 	offset, n := binary.Varint(buf)
 	if n <= 0 {
-		return 0, errors.E(errors.IO, "invalid offset read")
+		return 0, nil, errors.E(errors.IO, "invalid offset read")
+	}
+	buf = buf[n:]
+	if len(buf) == 0 {
+		return offset, nil, nil
 	}
-	return offset, nil
+	entries, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return 0, nil, errors.E(errors.IO, "invalid usage read")
+	}
+	bytes, n := binary.Uvarint(buf[n:])
+	if n <= 0 {
+		return 0, nil, errors.E(errors.IO, "invalid usage read")
+	}
+	return offset, &Usage{Entries: int64(entries), Bytes: int64(bytes)}, nil
 }
 
-// SaveOffset saves to stable storage the offset to process next.
+// SaveOffset saves to stable storage the offset to process next,
+// first flushing any pending entries, as does SaveOffsetAndUsage.
+// Any usage saved with the previous offset is discarded.
 func (u *User) SaveOffset(offset int64) error {
-	return u.checkpoint.saveOffset(offset)
+	return u.checkpoint.saveOffset(offset, nil)
 }
 
-// saveOffset saves to stable storage the offset to process next.
-func (cp *checkpoint) saveOffset(offset int64) error {
+// SaveOffsetAndUsage atomically saves to stable storage the offset to
+// process next and the usage of the tree after processing the entries
+// before that offset. If usage is nil it is the same as SaveOffset.
+func (u *User) SaveOffsetAndUsage(offset int64, usage *Usage) error {
+	return u.checkpoint.saveOffset(offset, usage)
+}
+
+// saveOffset saves to stable storage the offset to process next
+// and the usage, if not nil.
+func (cp *checkpoint) saveOffset(offset int64, usage *Usage) error {
 	if offset < 0 {
 		return errors.E(errors.Invalid, "negative offset")
 	}
-	var tmp [16]byte // For use by PutVarint.
-	n := binary.PutVarint(tmp[:], offset)
-
+	if usage != nil && (usage.Entries < 0 || usage.Bytes < 0) {
+		return errors.E(errors.Invalid, "negative usage")
+	}
 	cp.user.mu.Lock()
 	defer cp.user.mu.Unlock()
+	// The entries before offset must be durable before the offset is.
+	if err := cp.user.writer.flush(); err != nil {
+		return errors.E(errors.IO, err)
+	}
+	return cp.write(offset, usage)
+}
 
+// write is saveOffset without the locking or checking.
+// user.mu must be held.
+func (cp *checkpoint) write(offset int64, usage *Usage) error {
+	var tmp [3 * binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], offset)
+	if usage != nil {
+		n += binary.PutUvarint(tmp[n:], uint64(usage.Entries))
+		n += binary.PutUvarint(tmp[n:], uint64(usage.Bytes))
+	}
 	return overwriteAndSync(cp.checkpointFile, tmp[:n])
 }
 
@@ -1151,9 +1672,20 @@ func (cp *checkpoint) close() error {
 	return nil
 }
 
+// crcRecord is the first byte of a record whose checksum is a CRC-32.
+// Older records begin directly with the Op byte, which is always even,
+// and end with the XOR checksum calculated by the checksum function.
+const crcRecord = 0x01
+
+// moreRecord is added to the Op byte of a CRC-32 record that is followed
+// by another record of the same transaction.
+const moreRecord = 0x04
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
 // marshal packs the Entry into a new byte slice for storage.
 func (le *Entry) marshal() ([]byte, error) {
-	var b []byte
+	b := []byte{crcRecord}
 	// For historical reasons, the entry was written with binary.PutVarint,
 	// but that adds unnecessary overhead.
 	switch le.Op {
@@ -1164,19 +1696,32 @@ func (le *Entry) marshal() ([]byte, error) {
 	default:
 		panic("bad Op in marshal")
 	}
+	if le.more {
+		b[1] |= moreRecord
+	}
 
 	entry, err := le.Entry.Marshal()
 	if err != nil {
 		return nil, err
 	}
 	b = appendBytes(b, entry)
-	chksum := checksum(b)
+	chksum := crcChecksum(b)
 	b = append(b, chksum[:]...)
 	return b, nil
 }
 
+// crcChecksum returns the big-endian CRC-32 (Castagnoli) checksum of buf.
+func crcChecksum(buf []byte) [4]byte {
+	var c [4]byte
+	binary.BigEndian.PutUint32(c[:], crc32.Checksum(buf, crcTable))
+	return c
+}
+
 var checksumSalt = [4]byte{0xde, 0xad, 0xbe, 0xef}
 
+// checksum returns the XOR checksum used by records written before
+// crcRecord was introduced. It misses many simple corruptions, such as
+// the transposition of bytes four apart, so it is used only for reading.
 func checksum(buf []byte) [4]byte {
 	var c [4]byte
 	copy(c[:], checksumSalt[:])
@@ -1205,19 +1750,33 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 	// At least from the test, which uses bytes.Reader, we could get err==io.EOF
 	// but still have some data.
 	nRead, err := fd.ReadAt(data, offset)
-	if err != nil && err != io.EOF || nRead < 8 { // Sanity check.
+	if err != nil && err != io.EOF { // Sanity check.
 		return 0, errors.E(errors.IO, errors.Errorf("reading op: %s", err))
 	}
-	switch data[0] {
+	if nRead < 8 {
+		return 0, errors.E(errors.IO, incompleteError(fmt.Sprintf("reading op: got only %d bytes", nRead)))
+	}
+	// hdr is the length of the record header before the entry size.
+	hdr := 1
+	crc := data[0] == crcRecord
+	if crc {
+		hdr = 2
+	}
+	op := data[hdr-1]
+	le.more = crc && op&moreRecord != 0
+	if le.more {
+		op &^= moreRecord
+	}
+	switch op {
 	case 0x00:
 		le.Op = Put
 	case 0x02:
 		le.Op = Delete
 	default:
-		return 0, errors.E(errors.Invalid, errors.Errorf("unknown Op %d", data[0]))
+		return 0, errors.E(errors.Invalid, errors.Errorf("unknown Op %d", op))
 	}
 
-	size, n := binary.Varint(data[1:])
+	size, n := binary.Varint(data[hdr:])
 	if n <= 0 {
 		return 0, errors.E(errors.IO, errors.Errorf("could not read entry"))
 	}
@@ -1230,10 +1789,10 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 		return 0, errors.E(errors.IO, errors.Errorf("entry size too large: %d", size))
 	}
 	entrySize := int(size) // Will not overflow.
-	// We need a total of 1 + n + entrySize bytes, plus 4 bytes for the checksum,
+	// We need a total of hdr + n + entrySize bytes, plus 4 bytes for the checksum,
 	// which will give us a header, a marshaled entry, and a checksum.
 	// Do we need to do another read?
-	totalSize := 1 + n + entrySize + 4
+	totalSize := hdr + n + entrySize + 4
 	if totalSize > cap(data) {
 		nData := make([]byte, totalSize)
 		copy(nData, data)
@@ -1246,12 +1805,12 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 			return 0, errors.E(errors.IO, errors.Errorf("reading %d bytes from entry: got %d: %s", totalSize-nRead, n, err))
 		}
 		if n != totalSize-nRead {
-			return 0, errors.E(errors.IO, errors.Errorf("incomplete read getting %d bytes from entry: got %d", totalSize-nRead, n))
+			return 0, errors.E(errors.IO, incompleteError(fmt.Sprintf("incomplete read getting %d bytes from entry: got %d", totalSize-nRead, n)))
 		}
 	}
 
 	// Everything's loaded, so unpack it.
-	body := data[1+n : len(data)-4]
+	body := data[hdr+n : len(data)-4]
 	checksumData := data[len(data)-4:]
 	leftOver, err := le.Entry.Unmarshal(body)
 	if err != nil {
@@ -1260,10 +1819,15 @@ func (le *Entry) unmarshal(fd io.ReaderAt, data []byte, offset int64) (int, erro
 	if len(leftOver) != 0 {
 		return 0, errors.E(errors.IO, errors.Errorf("%d bytes left; log misaligned for entry %+v", len(leftOver), le.Entry))
 	}
-	chksum := checksum(data[:len(data)-4]) // Everything but the checksum bytes.
+	var chksum [4]byte
+	if crc {
+		chksum = crcChecksum(data[:len(data)-4]) // Everything but the checksum bytes.
+	} else {
+		chksum = checksum(data[:len(data)-4])
+	}
 	for i, c := range chksum {
 		if c != checksumData[i] {
-			return 0, errors.E(errors.IO, errors.Errorf("invalid checksum: got %x, expected %x for entry %+v", chksum, checksumData, le.Entry))
+			return 0, errors.E(errors.IO, errors.Errorf("invalid checksum: got %x, expected %x for entry %q", chksum, checksumData, le.Entry.Name))
 		}
 	}
 	return len(data), nil
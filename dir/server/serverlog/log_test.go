@@ -12,7 +12,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"upspin.io/cloud/storage"
 	"upspin.io/cloud/storage/storagetest"
@@ -109,7 +112,7 @@ func TestCountReadAtMallocs(t *testing.T) {
 		}
 	}
 	mallocs := testing.AllocsPerRun(100, fn)
-	if got, want := mallocs, 3.0; got != want {
+	if got, want := mallocs, 2.0; got != want {
 		t.Errorf("got %v allocs, want <=%v", got, want)
 	}
 }
@@ -472,7 +475,7 @@ func TestRotateLogAndTruncate(t *testing.T) {
 	}
 	// Verify we have logs of roughly at the expected offsets.
 	offsets := logOffsets(user)
-	expectedOffs := []int64{0, 116, 232, 348, 464}
+	expectedOffs := []int64{0, 118, 236, 354, 472}
 	if got, want := len(offsets), len(expectedOffs); got != want {
 		t.Fatalf("Expected %d offsets, got %d", want, got)
 	}
@@ -486,7 +489,7 @@ func TestRotateLogAndTruncate(t *testing.T) {
 		t.Fatal(err)
 	}
 	offsets = logOffsets(user)
-	expectedOffs = []int64{0, 116, 232}
+	expectedOffs = []int64{0, 118, 236}
 	if got, want := len(offsets), len(expectedOffs); got != want {
 		t.Fatalf("Expected %d offsets, got %d", want, got)
 	}
@@ -499,12 +502,12 @@ func TestRotateLogAndTruncate(t *testing.T) {
 		t.Fatal(err)
 	}
 	// Read at a valid offset and verify there's a next record.
-	_, next, err := r.ReadAt(232)
+	_, next, err := r.ReadAt(236)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if next <= 232 {
-		t.Fatalf("Expected next > 232, got %d", next)
+	if next <= 236 {
+		t.Fatalf("Expected next > 236, got %d", next)
 	}
 }
 
@@ -594,6 +597,30 @@ func TestIndex(t *testing.T) {
 	if recoveredOffset != offset {
 		t.Errorf("recoveredOffset = %d, want = %d", recoveredOffset, offset)
 	}
+	if usage, err := user.ReadUsage(); err != nil || usage != nil {
+		t.Errorf("ReadUsage = %v, %v, want nil, nil", usage, err)
+	}
+
+	// Save and read offset and usage.
+	want := Usage{Entries: 17, Bytes: 1 << 40}
+	err = user.SaveOffsetAndUsage(offset, &want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveredOffset, err = user.ReadOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recoveredOffset != offset {
+		t.Errorf("recoveredOffset = %d, want = %d", recoveredOffset, offset)
+	}
+	usage, err := user.ReadUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage == nil || *usage != want {
+		t.Errorf("usage = %v, want = %v", usage, want)
+	}
 
 	// Clone the log index and ensure it's read-only.
 	clone, err := user.checkpoint.readOnlyClone()
@@ -608,7 +635,7 @@ func TestIndex(t *testing.T) {
 		t.Errorf("LastOffset = %d, want = %d", got, want)
 	}
 	// Now write something and get an error.
-	err = clone.saveOffset(999999)
+	err = clone.saveOffset(999999, nil)
 	expectedErr = errors.E(errors.IO)
 	if !errors.Match(expectedErr, err) {
 		t.Errorf("err = %v, want = %v", err, expectedErr)
@@ -716,6 +743,83 @@ func TestChecksum(t *testing.T) {
 	}
 }
 
+// marshalXOR packs the Entry the way older servers did, with no
+// leading crcRecord byte and an XOR checksum.
+func marshalXOR(t *testing.T, le *Entry) []byte {
+	b, err := le.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = b[1 : len(b)-4]
+	chksum := checksum(b)
+	return append(b, chksum[:]...)
+}
+
+func TestUnmarshalXOR(t *testing.T) {
+	buf := marshalXOR(t, &entry)
+	var newEntry Entry
+	count, err := newEntry.unmarshal(bytes.NewReader(buf), make([]byte, 1024), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(buf) {
+		t.Fatalf("got %d bytes; want %d", count, len(buf))
+	}
+	if !reflect.DeepEqual(&entry, &newEntry) {
+		t.Errorf("newEntry = %v, want = %v", newEntry, entry)
+	}
+}
+
+func TestCRCDetectsCorruption(t *testing.T) {
+	// The XOR checksum misses both of these corruptions of the packdata
+	// because they leave the XOR of every fourth byte unchanged.
+	for _, tc := range []struct {
+		name     string
+		packdata string
+		corrupt  func(packdata []byte)
+	}{
+		{"transposition", "abcdefgh", func(b []byte) { b[0], b[4] = b[4], b[0] }},
+		{"zeroed run", "abcdabcd", func(b []byte) {
+			for i := range b {
+				b[i] = 0
+			}
+		}},
+	} {
+		e := entry
+		e.Entry.Packdata = []byte(tc.packdata)
+		for _, xor := range []bool{true, false} {
+			var buf []byte
+			if xor {
+				buf = marshalXOR(t, &e)
+			} else {
+				var err error
+				buf, err = e.marshal()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			i := bytes.Index(buf, e.Entry.Packdata)
+			if i < 0 {
+				t.Fatal("packdata not found in record")
+			}
+			tc.corrupt(buf[i : i+len(e.Entry.Packdata)])
+
+			var le Entry
+			_, err := le.unmarshal(bytes.NewReader(buf), make([]byte, 1024), 0)
+			switch {
+			case xor && err != nil:
+				// If this fails, the corruption no longer demonstrates
+				// the weakness of the XOR checksum.
+				t.Errorf("%s: XOR checksum detected corruption: %v", tc.name, err)
+			case !xor && err == nil:
+				t.Errorf("%s: CRC-32 checksum missed corruption", tc.name)
+			case !xor && !strings.Contains(err.Error(), "invalid checksum"):
+				t.Errorf("%s: err = %v, want invalid checksum", tc.name, err)
+			}
+		}
+	}
+}
+
 func TestAddOffSeq(t *testing.T) {
 	// Generate a random ordering and make sure it comes out sorted.
 	var u User // Zero value will do.
@@ -990,6 +1094,413 @@ func TestReOpen(t *testing.T) {
 	}
 }
 
+func TestSetMaxLogSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "SetMaxLogSize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	user, err := Open("user@example.com", dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer user.Close()
+	user.SetMaxLogSize(200)
+
+	const n = 20
+	for i := int64(1); i <= n; i++ {
+		err = user.Append(&Entry{
+			Op: Put,
+			Entry: upspin.DirEntry{
+				Name:     "user@example.com/foo",
+				Sequence: i,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The default limit is far larger, so only the
+	// per-user limit can have started new files.
+	if len(user.files) < 2 {
+		t.Fatalf("got %d log files, want several", len(user.files))
+	}
+	for _, f := range user.files[:len(user.files)-1] {
+		size, err := sizeOfFile(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size < 200 || size > 400 {
+			t.Errorf("sealed log file %s has size %d, want at least the limit and less than one more entry", f.name, size)
+		}
+	}
+
+	// Reading continues from one file to the next.
+	r, err := user.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	offset := user.firstOffset()
+	for i := int64(1); i <= n; i++ {
+		entry, next, err := r.ReadAt(offset)
+		if err != nil {
+			t.Fatalf("ReadAt(%d): %v", offset, err)
+		}
+		if got := entry.Entry.Sequence; got != i {
+			t.Errorf("ReadAt(%d): got sequence %d, want %d", offset, got, i)
+		}
+		offset = next
+	}
+	if end := r.EndOffset(); offset != end {
+		t.Errorf("read to offset %d, want end offset %d", offset, end)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir, err := os.MkdirTemp("", "Compact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldMax := MaxLogSize
+	MaxLogSize = 1024
+	defer func() {
+		MaxLogSize = oldMax
+	}()
+
+	const user = "user@example.com"
+	u, err := Open(user, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.SaveOffset(0); err != nil {
+		t.Fatal(err)
+	}
+	put := func(name upspin.PathName, seq int64) *Entry {
+		e := &Entry{
+			Op: Put,
+			Entry: upspin.DirEntry{
+				Name:     name,
+				Sequence: seq,
+			},
+		}
+		if err := u.Append(e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	for i := int64(1); i < 100; i++ {
+		put(user+"/foo", i)
+	}
+	r, err := u.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, _, err := r.ReadAt(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log must be checkpointed before it can be compacted.
+	if err := u.Compact(nil); !errors.Is(errors.Invalid, err) {
+		t.Fatalf("Compact with unprocessed entries: err = %v, want Invalid", err)
+	}
+	start := u.AppendOffset()
+	usage := Usage{Entries: 1, Bytes: 0}
+	if err := u.SaveOffsetAndUsage(start, &usage); err != nil {
+		t.Fatal(err)
+	}
+	live := &Entry{
+		Op: Put,
+		Entry: upspin.DirEntry{
+			Name:     user + "/foo",
+			Sequence: 99,
+		},
+	}
+	if err := u.Compact([]*Entry{live}); err != nil {
+		t.Fatal(err)
+	}
+	end := u.AppendOffset()
+	if offset, err := u.ReadOffset(); err != nil || offset != end {
+		t.Fatalf("checkpoint = %d, %v; want %d", offset, err, end)
+	}
+	if got, err := u.ReadUsage(); err != nil || got == nil || *got != usage {
+		t.Fatalf("usage = %v, %v; want %v", got, err, usage)
+	}
+
+	// Old offsets and sequence numbers are gone,
+	// even for readers opened before compaction.
+	if _, _, err := r.ReadAt(0); !errors.Is(errors.Invalid, err) {
+		t.Errorf("ReadAt(0) after Compact: err = %v, want Invalid", err)
+	}
+	if offset := u.OffsetOf(50); offset != -1 {
+		t.Errorf("OffsetOf(50) = %d, want -1", offset)
+	}
+	if offset := u.OffsetOf(0); offset != start {
+		t.Errorf("OffsetOf(0) = %d, want %d", offset, start)
+	}
+	le, next, err := r.ReadAt(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(le, *live) || next != end {
+		t.Fatalf("ReadAt(%d) = %v, %d; want %v, %d", start, le, next, live, end)
+	}
+
+	// New entries are appended after the compacted log and
+	// survive reopening.
+	put(user+"/bar", 100)
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	u, err = Open(user, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+	if got := len(u.files); got != 2 {
+		t.Fatalf("got %d log files, want 2", got)
+	}
+	if offset := u.OffsetOf(100); offset != end {
+		t.Errorf("OffsetOf(100) = %d, want %d", offset, end)
+	}
+	if offset := u.OffsetOf(99); offset != -1 {
+		t.Errorf("OffsetOf(99) = %d, want -1", offset)
+	}
+	r, err = u.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []upspin.PathName
+	for curr := u.OffsetOf(0); ; {
+		le, next, err := r.ReadAt(curr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == curr {
+			break
+		}
+		names = append(names, le.Entry.Name)
+		curr = next
+	}
+	if want := []upspin.PathName{user + "/foo", user + "/bar"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("log holds %v, want %v", names, want)
+	}
+
+	// Truncating to zero discards everything.
+	if err := u.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if offset := u.AppendOffset(); offset != 0 {
+		t.Errorf("AppendOffset after Truncate(0) = %d, want 0", offset)
+	}
+}
+
+func TestInterruptedCompact(t *testing.T) {
+	dir, err := os.MkdirTemp("", "InterruptedCompact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const user = "user@example.com"
+	u, err := Open(user, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		if err := u.Append(newEntry(user+"/foo", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := u.AppendOffset()
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash just after the compacted log was moved into place.
+	e := newEntry(user+"/foo", 11)
+	buf, err := e.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compacted := u.logFileName(start, version) + compactedSuffix
+	if err := os.WriteFile(compacted, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err = Open(user, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+	if _, err := os.Stat(u.logFileName(0, version)); !os.IsNotExist(err) {
+		t.Errorf("old log file still exists: %v", err)
+	}
+	if got, want := u.AppendOffset(), start+int64(len(buf)); got != want {
+		t.Errorf("AppendOffset = %d, want %d", got, want)
+	}
+	r, err := u.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	le, _, err := r.ReadAt(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if le.Entry.Sequence != 11 {
+		t.Errorf("compacted entry has sequence %d, want 11", le.Entry.Sequence)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "Verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const user = "user@example.com"
+	u, err := Open(user, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+	for i := 1; i <= 5; i++ {
+		if err := u.Append(newEntry(user+"/foo", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	good := u.AppendOffset()
+	r, err := u.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	check := func(wantBad int64, wantIncomplete, wantErr bool) {
+		t.Helper()
+		bad, incomplete, err := r.Verify(0)
+		if bad != wantBad || incomplete != wantIncomplete || (err != nil) != wantErr {
+			t.Fatalf("Verify(0) = %d, %t, %v; want %d, %t, error %t", bad, incomplete, err, wantBad, wantIncomplete, wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("corrupt log entry at offset %d", bad)) {
+			t.Errorf("error does not report offset: %v", err)
+		}
+	}
+	check(good, false, false)
+
+	// Corrupt an entry in the middle of the log.
+	if _, err := u.Write([]byte("Some garbage")); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Append(newEntry(user+"/foo", 6)); err != nil {
+		t.Fatal(err)
+	}
+	check(good, false, true)
+
+	// An entry cut short at the end of the log is incomplete.
+	if err := u.Truncate(good); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := newEntry(user+"/foo", 6).marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Write(buf[:len(buf)-2]); err != nil {
+		t.Fatal(err)
+	}
+	check(good, true, true)
+}
+
+func TestAppendAll(t *testing.T) {
+	dir, cleanup := setup(t, "AppendAll")
+	defer cleanup()
+
+	u, err := Open(userName, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+	if err := u.Append(newEntry("foo@bar.com/single", 1)); err != nil {
+		t.Fatal(err)
+	}
+	txStart := u.AppendOffset()
+	tx := []*Entry{
+		newEntry("foo@bar.com/tx1", 2),
+		newEntry("foo@bar.com/tx2", 3),
+		newEntry("foo@bar.com/tx3", 4),
+	}
+	if err := u.AppendAll(tx); err != nil {
+		t.Fatal(err)
+	}
+	good := u.AppendOffset()
+
+	// The entries are read back one by one, and each has its offset.
+	r, err := u.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	offset := int64(0)
+	for seq := int64(1); seq <= 4; seq++ {
+		if got := u.OffsetOf(seq); got != offset {
+			t.Errorf("OffsetOf(%d) = %d, want %d", seq, got, offset)
+		}
+		le, next, err := r.ReadAt(offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if le.Entry.Sequence != seq {
+			t.Fatalf("entry at %d has sequence %d, want %d", offset, le.Entry.Sequence, seq)
+		}
+		if want := seq > 1 && seq < 4; le.more != want {
+			t.Errorf("entry %d: more = %t, want %t", seq, le.more, want)
+		}
+		offset = next
+	}
+	if offset != good {
+		t.Fatalf("entries end at %d, want %d", offset, good)
+	}
+	if bad, incomplete, err := r.Verify(0); bad != good || incomplete || err != nil {
+		t.Fatalf("Verify(0) = %d, %t, %v; want %d, false, nil", bad, incomplete, err, good)
+	}
+
+	// A crash part way through the last entry leaves the transaction
+	// unfinished, and all of it is reported incomplete.
+	if err := u.Truncate(good - 2); err != nil {
+		t.Fatal(err)
+	}
+	if bad, incomplete, err := r.Verify(0); bad != txStart || !incomplete || err == nil {
+		t.Fatalf("Verify(0) of truncated entry = %d, %t, %v; want %d, true, error", bad, incomplete, err, txStart)
+	}
+
+	// So does one between entries.
+	if err := u.Truncate(u.OffsetOf(4)); err != nil {
+		t.Fatal(err)
+	}
+	if bad, incomplete, err := r.Verify(0); bad != txStart || !incomplete || err == nil {
+		t.Fatalf("Verify(0) of unfinished transaction = %d, %t, %v; want %d, true, error", bad, incomplete, err, txStart)
+	}
+
+	// Corruption in a transaction that is followed by complete entries
+	// is not an incomplete write, but still invalidates the transaction.
+	if _, err := u.Write([]byte("Some garbage")); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Append(newEntry("foo@bar.com/single", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if bad, incomplete, err := r.Verify(0); bad != txStart || incomplete || err == nil {
+		t.Fatalf("Verify(0) of corrupt transaction = %d, %t, %v; want %d, false, error", bad, incomplete, err, txStart)
+	}
+}
+
 func newEntry(path upspin.PathName, seq int) *Entry {
 	var op Operation
 	if seq%2 == 0 {
@@ -1040,3 +1551,243 @@ type userNameSlice []upspin.UserName
 func (p userNameSlice) Len() int           { return len(p) }
 func (p userNameSlice) Less(i, j int) bool { return p[i] < p[j] }
 func (p userNameSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// simulateCrashes arranges for crash to return a copy of the log directory
+// dir as it would be after a machine crash, holding only the data in the
+// user's log files as of the last time they were synced.
+func simulateCrashes(t *testing.T, dir string) (crash func() string) {
+	var mu sync.Mutex
+	durable := make(map[string][]byte) // File name to contents as of last sync.
+	oldSync := syncFile
+	syncFile = func(f *os.File) error {
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		durable[f.Name()] = data
+		mu.Unlock()
+		return oldSync(f)
+	}
+	t.Cleanup(func() { syncFile = oldSync })
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		crashDir := t.TempDir()
+		for name, data := range durable {
+			rel, err := filepath.Rel(dir, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			newName := filepath.Join(crashDir, rel)
+			if err := os.MkdirAll(filepath.Dir(newName), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newName, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return crashDir
+	}
+}
+
+// countEntries returns the number of entries in the logs in dir.
+func countEntries(t *testing.T, dir string) int {
+	user, err := Open(userName, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer user.Close()
+	r, err := user.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	n := 0
+	for offset := int64(0); offset < user.AppendOffset(); n++ {
+		_, next, err := r.ReadAt(offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset = next
+	}
+	return n
+}
+
+func TestSyncAlwaysSurvivesCrash(t *testing.T) {
+	dir, cleanup := setup(t, "SyncAlways")
+	defer cleanup()
+	crash := simulateCrashes(t, dir)
+
+	user, err := Open(userName, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 10
+	for i := 1; i <= n; i++ {
+		if err := user.Append(newEntry("foo@bar.com/file", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Crash immediately after the last Append, without closing.
+	if got := countEntries(t, crash()); got != n {
+		t.Errorf("after crash got %d entries, want %d", got, n)
+	}
+}
+
+func TestSyncInterval(t *testing.T) {
+	dir, cleanup := setup(t, "SyncInterval")
+	defer cleanup()
+	crash := simulateCrashes(t, dir)
+
+	user, err := Open(userName, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const interval = 50 * time.Millisecond
+	if err := user.SetSync(SyncPolicy{Mode: SyncInterval, Interval: interval, Entries: 5}); err != nil {
+		t.Fatal(err)
+	}
+	// The fifth entry fills a batch and flushes it; the rest are pending.
+	for i := 1; i <= 7; i++ {
+		if err := user.Append(newEntry("foo@bar.com/file", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := countEntries(t, crash()), 5; got != want {
+		t.Errorf("after crash got %d entries, want %d", got, want)
+	}
+
+	// Once the interval has passed the pending entries are flushed.
+	for i := 8; i <= 9; i++ {
+		if err := user.Append(newEntry("foo@bar.com/file", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(4 * interval)
+	if got, want := countEntries(t, crash()), 9; got != want {
+		t.Errorf("after interval and crash got %d entries, want %d", got, want)
+	}
+	if err := user.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveOffsetFlushesLog(t *testing.T) {
+	for _, mode := range []SyncMode{SyncInterval, SyncOS} {
+		dir, cleanup := setup(t, "SaveOffsetFlushesLog")
+		defer cleanup()
+		crash := simulateCrashes(t, dir)
+
+		user, err := Open(userName, dir, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := user.SetSync(SyncPolicy{Mode: mode, Interval: time.Hour}); err != nil {
+			t.Fatal(err)
+		}
+		const n = 3
+		for i := 1; i <= n; i++ {
+			if err := user.Append(newEntry("foo@bar.com/file", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := user.SaveOffset(user.AppendOffset()); err != nil {
+			t.Fatal(err)
+		}
+		// The entries before the saved offset survive a crash.
+		if got := countEntries(t, crash()); got != n {
+			t.Errorf("mode %d: after crash got %d entries, want %d", mode, got, n)
+		}
+		if err := user.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	for _, mode := range []struct {
+		name   string
+		policy SyncPolicy
+	}{
+		{"always", SyncPolicy{Mode: SyncAlways}},
+		{"interval", SyncPolicy{Mode: SyncInterval}},
+		{"os", SyncPolicy{Mode: SyncOS}},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			dir, cleanup := setup(b, "BenchmarkAppend")
+			defer cleanup()
+			user, err := Open(userName, dir, nil, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer user.Close()
+			if err := user.SetSync(mode.policy); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := user.Append(newEntry("foo@bar.com/file", i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkReadAtDuringAppend measures reads of committed entries in the
+// log file being written while another goroutine appends to it.
+func BenchmarkReadAtDuringAppend(b *testing.B) {
+	dir, cleanup := setup(b, "BenchmarkReadAtDuringAppend")
+	defer cleanup()
+	user, err := Open(userName, dir, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer user.Close()
+	const n = 100
+	var offsets []int64
+	for i := 1; i <= n; i++ {
+		offsets = append(offsets, user.AppendOffset())
+		if err := user.Append(newEntry("foo@bar.com/file", i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	done := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for seq := n + 1; ; seq++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := user.Append(newEntry("foo@bar.com/file", seq)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		lrd, err := user.NewReader()
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		defer lrd.Close()
+		for i := 0; pb.Next(); i++ {
+			if _, _, err := lrd.ReadAt(offsets[i%n]); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+}
@@ -20,7 +20,10 @@ import (
 // contains directories that form the timestamp of when the snapshot was taken,
 // such as bob@example.com/2017/02/12/15:45/.
 //
-// Snapshots are automatically taken every 12 hours.
+// Snapshots are automatically taken every 12 hours. The owner may also
+// request one by putting an empty file named TakeSnapshot in the root of
+// the snapshot tree, or, to snapshot just one directory, a name below it
+// such as TakeSnapshot/some/dir.
 const (
 	snapshotSuffix          = "snapshot"
 	snapshotControlFile     = "TakeSnapshot"
@@ -89,7 +92,7 @@ func (s *server) snapshotLoop() {
 				// Closing the ticker channel.
 				return
 			}
-			sc.created <- s.takeSnapshotFor(sc.userName)
+			sc.created <- s.takeSnapshotFor(sc.userName, sc.subtree)
 		}
 	}
 }
@@ -98,10 +101,14 @@ func (s *server) snapshotLoop() {
 // it's time to perform a new snapshot for them and if so snapshots them.
 func (s *server) snapshotAll() error {
 	const op errors.Op = "dir/server.snapshotAll"
-	users, err := serverlog.ListUsersWithSuffix(snapshotSuffix, s.logDir)
-	if err != nil {
-		log.Error.Printf("%s: error listing snapshot users: %s", op, err)
-		return err
+	var users []upspin.UserName
+	for _, dir := range s.logDirs {
+		u, err := serverlog.ListUsersWithSuffix(snapshotSuffix, dir)
+		if err != nil {
+			log.Error.Printf("%s: error listing snapshot users: %s", op, err)
+			return err
+		}
+		users = append(users, u...)
 	}
 	var firstErr error
 	check := func(err error) error {
@@ -191,9 +198,11 @@ func (s *server) shouldSnapshot(cfg *snapshotConfig) (bool, path.Parsed, error)
 	return true, p, nil
 }
 
-// takeSnapshotFor takes a snapshot for a user.
+// takeSnapshotFor takes a snapshot for a user of the directory subtree,
+// a path relative to the root of the user's tree, or of the whole tree if
+// subtree is empty.
 // Other than in tests, it is called only from the snapshotLoop goroutine.
-func (s *server) takeSnapshotFor(user upspin.UserName) error {
+func (s *server) takeSnapshotFor(user upspin.UserName, subtree string) error {
 	cfg, err := s.getSnapshotConfig(user)
 	if err != nil {
 		return err
@@ -202,19 +211,32 @@ func (s *server) takeSnapshotFor(user upspin.UserName) error {
 	if err != nil {
 		return err
 	}
-	return s.takeSnapshot(dstDir, cfg.srcDir)
+	if subtree == "" {
+		return s.takeSnapshot(dstDir, cfg.srcDir)
+	}
+	return s.takeSnapshot(dstDir, path.Join(cfg.srcDir, subtree))
 }
 
-// takeSnapshot takes a snapshot to dstDir from srcDir.
+// takeSnapshot takes a snapshot to dstDir from srcDir. The snapshot is named
+// for the time it was taken, hh:mm, followed for a snapshot of a directory
+// other than the root by a hyphen and the last element of srcDir, so that it
+// is not mistaken for a snapshot of the whole tree by shouldSnapshot.
+// The snapshot's SignedName records srcDir.
 func (s *server) takeSnapshot(dstDir path.Parsed, srcDir upspin.PathName) error {
 	srcParsed, err := path.Parse(srcDir)
 	if err != nil {
 		return err
 	}
 	entry, err := s.lookup(srcParsed, entryMustBeClean)
+	if err == upspin.ErrFollowLink {
+		return errors.E(errors.Invalid, srcDir, "cannot snapshot through a link")
+	}
 	if err != nil {
 		return err
 	}
+	if !entry.IsDir() {
+		return errors.E(errors.NotDir, srcDir, "can only snapshot a directory")
+	}
 
 	tree, err := s.loadTreeFor(dstDir.User())
 	if err != nil {
@@ -222,6 +244,9 @@ func (s *server) takeSnapshot(dstDir path.Parsed, srcDir upspin.PathName) error
 	}
 
 	timeNow := s.now().Go().UTC().Format(snapshotTimeFormat)
+	if !srcParsed.IsRoot() {
+		timeNow += "-" + srcParsed.Elem(srcParsed.NElem()-1)
+	}
 	dstDir, _ = path.Parse(path.Join(dstDir.Path(), timeNow))
 	err = s.makeSnapshotPath(dstDir.Path())
 	if err != nil {
@@ -322,9 +347,22 @@ func (s *server) isSnapshotOwner(snapshotUser upspin.UserName) bool {
 }
 
 // isSnapshotControlFile reports whether the path name is for an entry in the
-// root named snapshotControlFile.
+// root named snapshotControlFile, or below such an entry. A name below it,
+// such as TakeSnapshot/dir/subdir, requests a snapshot of just that
+// directory of the user's tree.
 func isSnapshotControlFile(p path.Parsed) bool {
-	return p.NElem() == 1 && p.Elem(0) == snapshotControlFile
+	return p.NElem() >= 1 && p.Elem(0) == snapshotControlFile
+}
+
+// snapshotSubtree returns the directory whose snapshot is requested by the
+// control entry at p, relative to the root of the tree, or the empty string
+// for the root itself.
+func snapshotSubtree(p path.Parsed) string {
+	elems := make([]string, 0, p.NElem())
+	for i := 1; i < p.NElem(); i++ {
+		elems = append(elems, p.Elem(i))
+	}
+	return strings.Join(elems, "/")
 }
 
 // isValidSnapshotControlEntry reports whether an entry correctly represents the
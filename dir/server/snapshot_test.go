@@ -226,7 +226,7 @@ func TestForceSnapshot(t *testing.T) {
 	mockTime.addSecond(60) // A minute has elapsed.
 
 	// Force a snapshot to be taken for canonicalUser.
-	err = s.takeSnapshotFor(snapshotUser)
+	err = s.takeSnapshotFor(snapshotUser, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -423,6 +423,43 @@ func TestSnapshotUserCanCreateSnapshotRoot(t *testing.T) {
 	create(t, s, "user+snapshot@example.com/", isDir)
 }
 
+func TestTriggerSubtreeSnapshotWithPut(t *testing.T) {
+	s, _ := newDirServerForTesting(t, snapshotUser)
+	mockTime.addSecond(60)
+
+	trigger := func(subtree upspin.PathName) error {
+		name := snapshotUser + "/" + snapshotControlFile + "/" + subtree
+		_, err := s.Put(&upspin.DirEntry{
+			Name:       name,
+			SignedName: name,
+			Packing:    upspin.PlainPack,
+			Writer:     canonicalUser,
+		})
+		return err
+	}
+	if err := trigger("dir"); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := s.Glob(snapshotUser + "/*/*/*/*-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 1 {
+		t.Fatalf("got %d snapshots of dir, want 1", len(ents))
+	}
+	if got, want := ents[0].SignedName, upspin.PathName(canonicalUser+"/dir"); got != want {
+		t.Errorf("snapshot SignedName = %q, want %q", got, want)
+	}
+
+	// A file cannot be snapshotted.
+	if err := trigger("file.pdf"); !errors.Is(errors.NotDir, err) {
+		t.Errorf("snapshot of file: err = %v, want NotDir", err)
+	}
+	if err := trigger("missing"); !errors.Is(errors.NotExist, err) {
+		t.Errorf("snapshot of missing directory: err = %v, want NotExist", err)
+	}
+}
+
 func create(t *testing.T, s *server, name upspin.PathName, isDir bool) {
 	var err error
 	if isDir {
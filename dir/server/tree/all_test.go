@@ -7,6 +7,7 @@ package tree
 import (
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -1074,8 +1075,15 @@ func TestCorruptTreeAndRecover(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Crash and recover the tree.
-	tree2, err := New(config, user)
+	// Crash. By default the tree cannot be recovered,
+	// and the error identifies the corrupt entry.
+	_, err = New(config, user)
+	if !errors.Is(errors.IO, err) || !strings.Contains(err.Error(), "corrupt log entry at offset") {
+		t.Fatalf("New with corrupt log: err = %v, want corrupt log entry", err)
+	}
+
+	// Recover the tree, truncating the log.
+	tree2, err := New(config, user, TruncateCorruptLog())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1124,6 +1132,94 @@ func TestCorruptTreeAndRecover(t *testing.T) {
 	}
 }
 
+func TestRecoverIncompleteEntry(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []upspin.PathName{"/", "/dir1", "/dir2"} {
+		_, err = tree.Put(newDirEntry(name, isDir, config))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	end := user.AppendOffset()
+
+	// A crash part way through writing an entry leaves its start.
+	_, err = user.Write([]byte{0x00, 0x20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recovery discards the incomplete entry without being asked.
+	tree2, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := user.AppendOffset(); got != end {
+		t.Errorf("AppendOffset = %d, want %d", got, end)
+	}
+	entries, _, err := tree2.List(mkpath(t, userName+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkDirList(entries, map[upspin.PathName]upspin.PathName{
+		userName + "/dir1": userName + "/dir1",
+		userName + "/dir2": userName + "/dir2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverCheckpointBeyondLog(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	if err := user.SetSync(serverlog.SyncPolicy{Mode: serverlog.SyncOS}); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mkdir(t, tree, config, "/")
+	mkdir(t, tree, config, "/dir1")
+	end := user.AppendOffset()
+	mkdir(t, tree, config, "/dir2")
+	if err := tree.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lose the end of the log, as a crash could before the log
+	// was flushed along with the checkpoint.
+	if err := user.Truncate(end); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries put after recovery are not skipped by the next one.
+	tree2, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mkdir(t, tree2, config, "/dir3")
+	tree3, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := tree3.List(mkpath(t, userName+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkDirList(entries, map[upspin.PathName]upspin.PathName{
+		userName + "/dir1": userName + "/dir1",
+		userName + "/dir2": userName + "/dir2",
+		userName + "/dir3": userName + "/dir3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 var topDir string // where we write our test data.
 
 func TestMain(m *testing.M) {
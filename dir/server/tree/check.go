@@ -0,0 +1,247 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+// This file implements consistency checks of the tree and of the blocks
+// written by store.
+
+import (
+	"bytes"
+	"sort"
+
+	"upspin.io/client/clientutil"
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+// Check walks the tree and its log, changing neither, and returns an error
+// describing each inconsistency found. It reports:
+//   - a saved root or directory whose blocks cannot be read from the Store
+//     or parsed, leaving its contents unreachable;
+//   - an entry whose name does not match its place in the tree;
+//   - an entry with a sequence number beyond the tree's;
+//   - a modified directory, not yet flushed, within one that is not marked
+//     as modified, which would be lost by a flush;
+//   - a link to a nonexistent item in the same tree;
+//   - successive log entries for an item whose sequence numbers do not
+//     increase;
+//   - for a tree created with the ReadOnly option, a corrupt or incomplete
+//     log entry, found by New, that a writable tree would have truncated.
+//
+// Like Compact, Check reads every directory from the Store and blocks all
+// other operations on the tree while it runs. The returned error is non-nil
+// only if the check could not be completed.
+func (t *Tree) Check() ([]error, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.loadRoot()
+	if err != nil {
+		return nil, err
+	}
+	c := &checker{
+		t:     t,
+		names: make(map[upspin.PathName]bool),
+		links: make(map[upspin.PathName]upspin.PathName),
+	}
+
+	// The saved root may differ from the one in memory if the tree
+	// has not been flushed, so check its blocks separately.
+	saved, err := t.user.Root()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.load(saved); err != nil {
+		c.problem(saved.Name, errors.Errorf("cannot load saved root: %v", err))
+	}
+
+	if t.logErr != nil {
+		c.problem(t.root.entry.Name, t.logErr)
+	}
+	c.walk(t.root, t.root.entry.Name, true)
+	c.checkLinks()
+	if err := c.checkLog(); err != nil {
+		return nil, err
+	}
+	return c.problems, nil
+}
+
+// checker holds the state of a Check.
+type checker struct {
+	t        *Tree
+	problems []error
+
+	// names holds the names of all items in the tree.
+	names map[upspin.PathName]bool
+	// links maps the names of links in the tree to their targets.
+	links map[upspin.PathName]upspin.PathName
+}
+
+// problem records an inconsistency at the named item.
+func (c *checker) problem(name upspin.PathName, err error) {
+	c.problems = append(c.problems, errors.E(name, errors.Internal, err))
+}
+
+// walk checks the node, which should be named name, and its descendants.
+// parentDirty reports whether its parent is marked as modified.
+// The contents of directories that are not in memory are read from the
+// Store but not added to the tree.
+// t.mu must be held.
+func (c *checker) walk(n *node, name upspin.PathName, parentDirty bool) {
+	e := &n.entry
+	c.names[name] = true
+	if e.Name != name {
+		c.problem(name, errors.Errorf("entry is named %q", e.Name))
+	}
+	if e.Sequence > c.t.sequence {
+		c.problem(name, errors.Errorf("sequence number %d is beyond the tree's %d", e.Sequence, c.t.sequence))
+	}
+	if n.dirty && !parentDirty {
+		c.problem(name, errors.Str("modified directory within unmodified directory"))
+	}
+	if e.IsLink() {
+		c.links[name] = e.Link
+	}
+	if !e.IsDir() {
+		return
+	}
+	kids := n.kids
+	if kids == nil && len(e.Blocks) > 0 {
+		var err error
+		kids, err = c.t.load(e)
+		if err != nil {
+			c.problem(name, errors.Errorf("cannot load directory: %v", err))
+			return
+		}
+	}
+	elems := make([]string, 0, len(kids))
+	for elem := range kids {
+		elems = append(elems, elem)
+	}
+	sort.Strings(elems)
+	for _, elem := range elems {
+		c.walk(kids[elem], path.Join(name, elem), n.dirty)
+	}
+}
+
+// checkLinks checks that links to items in the tree refer to items that
+// exist. Targets reached through another link are not followed.
+func (c *checker) checkLinks() {
+	for name, target := range c.links {
+		p, err := path.Parse(target)
+		if err != nil {
+			c.problem(name, errors.Errorf("invalid link target: %v", err))
+			continue
+		}
+		if p.User() != c.t.user.Name() || c.names[p.Path()] {
+			continue
+		}
+		throughLink := false
+		for i := 0; i < p.NElem(); i++ {
+			if _, ok := c.links[p.First(i).Path()]; ok {
+				throughLink = true
+				break
+			}
+		}
+		if !throughLink {
+			c.problem(name, errors.Errorf("link target %q does not exist", target))
+		}
+	}
+}
+
+// checkLog checks that the sequence numbers of the log entries for each
+// item increase, stopping at any bad entry found by New.
+// t.mu must be held.
+func (c *checker) checkLog() error {
+	user := c.t.user
+	start := user.OffsetOf(0) // Not zero if the logs were compacted.
+	end := user.AppendOffset()
+	if c.t.logErr != nil {
+		end = c.t.logErrOffset
+	}
+	lrd, err := user.NewReader()
+	if err != nil {
+		return err
+	}
+	defer lrd.Close()
+
+	last := make(map[upspin.PathName]int64)
+	for curr := start; curr < end; {
+		logEntry, next, err := lrd.ReadAt(curr)
+		if err != nil {
+			return errors.E(errors.IO, user.Name(), errors.Errorf("cannot read log at offset %d: %v", curr, err))
+		}
+		if next == curr {
+			break
+		}
+		e := &logEntry.Entry
+		if prev, ok := last[e.Name]; ok && e.Sequence <= prev {
+			op := "put"
+			if logEntry.Op == serverlog.Delete {
+				op = "delete"
+			}
+			c.problem(e.Name, errors.Errorf("log entry at offset %d: %s with sequence number %d follows sequence number %d", curr, op, e.Sequence, prev))
+		}
+		last[e.Name] = e.Sequence
+		curr = next
+	}
+	return nil
+}
+
+// checkStored verifies that the blocks stored for entry, when read back,
+// concatenated and parsed, reproduce exactly the DirEntries of kids, and
+// that no DirEntry is split across blocks. It is called after each store
+// when the debug build tag is set, and by tests.
+func (t *Tree) checkStored(entry *upspin.DirEntry, kids map[string]*node) error {
+	const op errors.Op = "dir/server/tree.checkStored"
+	data, err := clientutil.ReadAll(t.config, entry)
+	if err != nil {
+		return errors.E(op, entry.Name, err)
+	}
+	want := make(map[upspin.PathName][]byte)
+	for _, kid := range kids {
+		b, err := kid.entry.Marshal()
+		if err != nil {
+			return errors.E(op, kid.entry.Name, err)
+		}
+		want[kid.entry.Name] = b
+	}
+
+	// Parse the entries, recording where each one ends.
+	ends := make(map[int64]bool)
+	for pos := 0; pos < len(data); {
+		var kid upspin.DirEntry
+		remaining, err := kid.Unmarshal(data[pos:])
+		if err != nil {
+			return errors.E(op, entry.Name, errors.Internal, errors.Errorf("parsing entry at offset %d: %v", pos, err))
+		}
+		end := len(data) - len(remaining)
+		b, ok := want[kid.Name]
+		if !ok {
+			return errors.E(op, entry.Name, errors.Internal, errors.Errorf("stored entry %q is not in the directory", kid.Name))
+		}
+		if !bytes.Equal(b, data[pos:end]) {
+			return errors.E(op, entry.Name, errors.Internal, errors.Errorf("stored entry %q differs from the directory", kid.Name))
+		}
+		delete(want, kid.Name)
+		ends[int64(end)] = true
+		pos = end
+	}
+	for name := range want {
+		return errors.E(op, entry.Name, errors.Internal, errors.Errorf("entry %q is missing from the stored blocks", name))
+	}
+
+	// Each block must end where an entry ends.
+	var offset int64
+	for i, b := range entry.Blocks {
+		offset += b.Size
+		if !ends[offset] {
+			return errors.E(op, entry.Name, errors.Internal, errors.Errorf("block %d ends inside an entry", i))
+		}
+	}
+	return nil
+}
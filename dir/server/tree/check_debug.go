@@ -0,0 +1,12 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build debug
+// +build debug
+
+package tree
+
+// checkBlocks enables checking the blocks of each directory as it is
+// stored. See checkStored.
+const checkBlocks = true
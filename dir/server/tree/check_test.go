@@ -0,0 +1,178 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestCheckStoredLargeNode(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, root := newDirEntry("/", isDir, config)
+	if _, err := tree.Put(p, root); err != nil {
+		t.Fatal(err)
+	}
+	// Interleave large and small entries so the blocks split
+	// between entries of different sizes.
+	var want []string
+	for i := 0; i < 12; i++ {
+		name := upspin.PathName(fmt.Sprintf("/file%02d", i))
+		p, de := newDirEntry(name, !isDir, config)
+		if i%3 == 0 {
+			de.Packdata = make([]byte, upspin.BlockSize/3)
+		}
+		if _, err := tree.Put(p, de); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, string(userName+name))
+	}
+	if err := tree.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	rootEntry, err := tree.user.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rootEntry.Blocks) < 2 {
+		t.Fatalf("len(root.Blocks) = %d, want at least 2", len(rootEntry.Blocks))
+	}
+	if err := tree.checkStored(rootEntry, tree.root.kids); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the blocks back reproduces the listing.
+	kids, err := tree.load(rootEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, kid := range kids {
+		got = append(got, string(kid.entry.Name))
+	}
+	sort.Strings(got)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("listing from blocks:\n\t%v\nwant:\n\t%v", got, want)
+	}
+
+	// A directory that lacks an entry, has an extra one, or has a
+	// changed one does not match the stored blocks.
+	mismatched := func(what string, edit func(kids map[string]*node)) {
+		kids := make(map[string]*node)
+		for elem, kid := range tree.root.kids {
+			kids[elem] = &node{entry: *kid.entry.Copy()}
+		}
+		edit(kids)
+		err := tree.checkStored(rootEntry, kids)
+		if !errors.Is(errors.Internal, err) {
+			t.Errorf("%s: got error %v, want Internal", what, err)
+		}
+	}
+	mismatched("extra stored entry", func(kids map[string]*node) {
+		delete(kids, "file05")
+	})
+	mismatched("missing stored entry", func(kids map[string]*node) {
+		_, de := newDirEntry("/newfile", !isDir, config)
+		kids["newfile"] = &node{entry: *de}
+	})
+	mismatched("changed entry", func(kids map[string]*node) {
+		kids["file03"].entry.Sequence++
+	})
+}
+
+func TestCheck(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []upspin.PathName{"/", "/dir", "/dir/sub"} {
+		p, de := newDirEntry(name, isDir, config)
+		if _, err := tree.Put(p, de); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, de := newDirEntry("/dir/file.txt", !isDir, config)
+	if _, err := tree.Put(p, de); err != nil {
+		t.Fatal(err)
+	}
+	p, link := newDirEntry("/dir/link", !isDir, config)
+	link.Attr = upspin.AttrLink
+	link.Link = userName + "/dir/file.txt"
+	if _, err := tree.Put(p, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Leave something unflushed too.
+	p, de = newDirEntry("/dir/sub/file2.txt", !isDir, config)
+	if _, err := tree.Put(p, de); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := tree.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("Check of consistent tree found problems: %v", problems)
+	}
+
+	// Break the tree in memory and in its log.
+	dir := tree.root.kids["dir"]
+	dir.kids["file.txt"].entry.Name = userName + "/dir/other.txt"
+	dir.kids["sub"].entry.Sequence = tree.sequence + 10
+	p, de = newDirEntry("/dir/link2", !isDir, config)
+	de.Attr = upspin.AttrLink
+	de.Link = userName + "/dir/missing.txt"
+	if _, err := tree.Put(p, de); err != nil {
+		t.Fatal(err)
+	}
+	_, de = newDirEntry("/dir/file.txt", !isDir, config)
+	de.Sequence = upspin.SeqBase
+	if err := user.Append(&serverlog.Entry{Op: serverlog.Put, Entry: *de}); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err = tree.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"entry is named",
+		"beyond the tree's",
+		"does not exist",
+		"follows sequence number",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Check found %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+	for _, w := range want {
+		found := false
+		for _, p := range problems {
+			if !errors.Is(errors.Internal, p) {
+				t.Errorf("problem %v is not Internal", p)
+			}
+			if strings.Contains(p.Error(), w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no problem mentions %q: %v", w, problems)
+		}
+	}
+}
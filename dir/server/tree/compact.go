@@ -0,0 +1,49 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"upspin.io/dir/server/serverlog"
+)
+
+// Compact rewrites the tree's log so that it holds a single Put for each
+// live entry in the tree, reclaiming the space used by superseded and
+// deleted entries. The tree is flushed first and the root and the sequence
+// numbers of all entries are preserved. Compact loads the entire tree into
+// memory and blocks all other operations on the tree while it runs, so it
+// is best run while the tree is quiescent.
+//
+// Watchers positioned in the old log receive an error and must start
+// again; see serverlog.User.Compact.
+func (t *Tree) Compact() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.loadRoot()
+	if err != nil {
+		return err
+	}
+	err = t.flush()
+	if err != nil {
+		return err
+	}
+
+	// The traversal visits each directory before its contents, as needed
+	// to rebuild the tree from the log. The root itself is not logged.
+	var entries []*serverlog.Entry
+	err = t.traverse(t.root, 0, func(n *node, level int) error {
+		if level > 0 {
+			entries = append(entries, &serverlog.Entry{
+				Op:    serverlog.Put,
+				Entry: n.entry,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.user.Compact(entries)
+}
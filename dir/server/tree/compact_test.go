@@ -0,0 +1,136 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"testing"
+	"time"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestCompact(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildTree(t, tree, config)
+
+	// Give the log some garbage: overwritten and deleted entries.
+	put := func(name upspin.PathName) *upspin.DirEntry {
+		p, de := newDirEntry(name, !isDir, config)
+		de, err := tree.Put(p, de)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return de
+	}
+	for i := 0; i < 100; i++ {
+		put("/orig/sub1/file1.txt")
+	}
+	gone := put("/other/gone.txt")
+	if _, err := tree.Delete(mkpath(t, userName+"/other/gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(name upspin.PathName) *upspin.DirEntry {
+		de, _, err := tree.Lookup(mkpath(t, userName+name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return de
+	}
+	root := lookup("/")
+	file := lookup("/orig/sub1/file1.txt")
+	before := user.AppendOffset() - user.OffsetOf(0)
+
+	if err := tree.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := user.AppendOffset() - user.OffsetOf(0); after >= before {
+		t.Errorf("log is %d bytes after compaction, was %d", after, before)
+	}
+	if got := lookup("/"); got.Sequence != root.Sequence {
+		t.Errorf("root sequence = %d, want %d", got.Sequence, root.Sequence)
+	}
+	if got := lookup("/orig/sub1/file1.txt"); got.Sequence != file.Sequence {
+		t.Errorf("file sequence = %d, want %d", got.Sequence, file.Sequence)
+	}
+
+	// Watching from a sequence number in the old log fails cleanly.
+	done := make(chan struct{})
+	defer close(done)
+	ch, err := tree.Watch(mkpath(t, userName+"/"), gone.Sequence, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := <-ch; !errors.Is(errors.Invalid, event.Error) {
+		t.Errorf("watching from old sequence: event = %v, want Invalid error", event)
+	}
+
+	// Watching from the start reports each live entry once.
+	ch, err = tree.Watch(mkpath(t, userName+"/"), upspin.WatchStart, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []upspin.PathName
+	for {
+		select {
+		case event := <-ch:
+			if event.Error != nil {
+				t.Fatal(event.Error)
+			}
+			if event.Delete {
+				t.Errorf("unexpected delete of %s", event.Entry.Name)
+			}
+			names = append(names, event.Entry.Name)
+			continue
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+	want := []upspin.PathName{
+		userName + "/orig",
+		userName + "/orig/sub1",
+		userName + "/orig/sub1/file1.txt",
+		userName + "/orig/sub1/subsub",
+		userName + "/orig/sub2",
+		userName + "/other",
+		userName + "/snapshot",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("got events for %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("event %d is for %s, want %s", i, names[i], want[i])
+		}
+	}
+
+	// The history of the deleted entry is gone.
+	history, err := tree.History(mkpath(t, userName+"/other/gone.txt"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Errorf("deleted entry has %d history entries, want none", len(history))
+	}
+
+	// The tree keeps working after a restart.
+	added := put("/orig/sub2/new.txt")
+	tree, err = New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup("/orig/sub2/new.txt"); got.Sequence != added.Sequence {
+		t.Errorf("new file sequence = %d, want %d", got.Sequence, added.Sequence)
+	}
+	if got := lookup("/orig/sub1/file1.txt"); got.Sequence != file.Sequence {
+		t.Errorf("file sequence after restart = %d, want %d", got.Sequence, file.Sequence)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/path"
+)
+
+// History returns, oldest first, the log entries recording the creation,
+// modification and deletion of the item at p. Only changes that are
+// recorded in the tree's log are reported; in particular, the root
+// itself is never logged. If the item was deleted and later recreated,
+// all of its incarnations are reported. Once the logs have been compacted,
+// the history of a live item begins with its state at the time of the
+// compaction and that of a deleted item is empty.
+//
+// If max is greater than zero, at most max entries are returned: the
+// most recent ones. The whole log is still scanned.
+func (t *Tree) History(p path.Parsed, max int) ([]serverlog.Entry, error) {
+	t.mu.Lock()
+	// Fix the end of the log so concurrent Puts do not extend the
+	// scan indefinitely, and clone a reader so we can read the log
+	// without holding the tree lock.
+	start := t.user.OffsetOf(0) // Not zero if the logs were compacted.
+	end := t.user.AppendOffset()
+	lrd, err := t.user.NewReader()
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer lrd.Close()
+
+	name := p.Path()
+	var entries []serverlog.Entry
+	for curr := start; curr < end; {
+		logEntry, next, err := lrd.ReadAt(curr)
+		if err != nil {
+			return nil, errors.E(errors.IO, name, errors.Errorf("cannot read log at offset %d: %v", curr, err))
+		}
+		if next == curr {
+			break
+		}
+		curr = next
+		if logEntry.Entry.Name != name {
+			continue
+		}
+		entries = append(entries, logEntry)
+		if max > 0 && len(entries) >= 2*max {
+			// Drop the oldest entries, reusing the array.
+			entries = append(entries[:0], entries[len(entries)-max:]...)
+		}
+	}
+	if max > 0 && len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+	return entries, nil
+}
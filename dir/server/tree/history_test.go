@@ -0,0 +1,106 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"testing"
+
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/upspin"
+)
+
+func TestHistory(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mkdir(t, tree, config, "/")
+	mkdir(t, tree, config, "/dir")
+
+	const (
+		writer1 = upspin.UserName("one@example.com")
+		writer2 = upspin.UserName("two@example.com")
+	)
+	put := func(name upspin.PathName, writer upspin.UserName) {
+		p, entry := newDirEntry(name, !isDir, config)
+		entry.Writer = writer
+		if _, err := tree.Put(p, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create, overwrite, delete and recreate a file,
+	// interleaved with changes to an unrelated file.
+	put("/dir/file", writer1)
+	put("/dir/other", writer1)
+	put("/dir/file", writer2)
+	p := mkpath(t, userName+"/dir/file")
+	if _, err := tree.Delete(p); err != nil {
+		t.Fatal(err)
+	}
+	put("/dir/file", writer1)
+
+	entries, err := tree.History(p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		op     serverlog.Operation
+		writer upspin.UserName
+	}{
+		{serverlog.Put, writer1},
+		{serverlog.Put, writer2},
+		{serverlog.Delete, writer2},
+		{serverlog.Put, writer1},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d history entries, want %d", len(entries), len(want))
+	}
+	var lastSeq int64
+	for i, e := range entries {
+		if e.Entry.Name != p.Path() {
+			t.Errorf("%d: name = %q, want %q", i, e.Entry.Name, p.Path())
+		}
+		if e.Op != want[i].op {
+			t.Errorf("%d: op = %v, want %v", i, e.Op, want[i].op)
+		}
+		if e.Entry.Writer != want[i].writer {
+			t.Errorf("%d: writer = %q, want %q", i, e.Entry.Writer, want[i].writer)
+		}
+		if e.Op == serverlog.Put {
+			if e.Entry.Sequence <= lastSeq {
+				t.Errorf("%d: sequence %d not after %d", i, e.Entry.Sequence, lastSeq)
+			}
+			lastSeq = e.Entry.Sequence
+		}
+	}
+
+	// A limit keeps only the most recent entries.
+	for _, max := range []int{1, 3} {
+		limited, err := tree.History(p, max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(limited) != max {
+			t.Fatalf("limit %d: got %d history entries", max, len(limited))
+		}
+		for i, e := range limited {
+			w := want[len(want)-max+i]
+			if e.Op != w.op || e.Entry.Writer != w.writer {
+				t.Errorf("limit %d: %d: got %v by %q, want %v by %q", max, i, e.Op, e.Entry.Writer, w.op, w.writer)
+			}
+		}
+	}
+
+	// A name that was never put has no history.
+	entries, err = tree.History(mkpath(t, userName+"/dir/nothing"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %d history entries for non-existent file, want 0", len(entries))
+	}
+}
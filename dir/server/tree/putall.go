@@ -0,0 +1,141 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/log"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+// PutAll puts the entries into the Tree, in order, as a single transaction:
+// either all of them are put and logged together, or, if any of them cannot
+// be put, none is. An entry may be put in a directory put by an earlier
+// entry. The entries must be in the Tree's own user's tree, no two may have
+// the same name, and none may be the root. If an entry exceeds the quota,
+// none is put.
+//
+// If the returned error is ErrFollowLink, the only entry returned is that
+// of the link found along the path of one of the entries, and the caller
+// should retry as outlined in the description for upspin.ErrFollowLink.
+// Otherwise, the returned entries are the ones put.
+func (t *Tree) PutAll(entries []*upspin.DirEntry) ([]*upspin.DirEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.hasQuota() {
+		err := t.loadUsage()
+		if err != nil {
+			return nil, err
+		}
+	}
+	paths, link, err := t.checkPutAll(entries)
+	if err == upspin.ErrFollowLink {
+		return []*upspin.DirEntry{link.entry.Copy()}, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logEntries := make([]*serverlog.Entry, len(entries))
+	for i, de := range entries {
+		_, err := t.put(paths[i], de, false)
+		if err != nil {
+			// The checks have loaded the path of every entry,
+			// so this cannot happen.
+			err = errors.E(errors.Internal, de.Name, err)
+			log.Error.Print(err)
+			return nil, err
+		}
+		logEntries[i] = &serverlog.Entry{
+			Op:    serverlog.Put,
+			Entry: *de,
+		}
+	}
+	err = t.user.AppendAll(logEntries)
+	if err != nil {
+		return nil, err
+	}
+	put := make([]*upspin.DirEntry, len(entries))
+	for i, de := range entries {
+		t.notifyWatchers(de.Name)
+		put[i] = de.Copy()
+	}
+	return put, nil
+}
+
+// checkPutAll checks that all the entries can be put, without changing the
+// tree, and returns their parsed paths. If the path of an entry crosses a
+// link, it returns the link's node and ErrFollowLink.
+// t.mu must be held.
+func (t *Tree) checkPutAll(entries []*upspin.DirEntry) ([]path.Parsed, *node, error) {
+	paths := make([]path.Parsed, len(entries))
+	// dirs records the directories put by the entries checked so far.
+	dirs := make(map[upspin.PathName]bool)
+	seen := make(map[upspin.PathName]bool)
+	var delta serverlog.Usage
+	for i, de := range entries {
+		p, err := path.Parse(de.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p.User() != t.user.Name() {
+			return nil, nil, errors.E(errors.Invalid, p.Path(), errors.Errorf("entry is not in the tree of %s", t.user.Name()))
+		}
+		if p.IsRoot() {
+			return nil, nil, errors.E(errors.Invalid, p.Path(), "can't put the root with other entries")
+		}
+		if seen[p.Path()] {
+			return nil, nil, errors.E(errors.Invalid, p.Path(), "entry is put more than once")
+		}
+		seen[p.Path()] = true
+		paths[i] = p
+
+		n := &node{entry: *de}
+		var u serverlog.Usage
+		parentPath := p.Drop(1)
+		if dirs[parentPath.Path()] {
+			// The parent is a new directory put by an earlier entry.
+			if t.usage != nil {
+				u, err = t.usageOf(n)
+			}
+		} else {
+			var parent *node
+			parent, err = t.loadPath(parentPath)
+			if err == upspin.ErrFollowLink {
+				return nil, parent, err
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if parent.entry.IsLink() {
+				return nil, parent, upspin.ErrFollowLink
+			}
+			if !parent.entry.IsDir() {
+				return nil, nil, errors.E(errors.NotDir, parentPath.Path())
+			}
+			if t.usage != nil {
+				u, err = t.putUsage(n, p, parent)
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if de.IsDir() {
+			dirs[p.Path()] = true
+		}
+		if t.hasQuota() {
+			delta.Entries += u.Entries
+			delta.Bytes += u.Bytes
+			err = t.checkQuota(p, delta)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return paths, nil, nil
+}
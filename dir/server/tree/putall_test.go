@@ -0,0 +1,147 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"testing"
+
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestPutAll(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user, Quota(5, 2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []upspin.PathName{"/", "/dir"} {
+		if _, err := tree.Put(newDirEntry(name, isDir, config)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type entry struct {
+		name upspin.PathName
+		dir  bool
+	}
+	putAll := func(entries ...entry) ([]*upspin.DirEntry, error) {
+		var des []*upspin.DirEntry
+		for _, e := range entries {
+			_, de := newDirEntry(e.name, e.dir, config)
+			des = append(des, de)
+		}
+		return tree.PutAll(des)
+	}
+	exists := func(tree *Tree, name upspin.PathName) bool {
+		t.Helper()
+		_, _, err := tree.Lookup(mkpath(t, userName+name))
+		if err != nil && !errors.Is(errors.NotExist, err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	// A file may be put in a directory made by the same transaction.
+	put, err := putAll(
+		entry{"/new", isDir},
+		entry{"/new/file", !isDir},
+		entry{"/dir/file", !isDir},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(put) != 3 {
+		t.Fatalf("PutAll returned %d entries, want 3", len(put))
+	}
+	for i := 1; i < len(put); i++ {
+		if put[i].Sequence != put[i-1].Sequence+1 {
+			t.Errorf("sequence of entry %d is %d, want %d", i, put[i].Sequence, put[i-1].Sequence+1)
+		}
+	}
+
+	// If any entry cannot be put, none is.
+	for _, test := range []struct {
+		entries []entry
+		kind    errors.Kind
+	}{
+		{[]entry{{"/dir/sub", isDir}, {"/missing/file", !isDir}}, errors.NotExist},
+		{[]entry{{"/dir/sub", isDir}, {"/dir/file/sub", !isDir}}, errors.NotDir},
+		{[]entry{{"/dir/sub", isDir}, {"/dir/sub", isDir}}, errors.Invalid},
+		{[]entry{{"/dir/sub", isDir}, {"/dir/big", !isDir}}, errors.Quota},
+	} {
+		if _, err := putAll(test.entries...); !errors.Is(test.kind, err) {
+			t.Errorf("PutAll(%v): err = %v, want %v", test.entries, err, test.kind)
+		}
+		if exists(tree, "/dir/sub") {
+			t.Fatalf("PutAll(%v) put /dir/sub", test.entries)
+		}
+	}
+	usage, err := tree.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (serverlog.Usage{Entries: 4, Bytes: 2048}); usage != want {
+		t.Errorf("usage = %+v, want %+v", usage, want)
+	}
+
+	// The transaction is recovered from the log.
+	tree2, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []upspin.PathName{"/new", "/new/file", "/dir/file"} {
+		if !exists(tree2, name) {
+			t.Errorf("%s does not exist after recovery", name)
+		}
+	}
+}
+
+func TestRecoverIncompleteTransaction(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []upspin.PathName{"/", "/dir1"} {
+		if _, err := tree.Put(newDirEntry(name, isDir, config)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	end := user.AppendOffset()
+
+	// A crash part way through writing the last entry of a transaction
+	// leaves the earlier ones.
+	var entries []*serverlog.Entry
+	for _, name := range []upspin.PathName{"/dir2", "/dir2/sub"} {
+		_, de := newDirEntry(name, isDir, config)
+		entries = append(entries, &serverlog.Entry{Op: serverlog.Put, Entry: *de})
+	}
+	if err := user.AppendAll(entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := user.Truncate(user.AppendOffset() - 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recovery discards the whole transaction.
+	tree2, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := user.AppendOffset(); got != end {
+		t.Errorf("AppendOffset = %d, want %d", got, end)
+	}
+	list, _, err := tree2.List(mkpath(t, userName+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkDirList(list, map[upspin.PathName]upspin.PathName{
+		userName + "/dir1": userName + "/dir1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
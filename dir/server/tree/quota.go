@@ -0,0 +1,147 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+// Quota limits the tree to maxEntries entries, not counting the root, and
+// to maxBytes bytes in the blocks of its files. Put fails with an error of
+// kind errors.Quota if it would take the tree over either limit, although
+// Puts that do not increase the usage are always allowed. A limit of zero
+// or less means no limit.
+//
+// PutDir, which is used to make snapshots, counts the contents of the
+// directory it puts but is not limited.
+func Quota(maxEntries, maxBytes int64) Option {
+	return func(t *Tree) {
+		t.quota = serverlog.Usage{Entries: maxEntries, Bytes: maxBytes}
+	}
+}
+
+// Usage returns the number of entries in the tree, not counting the root,
+// and the total size of the blocks of its files. The usage is maintained as
+// the tree changes and saved in the log checkpoint when the tree is
+// flushed. If it was not saved, as when the tree was last flushed by an
+// older server, it is computed by loading the entire tree.
+func (t *Tree) Usage() (serverlog.Usage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.loadUsage(); err != nil {
+		return serverlog.Usage{}, err
+	}
+	return *t.usage, nil
+}
+
+// hasQuota reports whether the Quota option set any limit.
+func (t *Tree) hasQuota() bool {
+	return t.quota.Entries > 0 || t.quota.Bytes > 0
+}
+
+// loadUsage computes the usage of the tree if it is not known.
+// t.mu must be held.
+func (t *Tree) loadUsage() error {
+	if t.usage != nil {
+		return nil
+	}
+	err := t.loadRoot()
+	if err != nil {
+		return err
+	}
+	u, err := t.usageOf(t.root)
+	if err != nil {
+		return err
+	}
+	u.Entries-- // The root is not counted.
+	t.usage = &u
+	return nil
+}
+
+// checkQuota returns an error if adding delta to the usage of the tree
+// would exceed the quota in a dimension that delta increases.
+// t.mu must be held and the usage must be known.
+func (t *Tree) checkQuota(p path.Parsed, delta serverlog.Usage) error {
+	if max := t.quota.Entries; max > 0 && delta.Entries > 0 && t.usage.Entries+delta.Entries > max {
+		return errors.E(p.Path(), errors.Quota, errors.Errorf("tree has %d entries, limit is %d", t.usage.Entries, max))
+	}
+	if max := t.quota.Bytes; max > 0 && delta.Bytes > 0 && t.usage.Bytes+delta.Bytes > max {
+		return errors.E(p.Path(), errors.Quota, errors.Errorf("tree has %d bytes, limit is %d", t.usage.Bytes, max))
+	}
+	return nil
+}
+
+// putUsage returns the change in usage caused by putting n, whose path is
+// p, into parent.
+// t.mu must be held.
+func (t *Tree) putUsage(n *node, p path.Parsed, parent *node) (serverlog.Usage, error) {
+	var old *node
+	if parent.entry.IsDir() {
+		// Otherwise the put will fail.
+		err := t.loadDir(parent)
+		if err != nil {
+			return serverlog.Usage{}, err
+		}
+		old = parent.kids[p.Elem(p.NElem()-1)]
+	}
+	if old != nil && old.entry.IsDir() && n.entry.IsDir() {
+		// A directory is put over another only when replaying a
+		// compacted log, and the contents are unchanged.
+		return serverlog.Usage{}, nil
+	}
+	delta, err := t.usageOf(n)
+	if err != nil {
+		return serverlog.Usage{}, err
+	}
+	if old != nil {
+		u, err := t.usageOf(old)
+		if err != nil {
+			return serverlog.Usage{}, err
+		}
+		delta.Entries -= u.Entries
+		delta.Bytes -= u.Bytes
+	}
+	return delta, nil
+}
+
+// usageOf returns the usage of the subtree rooted at n, including n
+// itself, loading any directories that are not in memory.
+// t.mu must be held.
+func (t *Tree) usageOf(n *node) (serverlog.Usage, error) {
+	u := entryUsage(&n.entry)
+	if !n.entry.IsDir() {
+		return u, nil
+	}
+	err := t.loadDir(n)
+	if err != nil {
+		return serverlog.Usage{}, err
+	}
+	for _, kid := range n.kids {
+		ku, err := t.usageOf(kid)
+		if err != nil {
+			return serverlog.Usage{}, err
+		}
+		u.Entries += ku.Entries
+		u.Bytes += ku.Bytes
+	}
+	return u, nil
+}
+
+// entryUsage returns the usage of a single entry: the entry itself and,
+// for a file, the size of its blocks.
+func entryUsage(de *upspin.DirEntry) serverlog.Usage {
+	u := serverlog.Usage{Entries: 1}
+	if de.IsDir() || de.IsLink() {
+		return u
+	}
+	for _, b := range de.Blocks {
+		u.Bytes += b.Size
+	}
+	return u
+}
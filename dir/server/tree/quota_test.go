@@ -0,0 +1,135 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tree
+
+import (
+	"testing"
+
+	"upspin.io/dir/server/serverlog"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestQuota(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user, Quota(4, 2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(name upspin.PathName, dir bool) error {
+		_, err := tree.Put(newDirEntry(name, dir, config))
+		return err
+	}
+	checkUsage := func(tree *Tree, want serverlog.Usage) {
+		t.Helper()
+		got, err := tree.Usage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("usage = %+v, want %+v", got, want)
+		}
+	}
+
+	for _, e := range []struct {
+		name upspin.PathName
+		dir  bool
+	}{
+		{"/", isDir},
+		{"/dir", isDir},
+		{"/dir/file1", !isDir},
+		{"/dir/file2", !isDir},
+	} {
+		if err := put(e.name, e.dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkUsage(tree, serverlog.Usage{Entries: 3, Bytes: 2048})
+
+	// Each file is 1024 bytes, so a third file exceeds the byte limit,
+	// while an empty directory fits.
+	if err := put("/dir/file3", !isDir); !errors.Is(errors.Quota, err) {
+		t.Fatalf("Put of file over byte limit: err = %v, want Quota", err)
+	}
+	if err := put("/dir/sub", isDir); err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(tree, serverlog.Usage{Entries: 4, Bytes: 2048})
+	if err := put("/dir/sub2", isDir); !errors.Is(errors.Quota, err) {
+		t.Fatalf("Put of directory over entry limit: err = %v, want Quota", err)
+	}
+
+	// Overwriting a file does not change the usage.
+	if err := put("/dir/file1", !isDir); err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(tree, serverlog.Usage{Entries: 4, Bytes: 2048})
+
+	// Deleting frees space.
+	if _, err := tree.Delete(mkpath(t, userName+"/dir/file2")); err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(tree, serverlog.Usage{Entries: 3, Bytes: 1024})
+	if err := put("/dir/file3", !isDir); err != nil {
+		t.Fatal(err)
+	}
+	want := serverlog.Usage{Entries: 4, Bytes: 2048}
+	checkUsage(tree, want)
+
+	// The usage survives a flush and a restart with unprocessed entries.
+	if err := tree.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Delete(mkpath(t, userName+"/dir/sub")); err != nil {
+		t.Fatal(err)
+	}
+	want.Entries--
+	tree2, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(tree2, want)
+
+	// Without a saved usage, it is computed from the tree.
+	if err := tree2.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := user.SaveOffset(user.AppendOffset()); err != nil {
+		t.Fatal(err)
+	}
+	tree3, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(tree3, want)
+}
+
+func TestNoUsageWithoutQuota(t *testing.T) {
+	config, user := newConfigForTesting(t, userName)
+	tree, err := New(config, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []upspin.PathName{"/", "/dir"} {
+		if _, err := tree.Put(newDirEntry(name, isDir, config)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tree.Put(newDirEntry("/dir/file", !isDir, config)); err != nil {
+		t.Fatal(err)
+	}
+	if tree.usage != nil {
+		t.Fatalf("usage = %+v without a quota, want none", *tree.usage)
+	}
+
+	// Usage computes it on demand.
+	got, err := tree.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (serverlog.Usage{Entries: 2, Bytes: 1024}); got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
@@ -69,6 +69,48 @@ type Tree struct {
 
 	// watchers holds the active watchers of this tree.
 	watchers map[upspin.PathName][]*watcher
+
+	// truncateCorruptLog is set by the TruncateCorruptLog option.
+	truncateCorruptLog bool
+
+	// readOnly is set by the ReadOnly option.
+	readOnly bool
+
+	// logErr, if not nil, reports the first bad entry of the log, found
+	// at logErrOffset by New for a read-only tree.
+	logErr       error
+	logErrOffset int64
+
+	// usage is the usage of the tree, as reported by Usage. It is nil
+	// if it is not known, in which case it is not maintained until
+	// it is computed by loadUsage. It is kept from the start only if
+	// the tree has a quota.
+	usage *serverlog.Usage
+
+	// quota holds the limits set by the Quota option.
+	quota serverlog.Usage
+}
+
+// An Option configures a Tree created by New.
+type Option func(*Tree)
+
+// TruncateCorruptLog makes New recover from a log with a corrupt entry by
+// truncating the log at that entry, losing it and all later entries, rather
+// than by failing.
+func TruncateCorruptLog() Option {
+	return func(t *Tree) {
+		t.truncateCorruptLog = true
+	}
+}
+
+// ReadOnly makes New leave the log and its checkpoint as they are. Rather
+// than truncating or failing at a corrupt or incomplete log entry, New
+// recovers the entries before it, and Check reports it. A read-only tree
+// is for checking; it must not be modified or flushed.
+func ReadOnly() Option {
+	return func(t *Tree) {
+		t.readOnly = true
+	}
 }
 
 // String implements fmt.Stringer.
@@ -88,9 +130,13 @@ func (t *Tree) User() *serverlog.User {
 // All fields of the config must be defined.
 // If there are unprocessed log entries in
 // the Log, the Tree's state is recovered from it.
+// Before recovery the unprocessed entries are checked. An incomplete final
+// entry, as left by a crash, is discarded. Any other corruption makes New
+// fail with an error reporting the offset of the first bad entry, unless
+// the TruncateCorruptLog option is given.
 // TODO: Maybe New is doing too much work. Figure out how to break in two without
 // returning an inconsistent new tree if log is unprocessed.
-func New(config upspin.Config, user *serverlog.User) (*Tree, error) {
+func New(config upspin.Config, user *serverlog.User, opts ...Option) (*Tree, error) {
 	if config == nil {
 		return nil, errors.E(errors.Invalid, "config is nil")
 	}
@@ -123,6 +169,9 @@ func New(config upspin.Config, user *serverlog.User) (*Tree, error) {
 		shutdown: make(chan struct{}),
 		watchers: make(map[upspin.PathName][]*watcher),
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
 	// Do we have entries in the log to process, to recover from a crash?
 	err := t.recoverFromLog()
 	if err != nil {
@@ -168,7 +217,13 @@ func (t *Tree) Put(p path.Parsed, de *upspin.DirEntry) (*upspin.DirEntry, error)
 		return de, t.createRoot(p, de)
 	}
 
-	node, err := t.put(p, de)
+	if t.hasQuota() {
+		err := t.loadUsage()
+		if err != nil {
+			return nil, err
+		}
+	}
+	node, err := t.put(p, de, t.hasQuota())
 	if err == upspin.ErrFollowLink {
 		return node.entry.Copy(), err
 	}
@@ -189,11 +244,10 @@ func (t *Tree) Put(p path.Parsed, de *upspin.DirEntry) (*upspin.DirEntry, error)
 }
 
 // put implements the bulk of Tree.Put, but does not append to the log so it
-// can be used to recover the Tree's state from the log.
+// can be used to recover the Tree's state from the log. If checkQuota is
+// true, the put fails if it would exceed the tree's quota.
 // t.mu must be held.
-func (t *Tree) put(p path.Parsed, de *upspin.DirEntry) (*node, error) {
-	t.sequence++
-	de.Sequence = t.sequence
+func (t *Tree) put(p path.Parsed, de *upspin.DirEntry, checkQuota bool) (*node, error) {
 	// If putting a/b/c/d, ensure a/b/c is loaded.
 	parentPath := p.Drop(1)
 	parent, err := t.loadPath(parentPath)
@@ -210,10 +264,30 @@ func (t *Tree) put(p path.Parsed, de *upspin.DirEntry) (*node, error) {
 	node := &node{
 		entry: *de,
 	}
+	var delta serverlog.Usage
+	if t.usage != nil {
+		delta, err = t.putUsage(node, p, parent)
+		if err != nil {
+			return nil, err
+		}
+		if checkQuota {
+			err = t.checkQuota(p, delta)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	t.sequence++
+	de.Sequence = t.sequence
+	node.entry.Sequence = t.sequence
 	err = t.addKid(node, p, parent, parentPath)
 	if err != nil {
 		return nil, err
 	}
+	if t.usage != nil {
+		t.usage.Entries += delta.Entries
+		t.usage.Bytes += delta.Bytes
+	}
 	return node, nil
 }
 
@@ -252,7 +326,7 @@ func (t *Tree) PutDir(dstDir path.Parsed, de *upspin.DirEntry) (*upspin.DirEntry
 	}
 
 	// Put the synthetic node into the tree at dst.
-	n, err := t.put(dstDir, &existingEntryNode.entry)
+	n, err := t.put(dstDir, &existingEntryNode.entry, false)
 	if err == upspin.ErrFollowLink {
 		return nil, errors.E(errors.Invalid, dstDir.Path(), "path cannot contain a link")
 	}
@@ -491,6 +565,9 @@ func (t *Tree) createRoot(p path.Parsed, de *upspin.DirEntry) error {
 	}
 	t.root = node
 	t.sequence = upspin.SeqBase
+	if t.hasQuota() {
+		t.usage = &serverlog.Usage{}
+	}
 	de.Sequence = upspin.SeqBase
 	err = t.markDirty(p)
 	if err != nil {
@@ -617,6 +694,13 @@ func (t *Tree) delete(p path.Parsed) (*node, error) {
 	// If node was dirty, there's no need to flush it to Store ever.
 	t.removeFromDirtyList(p, node)
 
+	if t.usage != nil {
+		// Only empty directories can be deleted.
+		u := entryUsage(&node.entry)
+		t.usage.Entries -= u.Entries
+		t.usage.Bytes -= u.Bytes
+	}
+
 	// Update parent: mark it dirty and log its new version.
 	err = t.markDirty(parentPath)
 	if err != nil {
@@ -703,6 +787,11 @@ func (t *Tree) flush() error {
 			if err != nil {
 				return err
 			}
+			if checkBlocks {
+				if err := t.checkStored(&n.entry, n.kids); err != nil {
+					return err
+				}
+			}
 			n.dirty = false
 		}
 	}
@@ -712,8 +801,9 @@ func (t *Tree) flush() error {
 	// TODO: Verify the log had at least the same number of dirty entries
 	// (it could have more because of deletes).
 
-	// Save the last index we operated on.
-	err := t.user.SaveOffset(t.user.AppendOffset())
+	// Save the last index we operated on, and the usage once the
+	// entries before it are applied.
+	err := t.user.SaveOffsetAndUsage(t.user.AppendOffset(), t.usage)
 	if err != nil {
 		return err
 	}
@@ -760,6 +850,27 @@ func (t *Tree) recoverFromLog() error {
 	if err != nil {
 		return err
 	}
+	// The usage, if saved, is brought up to date by the replay. Without
+	// a quota it is not needed; it is dropped at the next checkpoint.
+	if t.hasQuota() {
+		t.usage, err = t.user.ReadUsage()
+		if err != nil {
+			return err
+		}
+	}
+	if lastProcessed > lastOffset {
+		// The end of the log was lost after the checkpoint was saved,
+		// as can happen if the log was not flushed by an older server.
+		// The tree already reflects the lost entries; make sure new
+		// entries are not appended where the checkpoint skips them.
+		log.Error.Printf("recoverFromLog: checkpoint at offset %d is beyond end of log at %d for user %s", lastProcessed, lastOffset, t.user.Name())
+		if !t.readOnly {
+			if err := t.user.SaveOffsetAndUsage(lastOffset, t.usage); err != nil {
+				return err
+			}
+		}
+		lastProcessed = lastOffset
+	}
 	if lastOffset == lastProcessed {
 		// All caught up.
 		log.Debug.Printf("recoverFromLog: Tree is all caught up for user %s", t.user.Name())
@@ -770,19 +881,36 @@ func (t *Tree) recoverFromLog() error {
 		return err
 	}
 
-	// Tree is not current. Replay all entries from the log.
+	// Tree is not current. Check the entries to be replayed.
 	lrd, err := t.user.NewReader()
 	if err != nil {
 		return err
 	}
+	defer lrd.Close()
+	bad, incomplete, err := lrd.Verify(lastProcessed)
+	switch {
+	case err == nil:
+	case t.readOnly:
+		// Replay the entries before the bad one and leave the log alone.
+		t.logErr, t.logErrOffset = err, bad
+	case !incomplete && !t.truncateCorruptLog:
+		return err
+	default:
+		log.Error.Printf("recoverFromLog: truncating log at offset %d, losing any later entries: %s", bad, err)
+		err = t.user.Truncate(bad)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replay all entries from the log.
 	recovered := 0
 	curr := lastProcessed
-	for {
+	for t.logErr == nil || curr < t.logErrOffset {
 		log.Debug.Printf("recoverFromLog: Recovering from log... %d", curr)
 		logEntry, next, err := lrd.ReadAt(curr)
 		if err != nil {
-			log.Error.Printf("recoverFromLog: Error in log recovery, possible data loss at offset %d: %s", lastProcessed, err)
-			return t.user.Truncate(curr)
+			return errors.E(t.user.Name(), errors.Errorf("can't recover log: %v", err))
 		}
 		if next == curr {
 			break
@@ -800,7 +928,7 @@ func (t *Tree) recoverFromLog() error {
 		switch logEntry.Op {
 		case serverlog.Put:
 			log.Debug.Printf("recoverFromLog: Putting dirEntry: %q", de.Name)
-			_, err = t.put(p, &de)
+			_, err = t.put(p, &de, false)
 		case serverlog.Delete:
 			log.Debug.Printf("recoverFromLog: Deleting path: %q", p.Path())
 			_, err = t.delete(p)
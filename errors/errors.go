@@ -82,8 +82,9 @@ const (
 	Private                   // Information withheld.
 	Internal                  // Internal error or inconsistency.
 	CannotDecrypt             // No wrapped key for user with read access.
-	Transient                 // A transient error.
+	Transient                 // A transient error; try again later.
 	BrokenLink                // Link target does not exist.
+	Quota                     // Quota exceeded.
 )
 
 func (k Kind) String() string {
@@ -116,6 +117,8 @@ func (k Kind) String() string {
 		return `no wrapped key for user; owner must "upspin share -fix"`
 	case Transient:
 		return "transient error"
+	case Quota:
+		return "quota exceeded"
 	}
 	return "unknown error kind"
 }
@@ -275,6 +278,24 @@ func (e *Error) Error() string {
 	return b.String()
 }
 
+// Unwrap returns the underlying error, so that the functions of the
+// standard errors package can examine the chain of errors.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error that matches e, in the sense of
+// Match. It lets the Is function of the standard errors package test
+// whether err, or any error it wraps, has Kind NotExist by comparing it
+// with E(NotExist).
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return Match(t, e)
+}
+
 // Recreate the errors.New functionality of the standard Go errors package
 // so we can create simple text errors when needed.
 
@@ -7,6 +7,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"io"
 	"os"
 	"os/exec"
@@ -192,6 +193,49 @@ func TestKind(t *testing.T) {
 	}
 }
 
+func TestUnwrap(t *testing.T) {
+	inner := E(Op("Get"), upspin.PathName("jane@doe.com/file"), NotExist, io.ErrUnexpectedEOF)
+	outer := E(Op("Read"), inner)
+
+	// E copies the inner error, moving its Kind outward.
+	if got, ok := stderrors.Unwrap(outer).(*Error); !ok || got.Op != "Get" || got.Err != io.ErrUnexpectedEOF {
+		t.Errorf("Unwrap(outer) = %v, want copy of %v", got, inner)
+	}
+	if !stderrors.Is(outer, io.ErrUnexpectedEOF) {
+		t.Errorf("standard Is did not find io.ErrUnexpectedEOF in %v", outer)
+	}
+	if !stderrors.Is(outer, E(NotExist)) {
+		t.Errorf("standard Is did not match kind NotExist in %v", outer)
+	}
+	if stderrors.Is(outer, E(Exist)) {
+		t.Errorf("standard Is matched kind Exist in %v", outer)
+	}
+	if !stderrors.Is(outer, E(Op("Get"))) {
+		t.Errorf("standard Is did not match inner Op in %v", outer)
+	}
+	var e *Error
+	if !stderrors.As(outer, &e) || e != outer {
+		t.Errorf("As(outer) = %v, want %v", e, outer)
+	}
+	// Match and Is are unchanged.
+	if !Match(E(Op("Read"), E(Op("Get"), NotExist)), outer) {
+		t.Errorf("Match failed on %v", outer)
+	}
+	if !Is(NotExist, outer) {
+		t.Errorf("Is(NotExist) failed on %v", outer)
+	}
+
+	// A marshaled error keeps its kinds, but not the underlying
+	// error values, which are sent as strings.
+	out := UnmarshalError(MarshalError(outer))
+	if !stderrors.Is(out, E(NotExist)) {
+		t.Errorf("standard Is did not match kind NotExist in unmarshaled %v", out)
+	}
+	if stderrors.Is(out, io.ErrUnexpectedEOF) {
+		t.Errorf("standard Is found io.ErrUnexpectedEOF in unmarshaled %v", out)
+	}
+}
+
 // errorAsString returns the string form of the provided error value.
 // If the given string is an *Error, the stack information is removed
 // before the value is stringified.
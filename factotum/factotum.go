@@ -53,6 +53,32 @@ func KeyHash(p upspin.PublicKey) []byte {
 // AllUsersKeyHash is the hash of upspin.AllUsersKey.
 var AllUsersKeyHash = KeyHash(upspin.AllUsersKey)
 
+// RotationHash returns the hash that is signed with the named user's
+// previous key to authorize its replacement by the next one.
+// See upspin.KeyRotator.
+func RotationHash(name upspin.UserName, previous, next upspin.PublicKey) []byte {
+	h := sha256.New()
+	h.Write([]byte("upspin-rotate:"))
+	for _, s := range []string{string(name), string(previous), string(next)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
+
+// VerifyRotation verifies that the rotation of the named user's key was
+// signed with the key it replaced.
+func VerifyRotation(name upspin.UserName, r upspin.KeyRotation) error {
+	const op errors.Op = "factotum.VerifyRotation"
+	if r.Signature.R == nil || r.Signature.S == nil {
+		return errors.E(op, errors.Invalid, name, "key rotation is not signed")
+	}
+	if err := Verify(RotationHash(name, r.Previous, r.PublicKey), r.Signature, r.Previous); err != nil {
+		return errors.E(op, name, err)
+	}
+	return nil
+}
+
 // NewFromDir returns a new Factotum providing all needed private key operations,
 // loading keys from a directory containing *.upspinkey files.
 // Our desired end state is that Factotum is implemented on each platform by the
@@ -73,13 +99,40 @@ func NewFromDir(dir string) (upspin.Factotum, error) {
 	pubBytes = stripCR(pubBytes)
 
 	// Read older key pairs.
-	s2, err := readFile(op, dir, "secret2.upspinkey")
-	if err != nil && !errors.Is(errors.NotExist, err) {
+	archived, err := readArchives(op, dir)
+	if err != nil {
 		return nil, err
 	}
-	s2 = stripCR(s2)
 
-	return newFactotum(errors.Op(fmt.Sprintf("%s(%q)", op, dir)), pubBytes, privBytes, s2)
+	return newFactotum(errors.Op(fmt.Sprintf("%s(%q)", op, dir)), pubBytes, privBytes, archived)
+}
+
+// readArchives returns the archived key pairs held in secret2.upspinkey,
+// secret3.upspinkey, and so on, stopping at the first file that does not
+// exist. Each file after secret2.upspinkey holds keys older than those of
+// the file before it, as when a long secret2.upspinkey has been moved
+// aside, so the files are concatenated oldest first, making the last key
+// in secret2.upspinkey the previous key.
+func readArchives(op errors.Op, dir string) ([]byte, error) {
+	var files [][]byte
+	for i := 2; ; i++ {
+		b, err := readFile(op, dir, fmt.Sprintf("secret%d.upspinkey", i))
+		if errors.Is(errors.NotExist, err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, stripCR(b))
+	}
+	var archived []byte
+	for i := len(files) - 1; i >= 0; i-- {
+		archived = append(archived, files[i]...)
+		if len(archived) > 0 && archived[len(archived)-1] != '\n' {
+			archived = append(archived, '\n')
+		}
+	}
+	return archived, nil
 }
 
 // NewFromKeys returns a new Factotum by providing it with the raw
@@ -90,6 +143,37 @@ func NewFromKeys(public, private, archived []byte) (upspin.Factotum, error) {
 	return newFactotum(op, public, private, archived)
 }
 
+// Generate returns a new Factotum holding a randomly generated key pair
+// on the named curve, one of p256, p384, or p521, together with the
+// public and private keys in the format of the public.upspinkey and
+// secret.upspinkey files read by NewFromDir. It does not touch the file
+// system; to persist the keys, write them to those files.
+func Generate(curveName string) (f upspin.Factotum, public upspin.PublicKey, private string, err error) {
+	const op errors.Op = "factotum.Generate"
+	var curve elliptic.Curve
+	switch curveName {
+	case "p256":
+		curve = elliptic.P256()
+	case "p384":
+		curve = elliptic.P384()
+	case "p521":
+		curve = elliptic.P521()
+	default:
+		return nil, "", "", errors.E(op, errors.Invalid, errors.Errorf("unknown curve %q", curveName))
+	}
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, "", "", errors.E(op, err)
+	}
+	public = upspin.PublicKey(fmt.Sprintf("%s\n%s\n%s\n", curveName, priv.X, priv.Y))
+	private = priv.D.String() + "\n"
+	f, err = newFactotum(op, []byte(public), []byte(private), nil)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return f, public, private, nil
+}
+
 // newFactotum creates a new Factotum using the given keys.
 func newFactotum(op errors.Op, public, private, archived []byte) (upspin.Factotum, error) {
 	pfk, err := makeKey(upspin.PublicKey(public), string(private))
@@ -238,6 +322,8 @@ func (f factotum) FileSign(hash upspin.DEHash) (upspin.Signature, error) {
 }
 
 // ScalarMult is the bare private key operator, used in unwrapping packed data.
+// It uses whichever of the current and archived keys matches keyHash, so
+// callers need not Pop to unwrap data wrapped for an older key.
 func (f factotum) ScalarMult(keyHash []byte, curve elliptic.Curve, x, y *big.Int) (sx, sy *big.Int, err error) {
 	const op errors.Op = "factotum.ScalarMult"
 	var h keyHashArray
@@ -5,6 +5,7 @@
 package factotum
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -72,6 +73,48 @@ func TestNewFromDir(t *testing.T) {
 	}
 }
 
+func TestArchiveChain(t *testing.T) {
+	const (
+		oldestPubKey = "p256\n86754568856409436056886548963722747418663925733852968840719951502625645703023\n55374006944977701639377273685946154797448684848748065688191847332792959379206\n"
+		olderPubKey  = "p256\n6640270742675236934700552659758623510932789581985633007789325329362331148012\n68892645101823987570169861213316538980647268870890981023717754447508722389034\n"
+		pubKey       = "p256\n71691471659919484988656021403576023296423553447955453611065595241735801814793\n42468664594739915910506768982794478307957489931042399796674516783666758917627\n"
+	)
+	fi, err := NewFromDir(filepath.Join("testdata", "ok-chain"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fi.PublicKey(); got != pubKey {
+		t.Errorf("PublicKey = %q, want %q", got, pubKey)
+	}
+	// The previous key comes from secret2.upspinkey.
+	if got := fi.Pop().PublicKey(); got != olderPubKey {
+		t.Errorf("Pop().PublicKey = %q, want %q", got, olderPubKey)
+	}
+
+	// ScalarMult finds each key by its hash, without Pop.
+	for _, pub := range []upspin.PublicKey{pubKey, olderPubKey, oldestPubKey} {
+		hash := KeyHash(pub)
+		if got, err := fi.PublicKeyFromHash(hash); err != nil || got != pub {
+			t.Errorf("PublicKeyFromHash(%x) = %q, %v; want %q", hash[:4], got, err, pub)
+		}
+		key, err := ParsePublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Multiplying the generator by the private key
+		// must give the public key.
+		params := key.Curve.Params()
+		x, y, err := fi.ScalarMult(hash, key.Curve, params.Gx, params.Gy)
+		if err != nil {
+			t.Errorf("ScalarMult(%x): %v", hash[:4], err)
+			continue
+		}
+		if x.Cmp(key.X) != 0 || y.Cmp(key.Y) != 0 {
+			t.Errorf("ScalarMult(%x) used the wrong key", hash[:4])
+		}
+	}
+}
+
 func TestClean(t *testing.T) {
 	f, err := NewFromDir(filepath.Join("testdata", "ok"))
 	if err != nil {
@@ -101,3 +144,89 @@ func TestSign(t *testing.T) {
 		t.Errorf("factotum.Sing(longstring) should have failed")
 	}
 }
+
+func TestGenerate(t *testing.T) {
+	hash := make([]byte, 32)
+	copy(hash, "a hash of some sort")
+	for _, curve := range []string{"p256", "p384", "p521"} {
+		f, public, private, err := Generate(curve)
+		if err != nil {
+			t.Fatalf("%s: %v", curve, err)
+		}
+		if f.PublicKey() != public {
+			t.Errorf("%s: PublicKey = %q, want %q", curve, f.PublicKey(), public)
+		}
+		sig, err := f.Sign(hash)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", curve, err)
+		}
+		if err := Verify(hash, sig, public); err != nil {
+			t.Errorf("%s: Verify: %v", curve, err)
+		}
+
+		// The keys can be saved and loaded by NewFromDir.
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "public.upspinkey"), []byte(public), 0400); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "secret.upspinkey"), []byte(private), 0400); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := NewFromDir(dir)
+		if err != nil {
+			t.Fatalf("%s: NewFromDir: %v", curve, err)
+		}
+		if loaded.PublicKey() != public {
+			t.Errorf("%s: loaded PublicKey = %q, want %q", curve, loaded.PublicKey(), public)
+		}
+		if err := Verify(hash, sig, loaded.PublicKey()); err != nil {
+			t.Errorf("%s: Verify with loaded key: %v", curve, err)
+		}
+	}
+
+	// Each call generates a different key.
+	_, pub1, _, err := Generate("p256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pub2, _, err := Generate("p256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub1 == pub2 {
+		t.Errorf("Generate returned the same key twice")
+	}
+
+	if _, _, _, err := Generate("p255"); err == nil {
+		t.Errorf("Generate(p255) succeeded")
+	}
+}
+
+func TestVerifyRotation(t *testing.T) {
+	const name = upspin.UserName("ann@example.com")
+	f, err := NewFromDir(filepath.Join("testdata", "ok-archived"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, next := f.Pop().PublicKey(), f.PublicKey()
+	sig, err := f.Pop().Sign(RotationHash(name, prev, next))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := upspin.KeyRotation{Previous: prev, PublicKey: next, Signature: sig}
+	if err := VerifyRotation(name, r); err != nil {
+		t.Errorf("VerifyRotation: %v", err)
+	}
+
+	// The signature covers the user name and both keys.
+	if err := VerifyRotation("bob@example.com", r); err == nil {
+		t.Errorf("VerifyRotation succeeded for another user")
+	}
+	swapped := upspin.KeyRotation{Previous: next, PublicKey: prev, Signature: sig}
+	if err := VerifyRotation(name, swapped); err == nil {
+		t.Errorf("VerifyRotation succeeded with the keys swapped")
+	}
+	if err := VerifyRotation(name, upspin.KeyRotation{Previous: prev, PublicKey: next}); err == nil {
+		t.Errorf("VerifyRotation succeeded without a signature")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pkcs11 provides a factotum.Token that keeps an Upspin private key
+// on a hardware token, such as a smart card or hardware security module,
+// accessed through a PKCS#11 module.
+//
+// Access to PKCS#11 modules requires cgo and the build tag "pkcs11". In a
+// binary built without them, Open and ImportKey return errors.
+//
+// The token must hold the key as sensitive and unextractable, and allow it
+// to be used for signing (CKM_ECDSA) and for key derivation
+// (CKM_ECDH1_DERIVE). Open refuses a key that the token would reveal.
+package pkcs11 // import "upspin.io/factotum/pkcs11"
+
+import (
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	"upspin.io/upspin"
+)
+
+// Config identifies a key pair held on a token.
+type Config struct {
+	// Module is the path of the PKCS#11 module, a shared library
+	// provided by the maker of the token.
+	Module string
+
+	// Slot is the slot holding the token. It is ignored if TokenLabel
+	// is set.
+	Slot uint
+
+	// TokenLabel, if set, selects the token with that label in
+	// whichever slot it is.
+	TokenLabel string
+
+	// PIN is the user PIN of the token.
+	PIN string
+
+	// KeyLabel is the label of the key pair. If it is empty, the token
+	// must hold exactly one elliptic curve private key.
+	KeyLabel string
+}
+
+// NewFactotum returns a Factotum that performs its private key operations
+// using the key pair identified by cfg. See factotum.NewFromToken.
+func NewFactotum(cfg Config) (upspin.Factotum, error) {
+	const op errors.Op = "factotum/pkcs11.NewFactotum"
+	t, err := Open(cfg)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	f, err := factotum.NewFromToken(t)
+	if err != nil {
+		t.Close()
+		return nil, errors.E(op, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,812 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build pkcs11 && cgo
+// +build pkcs11,cgo
+
+package pkcs11
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The subset of the PKCS#11 v2.20 interface used by this package.
+// Only the types of the functions called are given in full; the layout of
+// CK_FUNCTION_LIST is fixed by the standard.
+
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef CK_ULONG CK_SLOT_ID;
+typedef CK_ULONG CK_SESSION_HANDLE;
+typedef CK_ULONG CK_OBJECT_HANDLE;
+typedef unsigned char CK_BYTE;
+typedef unsigned char CK_BBOOL;
+
+typedef struct {
+	CK_ULONG type;
+	void *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_ULONG mechanism;
+	void *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	CK_ULONG kdf;
+	CK_ULONG ulSharedDataLen;
+	CK_BYTE *pSharedData;
+	CK_ULONG ulPublicDataLen;
+	CK_BYTE *pPublicData;
+} CK_ECDH1_DERIVE_PARAMS;
+
+typedef CK_RV (*CK_FN)();
+
+typedef struct {
+	CK_BYTE major, minor;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	CK_FN C_GetInfo;
+	CK_FN C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(CK_BBOOL, CK_SLOT_ID *, CK_ULONG *);
+	CK_FN C_GetSlotInfo;
+	CK_RV (*C_GetTokenInfo)(CK_SLOT_ID, void *);
+	CK_FN C_GetMechanismList;
+	CK_FN C_GetMechanismInfo;
+	CK_FN C_InitToken;
+	CK_FN C_InitPIN;
+	CK_FN C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_SLOT_ID, CK_ULONG, void *, void *, CK_SESSION_HANDLE *);
+	CK_RV (*C_CloseSession)(CK_SESSION_HANDLE);
+	CK_FN C_CloseAllSessions;
+	CK_FN C_GetSessionInfo;
+	CK_FN C_GetOperationState;
+	CK_FN C_SetOperationState;
+	CK_RV (*C_Login)(CK_SESSION_HANDLE, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_FN C_Logout;
+	CK_RV (*C_CreateObject)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG, CK_OBJECT_HANDLE *);
+	CK_FN C_CopyObject;
+	CK_RV (*C_DestroyObject)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE);
+	CK_FN C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_FN C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_SESSION_HANDLE);
+	CK_FN C_EncryptInit, C_Encrypt, C_EncryptUpdate, C_EncryptFinal;
+	CK_FN C_DecryptInit, C_Decrypt, C_DecryptUpdate, C_DecryptFinal;
+	CK_FN C_DigestInit, C_Digest, C_DigestUpdate, C_DigestKey, C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE);
+	CK_RV (*C_Sign)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+	CK_FN C_SignUpdate, C_SignFinal, C_SignRecoverInit, C_SignRecover;
+	CK_FN C_VerifyInit, C_Verify, C_VerifyUpdate, C_VerifyFinal;
+	CK_FN C_VerifyRecoverInit, C_VerifyRecover;
+	CK_FN C_DigestEncryptUpdate, C_DecryptDigestUpdate;
+	CK_FN C_SignEncryptUpdate, C_DecryptVerifyUpdate;
+	CK_FN C_GenerateKey, C_GenerateKeyPair, C_WrapKey, C_UnwrapKey;
+	CK_RV (*C_DeriveKey)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE, CK_ATTRIBUTE *, CK_ULONG, CK_OBJECT_HANDLE *);
+	CK_FN C_SeedRandom, C_GenerateRandom;
+	CK_FN C_GetFunctionStatus, C_CancelFunction, C_WaitForSlotEvent;
+} CK_FUNCTION_LIST;
+
+// Go cannot call C function pointers, so each function used has a
+// trampoline here.
+
+static CK_FUNCTION_LIST *load(const char *path, void **handle) {
+	CK_RV (*get)(CK_FUNCTION_LIST **);
+	CK_FUNCTION_LIST *f = NULL;
+
+	*handle = dlopen(path, RTLD_NOW|RTLD_LOCAL);
+	if (*handle == NULL) {
+		return NULL;
+	}
+	get = (CK_RV (*)(CK_FUNCTION_LIST **))dlsym(*handle, "C_GetFunctionList");
+	if (get == NULL || get(&f) != 0) {
+		dlclose(*handle);
+		*handle = NULL;
+		return NULL;
+	}
+	return f;
+}
+
+static void unload(void *handle) {
+	dlclose(handle);
+}
+
+static CK_RV initialize(CK_FUNCTION_LIST *f) {
+	return f->C_Initialize(NULL);
+}
+
+static CK_RV finalize(CK_FUNCTION_LIST *f) {
+	return f->C_Finalize(NULL);
+}
+
+static CK_RV getSlotList(CK_FUNCTION_LIST *f, CK_SLOT_ID *slots, CK_ULONG *n) {
+	return f->C_GetSlotList(1, slots, n);
+}
+
+// getTokenLabel copies the 32-byte label that begins CK_TOKEN_INFO.
+static CK_RV getTokenLabel(CK_FUNCTION_LIST *f, CK_SLOT_ID slot, CK_BYTE *label) {
+	CK_BYTE info[512];
+	CK_RV rv = f->C_GetTokenInfo(slot, info);
+	if (rv == 0) {
+		memcpy(label, info, 32);
+	}
+	return rv;
+}
+
+static CK_RV openSession(CK_FUNCTION_LIST *f, CK_SLOT_ID slot, CK_ULONG flags, CK_SESSION_HANDLE *s) {
+	return f->C_OpenSession(slot, flags, NULL, NULL, s);
+}
+
+static CK_RV closeSession(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s) {
+	return f->C_CloseSession(s);
+}
+
+static CK_RV login(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ULONG user, CK_BYTE *pin, CK_ULONG n) {
+	return f->C_Login(s, user, pin, n);
+}
+
+static CK_RV findObjects(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ATTRIBUTE *t, CK_ULONG n, CK_OBJECT_HANDLE *objs, CK_ULONG max, CK_ULONG *count) {
+	CK_RV rv = f->C_FindObjectsInit(s, t, n);
+	if (rv != 0) {
+		return rv;
+	}
+	rv = f->C_FindObjects(s, objs, max, count);
+	f->C_FindObjectsFinal(s);
+	return rv;
+}
+
+static CK_RV getAttributes(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_OBJECT_HANDLE obj, CK_ATTRIBUTE *t, CK_ULONG n) {
+	return f->C_GetAttributeValue(s, obj, t, n);
+}
+
+static CK_RV createObject(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ATTRIBUTE *t, CK_ULONG n, CK_OBJECT_HANDLE *obj) {
+	return f->C_CreateObject(s, t, n, obj);
+}
+
+static CK_RV sign(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ULONG mech, CK_OBJECT_HANDLE key, CK_BYTE *data, CK_ULONG n, CK_BYTE *sig, CK_ULONG *siglen) {
+	CK_MECHANISM m = {mech, NULL, 0};
+	CK_RV rv = f->C_SignInit(s, &m, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return f->C_Sign(s, data, n, sig, siglen);
+}
+
+// derive derives a session secret key from key and the public point pub
+// using mech, reads the value of the secret into value, and destroys it.
+static CK_RV derive(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ULONG mech, CK_ULONG kdf, CK_OBJECT_HANDLE key, CK_BYTE *pub, CK_ULONG publen, CK_ATTRIBUTE *t, CK_ULONG n, CK_ATTRIBUTE *value) {
+	CK_ECDH1_DERIVE_PARAMS p = {kdf, 0, NULL, publen, pub};
+	CK_MECHANISM m = {mech, &p, sizeof p};
+	CK_OBJECT_HANDLE secret;
+	CK_RV rv = f->C_DeriveKey(s, &m, key, t, n, &secret);
+	if (rv != 0) {
+		return rv;
+	}
+	rv = f->C_GetAttributeValue(s, secret, value, 1);
+	f->C_DestroyObject(s, secret);
+	return rv;
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+	"unsafe"
+
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	"upspin.io/upspin"
+)
+
+// PKCS#11 constants, from the v2.20 standard.
+const (
+	ckrOK                         = 0x000
+	ckrAttributeSensitive         = 0x011
+	ckrUserAlreadyLoggedIn        = 0x100
+	ckrCryptokiAlreadyInitialized = 0x191
+
+	ckfRWSession     = 0x2
+	ckfSerialSession = 0x4
+
+	ckuUser = 1
+
+	ckaClass       = 0x000
+	ckaToken       = 0x001
+	ckaPrivate     = 0x002
+	ckaLabel       = 0x003
+	ckaValue       = 0x011
+	ckaKeyType     = 0x100
+	ckaID          = 0x102
+	ckaSensitive   = 0x103
+	ckaSign        = 0x108
+	ckaVerify      = 0x10A
+	ckaDerive      = 0x10C
+	ckaValueLen    = 0x161
+	ckaExtractable = 0x162
+	ckaECParams    = 0x180
+	ckaECPoint     = 0x181
+
+	ckoPublicKey  = 2
+	ckoPrivateKey = 3
+	ckoSecretKey  = 4
+
+	ckkEC            = 3
+	ckkGenericSecret = 0x10
+
+	ckmECDSA       = 0x1041
+	ckmECDH1Derive = 0x1050
+
+	ckdNull = 1
+)
+
+// Object identifiers of the curves Upspin uses, as found in CKA_EC_PARAMS.
+var curveOIDs = []struct {
+	name  string
+	oid   asn1.ObjectIdentifier
+	curve elliptic.Curve
+}{
+	{"p256", asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, elliptic.P256()},
+	{"p384", asn1.ObjectIdentifier{1, 3, 132, 0, 34}, elliptic.P384()},
+	{"p521", asn1.ObjectIdentifier{1, 3, 132, 0, 35}, elliptic.P521()},
+}
+
+// ckError records a failed PKCS#11 call.
+type ckError struct {
+	fn string
+	rv C.CK_RV
+}
+
+func (e ckError) Error() string {
+	return fmt.Sprintf("%s: CKR 0x%X", e.fn, uint64(e.rv))
+}
+
+func check(fn string, rv C.CK_RV) error {
+	if rv == ckrOK {
+		return nil
+	}
+	return ckError{fn, rv}
+}
+
+// module is a loaded PKCS#11 module.
+type module struct {
+	path     string
+	refs     int // Guarded by modulesMu.
+	handle   unsafe.Pointer
+	f        *C.CK_FUNCTION_LIST
+	finalize bool // Whether we initialized the module and must finalize it.
+}
+
+var (
+	modulesMu sync.Mutex
+	// modules holds the loaded modules by path. A module is initialized
+	// once per process, however many tokens are opened through it.
+	modules = make(map[string]*module)
+)
+
+func load(path string) (*module, error) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	if m, ok := modules[path]; ok {
+		m.refs++
+		return m, nil
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	m := &module{path: path, refs: 1}
+	m.f = C.load(cpath, &m.handle)
+	if m.f == nil {
+		return nil, errors.E(errors.NotExist, errors.Errorf("cannot load PKCS#11 module %q", path))
+	}
+	switch rv := C.initialize(m.f); rv {
+	case ckrOK:
+		m.finalize = true
+	case ckrCryptokiAlreadyInitialized:
+	default:
+		C.unload(m.handle)
+		return nil, check("C_Initialize", rv)
+	}
+	modules[path] = m
+	return m, nil
+}
+
+func (m *module) close() {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	if m.refs--; m.refs > 0 {
+		return
+	}
+	delete(modules, m.path)
+	if m.finalize {
+		C.finalize(m.f)
+	}
+	C.unload(m.handle)
+}
+
+// findSlot returns the slot holding the token labeled label.
+func (m *module) findSlot(label string) (C.CK_SLOT_ID, error) {
+	var n C.CK_ULONG
+	if err := check("C_GetSlotList", C.getSlotList(m.f, nil, &n)); err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, errors.E(errors.NotExist, errors.Str("no token present"))
+	}
+	slots := make([]C.CK_SLOT_ID, n)
+	if err := check("C_GetSlotList", C.getSlotList(m.f, &slots[0], &n)); err != nil {
+		return 0, err
+	}
+	var buf [32]C.CK_BYTE
+	for _, slot := range slots[:n] {
+		if err := check("C_GetTokenInfo", C.getTokenLabel(m.f, slot, &buf[0])); err != nil {
+			return 0, err
+		}
+		// Labels are padded with blanks.
+		l := C.GoBytes(unsafe.Pointer(&buf[0]), C.int(len(buf)))
+		if string(bytes.TrimRight(l, " ")) == label {
+			return slot, nil
+		}
+	}
+	return 0, errors.E(errors.NotExist, errors.Errorf("no token labeled %q", label))
+}
+
+// A session is a logged-in PKCS#11 session.
+type session struct {
+	m *module
+	h C.CK_SESSION_HANDLE
+}
+
+func openSession(cfg Config, rw bool) (*session, error) {
+	if cfg.Module == "" {
+		return nil, errors.E(errors.Invalid, errors.Str("no PKCS#11 module"))
+	}
+	m, err := load(cfg.Module)
+	if err != nil {
+		return nil, err
+	}
+	slot := C.CK_SLOT_ID(cfg.Slot)
+	if cfg.TokenLabel != "" {
+		slot, err = m.findSlot(cfg.TokenLabel)
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+	}
+	flags := C.CK_ULONG(ckfSerialSession)
+	if rw {
+		flags |= ckfRWSession
+	}
+	s := &session{m: m}
+	if err := check("C_OpenSession", C.openSession(m.f, slot, flags, &s.h)); err != nil {
+		m.close()
+		return nil, err
+	}
+	pin := C.CBytes([]byte(cfg.PIN))
+	defer C.free(pin)
+	rv := C.login(m.f, s.h, ckuUser, (*C.CK_BYTE)(pin), C.CK_ULONG(len(cfg.PIN)))
+	if rv != ckrUserAlreadyLoggedIn {
+		if err := check("C_Login", rv); err != nil {
+			s.close()
+			return nil, errors.E(errors.Permission, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *session) close() {
+	C.closeSession(s.m.f, s.h)
+	s.m.close()
+}
+
+// attribute is a PKCS#11 attribute and its value.
+type attribute struct {
+	typ   C.CK_ULONG
+	value []byte
+}
+
+func boolAttr(typ C.CK_ULONG, v bool) attribute {
+	if v {
+		return attribute{typ, []byte{1}}
+	}
+	return attribute{typ, []byte{0}}
+}
+
+func ulongAttr(typ C.CK_ULONG, v C.CK_ULONG) attribute {
+	b := C.GoBytes(unsafe.Pointer(&v), C.int(unsafe.Sizeof(v)))
+	return attribute{typ, b}
+}
+
+// template is a list of attributes whose values are in C memory, as
+// PKCS#11 calls may not be given Go pointers inside Go memory.
+type template []C.CK_ATTRIBUTE
+
+func newTemplate(attrs ...attribute) template {
+	t := make(template, len(attrs))
+	for i, a := range attrs {
+		t[i]._type = a.typ
+		t[i].ulValueLen = C.CK_ULONG(len(a.value))
+		if len(a.value) > 0 {
+			t[i].pValue = C.CBytes(a.value)
+		}
+	}
+	return t
+}
+
+func (t template) free() {
+	for _, a := range t {
+		C.free(a.pValue)
+	}
+}
+
+func (t template) ptr() *C.CK_ATTRIBUTE {
+	if len(t) == 0 {
+		return nil
+	}
+	return &t[0]
+}
+
+// find returns the handles of the objects matching the attributes.
+func (s *session) find(attrs ...attribute) ([]C.CK_OBJECT_HANDLE, error) {
+	t := newTemplate(attrs...)
+	defer t.free()
+	var objs [2]C.CK_OBJECT_HANDLE
+	var n C.CK_ULONG
+	rv := C.findObjects(s.m.f, s.h, t.ptr(), C.CK_ULONG(len(t)), &objs[0], C.CK_ULONG(len(objs)), &n)
+	if err := check("C_FindObjects", rv); err != nil {
+		return nil, err
+	}
+	return objs[:n], nil
+}
+
+// attribute returns the value of the attribute typ of obj.
+func (s *session) attribute(obj C.CK_OBJECT_HANDLE, typ C.CK_ULONG) ([]byte, error) {
+	t := template{{_type: typ}}
+	if err := check("C_GetAttributeValue", C.getAttributes(s.m.f, s.h, obj, &t[0], 1)); err != nil {
+		return nil, err
+	}
+	if t[0].ulValueLen == 0 {
+		return nil, nil
+	}
+	t[0].pValue = C.malloc(C.size_t(t[0].ulValueLen))
+	defer t.free()
+	if err := check("C_GetAttributeValue", C.getAttributes(s.m.f, s.h, obj, &t[0], 1)); err != nil {
+		return nil, err
+	}
+	return C.GoBytes(t[0].pValue, C.int(t[0].ulValueLen)), nil
+}
+
+func (s *session) boolAttribute(obj C.CK_OBJECT_HANDLE, typ C.CK_ULONG) (bool, error) {
+	v, err := s.attribute(obj, typ)
+	if err != nil {
+		return false, err
+	}
+	return len(v) == 1 && v[0] != 0, nil
+}
+
+// Token is a factotum.Token holding its key pair on a PKCS#11 token.
+type Token struct {
+	mu     sync.Mutex // Serializes operations in the session.
+	s      *session
+	key    C.CK_OBJECT_HANDLE
+	pub    *ecdsa.PublicKey
+	public upspin.PublicKey
+}
+
+var _ factotum.Token = (*Token)(nil)
+
+// Open logs in to the token identified by cfg and returns a Token using
+// the key pair found there.
+func Open(cfg Config) (*Token, error) {
+	const op errors.Op = "factotum/pkcs11.Open"
+	s, err := openSession(cfg, false)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	t, err := newToken(s, cfg.KeyLabel)
+	if err != nil {
+		s.close()
+		return nil, errors.E(op, err)
+	}
+	return t, nil
+}
+
+func newToken(s *session, label string) (*Token, error) {
+	attrs := []attribute{
+		ulongAttr(ckaClass, ckoPrivateKey),
+		ulongAttr(ckaKeyType, ckkEC),
+	}
+	if label != "" {
+		attrs = append(attrs, attribute{ckaLabel, []byte(label)})
+	}
+	keys, err := s.find(attrs...)
+	if err != nil {
+		return nil, err
+	}
+	switch len(keys) {
+	case 0:
+		return nil, errors.E(errors.NotExist, errors.Errorf("no private key labeled %q", label))
+	case 1:
+	default:
+		return nil, errors.E(errors.Invalid, errors.Errorf("more than one private key labeled %q", label))
+	}
+	key := keys[0]
+
+	// Refuse a key that the token would reveal or would not use.
+	for _, c := range []struct {
+		typ  C.CK_ULONG
+		want bool
+		msg  string
+	}{
+		{ckaSensitive, true, "private key is not sensitive"},
+		{ckaExtractable, false, "private key is extractable"},
+		{ckaSign, true, "private key cannot sign"},
+		{ckaDerive, true, "private key cannot derive"},
+	} {
+		v, err := s.boolAttribute(key, c.typ)
+		if err != nil {
+			return nil, err
+		}
+		if v != c.want {
+			return nil, errors.E(errors.Permission, errors.Str(c.msg))
+		}
+	}
+
+	// Find the public key with the same ID.
+	id, err := s.attribute(key, ckaID)
+	if err != nil {
+		return nil, err
+	}
+	pubs, err := s.find(ulongAttr(ckaClass, ckoPublicKey), attribute{ckaID, id})
+	if err != nil {
+		return nil, err
+	}
+	if len(pubs) != 1 {
+		return nil, errors.E(errors.NotExist, errors.Str("no public key matching private key"))
+	}
+	params, err := s.attribute(pubs[0], ckaECParams)
+	if err != nil {
+		return nil, err
+	}
+	point, err := s.attribute(pubs[0], ckaECPoint)
+	if err != nil {
+		return nil, err
+	}
+	name, pub, err := publicKey(params, point)
+	if err != nil {
+		return nil, err
+	}
+	return &Token{
+		s:      s,
+		key:    key,
+		pub:    pub,
+		public: upspin.PublicKey(fmt.Sprintf("%s\n%s\n%s\n", name, pub.X, pub.Y)),
+	}, nil
+}
+
+// publicKey decodes the CKA_EC_PARAMS and CKA_EC_POINT of a public key.
+func publicKey(params, point []byte) (string, *ecdsa.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return "", nil, errors.E(errors.Invalid, errors.Errorf("bad EC parameters: %v", err))
+	}
+	for _, c := range curveOIDs {
+		if !c.oid.Equal(oid) {
+			continue
+		}
+		// The point should be a DER-encoded OCTET STRING, but some
+		// tokens return it bare.
+		var raw []byte
+		if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+			raw = point
+		}
+		x, y := elliptic.Unmarshal(c.curve, raw)
+		if x == nil {
+			return "", nil, errors.E(errors.Invalid, errors.Str("bad EC point"))
+		}
+		return c.name, &ecdsa.PublicKey{Curve: c.curve, X: x, Y: y}, nil
+	}
+	return "", nil, errors.E(errors.Invalid, errors.Errorf("unsupported curve %v", oid))
+}
+
+// Close logs out of the token and releases the module.
+func (t *Token) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.s != nil {
+		t.s.close()
+		t.s = nil
+	}
+	return nil
+}
+
+// PublicKey implements factotum.Token.
+func (t *Token) PublicKey() upspin.PublicKey {
+	return t.public
+}
+
+// Sign implements factotum.Token.
+func (t *Token) Sign(hash []byte) (r, s *big.Int, err error) {
+	const op errors.Op = "factotum/pkcs11.Sign"
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.s == nil {
+		return nil, nil, errors.E(op, errors.Str("token closed"))
+	}
+	data := C.CBytes(hash)
+	defer C.free(data)
+	var sig [2 * 66]C.CK_BYTE // Large enough for P-521.
+	n := C.CK_ULONG(len(sig))
+	rv := C.sign(t.s.m.f, t.s.h, ckmECDSA, t.key, (*C.CK_BYTE)(data), C.CK_ULONG(len(hash)), &sig[0], &n)
+	if err := check("C_Sign", rv); err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	// The signature is r followed by s, each the length of the order.
+	b := C.GoBytes(unsafe.Pointer(&sig[0]), C.int(n))
+	if len(b)%2 != 0 {
+		return nil, nil, errors.E(op, errors.Errorf("bad signature length %d", len(b)))
+	}
+	r = new(big.Int).SetBytes(b[:len(b)/2])
+	s = new(big.Int).SetBytes(b[len(b)/2:])
+	return r, s, nil
+}
+
+// ECDH implements factotum.Token.
+//
+// CKM_ECDH1_DERIVE yields only the x coordinate of the product. The two
+// candidates for y are told apart by a second derivation from (x, y)+G,
+// whose product is the first plus the public key.
+func (t *Token) ECDH(x, y *big.Int) (sx, sy *big.Int, err error) {
+	const op errors.Op = "factotum/pkcs11.ECDH"
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.s == nil {
+		return nil, nil, errors.E(op, errors.Str("token closed"))
+	}
+	curve := t.pub.Curve
+	p := curve.Params()
+	sx, err = t.derive(x, y)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	sy = ordinate(p, sx)
+	if sy == nil {
+		return nil, nil, errors.E(op, errors.Str("derived point not on curve"))
+	}
+
+	// If (x, y) is -G, (x, y)+G is the point at infinity; use 2G instead.
+	gx, gy := p.Gx, p.Gy
+	px, py := t.pub.X, t.pub.Y
+	qx, qy := curve.Add(x, y, gx, gy)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		gx, gy = curve.Double(gx, gy)
+		px, py = curve.Double(px, py)
+		qx, qy = curve.Add(x, y, gx, gy)
+	}
+	rx, err := t.derive(qx, qy)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	if cx, _ := curve.Add(sx, sy, px, py); cx.Cmp(rx) != 0 {
+		sy.Sub(p.P, sy)
+	}
+	return sx, sy, nil
+}
+
+// derive returns the x coordinate of the product of the private key and
+// the point (x, y). t.mu must be held.
+func (t *Token) derive(x, y *big.Int) (*big.Int, error) {
+	size := (t.pub.Curve.Params().BitSize + 7) / 8
+	pub := C.CBytes(elliptic.Marshal(t.pub.Curve, x, y))
+	defer C.free(pub)
+	tmpl := newTemplate(
+		ulongAttr(ckaClass, ckoSecretKey),
+		ulongAttr(ckaKeyType, ckkGenericSecret),
+		boolAttr(ckaToken, false),
+		boolAttr(ckaSensitive, false),
+		boolAttr(ckaExtractable, true),
+		ulongAttr(ckaValueLen, C.CK_ULONG(size)),
+	)
+	defer tmpl.free()
+	value := template{{_type: ckaValue, pValue: C.malloc(C.size_t(size)), ulValueLen: C.CK_ULONG(size)}}
+	defer value.free()
+	rv := C.derive(t.s.m.f, t.s.h, ckmECDH1Derive, ckdNull, t.key,
+		(*C.CK_BYTE)(pub), C.CK_ULONG(2*size+1),
+		tmpl.ptr(), C.CK_ULONG(len(tmpl)), &value[0])
+	if err := check("C_DeriveKey", rv); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(C.GoBytes(value[0].pValue, C.int(value[0].ulValueLen))), nil
+}
+
+// ordinate returns a y such that (x, y) is on the curve, or nil if there
+// is none. The curves used by Upspin all have a = -3.
+func ordinate(p *elliptic.CurveParams, x *big.Int) *big.Int {
+	y2 := new(big.Int).Mul(x, x)
+	y2.Mul(y2, x)
+	x3 := new(big.Int).Lsh(x, 1)
+	x3.Add(x3, x)
+	y2.Sub(y2, x3)
+	y2.Add(y2, p.B)
+	y2.Mod(y2, p.P)
+	return new(big.Int).ModSqrt(y2, p.P)
+}
+
+// ImportKey stores the software key pair priv on the token identified by
+// cfg, labeled cfg.KeyLabel, with the private key sensitive and
+// unextractable. It is for moving an existing Upspin key to a token; once
+// the key is imported, the secret key file should be destroyed.
+func ImportKey(cfg Config, priv *ecdsa.PrivateKey) error {
+	const op errors.Op = "factotum/pkcs11.ImportKey"
+	var name string
+	var oid []byte
+	for _, c := range curveOIDs {
+		if c.curve == priv.Curve {
+			name = c.name
+			oid, _ = asn1.Marshal(c.oid)
+		}
+	}
+	if name == "" {
+		return errors.E(op, errors.Invalid, errors.Str("unsupported curve"))
+	}
+	size := (priv.Curve.Params().BitSize + 7) / 8
+	point, err := asn1.Marshal(elliptic.Marshal(priv.Curve, priv.X, priv.Y))
+	if err != nil {
+		return errors.E(op, err)
+	}
+	d := priv.D.FillBytes(make([]byte, size))
+	label := []byte(cfg.KeyLabel)
+	// The ID ties the pair together; use the hash of the public key.
+	public := upspin.PublicKey(fmt.Sprintf("%s\n%s\n%s\n", name, priv.X, priv.Y))
+	id := factotum.KeyHash(public)
+
+	s, err := openSession(cfg, true)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer s.close()
+	for _, attrs := range [][]attribute{{
+		ulongAttr(ckaClass, ckoPublicKey),
+		ulongAttr(ckaKeyType, ckkEC),
+		boolAttr(ckaToken, true),
+		boolAttr(ckaVerify, true),
+		attribute{ckaLabel, label},
+		attribute{ckaID, id},
+		attribute{ckaECParams, oid},
+		attribute{ckaECPoint, point},
+	}, {
+		ulongAttr(ckaClass, ckoPrivateKey),
+		ulongAttr(ckaKeyType, ckkEC),
+		boolAttr(ckaToken, true),
+		boolAttr(ckaPrivate, true),
+		boolAttr(ckaSensitive, true),
+		boolAttr(ckaExtractable, false),
+		boolAttr(ckaSign, true),
+		boolAttr(ckaDerive, true),
+		attribute{ckaLabel, label},
+		attribute{ckaID, id},
+		attribute{ckaECParams, oid},
+		attribute{ckaValue, d},
+	}} {
+		t := newTemplate(attrs...)
+		var obj C.CK_OBJECT_HANDLE
+		err := check("C_CreateObject", C.createObject(s.m.f, s.h, t.ptr(), C.CK_ULONG(len(t)), &obj))
+		t.free()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !pkcs11 || !cgo
+// +build !pkcs11 !cgo
+
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// errNoPKCS11 is returned when the binary was built without PKCS#11
+// support. See token.go.
+var errNoPKCS11 = errors.E(errors.NotExist, errors.Str("built without PKCS#11 support; rebuild with cgo and -tags pkcs11"))
+
+// Token is a factotum.Token holding its key pair on a PKCS#11 token.
+// This binary cannot open one.
+type Token struct{}
+
+// Open returns an error: this binary was built without PKCS#11 support.
+func Open(cfg Config) (*Token, error) {
+	return nil, errors.E(errors.Op("factotum/pkcs11.Open"), errNoPKCS11)
+}
+
+// ImportKey returns an error: this binary was built without PKCS#11
+// support.
+func ImportKey(cfg Config, priv *ecdsa.PrivateKey) error {
+	return errors.E(errors.Op("factotum/pkcs11.ImportKey"), errNoPKCS11)
+}
+
+// Close implements io.Closer.
+func (t *Token) Close() error { return nil }
+
+// PublicKey implements factotum.Token.
+func (t *Token) PublicKey() upspin.PublicKey { return "" }
+
+// Sign implements factotum.Token.
+func (t *Token) Sign(hash []byte) (r, s *big.Int, err error) { return nil, nil, errNoPKCS11 }
+
+// ECDH implements factotum.Token.
+func (t *Token) ECDH(x, y *big.Int) (sx, sy *big.Int, err error) { return nil, nil, errNoPKCS11 }
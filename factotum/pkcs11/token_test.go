@@ -0,0 +1,188 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build pkcs11 && cgo
+// +build pkcs11,cgo
+
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	"upspin.io/upspin"
+)
+
+// SoftHSM, a software token, is used for testing if it is installed.
+// UPSPIN_PKCS11_MODULE overrides the locations searched for its module.
+var softHSMModules = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+const (
+	testTokenLabel = "upspin-test"
+	testKeyLabel   = "upspin-key"
+	testPIN        = "1234"
+	testSOPIN      = "123456"
+)
+
+// newSoftHSM initializes a SoftHSM token in a temporary directory and
+// returns a Config for it. It skips the test if SoftHSM is not installed.
+func newSoftHSM(t *testing.T) Config {
+	module := os.Getenv("UPSPIN_PKCS11_MODULE")
+	if module == "" {
+		for _, m := range softHSMModules {
+			if _, err := os.Stat(m); err == nil {
+				module = m
+				break
+			}
+		}
+	}
+	if module == "" {
+		t.Skip("SoftHSM module not found; set UPSPIN_PKCS11_MODULE")
+	}
+	util, err := exec.LookPath("softhsm2-util")
+	if err != nil {
+		t.Skip("softhsm2-util not found")
+	}
+
+	dir := t.TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.Mkdir(tokens, 0700); err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(dir, "softhsm2.conf")
+	err = os.WriteFile(conf, []byte("directories.tokendir = "+tokens+"\nobjectstore.backend = file\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+	out, err := exec.Command(util, "--init-token", "--free", "--label", testTokenLabel,
+		"--so-pin", testSOPIN, "--pin", testPIN).CombinedOutput()
+	if err != nil {
+		t.Fatalf("softhsm2-util: %v\n%s", err, out)
+	}
+	return Config{
+		Module:     module,
+		TokenLabel: testTokenLabel,
+		PIN:        testPIN,
+		KeyLabel:   testKeyLabel,
+	}
+}
+
+// testKey returns the software Factotum and private key in
+// factotum/testdata/ok.
+func testKey(t *testing.T) (upspin.Factotum, *ecdsa.PrivateKey) {
+	dir := filepath.Join("..", "testdata", "ok")
+	soft, err := factotum.NewFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := factotum.ParsePublicKey(soft.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := os.ReadFile(filepath.Join(dir, "secret.upspinkey"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := new(big.Int).SetString(strings.TrimSpace(string(secret)), 10)
+	if !ok {
+		t.Fatal("bad secret key")
+	}
+	return soft, &ecdsa.PrivateKey{PublicKey: *pub, D: d}
+}
+
+func TestSoftHSM(t *testing.T) {
+	cfg := newSoftHSM(t)
+	soft, priv := testKey(t)
+	if err := ImportKey(cfg, priv); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tok.Close()
+	if got, want := tok.PublicKey(), soft.PublicKey(); got != want {
+		t.Fatalf("PublicKey = %q, want %q", got, want)
+	}
+	f, err := factotum.NewFromToken(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256([]byte("hello, token"))
+	sig, err := f.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := factotum.Verify(hash[:], sig, soft.PublicKey()); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+
+	// Check enough points that both choices of y are exercised.
+	curve := elliptic.P256()
+	keyHash := factotum.KeyHash(soft.PublicKey())
+	for i := 0; i < 20; i++ {
+		_, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotX, gotY, err := f.ScalarMult(keyHash, curve, x, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantX, wantY, err := soft.ScalarMult(keyHash, curve, x, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotX.Cmp(wantX) != 0 || gotY.Cmp(wantY) != 0 {
+			t.Fatalf("ScalarMult %d = (%v, %v), want (%v, %v)", i, gotX, gotY, wantX, wantY)
+		}
+	}
+
+	// The token must refuse to reveal the private key.
+	tok.mu.Lock()
+	_, err = tok.s.attribute(tok.key, ckaValue)
+	tok.mu.Unlock()
+	if err == nil {
+		t.Fatal("token revealed the private key")
+	}
+	if e, ok := err.(ckError); !ok || e.rv != ckrAttributeSensitive {
+		t.Fatalf("reading private key: got %v, want CKR_ATTRIBUTE_SENSITIVE", err)
+	}
+}
+
+func TestOpenBadPIN(t *testing.T) {
+	cfg := newSoftHSM(t)
+	_, priv := testKey(t)
+	if err := ImportKey(cfg, priv); err != nil {
+		t.Fatal(err)
+	}
+	cfg.PIN = "0000"
+	if _, err := Open(cfg); !errors.Is(errors.Permission, err) {
+		t.Fatalf("Open with bad PIN: got %v, want permission error", err)
+	}
+}
+
+func TestOpenNoKey(t *testing.T) {
+	cfg := newSoftHSM(t)
+	if _, err := Open(cfg); !errors.Is(errors.NotExist, err) {
+		t.Fatalf("Open with no key: got %v, want NotExist", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package factotum
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/hkdf"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// A Token performs the private key operations of a single key pair held
+// on a device, such as a hardware security module or a smart card accessed
+// through PKCS#11, that never reveals the private key.
+//
+// The device should hold the private key as sensitive and unextractable
+// and permit only the two operations below: ECDSA signing (CKM_ECDSA)
+// and elliptic-curve Diffie-Hellman derivation (CKM_ECDH1_DERIVE).
+// ScalarMult in particular exposes the bare private key operation, so the
+// Factotum returned by NewFromToken checks every point it is given before
+// passing it to the token.
+//
+// Package upspin.io/factotum/pkcs11 provides a Token for PKCS#11 devices.
+type Token interface {
+	// PublicKey returns the public key of the pair held by the token.
+	PublicKey() upspin.PublicKey
+
+	// Sign returns the ECDSA signature of hash.
+	Sign(hash []byte) (r, s *big.Int, err error)
+
+	// ECDH returns the product of the private key and the point (x, y),
+	// which is known to be on the key's curve.
+	ECDH(x, y *big.Int) (sx, sy *big.Int, err error)
+}
+
+// tokenFactotum implements upspin.Factotum using a Token.
+type tokenFactotum struct {
+	token   Token
+	public  upspin.PublicKey
+	keyHash []byte
+	key     *ecdsa.PublicKey
+}
+
+var _ upspin.Factotum = (*tokenFactotum)(nil)
+
+// NewFromToken returns a new Factotum that performs its private key
+// operations using the token. Since the token holds only one key, the
+// Factotum has no archived keys and Pop returns the Factotum itself.
+//
+// The secret mixed by HKDF is derived by the token from its private key,
+// so HKDF results differ from those of a Factotum holding the same key
+// in software.
+func NewFromToken(t Token) (upspin.Factotum, error) {
+	const op errors.Op = "factotum.NewFromToken"
+	pub := t.PublicKey()
+	key, err := ParsePublicKey(pub)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &tokenFactotum{
+		token:   t,
+		public:  pub,
+		keyHash: KeyHash(pub),
+		key:     key,
+	}, nil
+}
+
+// DirEntryHash implements upspin.Factotum.
+func (f *tokenFactotum) DirEntryHash(n, l upspin.PathName, a upspin.Attribute, p upspin.Packing, t upspin.Time, dkey, hash []byte) upspin.DEHash {
+	// The hash does not depend on the keys.
+	return factotum{}.DirEntryHash(n, l, a, p, t, dkey, hash)
+}
+
+// FileSign implements upspin.Factotum.
+func (f *tokenFactotum) FileSign(hash upspin.DEHash) (upspin.Signature, error) {
+	const op errors.Op = "factotum.FileSign"
+	r, s, err := f.token.Sign(hash)
+	if err != nil {
+		return sig0, errors.E(op, errors.IO, err)
+	}
+	return upspin.Signature{R: r, S: s}, nil
+}
+
+// ScalarMult implements upspin.Factotum. It refuses points not on the
+// curve of the token's key, so the token cannot be used as an oracle to
+// recover the private key.
+func (f *tokenFactotum) ScalarMult(keyHash []byte, curve elliptic.Curve, x, y *big.Int) (sx, sy *big.Int, err error) {
+	const op errors.Op = "factotum.ScalarMult"
+	if !bytes.Equal(keyHash, f.keyHash) {
+		return nil, nil, errors.E(op, errors.Errorf("no such key %x", keyHash))
+	}
+	if curve.Params().Name != f.key.Curve.Params().Name || !f.key.Curve.IsOnCurve(x, y) {
+		return nil, nil, errNotOnCurve
+	}
+	sx, sy, err = f.token.ECDH(x, y)
+	if err != nil {
+		return nil, nil, errors.E(op, errors.IO, err)
+	}
+	return sx, sy, nil
+}
+
+// Sign implements upspin.Factotum.
+func (f *tokenFactotum) Sign(hash []byte) (upspin.Signature, error) {
+	const op errors.Op = "factotum.Sign"
+	curveLength := (f.key.Curve.Params().N.BitLen() + 7) / 8
+	if len(hash) > curveLength {
+		return sig0, errors.E(op, errors.Invalid, "hash is too long to Sign")
+	}
+	r, s, err := f.token.Sign(hash)
+	if err != nil {
+		return sig0, errors.E(op, errors.IO, err)
+	}
+	return upspin.Signature{R: r, S: s}, nil
+}
+
+// HKDF implements upspin.Factotum. The secret it mixes is the product
+// of the private key and the public key, which only the token can compute.
+func (f *tokenFactotum) HKDF(salt, info, out []byte) error {
+	const op errors.Op = "factotum.HKDF"
+	sx, sy, err := f.token.ECDH(f.key.X, f.key.Y)
+	if err != nil {
+		return errors.E(op, errors.IO, err)
+	}
+	secret := elliptic.Marshal(f.key.Curve, sx, sy)
+	hkdf := hkdf.New(sha256.New, secret, salt, info)
+	_, err = io.ReadFull(hkdf, out)
+	return err
+}
+
+// Pop implements upspin.Factotum.
+func (f *tokenFactotum) Pop() upspin.Factotum {
+	return f
+}
+
+// PublicKey implements upspin.Factotum.
+func (f *tokenFactotum) PublicKey() upspin.PublicKey {
+	return f.public
+}
+
+// PublicKeyFromHash implements upspin.Factotum.
+func (f *tokenFactotum) PublicKeyFromHash(keyHash []byte) (upspin.PublicKey, error) {
+	const op errors.Op = "factotum.PublicKeyFromHash"
+	if len(keyHash) == 0 {
+		return "", errors.E(op, errors.Invalid, "invalid keyHash")
+	}
+	if !bytes.Equal(keyHash, f.keyHash) {
+		return "", errors.E(op, errors.NotExist, "no such key")
+	}
+	return f.public, nil
+}
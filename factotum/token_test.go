@@ -0,0 +1,183 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package factotum
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"upspin.io/upspin"
+)
+
+// softToken is a Token that holds its key in memory. It counts the
+// operations it performs so tests can check what reaches the token.
+type softToken struct {
+	public upspin.PublicKey
+	key    *ecdsa.PrivateKey
+	signs  int
+	ecdhs  int
+}
+
+func (t *softToken) PublicKey() upspin.PublicKey {
+	return t.public
+}
+
+func (t *softToken) Sign(hash []byte) (r, s *big.Int, err error) {
+	t.signs++
+	return ecdsa.Sign(rand.Reader, t.key, hash)
+}
+
+func (t *softToken) ECDH(x, y *big.Int) (sx, sy *big.Int, err error) {
+	t.ecdhs++
+	sx, sy = t.key.Curve.ScalarMult(x, y, t.key.D.Bytes())
+	return sx, sy, nil
+}
+
+// newTokenForTesting returns a software Factotum and a Factotum backed
+// by a softToken holding the same key.
+func newTokenForTesting(t *testing.T) (soft upspin.Factotum, token *softToken, f upspin.Factotum) {
+	soft, err := NewFromDir(filepath.Join("testdata", "ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fk := soft.(*factotum).keys[soft.(*factotum).current]
+	token = &softToken{
+		public: fk.public,
+		key:    &fk.ecdsaKeyPair,
+	}
+	f, err = NewFromToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return soft, token, f
+}
+
+func TestTokenSign(t *testing.T) {
+	soft, token, f := newTokenForTesting(t)
+	if f.PublicKey() != soft.PublicKey() {
+		t.Fatalf("PublicKey = %q, want %q", f.PublicKey(), soft.PublicKey())
+	}
+
+	// Signatures from the token and the software key both verify
+	// against the same public key.
+	hash := sha256.Sum256([]byte("the wren earns his living noiselessly"))
+	for _, fact := range []upspin.Factotum{soft, f} {
+		sig, err := fact.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(hash[:], sig, f.PublicKey()); err != nil {
+			t.Errorf("Sign: %v", err)
+		}
+	}
+	deHash := f.DirEntryHash("ann@example.com/file", "", upspin.AttrNone, upspin.EEPack, 17, []byte("dkey"), hash[:])
+	if want := soft.DirEntryHash("ann@example.com/file", "", upspin.AttrNone, upspin.EEPack, 17, []byte("dkey"), hash[:]); !bytes.Equal(deHash, want) {
+		t.Errorf("DirEntryHash = %x, want %x", deHash, want)
+	}
+	sig, err := f.FileSign(deHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(deHash, sig, soft.PublicKey()); err != nil {
+		t.Errorf("FileSign: %v", err)
+	}
+	if token.signs != 2 {
+		t.Errorf("token performed %d signatures, want 2", token.signs)
+	}
+
+	// A hash that is too long never reaches the token.
+	if _, err := f.Sign([]byte("this is too long a string for p256")); err == nil {
+		t.Errorf("Sign(long string) succeeded")
+	}
+	if token.signs != 2 {
+		t.Errorf("token signed a hash that is too long")
+	}
+}
+
+func TestTokenScalarMult(t *testing.T) {
+	soft, token, f := newTokenForTesting(t)
+	keyHash := KeyHash(f.PublicKey())
+	if pub, err := f.PublicKeyFromHash(keyHash); err != nil || pub != f.PublicKey() {
+		t.Errorf("PublicKeyFromHash = %q, %v; want %q, nil", pub, err, f.PublicKey())
+	}
+
+	// The token computes the same point as the software key.
+	curve := elliptic.P256()
+	eph, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sx, sy, err := f.ScalarMult(keyHash, curve, eph.X, eph.Y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wx, wy, err := soft.ScalarMult(keyHash, curve, eph.X, eph.Y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sx.Cmp(wx) != 0 || sy.Cmp(wy) != 0 {
+		t.Errorf("ScalarMult = (%v, %v), want (%v, %v)", sx, sy, wx, wy)
+	}
+	if token.ecdhs != 1 {
+		t.Fatalf("token performed %d derivations, want 1", token.ecdhs)
+	}
+
+	// Requests the token must not see are refused before reaching it.
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := []struct {
+		name    string
+		keyHash []byte
+		curve   elliptic.Curve
+		x, y    *big.Int
+	}{
+		{"unknown key", KeyHash("p256\n1\n2\n"), curve, eph.X, eph.Y},
+		{"point off curve", keyHash, curve, eph.X, new(big.Int).Add(eph.Y, big.NewInt(1))},
+		{"other curve", keyHash, elliptic.P384(), p384.X, p384.Y},
+	}
+	for _, test := range bad {
+		if _, _, err := f.ScalarMult(test.keyHash, test.curve, test.x, test.y); err == nil {
+			t.Errorf("%s: ScalarMult succeeded", test.name)
+		}
+	}
+	if token.ecdhs != 1 {
+		t.Errorf("token performed %d derivations, want 1", token.ecdhs)
+	}
+	if _, err := f.PublicKeyFromHash(KeyHash("p256\n1\n2\n")); err == nil {
+		t.Errorf("PublicKeyFromHash(unknown key) succeeded")
+	}
+}
+
+func TestTokenHKDF(t *testing.T) {
+	_, _, f := newTokenForTesting(t)
+	out1 := make([]byte, 16)
+	out2 := make([]byte, 16)
+	if err := f.HKDF([]byte("salt"), []byte("info"), out1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.HKDF([]byte("salt"), []byte("info"), out2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out1, out2) {
+		t.Errorf("HKDF is not deterministic: %x != %x", out1, out2)
+	}
+	if err := f.HKDF([]byte("salt"), []byte("other info"), out2); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(out1, out2) {
+		t.Errorf("HKDF gave the same result for different info")
+	}
+	if f.Pop() != f {
+		t.Errorf("Pop returned a different Factotum")
+	}
+}
@@ -105,6 +105,10 @@ var (
 	// Log ("log") sets the level of logging (implements flag.Value).
 	Log logFlag
 
+	// Metrics ("metrics") specifies whether a server serves its metrics
+	// at /metrics in the Prometheus text format.
+	Metrics = false
+
 	// NetAddr ("addr") is the publicly accessible network address of this
 	// server.
 	NetAddr = ""
@@ -186,6 +190,17 @@ var flags = map[string]*flagVar{
 		},
 		arg: func() string { return strArg("log", Log.String(), defaultLog) },
 	},
+	"metrics": &flagVar{
+		set: func(fs *flag.FlagSet) {
+			fs.BoolVar(&Metrics, "metrics", false, "serve metrics at /metrics in Prometheus text format")
+		},
+		arg: func() string {
+			if !Metrics {
+				return ""
+			}
+			return "-metrics"
+		},
+	},
 	"serverconfig": &flagVar{
 		set: func(fs *flag.FlagSet) {
 			fs.Var(configFlag{&ServerConfig}, "serverconfig", "comma-separated list of configuration options (key=value) for this server")
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"upspin.io/errors"
 	"upspin.io/key/proquint"
@@ -105,26 +106,89 @@ func writeKeyFile(name, key string) error {
 	return fd.Close()
 }
 
+// A keyFile holds the contents to be written to a key file.
+type keyFile struct {
+	name string
+	data string
+}
+
+// replaceKeyFiles writes the files into the directory, replacing any
+// existing files of the same names. Each file is first written in full
+// under a temporary name, so an error writing any of them leaves all the
+// existing files untouched.
+func replaceKeyFiles(where string, files []keyFile) error {
+	const newSuffix = ".new"
+	for i, f := range files {
+		err := writeKeyFile(filepath.Join(where, f.name+newSuffix), f.data)
+		if err != nil {
+			for _, f := range files[:i+1] {
+				os.Remove(filepath.Join(where, f.name+newSuffix))
+			}
+			return err
+		}
+	}
+	for _, f := range files {
+		name := filepath.Join(where, f.name)
+		err := os.Rename(name+newSuffix, name)
+		if os.IsPermission(err) && os.Remove(name) == nil {
+			// Rename may fail if the file exists and is unwritable.
+			err = os.Rename(name+newSuffix, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // writeKeys saves both the public and private keys to their respective files.
 // If secretStr is non-empty it is appended as a comment to the private key.
 // writeKeys will overwrite any existing keys.
 func writeKeys(where, publicKey, privateKey, secretStr string) error {
+	return replaceKeyFiles(where, newKeyFiles(publicKey, privateKey, secretStr))
+}
+
+// newKeyFiles returns the files holding the public and private keys.
+// If secretStr is non-empty it is appended as a comment to the private key.
+func newKeyFiles(publicKey, privateKey, secretStr string) []keyFile {
 	if secretStr != "" {
 		privateKey = strings.TrimSpace(privateKey) + " # " + secretStr + "\n"
 	}
-	err := writeKeyFile(filepath.Join(where, "secret.upspinkey"), privateKey)
-	if err != nil {
-		return err
+	return []keyFile{
+		{"secret.upspinkey", privateKey},
+		{"public.upspinkey", publicKey},
 	}
-	return writeKeyFile(filepath.Join(where, "public.upspinkey"), publicKey)
 }
 
 // SaveKeys writes the provided public and private keys to the given directory,
-// rotating them if requested by appending the old secret to secret2.upspinkey.
+// rotating them if requested as described for RotateKeys.
 // If secretStr is non-empty it is appended as a comment to the private key.
 // If rotate is false and there are existing keys, SaveKeys returns an error.
 // If rotate is true and there are no existing keys, SaveKeys returns an error.
 func SaveKeys(where string, rotate bool, newPublic, newPrivate, secretStr string) error {
+	if rotate {
+		_, err := RotateKeys(where, newPublic, newPrivate, secretStr)
+		return err
+	}
+	_, err := os.Stat(filepath.Join(where, "secret.upspinkey"))
+	if err == nil {
+		return errors.Errorf("prior keys exist in %s; rerun with rotate command to update keys", where)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	return writeKeys(where, newPublic, newPrivate, secretStr)
+}
+
+// RotateKeys replaces the key pair in the given directory with the provided
+// one, appending the old pair to secret2.upspinkey. Before changing anything,
+// it copies the existing key files, including any archives, to a new
+// subdirectory of where/backup named for the current time, and returns the
+// name of that subdirectory. The new files are written in full before any
+// existing file is replaced, so an error leaves the existing keys in place.
+// If there are no existing keys, RotateKeys returns an error. If the
+// existing keys are the new ones, it does nothing and returns an empty name.
+func RotateKeys(where, newPublic, newPrivate, secretStr string) (backup string, err error) {
 	var (
 		publicFile  = filepath.Join(where, "public.upspinkey")
 		privateFile = filepath.Join(where, "secret.upspinkey")
@@ -134,52 +198,80 @@ func SaveKeys(where string, rotate bool, newPublic, newPrivate, secretStr string
 	// Read existing key pair.
 	private, err := os.ReadFile(privateFile)
 	if os.IsNotExist(err) {
-		// There is nothing to save. Did we expect there to be?
-		if rotate {
-			return errors.Errorf("cannot rotate keys: no prior keys exist in %s", where)
-		}
-		// We didn't expect key rotation, so just write the new keys.
-		return writeKeys(where, newPublic, newPrivate, secretStr)
+		return "", errors.Errorf("cannot rotate keys: no prior keys exist in %s", where)
 	}
 	if err != nil {
-		return err
-	}
-
-	// There's an existing keypair. Did we not expect it?
-	if !rotate {
-		return errors.Errorf("prior keys exist in %s; rerun with rotate command to update keys", where)
+		return "", err
 	}
-
 	public, err := os.ReadFile(publicFile)
 	if err != nil {
-		return err // Halt. Existing files are corrupted and need manual attention.
+		return "", err // Halt. Existing files are corrupted and need manual attention.
 	}
 	if string(public) == newPublic && string(private) == newPrivate {
-		return nil // No need to save duplicates.
+		return "", nil // No need to save duplicates.
 	}
 
-	// Write old key pair to archive file.
-	archive, err := os.OpenFile(archiveFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	backup, err = backupKeys(where)
 	if err != nil {
-		return err // We don't have permission to archive old keys?
+		return "", err
 	}
 
+	// Append the old key pair to the archive.
+	archive, err := os.ReadFile(archiveFile)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
 	var modtime string
 	info, err := os.Stat(privateFile)
-	if err != nil {
-		modtime = ""
-	} else {
+	if err == nil {
 		modtime = info.ModTime().UTC().Format(" 2006-01-02 15:04:05Z")
 	}
-	_, err = fmt.Fprintf(archive, "# EE%s\n%s%s", modtime, public, private)
-	if err != nil {
-		return err
-	}
-	err = archive.Close()
-	if err != nil {
-		return err
+	archive = append(archive, fmt.Sprintf("# EE%s\n%s%s", modtime, public, private)...)
+
+	// Write the archive and the new keys.
+	files := append([]keyFile{{"secret2.upspinkey", string(archive)}}, newKeyFiles(newPublic, newPrivate, secretStr)...)
+	if err := replaceKeyFiles(where, files); err != nil {
+		return "", errors.Errorf("%v; previous keys are saved in %s", err, backup)
 	}
+	return backup, nil
+}
 
-	// Write the new keys.
-	return writeKeys(where, newPublic, newPrivate, secretStr)
+// backupKeys copies the key files in the directory, and any archives,
+// to a new subdirectory of where/backup named for the current time and
+// returns its name.
+func backupKeys(where string) (string, error) {
+	stamp := filepath.Join(where, "backup", time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.MkdirAll(filepath.Dir(stamp), 0700); err != nil {
+		return "", err
+	}
+	// Rotations within the same second get a numeric suffix.
+	backup := stamp
+	for i := 2; ; i++ {
+		err := os.Mkdir(backup, 0700)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		backup = fmt.Sprintf("%s.%d", stamp, i)
+	}
+	names := []string{"public.upspinkey", "secret.upspinkey"}
+	for i := 2; ; i++ {
+		name := fmt.Sprintf("secret%d.upspinkey", i)
+		if _, err := os.Stat(filepath.Join(where, name)); err != nil {
+			break
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(where, name))
+		if err != nil {
+			return "", err
+		}
+		if err := writeKeyFile(filepath.Join(backup, name), string(data)); err != nil {
+			return "", err
+		}
+	}
+	return backup, nil
 }
@@ -5,6 +5,8 @@
 package keygen
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -98,3 +100,73 @@ func TestValidSecretSeed(t *testing.T) {
 		}
 	}
 }
+
+func TestRotateKeys(t *testing.T) {
+	const (
+		seed1 = "latoj-katuf-kijuh-latuh.lanon-kunol-kinoz-lanuj"
+		seed2 = "disis-valid-fosoh-matij.disis-valid-fosoh-matij"
+	)
+	dir := t.TempDir()
+	public1, private1, _, err := FromSecret("p256", seed1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKeys(dir, false, public1, private1, ""); err != nil {
+		t.Fatal(err)
+	}
+	public2, private2, _, err := FromSecret("p256", seed2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(dir, name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// A failure to write any of the new files leaves the old keys in place.
+	if err := os.Mkdir(filepath.Join(dir, "public.upspinkey.new"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RotateKeys(dir, public2, private2, ""); err == nil {
+		t.Fatal("RotateKeys succeeded despite unwritable public key")
+	}
+	if got := read(dir, "public.upspinkey"); got != public1 {
+		t.Errorf("public key after failed rotation = %q, want %q", got, public1)
+	}
+	if got := read(dir, "secret.upspinkey"); got != private1 {
+		t.Errorf("secret key after failed rotation = %q, want %q", got, private1)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret2.upspinkey")); !os.IsNotExist(err) {
+		t.Errorf("secret2.upspinkey exists after failed rotation: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "public.upspinkey.new"))
+
+	// A successful rotation backs up the old keys first.
+	backup, err := RotateKeys(dir, public2, private2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(backup) != filepath.Join(dir, "backup") {
+		t.Errorf("backup directory %q not in %q", backup, filepath.Join(dir, "backup"))
+	}
+	if got := read(backup, "public.upspinkey"); got != public1 {
+		t.Errorf("backed up public key = %q, want %q", got, public1)
+	}
+	if got := read(backup, "secret.upspinkey"); got != private1 {
+		t.Errorf("backed up secret key = %q, want %q", got, private1)
+	}
+	if got := read(dir, "public.upspinkey"); got != public2 {
+		t.Errorf("public key after rotation = %q, want %q", got, public2)
+	}
+	if got := read(dir, "secret2.upspinkey"); !strings.HasSuffix(got, public1+private1) {
+		t.Errorf("secret2.upspinkey = %q, want old keys at end", got)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.new"))
+	if len(matches) > 0 {
+		t.Errorf("temporary files left behind: %q", matches)
+	}
+}
@@ -9,6 +9,8 @@ package remote // import "upspin.io/key/remote"
 import (
 	"fmt"
 
+	pb "github.com/golang/protobuf/proto"
+
 	"upspin.io/bind"
 	"upspin.io/errors"
 	"upspin.io/key/usercache"
@@ -30,7 +32,12 @@ type remote struct {
 	cfg        dialConfig
 }
 
-var _ upspin.KeyServer = (*remote)(nil)
+var (
+	_ upspin.KeyServer        = (*remote)(nil)
+	_ upspin.KeyLookupBatcher = (*remote)(nil)
+	_ upspin.KeyWatcher       = (*remote)(nil)
+	_ upspin.KeyRotator       = (*remote)(nil)
+)
 
 // Lookup implements upspin.Key.Lookup.
 func (r *remote) Lookup(name upspin.UserName) (*upspin.User, error) {
@@ -50,6 +57,40 @@ func (r *remote) Lookup(name upspin.UserName) (*upspin.User, error) {
 	return proto.UpspinUser(resp.User), nil
 }
 
+// LookupBatch implements upspin.KeyLookupBatcher.
+func (r *remote) LookupBatch(names []upspin.UserName) ([]*upspin.User, []error) {
+	op := r.opf("LookupBatch", "%q", names)
+
+	users := make([]*upspin.User, len(names))
+	errs := make([]error, len(names))
+	req := &proto.KeyLookupBatchRequest{
+		UserNames: make([]string, len(names)),
+	}
+	for i, name := range names {
+		req.UserNames[i] = string(name)
+	}
+	resp := new(proto.KeyLookupBatchResponse)
+	err := r.InvokeUnauthenticated("Key/LookupBatch", req, resp)
+	if err == nil && len(resp.Responses) != len(names) {
+		err = errors.E(errors.IO, errors.Errorf("got %d responses for %d users", len(resp.Responses), len(names)))
+	}
+	if err != nil {
+		err = op.error(err)
+		for i := range errs {
+			errs[i] = err
+		}
+		return users, errs
+	}
+	for i, lr := range resp.Responses {
+		if len(lr.Error) != 0 {
+			errs[i] = op.error(errors.UnmarshalError(lr.Error))
+			continue
+		}
+		users[i] = proto.UpspinUser(lr.User)
+	}
+	return users, errs
+}
+
 func userName(user *upspin.User) string {
 	if user == nil {
 		return "<nil>"
@@ -74,6 +115,117 @@ func (r *remote) Put(user *upspin.User) error {
 	return nil
 }
 
+// Rotate implements upspin.KeyRotator.
+func (r *remote) Rotate(user *upspin.User, sig upspin.Signature) error {
+	op := r.opf("Rotate", "%v", userName(user))
+
+	req := &proto.KeyRotateRequest{
+		User: proto.UserProto(user),
+	}
+	req.SignatureR, req.SignatureS = proto.SignatureBytes(sig)
+	resp := new(proto.KeyPutResponse)
+	if err := r.Invoke("Key/Rotate", req, resp, nil, nil); err != nil {
+		if err == upspin.ErrNotSupported {
+			return err
+		}
+		return op.error(err)
+	}
+	if len(resp.Error) != 0 {
+		return op.error(errors.UnmarshalError(resp.Error))
+	}
+	return nil
+}
+
+// KeyHistory implements upspin.KeyRotator.
+func (r *remote) KeyHistory(name upspin.UserName) ([]upspin.KeyRotation, error) {
+	op := r.opf("KeyHistory", "%q", name)
+
+	req := &proto.KeyLookupRequest{
+		UserName: string(name),
+	}
+	resp := new(proto.KeyHistoryResponse)
+	if err := r.Invoke("Key/KeyHistory", req, resp, nil, nil); err != nil {
+		if err == upspin.ErrNotSupported {
+			return nil, err
+		}
+		return nil, op.error(err)
+	}
+	if len(resp.Error) != 0 {
+		return nil, op.error(errors.UnmarshalError(resp.Error))
+	}
+	history := make([]upspin.KeyRotation, len(resp.Rotations))
+	for i, rot := range resp.Rotations {
+		history[i] = proto.UpspinKeyRotation(rot)
+	}
+	return history, nil
+}
+
+// Watch implements upspin.KeyWatcher.
+func (r *remote) Watch(sequence int64, done <-chan struct{}) (<-chan upspin.KeyEvent, error) {
+	op := r.opf("Watch", "sequence %d", sequence)
+	req := &proto.KeyWatchRequest{
+		Sequence: sequence,
+	}
+
+	stream := make(keyEventStream)
+	events := make(chan upspin.KeyEvent)
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case ep, ok := <-stream:
+				if !ok {
+					return
+				}
+				e := upspin.KeyEvent{
+					Sequence: ep.Sequence,
+					Error:    errors.UnmarshalError(ep.Error),
+				}
+				if ep.User != nil {
+					e.User = proto.UpspinUser(ep.User)
+				}
+				select {
+				case events <- e:
+				case <-done:
+					return
+				}
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := r.Invoke("Key/Watch", req, nil, stream, done); err != nil {
+		close(stream)
+		if err == upspin.ErrNotSupported {
+			return nil, err
+		}
+		return nil, op.error(err)
+	}
+	return events, nil
+}
+
+type keyEventStream chan proto.KeyEvent
+
+func (s keyEventStream) Send(b []byte, done <-chan struct{}) error {
+	var e proto.KeyEvent
+	if err := pb.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	select {
+	case s <- e:
+	case <-done:
+	}
+	return nil
+}
+
+func (s keyEventStream) Close() { close(s) }
+
+func (s keyEventStream) Error(err error) {
+	s <- proto.KeyEvent{Error: errors.MarshalError(err)}
+}
+
 // Endpoint implements upspin.StoreServer.Endpoint.
 func (r *remote) Endpoint() upspin.Endpoint {
 	return r.cfg.endpoint
@@ -9,6 +9,8 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -135,3 +137,37 @@ func (l *loggerImpl) populate() error {
 	l.log = data
 	return nil
 }
+
+// putsFromLog returns the user records stored by the successful Puts
+// recorded in the log data, in the order they were stored.
+func putsFromLog(data []byte) ([]upspin.User, error) {
+	const successBy = "put success by "
+	var users []upspin.User
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil, errors.Str("key log corrupt: record lacks newline")
+		}
+		line := string(data[:i])
+		data = data[i+1:]
+
+		// A record has the form "time: kind by "actor": user",
+		// and the time contains no colon followed by a space.
+		i = strings.Index(line, ": ")
+		if i < 0 || !strings.HasPrefix(line[i+2:], successBy) {
+			// A hash or a record of a Put attempt.
+			continue
+		}
+		line = line[i+2+len(successBy):]
+		actor, err := strconv.QuotedPrefix(line)
+		if err != nil || !strings.HasPrefix(line[len(actor):], ": ") {
+			return nil, errors.Errorf("key log corrupt: bad record %q", line)
+		}
+		var u upspin.User
+		if err := json.Unmarshal([]byte(line[len(actor)+2:]), &u); err != nil {
+			return nil, errors.Errorf("key log corrupt: %v", err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"upspin.io/cloud/storage/storagetest"
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	"upspin.io/upspin"
+)
+
+func TestRotate(t *testing.T) {
+	const (
+		ann   = upspin.UserName("ann@example.com")
+		admin = upspin.UserName("admin@example.com")
+	)
+	oldF, oldKey, _, err := factotum.Generate("p256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newF, newKey, _, err := factotum.Generate("p256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newMemoryKeyServer(storagetest.Memory())
+	err = s.putUserEntry("test", &userEntry{User: upspin.User{Name: admin, PublicKey: "admin key"}, IsAdmin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	annSvc := *s
+	annSvc.user = ann
+	if err := annSvc.Put(&upspin.User{Name: ann, PublicKey: oldKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ann cannot change her key with Put.
+	err = annSvc.Put(&upspin.User{Name: ann, PublicKey: newKey})
+	if !errors.Is(errors.Permission, err) {
+		t.Fatalf("Put with new key: got error %v, want Permission", err)
+	}
+
+	// Nor with a rotation signed by the wrong key.
+	badSig, err := newF.Sign(factotum.RotationHash(ann, oldKey, newKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = annSvc.Rotate(&upspin.User{Name: ann, PublicKey: newKey}, badSig)
+	if !errors.Is(errors.Permission, err) {
+		t.Fatalf("Rotate signed by new key: got error %v, want Permission", err)
+	}
+
+	// A rotation signed by the old key is accepted.
+	sig, err := oldF.Sign(factotum.RotationHash(ann, oldKey, newKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := annSvc.Rotate(&upspin.User{Name: ann, PublicKey: newKey}, sig); err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.Lookup(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.PublicKey != newKey {
+		t.Errorf("key after Rotate is %q, want %q", u.PublicKey, newKey)
+	}
+	// Repeating the rotation is a no-op, so it is not recorded again.
+	if err := annSvc.Rotate(&upspin.User{Name: ann, PublicKey: newKey}, sig); err != nil {
+		t.Errorf("Rotate to the same key: %v", err)
+	}
+
+	// Later Puts keep the history.
+	dirs := []upspin.Endpoint{{Transport: upspin.Remote, NetAddr: "dir.example.com:443"}}
+	if err := annSvc.Put(&upspin.User{Name: ann, Dirs: dirs, PublicKey: newKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An administrator can replace the key without a signature.
+	adminSvc := *s
+	adminSvc.user = admin
+	if err := adminSvc.Put(&upspin.User{Name: ann, Dirs: dirs, PublicKey: "reset key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := s.KeyHistory(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d rotations, want 2: %v", len(history), history)
+	}
+	if history[0].Previous != oldKey || history[0].PublicKey != newKey {
+		t.Errorf("first rotation is from %q to %q, want from %q to %q", history[0].Previous, history[0].PublicKey, oldKey, newKey)
+	}
+	if err := factotum.VerifyRotation(ann, history[0]); err != nil {
+		t.Errorf("first rotation: %v", err)
+	}
+	if history[1].Previous != newKey || history[1].PublicKey != "reset key" {
+		t.Errorf("second rotation is from %q to %q, want from %q to %q", history[1].Previous, history[1].PublicKey, newKey, "reset key")
+	}
+	if err := factotum.VerifyRotation(ann, history[1]); err == nil {
+		t.Errorf("unsigned rotation verified")
+	}
+
+	// A new server reads the history from storage.
+	restarted := newMemoryKeyServer(s.storage)
+	history, err = restarted.KeyHistory(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || factotum.VerifyRotation(ann, history[0]) != nil {
+		t.Errorf("history after restart is %v", history)
+	}
+}
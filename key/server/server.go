@@ -55,6 +55,7 @@ func New(options ...string) (upspin.KeyServer, error) {
 		refCount:  &refCount{count: 1},
 		lookupTXT: net.LookupTXT,
 		logger:    &loggerImpl{storage: s},
+		keyLog:    &keyLog{},
 		cache:     cache.NewLRU(cacheSize),
 		negCache:  cache.NewLRU(cacheSize),
 	}, nil
@@ -68,6 +69,10 @@ type server struct {
 	// A text log of all mutations to the key server.
 	logger
 
+	// The user records stored by Puts, for watchers.
+	// It is shared by all instances of the server.
+	keyLog *keyLog
+
 	// The name of the user accessing this server, set by Dial.
 	user upspin.UserName
 
@@ -83,7 +88,12 @@ type server struct {
 	negCache *cache.LRU
 }
 
-var _ upspin.KeyServer = (*server)(nil)
+var (
+	_ upspin.KeyServer        = (*server)(nil)
+	_ upspin.KeyLookupBatcher = (*server)(nil)
+	_ upspin.KeyWatcher       = (*server)(nil)
+	_ upspin.KeyRotator       = (*server)(nil)
+)
 
 type refCount struct {
 	sync.Mutex
@@ -91,10 +101,12 @@ type refCount struct {
 }
 
 // userEntry is the on-disk representation of upspin.User, further annotated with
-// non-public information, such as whether the user is an admin.
+// non-public information, such as whether the user is an admin, and with the
+// history of the user's public keys.
 type userEntry struct {
-	User    upspin.User
-	IsAdmin bool
+	User      upspin.User
+	IsAdmin   bool
+	Rotations []upspin.KeyRotation `json:",omitempty"`
 }
 
 // Lookup implements upspin.KeyServer.
@@ -113,6 +125,29 @@ func (s *server) Lookup(name upspin.UserName) (*upspin.User, error) {
 	return &entry.User, nil
 }
 
+// LookupBatch implements upspin.KeyLookupBatcher.
+func (s *server) LookupBatch(names []upspin.UserName) ([]*upspin.User, []error) {
+	const op errors.Op = "key/server.LookupBatch"
+	m, span := metric.NewSpan(op)
+	defer m.Done()
+
+	users := make([]*upspin.User, len(names))
+	errs := make([]error, len(names))
+	for i, name := range names {
+		if err := valid.UserName(name); err != nil {
+			errs[i] = errors.E(op, name, err)
+			continue
+		}
+		entry, err := s.lookup(op, name, span)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		users[i] = &entry.User
+	}
+	return users, errs
+}
+
 // lookup looks up the internal user record, using caches when available.
 func (s *server) lookup(op errors.Op, name upspin.UserName, span *metric.Span) (*userEntry, error) {
 	// Check positive cache first.
@@ -156,7 +191,6 @@ func (s *server) Put(u *upspin.User) error {
 	}
 
 	// Retrieve info about the user we want to Put.
-	isAdmin := false
 	newUser := false
 
 	entry, err := s.lookup(op, u.Name, span)
@@ -164,25 +198,106 @@ func (s *server) Put(u *upspin.User) error {
 	case errors.Is(errors.NotExist, err):
 		// OK; adding new user.
 		newUser = true
+		entry = &userEntry{}
 	case err != nil:
 		return err
-	default:
-		// User exists.
-		isAdmin = entry.IsAdmin
 	}
 
 	if err := s.canPut(op, u.Name, newUser, span); err != nil {
 		return err
 	}
 
+	rotations := entry.Rotations
+	if !newUser && u.PublicKey != entry.User.PublicKey {
+		// Users must rotate their own keys with a signature made
+		// using the old key. Others allowed to Put the record, such
+		// as administrators, replace the key without one.
+		if s.user == u.Name && !entry.IsAdmin {
+			return errors.E(op, errors.Permission, u.Name, "public key can be changed only by a signed rotation")
+		}
+		rotations = appendRotation(rotations, entry.User.PublicKey, u.PublicKey, upspin.Signature{})
+	}
+	newEntry := &userEntry{User: *u, IsAdmin: entry.IsAdmin, Rotations: rotations}
+	return s.store(op, newEntry, span)
+}
+
+// Rotate implements upspin.KeyRotator.
+func (s *server) Rotate(u *upspin.User, sig upspin.Signature) error {
+	const op errors.Op = "key/server.Rotate"
+	m, span := metric.NewSpan(op)
+	defer m.Done()
+
+	if s.user == "" {
+		return errors.E(op, errors.Internal, "not bound to user")
+	}
+	if err := valid.User(u); err != nil {
+		return errors.E(op, err)
+	}
+	entry, err := s.lookup(op, u.Name, span)
+	if err != nil {
+		return err
+	}
+	if err := s.canPut(op, u.Name, false, span); err != nil {
+		return err
+	}
+	if u.PublicKey == entry.User.PublicKey {
+		// Already rotated; rotating again is a no-op.
+		return nil
+	}
+	r := upspin.KeyRotation{
+		Previous:  entry.User.PublicKey,
+		PublicKey: u.PublicKey,
+		Signature: sig,
+	}
+	if err := factotum.VerifyRotation(u.Name, r); err != nil {
+		return errors.E(op, errors.Permission, err)
+	}
+	newEntry := &userEntry{
+		User:      *u,
+		IsAdmin:   entry.IsAdmin,
+		Rotations: appendRotation(entry.Rotations, r.Previous, r.PublicKey, sig),
+	}
+	return s.store(op, newEntry, span)
+}
+
+// appendRotation returns a copy of rotations with the replacement of the
+// previous key by next appended.
+func appendRotation(rotations []upspin.KeyRotation, previous, next upspin.PublicKey, sig upspin.Signature) []upspin.KeyRotation {
+	r := upspin.KeyRotation{
+		Previous:  previous,
+		PublicKey: next,
+		Signature: sig,
+		Time:      upspin.Now(),
+	}
+	return append(rotations[:len(rotations):len(rotations)], r)
+}
+
+// KeyHistory implements upspin.KeyRotator.
+func (s *server) KeyHistory(name upspin.UserName) ([]upspin.KeyRotation, error) {
+	const op errors.Op = "key/server.KeyHistory"
+	m, span := metric.NewSpan(op)
+	defer m.Done()
+
+	if err := valid.UserName(name); err != nil {
+		return nil, errors.E(op, name, err)
+	}
+	entry, err := s.lookup(op, name, span)
+	if err != nil {
+		return nil, err
+	}
+	return append([]upspin.KeyRotation(nil), entry.Rotations...), nil
+}
+
+// store logs and stores the new entry for a user, updating the caches.
+func (s *server) store(op errors.Op, entry *userEntry, span *metric.Span) error {
+	u := &entry.User
 	sp := span.StartSpan("logger.PutAttempt")
-	err = s.logger.PutAttempt(s.user, u)
+	err := s.logger.PutAttempt(s.user, u)
 	sp.End()
 	if err != nil {
 		return errors.E(op, err)
 	}
 
-	entry = &userEntry{User: *u, IsAdmin: isAdmin}
 	sp = span.StartSpan("putUserEntry")
 	err = s.putUserEntry(op, entry)
 	sp.End()
@@ -200,7 +315,7 @@ func (s *server) Put(u *upspin.User) error {
 	s.cache.Add(u.Name, entry)
 
 	sp = span.StartSpan("logger.PutSuccess")
-	err = s.logger.PutSuccess(s.user, u)
+	err = s.keyLog.putSuccess(s.logger, s.user, u)
 	sp.End()
 	if err != nil {
 		return errors.E(op, err)
@@ -66,6 +66,51 @@ func TestLookup(t *testing.T) {
 	}
 }
 
+func TestLookupBatch(t *testing.T) {
+	const (
+		myName  = "user@example.com"
+		ann     = "ann@example.com"
+		bob     = "bob@example.com"
+		missing = "missing@example.com"
+		invalid = "invalid"
+	)
+	var users []*upspin.User
+	for _, name := range []upspin.UserName{ann, bob} {
+		users = append(users, &upspin.User{
+			Name:      name,
+			Dirs:      []upspin.Endpoint{{Transport: upspin.Remote, NetAddr: "dir.example.com"}},
+			Stores:    []upspin.Endpoint{{Transport: upspin.Remote, NetAddr: "store.example.com"}},
+			PublicKey: upspin.PublicKey("key for " + name),
+		})
+	}
+	u, mockGCP := newKeyServerWithMocking(myName, ann, marshalUser(t, users[0], !isAdmin))
+	mockGCP.Ref = append(mockGCP.Ref, bob)
+	mockGCP.Data = append(mockGCP.Data, marshalUser(t, users[1], !isAdmin))
+
+	names := []upspin.UserName{ann, missing, bob, invalid, ann}
+	got, errs := u.LookupBatch(names)
+	if len(got) != len(names) || len(errs) != len(names) {
+		t.Fatalf("got %d users and %d errors, want %d of each", len(got), len(errs), len(names))
+	}
+	want := []*upspin.User{users[0], nil, users[1], nil, users[0]}
+	for i, name := range names {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("%s: user = %v, want %v", name, got[i], want[i])
+		}
+	}
+	for _, i := range []int{0, 2, 4} {
+		if errs[i] != nil {
+			t.Errorf("%s: unexpected error: %v", names[i], errs[i])
+		}
+	}
+	if !errors.Is(errors.NotExist, errs[1]) {
+		t.Errorf("%s: err = %v, want NotExist", names[1], errs[1])
+	}
+	if !errors.Is(errors.Invalid, errs[3]) {
+		t.Errorf("%s: err = %v, want Invalid", names[3], errs[3])
+	}
+}
+
 func BenchmarkLookup(b *testing.B) {
 	b.StopTimer()
 	k := benchKeyServer()
@@ -483,6 +528,7 @@ func newKeyServerWithMocking(user upspin.UserName, ref string, data []byte) (*se
 		user:      user,
 		lookupTXT: mockLookupTXT,
 		logger:    &noopLogger{},
+		keyLog:    &keyLog{},
 		cache:     cache.NewLRU(10),
 		negCache:  cache.NewLRU(10),
 	}
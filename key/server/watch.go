@@ -0,0 +1,125 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"sync"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// keyLog holds, in order, the user records stored by successful Puts,
+// and wakes watchers when a record is added. The sequence of a record
+// is one more than its index in the list. The list is read from the
+// logger the first time it is needed, so that sequences survive restarts.
+type keyLog struct {
+	mu      sync.Mutex
+	loaded  bool
+	users   []upspin.User
+	changed chan struct{} // Closed and replaced when a record is added.
+}
+
+// putSuccess records the successful Put of u by actor using the logger
+// and adds u to the list.
+func (k *keyLog) putSuccess(l logger, actor upspin.UserName, u *upspin.User) error {
+	// Hold the lock while logging, so a concurrent load of the
+	// log cannot see the record before it is added to the list.
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := l.PutSuccess(actor, u); err != nil {
+		return err
+	}
+	if !k.loaded {
+		// The record will be read from the log.
+		return nil
+	}
+	k.users = append(k.users, *u)
+	if k.changed != nil {
+		close(k.changed)
+		k.changed = nil
+	}
+	return nil
+}
+
+// start returns the index of the first record to send to a watcher that
+// asked for those following the given sequence.
+func (k *keyLog) start(l logger, sequence int64) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !k.loaded {
+		data, err := l.ReadAll()
+		if err != nil {
+			return 0, err
+		}
+		users, err := putsFromLog(data)
+		if err != nil {
+			return 0, errors.E(errors.Internal, err)
+		}
+		k.users = users
+		k.loaded = true
+	}
+	switch {
+	case sequence == upspin.WatchStart:
+		return 0, nil
+	case sequence == upspin.WatchNew:
+		return len(k.users), nil
+	case sequence > 0 && sequence <= int64(len(k.users)):
+		return int(sequence), nil
+	}
+	return 0, errors.E(errors.Invalid, errors.Errorf("invalid sequence %d", sequence))
+}
+
+// since returns the records from index i onwards and a channel that is
+// closed when another record is added.
+func (k *keyLog) since(i int) ([]upspin.User, <-chan struct{}) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.changed == nil {
+		k.changed = make(chan struct{})
+	}
+	// Records are only ever appended, so the slice may be
+	// read without the lock.
+	return k.users[i:], k.changed
+}
+
+// Watch implements upspin.KeyWatcher.
+func (s *server) Watch(sequence int64, done <-chan struct{}) (<-chan upspin.KeyEvent, error) {
+	const op errors.Op = "key/server.Watch"
+
+	next, err := s.keyLog.start(s.logger, sequence)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	events := make(chan upspin.KeyEvent)
+	go func() {
+		defer close(events)
+		for {
+			users, changed := s.keyLog.since(next)
+			for i := range users {
+				next++
+				u := users[i]
+				e := upspin.KeyEvent{
+					User:     &u,
+					Sequence: int64(next),
+				}
+				select {
+				case events <- e:
+				case <-done:
+					return
+				}
+			}
+			select {
+			case <-changed:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"upspin.io/cache"
+	"upspin.io/cloud/storage"
+	"upspin.io/cloud/storage/storagetest"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestPutsFromLog(t *testing.T) {
+	data, err := os.ReadFile("testdata/log.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := putsFromLog(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(users), 604; got != want {
+		t.Fatalf("got %d records, want %d", got, want)
+	}
+	if got, want := users[0].Name, upspin.UserName("upspin-dir@upspin.io"); got != want {
+		t.Errorf("first record is for %q, want %q", got, want)
+	}
+	if got, want := users[1].Dirs, []upspin.Endpoint{{Transport: upspin.Remote, NetAddr: "dir.upspin.io:443"}}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("second record has Dirs %v, want %v", got, want)
+	}
+
+	if _, err := putsFromLog(data[:len(data)-1]); err == nil {
+		t.Errorf("truncated log parsed without error")
+	}
+}
+
+// newMemoryKeyServer returns a key server that stores its data,
+// including its log, in the given storage.
+func newMemoryKeyServer(s storage.Storage) *server {
+	return &server{
+		storage:   s,
+		refCount:  &refCount{count: 1},
+		lookupTXT: mockLookupTXT,
+		logger:    &loggerImpl{storage: s},
+		keyLog:    &keyLog{},
+		cache:     cache.NewLRU(10),
+		negCache:  cache.NewLRU(10),
+	}
+}
+
+// putSelf stores the record for the named user, with the given directory
+// server address, as that user.
+func putSelf(t *testing.T, s *server, name upspin.UserName, addr upspin.NetAddr) {
+	t.Helper()
+	svc := *s
+	svc.user = name
+	u := &upspin.User{
+		Name:      name,
+		Dirs:      []upspin.Endpoint{{Transport: upspin.Remote, NetAddr: addr}},
+		PublicKey: "key",
+	}
+	if err := svc.Put(u); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectKeyEvent checks that the next event on events is the record
+// for the named user, with the given directory server address and sequence.
+func expectKeyEvent(t *testing.T, events <-chan upspin.KeyEvent, name upspin.UserName, addr upspin.NetAddr, seq int64) {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatalf("channel closed, want record %d for %s", seq, name)
+		}
+		if e.Error != nil {
+			t.Fatalf("got error %v, want record %d for %s", e.Error, seq, name)
+		}
+		if e.User.Name != name || len(e.User.Dirs) != 1 || e.User.Dirs[0].NetAddr != addr || e.Sequence != seq {
+			t.Fatalf("got record %d for %s with Dirs %v, want record %d for %s with Dirs %s", e.Sequence, e.User.Name, e.User.Dirs, seq, name, addr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for record %d for %s", seq, name)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	const (
+		ann = upspin.UserName("ann@example.com")
+		bob = upspin.UserName("bob@example.com")
+	)
+	mem := storagetest.Memory()
+	s := newMemoryKeyServer(mem)
+	putSelf(t, s, ann, "ann1.example.com:443")
+
+	done := make(chan struct{})
+	defer close(done)
+	all, err := s.Watch(upspin.WatchStart, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectKeyEvent(t, all, ann, "ann1.example.com:443", 1)
+	newOnly, err := s.Watch(upspin.WatchNew, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putSelf(t, s, bob, "bob1.example.com:443")
+	putSelf(t, s, ann, "ann2.example.com:443")
+	for _, events := range []<-chan upspin.KeyEvent{all, newOnly} {
+		expectKeyEvent(t, events, bob, "bob1.example.com:443", 2)
+		expectKeyEvent(t, events, ann, "ann2.example.com:443", 3)
+	}
+
+	// Resume after the first record.
+	after, err := s.Watch(1, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectKeyEvent(t, after, bob, "bob1.example.com:443", 2)
+	expectKeyEvent(t, after, ann, "ann2.example.com:443", 3)
+
+	// A restarted server numbers the records the same way.
+	restarted := newMemoryKeyServer(mem)
+	again, err := restarted.Watch(2, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectKeyEvent(t, again, ann, "ann2.example.com:443", 3)
+	putSelf(t, restarted, bob, "bob2.example.com:443")
+	expectKeyEvent(t, again, bob, "bob2.example.com:443", 4)
+
+	for _, seq := range []int64{upspin.WatchCurrent, 5, -10} {
+		_, err := restarted.Watch(seq, done)
+		if !errors.Is(errors.Invalid, err) {
+			t.Errorf("Watch(%d): got error %v, want Invalid", seq, err)
+		}
+	}
+}
+
+func TestWatchDone(t *testing.T) {
+	s := newMemoryKeyServer(storagetest.Memory())
+	done := make(chan struct{})
+	events, err := s.Watch(upspin.WatchNew, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(done)
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("got event, want channel closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed after done")
+	}
+}
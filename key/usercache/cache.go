@@ -45,7 +45,12 @@ type deferredDial struct {
 	dialed   upspin.KeyServer
 }
 
-var _ upspin.KeyServer = (*userCacheServer)(nil)
+var (
+	_ upspin.KeyServer        = (*userCacheServer)(nil)
+	_ upspin.KeyLookupBatcher = (*userCacheServer)(nil)
+	_ upspin.KeyWatcher       = (*userCacheServer)(nil)
+	_ upspin.KeyRotator       = (*userCacheServer)(nil)
+)
 
 type userCache struct {
 	entries  *cache.LRU
@@ -106,6 +111,68 @@ func (c *userCacheServer) Lookup(name upspin.UserName) (*upspin.User, error) {
 	return u, nil
 }
 
+// LookupBatch implements upspin.KeyLookupBatcher.
+// Users not in the cache are looked up in one request if the
+// underlying key server supports it, and one at a time otherwise.
+func (c *userCacheServer) LookupBatch(names []upspin.UserName) ([]*upspin.User, []error) {
+	const op errors.Op = "key/usercache.LookupBatch"
+
+	users := make([]*upspin.User, len(names))
+	errs := make([]error, len(names))
+	var (
+		missing []upspin.UserName
+		index   []int // Index in names of each missing user.
+	)
+	now := time.Now()
+	for i, name := range names {
+		if v, ok := c.cache.entries.Get(name); ok {
+			if e := v.(*entry); !now.After(e.expires) {
+				users[i] = e.user
+				continue
+			}
+			c.cache.entries.Remove(name)
+		}
+		missing = append(missing, name)
+		index = append(index, i)
+	}
+	if len(missing) == 0 {
+		return users, errs
+	}
+
+	if err := c.dial(); err != nil {
+		err = errors.E(op, err)
+		for _, i := range index {
+			errs[i] = err
+		}
+		return users, errs
+	}
+	var (
+		found     []*upspin.User
+		foundErrs []error
+	)
+	if b, ok := c.dd.dialed.(upspin.KeyLookupBatcher); ok {
+		found, foundErrs = b.LookupBatch(missing)
+	} else {
+		found = make([]*upspin.User, len(missing))
+		foundErrs = make([]error, len(missing))
+		for j, name := range missing {
+			found[j], foundErrs[j] = c.dd.dialed.Lookup(name)
+		}
+	}
+	for j, i := range index {
+		if foundErrs[j] != nil {
+			errs[i] = errors.E(op, foundErrs[j])
+			continue
+		}
+		users[i] = found[j]
+		c.cache.entries.Add(names[i], &entry{
+			expires: time.Now().Add(c.cache.duration),
+			user:    found[j],
+		})
+	}
+	return users, errs
+}
+
 // Put implements upspin.KeyServer.
 func (c *userCacheServer) Put(user *upspin.User) error {
 	const op errors.Op = "key/usercache.Put"
@@ -119,6 +186,90 @@ func (c *userCacheServer) Put(user *upspin.User) error {
 	return nil
 }
 
+// Rotate implements upspin.KeyRotator. It returns ErrNotSupported if the
+// underlying key server does not support it.
+func (c *userCacheServer) Rotate(user *upspin.User, sig upspin.Signature) error {
+	const op errors.Op = "key/usercache.Rotate"
+	if err := c.dial(); err != nil {
+		return errors.E(op, err)
+	}
+	r, ok := c.dd.dialed.(upspin.KeyRotator)
+	if !ok {
+		return upspin.ErrNotSupported
+	}
+	err := r.Rotate(user, sig)
+	if err == upspin.ErrNotSupported {
+		return err
+	}
+	if err != nil {
+		return errors.E(op, err)
+	}
+	c.cache.entries.Remove(user.Name)
+	return nil
+}
+
+// KeyHistory implements upspin.KeyRotator. Histories are not cached.
+func (c *userCacheServer) KeyHistory(name upspin.UserName) ([]upspin.KeyRotation, error) {
+	const op errors.Op = "key/usercache.KeyHistory"
+	if err := c.dial(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	r, ok := c.dd.dialed.(upspin.KeyRotator)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	history, err := r.KeyHistory(name)
+	if err == upspin.ErrNotSupported {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return history, nil
+}
+
+// Watch implements upspin.KeyWatcher. It returns ErrNotSupported if the
+// underlying key server does not support it. As each record passes
+// through, its user's entry is removed from the cache.
+func (c *userCacheServer) Watch(sequence int64, done <-chan struct{}) (<-chan upspin.KeyEvent, error) {
+	const op errors.Op = "key/usercache.Watch"
+	if err := c.dial(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	w, ok := c.dd.dialed.(upspin.KeyWatcher)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	events, err := w.Watch(sequence, done)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan upspin.KeyEvent)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if e.User != nil {
+					c.cache.entries.Remove(e.User.Name)
+				}
+				select {
+				case out <- e:
+				case <-done:
+					return
+				}
+
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
 // Endpoint implements upspin.Service.
 func (c *userCacheServer) Endpoint() upspin.Endpoint {
 	// We don't want Endpoint to trigger a Dial.
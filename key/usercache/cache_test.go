@@ -20,10 +20,12 @@ import (
 )
 
 // service is a KeyServer implementation that counts lookups.
+// Its Watch method returns the events sent on its events channel.
 type service struct {
 	lookups int
 	dials   int
 	entries map[string]*upspin.User
+	events  chan upspin.KeyEvent
 
 	config   upspin.Config
 	endpoint upspin.Endpoint
@@ -31,6 +33,7 @@ type service struct {
 
 var keyService = &service{
 	entries:  make(map[string]*upspin.User),
+	events:   make(chan upspin.KeyEvent),
 	endpoint: upspin.Endpoint{Transport: upspin.InProcess},
 }
 
@@ -201,6 +204,75 @@ func TestCache(t *testing.T) {
 	}
 }
 
+// TestLookupBatch tests that a batch lookup is served from the cache where
+// possible and that errors are reported for the right users.
+func TestLookupBatch(t *testing.T) {
+	_, c := setup(t, "TestLookupBatch@nowhere.com")
+	b := c.(upspin.KeyLookupBatcher)
+
+	// Cache a@a.com only.
+	if _, err := c.Lookup("a@a.com"); err != nil {
+		t.Fatal(err)
+	}
+	sofar := keyService.lookups
+
+	names := []upspin.UserName{"a@a.com", "nobody@nowhere.com", "b@b.com"}
+	users, errs := b.LookupBatch(names)
+	if len(users) != len(names) || len(errs) != len(names) {
+		t.Fatalf("got %d users and %d errors, want %d of each", len(users), len(errs), len(names))
+	}
+	for _, i := range []int{0, 2} {
+		if errs[i] != nil {
+			t.Errorf("%s: %v", names[i], errs[i])
+		} else if users[i].Name != names[i] {
+			t.Errorf("%s: got user %s", names[i], users[i].Name)
+		}
+	}
+	if !errors.Is(errors.NotExist, errs[1]) || users[1] != nil {
+		t.Errorf("%s: user = %v, err = %v, want NotExist", names[1], users[1], errs[1])
+	}
+	// Only the uncached users should have been looked up.
+	if n := keyService.lookups - sofar; n != 2 {
+		t.Errorf("underlying lookups = %d, want 2", n)
+	}
+}
+
+// TestWatch tests that the records reported by Watch are passed through
+// and remove the users' entries from the cache.
+func TestWatch(t *testing.T) {
+	_, c := setup(t, "TestWatch@nowhere.com")
+	w := c.(upspin.KeyWatcher)
+
+	// Cache a@a.com.
+	if _, err := c.Lookup("a@a.com"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	events, err := w.Watch(upspin.WatchNew, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyService.Put(&upspin.User{Name: "a@a.com", PublicKey: "new key"})
+	keyService.events <- upspin.KeyEvent{
+		User:     &upspin.User{Name: "a@a.com", PublicKey: "new key"},
+		Sequence: 7,
+	}
+	e := <-events
+	if e.User.Name != "a@a.com" || e.Sequence != 7 {
+		t.Fatalf("got record %d for %s, want record 7 for a@a.com", e.Sequence, e.User.Name)
+	}
+	u, err := c.Lookup("a@a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.PublicKey != "new key" {
+		t.Errorf("got key %q after Watch, want %q", u.PublicKey, "new key")
+	}
+	keyService.add("a@a.com")
+}
+
 // TestExpiration tests that cache entries time out.
 func TestExpiration(t *testing.T) {
 	if testing.Short() {
@@ -285,6 +357,10 @@ func (s *service) Put(user *upspin.User) error {
 	return nil
 }
 
+func (s *service) Watch(sequence int64, done <-chan struct{}) (<-chan upspin.KeyEvent, error) {
+	return s.events, nil
+}
+
 func (s *service) Dial(cfg upspin.Config, e upspin.Endpoint) (upspin.Service, error) {
 	s.dials++
 	s.config = cfg
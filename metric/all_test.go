@@ -5,6 +5,7 @@
 package metric
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -75,6 +76,40 @@ func TestFullChannel(t *testing.T) {
 	// If we block, this test will never finish.
 }
 
+func TestSpanID(t *testing.T) {
+	m, parent := NewSpan("client")
+	if parent.ID.IsZero() {
+		t.Fatal("span has zero ID")
+	}
+	if got := m.StartSpan("sibling").ID; got.Trace != parent.ID.Trace || got.Span == parent.ID.Span {
+		t.Errorf("sibling has ID %v, want a new span in trace %x", got, parent.ID.Trace)
+	}
+
+	id, err := ParseSpanID(parent.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != parent.ID {
+		t.Errorf("ParseSpanID(%q) = %v, want %v", parent.ID.String(), id, parent.ID)
+	}
+	for _, bad := range []string{"", "0123", "0123456789abcdef+0123456789abcdef", "0123456789abcdeg-0123456789abcdef"} {
+		if _, err := ParseSpanID(bad); !errors.Is(errors.Invalid, err) {
+			t.Errorf("ParseSpanID(%q): got error %v, want Invalid", bad, err)
+		}
+	}
+
+	_, child := NewSpanFrom("server", id)
+	if child.RemoteParent != parent.ID {
+		t.Errorf("child has remote parent %v, want %v", child.RemoteParent, parent.ID)
+	}
+	if child.ID.Trace != parent.ID.Trace || child.ID.Span == parent.ID.Span {
+		t.Errorf("child has ID %v, want a new span in trace %x", child.ID, parent.ID.Trace)
+	}
+	if _, orphan := NewSpanFrom("server", SpanID{}); !orphan.RemoteParent.IsZero() {
+		t.Errorf("span from zero ID has remote parent %v", orphan.RemoteParent)
+	}
+}
+
 func verifyMetric(t *testing.T, m *Metric, expectedName errors.Op, expectedSpanNames ...errors.Op) error {
 	if m.Name != expectedName {
 		return fmt.Errorf("Expected %q, got %q", expectedName, m.Name)
@@ -111,3 +146,14 @@ func (d *dummySaver) Register(queue chan *Metric) {
 		d.done <- true
 	}()
 }
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if s := FromContext(ctx); s != nil {
+		t.Fatalf("FromContext of empty context = %v, want nil", s)
+	}
+	_, s := NewSpan("span")
+	if got := FromContext(NewContext(ctx, s)); got != s {
+		t.Errorf("FromContext = %v, want %v", got, s)
+	}
+}
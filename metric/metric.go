@@ -7,6 +7,9 @@
 package metric // import "upspin.io/metric"
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,6 +23,8 @@ import (
 type Metric struct {
 	Name errors.Op
 
+	trace uint64 // the Trace of the IDs of the metric's spans.
+
 	mu    sync.Mutex // protects all fields below
 	spans []*Span
 }
@@ -41,6 +46,44 @@ type Span struct {
 	Parent     *Metric // parent of this span; may be nil.
 	ParentSpan *Span   // may be nil.
 	Annotation string  // optional.
+
+	// ID identifies the span, including in other processes.
+	ID SpanID
+
+	// RemoteParent identifies the span, typically in another process,
+	// of which this span is a child. It is zero if there is none.
+	RemoteParent SpanID
+}
+
+// A SpanID identifies a span so that spans in other processes, such as
+// the server handling an RPC, may refer to it. All the spans of a Metric
+// share a Trace, as do the spans started with NewSpanFrom.
+type SpanID struct {
+	Trace uint64
+	Span  uint64
+}
+
+// IsZero reports whether id is the zero SpanID, which identifies no span.
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// String returns id in the form parsed by ParseSpanID:
+// the Trace and Span in hexadecimal, separated by a hyphen.
+func (id SpanID) String() string {
+	return fmt.Sprintf("%016x-%016x", id.Trace, id.Span)
+}
+
+// ParseSpanID parses a SpanID in the form returned by its String method.
+func ParseSpanID(s string) (SpanID, error) {
+	var id SpanID
+	if len(s) != 33 || s[16] != '-' {
+		return id, errors.E(errors.Invalid, errors.Errorf("bad span ID %q", s))
+	}
+	if _, err := fmt.Sscanf(s, "%016x-%016x", &id.Trace, &id.Span); err != nil {
+		return SpanID{}, errors.E(errors.Invalid, errors.Errorf("bad span ID %q", s))
+	}
+	return id, nil
 }
 
 // Saver is the common interface that all implementation-specific backends must implement
@@ -73,7 +116,8 @@ var saveQueue = make(chan *Metric, SaveQueueLength)
 // descendant's Span name.
 func New(name errors.Op) *Metric {
 	return &Metric{
-		Name: name,
+		Name:  name,
+		trace: rand.Uint64(),
 	}
 }
 
@@ -83,6 +127,37 @@ func NewSpan(name errors.Op) (*Metric, *Span) {
 	return m, m.StartSpan(name)
 }
 
+// NewSpanFrom is like NewSpan but makes the new span a child of the span
+// identified by parent, typically a span in another process whose ID
+// arrived with a request. The spans of the new metric share the parent's
+// Trace. If parent is zero, NewSpanFrom is the same as NewSpan.
+func NewSpanFrom(name errors.Op, parent SpanID) (*Metric, *Span) {
+	if parent.IsZero() {
+		return NewSpan(name)
+	}
+	m := New(name)
+	m.trace = parent.Trace
+	s := m.StartSpan(name)
+	s.RemoteParent = parent
+	return m, s
+}
+
+// spanKey is the key of the span carried by a context.
+type spanKey struct{}
+
+// NewContext returns a copy of ctx that carries s, so that work done on
+// behalf of the caller, such as an RPC, may record its spans as children
+// of s.
+func NewContext(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// FromContext returns the span carried by ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
 var (
 	registered int32 // read/written atomically
 )
@@ -110,6 +185,7 @@ func (m *Metric) StartSpan(name errors.Op) *Span {
 		StartTime: time.Now(),
 		Parent:    m,
 		Kind:      Server,
+		ID:        SpanID{Trace: m.trace, Span: rand.Uint64()},
 	}
 	m.spans = append(m.spans, s)
 	return s
@@ -0,0 +1,189 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package prometheus implements a metric.Saver that accumulates metrics
+// and serves them over HTTP in the Prometheus text exposition format.
+//
+// Each completed Metric increments a counter named after the Metric, and
+// each of its spans is recorded in a histogram of durations named after
+// the span, with a "kind" label holding the span's metric.Kind.
+// For a span named "dir/server.Lookup" the histogram is
+// upspin_dir_server_Lookup_seconds.
+package prometheus // import "upspin.io/metric/prometheus"
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"upspin.io/errors"
+	"upspin.io/metric"
+)
+
+// namePrefix begins the name of every exported metric.
+const namePrefix = "upspin_"
+
+// buckets holds the upper bounds, in seconds, of the histogram buckets.
+var buckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Saver is a metric.Saver that keeps running totals of the metrics it
+// is sent, and serves them to Prometheus as an http.Handler.
+type Saver struct {
+	mu         sync.Mutex // Guards the fields below.
+	counters   map[string]uint64
+	histograms map[histogramKey]*histogram
+}
+
+var (
+	_ metric.Saver = (*Saver)(nil)
+	_ http.Handler = (*Saver)(nil)
+)
+
+type histogramKey struct {
+	name string
+	kind metric.Kind
+}
+
+type histogram struct {
+	counts []uint64 // counts[i] is the number of observations <= buckets[i].
+	count  uint64
+	sum    float64
+}
+
+// New returns a new Saver. It does not register it.
+func New() *Saver {
+	return &Saver{
+		counters:   make(map[string]uint64),
+		histograms: make(map[histogramKey]*histogram),
+	}
+}
+
+// NewHandler creates a Saver, registers it with metric.RegisterSaver,
+// and returns it as an http.Handler to be mounted at /metrics.
+// Since only one Saver may be registered, it must be called at most once.
+func NewHandler() http.Handler {
+	s := New()
+	metric.RegisterSaver(s)
+	return s
+}
+
+// Register implements metric.Saver.
+func (s *Saver) Register(queue chan *metric.Metric) {
+	go func() {
+		for m := range queue {
+			if m == nil {
+				continue
+			}
+			s.add(m)
+		}
+	}()
+}
+
+// add accumulates the counts and durations of m and its spans.
+func (s *Saver) add(m *metric.Metric) {
+	spans := m.Spans()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.Name != "" {
+		s.counters[exportName(m.Name, "_total")]++
+	}
+	for _, span := range spans {
+		if span.EndTime.IsZero() {
+			continue
+		}
+		key := histogramKey{name: exportName(span.Name, "_seconds"), kind: span.Kind}
+		h, ok := s.histograms[key]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(buckets))}
+			s.histograms[key] = h
+		}
+		secs := span.EndTime.Sub(span.StartTime).Seconds()
+		for i, b := range buckets {
+			if secs <= b {
+				h.counts[i]++
+			}
+		}
+		h.count++
+		h.sum += secs
+	}
+}
+
+// ServeHTTP implements http.Handler by writing the accumulated
+// metrics in the Prometheus text exposition format.
+func (s *Saver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	s.write(&buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// write writes the metrics to buf, sorted by name so that the output
+// is stable.
+func (s *Saver) write(buf *bytes.Buffer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.counters))
+	for name := range s.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(buf, "# TYPE %s counter\n", name)
+		fmt.Fprintf(buf, "%s %d\n", name, s.counters[name])
+	}
+
+	keys := make([]histogramKey, 0, len(s.histograms))
+	for k := range s.histograms {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	for i, k := range keys {
+		if i == 0 || keys[i-1].name != k.name {
+			fmt.Fprintf(buf, "# TYPE %s histogram\n", k.name)
+		}
+		h := s.histograms[k]
+		kind := kindLabel(k.kind)
+		for j, b := range buckets {
+			fmt.Fprintf(buf, "%s_bucket{kind=%q,le=\"%g\"} %d\n", k.name, kind, b, h.counts[j])
+		}
+		fmt.Fprintf(buf, "%s_bucket{kind=%q,le=\"+Inf\"} %d\n", k.name, kind, h.count)
+		fmt.Fprintf(buf, "%s_sum{kind=%q} %g\n", k.name, kind, h.sum)
+		fmt.Fprintf(buf, "%s_count{kind=%q} %d\n", k.name, kind, h.count)
+	}
+}
+
+// exportName returns the name of the exported metric for the metric or span
+// with the given name, which is prefixed with "upspin_" and suffixed
+// with suffix. Characters that may not appear in a Prometheus metric
+// name are replaced by underscores, so distinct names may map to the
+// same exported metric.
+func exportName(op errors.Op, suffix string) string {
+	mapping := func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}
+	return namePrefix + strings.Map(mapping, string(op)) + suffix
+}
+
+func kindLabel(k metric.Kind) string {
+	switch k {
+	case metric.Server:
+		return "server"
+	case metric.Client:
+		return "client"
+	}
+	return "other"
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prometheus
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"upspin.io/metric"
+)
+
+func TestSaver(t *testing.T) {
+	s := New()
+	queue := make(chan *metric.Metric, 3)
+	s.Register(queue)
+
+	start := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, d := range []time.Duration{2 * time.Millisecond, 3 * time.Second} {
+		m, span := metric.NewSpan("dir/server.Lookup")
+		sub := span.StartSpan("getRoot").SetKind(metric.Client)
+		m.Done()
+		span.StartTime, span.EndTime = start, start.Add(d)
+		sub.StartTime, sub.EndTime = start, start.Add(d/2)
+		queue <- m
+	}
+
+	want := []string{
+		"# TYPE upspin_dir_server_Lookup_total counter\n",
+		"upspin_dir_server_Lookup_total 2\n",
+		"# TYPE upspin_dir_server_Lookup_seconds histogram\n",
+		`upspin_dir_server_Lookup_seconds_bucket{kind="server",le="0.001"} 0` + "\n",
+		`upspin_dir_server_Lookup_seconds_bucket{kind="server",le="0.0025"} 1` + "\n",
+		`upspin_dir_server_Lookup_seconds_bucket{kind="server",le="2.5"} 1` + "\n",
+		`upspin_dir_server_Lookup_seconds_bucket{kind="server",le="5"} 2` + "\n",
+		`upspin_dir_server_Lookup_seconds_bucket{kind="server",le="+Inf"} 2` + "\n",
+		`upspin_dir_server_Lookup_seconds_sum{kind="server"} 3.002` + "\n",
+		`upspin_dir_server_Lookup_seconds_count{kind="server"} 2` + "\n",
+		`upspin_getRoot_seconds_bucket{kind="client",le="0.001"} 1` + "\n",
+		`upspin_getRoot_seconds_count{kind="client"} 2` + "\n",
+	}
+	var body string
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if got, want := w.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+			t.Fatalf("Content-Type is %q, want %q", got, want)
+		}
+		body = w.Body.String()
+		if strings.Contains(body, "upspin_dir_server_Lookup_total 2\n") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Each line must appear, in order.
+	rest := body
+	for _, line := range want {
+		i := strings.Index(rest, line)
+		if i < 0 {
+			t.Fatalf("output does not contain %q in order:\n%s", line, body)
+		}
+		rest = rest[i+len(line):]
+	}
+}
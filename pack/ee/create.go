@@ -50,6 +50,9 @@ func (d *drng) Read(p []byte) (n int, err error) {
 	return lenp, nil
 }
 
+// Curves lists the names of the curves CreateKeys supports.
+var Curves = []string{"p256", "p384", "p521"}
+
 // CreateKeys creates a key pair based on the chosen curve and a slice of entropy.
 func CreateKeys(curveName string, entropy []byte) (public upspin.PublicKey, private string, err error) {
 	const op errors.Op = "pack/ee.CreateKeys"
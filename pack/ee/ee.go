@@ -144,6 +144,9 @@ func (bp *blockPacker) Pack(cleartext []byte) (ciphertext []byte, err error) {
 	if err := internal.CheckLocationSet(bp.entry); err != nil {
 		return nil, err
 	}
+	if err := internal.CheckBlockSize(int64(len(cleartext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
 
 	// Compute offset of this block,
 	// the size of the preceding blocks.
@@ -260,6 +263,9 @@ func (ee ee) Unpack(cfg upspin.Config, d *upspin.DirEntry) (upspin.BlockUnpacker
 	if _, err := d.Size(); err != nil {
 		return nil, errors.E(op, d.Name, err)
 	}
+	if err := internal.CheckBlockSizes(d); err != nil {
+		return nil, errors.E(op, err)
+	}
 
 	var pd packdata
 	if err := pd.Unmarshal(d.Packdata); err != nil {
@@ -342,6 +348,9 @@ type blockUnpacker struct {
 
 func (bp *blockUnpacker) Unpack(ciphertext []byte) (cleartext []byte, err error) {
 	const op errors.Op = "pack/ee.blockUnpacker.Unpack"
+	if err := internal.CheckBlockSize(int64(len(ciphertext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
 	// Validate checksum.
 	b := sha256.Sum256(ciphertext)
 	sum := b[:]
@@ -472,6 +481,87 @@ func (ee ee) Share(cfg upspin.Config, readers []upspin.PublicKey, packdataSlice
 	}
 }
 
+// AddReaders returns a copy of data, the Packdata of a DirEntry packed
+// with ee, in which the file decryption key is also wrapped for each of the
+// readers that cannot already unwrap it. Unlike Share, it leaves the existing
+// wrapped keys intact and reports why it cannot update the packdata, which
+// may happen if the config's factotum holds no key able to unwrap the file
+// decryption key or if one of the readers' keys is invalid.
+// A reader key of upspin.AllUsersKey stores the decryption key unwrapped.
+func AddReaders(cfg upspin.Config, data []byte, readers []upspin.PublicKey) ([]byte, error) {
+	const op errors.Op = "pack/ee.AddReaders"
+	var pd packdata
+	if err := pd.Unmarshal(data); err != nil {
+		return nil, errors.E(op, errors.Invalid, err)
+	}
+	dkey, err := fileKey(cfg.Factotum(), &pd)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	wrapped := make(map[keyHashArray]bool)
+	for _, w := range pd.wrap {
+		var h keyHashArray
+		copy(h[:], w.keyHash)
+		wrapped[h] = true
+	}
+	for _, pub := range readers {
+		var h keyHashArray
+		if pub == upspin.AllUsersKey {
+			copy(h[:], factotum.AllUsersKeyHash)
+		} else {
+			copy(h[:], factotum.KeyHash(pub))
+		}
+		if wrapped[h] {
+			continue
+		}
+		wrapped[h] = true
+		if pub == upspin.AllUsersKey {
+			pd.wrap = append(pd.wrap, wrappedKey{
+				keyHash: factotum.AllUsersKeyHash,
+				dkey:    dkey,
+			})
+			continue
+		}
+		pubkey, err := factotum.ParsePublicKey(pub)
+		if err != nil {
+			return nil, errors.E(op, errors.Invalid, err)
+		}
+		w, err := gcmWrap(pub, pubkey, dkey)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		pd.wrap = append(pd.wrap, w)
+	}
+	var dst []byte
+	if err := pd.Marshal(&dst); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return dst, nil
+}
+
+// fileKey returns the file decryption key held in pd, either stored
+// unwrapped for all users or unwrapped using one of the factotum's keys.
+func fileKey(f upspin.Factotum, pd *packdata) ([]byte, error) {
+	for _, w := range pd.wrap {
+		if bytes.Equal(factotum.AllUsersKeyHash, w.keyHash) {
+			return w.dkey, nil
+		}
+		if _, err := f.PublicKeyFromHash(w.keyHash); err != nil {
+			// To unwrap dkey, we can only use our own private keys.
+			continue
+		}
+		dkey, err := aesUnwrap(f, w)
+		if err != nil {
+			return nil, err
+		}
+		if len(dkey) == 0 {
+			break
+		}
+		return dkey, nil
+	}
+	return nil, errors.E(errors.Permission, errNoWrappedKey)
+}
+
 // Name implements upspin.Name.
 func (ee ee) Name(cfg upspin.Config, d *upspin.DirEntry, newName upspin.PathName) error {
 	const op errors.Op = "pack/ee.Name"
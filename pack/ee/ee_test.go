@@ -268,6 +268,93 @@ func TestSharing(t *testing.T) {
 	}
 }
 
+func TestAddReaders(t *testing.T) {
+	const (
+		joesUserName upspin.UserName = "joe@upspin.io"
+		pathName                     = upspin.PathName(joesUserName + "/file_shared_by_adding_bob")
+		bobsUserName upspin.UserName = "bob@upspin.io"
+		text                         = "bob, you can read this now."
+	)
+	bobPublic := upspin.PublicKey("p256\n22501350716439586308300487995594907386227865907589820632958610970814693581908\n104071495646780593180743128812641149143422089655848205222288250096821814372528\n")
+
+	joecfg, packer := setup(joesUserName)
+	d := &upspin.DirEntry{
+		Name:       pathName,
+		SignedName: pathName,
+		Writer:     joesUserName,
+	}
+	cipher := packBlob(t, joecfg, packer, d, []byte(text))
+	joeHashes, err := packer.ReaderHashes(d.Packdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding Bob keeps Joe's wrapped key and adds one for Bob.
+	pd, err := ee.AddReaders(joecfg, d.Packdata, []upspin.PublicKey{joecfg.Factotum().PublicKey(), bobPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readers, err := packer.ReaderHashes(pd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(joeHashes, factotum.KeyHash(bobPublic))
+	if len(readers) != len(want) {
+		t.Fatalf("got %d reader hashes, want %d", len(readers), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(readers[i], want[i]) {
+			t.Errorf("reader hash %d: got %x, want %x", i, readers[i], want[i])
+		}
+	}
+	d.Packdata = pd
+
+	// Adding Bob again changes nothing.
+	pd, err = ee.AddReaders(joecfg, d.Packdata, []upspin.PublicKey{bobPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pd, d.Packdata) {
+		t.Error("adding an existing reader changed the packdata")
+	}
+
+	// Both Joe and Bob can read the file.
+	if clear := unpackBlob(t, joecfg, packer, d, cipher); string(clear) != text {
+		t.Errorf("Joe: got %q, want %q", clear, text)
+	}
+	bobcfg, packer := setup(bobsUserName)
+	bobcfg = config.SetKeyEndpoint(bobcfg, upspin.Endpoint{Transport: upspin.InProcess})
+	if clear := unpackBlob(t, bobcfg, packer, d, cipher); string(clear) != text {
+		t.Errorf("Bob: got %q, want %q", clear, text)
+	}
+
+	// Adding all users stores the key unwrapped.
+	pd, err = ee.AddReaders(joecfg, d.Packdata, []upspin.PublicKey{upspin.AllUsersKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readers, err = packer.ReaderHashes(pd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readers) != 3 || !bytes.Equal(readers[2], factotum.AllUsersKeyHash) {
+		t.Errorf("after adding all users, got reader hashes %x", readers)
+	}
+
+	// A bad key is an error.
+	_, err = ee.AddReaders(joecfg, d.Packdata, []upspin.PublicKey{"p256\nbogus\n"})
+	if !errors.Is(errors.Invalid, err) {
+		t.Errorf("bad key: got error %v, want Invalid", err)
+	}
+
+	// Someone without a wrapped key cannot add readers.
+	carlacfg, _ := setup("carla@baz.edu")
+	_, err = ee.AddReaders(carlacfg, d.Packdata, []upspin.PublicKey{carlacfg.Factotum().PublicKey()})
+	if !errors.Is(errors.Permission, err) {
+		t.Errorf("carla: got error %v, want Permission", err)
+	}
+}
+
 func TestBadSharing(t *testing.T) {
 	// joe@google.com is the owner of a file that is attempting to be shared with bob@foo.com, but share wasn't called.
 	const (
@@ -421,6 +508,12 @@ func TestMultiBlockRoundTrip(t *testing.T) {
 	packtest.TestMultiBlockRoundTrip(t, cfg, packer, userName)
 }
 
+func TestBlockSizeLimit(t *testing.T) {
+	const userName = upspin.UserName("aly@upspin.io")
+	cfg, packer := setup(userName)
+	packtest.TestBlockSizeLimit(t, cfg, packer, userName)
+}
+
 func TestConsistentKeyStream(t *testing.T) {
 	// This test that the EE packer with different block sizes still
 	// generates the same ciphertext when all blocks are concatenated.
@@ -101,6 +101,9 @@ func (bp *blockPacker) Pack(cleartext []byte) (ciphertext []byte, err error) {
 	if err := internal.CheckLocationSet(bp.entry); err != nil {
 		return nil, err
 	}
+	if err := internal.CheckBlockSize(int64(len(cleartext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
 
 	ciphertext = bp.buf.Bytes(len(cleartext))
 	copy(ciphertext, cleartext)
@@ -163,6 +166,9 @@ func (ei ei) Unpack(cfg upspin.Config, d *upspin.DirEntry) (upspin.BlockUnpacker
 	if _, err := d.Size(); err != nil {
 		return nil, errors.E(op, d.Name, err)
 	}
+	if err := internal.CheckBlockSizes(d); err != nil {
+		return nil, errors.E(op, err)
+	}
 
 	sig, sig2, hash, err := pdUnmarshal(d.Packdata)
 	if err != nil {
@@ -216,6 +222,9 @@ type blockUnpacker struct {
 // Unpack implements upspin.BlockUnpacker.
 func (bp *blockUnpacker) Unpack(ciphertext []byte) (cleartext []byte, err error) {
 	const op errors.Op = "pack/eeintegrity.blockUpacker.Unpack"
+	if err := internal.CheckBlockSize(int64(len(ciphertext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
 	// Validate checksum.
 	b := sha256.Sum256(ciphertext)
 	sum := b[:]
@@ -291,3 +291,9 @@ func TestMultiBlockRoundTrip(t *testing.T) {
 	cfg, packer := setup(userName)
 	packtest.TestMultiBlockRoundTrip(t, cfg, packer, userName)
 }
+
+func TestBlockSizeLimit(t *testing.T) {
+	const userName = upspin.UserName("aly@upspin.io")
+	cfg, packer := setup(userName)
+	packtest.TestBlockSizeLimit(t, cfg, packer, userName)
+}
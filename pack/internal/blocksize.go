@@ -0,0 +1,31 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+// CheckBlockSize returns an error if a block of n bytes
+// exceeds upspin.MaxBlockSize.
+func CheckBlockSize(n int64) error {
+	if n > upspin.MaxBlockSize {
+		return errors.E(errors.Invalid, errors.Errorf("block size %d exceeds maximum %d", n, upspin.MaxBlockSize))
+	}
+	return nil
+}
+
+// CheckBlockSizes checks that none of the entry's blocks claims to be
+// larger than upspin.MaxBlockSize, so a malicious entry cannot force
+// its reader to allocate a huge buffer.
+func CheckBlockSizes(d *upspin.DirEntry) error {
+	for i := range d.Blocks {
+		if n := d.Blocks[i].Size; n > upspin.MaxBlockSize {
+			return errors.E(d.Name, errors.Invalid, errors.Errorf("block %d size %d exceeds maximum %d", i, n, upspin.MaxBlockSize))
+		}
+	}
+	return nil
+}
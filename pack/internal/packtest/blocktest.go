@@ -14,6 +14,7 @@ import (
 	mRand "math/rand"
 	"testing"
 
+	"upspin.io/errors"
 	"upspin.io/upspin"
 )
 
@@ -70,6 +71,47 @@ func TestMultiBlockRoundTrip(t *testing.T, ctx upspin.Config, packer upspin.Pack
 	}
 }
 
+// TestBlockSizeLimit checks that the packer refuses to pack a block
+// larger than upspin.MaxBlockSize and to unpack an entry whose block
+// claims to be that large.
+func TestBlockSizeLimit(t *testing.T, ctx upspin.Config, packer upspin.Packer, userName upspin.UserName) {
+	pathName := upspin.PathName(userName + "/bigfile")
+	de := &upspin.DirEntry{
+		Name:       pathName,
+		SignedName: pathName,
+		Writer:     userName,
+		Packing:    packer.Packing(),
+	}
+	store := make(fakeStore)
+	if err := packEntry(ctx, store, packer, de, bytes.NewReader([]byte("small"))); err != nil {
+		t.Fatal("packEntry:", err)
+	}
+
+	// Claim a block twice the limit; Unpack must not trust it.
+	de.Blocks[0].Size = 2 * upspin.MaxBlockSize
+	if _, err := packer.Unpack(ctx, de); !errors.Is(errors.Invalid, err) {
+		t.Errorf("Unpack with oversized block: got error %v, want Invalid", err)
+	}
+
+	if testing.Short() {
+		return
+	}
+	// The memory is never touched, so this is cheap on 64-bit machines.
+	de = &upspin.DirEntry{
+		Name:       pathName,
+		SignedName: pathName,
+		Writer:     userName,
+		Packing:    packer.Packing(),
+	}
+	bp, err := packer.Pack(ctx, de)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bp.Pack(make([]byte, 2*upspin.MaxBlockSize)); !errors.Is(errors.Invalid, err) {
+		t.Errorf("Pack of oversized block: got error %v, want Invalid", err)
+	}
+}
+
 func packEntry(ctx upspin.Config, store fakeStore, packer upspin.Packer, de *upspin.DirEntry, r io.Reader) error {
 	bp, err := packer.Pack(ctx, de)
 	if err != nil {
@@ -7,6 +7,7 @@ package pack // import "upspin.io/pack"
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"upspin.io/errors"
@@ -44,7 +45,21 @@ func Lookup(p upspin.Packing) upspin.Packer {
 	return packer
 }
 
-// LookupByName returns the implementation of the specified Packing, or nil if none is registered.
+// Registered returns the Packings that have registered implementations,
+// in increasing order.
+func Registered() []upspin.Packing {
+	mu.Lock()
+	packings := make([]upspin.Packing, 0, len(packers))
+	for p := range packers {
+		packings = append(packings, p)
+	}
+	mu.Unlock()
+	sort.Slice(packings, func(i, j int) bool { return packings[i] < packings[j] })
+	return packings
+}
+
+// LookupByName returns the implementation of the Packing whose
+// String method returns name, or nil if none is registered.
 func LookupByName(name string) upspin.Packer {
 	mu.Lock()
 	defer mu.Unlock()
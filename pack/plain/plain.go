@@ -83,6 +83,9 @@ func (bp *blockPacker) Pack(cleartext []byte) (ciphertext []byte, err error) {
 	if err := internal.CheckLocationSet(bp.entry); err != nil {
 		return nil, errors.E(op, err)
 	}
+	if err := internal.CheckBlockSize(int64(len(cleartext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
 
 	ciphertext = cleartext
 
@@ -136,6 +139,9 @@ func (p plainPack) Unpack(cfg upspin.Config, d *upspin.DirEntry) (upspin.BlockUn
 	if _, err := d.Size(); err != nil {
 		return nil, errors.E(op, d.Name, err)
 	}
+	if err := internal.CheckBlockSizes(d); err != nil {
+		return nil, errors.E(op, err)
+	}
 
 	sig, sig2, err := pdUnmarshal(d.Packdata)
 	if err != nil {
@@ -181,6 +187,10 @@ type blockUnpacker struct {
 }
 
 func (bp *blockUnpacker) Unpack(ciphertext []byte) (cleartext []byte, err error) {
+	const op errors.Op = "pack/plain.blockUnpacker.Unpack"
+	if err := internal.CheckBlockSize(int64(len(ciphertext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
 	cleartext = ciphertext
 	return
 }
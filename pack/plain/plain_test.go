@@ -163,6 +163,12 @@ func TestMultiBlockRoundTrip(t *testing.T) {
 	packtest.TestMultiBlockRoundTrip(t, cfg, packer, userName)
 }
 
+func TestBlockSizeLimit(t *testing.T) {
+	const userName = upspin.UserName("aly@upspin.io")
+	cfg, packer := setup(userName)
+	packtest.TestBlockSizeLimit(t, cfg, packer, userName)
+}
+
 func setup(name upspin.UserName) (upspin.Config, upspin.Packer) {
 	cfg := config.SetUserName(config.New(), name)
 	packer := pack.Lookup(packing)
@@ -0,0 +1,199 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package plainsum is a Packing that passes the data untouched but
+// records a SHA-256 checksum of each block, which is verified when the
+// block is unpacked. Unlike plain and eeintegrity, it uses no keys and
+// signs nothing: it detects accidental corruption, not tampering.
+package plainsum // import "upspin.io/pack/plainsum"
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"upspin.io/errors"
+	"upspin.io/pack"
+	"upspin.io/pack/internal"
+	"upspin.io/path"
+	"upspin.io/upspin"
+)
+
+type plainSumPack struct{}
+
+var _ upspin.Packer = plainSumPack{}
+
+func init() {
+	pack.Register(plainSumPack{})
+}
+
+var (
+	errChecksum = errors.Str("checksum mismatch")
+	errBlockSum = errors.Str("block checksums do not match entry")
+)
+
+func (plainSumPack) Packing() upspin.Packing {
+	return upspin.PlainSumPack
+}
+
+func (plainSumPack) String() string {
+	return "plainsum"
+}
+
+func (plainSumPack) ReaderHashes(packdata []byte) ([][]byte, error) {
+	return nil, nil
+}
+
+func (plainSumPack) Share(cfg upspin.Config, readers []upspin.PublicKey, packdata []*[]byte) {
+	// Nothing to do.
+}
+
+func (p plainSumPack) Pack(cfg upspin.Config, d *upspin.DirEntry) (upspin.BlockPacker, error) {
+	const op errors.Op = "pack/plainsum.Pack"
+	if err := pack.CheckPacking(p, d); err != nil {
+		return nil, errors.E(op, errors.Invalid, d.Name, err)
+	}
+	return &blockPacker{
+		entry: d,
+	}, nil
+}
+
+type blockPacker struct {
+	entry *upspin.DirEntry
+}
+
+func (bp *blockPacker) Pack(cleartext []byte) (ciphertext []byte, err error) {
+	const op errors.Op = "pack/plainsum.blockPacker.Pack"
+	if err := internal.CheckLocationSet(bp.entry); err != nil {
+		return nil, errors.E(op, err)
+	}
+	if err := internal.CheckBlockSize(int64(len(cleartext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
+
+	ciphertext = cleartext
+
+	offs, err := bp.entry.Size()
+	if err != nil {
+		return nil, errors.E(op, errors.Invalid, err)
+	}
+	sum := sha256.Sum256(ciphertext)
+	block := upspin.DirBlock{
+		Size:     int64(len(ciphertext)),
+		Offset:   offs,
+		Packdata: sum[:],
+	}
+	bp.entry.Blocks = append(bp.entry.Blocks, block)
+	return
+}
+
+func (bp *blockPacker) SetLocation(l upspin.Location) {
+	bs := bp.entry.Blocks
+	bs[len(bs)-1].Location = l
+}
+
+// Close implements upspin.BlockPacker.
+func (bp *blockPacker) Close() error {
+	const op errors.Op = "pack/plainsum.blockPacker.Close"
+	if err := internal.CheckLocationSet(bp.entry); err != nil {
+		return errors.E(op, err)
+	}
+	// The entry's Packdata is a checksum of the block checksums,
+	// so that a block list that has lost or reordered blocks is caught.
+	bp.entry.Packdata = internal.BlockSum(bp.entry.Blocks)
+	return nil
+}
+
+// Unpack implements upspin.Packer.
+func (p plainSumPack) Unpack(cfg upspin.Config, d *upspin.DirEntry) (upspin.BlockUnpacker, error) {
+	const op errors.Op = "pack/plainsum.Unpack"
+	if err := pack.CheckPacking(p, d); err != nil {
+		return nil, errors.E(op, errors.Invalid, d.Name, err)
+	}
+
+	// Call Size to check that the block Offsets and Sizes are consistent.
+	if _, err := d.Size(); err != nil {
+		return nil, errors.E(op, d.Name, err)
+	}
+	if err := internal.CheckBlockSizes(d); err != nil {
+		return nil, errors.E(op, err)
+	}
+	if !bytes.Equal(d.Packdata, internal.BlockSum(d.Blocks)) {
+		return nil, errors.E(op, errors.Invalid, d.Name, errBlockSum)
+	}
+	return &blockUnpacker{
+		entry:        d,
+		BlockTracker: internal.NewBlockTracker(d.Blocks),
+	}, nil
+}
+
+type blockUnpacker struct {
+	entry                 *upspin.DirEntry
+	internal.BlockTracker // provides NextBlock method and Block field
+}
+
+func (bp *blockUnpacker) Unpack(ciphertext []byte) (cleartext []byte, err error) {
+	const op errors.Op = "pack/plainsum.blockUnpacker.Unpack"
+	if err := internal.CheckBlockSize(int64(len(ciphertext))); err != nil {
+		return nil, errors.E(op, bp.entry.Name, err)
+	}
+	sum := sha256.Sum256(ciphertext)
+	if got, want := sum[:], bp.entry.Blocks[bp.Block].Packdata; !bytes.Equal(got, want) {
+		return nil, errors.E(op, errors.IO, bp.entry.Name, errors.Errorf("block %d: %v", bp.Block, errChecksum))
+	}
+	cleartext = ciphertext
+	return
+}
+
+func (bp *blockUnpacker) Close() error {
+	return nil
+}
+
+// Name implements upspin.Name.
+func (p plainSumPack) Name(cfg upspin.Config, d *upspin.DirEntry, newName upspin.PathName) error {
+	const op errors.Op = "pack/plainsum.Name"
+	parsed, err := path.Parse(d.Name)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	parsedNew, err := path.Parse(newName)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if d.IsDir() && !parsed.Equal(parsedNew) {
+		return errors.E(op, d.Name, errors.IsDir, "cannot rename directory")
+	}
+	d.Name = parsedNew.Path()
+	d.SignedName = d.Name
+	return nil
+}
+
+// SetTime implements upspin.SetTime.
+func (p plainSumPack) SetTime(cfg upspin.Config, d *upspin.DirEntry, t upspin.Time) error {
+	d.Time = t
+	return nil
+}
+
+// Countersign implements upspin.Packer. There is no signature, so it does nothing.
+func (p plainSumPack) Countersign(oldKey upspin.PublicKey, f upspin.Factotum, d *upspin.DirEntry) error {
+	return nil
+}
+
+func (p plainSumPack) UnpackableByAll(d *upspin.DirEntry) (bool, error) {
+	// Content is not encrypted, so anyone can read it.
+	return true, nil
+}
+
+func (p plainSumPack) PackLen(cfg upspin.Config, cleartext []byte, entry *upspin.DirEntry) int {
+	if err := pack.CheckPacking(p, entry); err != nil {
+		return -1
+	}
+	return len(cleartext)
+}
+
+func (p plainSumPack) UnpackLen(cfg upspin.Config, ciphertext []byte, entry *upspin.DirEntry) int {
+	if err := pack.CheckPacking(p, entry); err != nil {
+		return -1
+	}
+	return len(ciphertext)
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package plainsum_test
+
+import (
+	"bytes"
+	"testing"
+
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/pack"
+	"upspin.io/pack/internal/packtest"
+	"upspin.io/upspin"
+
+	_ "upspin.io/pack/plainsum"
+)
+
+const (
+	user upspin.UserName = "joe@upspin.io"
+	name                 = upspin.PathName(user + "/file/of/user")
+)
+
+func TestRegister(t *testing.T) {
+	p := pack.Lookup(upspin.PlainSumPack)
+	if p == nil {
+		t.Fatal("Lookup failed")
+	}
+	if p.Packing() != upspin.PlainSumPack {
+		t.Fatalf("expected plainsum pack got %q", p)
+	}
+	if pack.LookupByName("plainsum") != p {
+		t.Fatal("LookupByName failed")
+	}
+}
+
+// packBlocks packs each of the blocks and returns the entry and ciphertexts.
+func packBlocks(t *testing.T, cfg upspin.Config, packer upspin.Packer, blocks ...string) (*upspin.DirEntry, [][]byte) {
+	d := &upspin.DirEntry{
+		Name:       name,
+		SignedName: name,
+		Writer:     cfg.UserName(),
+		Packing:    packer.Packing(),
+	}
+	bp, err := packer.Pack(cfg, d)
+	if err != nil {
+		t.Fatal("Pack:", err)
+	}
+	var ciphers [][]byte
+	for _, b := range blocks {
+		cipher, err := bp.Pack([]byte(b))
+		if err != nil {
+			t.Fatal("Pack:", err)
+		}
+		bp.SetLocation(upspin.Location{Reference: "dummy"})
+		ciphers = append(ciphers, append([]byte(nil), cipher...))
+	}
+	if err := bp.Close(); err != nil {
+		t.Fatal("Close:", err)
+	}
+	return d, ciphers
+}
+
+// unpackBlocks unpacks the ciphertexts and returns the concatenated clear text.
+func unpackBlocks(cfg upspin.Config, packer upspin.Packer, d *upspin.DirEntry, ciphers [][]byte) ([]byte, error) {
+	bu, err := packer.Unpack(cfg, d)
+	if err != nil {
+		return nil, err
+	}
+	var text []byte
+	for _, cipher := range ciphers {
+		if _, ok := bu.NextBlock(); !ok {
+			return nil, errors.Str("no next block")
+		}
+		clear, err := bu.Unpack(cipher)
+		if err != nil {
+			return nil, err
+		}
+		text = append(text, clear...)
+	}
+	return text, bu.Close()
+}
+
+func TestPack(t *testing.T) {
+	cfg, packer := setup(user)
+	d, ciphers := packBlocks(t, cfg, packer, "this is some text", " and some more")
+	for i, b := range d.Blocks {
+		if len(b.Packdata) == 0 {
+			t.Errorf("block %d has no checksum", i)
+		}
+	}
+	text, err := unpackBlocks(cfg, packer, d, ciphers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "this is some text and some more"; string(text) != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestCorruptBlock(t *testing.T) {
+	cfg, packer := setup(user)
+	d, ciphers := packBlocks(t, cfg, packer, "first block", "second block")
+	ciphers[1] = bytes.Replace(ciphers[1], []byte("second"), []byte("Second"), 1)
+	_, err := unpackBlocks(cfg, packer, d, ciphers)
+	if !errors.Is(errors.IO, err) {
+		t.Fatalf("Unpack of corrupt block: err = %v, want IO error", err)
+	}
+}
+
+func TestCorruptBlockList(t *testing.T) {
+	cfg, packer := setup(user)
+	d, _ := packBlocks(t, cfg, packer, "first block", "second block")
+	// Drop the last block. Its checksum was covered by the entry's.
+	d.Blocks = d.Blocks[:1]
+	_, err := packer.Unpack(cfg, d)
+	if !errors.Is(errors.Invalid, err) {
+		t.Fatalf("Unpack of truncated entry: err = %v, want Invalid error", err)
+	}
+}
+
+func TestMultiBlockRoundTrip(t *testing.T) {
+	const userName = upspin.UserName("aly@upspin.io")
+	cfg, packer := setup(userName)
+	packtest.TestMultiBlockRoundTrip(t, cfg, packer, userName)
+}
+
+func TestBlockSizeLimit(t *testing.T) {
+	const userName = upspin.UserName("aly@upspin.io")
+	cfg, packer := setup(userName)
+	packtest.TestBlockSizeLimit(t, cfg, packer, userName)
+}
+
+// setup returns a config for the user and the packer. No factotum is
+// needed as the packing uses no keys.
+func setup(name upspin.UserName) (upspin.Config, upspin.Packer) {
+	return config.SetUserName(config.New(), name), pack.Lookup(upspin.PlainSumPack)
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package path
+
+import (
+	"strings"
+
+	"upspin.io/upspin"
+)
+
+// GlobPrefix parses a Glob pattern and returns the longest prefix of it
+// that holds no glob metacharacters, with any escaping removed, and the
+// number of elements of the pattern that follow the prefix. The first of
+// those elements, if any, holds a metacharacter; the rest may or may not.
+// If the pattern holds no metacharacters, the prefix is the whole pattern
+// and the depth is zero. Metacharacters are only sought in the elements
+// after the user name, which is always treated as literal text.
+//
+// For example, the pattern "ann@example.com/a/b*/c" has prefix
+// "ann@example.com/a" and depth 2.
+func GlobPrefix(pattern upspin.PathName) (prefix Parsed, depth int, err error) {
+	p, err := Parse(pattern)
+	if err != nil {
+		return Parsed{}, 0, err
+	}
+	n := 0
+	for ; n < p.NElem(); n++ {
+		if hasMeta(p.Elem(n)) {
+			break
+		}
+	}
+	prefix, err = Parse(unquote(p.First(n).String()))
+	if err != nil {
+		return Parsed{}, 0, err
+	}
+	return prefix, p.NElem() - n, nil
+}
+
+// hasMeta reports whether the given path element contains unescaped glob
+// metacharacters.
+func hasMeta(elem string) bool {
+	esc := false
+	for _, r := range elem {
+		if esc {
+			esc = false
+			continue
+		}
+		switch r {
+		case '\\':
+			esc = true
+		case '*', '[', '?':
+			return true
+		}
+	}
+	return false
+}
+
+// unquote removes the escaping from the given pattern and returns the
+// resulting path.
+func unquote(pat string) upspin.PathName {
+	if !strings.Contains(pat, "\\") {
+		return upspin.PathName(pat)
+	}
+	b := make([]byte, 0, len(pat))
+	esc := false
+	for _, c := range []byte(pat) {
+		if !esc && c == '\\' {
+			esc = true
+			continue
+		}
+		esc = false
+		b = append(b, c)
+	}
+	if esc {
+		b = append(b, '\\')
+	}
+	return upspin.PathName(b)
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package path
+
+import (
+	"testing"
+
+	"upspin.io/upspin"
+)
+
+func TestGlobPrefix(t *testing.T) {
+	cases := []struct {
+		pattern upspin.PathName
+		prefix  upspin.PathName
+		depth   int
+	}{
+		{"a@b.co/a/b*/c", "a@b.co/a", 2},
+		{"a@b.co/a/b/c", "a@b.co/a/b/c", 0},
+		{"a@b.co", "a@b.co/", 0},
+		{"a@b.co/*", "a@b.co/", 1},
+		{"a@b.co/a/*/", "a@b.co/a", 1},
+		{"a@b.co/a/[bc]/d/*", "a@b.co/a", 3},
+		{"a@b.co//a/?", "a@b.co/a", 1},
+		// Escaped metacharacters are literal.
+		{"a@b.co/a\\*/b*", "a@b.co/a*", 1},
+		{"a@b.co/a\\*/b", "a@b.co/a*/b", 0},
+		// The user name is literal.
+		{"a*b@b.co/a/*", "a*b@b.co/a", 1},
+	}
+	for _, c := range cases {
+		prefix, depth, err := GlobPrefix(c.pattern)
+		if err != nil {
+			t.Errorf("GlobPrefix(%q): %v", c.pattern, err)
+			continue
+		}
+		if prefix.Path() != c.prefix || depth != c.depth {
+			t.Errorf("GlobPrefix(%q) = %q, %d; want %q, %d", c.pattern, prefix, depth, c.prefix, c.depth)
+		}
+	}
+	if _, _, err := GlobPrefix("not a user/*"); err == nil {
+		t.Error("GlobPrefix of bad user name succeeded")
+	}
+}
+
+func TestHasMeta(t *testing.T) {
+	cases := []struct {
+		in   string
+		want bool
+	}{
+		{"foo*", true},
+		{"fo?", true},
+		{"foo", false},
+		{"f\\*oo", false},
+		{"f\\[o]o", false},
+	}
+	for _, c := range cases {
+		got := hasMeta(c.in)
+		if got != c.want {
+			t.Errorf("hasMeta(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	cases := []struct {
+		in   string
+		want upspin.PathName
+	}{
+		{"foo", "foo"},
+		{"f[o]o", "f[o]o"},
+		{"f\\[o]o", "f[o]o"},
+		{"foo\\", "foo\\"},
+	}
+	for _, c := range cases {
+		got := unquote(c.in)
+		if got != c.want {
+			t.Errorf("unquote(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 
 	gopath "path"
 
+	"upspin.io/errors"
 	"upspin.io/upspin"
 	"upspin.io/user"
 )
@@ -238,6 +239,36 @@ func (p Parsed) HasPrefix(root Parsed) bool {
 	return len(rootStr) == len(pStr) || pStr[len(rootStr)] == '/'
 }
 
+// Rel returns the path of target relative to base: the elements of target
+// that follow those of base, separated by slashes. Both names are cleaned
+// first, as by Parse, so trailing slashes and the optional slash after a
+// user name make no difference. If target and base are the same, Rel
+// returns ".". It is an error if target is not in the subtree starting at
+// base, which includes being in another user's tree.
+func Rel(base, target upspin.PathName) (string, error) {
+	const op errors.Op = "path.Rel"
+	b, err := Parse(base)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	t, err := Parse(target)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	if !t.HasPrefix(b) {
+		return "", errors.E(op, errors.Invalid, t.Path(), errors.Errorf("not within %s", b.Path()))
+	}
+	rel := t.String()[len(b.String()):]
+	if !b.IsRoot() {
+		// Drop the slash that follows base.
+		rel = strings.TrimPrefix(rel, "/")
+	}
+	if rel == "" {
+		return ".", nil
+	}
+	return rel, nil
+}
+
 // Join appends any number of path elements onto a (possibly empty)
 // Upspin path, adding a separating slash if necessary. All empty
 // strings are ignored. The result, if non-empty, is passed through
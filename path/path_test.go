@@ -399,3 +399,38 @@ func TestFirstPath(t *testing.T) {
 		}
 	}
 }
+
+var relTests = []struct {
+	base, target upspin.PathName
+	rel          string
+	ok           bool
+}{
+	{"a@b.co/a", "a@b.co/a/b/c", "b/c", true},
+	{"a@b.co/a/", "a@b.co/a/b/c/", "b/c", true},
+	{"a@b.co/a", "a@b.co/a", ".", true},
+	{"a@b.co", "a@b.co/a/b", "a/b", true},
+	{"a@b.co/", "a@b.co/a", "a", true},
+	{"a@b.co", "a@b.co/", ".", true},
+	{"a@b.co/a/./b/..", "a@b.co/a//c", "c", true},
+	// Not within base.
+	{"a@b.co/a", "a@b.co/ab", "", false},
+	{"a@b.co/a/b", "a@b.co/a", "", false},
+	{"a@b.co/a", "c@d.co/a/b", "", false},
+	{"a@b.co/a", "a@b.co/a/../b", "", false},
+	// Bad names.
+	{"a/b", "a@b.co/a/b", "", false},
+	{"a@b.co/a", "a/b", "", false},
+}
+
+func TestRel(t *testing.T) {
+	for _, test := range relTests {
+		rel, err := Rel(test.base, test.target)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("Rel(%q, %q): error %v, want success %t", test.base, test.target, err, test.ok)
+			continue
+		}
+		if rel != test.rel {
+			t.Errorf("Rel(%q, %q) = %q, want %q", test.base, test.target, rel, test.rel)
+		}
+	}
+}
@@ -5,9 +5,14 @@
 package rpc
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"net/http"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	pb "github.com/golang/protobuf/proto"
@@ -47,10 +52,11 @@ func lookup(user upspin.UserName) (upspin.PublicKey, error) {
 type server struct {
 	t         *testing.T
 	iteration int
+	uploadErr chan error // Receives the error, if any, reading each upload.
 }
 
 func startServer(t *testing.T) (port string) {
-	srv = &server{t: t}
+	srv = &server{t: t, uploadErr: make(chan error, 1)}
 	var err error
 	port, err = testutil.PickPort()
 	if err != nil {
@@ -70,6 +76,9 @@ func startServer(t *testing.T) (port string) {
 		Streams: map[string]Stream{
 			"Count": srv.Count,
 		},
+		RequestStreams: map[string]RequestStream{
+			"Upload": srv.Upload,
+		},
 		Lookup: lookup,
 	}))
 
@@ -142,6 +151,28 @@ func (s *server) Count(session Session, reqBytes []byte, done <-chan struct{}) (
 	return out, nil
 }
 
+// Upload reassembles the data streamed by the client and responds
+// with its length and SHA-256 hash.
+func (s *server) Upload(session Session, reqBytes []byte, body io.Reader) (pb.Message, error) {
+	var req prototest.EchoRequest
+	if err := pb.Unmarshal(reqBytes, &req); err != nil {
+		return nil, err
+	}
+	log.Printf("Server: Upload request: %q", req.Payload)
+	if session.User() != joeUser {
+		s.t.Errorf("Expected user %q, got %q", joeUser, session.User())
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, body)
+	s.uploadErr <- err
+	if err != nil {
+		return nil, err
+	}
+	return &prototest.EchoResponse{
+		Payload: fmt.Sprintf("%s %d %x", req.Payload, n, h.Sum(nil)),
+	}, nil
+}
+
 type client struct {
 	Client   // For sessions and Close.
 	reqCount int
@@ -206,6 +237,83 @@ func (c *client) Count(t *testing.T, start, count int32) {
 	}
 }
 
+func (c *client) CountStream(t *testing.T, start, count int32) {
+	req := &prototest.CountRequest{
+		Start: start,
+		Count: count,
+	}
+	done := make(chan struct{})
+	defer close(done)
+	newMsg := func() pb.Message { return new(prototest.CountResponse) }
+	sr, err := c.InvokeStream("Server/Count", req, newMsg, done)
+	if err != nil {
+		t.Fatal("CountStream:", err)
+	}
+	defer sr.Close()
+	for i := int32(0); ; i++ {
+		msg, err := sr.Next()
+		if err == io.EOF {
+			if i != count {
+				t.Fatalf("stream closed after receiving %v items, want %v", i, count)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal("CountStream:", err)
+		}
+		if got, want := msg.(*prototest.CountResponse).Number, start+i; got != want {
+			t.Fatalf("stream message out of order, got %v want %v", got, want)
+		}
+	}
+}
+
+// Upload streams several megabytes of random data to the server
+// and checks that it was reassembled intact.
+func (c *client) Upload(t *testing.T) {
+	data := make([]byte, 5<<20+123)
+	rand.Read(data)
+	req := &prototest.EchoRequest{
+		Payload: "upload",
+	}
+	resp := new(prototest.EchoResponse)
+	if err := c.InvokeRequestStream("Server/Upload", req, bytes.NewReader(data), resp); err != nil {
+		t.Fatal("Upload:", err)
+	}
+	if err := <-srv.uploadErr; err != nil {
+		t.Fatal("Upload: server read:", err)
+	}
+	want := fmt.Sprintf("upload %d %x", len(data), sha256.Sum256(data))
+	if resp.Payload != want {
+		t.Errorf("Upload: got response %q, want %q", resp.Payload, want)
+	}
+}
+
+// UploadAbort streams data to the server but fails partway through,
+// and checks that the server sees the truncated stream.
+func (c *client) UploadAbort(t *testing.T) {
+	abort := errors.Str("abort")
+	body := io.MultiReader(bytes.NewReader(make([]byte, 1<<20)), iotest.ErrReader(abort))
+	req := &prototest.EchoRequest{
+		Payload: "abort",
+	}
+	resp := new(prototest.EchoResponse)
+	err := c.InvokeRequestStream("Server/Upload", req, body, resp)
+	if !errors.Is(errors.IO, err) {
+		t.Errorf("UploadAbort: got error %v, want IO error", err)
+	}
+	if !errors.Match(errors.E(abort), err) {
+		t.Errorf("UploadAbort: got error %v, want %v", err, abort)
+	}
+	select {
+	case err := <-srv.uploadErr:
+		if err == nil {
+			t.Error("UploadAbort: server read whole stream, want error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("UploadAbort: server did not see the stream end")
+	}
+}
+
 type countStream chan prototest.CountResponse
 
 func (s countStream) Send(b []byte, done <-chan struct{}) error {
@@ -283,6 +391,11 @@ func TestAll(t *testing.T) {
 
 	// Test authenticated stream.
 	cli.Count(t, 0, 5)
+	cli.CountStream(t, 10, 5)
+
+	// Test authenticated request stream.
+	cli.Upload(t)
+	cli.UploadAbort(t)
 
 	// Test that the client retries authentication properly
 	// when the server forgets the auth token.
@@ -6,17 +6,21 @@ package rpc // import "upspin.io/rpc"
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
-	"encoding/binary"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"upspin.io/bind"
 	"upspin.io/errors"
+	"upspin.io/metric"
 	"upspin.io/rpc/local"
 	"upspin.io/serverutil"
 	"upspin.io/upspin"
@@ -29,19 +33,44 @@ import (
 type Client interface {
 	Close()
 
+	// Ping reports whether the server is reachable.
+	// It implements bind.Pinger.
+	Ping() bool
+
 	// Invoke calls the given RPC method ("Server/Method") with the
 	// given request message and decodes the response into the given
 	// response message.
 	// For regular one-shot methods, the stream and done channels must be nil.
 	// For streaming RPC methods, the caller should provide a nil response
 	// and non-nil stream and done channels.
-	// TODO: remove stream param and add method InvokeStream.
+	// TODO: remove stream param in favor of InvokeStream.
 	Invoke(method string, req, resp pb.Message, stream ResponseChan, done <-chan struct{}) error
 
+	// InvokeStream calls the given streaming RPC method ("Server/Method")
+	// with the given request message and returns a StreamReader from which
+	// the caller reads the response messages, each allocated by newMsg,
+	// one at a time. Closing the done channel ends the stream.
+	// The caller must close the StreamReader when done with it.
+	InvokeStream(method string, req pb.Message, newMsg func() pb.Message, done <-chan struct{}) (*StreamReader, error)
+
+	// InvokeRequestStream calls the given request-streaming RPC method
+	// ("Server/Method") with the given request message, followed by the
+	// data read from body, which is sent as it is read rather than
+	// buffered. It decodes the response into the given response message.
+	// If reading body fails the request is abandoned, so the server
+	// sees a truncated stream, and the error is returned.
+	InvokeRequestStream(method string, req pb.Message, body io.Reader, resp pb.Message) error
+
 	// InvokeUnauthenticated invokes an unauthenticated one-shot RPC method
 	// ("Server/Method") with request body req. Upon success, resp, if nil,
 	// contains the server's reply, if any.
 	InvokeUnauthenticated(method string, req, resp pb.Message) error
+
+	// WithContext returns a Client that shares the connections and
+	// session of this one but makes its requests with ctx. The metric
+	// span carried by ctx, if any, is the parent of the client's span
+	// for each request; see metric.NewContext.
+	WithContext(ctx context.Context) Client
 }
 
 // ResponseChan describes a mechanism to report streamed messages to a client
@@ -79,8 +108,115 @@ type httpClient struct {
 	client   *http.Client
 	baseURL  string
 	proxyFor upspin.Endpoint // the server is a proxy for this endpoint.
+	opts     clientOpts
+	ctx      context.Context // the context of requests; nil means context.Background.
+
+	*clientAuth
+}
+
+// clientOpts holds the options that may be set by DialOpts.
+type clientOpts struct {
+	retries    int           // Number of times to retry an idempotent method.
+	retryDelay time.Duration // Delay before the first retry; doubled for each subsequent retry.
+
+	dialTimeout         time.Duration // Limit on establishing a connection; zero means the default.
+	tlsHandshakeTimeout time.Duration // Limit on the TLS handshake; zero means the default.
+	keepAlive           time.Duration // TCP keep-alive period; zero means the default.
+
+	certPool *x509.CertPool // Overrides the pool named by the config; nil means no override.
+}
 
-	clientAuth
+// Default transport settings, matching net/http.DefaultTransport.
+const (
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+)
+
+// DialOpts is a daisy-chaining mechanism for setting options on the Client
+// returned by NewClient.
+type DialOpts func(*clientOpts)
+
+// WithRetry returns a DialOpts that causes the client to retry idempotent
+// methods (Get, Lookup, Glob, and WhichAccess) up to n times when the request
+// fails due to a connection-level error. The first retry happens after
+// baseDelay, and the delay doubles for each subsequent retry.
+// Idempotent methods are also retried, in the same way, when the server
+// reports an error of kind errors.Transient, asking the client to try again
+// later. Other errors reported by the server are never retried, nor are
+// calls to methods that are not idempotent, such as Put.
+func WithRetry(n int, baseDelay time.Duration) DialOpts {
+	return func(o *clientOpts) {
+		o.retries = n
+		o.retryDelay = baseDelay
+	}
+}
+
+// WithDialTimeout returns a DialOpts that limits the time spent establishing
+// a network connection to the server. The default is 30 seconds.
+func WithDialTimeout(d time.Duration) DialOpts {
+	return func(o *clientOpts) {
+		o.dialTimeout = d
+	}
+}
+
+// WithTLSHandshakeTimeout returns a DialOpts that limits the time spent
+// performing the TLS handshake with the server. The default is 10 seconds.
+func WithTLSHandshakeTimeout(d time.Duration) DialOpts {
+	return func(o *clientOpts) {
+		o.tlsHandshakeTimeout = d
+	}
+}
+
+// WithKeepAlive returns a DialOpts that sets the interval between TCP
+// keep-alive probes on connections to the server, used to detect dead peers.
+// A negative duration disables keep-alives. The default is 30 seconds.
+func WithKeepAlive(d time.Duration) DialOpts {
+	return func(o *clientOpts) {
+		o.keepAlive = d
+	}
+}
+
+// WithCertPool returns a DialOpts that sets the certificate authorities
+// used to verify the server's TLS certificate, overriding those in the
+// directory named by the config's tlscerts value and the system's own.
+// It has no effect on insecure connections.
+func WithCertPool(pool *x509.CertPool) DialOpts {
+	return func(o *clientOpts) {
+		o.certPool = pool
+	}
+}
+
+// defaultDialOpts holds the options set by SetDefaultDialOpts.
+var defaultDialOpts struct {
+	sync.Mutex
+	opts []DialOpts
+}
+
+// SetDefaultDialOpts sets options to be applied by every subsequent call
+// to NewClient, before those passed to NewClient itself. It replaces the
+// options set by any previous call. Clients that already exist are not
+// affected.
+func SetDefaultDialOpts(opts ...DialOpts) {
+	defaultDialOpts.Lock()
+	defer defaultDialOpts.Unlock()
+	defaultDialOpts.opts = append([]DialOpts(nil), opts...)
+}
+
+// idempotentMethods is the set of method names (without the server prefix)
+// that may be safely retried after a transport error.
+var idempotentMethods = map[string]bool{
+	"Get":         true,
+	"Lookup":      true,
+	"Glob":        true,
+	"WhichAccess": true,
+}
+
+// isIdempotent reports whether the given RPC method ("Server/Method")
+// may be retried.
+func isIdempotent(method string) bool {
+	i := strings.LastIndex(method, "/")
+	return idempotentMethods[method[i+1:]]
 }
 
 // NewClient returns a new client that speaks to an HTTP server at a net
@@ -88,14 +224,28 @@ type httpClient struct {
 // number, as in domain.com:5580. The security level specifies the expected
 // security guarantees of the connection. If proxyFor is an assigned endpoint,
 // it indicates that this connection is being used to proxy request to that
-// endpoint.
-func NewClient(cfg upspin.Config, netAddr upspin.NetAddr, security SecurityLevel, proxyFor upspin.Endpoint) (Client, error) {
+// endpoint. The provided DialOpts, if any, configure the client's behavior,
+// after any set by SetDefaultDialOpts.
+//
+// Whatever the options, a request that fails because the idle connection it
+// used had died, as when the server restarts, is sent once more over a new
+// connection, provided the server answers a Ping and the method is idempotent
+// or the request was not sent in full.
+func NewClient(cfg upspin.Config, netAddr upspin.NetAddr, security SecurityLevel, proxyFor upspin.Endpoint, opts ...DialOpts) (Client, error) {
 	const op errors.Op = "rpc.NewClient"
 
 	c := &httpClient{
 		proxyFor: proxyFor,
 	}
-	c.clientAuth.config = cfg
+	defaultDialOpts.Lock()
+	all := append([]DialOpts(nil), defaultDialOpts.opts...)
+	defaultDialOpts.Unlock()
+	for _, o := range append(all, opts...) {
+		if o != nil {
+			o(&c.opts)
+		}
+	}
+	c.clientAuth = &clientAuth{config: cfg}
 
 	var tlsConfig *tls.Config
 	switch security {
@@ -106,9 +256,13 @@ func NewClient(cfg upspin.Config, netAddr upspin.NetAddr, security SecurityLevel
 		}
 		c.baseURL = "http://" + string(netAddr)
 	case Secure:
-		certPool, err := CertPoolFromConfig(cfg)
-		if err != nil {
-			return nil, errors.E(op, errors.Invalid, err)
+		certPool := c.opts.certPool
+		if certPool == nil {
+			var err error
+			certPool, err = CertPoolFromConfig(cfg)
+			if err != nil {
+				return nil, errors.E(op, errors.Invalid, err)
+			}
 		}
 		tlsConfig = &tls.Config{RootCAs: certPool}
 		c.baseURL = "https://" + string(netAddr)
@@ -118,17 +272,17 @@ func NewClient(cfg upspin.Config, netAddr upspin.NetAddr, security SecurityLevel
 
 	t := &http.Transport{
 		TLSClientConfig: tlsConfig,
-		// The following values are the same as
-		// net/http.DefaultTransport.
+		// Unless overridden by DialOpts, the following values
+		// are the same as net/http.DefaultTransport.
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&local.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
+			Timeout:   durationOr(c.opts.dialTimeout, defaultDialTimeout),
+			KeepAlive: durationOr(c.opts.keepAlive, defaultKeepAlive),
 		}).DialContext,
 		MaxIdleConns:          100,
 		MaxIdleConnsPerHost:   10,
 		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
+		TLSHandshakeTimeout:   durationOr(c.opts.tlsHandshakeTimeout, defaultTLSHandshakeTimeout),
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 	// TODO(adg): Re-enable HTTP/2 once it's fast enough to be usable.
@@ -140,7 +294,15 @@ func NewClient(cfg upspin.Config, netAddr upspin.NetAddr, security SecurityLevel
 	return c, nil
 }
 
-func (c *httpClient) makeAuthenticatedRequest(op errors.Op, method string, req pb.Message) (*http.Response, bool, error) {
+// durationOr returns d, or def if d is zero.
+func durationOr(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+func (c *httpClient) makeAuthenticatedRequest(op errors.Op, method string, body requestBody) (*http.Response, bool, error) {
 	token, haveToken := c.authToken()
 	header := make(http.Header)
 	needServerAuth := false
@@ -159,37 +321,174 @@ func (c *httpClient) makeAuthenticatedRequest(op errors.Op, method string, req p
 			header.Set(proxyRequestHeader, c.proxyFor.String())
 		}
 	}
-	resp, err := c.makeRequest(op, method, req, header)
+	resp, err := c.makeRequest(op, method, body, header)
 	return resp, needServerAuth, err
 }
 
-func (c *httpClient) makeRequest(op errors.Op, method string, req pb.Message, header http.Header) (*http.Response, error) {
-	// Encode the payload.
+// requestBody holds the body of a request: either an encoded request
+// message or, for request-streaming methods, a stream.
+type requestBody struct {
+	payload []byte
+	stream  *requestStreamBody
+}
+
+// newRequestBody returns a requestBody holding the encoded request message.
+func newRequestBody(op errors.Op, req pb.Message) (requestBody, error) {
 	payload, err := pb.Marshal(req)
 	if err != nil {
-		return nil, errors.E(op, err)
+		return requestBody{}, errors.E(op, err)
 	}
+	return requestBody{payload: payload}, nil
+}
+
+// reader returns a reader for a new request with the body.
+func (b requestBody) reader() io.Reader {
+	if b.stream != nil {
+		return b.stream.reader()
+	}
+	return bytes.NewReader(b.payload)
+}
+
+// makeRequest makes the HTTP request for method. It records a client span
+// lasting until the response arrives, and sends its ID to the server so
+// that the server's rpc.Serve span for the request is made a child of it.
+// The client span is a child of the span carried by the client's context,
+// if any, and otherwise begins a new trace.
+func (c *httpClient) makeRequest(op errors.Op, method string, body requestBody, header http.Header) (*http.Response, error) {
+	ctx := c.context()
+	name := errors.Op("rpc.Invoke/" + method)
+	var span *metric.Span
+	if parent := metric.FromContext(ctx); parent != nil {
+		span = parent.StartSpan(name)
+	}
+	if span == nil {
+		var m *metric.Metric
+		m, span = metric.NewSpan(name)
+		defer m.Done()
+	}
+	span.SetKind(metric.Client)
+	defer span.End()
+	header.Set(spanHeader, span.ID.String())
+
 	header.Set("Content-Type", "application/octet-stream")
+	if body.stream != nil {
+		// Hold back the stream until the server has accepted the
+		// request, so it may be sent again if authentication fails.
+		header.Set("Expect", "100-continue")
+	}
 
-	// Make the HTTP request.
+	// Make the HTTP request, retrying idempotent methods if the
+	// connection fails and the client was configured to do so.
+	// A response from the server, even one reporting an error,
+	// is never retried here.
 	url := fmt.Sprintf("%s/api/%s", c.baseURL, method)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	delay := c.opts.retryDelay
+	redialed := false
+	for i := 0; ; i++ {
+		httpReq, err := http.NewRequest("POST", url, body.reader())
+		if err != nil {
+			return nil, errors.E(op, errors.Invalid, err)
+		}
+		httpReq.Header = header
+		var conn connTrace
+		httpReq = httpReq.WithContext(httptrace.WithClientTrace(ctx, conn.trace()))
+		resp, err := c.client.Do(httpReq)
+		if err == nil {
+			return resp, nil
+		}
+		if !redialed && conn.stale(method, body) {
+			// The connection died while it sat idle, perhaps because
+			// the server restarted. If the server is there now, send
+			// the request once more, over a new connection.
+			redialed = true
+			c.client.CloseIdleConnections()
+			if c.Ping() {
+				i--
+				continue
+			}
+		}
+		if i >= c.opts.retries || !isIdempotent(method) {
+			return nil, errors.E(op, errors.IO, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// connTrace records how a request used its connection.
+type connTrace struct {
+	reused  atomic.Bool // The connection had served earlier requests.
+	written atomic.Bool // The request was written in full.
+}
+
+func (t *connTrace) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.reused.Store(info.Reused)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			t.written.Store(info.Err == nil)
+		},
+	}
+}
+
+// stale reports whether a request for method that failed may be sent again
+// over a new connection: the one it used was reused, and so may have gone
+// stale, and either the method is idempotent or the server cannot have seen
+// the whole request. Streamed requests cannot be sent again.
+func (t *connTrace) stale(method string, body requestBody) bool {
+	if !t.reused.Load() || body.stream != nil {
+		return false
+	}
+	return isIdempotent(method) || !t.written.Load()
+}
+
+// pingTimeout limits the time Ping waits for the server to answer.
+const pingTimeout = 10 * time.Second
+
+var _ bind.Pinger = (*httpClient)(nil)
+
+// Ping implements Client. Any HTTP response at all from the server,
+// even one reporting an error, shows it to be reachable.
+func (c *httpClient) Ping() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", c.baseURL+"/api/", nil)
 	if err != nil {
-		return nil, errors.E(op, errors.Invalid, err)
+		return false
 	}
-	httpReq.Header = header
-	resp, err := c.client.Do(httpReq)
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, errors.E(op, errors.IO, err)
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// WithContext implements Client.
+func (c *httpClient) WithContext(ctx context.Context) Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// context returns the context of the client's requests.
+func (c *httpClient) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
 	}
-	return resp, nil
+	return c.ctx
 }
 
 // InvokeUnauthenticated implements Client.
 func (c *httpClient) InvokeUnauthenticated(method string, req, resp pb.Message) error {
 	const op errors.Op = "rpc.InvokeUnauthenticated"
 
-	httpResp, err := c.makeRequest(op, method, req, make(http.Header))
+	body, err := newRequestBody(op, req)
+	if err != nil {
+		return err
+	}
+	httpResp, err := c.makeRequest(op, method, body, make(http.Header))
 	if err != nil {
 		return errors.E(op, errors.IO, err)
 	}
@@ -205,13 +504,70 @@ func (c *httpClient) Invoke(method string, req, resp pb.Message, stream Response
 		return errors.E(op, "exactly one of resp and stream must be nil")
 	}
 
+	reqBody, err := newRequestBody(op, req)
+	if err != nil {
+		return err
+	}
+	body, err := c.invoke(op, method, reqBody)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		// One-shot method, decode the response.
+		return readResponse(op, body, resp)
+	}
+	go decodeStream(stream, body, done)
+	return nil
+}
+
+// InvokeStream implements Client.
+func (c *httpClient) InvokeStream(method string, req pb.Message, newMsg func() pb.Message, done <-chan struct{}) (*StreamReader, error) {
+	const op errors.Op = "rpc.InvokeStream"
+
+	reqBody, err := newRequestBody(op, req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.invoke(op, method, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamReader(body, newMsg, done), nil
+}
+
+// InvokeRequestStream implements Client.
+func (c *httpClient) InvokeRequestStream(method string, req pb.Message, body io.Reader, resp pb.Message) error {
+	const op errors.Op = "rpc.InvokeRequestStream"
+
+	header, err := pb.Marshal(req)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	stream := newRequestStreamBody(header, body)
+	defer stream.close()
+	respBody, err := c.invoke(op, method, requestBody{stream: stream})
+	if err != nil {
+		if srcErr := stream.srcError(); srcErr != nil {
+			return errors.E(op, errors.IO, srcErr)
+		}
+		return err
+	}
+	return readResponse(op, respBody, resp)
+}
+
+// invoke makes an authenticated request for the given RPC method and, if the
+// server replies successfully, returns the body of its response, which the
+// caller must close.
+func (c *httpClient) invoke(op errors.Op, method string, req requestBody) (io.ReadCloser, error) {
 	var httpResp *http.Response
 	var err error
 	var needServerAuth bool
+	retries := 0
+	delay := c.opts.retryDelay
 	for i := 0; i < 2; i++ {
 		httpResp, needServerAuth, err = c.makeAuthenticatedRequest(op, method, req)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if httpResp.StatusCode != http.StatusOK {
 			msg, _ := io.ReadAll(httpResp.Body)
@@ -219,9 +575,17 @@ func (c *httpClient) Invoke(method string, req, resp pb.Message, stream Response
 			if httpResp.Header.Get("Content-type") == "application/octet-stream" {
 				err := errors.UnmarshalError(msg)
 				if err.Error() == upspin.ErrNotSupported.Error() {
-					return upspin.ErrNotSupported
+					return nil, upspin.ErrNotSupported
+				}
+				if errors.Is(errors.Transient, err) && isIdempotent(method) && retries < c.opts.retries {
+					// The server asked us to back off.
+					retries++
+					time.Sleep(delay)
+					delay *= 2
+					i--
+					continue
 				}
-				return errors.E(op, err)
+				return nil, errors.E(op, err)
 			}
 			// TODO(edpin,adg): unmarshal and check as it's more robust.
 			if bytes.Contains(msg, []byte(errUnauthenticated.Error())) {
@@ -231,26 +595,23 @@ func (c *httpClient) Invoke(method string, req, resp pb.Message, stream Response
 				c.invalidateSession()
 				continue
 			}
-			return errors.E(op, errors.IO, errors.Errorf("%s: %s", httpResp.Status, msg))
+			if httpResp.StatusCode == http.StatusNotFound {
+				// The server does not know the method,
+				// as when it predates it.
+				return nil, upspin.ErrNotSupported
+			}
+			return nil, errors.E(op, errors.IO, errors.Errorf("%s: %s", httpResp.Status, msg))
 		}
 		break
 	}
 	body := httpResp.Body
 
-	if resp != nil {
-		// One-shot method, decode the response.
-		err = readResponse(op, body, resp)
-		if err != nil {
-			return err
-		}
-	}
-
 	token := httpResp.Header.Get(authTokenHeader)
 	if len(token) == 0 {
 		authErr := httpResp.Header.Get(authErrorHeader)
 		if len(authErr) > 0 {
 			body.Close()
-			return errors.E(op, errors.Permission, authErr)
+			return nil, errors.E(op, errors.Permission, authErr)
 		}
 		// No authentication token returned, but no error either.
 		// Proceed.
@@ -263,18 +624,14 @@ func (c *httpClient) Invoke(method string, req, resp pb.Message, stream Response
 		msg, ok := httpResp.Header[authRequestHeader]
 		if !ok {
 			body.Close()
-			return errors.E(op, errors.Permission, "proxy server must authenticate")
+			return nil, errors.E(op, errors.Permission, "proxy server must authenticate")
 		}
 		if err := c.verifyServerUser(msg); err != nil {
 			body.Close()
-			return errors.E(op, errors.Permission, err)
+			return nil, errors.E(op, errors.Permission, err)
 		}
 	}
-
-	if stream != nil {
-		go decodeStream(stream, body, done)
-	}
-	return nil
+	return body, nil
 }
 
 func readResponse(op errors.Op, body io.ReadCloser, resp pb.Message) error {
@@ -294,88 +651,38 @@ func readResponse(op errors.Op, body io.ReadCloser, resp pb.Message) error {
 // closed then the stream and reader are closed and decodeStream returns.
 func decodeStream(stream ResponseChan, r io.ReadCloser, done <-chan struct{}) {
 	defer stream.Close()
-	defer r.Close()
+	sr := newStreamReader(r, nil, done)
+	defer sr.Close()
 
-	// A stream begins with the bytes "OK".
-	var ok [2]byte
-	if _, err := readFull(r, ok[:], done); err == io.ErrUnexpectedEOF {
-		// Server closed the stream.
-		return
-	} else if err != nil {
-		stream.Error(errors.E(errors.IO, err))
-		return
-	}
-	if ok[0] != 'O' || ok[1] != 'K' {
-		stream.Error(errors.E(errors.IO, "unexpected stream preamble"))
-		return
-	}
-
-	var msgLen [4]byte
-	var buf []byte
 	for {
-		// Messages are of the form
-		// [length, 4 byte, big-endian-encoded int32]
-		// [length bytes of encoded protobuf message]
-		if _, err := readFull(r, msgLen[:], done); err == io.ErrUnexpectedEOF {
-			return
-		} else if err != nil {
-			stream.Error(errors.E(errors.IO, err))
-			return
-		}
-
-		l := binary.BigEndian.Uint32(msgLen[:])
-
-		const reasonableMessageSize = 1 << 26 // 64MB
-		if l > reasonableMessageSize {
-			stream.Error(errors.E(errors.Invalid, errors.Errorf("message too long (%d bytes)", l)))
+		b, err := sr.next()
+		if err == io.EOF {
+			// Stream closed.
 			return
 		}
-		if cap(buf) < int(l) {
-			buf = make([]byte, l)
-		} else {
-			buf = buf[:l]
+		if err == io.ErrUnexpectedEOF {
+			err = errors.E(errors.IO, err)
 		}
-		if _, err := readFull(r, buf, done); err != nil {
-			stream.Error(errors.E(errors.IO, err))
+		if err != nil {
+			stream.Error(err)
 			return
 		}
-
-		if err := stream.Send(buf, done); err != nil {
+		if err := stream.Send(b, done); err != nil {
 			stream.Error(errors.E(errors.IO, err))
 			return
 		}
 	}
 }
 
-// readFull is like io.ReadFull but it will return io.EOF if the provided
-// channel is closed.
-func readFull(r io.Reader, b []byte, done <-chan struct{}) (int, error) {
-	type result struct {
-		n   int
-		err error
-	}
-	ch := make(chan result, 1)
-	go func() {
-		// TODO(adg): this may leak goroutines if the requisite reads
-		// never complete, but will that actually happen? It would be
-		// great to have something like this hooked into the runtime.
-		n, err := io.ReadFull(r, b)
-		ch <- result{n, err}
-	}()
-	select {
-	case r := <-ch:
-		return r.n, r.err
-	case <-done:
-		return 0, io.EOF
-	}
-}
-
 func (c *httpClient) isProxy() bool {
 	return c.proxyFor.Transport != upspin.Unassigned
 }
 
-// Stubs for unused methods.
-func (c *httpClient) Close() {}
+// Close implements Client by closing the connections that are not in use,
+// so that later requests are sent over new ones.
+func (c *httpClient) Close() {
+	c.client.CloseIdleConnections()
+}
 
 // clientAuth tracks the auth token and its freshness.
 type clientAuth struct {
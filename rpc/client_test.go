@@ -0,0 +1,418 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/golang/protobuf/proto"
+
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	prototest "upspin.io/rpc/testdata"
+	"upspin.io/test/testutil"
+	"upspin.io/upspin"
+)
+
+// flakyHandler drops the connection for the first fail requests
+// to each method and answers the rest with an EchoResponse.
+type flakyHandler struct {
+	fail int
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	h.mu.Lock()
+	h.calls[method]++
+	n := h.calls[method]
+	h.mu.Unlock()
+	if n <= h.fail {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		conn.Close()
+		return
+	}
+	b, err := pb.Marshal(&prototest.EchoResponse{Payload: method})
+	if err != nil {
+		panic(err)
+	}
+	w.Write(b)
+}
+
+func (h *flakyHandler) count(method string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[method]
+}
+
+func startFlakyServer(t *testing.T, fail int) (*flakyHandler, upspin.NetAddr) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &flakyHandler{fail: fail, calls: make(map[string]int)}
+	srv := &http.Server{Handler: h}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return h, upspin.NetAddr(ln.Addr().String())
+}
+
+func TestRetry(t *testing.T) {
+	const fail = 2
+	h, addr := startFlakyServer(t, fail)
+
+	cfg := config.New()
+	c, err := NewClient(cfg, addr, NoSecurity, upspin.Endpoint{}, WithRetry(fail, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An idempotent method is retried until it succeeds.
+	var resp prototest.EchoResponse
+	if err := c.InvokeUnauthenticated("Test/Get", &prototest.EchoRequest{}, &resp); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got, want := resp.Payload, "Test/Get"; got != want {
+		t.Errorf("Get payload = %q, want %q", got, want)
+	}
+	if got, want := h.count("Test/Get"), fail+1; got != want {
+		t.Errorf("Get called %d times, want %d", got, want)
+	}
+
+	// A non-idempotent method is never retried.
+	if err := c.InvokeUnauthenticated("Test/Put", &prototest.EchoRequest{}, &resp); err == nil {
+		t.Fatal("Put succeeded, expected error")
+	}
+	if got, want := h.count("Test/Put"), 1; got != want {
+		t.Errorf("Put called %d times, want %d", got, want)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	const fail = 3
+	h, addr := startFlakyServer(t, fail)
+
+	c, err := NewClient(config.New(), addr, NoSecurity, upspin.Endpoint{}, WithRetry(fail-1, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp prototest.EchoResponse
+	if err := c.InvokeUnauthenticated("Test/Lookup", &prototest.EchoRequest{}, &resp); err == nil {
+		t.Fatal("Lookup succeeded, expected error")
+	}
+	if got, want := h.count("Test/Lookup"), fail; got != want {
+		t.Errorf("Lookup called %d times, want %d", got, want)
+	}
+}
+
+func TestNoRetryByDefault(t *testing.T) {
+	h, addr := startFlakyServer(t, 1)
+
+	c, err := NewClient(config.New(), addr, NoSecurity, upspin.Endpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp prototest.EchoResponse
+	if err := c.InvokeUnauthenticated("Test/Get", &prototest.EchoRequest{}, &resp); err == nil {
+		t.Fatal("Get succeeded, expected error")
+	}
+	if got, want := h.count("Test/Get"), 1; got != want {
+		t.Errorf("Get called %d times, want %d", got, want)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	// This address is not routable, so a connection attempt
+	// will hang until it times out (or fail fast if there is
+	// no network at all).
+	const addr = "10.255.255.1:443"
+	const timeout = 100 * time.Millisecond
+
+	c, err := NewClient(config.New(), addr, Secure, upspin.Endpoint{}, WithDialTimeout(timeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	var resp prototest.EchoResponse
+	if err := c.InvokeUnauthenticated("Test/Get", &prototest.EchoRequest{}, &resp); err == nil {
+		t.Fatal("Get succeeded, expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 20*timeout {
+		t.Errorf("dial took %v, want about %v", elapsed, timeout)
+	}
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	// Accept connections but never speak, so the TLS handshake stalls.
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+
+	const timeout = 100 * time.Millisecond
+	addr := upspin.NetAddr(ln.Addr().String())
+	c, err := NewClient(config.New(), addr, Secure, upspin.Endpoint{}, WithTLSHandshakeTimeout(timeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	var resp prototest.EchoResponse
+	if err := c.InvokeUnauthenticated("Test/Get", &prototest.EchoRequest{}, &resp); err == nil {
+		t.Fatal("Get succeeded, expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 20*timeout {
+		t.Errorf("handshake took %v, want about %v", elapsed, timeout)
+	}
+}
+
+// dropHandler answers requests with an EchoResponse, except that it
+// drops the connection of the request numbered drop, counting from 1.
+type dropHandler struct {
+	drop int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (h *dropHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "HEAD" {
+		// A Ping.
+		return
+	}
+	h.mu.Lock()
+	h.calls++
+	n := h.calls
+	h.mu.Unlock()
+	if n == h.drop {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		conn.Close()
+		return
+	}
+	b, err := pb.Marshal(&prototest.EchoResponse{Payload: r.URL.Path})
+	if err != nil {
+		panic(err)
+	}
+	w.Write(b)
+}
+
+func TestRedial(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		calls  int // Calls made to the server, including the first.
+		ok     bool
+	}{
+		// An idempotent method is sent again over a new connection.
+		{"Test/Lookup", 3, true},
+		// Put might have been seen by the server, so is not.
+		{"Test/Put", 2, false},
+	} {
+		h := &dropHandler{drop: 2}
+		ln, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := &http.Server{Handler: h}
+		go srv.Serve(ln)
+		defer srv.Close()
+
+		c, err := NewClient(config.New(), upspin.NetAddr(ln.Addr().String()), NoSecurity, upspin.Endpoint{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The first call leaves an idle connection for the second,
+		// whose connection is then dropped.
+		var resp prototest.EchoResponse
+		if err := c.InvokeUnauthenticated(tc.method, &prototest.EchoRequest{}, &resp); err != nil {
+			t.Fatalf("%s: first call: %v", tc.method, err)
+		}
+		err = c.InvokeUnauthenticated(tc.method, &prototest.EchoRequest{}, &resp)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%s: second call: got error %v, want success %t", tc.method, err, tc.ok)
+		}
+		h.mu.Lock()
+		calls := h.calls
+		h.mu.Unlock()
+		if calls != tc.calls {
+			t.Errorf("%s: server saw %d calls, want %d", tc.method, calls, tc.calls)
+		}
+		if !c.Ping() {
+			t.Errorf("%s: Ping failed", tc.method)
+		}
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := upspin.NetAddr(ln.Addr().String())
+	ln.Close()
+	c, err := NewClient(config.New(), addr, NoSecurity, upspin.Endpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Ping() {
+		t.Error("Ping of closed port succeeded")
+	}
+}
+
+// busyHandler reports a Transient error for the first busy requests
+// to each method and answers the rest with an EchoResponse.
+type busyHandler struct {
+	busy int
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (h *busyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	h.mu.Lock()
+	h.calls[method]++
+	n := h.calls[method]
+	h.mu.Unlock()
+	if n <= h.busy {
+		sendError(w, errors.E(errors.Transient, errors.Str("server busy")))
+		return
+	}
+	sendResponse(w, &prototest.EchoResponse{Payload: method}, nil)
+}
+
+func (h *busyHandler) count(method string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[method]
+}
+
+func TestRetryTransient(t *testing.T) {
+	const busy = 2
+	h := &busyHandler{busy: busy, calls: make(map[string]int)}
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: h}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	f, err := factotum.NewFromDir(testutil.Repo("key", "testdata", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SetFactotum(config.SetUserName(config.New(), "user@example.com"), f)
+	c, err := NewClient(cfg, upspin.NetAddr(ln.Addr().String()), NoSecurity, upspin.Endpoint{}, WithRetry(busy, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An idempotent method is retried until the server is not busy.
+	var resp prototest.EchoResponse
+	if err := c.Invoke("Test/Lookup", &prototest.EchoRequest{}, &resp, nil, nil); err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if got, want := h.count("Test/Lookup"), busy+1; got != want {
+		t.Errorf("Lookup called %d times, want %d", got, want)
+	}
+
+	// A non-idempotent method is not, and the error keeps its kind.
+	err = c.Invoke("Test/Put", &prototest.EchoRequest{}, &resp, nil, nil)
+	if !errors.Is(errors.Transient, err) {
+		t.Fatalf("Put: err = %v, want Transient error", err)
+	}
+	if got, want := h.count("Test/Put"), 1; got != want {
+		t.Errorf("Put called %d times, want %d", got, want)
+	}
+}
+
+func TestMethodNotFound(t *testing.T) {
+	// A server that predates a method answers it with a 404.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	f, err := factotum.NewFromDir(testutil.Repo("key", "testdata", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SetFactotum(config.SetUserName(config.New(), "user@example.com"), f)
+	c, err := NewClient(cfg, upspin.NetAddr(strings.TrimPrefix(srv.URL, "http://")), NoSecurity, upspin.Endpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp prototest.EchoResponse
+	if err := c.Invoke("Test/New", &prototest.EchoRequest{}, &resp, nil, nil); err != upspin.ErrNotSupported {
+		t.Errorf("Invoke: err = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestCertPool(t *testing.T) {
+	h := &flakyHandler{calls: make(map[string]int)}
+	ts := httptest.NewTLSServer(h)
+	defer ts.Close()
+	addr := upspin.NetAddr(ts.Listener.Addr().String())
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	get := func(opts ...DialOpts) error {
+		c, err := NewClient(config.New(), addr, Secure, upspin.Endpoint{}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		var resp prototest.EchoResponse
+		return c.InvokeUnauthenticated("Test/Get", &prototest.EchoRequest{}, &resp)
+	}
+
+	// The server's certificate is self-signed, so by default it is rejected.
+	if err := get(); err == nil {
+		t.Fatal("Get succeeded without cert pool, expected error")
+	}
+	if err := get(WithCertPool(pool)); err != nil {
+		t.Fatalf("Get with cert pool: %v", err)
+	}
+
+	// The pool may be given as a default for all clients.
+	SetDefaultDialOpts(WithCertPool(pool))
+	defer SetDefaultDialOpts()
+	if err := get(); err != nil {
+		t.Fatalf("Get with default cert pool: %v", err)
+	}
+	// Options passed to NewClient take precedence.
+	if err := get(WithCertPool(x509.NewCertPool())); err == nil {
+		t.Fatal("Get succeeded with empty cert pool, expected error")
+	}
+}
@@ -44,13 +44,16 @@ func New(cfg upspin.Config, dir upspin.DirServer, addr upspin.NetAddr) http.Hand
 		Name: "Dir",
 		Methods: map[string]rpc.Method{
 			"Delete":      s.Delete,
+			"Exists":      s.Exists,
 			"Glob":        s.Glob,
 			"Lookup":      s.Lookup,
 			"Put":         s.Put,
+			"PutAll":      s.PutAll,
 			"WhichAccess": s.WhichAccess,
 		},
 		Streams: map[string]rpc.Stream{
-			"Watch": s.Watch,
+			"History": s.History,
+			"Watch":   s.Watch,
 		},
 	})
 }
@@ -63,7 +66,11 @@ func (s *server) serverFor(session rpc.Session, reqBytes []byte, req pb.Message)
 	if ep := session.ProxiedEndpoint(); ep.Transport != upspin.Unassigned {
 		e = ep
 	}
-	svc, err := s.dir.Dial(config.SetUserName(s.config, session.User()), e)
+	user, err := rpc.AuthenticatedUser(session)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := s.dir.Dial(config.SetUserName(s.config, user), e)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +105,41 @@ func (s *server) Put(session rpc.Session, reqBytes []byte) (pb.Message, error) {
 	return op.entryError(dir.Put(entry))
 }
 
+// PutAll implements upspin.DirPutAller. The response is an EntriesError.
+func (s *server) PutAll(session rpc.Session, reqBytes []byte) (pb.Message, error) {
+	var req proto.DirPutAllRequest
+	dir, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := proto.UpspinDirEntries(req.Entries)
+	if err != nil {
+		return globError(err), nil
+	}
+	op := logf(session, "PutAll(%d entries)", len(entries))
+
+	p, ok := dir.(upspin.DirPutAller)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	put, putErr := p.PutAll(entries)
+	if putErr != nil && putErr != upspin.ErrFollowLink {
+		op.log(putErr)
+		return globError(putErr), nil
+	}
+	// Fall through OK for ErrFollowLink.
+
+	b, err := proto.DirEntryBytes(put)
+	if err != nil {
+		op.log(err)
+		return globError(err), nil
+	}
+	return &proto.EntriesError{
+		Entries: b,
+		Error:   errors.MarshalError(putErr),
+	}, nil
+}
+
 // Glob implements proto.DirServer.
 func (s *server) Glob(session rpc.Session, reqBytes []byte) (pb.Message, error) {
 	var req proto.DirGlobRequest
@@ -153,12 +195,80 @@ func (s *server) Watch(session rpc.Session, reqBytes []byte, done <-chan struct{
 				op.logf("error converting event to proto: %v", err)
 				return
 			}
-			out <- ep
+			select {
+			case out <- ep:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// History implements upspin.DirHistorian. The request is a DirLookupRequest
+// and the response is a stream of Events, oldest first.
+func (s *server) History(session rpc.Session, reqBytes []byte, done <-chan struct{}) (<-chan pb.Message, error) {
+	var req proto.DirLookupRequest
+	dir, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := logf(session, "History(%q)", req.Name)
+
+	h, ok := dir.(upspin.DirHistorian)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	events, err := h.History(upspin.PathName(req.Name))
+	if err != nil {
+		op.log(err)
+		return nil, err
+	}
+
+	out := make(chan pb.Message)
+	go func() {
+		defer close(out)
+		for i := range events {
+			ep, err := proto.EventProto(&events[i])
+			if err != nil {
+				op.logf("error converting event to proto: %v", err)
+				return
+			}
+			select {
+			case out <- ep:
+			case <-done:
+				return
+			}
 		}
 	}()
 	return out, nil
 }
 
+// Exists implements upspin.DirExister. The request is a DirLookupRequest and
+// the response is an EntryError with no entry. The error is nil if the item
+// exists and NotExist if it does not.
+func (s *server) Exists(session rpc.Session, reqBytes []byte) (pb.Message, error) {
+	var req proto.DirLookupRequest
+	dir, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := logf(session, "Exists(%q)", req.Name)
+
+	e, ok := dir.(upspin.DirExister)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	exists, err := e.Exists(upspin.PathName(req.Name))
+	if err == nil && !exists {
+		err = errors.E(errors.NotExist, upspin.PathName(req.Name))
+	}
+	if err != nil && err != upspin.ErrFollowLink {
+		op.log(err)
+	}
+	return &proto.EntryError{Error: errors.MarshalError(err)}, nil
+}
+
 // Delete implements proto.DirServer.
 func (s *server) Delete(session rpc.Session, reqBytes []byte) (pb.Message, error) {
 	var req proto.DirDeleteRequest
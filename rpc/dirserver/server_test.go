@@ -0,0 +1,252 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dirserver_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"upspin.io/bind"
+	"upspin.io/cloud/https"
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	"upspin.io/pack"
+	"upspin.io/rpc/dirserver"
+	"upspin.io/serverutil/perm"
+	"upspin.io/test/testutil"
+	"upspin.io/upspin"
+
+	dirserver_server "upspin.io/dir/server"
+	keyserver "upspin.io/key/inprocess"
+	storeserver "upspin.io/store/inprocess"
+
+	_ "upspin.io/dir/remote"
+	_ "upspin.io/pack/eeintegrity"
+	_ "upspin.io/pack/plain"
+)
+
+const (
+	serverName = "dirserver@example.com"
+	owner      = "joe@example.com"
+	reader     = "bob@example.com"
+)
+
+var inProcess = upspin.Endpoint{Transport: upspin.InProcess}
+
+// startServer starts a permission-checked dir/server, as the directory
+// servers run it, behind this package's RPC server. It returns the
+// server's endpoint.
+func startServer(t *testing.T) upspin.Endpoint {
+	port, err := testutil.PickPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := upspin.NetAddr("localhost:" + port)
+	ep := upspin.Endpoint{Transport: upspin.Remote, NetAddr: addr}
+
+	bind.RegisterKeyServer(upspin.InProcess, keyserver.New())
+	bind.RegisterStoreServer(upspin.InProcess, storeserver.New())
+	cfg := newConfig(t, serverName, "dir-server", ep)
+	for _, u := range []struct {
+		name upspin.UserName
+		keys string
+	}{
+		{serverName, "dir-server"},
+		{owner, "joe"},
+		{reader, "bob"},
+	} {
+		putUser(t, cfg, u.name, u.keys, ep)
+	}
+
+	logDir, err := os.MkdirTemp("", "dirserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(logDir) })
+	dir, err := dirserver_server.New(cfg, "logDir="+logDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan struct{})
+	close(ready)
+	dir = perm.WrapDir(cfg, ready, serverName, dir)
+	http.Handle("/api/Dir/", dirserver.New(cfg, dir, addr))
+
+	ready = make(chan struct{})
+	go https.ListenAndServe(ready, &https.Options{Addr: string(addr)})
+	<-ready
+	return ep
+}
+
+// newConfig returns a config for the named user, with the keys in the
+// named directory of key/testdata, that uses the given directory server
+// and in-process key and store servers.
+func newConfig(t *testing.T, name upspin.UserName, keys string, dir upspin.Endpoint) upspin.Config {
+	f, err := factotum.NewFromDir(testutil.Repo("key", "testdata", keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg = config.SetUserName(cfg, name)
+	cfg = config.SetFactotum(cfg, f)
+	cfg = config.SetPacking(cfg, upspin.EEIntegrityPack)
+	cfg = config.SetKeyEndpoint(cfg, inProcess)
+	cfg = config.SetStoreEndpoint(cfg, inProcess)
+	cfg = config.SetDirEndpoint(cfg, dir)
+	return config.SetValue(cfg, "tlscerts", testutil.Repo("rpc", "testdata"))
+}
+
+// putUser records the named user, with the keys in the named directory
+// of key/testdata, in the key server.
+func putUser(t *testing.T, cfg upspin.Config, name upspin.UserName, keys string, dir upspin.Endpoint) {
+	f, err := factotum.NewFromDir(testutil.Repo("key", "testdata", keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := bind.KeyServer(cfg, cfg.KeyEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = key.Put(&upspin.User{
+		Name:      name,
+		Dirs:      []upspin.Endpoint{dir},
+		Stores:    []upspin.Endpoint{inProcess},
+		PublicKey: f.PublicKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// accessEntry packs and stores the contents of an Access file and
+// returns its entry.
+func accessEntry(t *testing.T, cfg upspin.Config, name upspin.PathName, contents string) *upspin.DirEntry {
+	entry := &upspin.DirEntry{
+		Name:       name,
+		SignedName: name,
+		Time:       upspin.Now(),
+		Sequence:   upspin.SeqIgnore,
+		Writer:     cfg.UserName(),
+		Packing:    upspin.EEIntegrityPack,
+	}
+	bp, err := pack.Lookup(upspin.EEIntegrityPack).Pack(cfg, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := bp.Pack([]byte(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := bind.StoreServer(cfg, cfg.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refdata, err := store.Put(cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp.SetLocation(upspin.Location{Endpoint: store.Endpoint(), Reference: refdata.Reference})
+	if err := bp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func dirEntry(name upspin.PathName) *upspin.DirEntry {
+	return &upspin.DirEntry{
+		Name:       name,
+		SignedName: name,
+		Attr:       upspin.AttrDirectory,
+		Sequence:   upspin.SeqIgnore,
+	}
+}
+
+func fileEntry(name upspin.PathName) *upspin.DirEntry {
+	return &upspin.DirEntry{
+		Name:       name,
+		SignedName: name,
+		Writer:     owner,
+		Sequence:   upspin.SeqNotExist,
+		Packing:    upspin.PlainPack,
+	}
+}
+
+type putAller interface {
+	upspin.DirServer
+	upspin.DirPutAller
+}
+
+// dial returns the client for the directory server at ep for cfg's user.
+func dial(t *testing.T, cfg upspin.Config, ep upspin.Endpoint) putAller {
+	dir, err := bind.DirServer(cfg, ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := dir.(putAller)
+	if !ok {
+		t.Fatal("remote DirServer does not implement upspin.DirPutAller")
+	}
+	return p
+}
+
+func TestPutAll(t *testing.T) {
+	ep := startServer(t)
+	ownerCfg := newConfig(t, owner, "joe", ep)
+	ownerDir := dial(t, ownerCfg, ep)
+	readerCfg := newConfig(t, reader, "bob", ep)
+	readerDir := dial(t, readerCfg, ep)
+
+	if _, err := ownerDir.Put(dirEntry(owner + "/")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory, its Access file and a file protected by it.
+	entries := []*upspin.DirEntry{
+		dirEntry(owner + "/dir"),
+		accessEntry(t, ownerCfg, owner+"/dir/Access", fmt.Sprintf("*: %s\nr: %s\n", owner, reader)),
+		fileEntry(owner + "/dir/file"),
+	}
+	put, err := ownerDir.PutAll(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(put) != len(entries) {
+		t.Fatalf("PutAll returned %d entries, want %d", len(put), len(entries))
+	}
+	for i, e := range entries {
+		if !put[i].IsIncomplete() {
+			t.Errorf("entry returned for %s is not incomplete", e.Name)
+		}
+		got, err := ownerDir.Lookup(e.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.IsDir() && got.Sequence != put[i].Sequence {
+			t.Errorf("Lookup(%s) has sequence %d, want %d", e.Name, got.Sequence, put[i].Sequence)
+		}
+	}
+	// The Access file put with the file governs it.
+	if _, err := readerDir.Lookup(owner + "/dir/file"); err != nil {
+		t.Errorf("Lookup by reader: %v", err)
+	}
+
+	// If any entry cannot be put, none is.
+	_, err = ownerDir.PutAll([]*upspin.DirEntry{fileEntry(owner + "/dir/new"), fileEntry(owner + "/dir/file")})
+	if !errors.Is(errors.Exist, err) {
+		t.Errorf("PutAll of existing file: err = %v, want Exist", err)
+	}
+	if _, err := ownerDir.Lookup(owner + "/dir/new"); !errors.Is(errors.NotExist, err) {
+		t.Errorf("after failed PutAll, Lookup: err = %v, want NotExist", err)
+	}
+
+	// The reader may not write.
+	_, err = readerDir.PutAll([]*upspin.DirEntry{fileEntry(owner + "/dir/other")})
+	if !errors.Is(errors.Permission, err) {
+		t.Errorf("PutAll by reader: err = %v, want Permission", err)
+	}
+}
@@ -28,6 +28,13 @@ Each encoded message is preceded by a four byte, big-endian-encoded int32
 that describes the length of the following encoded protocol buffer. The
 stream is considered closed when the HTTP response stream ends.
 
+For methods with streaming requests, the request is sent as a series of
+frames, each a four byte, big-endian-encoded length followed by that many
+bytes. The first frame holds the encoded request message and the following
+ones successive chunks of the streamed data. A frame of length zero ends
+the stream; if the request ends without it, the server treats the stream
+as abandoned. The response is the same as for a one-shot method.
+
 If an error occurs while processing a request, the server returns a 500
 Internal Server Error status code and the response body contains the error
 string.
@@ -63,6 +70,21 @@ returns an error message in the 'Upspin-Auth-Error' response header.
 
 TODO: document the 'Upspin-Proxy-Request' header.
 
+Tracing
+
+Each request may carry an 'Upspin-Span' header identifying the client's
+metric span for the call, as formatted by metric.SpanID's String method.
+The server's span for the request is made a child of it, sharing its trace,
+so the two may be joined to see how much of a call's time was spent in the
+server. A missing or malformed header is ignored.
+
+The spans link end to end. A Client made by WithContext with a context
+carrying a span, as set by metric.NewContext, makes its "rpc.Invoke/<method>"
+span a child of that span; otherwise the client's span begins a new trace.
+On the server, the context of the Session passed to each authenticated
+method carries the server's "rpc.Serve/<service>/<method>" span, so the
+spans the method records, found with metric.FromContext, are its children.
+
 Encoding
 
 The arguments to each method, and the returned values, are encoded as
@@ -0,0 +1,101 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"upspin.io/upspin"
+)
+
+// Call describes an RPC call to an Interceptor.
+type Call struct {
+	// Service is the name of the Service being called.
+	Service string
+
+	// Method is the name of the method being called.
+	Method string
+
+	// Session is the caller's session. It is nil for an
+	// UnauthenticatedMethod.
+	Session Session
+}
+
+// User returns the authenticated user making the call,
+// or the empty string for an UnauthenticatedMethod.
+func (c *Call) User() upspin.UserName {
+	if c.Session == nil {
+		return ""
+	}
+	return c.Session.User()
+}
+
+// An Interceptor is called before each method of a Service runs, of
+// whatever kind, once the caller has been authenticated. If it returns an
+// error, the method is not run and the error is sent to the client instead.
+// Otherwise, if it returns a non-nil done function, done is called when the
+// method has finished, with the time it took and the error it returned, if
+// any. A Stream has finished once it has closed its channel; a
+// RequestStream once it has returned its response.
+type Interceptor func(call *Call) (done func(elapsed time.Duration, err error), err error)
+
+var (
+	interceptorsMu sync.Mutex
+	interceptors   []Interceptor
+)
+
+// RegisterInterceptor adds an Interceptor to be run for every method of
+// every Service served by servers created afterwards by NewServer.
+// Registered Interceptors run in the order they were registered, before
+// those of the Service itself.
+func RegisterInterceptor(i Interceptor) {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	interceptors = append(interceptors, i)
+}
+
+// registeredInterceptors returns the registered Interceptors followed
+// by those given.
+func registeredInterceptors(more []Interceptor) []Interceptor {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	var list []Interceptor
+	list = append(list, interceptors...)
+	return append(list, more...)
+}
+
+// intercept runs the server's Interceptors for the call. If one refuses
+// the call, intercept returns its error, having told the Interceptors
+// that ran before it. Otherwise it returns a function to be called with
+// the result of the method, which tells each Interceptor, in reverse order,
+// how the method fared.
+func (s *serverImpl) intercept(call *Call) (finish func(error), err error) {
+	var (
+		dones []func(time.Duration, error)
+		start time.Time // Zero until the method starts.
+	)
+	finish = func(err error) {
+		var elapsed time.Duration
+		if !start.IsZero() {
+			elapsed = time.Since(start)
+		}
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](elapsed, err)
+		}
+	}
+	for _, i := range s.interceptors {
+		done, err := i(call)
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		if done != nil {
+			dones = append(dones, done)
+		}
+	}
+	start = time.Now()
+	return finish, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/golang/protobuf/proto"
+
+	"upspin.io/config"
+	"upspin.io/errors"
+	prototest "upspin.io/rpc/testdata"
+	"upspin.io/upspin"
+)
+
+func TestInterceptors(t *testing.T) {
+	const (
+		token = "0123456789ABCDEF0123456789ABCDEF"
+		user  = upspin.UserName("caller@upspin.io")
+	)
+	NewSession(user, time.Now().Add(time.Hour), token, &upspin.Endpoint{}, nil)
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		events = append(events, s)
+		mu.Unlock()
+	}
+	errRefused := errors.E(errors.Permission, "refused")
+	// outer records every call; inner refuses calls to Refuse.
+	outer := func(call *Call) (func(time.Duration, error), error) {
+		record("start " + call.Method + " " + string(call.User()))
+		return func(elapsed time.Duration, err error) {
+			record("done " + call.Method + " " + errString(err))
+		}, nil
+	}
+	inner := func(call *Call) (func(time.Duration, error), error) {
+		if call.Method == "Refuse" {
+			return nil, errRefused
+		}
+		return nil, nil
+	}
+
+	echo := func(_ Session, _ []byte) (pb.Message, error) {
+		record("run Echo")
+		return &prototest.EchoResponse{}, nil
+	}
+	count := func(_ Session, _ []byte, done <-chan struct{}) (<-chan pb.Message, error) {
+		out := make(chan pb.Message)
+		go func() {
+			defer close(out)
+			for i := int32(0); i < 3; i++ {
+				select {
+				case out <- &prototest.CountResponse{Number: i}:
+				case <-done:
+					return
+				}
+			}
+			record("run Count")
+		}()
+		return out, nil
+	}
+	cfg := config.SetUserName(config.New(), "server@upspin.io")
+	ts := httptest.NewServer(NewServer(cfg, Service{
+		Name: "Intercept",
+		Methods: map[string]Method{
+			"Echo":   echo,
+			"Refuse": echo,
+		},
+		Streams: map[string]Stream{
+			"Count": count,
+		},
+		Interceptors: []Interceptor{outer, inner},
+	}))
+	defer ts.Close()
+
+	call := func(method string) (int, []byte) {
+		req, err := http.NewRequest("POST", ts.URL+"/api/Intercept/"+method, bytes.NewReader(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(authTokenHeader, token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, b
+	}
+
+	if code, _ := call("Echo"); code != http.StatusOK {
+		t.Errorf("Echo: status %d, want OK", code)
+	}
+	code, b := call("Refuse")
+	if code != http.StatusInternalServerError {
+		t.Errorf("Refuse: status %d, want %d", code, http.StatusInternalServerError)
+	}
+	if err := errors.UnmarshalError(b); !errors.Is(errors.Permission, err) {
+		t.Errorf("Refuse: got error %v, want Permission", err)
+	}
+	if _, b := call("Count"); !bytes.Equal(b, encodeStream(t, 0, 1, 2)) {
+		t.Errorf("Count: got %q, want three messages", b)
+	}
+
+	want := []string{
+		"start Echo caller@upspin.io",
+		"run Echo",
+		"done Echo <nil>",
+		"start Refuse caller@upspin.io",
+		"done Refuse " + errRefused.Error(),
+		"start Count caller@upspin.io",
+		"run Count",
+		"done Count <nil>",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
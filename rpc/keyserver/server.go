@@ -56,10 +56,16 @@ func New(cfg upspin.Config, key upspin.KeyServer, addr upspin.NetAddr) http.Hand
 	return rpc.NewServer(cfg, rpc.Service{
 		Name: "Key",
 		Methods: map[string]rpc.Method{
-			"Put": s.Put,
+			"Put":        s.Put,
+			"Rotate":     s.Rotate,
+			"KeyHistory": s.KeyHistory,
 		},
 		UnauthenticatedMethods: map[string]rpc.UnauthenticatedMethod{
-			"Lookup": s.Lookup,
+			"Lookup":      s.Lookup,
+			"LookupBatch": s.LookupBatch,
+		},
+		Streams: map[string]rpc.Stream{
+			"Watch": s.Watch,
 		},
 		Lookup: func(userName upspin.UserName) (upspin.PublicKey, error) {
 			user, err := key.Lookup(userName)
@@ -103,7 +109,11 @@ func (s *server) serverFor(session rpc.Session, reqBytes []byte, req pb.Message)
 	if err := pb.Unmarshal(reqBytes, req); err != nil {
 		return nil, err
 	}
-	svc, err := s.key.Dial(config.SetUserName(s.config, session.User()), s.key.Endpoint())
+	user, err := rpc.AuthenticatedUser(session)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := s.key.Dial(config.SetUserName(s.config, user), s.key.Endpoint())
 	if err != nil {
 		return nil, err
 	}
@@ -124,18 +134,67 @@ func (s *server) Lookup(reqBytes []byte) (pb.Message, error) {
 	}
 
 	user, err := s.key.Lookup(upspin.UserName(req.UserName))
-	if err != nil {
-		if doLog {
-			logf(nil, "Lookup(%q) failed: %s", req.UserName, err)
+	if err != nil && doLog {
+		logf(nil, "Lookup(%q) failed: %s", req.UserName, err)
+	}
+	return lookupResponse(upspin.UserName(req.UserName), user, err), nil
+}
+
+// LookupBatch implements proto.KeyServer, and does not do any authentication.
+// If the underlying key server does not implement upspin.KeyLookupBatcher,
+// the users are looked up one at a time.
+func (s *server) LookupBatch(reqBytes []byte) (pb.Message, error) {
+	var req proto.KeyLookupBatchRequest
+	if err := pb.Unmarshal(reqBytes, &req); err != nil {
+		return nil, err
+	}
+	names := make([]upspin.UserName, len(req.UserNames))
+	for i, name := range req.UserNames {
+		names[i] = upspin.UserName(name)
+		s.incLookupCounters()
+	}
+	doLog := s.lookupLogCounter.Rate() < lookupLogMaxRate
+	if doLog {
+		s.lookupLogCounter.Add(1)
+		logf(nil, "LookupBatch(%q)", names)
+	}
+
+	var (
+		users []*upspin.User
+		errs  []error
+	)
+	if b, ok := s.key.(upspin.KeyLookupBatcher); ok {
+		users, errs = b.LookupBatch(names)
+	} else {
+		users = make([]*upspin.User, len(names))
+		errs = make([]error, len(names))
+		for i, name := range names {
+			users[i], errs[i] = s.key.Lookup(name)
 		}
+	}
+	resp := &proto.KeyLookupBatchResponse{
+		Responses: make([]*proto.KeyLookupResponse, len(names)),
+	}
+	for i, name := range names {
+		if errs[i] != nil && doLog {
+			logf(nil, "LookupBatch(%q) failed: %s", name, errs[i])
+		}
+		resp.Responses[i] = lookupResponse(name, users[i], errs[i])
+	}
+	return resp, nil
+}
+
+// lookupResponse returns the response to a Lookup of the named user.
+func lookupResponse(name upspin.UserName, user *upspin.User, err error) *proto.KeyLookupResponse {
+	if err != nil {
 		if errors.Is(errors.NotExist, err) {
 			// The end user doesn't care about the backend
 			// error if it's a "not exist" error.
-			err = errors.E(errors.Op("rpc/keyserver"), upspin.UserName(req.UserName), errors.NotExist)
+			err = errors.E(errors.Op("rpc/keyserver"), name, errors.NotExist)
 		}
-		return &proto.KeyLookupResponse{Error: errors.MarshalError(err)}, nil
+		return &proto.KeyLookupResponse{Error: errors.MarshalError(err)}
 	}
-	return &proto.KeyLookupResponse{User: proto.UserProto(user)}, nil
+	return &proto.KeyLookupResponse{User: proto.UserProto(user)}
 }
 
 // Put implements proto.KeyServer.
@@ -157,10 +216,101 @@ func (s *server) Put(session rpc.Session, reqBytes []byte) (pb.Message, error) {
 	return &proto.KeyPutResponse{}, nil
 }
 
+// Rotate implements upspin.KeyRotator.
+func (s *server) Rotate(session rpc.Session, reqBytes []byte) (pb.Message, error) {
+	var req proto.KeyRotateRequest
+	key, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := logf(session, "Rotate(%v)", req.User)
+	s.incPutCounters()
+
+	r, ok := key.(upspin.KeyRotator)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	err = r.Rotate(proto.UpspinUser(req.User), proto.UpspinSignature(req.SignatureR, req.SignatureS))
+	if err != nil {
+		op.log(err)
+		return putError(err), nil
+	}
+	return &proto.KeyPutResponse{}, nil
+}
+
+// KeyHistory implements upspin.KeyRotator.
+func (s *server) KeyHistory(session rpc.Session, reqBytes []byte) (pb.Message, error) {
+	var req proto.KeyLookupRequest
+	key, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := logf(session, "KeyHistory(%q)", req.UserName)
+	s.incLookupCounters()
+
+	r, ok := key.(upspin.KeyRotator)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	history, err := r.KeyHistory(upspin.UserName(req.UserName))
+	if err != nil {
+		op.log(err)
+		return &proto.KeyHistoryResponse{Error: errors.MarshalError(err)}, nil
+	}
+	resp := &proto.KeyHistoryResponse{
+		Rotations: make([]*proto.KeyRotation, len(history)),
+	}
+	for i := range history {
+		resp.Rotations[i] = proto.KeyRotationProto(&history[i])
+	}
+	return resp, nil
+}
+
 func putError(err error) *proto.KeyPutResponse {
 	return &proto.KeyPutResponse{Error: errors.MarshalError(err)}
 }
 
+// Watch implements upspin.KeyWatcher. User records are public,
+// so any authenticated user may watch them.
+func (s *server) Watch(session rpc.Session, reqBytes []byte, done <-chan struct{}) (<-chan pb.Message, error) {
+	var req proto.KeyWatchRequest
+	key, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := logf(session, "Watch(%d)", req.Sequence)
+
+	w, ok := key.(upspin.KeyWatcher)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	events, err := w.Watch(req.Sequence, done)
+	if err != nil {
+		op.log(err)
+		return nil, err
+	}
+
+	out := make(chan pb.Message)
+	go func() {
+		defer close(out)
+		for e := range events {
+			ep := &proto.KeyEvent{Sequence: e.Sequence}
+			if e.User != nil {
+				ep.User = proto.UserProto(e.User)
+			}
+			if e.Error != nil {
+				ep.Error = errors.MarshalError(e.Error)
+			}
+			select {
+			case out <- ep:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
 func logf(sess rpc.Session, format string, args ...interface{}) operation {
 	op := "rpc/keyserver: "
 	if sess != nil {
@@ -0,0 +1,205 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"upspin.io/errors"
+)
+
+// requestChunkSize is the largest chunk of data a client sends
+// in one frame of a streaming request.
+const requestChunkSize = 1 << 16 // 64KB
+
+// A streaming request is a series of frames, each a four byte,
+// big-endian-encoded length followed by that many bytes. The first
+// frame holds the encoded request message; the following frames hold
+// chunks of the streamed data. A frame of length zero ends the stream.
+
+// writeFrame writes b to w as a single frame.
+func writeFrame(w io.Writer, b []byte) error {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+	if _, err := w.Write(l[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// readFrameLength reads the length that begins a frame. It returns
+// io.ErrUnexpectedEOF if r ends before the length is complete.
+func readFrameLength(r io.Reader) (uint32, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, errors.E(errors.IO, err)
+	}
+	n := binary.BigEndian.Uint32(l[:])
+	if n > reasonableMessageSize {
+		return 0, errors.E(errors.Invalid, errors.Errorf("message too long (%d bytes)", n))
+	}
+	return n, nil
+}
+
+// readRequestHeader reads the first frame of a streaming request,
+// which holds the encoded request message.
+func readRequestHeader(r io.Reader) ([]byte, error) {
+	n, err := readFrameLength(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, errors.E(errors.IO, err)
+	}
+	return b, nil
+}
+
+// requestStreamReader is the io.Reader given to a RequestStream.
+// It reads the data held in the frames that follow the request message.
+type requestStreamReader struct {
+	r         io.Reader
+	remaining uint32 // Bytes left in the current frame.
+	err       error  // Sticky error, io.EOF after the final frame.
+}
+
+// Read implements io.Reader. It returns io.EOF once the frame ending the
+// stream has been read, and io.ErrUnexpectedEOF if the stream is truncated
+// before then, as happens if the client abandons the request.
+func (s *requestStreamReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.remaining == 0 {
+		n, err := readFrameLength(s.r)
+		if err != nil {
+			s.err = err
+			return 0, err
+		}
+		if n == 0 {
+			s.err = io.EOF
+			return 0, io.EOF
+		}
+		s.remaining = n
+	}
+	if uint32(len(p)) > s.remaining {
+		p = p[:s.remaining]
+	}
+	n, err := s.r.Read(p)
+	s.remaining -= uint32(n)
+	if n > 0 || err == nil {
+		// Any error will recur on the next call.
+		return n, nil
+	}
+	if err == io.EOF {
+		// The stream ended before its final frame.
+		err = io.ErrUnexpectedEOF
+	}
+	if err != io.ErrUnexpectedEOF {
+		err = errors.E(errors.IO, err)
+	}
+	s.err = err
+	return 0, err
+}
+
+// requestStreamBody provides the body of a streaming request, framing
+// the data read from the source as it is consumed by the HTTP client.
+type requestStreamBody struct {
+	pr *io.PipeReader
+	pw *io.PipeWriter
+
+	mu     sync.Mutex
+	used   bool  // Whether the body has been read.
+	srcErr error // Error reading from the source, if any.
+}
+
+func newRequestStreamBody(header []byte, src io.Reader) *requestStreamBody {
+	pr, pw := io.Pipe()
+	b := &requestStreamBody{pr: pr, pw: pw}
+	go b.write(header, src)
+	return b
+}
+
+// write writes the frames of the request into the pipe.
+func (b *requestStreamBody) write(header []byte, src io.Reader) {
+	if err := writeFrame(b.pw, header); err != nil {
+		return // The request is over.
+	}
+	buf := make([]byte, requestChunkSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if werr := writeFrame(b.pw, buf[:n]); werr != nil {
+				return // The request is over.
+			}
+		}
+		if err == io.EOF {
+			writeFrame(b.pw, nil)
+			b.pw.Close()
+			return
+		}
+		if err != nil {
+			b.mu.Lock()
+			b.srcErr = err
+			b.mu.Unlock()
+			// Abandon the request; the server will see
+			// the stream end without its final frame.
+			b.pw.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// reader returns the reader for the body of an HTTP request. Since the
+// stream cannot be replayed, once it has been read by one request it
+// returns an error for any other.
+func (b *requestStreamBody) reader() io.Reader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used {
+		return errReader{errors.Str("streaming request cannot be resent")}
+	}
+	return bodyReader{b}
+}
+
+// bodyReader is an io.Reader that records that the body has been read.
+type bodyReader struct {
+	b *requestStreamBody
+}
+
+func (r bodyReader) Read(p []byte) (int, error) {
+	r.b.mu.Lock()
+	r.b.used = true
+	r.b.mu.Unlock()
+	return r.b.pr.Read(p)
+}
+
+// srcError returns the error, if any, from reading the source.
+func (b *requestStreamBody) srcError() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.srcErr
+}
+
+// close releases the goroutine writing the frames, if it is still running.
+func (b *requestStreamBody) close() {
+	b.pr.Close()
+}
+
+// errReader is an io.Reader that always returns an error.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
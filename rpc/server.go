@@ -20,6 +20,7 @@ import (
 	"upspin.io/errors"
 	"upspin.io/factotum"
 	"upspin.io/log"
+	"upspin.io/metric"
 	"upspin.io/upspin"
 	"upspin.io/valid"
 )
@@ -46,6 +47,10 @@ const (
 	// proxyRequestHeader key is for inline proxy configuration requests.
 	proxyRequestHeader = "Upspin-Proxy-Request"
 
+	// spanHeader is the key for the metric.SpanID of the client's span
+	// for the request, of which the server's span is made a child.
+	spanHeader = "Upspin-Span"
+
 	// authTokenEntropyLen is the size of random bytes in an auth token.
 	authTokenEntropyLen = 16
 
@@ -71,10 +76,17 @@ type Service struct {
 	// The streaming RPC methods to serve.
 	Streams map[string]Stream
 
+	// The RPC methods to serve whose requests are streamed.
+	RequestStreams map[string]RequestStream
+
 	// Lookup is KeyServer.Lookup function that should be used for key
 	// lookups during authentication.
 	// If nil, PublicUserKeyService will be used.
 	Lookup func(userName upspin.UserName) (upspin.PublicKey, error)
+
+	// Interceptors are run, in order, around each call to a method of
+	// the service, after any registered with RegisterInterceptor.
+	Interceptors []Interceptor
 }
 
 // Method describes an authenticated RPC method.
@@ -85,8 +97,18 @@ type Method func(s Session, reqBytes []byte) (pb.Message, error)
 type UnauthenticatedMethod func(reqBytes []byte) (pb.Message, error)
 
 // Stream describes an authenticated streaming RPC method.
+// The server writes each message to the client before receiving the
+// next, so a Stream that sends on an unbuffered channel is paced by
+// the client. The done channel is closed when the client goes away;
+// the Stream should then stop sending and close its channel.
 type Stream func(s Session, reqBytes []byte, done <-chan struct{}) (<-chan pb.Message, error)
 
+// RequestStream describes an authenticated RPC method whose request
+// message is followed by a stream of data, which the method reads from
+// body. Reading body returns io.EOF at the end of the stream and
+// io.ErrUnexpectedEOF if the client abandoned it part way.
+type RequestStream func(s Session, reqBytes []byte, body io.Reader) (pb.Message, error)
+
 // NewServer returns a new Server that uses the given ServerConfig.
 func NewServer(cfg upspin.Config, svc Service) http.Handler {
 	// Validate Service.
@@ -106,16 +128,29 @@ func NewServer(cfg upspin.Config, svc Service) http.Handler {
 			panic(fmt.Sprintf("Stream %q also specified as UnauthenticatedMethod", name))
 		}
 	}
+	for name := range svc.RequestStreams {
+		if _, ok := svc.Methods[name]; ok {
+			panic(fmt.Sprintf("RequestStream %q also specified as Method", name))
+		}
+		if _, ok := svc.UnauthenticatedMethods[name]; ok {
+			panic(fmt.Sprintf("RequestStream %q also specified as UnauthenticatedMethod", name))
+		}
+		if _, ok := svc.Streams[name]; ok {
+			panic(fmt.Sprintf("RequestStream %q also specified as Stream", name))
+		}
+	}
 
 	return &serverImpl{
-		config:  cfg,
-		service: svc,
+		config:       cfg,
+		service:      svc,
+		interceptors: registeredInterceptors(svc.Interceptors),
 	}
 }
 
 type serverImpl struct {
-	config  upspin.Config
-	service Service
+	config       upspin.Config
+	service      Service
+	interceptors []Interceptor
 }
 
 func (s *serverImpl) lookup(u upspin.UserName) (upspin.PublicKey, error) {
@@ -150,11 +185,19 @@ func (s *serverImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	method := d.Methods[name]
 	umethod := d.UnauthenticatedMethods[name]
 	stream := d.Streams[name]
-	if method == nil && umethod == nil && stream == nil {
+	reqStream := d.RequestStreams[name]
+	if method == nil && umethod == nil && stream == nil && reqStream == nil {
 		http.NotFound(w, r)
 		return
 	}
 
+	// Record a span for the request, linked to the client's span
+	// if the client sent its ID. The session's context carries it,
+	// so the spans recorded by the service's methods may be its children.
+	parent, _ := metric.ParseSpanID(r.Header.Get(spanHeader))
+	m, span := metric.NewSpanFrom(errors.Op("rpc.Serve/"+d.Name+"/"+name), parent)
+	defer m.Done()
+
 	var session Session
 	if umethod == nil {
 		var err error
@@ -163,12 +206,25 @@ func (s *serverImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
+		session = requestSession{session, metric.NewContext(r.Context(), span)}
+	}
+
+	finish, err := s.intercept(&Call{Service: d.Name, Method: name, Session: session})
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	if reqStream != nil {
+		finish(serveRequestStream(reqStream, session, w, r.Body))
+		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		finish(err)
 		return
 	}
 
@@ -176,11 +232,13 @@ func (s *serverImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case method != nil:
 		resp, err := method(session, body)
 		sendResponse(w, resp, err)
+		finish(err)
 	case umethod != nil:
 		resp, err := umethod(body)
 		sendResponse(w, resp, err)
+		finish(err)
 	case stream != nil:
-		serveStream(stream, session, w, body)
+		finish(serveStream(stream, session, w, r.Context().Done(), body))
 	default:
 		panic("this should never happen")
 	}
@@ -207,19 +265,46 @@ func sendError(w http.ResponseWriter, err error) {
 	w.Write(errors.MarshalError(err))
 }
 
-func serveStream(s Stream, sess Session, w http.ResponseWriter, body []byte) {
+// serveRequestStream runs the request stream and writes its response to w.
+// It returns the error, if any, from reading the request or from s.
+func serveRequestStream(s RequestStream, sess Session, w http.ResponseWriter, body io.ReadCloser) error {
+	defer body.Close()
+	header, err := readRequestHeader(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+	resp, err := s(sess, header, &requestStreamReader{r: body})
+	sendResponse(w, resp, err)
+	return err
+}
+
+// serveStream runs the stream and writes its messages to w. Each message
+// is written and flushed before the next is received from the stream, so
+// a client that reads slowly stalls the producer once the connection's
+// buffers fill, rather than the messages accumulating in server memory.
+// A producer that sends on an unbuffered channel therefore holds at most
+// one message that has not been written to the connection.
+// serveStream returns once the stream's channel is closed. The returned
+// error is that, if any, from starting the stream.
+func serveStream(s Stream, sess Session, w http.ResponseWriter, connClosed <-chan struct{}, body []byte) error {
 	done := make(chan struct{})
 	msgs, err := s(sess, body, done)
 	if err != nil {
 		sendError(w, err)
-		return
+		return err
 	}
 
-	connClosed := w.(http.CloseNotifier).CloseNotify()
-	go func() {
-		<-connClosed
-		close(done)
-	}()
+	// Once the client goes away or a write fails, close done to stop
+	// the producer, and drain any messages it sends meanwhile.
+	stopped := false
+	stop := func() {
+		if !stopped {
+			stopped = true
+			close(done)
+		}
+	}
+	defer stop()
 
 	// Write the headers, beginning the stream.
 	w.Write([]byte("OK"))
@@ -230,9 +315,9 @@ func serveStream(s Stream, sess Session, w http.ResponseWriter, body []byte) {
 		select {
 		case msg, ok := <-msgs:
 			if !ok {
-				return
+				return nil
 			}
-			if done == nil {
+			if stopped {
 				// Drop this message as there's nobody to deliver to.
 				continue
 			}
@@ -240,20 +325,24 @@ func serveStream(s Stream, sess Session, w http.ResponseWriter, body []byte) {
 			b, err := pb.Marshal(msg)
 			if err != nil {
 				log.Error.Printf("rpc/auth: error encoding proto in stream: %v", err)
-				return
+				stop()
+				continue
 			}
 
 			binary.BigEndian.PutUint32(lenBytes[:], uint32(len(b)))
 			if _, err := w.Write(lenBytes[:]); err != nil {
-				return
+				stop()
+				continue
 			}
 			if _, err := w.Write(b); err != nil {
-				return
+				stop()
+				continue
 			}
 			w.(http.Flusher).Flush()
 
-		case <-done:
-			done = nil
+		case <-connClosed:
+			stop()
+			connClosed = nil
 		}
 	}
 }
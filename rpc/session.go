@@ -5,9 +5,11 @@
 package rpc
 
 import (
+	"context"
 	"time"
 
 	"upspin.io/cache"
+	"upspin.io/errors"
 	"upspin.io/upspin"
 )
 
@@ -26,6 +28,31 @@ type Session interface {
 	// ProxiedEndpoint returns the endpoint for which this session is a proxy.
 	// If we aren't proxying it returns an Unassigned endpoint.
 	ProxiedEndpoint() upspin.Endpoint
+
+	// Context returns the context of the request being served. It carries
+	// the server's metric span for the request, so the methods serving it
+	// may record their spans as its children; see metric.FromContext.
+	// Outside a request it returns context.Background.
+	Context() context.Context
+}
+
+// AuthenticatedUser returns the user whose identity was verified by the
+// authentication handshake that established the session. Handlers should
+// use it rather than Session.User, which may hold an unverified name. It
+// returns a Permission error if there is no session, as for an
+// UnauthenticatedMethod, or if the session has no user, recorded an error,
+// or has expired.
+func AuthenticatedUser(s Session) (upspin.UserName, error) {
+	const op errors.Op = "rpc.AuthenticatedUser"
+	switch {
+	case s == nil || s.User() == "":
+		return "", errors.E(op, errors.Permission, errUnauthenticated)
+	case s.Err() != nil:
+		return "", errors.E(op, errors.Permission, s.User(), s.Err())
+	case s.Expires().Before(time.Now()):
+		return "", errors.E(op, errors.Permission, s.User(), errExpired)
+	}
+	return s.User(), nil
 }
 
 // sessionCacheSize is the max number of sessions to remember. Small values will limit parallelism and
@@ -96,6 +123,22 @@ func (s *sessionImpl) ProxiedEndpoint() upspin.Endpoint {
 	return s.endpoint
 }
 
+// Context implements Session.
+func (s *sessionImpl) Context() context.Context {
+	return context.Background()
+}
+
+// requestSession is a Session for serving one request.
+type requestSession struct {
+	Session
+	ctx context.Context
+}
+
+// Context implements Session.
+func (s requestSession) Context() context.Context {
+	return s.ctx
+}
+
 func init() {
 	resetSessions()
 }
@@ -0,0 +1,42 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestAuthenticatedUser(t *testing.T) {
+	const user = upspin.UserName("who@upspin.io")
+	now := time.Now()
+	ep := &upspin.Endpoint{}
+	tests := []struct {
+		name    string
+		session Session
+		ok      bool
+	}{
+		{"valid", NewSession(user, now.Add(time.Hour), "token-valid", ep, nil), true},
+		{"no session", nil, false},
+		{"no user", NewSession("", now.Add(time.Hour), "token-nouser", ep, nil), false},
+		{"error", NewSession(user, now.Add(time.Hour), "token-error", ep, errors.Str("bad")), false},
+		{"expired", NewSession(user, now.Add(-time.Second), "token-expired", ep, nil), false},
+	}
+	for _, test := range tests {
+		got, err := AuthenticatedUser(test.session)
+		if test.ok {
+			if err != nil || got != user {
+				t.Errorf("%s: got %q, %v; want %q", test.name, got, err, user)
+			}
+			continue
+		}
+		if !errors.Is(errors.Permission, err) || got != "" {
+			t.Errorf("%s: got %q, %v; want Permission error", test.name, got, err)
+		}
+	}
+}
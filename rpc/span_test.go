@@ -0,0 +1,150 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/golang/protobuf/proto"
+
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/factotum"
+	"upspin.io/metric"
+	prototest "upspin.io/rpc/testdata"
+	"upspin.io/test/testutil"
+	"upspin.io/upspin"
+)
+
+// spanSaver is a metric.Saver that sends the spans it is given on a
+// channel, dropping them if the channel is full.
+type spanSaver chan *metric.Span
+
+func (s spanSaver) Register(queue chan *metric.Metric) {
+	go func() {
+		for m := range queue {
+			for _, span := range m.Spans() {
+				select {
+				case s <- span:
+				default:
+				}
+			}
+		}
+	}()
+}
+
+// collectSpans returns the spans with the given names received by saver,
+// in the order of the names.
+func collectSpans(t *testing.T, saver spanSaver, names ...errors.Op) []*metric.Span {
+	t.Helper()
+	got := make(map[errors.Op]*metric.Span)
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(names) {
+		select {
+		case span := <-saver:
+			for _, name := range names {
+				if span.Name == name {
+					got[name] = span
+				}
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for spans %q; got %v", names, got)
+		}
+	}
+	spans := make([]*metric.Span, len(names))
+	for i, name := range names {
+		spans[i] = got[name]
+	}
+	return spans
+}
+
+func TestSpanPropagation(t *testing.T) {
+	saver := make(spanSaver, 100)
+	metric.RegisterSaver(saver)
+
+	cfg := config.SetUserName(config.New(), "server@upspin.io")
+	s := httptest.NewServer(NewServer(cfg, Service{
+		Name: "Span",
+		Methods: map[string]Method{
+			"Echo": func(session Session, reqBytes []byte) (pb.Message, error) {
+				// The implementation's span.
+				span := metric.FromContext(session.Context())
+				if span == nil {
+					return nil, errors.Str("no span in session context")
+				}
+				span.StartSpan("impl").End()
+				return &prototest.EchoResponse{}, nil
+			},
+		},
+		UnauthenticatedMethods: map[string]UnauthenticatedMethod{
+			"UnauthenticatedEcho": func(reqBytes []byte) (pb.Message, error) {
+				return &prototest.EchoResponse{}, nil
+			},
+		},
+		Lookup: lookup,
+	}))
+	defer s.Close()
+
+	f, err := factotum.NewFromDir(testutil.Repo("key", "testdata", "joe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCfg := config.SetFactotum(config.SetUserName(config.New(), joeUser), f)
+	c, err := NewClient(clientCfg, upspin.NetAddr(strings.TrimPrefix(s.URL, "http://")), NoSecurity, upspin.Endpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A call whose caller has a span.
+	m, caller := metric.NewSpan("caller")
+	ctx := metric.NewContext(context.Background(), caller)
+	if err := c.WithContext(ctx).Invoke("Span/Echo", &prototest.EchoRequest{}, &prototest.EchoResponse{}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	m.Done()
+
+	spans := collectSpans(t, saver, "caller", "rpc.Invoke/Span/Echo", "rpc.Serve/Span/Echo", "impl")
+	caller, client, server, impl := spans[0], spans[1], spans[2], spans[3]
+	if client.Kind != metric.Client {
+		t.Errorf("client span has kind %v, want Client", client.Kind)
+	}
+	if client.ParentSpan == nil || client.ParentSpan.ID != caller.ID {
+		t.Errorf("client span has parent %v, want caller's span %v", client.ParentSpan, caller.ID)
+	}
+	if server.RemoteParent != client.ID {
+		t.Errorf("server span has remote parent %v, want %v", server.RemoteParent, client.ID)
+	}
+	if impl.ParentSpan == nil || impl.ParentSpan.ID != server.ID {
+		t.Errorf("implementation's span has parent %v, want server's span %v", impl.ParentSpan, server.ID)
+	}
+	for _, span := range spans {
+		if span.ID.Trace != caller.ID.Trace {
+			t.Errorf("span %s is in trace %x, want %x", span.Name, span.ID.Trace, caller.ID.Trace)
+		}
+	}
+
+	// A call whose caller has none begins a trace.
+	if err := c.InvokeUnauthenticated("Span/UnauthenticatedEcho", &prototest.EchoRequest{}, &prototest.EchoResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	spans = collectSpans(t, saver, "rpc.Invoke/Span/UnauthenticatedEcho", "rpc.Serve/Span/UnauthenticatedEcho")
+	client, server = spans[0], spans[1]
+	if client.ParentSpan != nil {
+		t.Errorf("client span has parent %v, want none", client.ParentSpan)
+	}
+	if client.ID.IsZero() {
+		t.Fatal("client span has zero ID")
+	}
+	if server.RemoteParent != client.ID {
+		t.Errorf("server span has remote parent %v, want %v", server.RemoteParent, client.ID)
+	}
+	if server.ID.Trace != client.ID.Trace {
+		t.Errorf("server span is in trace %x, want %x", server.ID.Trace, client.ID.Trace)
+	}
+}
@@ -37,9 +37,11 @@ func New(cfg upspin.Config, store upspin.StoreServer, _ upspin.NetAddr) http.Han
 	return rpc.NewServer(cfg, rpc.Service{
 		Name: "Store",
 		Methods: map[string]rpc.Method{
-			"Get":    s.Get,
-			"Put":    s.Put,
-			"Delete": s.Delete,
+			"Get":      s.Get,
+			"Put":      s.Put,
+			"Delete":   s.Delete,
+			"Exists":   s.Exists,
+			"GetBatch": s.GetBatch,
 		},
 	})
 }
@@ -52,7 +54,11 @@ func (s *server) serverFor(session rpc.Session, reqBytes []byte, req pb.Message)
 	if ep := session.ProxiedEndpoint(); ep.Transport != upspin.Unassigned {
 		e = ep
 	}
-	svc, err := s.store.Dial(config.SetUserName(s.config, session.User()), e)
+	user, err := rpc.AuthenticatedUser(session)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := s.store.Dial(config.SetUserName(s.config, user), e)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +127,95 @@ func (s *server) Delete(session rpc.Session, reqBytes []byte) (pb.Message, error
 	return &deleteResponse, nil
 }
 
+// Exists implements proto.StoreServer.
+func (s *server) Exists(session rpc.Session, reqBytes []byte) (pb.Message, error) {
+	var req proto.StoreExistsRequest
+	store, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := s.logf(session, "Exists(%q)", req.Reference)
+
+	ex, ok := store.(upspin.StoreExister)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	exists, err := ex.Exists(upspin.Reference(req.Reference))
+	if err == upspin.ErrNotSupported {
+		// The store wraps one that does not implement Exists.
+		return nil, err
+	}
+	if err != nil {
+		op.log(err)
+		return &proto.StoreExistsResponse{Error: errors.MarshalError(err)}, nil
+	}
+	return &proto.StoreExistsResponse{Exists: exists}, nil
+}
+
+// GetBatch implements proto.StoreServer.
+// If the underlying store does not implement upspin.StoreGetBatcher,
+// or reports that it is not supported, the references are fetched
+// one at a time.
+func (s *server) GetBatch(session rpc.Session, reqBytes []byte) (pb.Message, error) {
+	var req proto.StoreGetBatchRequest
+	store, err := s.serverFor(session, reqBytes, &req)
+	if err != nil {
+		return nil, err
+	}
+	op := s.logf(session, "GetBatch(%q)", req.References)
+
+	if n := len(req.References); n > upspin.MaxGetBatchRefs {
+		err := errors.E(errors.Invalid, errors.Errorf("%d references in batch; limit is %d", n, upspin.MaxGetBatchRefs))
+		op.log(err)
+		return nil, err
+	}
+	refs := make([]upspin.Reference, len(req.References))
+	for i, ref := range req.References {
+		refs[i] = upspin.Reference(ref)
+	}
+	var results []upspin.StoreGetResult
+	if b, ok := store.(upspin.StoreGetBatcher); ok {
+		results = b.GetBatch(refs)
+	}
+	if len(results) == 0 || results[0].Error == upspin.ErrNotSupported {
+		// The store, or the one it wraps, cannot batch.
+		results = getBatch(store, refs)
+	}
+	resp := &proto.StoreGetBatchResponse{
+		Responses: make([]*proto.StoreGetResponse, len(results)),
+	}
+	for i, r := range results {
+		if r.Error != nil {
+			op.log(r.Error)
+			resp.Responses[i] = &proto.StoreGetResponse{Error: errors.MarshalError(r.Error)}
+			continue
+		}
+		resp.Responses[i] = &proto.StoreGetResponse{
+			Data:      r.Data,
+			Refdata:   proto.RefdataProto(r.Refdata),
+			Locations: proto.Locations(r.Locations),
+		}
+	}
+	return resp, nil
+}
+
+// getBatch implements upspin.StoreGetBatcher.GetBatch for a store that
+// does not, by calling Get for each reference in turn.
+func getBatch(store upspin.StoreServer, refs []upspin.Reference) []upspin.StoreGetResult {
+	var results []upspin.StoreGetResult
+	size := 0
+	for _, ref := range refs {
+		if size >= upspin.MaxGetBatchBytes {
+			break
+		}
+		var r upspin.StoreGetResult
+		r.Data, r.Refdata, r.Locations, r.Error = store.Get(ref)
+		size += len(r.Data)
+		results = append(results, r)
+	}
+	return results
+}
+
 func (s *server) logf(sess rpc.Session, format string, args ...interface{}) operation {
 	op := fmt.Sprintf("rpc/storeserver: %q: store.", sess.User())
 	op += fmt.Sprintf(format, args...)
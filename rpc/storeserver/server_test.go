@@ -0,0 +1,186 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storeserver
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/golang/protobuf/proto"
+
+	"upspin.io/bind"
+	"upspin.io/errors"
+	"upspin.io/rpc"
+	"upspin.io/serverutil/perm"
+	"upspin.io/test/testenv"
+	"upspin.io/upspin"
+	"upspin.io/upspin/proto"
+)
+
+const (
+	owner  = "aly@example.com" // aly has keys in key/testdata/aly
+	reader = "bob@uncle.com"   // bob has keys in key/testdata/bob
+)
+
+// setup returns a test environment, its store, and a reference to some
+// data in that store.
+func setup(t *testing.T) (env *testenv.Env, store upspin.StoreServer, ref upspin.Reference) {
+	env, err := testenv.New(&testenv.Setup{
+		OwnerName: owner,
+		Packing:   upspin.PlainPack,
+		Kind:      "server",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err = bind.StoreServer(env.Config, env.Config.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refdata, err := store.Put([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env, store, refdata.Reference
+}
+
+// newServer returns a server for store wrapped to check permissions,
+// as the store servers run it.
+func newServer(cfg upspin.Config, store upspin.StoreServer) *server {
+	ready := make(chan struct{})
+	close(ready)
+	return &server{
+		config: cfg,
+		store:  perm.WrapStore(cfg, ready, store),
+	}
+}
+
+func session(user upspin.UserName) rpc.Session {
+	return rpc.NewSession(user, time.Now().Add(time.Hour), "token-"+string(user), &upspin.Endpoint{}, nil)
+}
+
+func TestExists(t *testing.T) {
+	env, store, ref := setup(t)
+	defer env.Exit()
+	s := newServer(env.Config, store)
+
+	for _, test := range []struct {
+		ref    upspin.Reference
+		exists bool
+	}{
+		{ref, true},
+		{"nosuchref", false},
+	} {
+		reqBytes, err := pb.Marshal(&proto.StoreExistsRequest{Reference: string(test.ref)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := s.Exists(session(reader), reqBytes)
+		if err != nil {
+			t.Fatalf("Exists(%q): %v", test.ref, err)
+		}
+		resp := msg.(*proto.StoreExistsResponse)
+		if len(resp.Error) != 0 {
+			t.Fatalf("Exists(%q): %v", test.ref, errors.UnmarshalError(resp.Error))
+		}
+		if resp.Exists != test.exists {
+			t.Errorf("Exists(%q) = %v, want %v", test.ref, resp.Exists, test.exists)
+		}
+	}
+}
+
+// noExister is a StoreServer that does not implement upspin.StoreExister.
+type noExister struct {
+	upspin.StoreServer
+}
+
+func (s noExister) Dial(cfg upspin.Config, e upspin.Endpoint) (upspin.Service, error) {
+	svc, err := s.StoreServer.Dial(cfg, e)
+	if err != nil {
+		return nil, err
+	}
+	return noExister{svc.(upspin.StoreServer)}, nil
+}
+
+func TestExistsNotSupported(t *testing.T) {
+	env, store, ref := setup(t)
+	defer env.Exit()
+	s := newServer(env.Config, noExister{store})
+
+	reqBytes, err := pb.Marshal(&proto.StoreExistsRequest{Reference: string(ref)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Exists(session(reader), reqBytes); err != upspin.ErrNotSupported {
+		t.Fatalf("Exists: err = %v, want %v", err, upspin.ErrNotSupported)
+	}
+}
+
+// batchStore is a StoreServer that implements upspin.StoreGetBatcher
+// and records the references it is asked for.
+type batchStore struct {
+	upspin.StoreServer
+	batched *[]upspin.Reference
+}
+
+func (s batchStore) GetBatch(refs []upspin.Reference) []upspin.StoreGetResult {
+	*s.batched = append(*s.batched, refs...)
+	results := make([]upspin.StoreGetResult, len(refs))
+	for i, ref := range refs {
+		r := &results[i]
+		r.Data, r.Refdata, r.Locations, r.Error = s.Get(ref)
+	}
+	return results
+}
+
+func (s batchStore) Dial(cfg upspin.Config, e upspin.Endpoint) (upspin.Service, error) {
+	svc, err := s.StoreServer.Dial(cfg, e)
+	if err != nil {
+		return nil, err
+	}
+	return batchStore{svc.(upspin.StoreServer), s.batched}, nil
+}
+
+func TestGetBatch(t *testing.T) {
+	env, store, ref := setup(t)
+	defer env.Exit()
+
+	var batched []upspin.Reference
+	for _, test := range []struct {
+		store   upspin.StoreServer
+		batched int
+	}{
+		{batchStore{store, &batched}, 1}, // perm refuses the second.
+		{store, 0},                       // Falls back to Get.
+	} {
+		batched = nil
+		s := newServer(env.Config, test.store)
+		reqBytes, err := pb.Marshal(&proto.StoreGetBatchRequest{
+			References: []string{string(ref), string(upspin.ListRefsMetadata)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := s.GetBatch(session(reader), reqBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := msg.(*proto.StoreGetBatchResponse)
+		if len(resp.Responses) != 2 {
+			t.Fatalf("got %d responses, want 2", len(resp.Responses))
+		}
+		if got := resp.Responses[0]; len(got.Error) != 0 || string(got.Data) != "data" {
+			t.Errorf("response 0 = %q, %v; want %q", got.Data, errors.UnmarshalError(got.Error), "data")
+		}
+		// Only the owner may list references.
+		err = errors.UnmarshalError(resp.Responses[1].Error)
+		if want := errors.E(errors.Permission, upspin.UserName(reader)); !errors.Match(want, err) {
+			t.Errorf("response 1 err = %v, want = %v", err, want)
+		}
+		if len(batched) != test.batched {
+			t.Errorf("store batched %q, want %d references", batched, test.batched)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"encoding/binary"
+	"io"
+
+	pb "github.com/golang/protobuf/proto"
+
+	"upspin.io/errors"
+)
+
+// reasonableMessageSize is the largest stream message a client will accept.
+const reasonableMessageSize = 1 << 26 // 64MB
+
+// StreamReader reads the messages of a streaming RPC response one at a time.
+// It is created by Client.InvokeStream.
+type StreamReader struct {
+	r      io.ReadCloser
+	newMsg func() pb.Message
+	done   <-chan struct{}
+
+	started bool   // Whether the stream preamble has been read.
+	buf     []byte // Reused for each message.
+	err     error  // Sticky error returned by all calls after a failure.
+}
+
+func newStreamReader(r io.ReadCloser, newMsg func() pb.Message, done <-chan struct{}) *StreamReader {
+	return &StreamReader{
+		r:      r,
+		newMsg: newMsg,
+		done:   done,
+	}
+}
+
+// Next reads and decodes the next message in the stream.
+// It returns io.EOF if the server closed the stream cleanly or if the done
+// channel was closed, and io.ErrUnexpectedEOF if the stream was truncated
+// part way through a message. Once Next returns an error, all subsequent
+// calls return the same error.
+func (s *StreamReader) Next() (pb.Message, error) {
+	b, err := s.next()
+	if err != nil {
+		return nil, err
+	}
+	msg := s.newMsg()
+	if err := pb.Unmarshal(b, msg); err != nil {
+		s.err = errors.E(errors.Invalid, err)
+		return nil, s.err
+	}
+	return msg, nil
+}
+
+// Close closes the underlying connection.
+func (s *StreamReader) Close() error {
+	return s.r.Close()
+}
+
+// next returns the encoded bytes of the next message in the stream.
+// The returned slice is only valid until the next call to next.
+func (s *StreamReader) next() ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	b, err := s.readMessage()
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *StreamReader) readMessage() ([]byte, error) {
+	if !s.started {
+		// A stream begins with the bytes "OK".
+		var ok [2]byte
+		if _, err := readFull(s.r, ok[:], s.done); err == io.EOF || err == io.ErrUnexpectedEOF {
+			// Server closed the stream.
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, errors.E(errors.IO, err)
+		}
+		if ok[0] != 'O' || ok[1] != 'K' {
+			return nil, errors.E(errors.IO, "unexpected stream preamble")
+		}
+		s.started = true
+	}
+
+	// Messages are of the form
+	// [length, 4 byte, big-endian-encoded int32]
+	// [length bytes of encoded protobuf message]
+	var msgLen [4]byte
+	if _, err := readFull(s.r, msgLen[:], s.done); err == io.EOF {
+		// Stream closed between messages.
+		return nil, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.E(errors.IO, err)
+	}
+
+	l := binary.BigEndian.Uint32(msgLen[:])
+	if l > reasonableMessageSize {
+		return nil, errors.E(errors.Invalid, errors.Errorf("message too long (%d bytes)", l))
+	}
+	if cap(s.buf) < int(l) {
+		s.buf = make([]byte, l)
+	} else {
+		s.buf = s.buf[:l]
+	}
+	if _, err := readFull(s.r, s.buf, s.done); err != nil {
+		if err == io.EOF && !isClosed(s.done) {
+			// The message length arrived but none of its body.
+			err = io.ErrUnexpectedEOF
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return nil, errors.E(errors.IO, err)
+	}
+	return s.buf, nil
+}
+
+// isClosed reports whether the done channel is closed.
+func isClosed(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// readFull is like io.ReadFull but it will return io.EOF if the provided
+// channel is closed.
+func readFull(r io.Reader, b []byte, done <-chan struct{}) (int, error) {
+	type result struct {
+		n   int
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		// TODO(adg): this may leak goroutines if the requisite reads
+		// never complete, but will that actually happen? It would be
+		// great to have something like this hooked into the runtime.
+		n, err := io.ReadFull(r, b)
+		ch <- result{n, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.n, r.err
+	case <-done:
+		return 0, io.EOF
+	}
+}
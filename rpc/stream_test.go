@@ -0,0 +1,257 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	pb "github.com/golang/protobuf/proto"
+
+	prototest "upspin.io/rpc/testdata"
+)
+
+// encodeStream returns the wire encoding of a stream of CountResponses
+// with the given numbers.
+func encodeStream(t *testing.T, nums ...int32) []byte {
+	buf := bytes.NewBufferString("OK")
+	for _, n := range nums {
+		b, err := pb.Marshal(&prototest.CountResponse{Number: n})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+		buf.Write(l[:])
+		buf.Write(b)
+	}
+	return buf.Bytes()
+}
+
+func newTestStreamReader(b []byte) *StreamReader {
+	newMsg := func() pb.Message { return new(prototest.CountResponse) }
+	return newStreamReader(io.NopCloser(bytes.NewReader(b)), newMsg, make(chan struct{}))
+}
+
+func TestStreamReader(t *testing.T) {
+	full := encodeStream(t, 1, 2, 3)
+	frame := (len(full) - 2) / 3 // Each small message encodes to the same size.
+	lastFrame := len(full) - frame
+	tests := []struct {
+		name string
+		data []byte
+		n    int   // Number of messages to expect.
+		err  error // Error to expect after them.
+	}{
+		{"empty", nil, 0, io.EOF},
+		{"preamble only", full[:2], 0, io.EOF},
+		{"complete", full, 3, io.EOF},
+		{"truncated length", full[:lastFrame+2], 2, io.ErrUnexpectedEOF},
+		{"no body", full[:lastFrame+4], 2, io.ErrUnexpectedEOF},
+		{"truncated body", full[:len(full)-1], 2, io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		sr := newTestStreamReader(test.data)
+		for i := 0; i < test.n; i++ {
+			msg, err := sr.Next()
+			if err != nil {
+				t.Fatalf("%s: message %d: %v", test.name, i, err)
+			}
+			if got, want := msg.(*prototest.CountResponse).Number, int32(i+1); got != want {
+				t.Errorf("%s: message %d = %d, want %d", test.name, i, got, want)
+			}
+		}
+		_, err := sr.Next()
+		if err != test.err {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.err)
+		}
+		// The error is sticky.
+		if _, err2 := sr.Next(); err2 != err {
+			t.Errorf("%s: second err = %v, want %v", test.name, err2, err)
+		}
+	}
+}
+
+func TestStreamReaderBadPreamble(t *testing.T) {
+	sr := newTestStreamReader([]byte("NO"))
+	if _, err := sr.Next(); err == nil || err == io.EOF {
+		t.Errorf("err = %v, want preamble error", err)
+	}
+}
+
+// encodeRequestStream returns the wire encoding of a streaming request
+// with the given header and data chunks, ended by the final frame.
+func encodeRequestStream(t *testing.T, header string, chunks ...string) []byte {
+	var buf bytes.Buffer
+	for _, b := range append([]string{header}, chunks...) {
+		if err := writeFrame(&buf, []byte(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFrame(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRequestStreamReader(t *testing.T) {
+	full := encodeRequestStream(t, "header", "The wren", "Earns his living", "Noiselessly.")
+	const data = "The wrenEarns his livingNoiselessly."
+	tests := []struct {
+		name string
+		data []byte
+		want string // Data to expect.
+		err  error  // Error to expect after it.
+	}{
+		{"complete", full, data, nil},
+		{"no final frame", full[:len(full)-4], data, io.ErrUnexpectedEOF},
+		{"truncated final frame", full[:len(full)-2], data, io.ErrUnexpectedEOF},
+		{"truncated chunk", full[:len(full)-6], data[:len(data)-2], io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		r := iotest.OneByteReader(bytes.NewReader(test.data))
+		header, err := readRequestHeader(r)
+		if err != nil {
+			t.Fatalf("%s: reading header: %v", test.name, err)
+		}
+		if string(header) != "header" {
+			t.Errorf("%s: header = %q, want %q", test.name, header, "header")
+		}
+		sr := &requestStreamReader{r: r}
+		got, err := io.ReadAll(sr)
+		if err != test.err {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.err)
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: read %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	if _, err := readRequestHeader(bytes.NewReader(full[:5])); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated header: err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// TestStreamBackpressure checks that a fast producer is paced by a slow
+// client: the server must not run ahead of the client by more than the
+// connection's buffers hold, and every message must arrive in order.
+func TestStreamBackpressure(t *testing.T) {
+	const (
+		total   = 1000
+		padSize = 64 << 10
+		// The server may run ahead by what fits in the socket
+		// buffers, a few megabytes, but not by all the messages.
+		maxAhead = total / 2
+	)
+	pad := strings.Repeat("x", padSize)
+	var sent int64
+	producerDone := make(chan struct{})
+	fast := func(_ Session, _ []byte, done <-chan struct{}) (<-chan pb.Message, error) {
+		out := make(chan pb.Message)
+		go func() {
+			defer close(producerDone)
+			defer close(out)
+			for i := 0; i < total; i++ {
+				select {
+				case out <- &prototest.EchoResponse{Payload: fmt.Sprintf("%d %s", i, pad)}:
+					atomic.AddInt64(&sent, 1)
+				case <-done:
+					return
+				}
+			}
+		}()
+		return out, nil
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveStream(fast, nil, w, r.Context().Done(), nil)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMsg := func() pb.Message { return new(prototest.EchoResponse) }
+	sr := newStreamReader(resp.Body, newMsg, make(chan struct{}))
+	defer sr.Close()
+
+	// Read nothing until the producer stalls.
+	last := int64(-1)
+	for n := atomic.LoadInt64(&sent); n != last; n = atomic.LoadInt64(&sent) {
+		last = n
+		time.Sleep(100 * time.Millisecond)
+	}
+	if last >= maxAhead {
+		t.Fatalf("server sent %d messages to a client that read none; want fewer than %d", last, maxAhead)
+	}
+
+	for i := 0; i < total; i++ {
+		msg, err := sr.Next()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("%d %s", i, pad); msg.(*prototest.EchoResponse).Payload != want {
+			t.Fatalf("message %d out of order", i)
+		}
+		if i%100 == 0 {
+			// Stay slow.
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if _, err := sr.Next(); err != io.EOF {
+		t.Fatalf("after last message: got %v, want EOF", err)
+	}
+	<-producerDone
+}
+
+// TestStreamClientGone checks that the producer is told to stop when the
+// client abandons the stream.
+func TestStreamClientGone(t *testing.T) {
+	stopped := make(chan struct{})
+	endless := func(_ Session, _ []byte, done <-chan struct{}) (<-chan pb.Message, error) {
+		out := make(chan pb.Message)
+		go func() {
+			defer close(stopped)
+			defer close(out)
+			for i := int32(0); ; i++ {
+				select {
+				case out <- &prototest.CountResponse{Number: i}:
+				case <-done:
+					return
+				}
+			}
+		}()
+		return out, nil
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveStream(endless, nil, w, r.Context().Done(), nil)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMsg := func() pb.Message { return new(prototest.CountResponse) }
+	sr := newStreamReader(resp.Body, newMsg, make(chan struct{}))
+	if _, err := sr.Next(); err != nil {
+		t.Fatal(err)
+	}
+	sr.Close()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("producer still running after client went away")
+	}
+}
@@ -8,7 +8,10 @@ package dirserver // import "upspin.io/serverutil/dirserver"
 
 import (
 	"flag"
+	"fmt"
 	"net/http"
+	"os"
+	"sort"
 
 	"upspin.io/config"
 	"upspin.io/dir/inprocess"
@@ -16,6 +19,7 @@ import (
 	"upspin.io/errors"
 	"upspin.io/flags"
 	"upspin.io/log"
+	"upspin.io/metric/prometheus"
 	"upspin.io/rpc/dirserver"
 	"upspin.io/serverutil/perm"
 	"upspin.io/upspin"
@@ -31,10 +35,18 @@ import (
 	_ "upspin.io/transports"
 )
 
-var storeServerUser = flag.String("storeserveruser", "", "`user name` of the StoreServer")
+var (
+	storeServerUser = flag.String("storeserveruser", "", "`user name` of the StoreServer")
+	check           = flag.Bool("check", false, "check the consistency of the users' trees, report any problems, and exit")
+)
+
+// checker is implemented by DirServers that can check their trees.
+type checker interface {
+	Check(users ...upspin.UserName) (map[upspin.UserName][]error, error)
+}
 
 func Main() (ready chan<- struct{}) {
-	flags.Parse(flags.Server, "kind", "serverconfig")
+	flags.Parse(flags.Server, "kind", "metrics", "serverconfig")
 
 	// Load configuration and keys for this server. It needs a real upspin username and keys.
 	cfg, err := config.FromFile(flags.Config)
@@ -56,6 +68,9 @@ func Main() (ready chan<- struct{}) {
 	if err != nil {
 		log.Fatalf("Setting up DirServer: %v", err)
 	}
+	if *check {
+		runCheck(dir)
+	}
 
 	// Wrap with permission checks, if requested.
 	if *storeServerUser != "" {
@@ -68,6 +83,41 @@ func Main() (ready chan<- struct{}) {
 
 	httpDir := dirserver.New(cfg, dir, upspin.NetAddr(flags.NetAddr))
 	http.Handle("/api/Dir/", httpDir)
+	if flags.Metrics {
+		http.Handle("/metrics", prometheus.NewHandler())
+	}
 
 	return ready
 }
+
+// runCheck checks the trees of the users named by the command-line
+// arguments, or of all users if there are none, prints the problems
+// found, and exits. The exit status is non-zero if there are problems.
+func runCheck(dir upspin.DirServer) {
+	c, ok := dir.(checker)
+	if !ok {
+		log.Fatalf("-kind=%s does not support -check", flags.ServerKind)
+	}
+	var users []upspin.UserName
+	for _, arg := range flag.Args() {
+		users = append(users, upspin.UserName(arg))
+	}
+	report, err := c.Check(users...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var bad []string
+	for user := range report {
+		bad = append(bad, string(user))
+	}
+	sort.Strings(bad)
+	for _, user := range bad {
+		for _, p := range report[upspin.UserName(user)] {
+			fmt.Println(p)
+		}
+	}
+	if len(bad) > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -31,37 +31,32 @@ func Glob(pattern string, lookup LookupFunc, ls ListFunc) ([]*upspin.DirEntry, e
 	if err != nil {
 		return nil, err
 	}
+	// Find the longest path prefix that does not contain a
+	// metacharacter, so we know which level we need to start listing.
+	prefix, depth, err := path.GlobPrefix(p.Path())
+	if err != nil {
+		return nil, err
+	}
 
 	// If there are no glob meta-characters in the pattern, just do a lookup.
-	if !hasMeta(p.FilePath()) {
-		de, err := lookup(unquote(p.String()))
+	if depth == 0 {
+		de, err := lookup(prefix.Path())
 		if de == nil {
 			return nil, err
 		}
 		// If the pattern we look up is just a plain file, and it's a link,
 		// just return it. In effect this is equivalent to passing false as the
 		// final argument to Client.Lookup.
-		if err == upspin.ErrFollowLink && de.Name == p.Path() {
+		if err == upspin.ErrFollowLink && de.Name == prefix.Path() {
 			err = nil
 		}
 		return []*upspin.DirEntry{de}, err
 	}
 
-	// Look for the longest path prefix that does not contain a
-	// metacharacter, so we know which level we need to start listing.
-	firstMeta := 0
-	i := 0
-	for ; i < p.NElem(); i++ {
-		firstMeta = i
-		if hasMeta(p.Elem(i)) {
-			break
-		}
-	}
-
 	// Path without the first meta component.
-	basePath := unquote(p.First(firstMeta).String())
+	basePath := prefix.Path()
 	// Pattern including first meta component.
-	basePattern := p.First(firstMeta + 1).String()
+	basePattern := p.First(p.NElem() - depth + 1).String()
 	// Tail of the patterm starting with the first meta component.
 	patternTail := strings.TrimPrefix(p.String(), basePattern)
 
@@ -131,44 +126,3 @@ func Glob(pattern string, lookup LookupFunc, ls ListFunc) ([]*upspin.DirEntry, e
 	upspin.SortDirEntries(result, false)
 	return result, errLink
 }
-
-// hasMeta reports whether the given path element contains unescaped glob
-// metacharacters.
-func hasMeta(elem string) bool {
-	esc := false
-	for _, r := range elem {
-		if esc {
-			esc = false
-			continue
-		}
-		switch r {
-		case '\\':
-			esc = true
-		case '*', '[', '?':
-			return true
-		}
-	}
-	return false
-}
-
-// unquote removes the escaping from the given pattern and returns the
-// resulting path.
-func unquote(pat string) upspin.PathName {
-	if !strings.Contains(pat, "\\") {
-		return upspin.PathName(pat)
-	}
-	b := make([]byte, 0, len(pat))
-	esc := false
-	for _, c := range []byte(pat) {
-		if !esc && c == '\\' {
-			esc = true
-			continue
-		}
-		esc = false
-		b = append(b, c)
-	}
-	if esc {
-		b = append(b, '\\')
-	}
-	return upspin.PathName(b)
-}
@@ -184,40 +184,3 @@ func matchEntries(entries []*upspin.DirEntry, names ...upspin.PathName) error {
 
 	return nil
 }
-
-func TestHasMeta(t *testing.T) {
-	cases := []struct {
-		in   string
-		want bool
-	}{
-		{"foo*", true},
-		{"fo?", true},
-		{"foo", false},
-		{"f\\*oo", false},
-		{"f\\[o]o", false},
-	}
-	for _, c := range cases {
-		got := hasMeta(c.in)
-		if got != c.want {
-			t.Errorf("hasMeta(%q) = %v, want %v", c.in, got, c.want)
-		}
-	}
-}
-
-func TestUnquote(t *testing.T) {
-	cases := []struct {
-		in   string
-		want upspin.PathName
-	}{
-		{"foo", "foo"},
-		{"f[o]o", "f[o]o"},
-		{"f\\[o]o", "f[o]o"},
-		{"foo\\", "foo\\"},
-	}
-	for _, c := range cases {
-		got := unquote(c.in)
-		if got != c.want {
-			t.Errorf("unquote(%q) = %q, want %q", c.in, got, c.want)
-		}
-	}
-}
@@ -8,8 +8,10 @@ package keyserver // import "upspin.io/serverutil/keyserver"
 
 import (
 	"flag"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 
 	"upspin.io/cloud/mail"
 	"upspin.io/cloud/mail/sendgrid"
@@ -19,7 +21,9 @@ import (
 	"upspin.io/key/inprocess"
 	"upspin.io/key/server"
 	"upspin.io/log"
+	"upspin.io/metric/prometheus"
 	"upspin.io/rpc/keyserver"
+	"upspin.io/serverutil"
 	"upspin.io/serverutil/signup"
 	"upspin.io/upspin"
 
@@ -34,10 +38,19 @@ import (
 // provider user name and password.
 var mailConfigFile = flag.String("mail_config", "", "config file name for mail service")
 
+// The limits on the signup requests that send mail.
+var (
+	signupAddrLimit      = flag.Int("signup_addr_limit", signup.DefaultAddrLimit, "maximum signup requests from an IP address per -signup_addr_window")
+	signupAddrWindow     = flag.Duration("signup_addr_window", signup.DefaultAddrWindow, "`duration` of the sliding window for the signup limit per IP address")
+	signupUserBackoff    = flag.Duration("signup_user_backoff", signup.DefaultUserBackoff, "initial `duration` for which repeated signup requests for a user are refused")
+	signupUserMaxBackoff = flag.Duration("signup_user_max_backoff", signup.DefaultUserMaxBackoff, "maximum `duration` for which repeated signup requests for a user are refused")
+	signupProxies        = flag.String("signup_proxies", "", "comma-separated `networks` of trusted proxies whose X-Forwarded-For headers give the client address")
+)
+
 // Main starts the keyserver. If setup is not nil it is called with the
 // instantiated KeyServer.
 func Main(setup func(upspin.KeyServer)) {
-	flags.Parse(flags.Server, "kind", "serverconfig")
+	flags.Parse(flags.Server, "kind", "metrics", "serverconfig")
 
 	cfg, err := config.FromFile(flags.Config)
 	if err != nil {
@@ -63,6 +76,9 @@ func Main(setup func(upspin.KeyServer)) {
 	}
 
 	http.Handle("/api/Key/", keyserver.New(cfg, key, upspin.NetAddr(flags.NetAddr)))
+	if flags.Metrics {
+		http.Handle("/metrics", prometheus.NewHandler())
+	}
 
 	if logger, ok := key.(server.Logger); ok {
 		http.Handle("/log", logHandler{logger: logger})
@@ -87,7 +103,45 @@ func Main(setup func(upspin.KeyServer)) {
 			log.Fatalf("keyserver: %v", err)
 		}
 	}
-	http.Handle("/signup", signup.NewHandler(signupURL, f, key, mc))
+	if *signupAddrLimit <= 0 || *signupAddrWindow <= 0 || *signupUserBackoff <= 0 || *signupUserMaxBackoff < *signupUserBackoff {
+		log.Fatal("keyserver: signup limits must be positive")
+	}
+	proxies, err := parseNetworks(*signupProxies)
+	if err != nil {
+		log.Fatalf("keyserver: -signup_proxies: %v", err)
+	}
+	limits := &signup.Limits{
+		Addr:    &serverutil.WindowLimiter{Limit: *signupAddrLimit, Window: *signupAddrWindow},
+		User:    &serverutil.RateLimiter{Backoff: *signupUserBackoff, Max: *signupUserMaxBackoff},
+		Proxies: proxies,
+	}
+	http.Handle("/signup", signup.NewHandler(signupURL, f, key, mc, limits))
+}
+
+// parseNetworks parses a comma-separated list of networks in CIDR
+// notation. A bare IP address is taken as a network of one address.
+func parseNetworks(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if ip := net.ParseIP(s); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
 }
 
 // parseMailConfig reads YAML data and returns a signup.MailConfig
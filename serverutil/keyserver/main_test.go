@@ -78,3 +78,24 @@ from: server@upspin.io`,
 		})
 	}
 }
+
+func TestParseNetworks(t *testing.T) {
+	nets, err := parseNetworks("10.0.0.0/8, 192.168.1.1,2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range nets {
+		got = append(got, n.String())
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.1/32", "2001:db8::/32"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNetworks = %q, want %q", got, want)
+	}
+	if nets, err := parseNetworks(""); err != nil || nets != nil {
+		t.Errorf("parseNetworks of empty list = %v, %v; want nil, nil", nets, err)
+	}
+	if _, err := parseNetworks("10.0.0.0/33"); err == nil {
+		t.Error("parseNetworks of bad network succeeded")
+	}
+}
@@ -11,8 +11,8 @@ import (
 )
 
 // WrapDir wraps the given DirServer with a DirServer that checks root-creation
-// permissions. It will only start polling the store permissions after the
-// ready channel is closed.
+// and read permissions. It will only start polling the store permissions after
+// the ready channel is closed.
 func WrapDir(cfg upspin.Config, ready <-chan struct{}, target upspin.UserName, dir upspin.DirServer) upspin.DirServer {
 	const op errors.Op = "serverutil/perm.WrapDir"
 	p := newPerm(op, cfg, ready, target, dir.Lookup, dir.Watch, noop, retry, nil)
@@ -20,7 +20,7 @@ func WrapDir(cfg upspin.Config, ready <-chan struct{}, target upspin.UserName, d
 }
 
 // WrapDir wraps the given DirServer with a DirServer that checks root-creation
-// permissions using Perm.
+// and read permissions using Perm.
 func (p *Perm) WrapDir(dir upspin.DirServer) upspin.DirServer {
 	return &dirWrapper{
 		DirServer: dir,
@@ -30,7 +30,7 @@ func (p *Perm) WrapDir(dir upspin.DirServer) upspin.DirServer {
 }
 
 // dirWrapper wraps a DirServer and implements permission checking when
-// creating new roots.
+// creating new roots and when reading.
 type dirWrapper struct {
 	upspin.DirServer
 
@@ -41,14 +41,83 @@ type dirWrapper struct {
 // Put implements upspin.DirServer.
 func (d *dirWrapper) Put(entry *upspin.DirEntry) (*upspin.DirEntry, error) {
 	const op errors.Op = "serverutil/perm.Put"
+	if err := d.checkWriter(op, entry); err != nil {
+		return nil, err
+	}
+	return d.DirServer.Put(entry)
+}
+
+// PutAll implements upspin.DirPutAller. Each entry is checked as by Put.
+func (d *dirWrapper) PutAll(entries []*upspin.DirEntry) ([]*upspin.DirEntry, error) {
+	const op errors.Op = "serverutil/perm.PutAll"
+	for _, entry := range entries {
+		if err := d.checkWriter(op, entry); err != nil {
+			return nil, err
+		}
+	}
+	p, ok := d.DirServer.(upspin.DirPutAller)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	return p.PutAll(entries)
+}
+
+// checkWriter returns a Permission error if the entry is a root and the
+// user is not in the Writers group.
+func (d *dirWrapper) checkWriter(op errors.Op, entry *upspin.DirEntry) error {
 	p, err := path.Parse(entry.Name)
 	if err != nil {
-		return nil, errors.E(op, err)
+		return errors.E(op, err)
 	}
 	if p.IsRoot() && !d.perm.IsWriter(d.user) {
-		return nil, errors.E(op, d.user, errors.Permission, "user not authorized")
+		return errors.E(op, d.user, errors.Permission, "user not authorized")
 	}
-	return d.DirServer.Put(entry)
+	return nil
+}
+
+// Lookup implements upspin.DirServer.
+func (d *dirWrapper) Lookup(name upspin.PathName) (*upspin.DirEntry, error) {
+	const op errors.Op = "serverutil/perm.Lookup"
+	if err := d.checkReader(op); err != nil {
+		return nil, err
+	}
+	return d.DirServer.Lookup(name)
+}
+
+// Glob implements upspin.DirServer.
+func (d *dirWrapper) Glob(pattern string) ([]*upspin.DirEntry, error) {
+	const op errors.Op = "serverutil/perm.Glob"
+	if err := d.checkReader(op); err != nil {
+		return nil, err
+	}
+	return d.DirServer.Glob(pattern)
+}
+
+// WhichAccess implements upspin.DirServer.
+func (d *dirWrapper) WhichAccess(name upspin.PathName) (*upspin.DirEntry, error) {
+	const op errors.Op = "serverutil/perm.WhichAccess"
+	if err := d.checkReader(op); err != nil {
+		return nil, err
+	}
+	return d.DirServer.WhichAccess(name)
+}
+
+// Watch implements upspin.DirServer.
+func (d *dirWrapper) Watch(name upspin.PathName, sequence int64, done <-chan struct{}) (<-chan upspin.Event, error) {
+	const op errors.Op = "serverutil/perm.Watch"
+	if err := d.checkReader(op); err != nil {
+		return nil, err
+	}
+	return d.DirServer.Watch(name, sequence, done)
+}
+
+// checkReader returns a Permission error if the user is not in the
+// Readers group.
+func (d *dirWrapper) checkReader(op errors.Op) error {
+	if !d.perm.IsReader(d.user) {
+		return errors.E(op, d.user, errors.Permission, "user not authorized")
+	}
+	return nil
 }
 
 // Dial implements upspin.Service.
@@ -63,3 +132,29 @@ func (d *dirWrapper) Dial(config upspin.Config, e upspin.Endpoint) (upspin.Servi
 	newDir.DirServer = service.(upspin.DirServer)
 	return &newDir, nil
 }
+
+// History implements upspin.DirHistorian.
+func (d *dirWrapper) History(name upspin.PathName) ([]upspin.Event, error) {
+	const op errors.Op = "serverutil/perm.History"
+	if err := d.checkReader(op); err != nil {
+		return nil, err
+	}
+	h, ok := d.DirServer.(upspin.DirHistorian)
+	if !ok {
+		return nil, upspin.ErrNotSupported
+	}
+	return h.History(name)
+}
+
+// Exists implements upspin.DirExister.
+func (d *dirWrapper) Exists(name upspin.PathName) (bool, error) {
+	const op errors.Op = "serverutil/perm.Exists"
+	if err := d.checkReader(op); err != nil {
+		return false, err
+	}
+	e, ok := d.DirServer.(upspin.DirExister)
+	if !ok {
+		return false, upspin.ErrNotSupported
+	}
+	return e.Exists(name)
+}
@@ -69,3 +69,129 @@ func TestDirIntegration(t *testing.T) {
 		t.Fatalf("Expected root creation to succeed; instead err = %s", err)
 	}
 }
+
+func TestDirReaders(t *testing.T) {
+	env := setupEnv(t)
+	defer env.Exit()
+
+	r := testenv.NewRunner()
+	r.AddUser(env.Config)
+	r.As(owner)
+	r.Put(accessFile, "r,l:all\n*:"+owner) // Permission for anyone to read and list, owner has all rights.
+	r.MakeDirectory(groupDir)
+	r.Put(readersGroup, owner) // Only owner may read.
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+
+	perm, wait, done := newWithEnv(t, env)
+	defer done()
+	wait()
+	wait()
+
+	readerCtx, err := env.NewUser(writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := bind.DirServer(env.Config, env.Config.DirEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := perm.WrapDir(dir).Dial(readerCtx, readerCtx.DirEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir = svc.(upspin.DirServer)
+
+	// Access would allow the lookup, but the Readers group does not.
+	_, err = dir.Lookup(accessFile)
+	expectedErr := errors.E(errors.Permission, upspin.UserName(writer))
+	if !errors.Match(expectedErr, err) {
+		t.Fatalf("Lookup: err = %v, want = %v", err, expectedErr)
+	}
+	_, err = dir.Glob(owner + "/*")
+	if !errors.Match(expectedErr, err) {
+		t.Fatalf("Glob: err = %v, want = %v", err, expectedErr)
+	}
+
+	// Admit the reader.
+	r.Put(readersGroup, owner+" "+writer)
+	wait()
+
+	if _, err := dir.Lookup(accessFile); err != nil {
+		t.Fatalf("Lookup after admission: %v", err)
+	}
+
+	// Removing the Readers file opens the server again.
+	r.Delete(readersGroup)
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+	wait()
+	if !perm.IsReader("nobody@nobody.org") {
+		t.Error("IsReader(nobody@nobody.org) = false after Readers removed, want true")
+	}
+}
+
+func TestDirPutAll(t *testing.T) {
+	env := setupEnv(t)
+	defer env.Exit()
+
+	r := testenv.NewRunner()
+	r.AddUser(env.Config)
+	r.As(owner)
+	r.Put(accessFile, "r,l:all\n*:"+owner) // Permission for anyone to read and list, owner has all rights.
+	r.MakeDirectory(groupDir)
+	r.Put(writersGroup, owner) // Only owner allowed to create roots.
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+
+	perm, wait, done := newWithEnv(t, env)
+	defer done()
+	wait()
+	wait()
+
+	writerCtx, err := env.NewUser(writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := bind.DirServer(env.Config, env.Config.DirEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := perm.WrapDir(dir).Dial(writerCtx, writerCtx.DirEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	putAller, ok := svc.(upspin.DirPutAller)
+	if !ok {
+		t.Fatal("wrapped DirServer does not implement upspin.DirPutAller")
+	}
+
+	// Each entry is checked as by Put, so only the owner can create
+	// a root, whatever else is being put with it.
+	entries := []*upspin.DirEntry{
+		{
+			Name:       writer + "/",
+			SignedName: writer + "/",
+			Attr:       upspin.AttrDirectory,
+		},
+		{
+			Name:       writer + "/dir",
+			SignedName: writer + "/dir",
+			Attr:       upspin.AttrDirectory,
+		},
+	}
+	_, err = putAller.PutAll(entries)
+	expectedErr := errors.E(errors.Permission, upspin.UserName(writer))
+	if !errors.Match(expectedErr, err) {
+		t.Fatalf("err = %v, want = %v", err, expectedErr)
+	}
+
+	// Entries that Put would pass reach the DirServer.
+	_, err = putAller.PutAll(entries[1:])
+	if !errors.Is(errors.NotExist, err) {
+		t.Fatalf("err = %v, want NotExist from the DirServer", err)
+	}
+}
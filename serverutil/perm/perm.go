@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package perm implements mutation permission checking for servers.
+// Package perm implements mutation and read permission checking for servers.
 package perm
 
 import (
@@ -23,17 +23,26 @@ import (
 // writers for a Perm instance.
 const WritersGroupFile = "Writers"
 
+// ReadersGroupFile is the name of the Group file that specifies
+// readers for a Perm instance.
+const ReadersGroupFile = "Readers"
+
 // retryTimeout is the default interval between attempts when a failure occurs.
 const retryTimeout = 30 * time.Second
 
 // Perm tracks the set of users with write access to a server, as specified by
 // the Writers Group file. These might be users who can write blocks to a
 // StoreServer or create a root on a DirServer.
+//
+// It also tracks the set of users who may read from the server at all, as
+// specified by the Readers Group file. This is a coarse gate applied before
+// any Access file is consulted, for closed deployments.
 type Perm struct {
 	cfg upspin.Config
 
-	targetUser upspin.UserName
-	targetFile upspin.PathName
+	targetUser  upspin.UserName
+	writersFile upspin.PathName
+	readersFile upspin.PathName
 
 	lookupFunc lookupFunc
 	watchFunc  watchFunc
@@ -45,6 +54,9 @@ type Perm struct {
 	// channel is closed.
 	onRetry func()
 
+	// ready is closed when the server is ready to look up Group files.
+	ready <-chan struct{}
+
 	// done signals the watch loop to exit.
 	done <-chan struct{}
 
@@ -55,7 +67,13 @@ type Perm struct {
 	// writers is the set of users allowed to write. If it's nil, all users
 	// are allowed. An empty map means no one is allowed.
 	writers map[upspin.UserName]bool
-	mu      sync.RWMutex // guards writers
+	// readers is the set of users allowed to read, in the same form.
+	// Until the Readers Group file, or its absence, is first seen it is
+	// empty, so that a closed deployment does not start open.
+	readers        map[upspin.UserName]bool
+	readersLoaded  bool         // whether readers has been set
+	readersLoading bool         // whether IsReader is loading readers
+	mu             sync.RWMutex // guards writers, readers and the flags
 }
 
 // lookupFunc looks up name, as defined by upspin.DirServer.
@@ -64,16 +82,16 @@ type lookupFunc func(upspin.PathName) (*upspin.DirEntry, error)
 // watchFunc watches name, as defined by upspin.DirServer.
 type watchFunc func(upspin.PathName, int64, <-chan struct{}) (<-chan upspin.Event, error)
 
-// New creates a new Perm monitoring the target user's Writers Group file,
-// resolving the DirServer using the given config. The target user is
+// New creates a new Perm monitoring the target user's Writers and Readers
+// Group files, resolving the DirServer using the given config. The target user is
 // typically the user name of a server, such as a StoreServer or a DirServer.
 func New(cfg upspin.Config, ready <-chan struct{}, target upspin.UserName) *Perm {
 	const op errors.Op = "serverutil/perm.New"
 	return newPerm(op, cfg, ready, target, nil, nil, noop, retry, nil)
 }
 
-// NewWithDir creates a new Perm monitoring the target user's Writers Group
-// file which must reside on the given DirServer. The target user is typically
+// NewWithDir creates a new Perm monitoring the target user's Writers and
+// Readers Group files, which must reside on the given DirServer. The target user is typically
 // the user name of a server, such as a StoreServer or a DirServer.
 func NewWithDir(cfg upspin.Config, ready <-chan struct{}, target upspin.UserName, dir upspin.DirServer) *Perm {
 	const op errors.Op = "serverutil/perm.NewFromDir"
@@ -85,22 +103,25 @@ func noop() {}
 // retry is the default implementation of Perm.onRetry.
 func retry() { time.Sleep(retryTimeout) }
 
-// newPerm creates a new Perm monitoring the target user's Writers Group file,
-// using the provided LookupFunc for lookups and the WatchFunc function to
-// watch changes on the writers file. If lookup or watch are nil the DirServer
+// newPerm creates a new Perm monitoring the target user's Writers and Readers
+// Group files, using the provided LookupFunc for lookups and the WatchFunc
+// function to watch changes on the files. If lookup or watch are nil the DirServer
 // is resolved using bind and the given config. The target user is typically
 // the user name of a server, such as a StoreServer or a DirServer.
 func newPerm(op errors.Op, cfg upspin.Config, ready <-chan struct{}, target upspin.UserName, lookup lookupFunc, watch watchFunc, onUpdate, onRetry func(), done <-chan struct{}) *Perm {
 	p := &Perm{
-		cfg:        cfg,
-		targetUser: target,
-		targetFile: upspin.PathName(target) + "/Group/" + WritersGroupFile,
-		lookupFunc: lookup,
-		watchFunc:  watch,
-		onUpdate:   onUpdate,
-		onRetry:    onRetry,
-		writers:    nil, // Start open.
-		done:       done,
+		cfg:         cfg,
+		targetUser:  target,
+		writersFile: upspin.PathName(target) + "/Group/" + WritersGroupFile,
+		readersFile: upspin.PathName(target) + "/Group/" + ReadersGroupFile,
+		lookupFunc:  lookup,
+		watchFunc:   watch,
+		onUpdate:    onUpdate,
+		onRetry:     onRetry,
+		writers:     nil,                        // Start open.
+		readers:     map[upspin.UserName]bool{}, // Start closed.
+		ready:       ready,
+		done:        done,
 	}
 
 	go func() {
@@ -115,7 +136,8 @@ func newPerm(op errors.Op, cfg upspin.Config, ready <-chan struct{}, target upsp
 	return p
 }
 
-// updateLoop continuously watches for updates on WritersGroupFile.
+// updateLoop continuously watches for updates on WritersGroupFile
+// and ReadersGroupFile.
 // It must be run in a goroutine.
 func (p *Perm) updateLoop(op errors.Op) {
 	var (
@@ -177,6 +199,11 @@ func (p *Perm) updateLoop(op errors.Op) {
 			done()
 			continue
 		}
+		// If the initial Update could not determine the readers,
+		// try again now that the DirServer is answering.
+		if err := p.loadReaders(); err != nil {
+			log.Error.Printf("%s: update readers: %s", op, err)
+		}
 		// An Access file could have granted or revoked our permission
 		// to watch the Writers file. Therefore, we must start the Watch
 		// again, after the Access event.
@@ -197,11 +224,11 @@ func (p *Perm) updateLoop(op errors.Op) {
 			accessSeq = e.Entry.Sequence
 		}
 		// Process event.
-		if e.Entry.Name != p.targetFile {
+		if e.Entry.Name != p.writersFile && e.Entry.Name != p.readersFile {
 			continue
 		}
 		if e.Delete {
-			p.deleteUsers()
+			p.deleteUsers(e.Entry.Name)
 			continue
 		}
 		err = p.updateUsers(e.Entry)
@@ -223,53 +250,103 @@ func isRelevantAccess(name upspin.PathName) bool {
 	return file == "Access" || file == "Group/Access"
 }
 
-// Update retrieves and parses the Group file that rules over the set of allowed
-// writers. This is mostly only exported for testing, but servers may use it to
-// force immediate updates.
+// Update retrieves and parses the Group files that rule over the sets of
+// allowed writers and readers. This is mostly only exported for testing,
+// but servers may use it to force immediate updates.
 func (p *Perm) Update() error {
-	entry, err := p.lookup(p.targetFile)
+	werr := p.update(p.writersFile)
+	rerr := p.update(p.readersFile)
+	p.onUpdate() // Even if we failed, unblock tests.
+	if werr != nil {
+		return werr
+	}
+	return rerr
+}
+
+// update retrieves and parses the named Group file. If it does not
+// exist, its user set is reset so that everyone is allowed.
+func (p *Perm) update(file upspin.PathName) error {
+	entry, err := p.lookup(file)
 	if err != nil {
-		// If the group file does not exist, reset writers map.
 		if errors.Is(errors.NotExist, err) {
-			p.deleteUsers() // Calls onUpdate.
+			p.setUsers(file, nil)
 			return nil
 		}
-		p.onUpdate() // Even if we failed, unblock tests.
 		return err
 	}
-	return p.updateUsers(entry) // Calls onUpdate.
+	return p.loadUsers(entry)
 }
 
-// updateUsers reads the writers Group file entry and updates the user set.
+// updateUsers reads the Group file entry and updates its user set.
 func (p *Perm) updateUsers(entry *upspin.DirEntry) error {
-	users, err := p.allowedWriters(entry)
+	err := p.loadUsers(entry)
+	p.onUpdate() // Even if we failed, unblock tests.
+	return err
+}
+
+// loadUsers reads the Group file entry and sets its user set.
+func (p *Perm) loadUsers(entry *upspin.DirEntry) error {
+	users, err := p.groupUsers(entry)
 	if err != nil {
-		p.onUpdate() // Even if we failed, unblock tests.
 		return err
 	}
-	log.Info.Printf("serverutil/perm: Setting writers to: %v", users)
-	p.mu.Lock()
-	p.writers = make(map[upspin.UserName]bool, len(users))
+	log.Info.Printf("serverutil/perm: Setting users of %s to: %v", entry.Name, users)
+	set := make(map[upspin.UserName]bool, len(users))
 	for _, u := range users {
-		p.writers[u] = true
+		set[u] = true
 	}
-	p.mu.Unlock()
-	p.onUpdate()
+	p.setUsers(entry.Name, set)
 	return nil
 }
 
-// deleteUsers resets the writers list to nil.
-func (p *Perm) deleteUsers() {
+// deleteUsers resets the user set of the named Group file to nil.
+func (p *Perm) deleteUsers(file upspin.PathName) {
+	p.setUsers(file, nil)
+	p.onUpdate()
+}
+
+// setUsers sets the user set of the named Group file.
+func (p *Perm) setUsers(file upspin.PathName, set map[upspin.UserName]bool) {
 	p.mu.Lock()
-	p.writers = nil
+	defer p.mu.Unlock()
+	switch file {
+	case p.writersFile:
+		p.writers = set
+	case p.readersFile:
+		p.readers = set
+		p.readersLoaded = true
+	}
+}
+
+// loadReaders reads the Readers Group file if it has not yet been read,
+// as when the DirServer was not answering when the Perm started. It does
+// nothing before the server is ready, or while another call is loading.
+func (p *Perm) loadReaders() error {
+	select {
+	case <-p.ready:
+	default:
+		return nil
+	}
+	p.mu.Lock()
+	if p.readersLoaded || p.readersLoading {
+		p.mu.Unlock()
+		return nil
+	}
+	p.readersLoading = true
 	p.mu.Unlock()
-	p.onUpdate()
+
+	err := p.update(p.readersFile)
+
+	p.mu.Lock()
+	p.readersLoading = false
+	p.mu.Unlock()
+	return err
 }
 
-// allowedWriters reads the contents of the entry, interprets it exactly as
+// groupUsers reads the contents of the entry, interprets it exactly as
 // an access Group file, expanding recursively if needed, and returns the slice
-// of users allowed to write to the store.
-func (p *Perm) allowedWriters(entry *upspin.DirEntry) ([]upspin.UserName, error) {
+// of users it names.
+func (p *Perm) groupUsers(entry *upspin.DirEntry) ([]upspin.UserName, error) {
 	// Pretend this is an Access file, so we can easily use it to retrieve a
 	// slice of  all authorized users.
 	fakeAccess := "w,d:" + entry.Name
@@ -296,15 +373,38 @@ func (p *Perm) IsWriter(u upspin.UserName) bool {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	// Everyone is allowed if there is no Writers Group file.
-	if p.writers == nil {
+	return isMember(p.writers, u)
+}
+
+// IsReader reports whether the user may read from the server at all.
+// The target user is always a reader. No one else is until the Readers
+// Group file has been read or found not to exist; if that has not yet
+// happened, IsReader tries to read it before answering.
+func (p *Perm) IsReader(u upspin.UserName) bool {
+	if u == p.targetUser {
+		return true
+	}
+	if err := p.loadReaders(); err != nil {
+		log.Error.Printf("serverutil/perm.IsReader: %s", err)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	// Everyone is allowed if there is no Readers Group file.
+	return isMember(p.readers, u)
+}
+
+// isMember reports whether the user is in the set, which is nil if
+// everyone is allowed.
+func isMember(set map[upspin.UserName]bool, u upspin.UserName) bool {
+	if set == nil {
 		return true
 	}
 	// If the special user "all@upspin.io" is present, allow all.
-	if p.writers[access.AllUsers] {
+	if set[access.AllUsers] {
 		return true
 	}
 	// Is this exact user allowed?
-	if p.writers[u] {
+	if set[u] {
 		return true
 	}
 	// Maybe the domain is wildcarded. Check this case last as it's the most
@@ -315,7 +415,7 @@ func (p *Perm) IsWriter(u upspin.UserName) bool {
 		log.Error.Printf("serverutil/perm: unexpected error: %s", err)
 		return false
 	}
-	return p.writers[upspin.UserName("*@"+domain)]
+	return set[upspin.UserName("*@"+domain)]
 }
 
 func (p *Perm) lookup(name upspin.PathName) (*upspin.DirEntry, error) {
@@ -330,6 +430,10 @@ func (p *Perm) lookup(name upspin.PathName) (*upspin.DirEntry, error) {
 	if err != nil {
 		return nil, err
 	}
+	if dir.Endpoint().Transport == upspin.Unassigned {
+		// A user without a directory server has no Group files.
+		return nil, errors.E(name, errors.NotExist, "no directory server")
+	}
 	return dir.Lookup(name)
 }
 
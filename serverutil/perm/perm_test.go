@@ -6,10 +6,12 @@ package perm
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"upspin.io/bind"
+	"upspin.io/errors"
 	"upspin.io/test/testenv"
 	"upspin.io/upspin"
 )
@@ -23,6 +25,7 @@ const (
 
 	groupDir     = owner + "/Group"
 	writersGroup = groupDir + "/" + WritersGroupFile
+	readersGroup = groupDir + "/" + ReadersGroupFile
 )
 
 // setupEnv sets up a test environment, used by the tests in this package.
@@ -110,6 +113,29 @@ func TestCantFindFileAllowsAll(t *testing.T) {
 		if !perm.IsWriter(user) {
 			t.Errorf("IsWriter(%q)=false, want true", user)
 		}
+		if !perm.IsReader(user) {
+			t.Errorf("IsReader(%q)=false, want true", user)
+		}
+	}
+}
+
+func TestReadersClosedUntilLoaded(t *testing.T) {
+	env := setupEnv(t)
+	defer env.Exit()
+
+	perm, wait, done := newWithEnv(t, env)
+	defer done()
+
+	// No one but the target user may read before the first update.
+	if perm.IsReader("nobody@nobody.org") {
+		t.Error("IsReader(nobody@nobody.org) = true before update, want false")
+	}
+	if !perm.IsReader(owner) {
+		t.Errorf("IsReader(%q) = false before update, want true", owner)
+	}
+	wait()
+	if !perm.IsReader("nobody@nobody.org") {
+		t.Error("IsReader(nobody@nobody.org) = false after update, want true")
 	}
 }
 
@@ -312,3 +338,40 @@ func errorReturningWatch(_ upspin.PathName, _ int64, done <-chan struct{}) (<-ch
 	}()
 	return c, nil
 }
+
+func TestReadersLoadedOnRead(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		dirDown = true
+	)
+	lookup := func(name upspin.PathName) (*upspin.DirEntry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if dirDown {
+			return nil, errors.E(name, errors.IO, "dir server not answering")
+		}
+		return nil, errors.E(name, errors.NotExist)
+	}
+	watch := func(upspin.PathName, int64, <-chan struct{}) (<-chan upspin.Event, error) {
+		return nil, upspin.ErrNotSupported
+	}
+	wait, onUpdate, onRetry, ready := newStubs(t)
+	done := make(chan struct{})
+	defer close(done)
+	perm := newPerm("TestReadersLoadedOnRead", nil, ready, owner, lookup, watch, onUpdate, onRetry, done)
+
+	// The first update fails, leaving the gate closed.
+	wait()
+	if perm.IsReader("nobody@nobody.org") {
+		t.Error("IsReader(nobody@nobody.org) = true while dir server is down, want false")
+	}
+
+	// Once the dir server answers, the next read finds there is no
+	// Readers file without waiting for a watch event.
+	mu.Lock()
+	dirDown = false
+	mu.Unlock()
+	if !perm.IsReader("nobody@nobody.org") {
+		t.Error("IsReader(nobody@nobody.org) = false once dir server is up, want true")
+	}
+}
@@ -6,6 +6,9 @@ package perm
 
 // Features:
 // - Resolves remote Group files if necessary.
+// - Refuses reads by users not in the Readers Group file, if there is one,
+//   and by all but the server's own user until that file has been loaded
+//   or found not to exist, so a closed deployment never starts open.
 // - Blocks mutations to Store until it has had a chance to prove that either
 //   there is no Group file and hence writes are free for all, or until the
 //   Group file has been fully loaded. This prevents a window of vulnerability
@@ -51,15 +54,91 @@ type storeWrapper struct {
 	perm *Perm
 }
 
+var (
+	_ upspin.StoreExister    = (*storeWrapper)(nil)
+	_ upspin.StoreGetBatcher = (*storeWrapper)(nil)
+)
+
 // Get implements upspin.StoreServer.
 func (s *storeWrapper) Get(ref upspin.Reference) ([]byte, *upspin.Refdata, []upspin.Location, error) {
 	const op errors.Op = "store/perm.Get"
 
+	if err := s.canGet(op, ref); err != nil {
+		return nil, nil, nil, err
+	}
+	return s.StoreServer.Get(ref)
+}
+
+// Exists implements upspin.StoreExister. If the wrapped StoreServer
+// does not implement it, Exists returns upspin.ErrNotSupported.
+func (s *storeWrapper) Exists(ref upspin.Reference) (bool, error) {
+	const op errors.Op = "store/perm.Exists"
+
+	if err := s.canGet(op, ref); err != nil {
+		return false, err
+	}
+	ex, ok := s.StoreServer.(upspin.StoreExister)
+	if !ok {
+		return false, upspin.ErrNotSupported
+	}
+	return ex.Exists(ref)
+}
+
+// GetBatch implements upspin.StoreGetBatcher. Each reference is checked
+// as by Get; one the user may not get fails without affecting the others.
+// If the wrapped StoreServer does not implement upspin.StoreGetBatcher,
+// GetBatch returns upspin.ErrNotSupported in every result.
+func (s *storeWrapper) GetBatch(refs []upspin.Reference) []upspin.StoreGetResult {
+	const op errors.Op = "store/perm.GetBatch"
+
+	b, ok := s.StoreServer.(upspin.StoreGetBatcher)
+	if !ok {
+		results := make([]upspin.StoreGetResult, len(refs))
+		for i := range results {
+			results[i].Error = upspin.ErrNotSupported
+		}
+		return results
+	}
+	var results []upspin.StoreGetResult
+	size := 0
+	for len(refs) > 0 && size < upspin.MaxGetBatchBytes {
+		// Find the run of references the user may get.
+		n := 0
+		var err error
+		for ; n < len(refs); n++ {
+			if err = s.canGet(op, refs[n]); err != nil {
+				break
+			}
+		}
+		if n == 0 {
+			results = append(results, upspin.StoreGetResult{Error: err})
+			refs = refs[1:]
+			continue
+		}
+		batch := b.GetBatch(refs[:n])
+		for _, r := range batch {
+			size += len(r.Data)
+		}
+		results = append(results, batch...)
+		if len(batch) < n {
+			// The wrapped store stopped early.
+			break
+		}
+		refs = refs[n:]
+	}
+	return results
+}
+
+// canGet returns an error if the user may not get the data for ref.
+func (s *storeWrapper) canGet(op errors.Op, ref upspin.Reference) error {
+	if !s.perm.IsReader(s.user) {
+		return errors.E(op, s.user, errors.Permission, "user not authorized")
+	}
 	// Only storage administrators should be permitted to list references.
 	if strings.HasPrefix(string(ref), string(upspin.ListRefsMetadata)) && s.user != s.perm.targetUser {
-		return nil, nil, nil, errors.E(op, s.user, errors.Permission, "user not authorized")
+		return errors.E(op, s.user, errors.Permission, "user not authorized")
 	}
-	return s.StoreServer.Get(ref)
+	return nil
 }
 
 // Put implements upspin.StoreServer.
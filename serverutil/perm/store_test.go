@@ -303,3 +303,227 @@ func TestStoreIntegration(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStoreReaders(t *testing.T) {
+	ownerStore, _, ownerEnv, wait, cleanup := setupStoreEnv(t)
+	defer cleanup()
+
+	readerConfig, err := ownerEnv.NewUser(writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testenv.NewRunner()
+	r.AddUser(ownerEnv.Config)
+
+	wait()
+
+	srv, err := ownerStore.Dial(readerConfig, ownerEnv.Config.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerStore := srv.(upspin.StoreServer)
+
+	// Everyone can read when there is no Readers file.
+	ref, err := ownerStore.Put([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := readerStore.Get(ref.Reference); err != nil {
+		t.Fatal(err)
+	}
+
+	// Allow only owner.
+	r.As(owner)
+	r.Put(accessFile, accessContent) // So server can lookup Readers.
+	r.MakeDirectory(groupDir)
+	r.Put(readersGroup, owner)
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+
+	wait()
+
+	if _, _, _, err := ownerStore.Get(ref.Reference); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err = readerStore.Get(ref.Reference)
+	expectedErr := errors.E(errors.Permission, upspin.UserName(writer))
+	if !errors.Match(expectedErr, err) {
+		t.Fatalf("err = %v, want = %v", err, expectedErr)
+	}
+	// Writing is still governed by Writers alone.
+	if _, err := readerStore.Put([]byte("456")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Admit the reader.
+	r.Put(readersGroup, owner+" "+writer)
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+	wait()
+
+	if _, _, _, err := readerStore.Get(ref.Reference); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreExists(t *testing.T) {
+	ownerStore, _, ownerEnv, wait, cleanup := setupStoreEnv(t)
+	defer cleanup()
+
+	readerConfig, err := ownerEnv.NewUser(writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testenv.NewRunner()
+	r.AddUser(ownerEnv.Config)
+
+	wait()
+
+	srv, err := ownerStore.Dial(readerConfig, ownerEnv.Config.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerStore, ok := srv.(upspin.StoreExister)
+	if !ok {
+		t.Fatal("wrapped store does not implement upspin.StoreExister")
+	}
+
+	ref, err := ownerStore.Put([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := readerStore.Exists(ref.Reference); err != nil || !exists {
+		t.Fatalf("Exists(%q) = %v, %v; want true, nil", ref.Reference, exists, err)
+	}
+	if exists, err := readerStore.Exists("nosuchref"); err != nil || exists {
+		t.Fatalf("Exists(nosuchref) = %v, %v; want false, nil", exists, err)
+	}
+
+	// Allow only owner to read.
+	r.As(owner)
+	r.Put(accessFile, accessContent) // So server can lookup Readers.
+	r.MakeDirectory(groupDir)
+	r.Put(readersGroup, owner)
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+
+	wait()
+
+	_, err = readerStore.Exists(ref.Reference)
+	expectedErr := errors.E(errors.Permission, upspin.UserName(writer))
+	if !errors.Match(expectedErr, err) {
+		t.Fatalf("err = %v, want = %v", err, expectedErr)
+	}
+	if exists, err := ownerStore.(upspin.StoreExister).Exists(ref.Reference); err != nil || !exists {
+		t.Fatalf("owner Exists(%q) = %v, %v; want true, nil", ref.Reference, exists, err)
+	}
+}
+
+// batchStore is a StoreServer that implements upspin.StoreGetBatcher
+// by calling Get for each reference.
+type batchStore struct {
+	upspin.StoreServer
+}
+
+func (s batchStore) GetBatch(refs []upspin.Reference) []upspin.StoreGetResult {
+	results := make([]upspin.StoreGetResult, len(refs))
+	for i, ref := range refs {
+		r := &results[i]
+		r.Data, r.Refdata, r.Locations, r.Error = s.Get(ref)
+	}
+	return results
+}
+
+func (s batchStore) Dial(cfg upspin.Config, e upspin.Endpoint) (upspin.Service, error) {
+	svc, err := s.StoreServer.Dial(cfg, e)
+	if err != nil {
+		return nil, err
+	}
+	return batchStore{svc.(upspin.StoreServer)}, nil
+}
+
+func TestStoreGetBatch(t *testing.T) {
+	ownerEnv := setupEnv(t)
+	defer ownerEnv.Exit()
+	store, err := bind.StoreServer(ownerEnv.Config, ownerEnv.Config.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	perm, wait, done := newWithEnv(t, ownerEnv)
+	defer done()
+	ownerStore := perm.WrapStore(batchStore{store})
+
+	readerConfig, err := ownerEnv.NewUser(writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testenv.NewRunner()
+	r.AddUser(ownerEnv.Config)
+
+	wait()
+
+	srv, err := ownerStore.Dial(readerConfig, ownerEnv.Config.StoreEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerStore, ok := srv.(upspin.StoreGetBatcher)
+	if !ok {
+		t.Fatal("wrapped store does not implement upspin.StoreGetBatcher")
+	}
+
+	ref1, err := ownerStore.Put([]byte("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref2, err := ownerStore.Put([]byte("two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listRefs := upspin.ListRefsMetadata
+
+	// Only the owner may list references; that fails alone.
+	results := readerStore.GetBatch([]upspin.Reference{ref1.Reference, listRefs, ref2.Reference})
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, want := range map[int]string{0: "one", 2: "two"} {
+		if results[i].Error != nil || string(results[i].Data) != want {
+			t.Errorf("result %d = %q, %v; want %q, nil", i, results[i].Data, results[i].Error, want)
+		}
+	}
+	expectedErr := errors.E(errors.Permission, upspin.UserName(writer))
+	if !errors.Match(expectedErr, results[1].Error) {
+		t.Errorf("result 1 err = %v, want = %v", results[1].Error, expectedErr)
+	}
+
+	// Allow only owner to read.
+	r.As(owner)
+	r.Put(accessFile, accessContent) // So server can lookup Readers.
+	r.MakeDirectory(groupDir)
+	r.Put(readersGroup, owner)
+	if r.Failed() {
+		t.Fatal(r.Diag())
+	}
+
+	wait()
+
+	results = readerStore.GetBatch([]upspin.Reference{ref1.Reference, ref2.Reference})
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for i, r := range results {
+		if !errors.Match(expectedErr, r.Error) {
+			t.Errorf("result %d err = %v, want = %v", i, r.Error, expectedErr)
+		}
+	}
+	results = ownerStore.(upspin.StoreGetBatcher).GetBatch([]upspin.Reference{ref1.Reference, ref2.Reference})
+	if len(results) != 2 || results[0].Error != nil || results[1].Error != nil {
+		t.Fatalf("owner GetBatch = %v, want two results without error", results)
+	}
+}
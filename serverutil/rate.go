@@ -139,3 +139,75 @@ func (r *RateLimiter) pass(now time.Time, key string) (bool, time.Duration) {
 
 	return true, 0
 }
+
+// WindowLimiter implements a rate limiter that permits at most Limit
+// requests for a key in any sliding window of time of length Window.
+// Denied requests do not count against the limit.
+type WindowLimiter struct {
+	// Limit specifies the number of requests permitted for a key
+	// within Window. It must be positive.
+	Limit int
+
+	// Window specifies the length of the sliding window.
+	Window time.Duration
+
+	mu    sync.Mutex // Guards the fields below.
+	m     map[string][]time.Time
+	swept time.Time
+}
+
+// Pass attempts to pass key through the rate limiter, returning true if key is
+// within the rate limit. If it returns false it also returns the duration that
+// must elapse before the key will be allowed to pass again.
+func (w *WindowLimiter) Pass(key string) (bool, time.Duration) {
+	return w.pass(time.Now(), key)
+}
+
+func (w *WindowLimiter) pass(now time.Time, key string) (bool, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Initialize the map lazily so that WindowLimiter
+	// may be useful without a constructor.
+	if w.m == nil {
+		w.m = map[string][]time.Time{}
+	}
+
+	// Once per window, forget the keys whose requests have all left
+	// the window. Sweeping more often would cost a pass over every
+	// key on each request while we are tracking the maximum.
+	if now.Sub(w.swept) >= w.Window {
+		for k, times := range w.m {
+			if !now.Before(times[len(times)-1].Add(w.Window)) {
+				delete(w.m, k)
+			}
+		}
+		w.swept = now
+	}
+
+	// Drop the requests for key that have left the window.
+	// The times are kept in order, oldest first.
+	times := w.m[key]
+	i := 0
+	for i < len(times) && !now.Before(times[i].Add(w.Window)) {
+		i++
+	}
+	times = times[i:]
+	if len(times) >= w.Limit {
+		w.m[key] = times
+		return false, times[0].Add(w.Window).Sub(now)
+	}
+
+	// If we are tracking too many keys, drop an arbitrary one
+	// to make room.
+	if _, ok := w.m[key]; !ok {
+		for k := range w.m {
+			if len(w.m) < rateMaxVisitors {
+				break
+			}
+			delete(w.m, k)
+		}
+	}
+	w.m[key] = append(times, now)
+	return true, 0
+}
@@ -79,6 +79,94 @@ func TestRateLimiter(t *testing.T) {
 	}
 }
 
+func TestWindowLimiter(t *testing.T) {
+	w := WindowLimiter{
+		Limit:  3,
+		Window: 60 * time.Second,
+	}
+
+	now := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	const (
+		a, b = "a", "b"
+	)
+	testCases := []struct {
+		key     string
+		sec     int
+		pass    bool
+		waitSec int
+		len     int
+	}{
+		{a, 0, true, 0, 1},
+		{a, 10, true, 0, 1},
+		{a, 20, true, 0, 1},
+		{a, 30, false, 30, 1}, // a has had 3 requests since 0s.
+		{b, 30, true, 0, 2},
+		{a, 59, false, 1, 2},
+		{a, 60, true, 0, 2}, // The request at 0s has left the window.
+		{a, 61, false, 9, 2},
+		{a, 90, true, 0, 2},
+
+		{b, 100, true, 0, 2},
+		{"c", 140, true, 0, 3},
+		{"c", 200, true, 0, 1}, // Sweep forgets a and b.
+		{a, 205, true, 0, 2},
+	}
+	for i, c := range testCases {
+		pass, wait := w.pass(now.Add(time.Duration(c.sec)*time.Second), c.key)
+		if pass != c.pass {
+			t.Errorf("case %d: %d seconds for %q: got %v, want %v", i, c.sec, c.key, pass, c.pass)
+		}
+		if wait != time.Duration(c.waitSec)*time.Second {
+			t.Errorf("case %d: expected wait = %d s, got = %v", i, c.waitSec, wait)
+		}
+		if got, want := len(w.m), c.len; got != want {
+			t.Errorf("case %d: %d seconds for %q: len(w.m) = %d, want %d", i, c.sec, c.key, got, want)
+		}
+	}
+}
+
+func TestWindowLimiterFull(t *testing.T) {
+	w := WindowLimiter{
+		Limit:  1,
+		Window: 60 * time.Second,
+	}
+
+	now := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < rateMaxVisitors; i++ {
+		w.pass(now, fmt.Sprint(i))
+	}
+	swept := w.swept
+
+	// At the maximum, each new key makes room without a sweep.
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		k := fmt.Sprint("new", i)
+		if ok, _ := w.pass(now, k); !ok {
+			t.Errorf("new key %q denied", k)
+		}
+		if _, ok := w.m[k]; !ok {
+			t.Errorf("new key %q not tracked", k)
+		}
+		if got, want := len(w.m), rateMaxVisitors; got != want {
+			t.Errorf("after %q: len(w.m) = %d, want %d", k, got, want)
+		}
+	}
+	if !w.swept.Equal(swept) {
+		t.Errorf("swept at %v, want no sweep since %v", w.swept, swept)
+	}
+	if ok, _ := w.pass(now, "new9"); ok {
+		t.Errorf("key %q passed twice within the window", "new9")
+	}
+
+	// Once the window has passed, the sweep forgets the first keys.
+	now = swept.Add(w.Window)
+	w.pass(now, "last")
+	if got, want := len(w.m), 11; got != want {
+		t.Errorf("after sweep: len(w.m) = %d, want %d", got, want)
+	}
+}
+
 func BenchmarkRateLimiter(b *testing.B) {
 	r := RateLimiter{
 		Backoff: 10 * time.Second,
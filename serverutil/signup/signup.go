@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -47,10 +48,58 @@ type handler struct {
 	fact    upspin.Factotum
 	key     upspin.KeyServer
 	mail    *MailConfig
+	limits  Limits
+}
+
+// RateLimiter is implemented by serverutil.RateLimiter and
+// serverutil.WindowLimiter. Pass reports whether a request for key is
+// within the limit and, if not, how long until it will be.
+type RateLimiter interface {
+	Pass(key string) (bool, time.Duration)
+}
 
-	rate serverutil.RateLimiter
+// Limits holds the rate limiters applied to the signup requests made by
+// 'upspin signup', each of which sends mail to the user signing up.
+// Requests that exceed either limit are refused with HTTP status 429
+// (Too Many Requests). A nil limiter imposes no limit. The address limit
+// is applied first, so requests refused by the user limit still count
+// against the address limit.
+type Limits struct {
+	// Addr limits requests by the IP address of the client.
+	Addr RateLimiter
+
+	// User limits requests by the user name, and so by the
+	// email address to which mail is sent.
+	User RateLimiter
+
+	// Proxies lists the networks of the trusted reverse proxies or
+	// load balancers in front of the server. For a request arriving
+	// from one of them, the client address used by Addr is taken from
+	// the X-Forwarded-For header instead.
+	Proxies []*net.IPNet
 }
 
+// The limits used by NewHandler when it is given nil Limits.
+const (
+	// DefaultAddrLimit is the number of requests permitted from
+	// an IP address within DefaultAddrWindow.
+	DefaultAddrLimit = 20
+
+	// DefaultAddrWindow is the length of the sliding window over
+	// which requests from an IP address are counted.
+	DefaultAddrWindow = 1 * time.Hour
+
+	// DefaultUserBackoff is the time for which further requests for a
+	// user name are refused after a request. It doubles with each
+	// request that arrives before DefaultUserMaxBackoff has passed.
+	DefaultUserBackoff = 1 * time.Minute
+
+	// DefaultUserMaxBackoff is the longest time for which requests for
+	// a user name are refused, limiting the mail sent to any address
+	// to about one message a day.
+	DefaultUserMaxBackoff = 24 * time.Hour
+)
+
 // MailConfig holds the mail configuration used by the signup handler.
 type MailConfig struct {
 	// Mail holds the mailer used for sending signup emails and notifications.
@@ -72,16 +121,23 @@ type MailConfig struct {
 // the email).
 // The Factotum is used to sign the verification URL. The KeyServer is where
 // the new user will be created. The MailConfig is used to send mail.
-func NewHandler(baseURL string, fact upspin.Factotum, key upspin.KeyServer, mc *MailConfig) http.Handler {
+// The Limits throttle the requests that send mail; if they are nil,
+// requests are limited to DefaultAddrLimit per IP address in each
+// DefaultAddrWindow, and requests for a user back off exponentially from
+// DefaultUserBackoff to DefaultUserMaxBackoff.
+func NewHandler(baseURL string, fact upspin.Factotum, key upspin.KeyServer, mc *MailConfig, limits *Limits) http.Handler {
+	if limits == nil {
+		limits = &Limits{
+			Addr: &serverutil.WindowLimiter{Limit: DefaultAddrLimit, Window: DefaultAddrWindow},
+			User: &serverutil.RateLimiter{Backoff: DefaultUserBackoff, Max: DefaultUserMaxBackoff},
+		}
+	}
 	return &handler{
 		baseURL: baseURL,
 		fact:    fact,
 		key:     key,
 		mail:    mc,
-		rate: serverutil.RateLimiter{
-			Backoff: 1 * time.Minute,
-			Max:     24 * time.Hour,
-		},
+		limits:  *limits,
 	}
 }
 
@@ -197,16 +253,26 @@ func (m *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Aggressively rate limit requests to this service,
 	// so that we can't be used for a mail bomb.
-	// TODO(adg): also limit by remote IP address
 	name, _, domain, err := user.Parse(u.Name)
 	if err != nil {
 		errorf(http.StatusBadRequest, "invalid user name: %v", err)
 		return
 	}
-	key := strings.ToLower(name + "@" + domain)
-	if ok, wait := m.rate.Pass(key); !ok {
-		errorf(http.StatusTooManyRequests, "repeated signup attempt; please wait %v before trying again", wait)
-		return
+	addr := m.limits.clientAddr(r)
+	if m.limits.Addr != nil {
+		if ok, wait := m.limits.Addr.Pass(addr); !ok {
+			log.Info.Printf("signup: throttled request from %s for %q", addr, u.Name)
+			errorf(http.StatusTooManyRequests, "too many signup attempts; please wait %v before trying again", wait)
+			return
+		}
+	}
+	if m.limits.User != nil {
+		key := strings.ToLower(name + "@" + domain)
+		if ok, wait := m.limits.User.Pass(key); !ok {
+			log.Info.Printf("signup: throttled repeated request from %s for %q", addr, u.Name)
+			errorf(http.StatusTooManyRequests, "repeated signup attempt; please wait %v before trying again", wait)
+			return
+		}
 	}
 
 	// Construct signed sign-up URL.
@@ -256,6 +322,51 @@ func (m *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "OK")
 }
 
+// clientAddr returns the IP address of the client that made r. If r came
+// from a trusted proxy, it is the rightmost address in X-Forwarded-For
+// that is not itself a trusted proxy: each proxy appends the address from
+// which it received the request, so the addresses to the left of that
+// one were supplied by the client and may be forged.
+func (l *Limits) clientAddr(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	if !l.isProxy(addr) {
+		return addr
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			// Count a malformed header against the proxy.
+			break
+		}
+		addr = hop
+		if !l.isProxy(addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// isProxy reports whether addr is the address of a trusted proxy.
+func (l *Limits) isProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range l.Proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // verifySignupSignature verifies that the new user record comprised of name,
 // dir, store and key were properly signed by the new user using the private key
 // that corresponds to the public key provided.
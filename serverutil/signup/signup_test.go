@@ -6,14 +6,17 @@ package signup
 
 import (
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"upspin.io/config"
 	"upspin.io/factotum"
 	"upspin.io/key/inprocess"
+	"upspin.io/serverutil"
 	"upspin.io/test/testutil"
 	"upspin.io/upspin"
 )
@@ -31,7 +34,7 @@ func TestSignup(t *testing.T) {
 		Mail:    mail,
 		Notify:  "signup@noti.fy",
 	}
-	h := NewHandler("will-be-overridden", serverFact, key, &mc)
+	h := NewHandler("will-be-overridden", serverFact, key, &mc, nil)
 	s := httptest.NewServer(h)
 	defer s.Close()
 	h.(*handler).baseURL = s.URL
@@ -128,6 +131,97 @@ func TestSignup(t *testing.T) {
 	}
 }
 
+func TestSignupLimits(t *testing.T) {
+	serverFact, err := factotum.NewFromDir(testutil.Repo("key", "testdata", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := &mailStub{}
+	limits := &Limits{
+		Addr: &serverutil.WindowLimiter{Limit: 4, Window: time.Hour},
+		User: &serverutil.WindowLimiter{Limit: 1, Window: time.Hour},
+	}
+	h := NewHandler("will-be-overridden", serverFact, inprocess.New(), &MailConfig{Mail: mail}, limits)
+	s := httptest.NewServer(h)
+	defer s.Close()
+	h.(*handler).baseURL = s.URL
+
+	signupURLScheme = "http"
+	defer func() {
+		signupURLScheme = "https"
+	}()
+	userFact, err := factotum.NewFromDir(testutil.Repo("key", "testdata", "bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(name upspin.UserName) error {
+		cfg := config.New()
+		cfg = config.SetUserName(cfg, name)
+		cfg = config.SetFactotum(cfg, userFact)
+		cfg = config.SetKeyEndpoint(cfg, upspin.Endpoint{
+			Transport: upspin.Remote,
+			NetAddr:   upspin.NetAddr(strings.TrimPrefix(s.URL, "http://")),
+		})
+		return MakeRequest(cfg)
+	}
+
+	for _, c := range []struct {
+		name upspin.UserName
+		err  string
+	}{
+		{"bob@example.com", ""},
+		{"bob@example.com", "repeated signup attempt"},
+		{"carla@example.com", ""},
+		{"dave@example.com", ""},
+		{"erin@example.com", "too many signup attempts"},
+	} {
+		err := request(c.name)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: %v", c.name, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: got error %v, want %q", c.name, err, c.err)
+		}
+	}
+	if got, want := len(mail.text), 3; got != want {
+		t.Errorf("got %d mail messages, want %d", got, want)
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Limits{Proxies: []*net.IPNet{proxies}}
+	for _, c := range []struct {
+		remote string
+		fwd    []string
+		want   string
+	}{
+		{"192.0.2.1:1234", nil, "192.0.2.1"},
+		// Only a trusted proxy may name the client.
+		{"192.0.2.1:1234", []string{"198.51.100.7"}, "192.0.2.1"},
+		{"10.0.0.1:1234", []string{"198.51.100.7"}, "198.51.100.7"},
+		// Addresses left of the first untrusted one may be forged.
+		{"10.0.0.1:1234", []string{"203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"10.0.0.1:1234", []string{"203.0.113.9", "198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		// A proxy that does not say is held responsible.
+		{"10.0.0.1:1234", nil, "10.0.0.1"},
+		{"10.0.0.1:1234", []string{"bogus"}, "10.0.0.1"},
+		{"10.0.0.1:1234", []string{"10.0.0.2"}, "10.0.0.2"},
+	} {
+		r := httptest.NewRequest("POST", "/signup", nil)
+		r.RemoteAddr = c.remote
+		for _, f := range c.fwd {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+		if got := l.clientAddr(r); got != c.want {
+			t.Errorf("clientAddr from %s with X-Forwarded-For %q = %s, want %s", c.remote, c.fwd, got, c.want)
+		}
+	}
+}
+
 // mailStub is an implementation of mail.Mail that simply stores the text of
 // the sent messages.
 type mailStub struct {
@@ -8,11 +8,14 @@ package storeserver // import "upspin.io/serverutil/storeserver"
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"upspin.io/config"
 	"upspin.io/errors"
 	"upspin.io/flags"
 	"upspin.io/log"
+	"upspin.io/metric/prometheus"
 	"upspin.io/rpc/storeserver"
 	"upspin.io/serverutil/perm"
 	"upspin.io/store/inprocess"
@@ -28,7 +31,7 @@ import (
 )
 
 func Main() (ready chan<- struct{}) {
-	flags.Parse(flags.Server, "kind", "serverconfig")
+	flags.Parse(flags.Server, "kind", "metrics", "serverconfig")
 
 	// Load configuration and keys for this server. It needs a real upspin username and keys.
 	cfg, err := config.FromFile(flags.Config)
@@ -41,7 +44,7 @@ func Main() (ready chan<- struct{}) {
 	err = nil
 	switch flags.ServerKind {
 	case "inprocess":
-		store = inprocess.New()
+		store, err = newInProcess(flags.ServerConfig)
 	case "server":
 		store, err = server.New(flags.ServerConfig...)
 	default:
@@ -58,6 +61,27 @@ func Main() (ready chan<- struct{}) {
 
 	httpStore := storeserver.New(cfg, store, upspin.NetAddr(flags.NetAddr))
 	http.Handle("/api/Store/", httpStore)
+	if flags.Metrics {
+		http.Handle("/metrics", prometheus.NewHandler())
+	}
 
 	return ready
 }
+
+// newInProcess returns an in-process StoreServer configured by the options.
+// The only option is "maxBytes=n", which limits the memory used for data.
+func newInProcess(options []string) (upspin.StoreServer, error) {
+	var opts []inprocess.Option
+	for _, option := range options {
+		const prefix = "maxBytes="
+		if !strings.HasPrefix(option, prefix) {
+			return nil, errors.Errorf("unrecognized inprocess option %q", option)
+		}
+		n, err := strconv.ParseInt(option[len(prefix):], 10, 64)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid inprocess option %q", option)
+		}
+		opts = append(opts, inprocess.MaxBytes(n))
+	}
+	return inprocess.New(opts...), nil
+}
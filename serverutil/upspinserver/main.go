@@ -26,6 +26,7 @@ import (
 	"upspin.io/factotum"
 	"upspin.io/flags"
 	"upspin.io/log"
+	"upspin.io/metric/prometheus"
 	"upspin.io/rpc/dirserver"
 	"upspin.io/rpc/storeserver"
 	"upspin.io/serverutil/perm"
@@ -59,7 +60,7 @@ func defaultCfgPath() string {
 }
 
 func Main() (ready chan struct{}) {
-	flags.Parse(flags.Server)
+	flags.Parse(flags.Server, "metrics")
 
 	if git := version.GitSHA; git != "" {
 		log.Info.Printf("upspinserver built on %s at commit %s",
@@ -152,6 +153,9 @@ func initServer(mode initMode) (*subcmd.ServerConfig, upspin.Config, *perm.Perm,
 	httpDir := dirserver.New(dirCfg, dir, serverConfig.Addr)
 	http.Handle("/api/Store/", httpStore)
 	http.Handle("/api/Dir/", httpDir)
+	if flags.Metrics {
+		http.Handle("/metrics", prometheus.NewHandler())
+	}
 
 	// Set public-facing network address (used by Let's Encrypt).
 	flags.NetAddr = string(serverConfig.Addr)
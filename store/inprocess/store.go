@@ -6,6 +6,7 @@
 package inprocess // import "upspin.io/store/inprocess"
 
 import (
+	"container/list"
 	"sync"
 
 	"upspin.io/errors"
@@ -19,17 +20,39 @@ type service struct {
 	data *dataService
 }
 
-var _ upspin.StoreServer = (*service)(nil)
+var (
+	_ upspin.StoreServer  = (*service)(nil)
+	_ upspin.StoreExister = (*service)(nil)
+)
 
-func New() upspin.StoreServer {
-	return &service{
-		data: &dataService{
-			endpoint: upspin.Endpoint{
-				Transport: upspin.InProcess,
-				NetAddr:   "", // Ignored.
-			},
-			blob: make(map[upspin.Reference][]byte),
+// An Option configures a StoreServer created by New.
+type Option func(*dataService)
+
+// MaxBytes limits the total size of the data held by the StoreServer to n
+// bytes. When a Put exceeds the limit, the least recently used references
+// are evicted and subsequent Gets for them report that they do not exist.
+// A limit of zero, the default, means no limit.
+func MaxBytes(n int64) Option {
+	return func(d *dataService) {
+		d.maxBytes = n
+	}
+}
+
+// New returns a new, empty StoreServer configured by the options.
+func New(opts ...Option) upspin.StoreServer {
+	d := &dataService{
+		endpoint: upspin.Endpoint{
+			Transport: upspin.InProcess,
+			NetAddr:   "", // Ignored.
 		},
+		blob: make(map[upspin.Reference]*list.Element),
+		lru:  list.New(),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return &service{
+		data: d,
 	}
 }
 
@@ -41,8 +64,61 @@ type dataService struct {
 	mu sync.Mutex
 	// dialed reports whether anyone has dialed us.
 	dialed bool
-	// blob contains the underlying data.
-	blob map[upspin.Reference][]byte // reference is made from SHA256 hash of data.
+	// blob holds the element of lru for each reference, which
+	// is made from SHA256 hash of data.
+	blob map[upspin.Reference]*list.Element
+	// lru holds the underlying data as *blob values,
+	// most recently used first.
+	lru *list.List
+	// size is the total length of the data held.
+	size int64
+	// maxBytes is the limit on size; zero means no limit.
+	maxBytes int64
+}
+
+// A blob is the value of an element of dataService.lru.
+type blob struct {
+	ref  upspin.Reference
+	data []byte
+}
+
+// get returns the data for ref and marks it as recently used.
+// The caller must hold d.mu.
+func (d *dataService) get(ref upspin.Reference) ([]byte, bool) {
+	elem, ok := d.blob[ref]
+	if !ok {
+		return nil, false
+	}
+	d.lru.MoveToFront(elem)
+	return elem.Value.(*blob).data, true
+}
+
+// put stores the data for ref, evicting the least recently used
+// references if the limit is exceeded. The caller must hold d.mu.
+func (d *dataService) put(ref upspin.Reference, data []byte) {
+	if _, ok := d.get(ref); ok {
+		// The reference identifies the data, so there's nothing to update.
+		return
+	}
+	d.blob[ref] = d.lru.PushFront(&blob{ref: ref, data: data})
+	d.size += int64(len(data))
+	// Never evict the data just stored, even if it alone exceeds the limit.
+	for d.maxBytes > 0 && d.size > d.maxBytes && d.lru.Len() > 1 {
+		d.remove(d.lru.Back().Value.(*blob).ref)
+	}
+}
+
+// remove deletes the data for ref, reporting whether it was present.
+// The caller must hold d.mu.
+func (d *dataService) remove(ref upspin.Reference) bool {
+	elem, ok := d.blob[ref]
+	if !ok {
+		return false
+	}
+	d.lru.Remove(elem)
+	delete(d.blob, ref)
+	d.size -= int64(len(elem.Value.(*blob).data))
+	return true
 }
 
 func copyOf(in []byte) (out []byte) {
@@ -60,7 +136,7 @@ func (s *service) Endpoint() upspin.Endpoint {
 func (s *service) Put(ciphertext []byte) (*upspin.Refdata, error) {
 	ref := upspin.Reference(sha256key.Of(ciphertext).String())
 	s.data.mu.Lock()
-	s.data.blob[ref] = copyOf(ciphertext)
+	s.data.put(ref, copyOf(ciphertext))
 	s.data.mu.Unlock()
 	refdata := &upspin.Refdata{
 		Reference: ref,
@@ -75,21 +151,28 @@ func (s *service) Delete(ref upspin.Reference) error {
 	const op errors.Op = "store/inprocess.Delete"
 	s.data.mu.Lock()
 	defer s.data.mu.Unlock()
-	_, ok := s.data.blob[ref]
-	if !ok {
+	if !s.data.remove(ref) {
 		return errors.E(op, errors.NotExist, errors.Errorf("no such blob: %s", ref))
 	}
-	delete(s.data.blob, ref)
 	return nil
 }
 
 // DeleteAll deletes all data from memory.
 func (s *service) DeleteAll() {
 	s.data.mu.Lock()
-	s.data.blob = make(map[upspin.Reference][]byte)
+	s.data.blob = make(map[upspin.Reference]*list.Element)
+	s.data.lru.Init()
+	s.data.size = 0
 	s.data.mu.Unlock()
 }
 
+// Size returns the total number of bytes of data held in memory.
+func (s *service) Size() int64 {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	return s.data.size
+}
+
 // Get implements upspin.StoreServer
 // TODO: Get should provide alternate location if missing.
 func (s *service) Get(ref upspin.Reference) (ciphertext []byte, refdata *upspin.Refdata, other []upspin.Location, err error) {
@@ -98,7 +181,7 @@ func (s *service) Get(ref upspin.Reference) (ciphertext []byte, refdata *upspin.
 		return nil, nil, nil, errors.E(op, errors.Invalid, "empty reference")
 	}
 	s.data.mu.Lock()
-	data, ok := s.data.blob[ref]
+	data, ok := s.data.get(ref)
 	s.data.mu.Unlock()
 	if !ok {
 		return nil, nil, nil, errors.E(op, errors.NotExist, errors.Errorf("no such blob: %s", ref))
@@ -114,6 +197,18 @@ func (s *service) Get(ref upspin.Reference) (ciphertext []byte, refdata *upspin.
 	return copyOf(data), refdata, nil, nil
 }
 
+// Exists implements upspin.StoreExister.
+func (s *service) Exists(ref upspin.Reference) (bool, error) {
+	const op errors.Op = "store/inprocess.Exists"
+	if ref == "" {
+		return false, errors.E(op, errors.Invalid, "empty reference")
+	}
+	s.data.mu.Lock()
+	_, ok := s.data.blob[ref]
+	s.data.mu.Unlock()
+	return ok, nil
+}
+
 // Dial always returns an authenticated instance to the underlying service.
 // There is only one data set in the address space.
 // Dial ignores the address within the endpoint but requires that the transport be InProcess.
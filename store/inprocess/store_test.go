@@ -0,0 +1,117 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inprocess
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"upspin.io/errors"
+	"upspin.io/upspin"
+)
+
+func TestMaxBytes(t *testing.T) {
+	s := New(MaxBytes(30)).(*service)
+
+	put := func(data string) upspin.Reference {
+		t.Helper()
+		refdata, err := s.Put([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return refdata.Reference
+	}
+	present := func(ref upspin.Reference) bool {
+		t.Helper()
+		_, _, _, err := s.Get(ref)
+		if errors.Is(errors.NotExist, err) {
+			return false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return true
+	}
+
+	a := put("aaaaaaaaaa")
+	b := put("bbbbbbbbbb")
+	c := put("cccccccccc")
+	if got := s.Size(); got != 30 {
+		t.Fatalf("Size = %d, want 30", got)
+	}
+	// Putting the same data again does not change the size.
+	put("cccccccccc")
+	if got := s.Size(); got != 30 {
+		t.Fatalf("after repeated Put, Size = %d, want 30", got)
+	}
+
+	// Use a, so b is the least recently used and is evicted by d.
+	if !present(a) {
+		t.Fatal("a missing before limit was reached")
+	}
+	d := put("dddddddddd")
+	if got := s.Size(); got != 30 {
+		t.Errorf("after eviction, Size = %d, want 30", got)
+	}
+	if present(b) {
+		t.Error("b present; should have been evicted")
+	}
+	for _, ref := range []upspin.Reference{a, c, d} {
+		if !present(ref) {
+			t.Errorf("%s missing; should not have been evicted", ref)
+		}
+	}
+
+	// Data larger than the limit evicts everything else but is kept.
+	big := put("0123456789012345678901234567890123456789")
+	if got := s.Size(); got != 40 {
+		t.Errorf("after big Put, Size = %d, want 40", got)
+	}
+	if !present(big) {
+		t.Error("big data missing")
+	}
+	for _, ref := range []upspin.Reference{a, c, d} {
+		if present(ref) {
+			t.Errorf("%s present; should have been evicted", ref)
+		}
+	}
+
+	if err := s.Delete(big); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Size(); got != 0 {
+		t.Errorf("after Delete, Size = %d, want 0", got)
+	}
+}
+
+func TestMaxBytesConcurrent(t *testing.T) {
+	const max = 1000
+	s := New(MaxBytes(max)).(*service)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				refdata, err := s.Put([]byte(fmt.Sprintf("%d-%d: some data to store", i, j)))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				_, _, _, err = s.Get(refdata.Reference)
+				if err != nil && !errors.Is(errors.NotExist, err) {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := s.Size(); got > max {
+		t.Errorf("Size = %d, exceeds limit %d", got, max)
+	}
+}
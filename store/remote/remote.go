@@ -41,7 +41,11 @@ type remote struct {
 	baseURL string
 }
 
-var _ upspin.StoreServer = (*remote)(nil)
+var (
+	_ upspin.StoreServer     = (*remote)(nil)
+	_ upspin.StoreExister    = (*remote)(nil)
+	_ upspin.StoreGetBatcher = (*remote)(nil)
+)
 
 // Get implements upspin.StoreServer.Get.
 func (r *remote) Get(ref upspin.Reference) ([]byte, *upspin.Refdata, []upspin.Location, error) {
@@ -120,6 +124,81 @@ func (r *remote) Delete(ref upspin.Reference) error {
 	return op.error(errors.UnmarshalError(resp.Error))
 }
 
+// Exists implements upspin.StoreExister.Exists.
+func (r *remote) Exists(ref upspin.Reference) (bool, error) {
+	op := r.opf("Exists", "%q", ref)
+
+	req := &proto.StoreExistsRequest{
+		Reference: string(ref),
+	}
+	resp := new(proto.StoreExistsResponse)
+	if err := r.Invoke("Store/Exists", req, resp, nil, nil); err != nil {
+		if err == upspin.ErrNotSupported {
+			return false, err
+		}
+		return false, op.error(err)
+	}
+	if len(resp.Error) != 0 {
+		return false, op.error(errors.UnmarshalError(resp.Error))
+	}
+	return resp.Exists, nil
+}
+
+// GetBatch implements upspin.StoreGetBatcher.GetBatch.
+// If the data may be fetched directly by HTTP, as it is by Get,
+// GetBatch reports ErrNotSupported.
+func (r *remote) GetBatch(refs []upspin.Reference) []upspin.StoreGetResult {
+	op := r.opf("GetBatch", "%q", refs)
+
+	fail := func(err error) []upspin.StoreGetResult {
+		results := make([]upspin.StoreGetResult, len(refs))
+		for i := range results {
+			results[i].Error = err
+		}
+		return results
+	}
+	if err := r.probeDirect(); err != nil {
+		op.error(err)
+	}
+	if r.baseURL != "" {
+		return fail(upspin.ErrNotSupported)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	req := &proto.StoreGetBatchRequest{
+		References: make([]string, len(refs)),
+	}
+	for i, ref := range refs {
+		req.References[i] = string(ref)
+	}
+	resp := new(proto.StoreGetBatchResponse)
+	err := r.Invoke("Store/GetBatch", req, resp, nil, nil)
+	if n := len(resp.Responses); err == nil && (n == 0 || n > len(refs)) {
+		err = errors.E(errors.IO, errors.Errorf("got %d responses for %d references", n, len(refs)))
+	}
+	if err != nil {
+		if err != upspin.ErrNotSupported {
+			err = op.error(err)
+		}
+		return fail(err)
+	}
+	results := make([]upspin.StoreGetResult, len(resp.Responses))
+	for i, gr := range resp.Responses {
+		if len(gr.Error) != 0 {
+			results[i].Error = op.error(errors.UnmarshalError(gr.Error))
+			continue
+		}
+		results[i] = upspin.StoreGetResult{
+			Data:      gr.Data,
+			Refdata:   proto.UpspinRefdata(gr.Refdata),
+			Locations: proto.UpspinLocations(gr.Locations),
+		}
+	}
+	return results
+}
+
 // Endpoint implements upspin.StoreServer.Endpoint.
 func (r *remote) Endpoint() upspin.Endpoint {
 	return r.cfg.endpoint
@@ -29,7 +29,10 @@ type server struct {
 	linkBase []byte
 }
 
-var _ upspin.StoreServer = (*server)(nil)
+var (
+	_ upspin.StoreServer  = (*server)(nil)
+	_ upspin.StoreExister = (*server)(nil)
+)
 
 // New returns a StoreServer that serves the given endpoint with the provided options.
 func New(options ...string) (upspin.StoreServer, error) {
@@ -148,6 +151,32 @@ func (s *server) Get(ref upspin.Reference) ([]byte, *upspin.Refdata, []upspin.Lo
 	}
 }
 
+// Exists implements upspin.StoreExister.
+// If the storage back end cannot check for a reference directly,
+// Exists downloads the data and discards it.
+func (s *server) Exists(ref upspin.Reference) (bool, error) {
+	const op errors.Op = "store/server.Exists"
+
+	m, _ := metric.NewSpan(op)
+	defer m.Done()
+
+	if ex, ok := s.storage.(storage.Exister); ok {
+		exists, err := ex.Exists(string(ref))
+		if err != nil {
+			return false, errors.E(op, err)
+		}
+		return exists, nil
+	}
+	_, err := s.storage.Download(string(ref))
+	if errors.Is(errors.NotExist, err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	return true, nil
+}
+
 // Delete implements upspin.StoreServer.
 func (s *server) Delete(ref upspin.Reference) error {
 	const op errors.Op = "store/server.Delete"
@@ -61,6 +61,35 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestExists(t *testing.T) {
+	for _, s := range []*server{
+		// Falls back to Download.
+		newStoreServer(nil),
+		// Uses storage.Exister.
+		newStoreServer(storagetest.Memory()),
+	} {
+		if _, ok := s.storage.(storage.Exister); ok {
+			if _, err := s.Put([]byte(contents)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		exists, err := s.Exists(expectedRef)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("Exists(%q) = false, want true", expectedRef)
+		}
+		exists, err = s.Exists("bla bla bla")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Errorf("Exists(%q) = true, want false", "bla bla bla")
+		}
+	}
+}
+
 // Test some error conditions.
 
 func TestGetInvalidRef(t *testing.T) {
@@ -210,8 +210,12 @@ func (s *State) GlobUpspin(pattern string) []*upspin.DirEntry {
 	if err != nil {
 		s.Exit(err)
 	}
+	_, depth, err := path.GlobPrefix(pat)
+	if err != nil {
+		s.Exit(err)
+	}
 	// If it has no metacharacters, look it up to be sure it exists.
-	if !HasGlobChar(string(pat)) {
+	if depth == 0 {
 		entry, err := s.Client.Lookup(pat, false)
 		if err != nil {
 			s.Exit(err)
@@ -238,8 +242,12 @@ func (s *State) GlobUpspinPath(pattern string) []upspin.PathName {
 	if err != nil {
 		s.Exit(err)
 	}
+	_, depth, err := path.GlobPrefix(pat)
+	if err != nil {
+		s.Exit(err)
+	}
 	// If it has no metacharacters, leave it alone but clean it.
-	if !HasGlobChar(string(pat)) {
+	if depth == 0 {
 		return []upspin.PathName{parsed.Path()}
 	}
 	entries, err := s.Client.Glob(parsed.String())
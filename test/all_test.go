@@ -17,11 +17,12 @@ import (
 	_ "upspin.io/pack/ee"
 	_ "upspin.io/pack/eeintegrity"
 	_ "upspin.io/pack/plain"
+	_ "upspin.io/pack/plainsum"
 	_ "upspin.io/store/inprocess"
 )
 
 func TestClientFile(t *testing.T) {
-	for _, p := range []upspin.Packing{upspin.PlainPack, upspin.EEIntegrityPack, upspin.EEPack} {
+	for _, p := range []upspin.Packing{upspin.PlainPack, upspin.PlainSumPack, upspin.EEIntegrityPack, upspin.EEPack} {
 		t.Run(fmt.Sprintf("packing=%v", p), func(t *testing.T) {
 			env := newEnv(t, p)
 			defer env.Exit()
@@ -13,6 +13,7 @@ import (
 
 	"upspin.io/bind"
 	"upspin.io/dir/inprocess"
+	"upspin.io/rpc"
 	"upspin.io/upspin"
 
 	_ "upspin.io/key/transports"
@@ -41,3 +42,13 @@ func Init(cfg upspin.Config) {
 		})
 	}
 }
+
+// InitWithOptions is like Init but also sets options, such as dial
+// timeouts and a pool of TLS certificate authorities, for every
+// connection subsequently made to a remote server. Options set by a
+// previous call are replaced. Since connections to servers are cached
+// and reused, it should be called before any server is contacted.
+func InitWithOptions(cfg upspin.Config, opts ...rpc.DialOpts) {
+	rpc.SetDefaultDialOpts(opts...)
+	Init(cfg)
+}
@@ -15,6 +15,15 @@ Schema files must be in YAML format, of this general form:
 	  dirserver: dir.upspin.io
 	  packing: ee
 	  cache: true
+	  files:
+	  - name: Group/friends
+	    contents: joe@example.com
+	  - name: Access
+	    contents: |
+	      r: friends
+	      *: jess@example.net
+	  - name: notes/todo
+	    source: /path/to/todo.txt
 	servers:
 	- name: storeserver
 	- name: dirserver
@@ -48,6 +57,19 @@ If empty, it defaults to "ee".
 
 Cache is a boolean that specifies whether to start a cacheserver for this user.
 
+Files lists files to be written into the user's tree, as the user, once the
+servers have started. The user's root is created first if need be. Each file's
+Name is a path relative to the root; any missing directories along it are
+created. The file holds the text of Contents or, if Source is set, the
+contents of that local file. Access files are written after all others,
+and the user's files are then shared with the readers they name; the Access
+files must therefore leave the user the right to write them.
+A file that already exists, as it may when resuming a session, is left as is.
+These files make it possible to set up sharing scenarios, with their Access
+and Group files, from a single schema. The program that starts the schema
+writes them, so it must link in the remote transports and the packers the
+users need, as command upbox does.
+
 Servers
 
 Name specifies a short name for this server. It must be non-empty.
@@ -102,8 +124,12 @@ the Start method and removed by the Stop method.
 
 If the "dir" property is set, upbox will use that path to store its data and
 will not clean up inside Stop. Upon a restart, upbox will use whatever it finds,
-filling in any missing gaps in regards to the schema. If persistent storage
-(eg. 'disk') is used, one can resume a previously started session.
+filling in any missing gaps in regards to the schema. In particular, users
+whose keys are already present keep them, and servers listen on the same
+addresses as before. Unless their "kind" flag says otherwise, the servers
+named "storeserver" and "dirserver" are then of kind 'server', keeping their
+blocks and logs beneath the directory, so that a later run resumes the
+previous session with its tree intact.
 An example schema for a resumable session:
 
 	dir: /tmp/upbox
@@ -111,11 +137,7 @@ An example schema for a resumable session:
 	- name: john
 	servers:
 	- name: storeserver
-	  flags:
-	    kind: server
 	- name: dirserver
-	  flags:
-	    kind: server
 	- name: keyserver
 	domain: local.host
 
@@ -139,7 +161,11 @@ import (
 	"sync"
 	"time"
 
+	"upspin.io/access"
+	"upspin.io/client"
 	"upspin.io/config"
+	"upspin.io/factotum"
+	"upspin.io/key/keygen"
 	"upspin.io/log"
 	"upspin.io/rpc/local"
 	"upspin.io/test/testutil"
@@ -153,10 +179,10 @@ type Schema struct {
 	Users   []*User
 	Servers []*Server
 
-	// Dir specifies the directory in which to store the config files and keys.
-	// Any data that may already be present there will be reused. This allows
-	// restoring previous sessions. If unspecified, a temporary directory
-	// will be used.
+	// Dir specifies the directory in which to store the config files, keys,
+	// and server data. Any data that may already be present there will be
+	// reused. This allows restoring previous sessions. If unspecified,
+	// a temporary directory will be used.
 	Dir string
 
 	// Domain specifies the default domain of any user names that do not
@@ -202,11 +228,27 @@ type User struct {
 	// Cache specifies whether to run a cacheserver for this user.
 	Cache bool
 
+	// Files specifies files to write into this user's tree on Start.
+	Files []*File
+
 	secrets string // path to user's public and private keys; set by Run
 
 	cacheserver *exec.Cmd
 }
 
+// File defines a file to be written into a user's tree.
+type File struct {
+	// Name specifies the path of the file relative to the user's root.
+	Name string
+
+	// Contents specifies the contents of the file.
+	Contents string
+
+	// Source specifies a local file holding the contents of the file.
+	// If set, Contents must be empty.
+	Source string
+}
+
 // Server defines an Upspin server to be created and used within a schema.
 type Server struct {
 	// Name specifies a short name for this server.
@@ -293,6 +335,15 @@ func SchemaFromYAML(doc string) (*Schema, error) {
 		if u.Packing == "" {
 			u.Packing = "ee"
 		}
+		for j, f := range u.Files {
+			f.Name = strings.Trim(f.Name, "/")
+			if f.Name == "" {
+				return nil, fmt.Errorf("user %q: file[%d] must specify a name", u.Name, j)
+			}
+			if f.Source != "" && f.Contents != "" {
+				return nil, fmt.Errorf("user %q: file %q specifies both contents and source", u.Name, f.Name)
+			}
+		}
 
 		// Add to map only after name has been normalized.
 		sc.user[u.Name] = u
@@ -517,20 +568,19 @@ func (sc *Schema) Start() error {
 	for _, u := range sc.Users {
 		dir := filepath.Join(sc.Dir, u.Name)
 		u.secrets = dir
-		if pathExists(dir) {
-			// The keys are already there from a pervious install.
+		if pathExists(filepath.Join(dir, "public.upspinkey")) {
+			// The keys are already there from a previous session.
 			log.Debug.Printf("found keys: %s", dir)
 			continue
 		}
 		if err := os.MkdirAll(dir, 0700); err != nil {
 			return err
 		}
-		var buf bytes.Buffer
-		keygen := exec.Command(sc.Command("upspin"), "keygen", dir)
-		keygen.Stdout = prefix("keygen: ", &buf)
-		keygen.Stderr = prefix("keygen: ", &buf)
-		if err := keygen.Run(); err != nil {
-			fmt.Fprintf(os.Stderr, "%s", buf.Bytes())
+		_, public, private, err := factotum.Generate("p256")
+		if err != nil {
+			return err
+		}
+		if err := keygen.SaveKeys(dir, false, string(public), private, ""); err != nil {
 			return err
 		}
 	}
@@ -644,6 +694,13 @@ func (sc *Schema) Start() error {
 		}
 	}
 
+	// Write the users' files.
+	for _, u := range sc.Users {
+		if err := sc.writeFiles(u); err != nil {
+			return fmt.Errorf("writing files for %v: %v", u.Name, err)
+		}
+	}
+
 	if err := sc.session.toDir(sc.Dir); err != nil {
 		return err
 	}
@@ -727,6 +784,87 @@ func (sc *Schema) writeConfig(user string) error {
 	return os.WriteFile(filename, []byte(strings.Join(cfg, "\n")), 0644)
 }
 
+// writeFiles writes the user's Files into its tree, as the user.
+func (sc *Schema) writeFiles(u *User) error {
+	if len(u.Files) == 0 {
+		return nil
+	}
+	cfg, err := config.FromFile(sc.Config(u.Name))
+	if err != nil {
+		return err
+	}
+	c := client.New(cfg)
+	// exists reports whether name is present in the tree.
+	exists := func(name upspin.PathName) bool {
+		_, err := c.Lookup(name, false)
+		return err == nil
+	}
+	// mkdirs creates, if missing, dir and the directories above it.
+	var mkdirs func(dir upspin.PathName) error
+	mkdirs = func(dir upspin.PathName) error {
+		if exists(dir) {
+			return nil
+		}
+		if i := strings.LastIndex(strings.TrimSuffix(string(dir), "/"), "/"); i > 0 {
+			if err := mkdirs(dir[:i]); err != nil {
+				return err
+			}
+		}
+		_, err := c.MakeDirectory(dir)
+		return err
+	}
+	if err := mkdirs(upspin.PathName(u.Name + "/")); err != nil {
+		return err
+	}
+
+	// Write Access files last, so they cannot lock the user
+	// out of writing the others.
+	var files, accessFiles []*File
+	for _, f := range u.Files {
+		if path.Base(f.Name) == access.AccessFile {
+			accessFiles = append(accessFiles, f)
+		} else {
+			files = append(files, f)
+		}
+	}
+	for _, f := range append(files, accessFiles...) {
+		name := upspin.PathName(u.Name + "/" + f.Name)
+		if exists(name) {
+			log.Debug.Printf("found file: %s", name)
+			continue
+		}
+		data := []byte(f.Contents)
+		if f.Source != "" {
+			data, err = os.ReadFile(f.Source)
+			if err != nil {
+				return err
+			}
+		}
+		if dir := path.Dir(f.Name); dir != "." {
+			if err := mkdirs(upspin.PathName(u.Name + "/" + dir)); err != nil {
+				return err
+			}
+		}
+		if _, err := c.Put(name, data); err != nil {
+			return err
+		}
+	}
+	if len(accessFiles) == 0 {
+		return nil
+	}
+
+	// The files were packed before the Access files granted anyone
+	// else the right to read them, so share them now.
+	cmd := exec.Command(sc.Command("upspin"),
+		"-config="+sc.Config(u.Name),
+		"-log="+sc.logLevel(),
+		"share", "-fix", "-q", "-r", u.Name+"/",
+	)
+	cmd.Stdout = prefix("share:\t", os.Stdout)
+	cmd.Stderr = prefix("share:\t", os.Stderr)
+	return cmd.Run()
+}
+
 func (u *User) cacheAddr() string {
 	return config.LocalName(
 		config.SetUserName(config.New(), upspin.UserName(u.Name)),
@@ -751,8 +889,17 @@ func (sc *Schema) startServer(s *Server) (*exec.Cmd, error) {
 			"-test_secrets="+filepath.Join(sc.Dir, s.User),
 		)
 	}
-	_, hasServerConfigFlag := s.Flags["serverconfig"]
-	for k, v := range s.Flags {
+	flags := s.Flags
+	if _, hasKindFlag := flags["kind"]; !hasKindFlag && !sc.cleanup && (s.Name == "dirserver" || s.Name == "storeserver") {
+		// The schema's state is kept across sessions,
+		// so the server's data should be too.
+		flags = map[string]string{"kind": "server"}
+		for k, v := range s.Flags {
+			flags[k] = v
+		}
+	}
+	_, hasServerConfigFlag := flags["serverconfig"]
+	for k, v := range flags {
 		args = append(args, fmt.Sprintf("-%s=%v", k, v))
 		if !hasServerConfigFlag && k == "kind" && v == "server" {
 			switch s.Name {
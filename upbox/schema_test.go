@@ -0,0 +1,97 @@
+// Copyright 2017 The Upspin Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package upbox
+
+import (
+	"testing"
+
+	"upspin.io/client"
+	"upspin.io/config"
+	"upspin.io/errors"
+	"upspin.io/upspin"
+
+	_ "upspin.io/dir/remote"
+	_ "upspin.io/key/remote"
+	_ "upspin.io/pack/ee"
+	_ "upspin.io/store/remote"
+)
+
+const filesSchema = `
+users:
+- name: ann
+  files:
+  - name: Group/friends
+    contents: bob@example.com
+  - name: Access
+    contents: |
+      r: friends
+      *: ann@example.com
+  - name: notes/todo
+    contents: buy milk
+- name: bob
+- name: cal
+servers:
+- name: keyserver
+- name: storeserver
+- name: dirserver
+domain: example.com
+`
+
+func TestFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that builds and runs servers in short mode")
+	}
+	sc, err := SchemaFromYAML(filesSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Stop()
+
+	clientFor := func(user string) upspin.Client {
+		cfg, err := config.FromFile(sc.Config(user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.New(cfg)
+	}
+	ann := clientFor("ann@example.com")
+	bob := clientFor("bob@example.com")
+	cal := clientFor("cal@example.com")
+
+	for _, f := range []struct {
+		name     upspin.PathName
+		contents string
+	}{
+		{"ann@example.com/Group/friends", "bob@example.com"},
+		{"ann@example.com/Access", "r: friends\n*: ann@example.com\n"},
+		{"ann@example.com/notes/todo", "buy milk"},
+	} {
+		got, err := ann.Get(f.name)
+		if err != nil {
+			t.Errorf("ann: Get(%s): %v", f.name, err)
+			continue
+		}
+		if string(got) != f.contents {
+			t.Errorf("ann: Get(%s) = %q, want %q", f.name, got, f.contents)
+		}
+	}
+
+	// The Access and Group files let bob, a friend, read but not write.
+	const todo = "ann@example.com/notes/todo"
+	if got, err := bob.Get(todo); err != nil || string(got) != "buy milk" {
+		t.Errorf("bob: Get(%s) = %q, %v; want %q", todo, got, err, "buy milk")
+	}
+	if _, err := bob.Put(todo, []byte("buy beer")); !errors.Is(errors.Permission, err) {
+		t.Errorf("bob: Put(%s): err = %v, want Permission", todo, err)
+	}
+
+	// Cal, who is not a friend, may not read.
+	if _, err := cal.Get(todo); !errors.Is(errors.Private, err) {
+		t.Errorf("cal: Get(%s): err = %v, want Private", todo, err)
+	}
+}
@@ -21,6 +21,11 @@ const (
 	// DirBlocks in a DirEntry. It should be high enough that the limit
 	// will never be reached in practice. 1 million seems okay.
 	maxDirBlocks = 1000000
+
+	// minDirBlockLen is the length of the shortest marshaled DirBlock:
+	// one byte for the transport and one for each of the five varints
+	// that follow it.
+	minDirBlockLen = 6
 )
 
 // This code is very careful not to grow a buffer of length more than a 32-bit
@@ -132,7 +137,8 @@ func (c *consumer) bytes() []byte {
 	u, n := binary.Varint(c.buf)
 	// If n <= 0, Varint returned an error. Otherwise we know n <= len(b).
 	// We also test that u is good and u bytes remain in the buffer after the count.
-	if n <= 0 || u < 0 || len(c.buf[n:]) < int(u) {
+	// The comparisons are done in uint64 so a huge count cannot overflow an int.
+	if n <= 0 || u < 0 {
 		c.err = ErrTooShort
 		return c.buf[:0]
 	}
@@ -140,6 +146,10 @@ func (c *consumer) bytes() []byte {
 		c.err = ErrTooLarge
 		return c.buf[:0]
 	}
+	if uint64(len(c.buf[n:])) < uint64(u) {
+		c.err = ErrTooShort
+		return c.buf[:0]
+	}
 	c.buf = c.buf[n:]
 	data := c.buf[:u]
 	c.buf = c.buf[u:]
@@ -155,10 +165,14 @@ func (c *consumer) nBytes(n int) []byte {
 		c.err = ErrTooShort
 		return c.buf[:0]
 	}
-	if len(c.buf) < n || maxInt32 < uint64(n) {
+	if maxInt32 < uint64(n) {
 		c.err = ErrTooLarge
 		return c.buf[:0]
 	}
+	if len(c.buf) < n {
+		c.err = ErrTooShort
+		return c.buf[:0]
+	}
 	data := c.buf[:n]
 	c.buf = c.buf[n:]
 	return data
@@ -247,6 +261,9 @@ func (cons *consumer) DirBlock(d *DirBlock) ([]byte, error) {
 
 	d.Offset = cons.int64()
 	d.Size = cons.int64()
+	if d.Size > MaxBlockSize && cons.err == nil {
+		cons.err = ErrTooLarge
+	}
 
 	// Packdata.
 	if bytes = cons.bytes(); len(bytes) > 0 {
@@ -357,6 +374,9 @@ func (d *DirEntry) Unmarshal(b []byte) ([]byte, error) {
 		return nil, fmt.Errorf("block count out of range (max %d): %d", maxDirBlocks, nBlocks)
 	case nBlocks < 0:
 		return nil, fmt.Errorf("negative block count: %d", nBlocks)
+	case nBlocks > int64(len(cons.buf)/minDirBlockLen):
+		// Don't allocate blocks that cannot possibly be present.
+		return nil, ErrTooShort
 	case nBlocks > 0:
 		d.Blocks = make([]DirBlock, nBlocks)
 		for i := range d.Blocks {
@@ -406,7 +426,7 @@ func (d *DirEntry) Unmarshal(b []byte) ([]byte, error) {
 // in the format similar to RFC 3339: "2006-01-02T15:04:05 UTC"
 // The time zone is always UTC.
 func (t Time) String() string {
-	return t.Go().Format("2006-01-02T15:04:05 UTC")
+	return t.Go().Format(timeFormat)
 }
 
 // Go returns the Go Time value representation of an Upspin time.
@@ -420,6 +440,24 @@ func TimeFromGo(t time.Time) Time {
 	return Time(t.Unix())
 }
 
+// timeFormat is the layout used by Time.String.
+const timeFormat = "2006-01-02T15:04:05 UTC"
+
+// ParseTime parses a time in RFC 3339 format, such as
+// "2006-01-02T15:04:05Z" or "2006-01-02T15:04:05-07:00", or in the format
+// produced by Time.String. Fractions of a second are discarded.
+func ParseTime(s string) (Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		var err2 error
+		t, err2 = time.Parse(timeFormat, s)
+		if err2 != nil {
+			return 0, err
+		}
+	}
+	return TimeFromGo(t), nil
+}
+
 // Now returns the current Upspin Time.
 func Now() Time {
 	return TimeFromGo(time.Now())
@@ -480,6 +518,8 @@ func (p Packing) String() string {
 	switch p {
 	case PlainPack:
 		return "plain"
+	case PlainSumPack:
+		return "plainsum"
 	case EEPack:
 		return "ee"
 	case EEIntegrityPack:
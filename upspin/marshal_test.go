@@ -5,6 +5,7 @@
 package upspin
 
 import (
+	"encoding/binary"
 	"fmt"
 	"reflect"
 	"strings"
@@ -213,6 +214,84 @@ func TestDirEntryUnmarshalCrashers(t *testing.T) {
 	}
 }
 
+// varints returns the concatenated varint encodings of the values.
+func varints(values ...int64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.AppendVarint(b, v)
+	}
+	return b
+}
+
+func TestDirEntryUnmarshalBadInput(t *testing.T) {
+	good, err := dirEnt.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SignedName, Packing, Time and the start of a block count.
+	header := append(varints(1), 'x', byte(EEPack))
+	header = append(header, varints(123456)...)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrTooShort},
+		{"short name", append(varints(10), "abc"...), ErrTooShort},
+		{"huge name", append(varints(1<<40), "abc"...), ErrTooLarge},
+		{"largest name", append(varints(1<<63-1), "abc"...), ErrTooLarge},
+		{"too many blocks", append(header, varints(maxDirBlocks)...), ErrTooShort},
+		{"block with huge reference", append(append(header, varints(1)...), append([]byte{byte(Remote)}, varints(0, 1<<50)...)...), ErrTooLarge},
+		{"huge name after signed name", append(append(header, varints(0)...), append(varints(0, 0, 0), varints(MaxBlockSize+1)...)...), ErrTooLarge},
+		{"short name after signed name", append(append(header, varints(0)...), append(varints(0, 0, 0), varints(100)...)...), ErrTooShort},
+	}
+	for i := range good {
+		tests = append(tests, struct {
+			name string
+			data []byte
+			want error
+		}{fmt.Sprintf("truncated to %d bytes", i), good[:i], ErrTooShort})
+	}
+	for _, test := range tests {
+		var de DirEntry
+		_, err := de.Unmarshal(test.data)
+		if err != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestDirBlockUnmarshalBadInput(t *testing.T) {
+	good, err := dirBlock1.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"huge address", append([]byte{byte(Remote)}, varints(1<<40)...), ErrTooLarge},
+		{"short address", append([]byte{byte(Remote)}, varints(5, 'a')...), ErrTooShort},
+		{"block twice the maximum size", append([]byte{byte(Remote)}, varints(0, 0, 0, 2*MaxBlockSize, 0)...), ErrTooLarge},
+		{"negative packdata length", append([]byte{byte(Remote)}, varints(0, 0, 0, 0, -2)...), ErrTooShort},
+	}
+	for i := range good {
+		tests = append(tests, struct {
+			name string
+			data []byte
+			want error
+		}{fmt.Sprintf("truncated to %d bytes", i), good[:i], ErrTooShort})
+	}
+	for _, test := range tests {
+		var db DirBlock
+		_, err := db.Unmarshal(test.data)
+		if err != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
 // Tests of the buffer overflow code. These know about the structure of
 // the code, but otherwise we'd be playing with 2GB buffers.
 
@@ -396,3 +475,38 @@ func TestUnmarshalBigDirEntry(t *testing.T) {
 		t.Errorf("Unmarshal got %v; want ErrTooLarge", err)
 	}
 }
+
+func TestDirEntrySize(t *testing.T) {
+	blocks := func(sizes ...int64) []DirBlock {
+		var b []DirBlock
+		var offset int64
+		for _, size := range sizes {
+			b = append(b, DirBlock{Offset: offset, Size: size})
+			offset += size
+		}
+		return b
+	}
+	tests := []struct {
+		name   string
+		blocks []DirBlock
+		size   int64
+		ok     bool
+	}{
+		{"nil blocks", nil, 0, true},
+		{"empty blocks", []DirBlock{}, 0, true},
+		{"one block", blocks(100), 100, true},
+		{"several blocks", blocks(BlockSize, BlockSize, 17), 2*BlockSize + 17, true},
+		{"gap", []DirBlock{{Offset: 0, Size: 10}, {Offset: 20, Size: 10}}, 20, false},
+		{"negative size", []DirBlock{{Offset: 0, Size: 10}, {Offset: 10, Size: -1}}, 0, false},
+	}
+	for _, test := range tests {
+		d := &DirEntry{Name: "u@x.com/file", Blocks: test.blocks}
+		size, err := d.Size()
+		if size != test.size {
+			t.Errorf("%s: Size() = %d, want %d", test.name, size, test.size)
+		}
+		if (err == nil) != test.ok {
+			t.Errorf("%s: Size() error = %v, want error: %t", test.name, err, !test.ok)
+		}
+	}
+}
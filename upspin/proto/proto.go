@@ -24,6 +24,7 @@
 package proto // import "upspin.io/upspin/proto"
 
 import (
+	"math/big"
 	"time"
 
 	"upspin.io/errors"
@@ -176,6 +177,53 @@ func UserProto(user *upspin.User) *User {
 	}
 }
 
+// SignatureBytes returns the two halves of a signature as byte slices,
+// which are nil for a zero Signature.
+func SignatureBytes(sig upspin.Signature) (r, s []byte) {
+	if sig.R != nil {
+		r = sig.R.Bytes()
+	}
+	if sig.S != nil {
+		s = sig.S.Bytes()
+	}
+	return r, s
+}
+
+// UpspinSignature converts the byte slices returned by SignatureBytes
+// to an upspin.Signature.
+func UpspinSignature(r, s []byte) upspin.Signature {
+	var sig upspin.Signature
+	if len(r) > 0 {
+		sig.R = new(big.Int).SetBytes(r)
+	}
+	if len(s) > 0 {
+		sig.S = new(big.Int).SetBytes(s)
+	}
+	return sig
+}
+
+// KeyRotationProto converts an upspin.KeyRotation to a proto.KeyRotation.
+func KeyRotationProto(r *upspin.KeyRotation) *KeyRotation {
+	sigR, sigS := SignatureBytes(r.Signature)
+	return &KeyRotation{
+		Previous:   string(r.Previous),
+		PublicKey:  string(r.PublicKey),
+		SignatureR: sigR,
+		SignatureS: sigS,
+		Time:       int64(r.Time),
+	}
+}
+
+// UpspinKeyRotation converts a proto.KeyRotation to an upspin.KeyRotation.
+func UpspinKeyRotation(r *KeyRotation) upspin.KeyRotation {
+	return upspin.KeyRotation{
+		Previous:  upspin.PublicKey(r.Previous),
+		PublicKey: upspin.PublicKey(r.PublicKey),
+		Signature: UpspinSignature(r.SignatureR, r.SignatureS),
+		Time:      upspin.Time(r.Time),
+	}
+}
+
 // RefdataProto converts an upspin.Refdata to a proto.Refdata.
 func RefdataProto(refdata *upspin.Refdata) *Refdata {
 	if refdata == nil {
@@ -19,11 +19,22 @@ It has these top-level messages:
 	StorePutResponse
 	StoreDeleteRequest
 	StoreDeleteResponse
+	StoreExistsRequest
+	StoreExistsResponse
+	StoreGetBatchRequest
+	StoreGetBatchResponse
 	User
 	KeyLookupRequest
 	KeyLookupResponse
 	KeyPutRequest
 	KeyPutResponse
+	KeyLookupBatchRequest
+	KeyLookupBatchResponse
+	KeyRotateRequest
+	KeyRotation
+	KeyHistoryResponse
+	KeyWatchRequest
+	KeyEvent
 	EntryError
 	EntriesError
 	DirLookupRequest
@@ -31,6 +42,7 @@ It has these top-level messages:
 	DirGlobRequest
 	DirDeleteRequest
 	DirWhichAccessRequest
+	DirPutAllRequest
 	DirWatchRequest
 	Event
 */
@@ -286,6 +298,80 @@ func (m *StoreDeleteResponse) GetError() []byte {
 	return nil
 }
 
+type StoreExistsRequest struct {
+	Reference string `protobuf:"bytes,1,opt,name=reference" json:"reference,omitempty"`
+}
+
+func (m *StoreExistsRequest) Reset()                    { *m = StoreExistsRequest{} }
+func (m *StoreExistsRequest) String() string            { return proto1.CompactTextString(m) }
+func (*StoreExistsRequest) ProtoMessage()               {}
+func (*StoreExistsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *StoreExistsRequest) GetReference() string {
+	if m != nil {
+		return m.Reference
+	}
+	return ""
+}
+
+type StoreExistsResponse struct {
+	Exists bool   `protobuf:"varint,1,opt,name=exists" json:"exists,omitempty"`
+	Error  []byte `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *StoreExistsResponse) Reset()                    { *m = StoreExistsResponse{} }
+func (m *StoreExistsResponse) String() string            { return proto1.CompactTextString(m) }
+func (*StoreExistsResponse) ProtoMessage()               {}
+func (*StoreExistsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *StoreExistsResponse) GetExists() bool {
+	if m != nil {
+		return m.Exists
+	}
+	return false
+}
+
+func (m *StoreExistsResponse) GetError() []byte {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type StoreGetBatchRequest struct {
+	References []string `protobuf:"bytes,1,rep,name=references" json:"references,omitempty"`
+}
+
+func (m *StoreGetBatchRequest) Reset()                    { *m = StoreGetBatchRequest{} }
+func (m *StoreGetBatchRequest) String() string            { return proto1.CompactTextString(m) }
+func (*StoreGetBatchRequest) ProtoMessage()               {}
+func (*StoreGetBatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *StoreGetBatchRequest) GetReferences() []string {
+	if m != nil {
+		return m.References
+	}
+	return nil
+}
+
+// The responses are in the same order as the references in the request.
+// There may be fewer responses than references; see upspin.StoreGetBatcher.
+type StoreGetBatchResponse struct {
+	Responses []*StoreGetResponse `protobuf:"bytes,1,rep,name=responses" json:"responses,omitempty"`
+}
+
+func (m *StoreGetBatchResponse) Reset()                    { *m = StoreGetBatchResponse{} }
+func (m *StoreGetBatchResponse) String() string            { return proto1.CompactTextString(m) }
+func (*StoreGetBatchResponse) ProtoMessage()               {}
+func (*StoreGetBatchResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *StoreGetBatchResponse) GetResponses() []*StoreGetResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
 type User struct {
 	Name      string      `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Dirs      []*Endpoint `protobuf:"bytes,2,rep,name=dirs" json:"dirs,omitempty"`
@@ -296,7 +382,7 @@ type User struct {
 func (m *User) Reset()                    { *m = User{} }
 func (m *User) String() string            { return proto1.CompactTextString(m) }
 func (*User) ProtoMessage()               {}
-func (*User) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*User) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 func (m *User) GetName() string {
 	if m != nil {
@@ -333,7 +419,7 @@ type KeyLookupRequest struct {
 func (m *KeyLookupRequest) Reset()                    { *m = KeyLookupRequest{} }
 func (m *KeyLookupRequest) String() string            { return proto1.CompactTextString(m) }
 func (*KeyLookupRequest) ProtoMessage()               {}
-func (*KeyLookupRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (*KeyLookupRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 func (m *KeyLookupRequest) GetUserName() string {
 	if m != nil {
@@ -350,7 +436,7 @@ type KeyLookupResponse struct {
 func (m *KeyLookupResponse) Reset()                    { *m = KeyLookupResponse{} }
 func (m *KeyLookupResponse) String() string            { return proto1.CompactTextString(m) }
 func (*KeyLookupResponse) ProtoMessage()               {}
-func (*KeyLookupResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+func (*KeyLookupResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
 
 func (m *KeyLookupResponse) GetUser() *User {
 	if m != nil {
@@ -373,7 +459,7 @@ type KeyPutRequest struct {
 func (m *KeyPutRequest) Reset()                    { *m = KeyPutRequest{} }
 func (m *KeyPutRequest) String() string            { return proto1.CompactTextString(m) }
 func (*KeyPutRequest) ProtoMessage()               {}
-func (*KeyPutRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+func (*KeyPutRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 func (m *KeyPutRequest) GetUser() *User {
 	if m != nil {
@@ -389,7 +475,7 @@ type KeyPutResponse struct {
 func (m *KeyPutResponse) Reset()                    { *m = KeyPutResponse{} }
 func (m *KeyPutResponse) String() string            { return proto1.CompactTextString(m) }
 func (*KeyPutResponse) ProtoMessage()               {}
-func (*KeyPutResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+func (*KeyPutResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 func (m *KeyPutResponse) GetError() []byte {
 	if m != nil {
@@ -398,6 +484,197 @@ func (m *KeyPutResponse) GetError() []byte {
 	return nil
 }
 
+type KeyLookupBatchRequest struct {
+	UserNames []string `protobuf:"bytes,1,rep,name=user_names,json=userNames" json:"user_names,omitempty"`
+}
+
+func (m *KeyLookupBatchRequest) Reset()                    { *m = KeyLookupBatchRequest{} }
+func (m *KeyLookupBatchRequest) String() string            { return proto1.CompactTextString(m) }
+func (*KeyLookupBatchRequest) ProtoMessage()               {}
+func (*KeyLookupBatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *KeyLookupBatchRequest) GetUserNames() []string {
+	if m != nil {
+		return m.UserNames
+	}
+	return nil
+}
+
+// The responses are in the same order as the user names in the request.
+type KeyLookupBatchResponse struct {
+	Responses []*KeyLookupResponse `protobuf:"bytes,1,rep,name=responses" json:"responses,omitempty"`
+}
+
+func (m *KeyLookupBatchResponse) Reset()                    { *m = KeyLookupBatchResponse{} }
+func (m *KeyLookupBatchResponse) String() string            { return proto1.CompactTextString(m) }
+func (*KeyLookupBatchResponse) ProtoMessage()               {}
+func (*KeyLookupBatchResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *KeyLookupBatchResponse) GetResponses() []*KeyLookupResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
+// The signature is made with the user's current key; see upspin.KeyRotator.
+// The response is a KeyPutResponse.
+type KeyRotateRequest struct {
+	User       *User  `protobuf:"bytes,1,opt,name=user" json:"user,omitempty"`
+	SignatureR []byte `protobuf:"bytes,2,opt,name=signature_r,json=signatureR,proto3" json:"signature_r,omitempty"`
+	SignatureS []byte `protobuf:"bytes,3,opt,name=signature_s,json=signatureS,proto3" json:"signature_s,omitempty"`
+}
+
+func (m *KeyRotateRequest) Reset()                    { *m = KeyRotateRequest{} }
+func (m *KeyRotateRequest) String() string            { return proto1.CompactTextString(m) }
+func (*KeyRotateRequest) ProtoMessage()               {}
+func (*KeyRotateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *KeyRotateRequest) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *KeyRotateRequest) GetSignatureR() []byte {
+	if m != nil {
+		return m.SignatureR
+	}
+	return nil
+}
+
+func (m *KeyRotateRequest) GetSignatureS() []byte {
+	if m != nil {
+		return m.SignatureS
+	}
+	return nil
+}
+
+type KeyRotation struct {
+	Previous   string `protobuf:"bytes,1,opt,name=previous" json:"previous,omitempty"`
+	PublicKey  string `protobuf:"bytes,2,opt,name=public_key,json=publicKey" json:"public_key,omitempty"`
+	SignatureR []byte `protobuf:"bytes,3,opt,name=signature_r,json=signatureR,proto3" json:"signature_r,omitempty"`
+	SignatureS []byte `protobuf:"bytes,4,opt,name=signature_s,json=signatureS,proto3" json:"signature_s,omitempty"`
+	Time       int64  `protobuf:"varint,5,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *KeyRotation) Reset()                    { *m = KeyRotation{} }
+func (m *KeyRotation) String() string            { return proto1.CompactTextString(m) }
+func (*KeyRotation) ProtoMessage()               {}
+func (*KeyRotation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *KeyRotation) GetPrevious() string {
+	if m != nil {
+		return m.Previous
+	}
+	return ""
+}
+
+func (m *KeyRotation) GetPublicKey() string {
+	if m != nil {
+		return m.PublicKey
+	}
+	return ""
+}
+
+func (m *KeyRotation) GetSignatureR() []byte {
+	if m != nil {
+		return m.SignatureR
+	}
+	return nil
+}
+
+func (m *KeyRotation) GetSignatureS() []byte {
+	if m != nil {
+		return m.SignatureS
+	}
+	return nil
+}
+
+func (m *KeyRotation) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+// The request is a KeyLookupRequest.
+type KeyHistoryResponse struct {
+	Rotations []*KeyRotation `protobuf:"bytes,1,rep,name=rotations" json:"rotations,omitempty"`
+	Error     []byte         `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *KeyHistoryResponse) Reset()                    { *m = KeyHistoryResponse{} }
+func (m *KeyHistoryResponse) String() string            { return proto1.CompactTextString(m) }
+func (*KeyHistoryResponse) ProtoMessage()               {}
+func (*KeyHistoryResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *KeyHistoryResponse) GetRotations() []*KeyRotation {
+	if m != nil {
+		return m.Rotations
+	}
+	return nil
+}
+
+func (m *KeyHistoryResponse) GetError() []byte {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type KeyWatchRequest struct {
+	Sequence int64 `protobuf:"varint,1,opt,name=sequence" json:"sequence,omitempty"`
+}
+
+func (m *KeyWatchRequest) Reset()                    { *m = KeyWatchRequest{} }
+func (m *KeyWatchRequest) String() string            { return proto1.CompactTextString(m) }
+func (*KeyWatchRequest) ProtoMessage()               {}
+func (*KeyWatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *KeyWatchRequest) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+// Each response in the stream returned by Watch holds one user record.
+// If an error occurs while waiting for records, the last response holds
+// only the error.
+type KeyEvent struct {
+	User     *User  `protobuf:"bytes,1,opt,name=user" json:"user,omitempty"`
+	Sequence int64  `protobuf:"varint,2,opt,name=sequence" json:"sequence,omitempty"`
+	Error    []byte `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *KeyEvent) Reset()                    { *m = KeyEvent{} }
+func (m *KeyEvent) String() string            { return proto1.CompactTextString(m) }
+func (*KeyEvent) ProtoMessage()               {}
+func (*KeyEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *KeyEvent) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *KeyEvent) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *KeyEvent) GetError() []byte {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
 type EntryError struct {
 	Entry []byte `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
 	Error []byte `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
@@ -406,7 +683,7 @@ type EntryError struct {
 func (m *EntryError) Reset()                    { *m = EntryError{} }
 func (m *EntryError) String() string            { return proto1.CompactTextString(m) }
 func (*EntryError) ProtoMessage()               {}
-func (*EntryError) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+func (*EntryError) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
 
 func (m *EntryError) GetEntry() []byte {
 	if m != nil {
@@ -430,7 +707,7 @@ type EntriesError struct {
 func (m *EntriesError) Reset()                    { *m = EntriesError{} }
 func (m *EntriesError) String() string            { return proto1.CompactTextString(m) }
 func (*EntriesError) ProtoMessage()               {}
-func (*EntriesError) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+func (*EntriesError) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
 
 func (m *EntriesError) GetEntries() [][]byte {
 	if m != nil {
@@ -453,7 +730,7 @@ type DirLookupRequest struct {
 func (m *DirLookupRequest) Reset()                    { *m = DirLookupRequest{} }
 func (m *DirLookupRequest) String() string            { return proto1.CompactTextString(m) }
 func (*DirLookupRequest) ProtoMessage()               {}
-func (*DirLookupRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+func (*DirLookupRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
 
 func (m *DirLookupRequest) GetName() string {
 	if m != nil {
@@ -469,7 +746,7 @@ type DirPutRequest struct {
 func (m *DirPutRequest) Reset()                    { *m = DirPutRequest{} }
 func (m *DirPutRequest) String() string            { return proto1.CompactTextString(m) }
 func (*DirPutRequest) ProtoMessage()               {}
-func (*DirPutRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+func (*DirPutRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
 
 func (m *DirPutRequest) GetEntry() []byte {
 	if m != nil {
@@ -485,7 +762,7 @@ type DirGlobRequest struct {
 func (m *DirGlobRequest) Reset()                    { *m = DirGlobRequest{} }
 func (m *DirGlobRequest) String() string            { return proto1.CompactTextString(m) }
 func (*DirGlobRequest) ProtoMessage()               {}
-func (*DirGlobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+func (*DirGlobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
 
 func (m *DirGlobRequest) GetPattern() string {
 	if m != nil {
@@ -501,7 +778,7 @@ type DirDeleteRequest struct {
 func (m *DirDeleteRequest) Reset()                    { *m = DirDeleteRequest{} }
 func (m *DirDeleteRequest) String() string            { return proto1.CompactTextString(m) }
 func (*DirDeleteRequest) ProtoMessage()               {}
-func (*DirDeleteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+func (*DirDeleteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
 
 func (m *DirDeleteRequest) GetName() string {
 	if m != nil {
@@ -517,7 +794,7 @@ type DirWhichAccessRequest struct {
 func (m *DirWhichAccessRequest) Reset()                    { *m = DirWhichAccessRequest{} }
 func (m *DirWhichAccessRequest) String() string            { return proto1.CompactTextString(m) }
 func (*DirWhichAccessRequest) ProtoMessage()               {}
-func (*DirWhichAccessRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+func (*DirWhichAccessRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
 
 func (m *DirWhichAccessRequest) GetName() string {
 	if m != nil {
@@ -526,6 +803,23 @@ func (m *DirWhichAccessRequest) GetName() string {
 	return ""
 }
 
+// The response is an EntriesError.
+type DirPutAllRequest struct {
+	Entries [][]byte `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (m *DirPutAllRequest) Reset()                    { *m = DirPutAllRequest{} }
+func (m *DirPutAllRequest) String() string            { return proto1.CompactTextString(m) }
+func (*DirPutAllRequest) ProtoMessage()               {}
+func (*DirPutAllRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *DirPutAllRequest) GetEntries() [][]byte {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 type DirWatchRequest struct {
 	Name     string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Sequence int64  `protobuf:"varint,2,opt,name=sequence" json:"sequence,omitempty"`
@@ -534,7 +828,7 @@ type DirWatchRequest struct {
 func (m *DirWatchRequest) Reset()                    { *m = DirWatchRequest{} }
 func (m *DirWatchRequest) String() string            { return proto1.CompactTextString(m) }
 func (*DirWatchRequest) ProtoMessage()               {}
-func (*DirWatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+func (*DirWatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
 
 func (m *DirWatchRequest) GetName() string {
 	if m != nil {
@@ -564,7 +858,7 @@ type Event struct {
 func (m *Event) Reset()                    { *m = Event{} }
 func (m *Event) String() string            { return proto1.CompactTextString(m) }
 func (*Event) ProtoMessage()               {}
-func (*Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+func (*Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
 
 func (m *Event) GetEntry() []byte {
 	if m != nil {
@@ -606,11 +900,22 @@ func init() {
 	proto1.RegisterType((*StorePutResponse)(nil), "proto.StorePutResponse")
 	proto1.RegisterType((*StoreDeleteRequest)(nil), "proto.StoreDeleteRequest")
 	proto1.RegisterType((*StoreDeleteResponse)(nil), "proto.StoreDeleteResponse")
+	proto1.RegisterType((*StoreExistsRequest)(nil), "proto.StoreExistsRequest")
+	proto1.RegisterType((*StoreExistsResponse)(nil), "proto.StoreExistsResponse")
+	proto1.RegisterType((*StoreGetBatchRequest)(nil), "proto.StoreGetBatchRequest")
+	proto1.RegisterType((*StoreGetBatchResponse)(nil), "proto.StoreGetBatchResponse")
 	proto1.RegisterType((*User)(nil), "proto.User")
 	proto1.RegisterType((*KeyLookupRequest)(nil), "proto.KeyLookupRequest")
 	proto1.RegisterType((*KeyLookupResponse)(nil), "proto.KeyLookupResponse")
 	proto1.RegisterType((*KeyPutRequest)(nil), "proto.KeyPutRequest")
 	proto1.RegisterType((*KeyPutResponse)(nil), "proto.KeyPutResponse")
+	proto1.RegisterType((*KeyLookupBatchRequest)(nil), "proto.KeyLookupBatchRequest")
+	proto1.RegisterType((*KeyLookupBatchResponse)(nil), "proto.KeyLookupBatchResponse")
+	proto1.RegisterType((*KeyRotateRequest)(nil), "proto.KeyRotateRequest")
+	proto1.RegisterType((*KeyRotation)(nil), "proto.KeyRotation")
+	proto1.RegisterType((*KeyHistoryResponse)(nil), "proto.KeyHistoryResponse")
+	proto1.RegisterType((*KeyWatchRequest)(nil), "proto.KeyWatchRequest")
+	proto1.RegisterType((*KeyEvent)(nil), "proto.KeyEvent")
 	proto1.RegisterType((*EntryError)(nil), "proto.EntryError")
 	proto1.RegisterType((*EntriesError)(nil), "proto.EntriesError")
 	proto1.RegisterType((*DirLookupRequest)(nil), "proto.DirLookupRequest")
@@ -618,6 +923,7 @@ func init() {
 	proto1.RegisterType((*DirGlobRequest)(nil), "proto.DirGlobRequest")
 	proto1.RegisterType((*DirDeleteRequest)(nil), "proto.DirDeleteRequest")
 	proto1.RegisterType((*DirWhichAccessRequest)(nil), "proto.DirWhichAccessRequest")
+	proto1.RegisterType((*DirPutAllRequest)(nil), "proto.DirPutAllRequest")
 	proto1.RegisterType((*DirWatchRequest)(nil), "proto.DirWatchRequest")
 	proto1.RegisterType((*Event)(nil), "proto.Event")
 }
@@ -625,58 +931,80 @@ func init() {
 func init() { proto1.RegisterFile("upspin.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 839 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0x5f, 0x8f, 0xdb, 0x44,
-	0x10, 0x8f, 0xcf, 0xf9, 0xe3, 0x4c, 0xd2, 0x4b, 0x6e, 0xdb, 0x2b, 0xae, 0x29, 0x22, 0x5a, 0xd4,
-	0x12, 0x71, 0xa2, 0x3d, 0x42, 0x85, 0xfa, 0x52, 0x20, 0x22, 0xd1, 0x49, 0xa4, 0x42, 0x95, 0x51,
-	0xc5, 0x63, 0xe4, 0x8b, 0xb7, 0x9c, 0xd5, 0xd4, 0x36, 0xeb, 0xf5, 0x49, 0xf9, 0x04, 0x3c, 0xf3,
-	0xc0, 0x87, 0xe1, 0xc3, 0x21, 0xa1, 0x5d, 0xef, 0xae, 0xd7, 0x8e, 0x13, 0x40, 0xf7, 0x74, 0x99,
-	0x9d, 0xdf, 0x6f, 0xe6, 0x37, 0x33, 0x9e, 0x39, 0x18, 0xe6, 0x69, 0x96, 0x46, 0xf1, 0xb3, 0x94,
-	0x26, 0x2c, 0x41, 0x1d, 0xf1, 0x07, 0xff, 0x00, 0xce, 0x32, 0x0e, 0xd3, 0x24, 0x8a, 0x19, 0x7a,
-	0x0c, 0x7d, 0x46, 0x83, 0x38, 0x4b, 0x13, 0xca, 0x5c, 0x6b, 0x62, 0x4d, 0x3b, 0x7e, 0xf9, 0x80,
-	0x1e, 0x81, 0x13, 0x13, 0xb6, 0x0e, 0xc2, 0x90, 0xba, 0x27, 0x13, 0x6b, 0xda, 0xf7, 0x7b, 0x31,
-	0x61, 0xf3, 0x30, 0xa4, 0xf8, 0x2d, 0x38, 0xaf, 0x93, 0x4d, 0xc0, 0xa2, 0x24, 0x46, 0x17, 0xe0,
-	0x10, 0x19, 0x50, 0xc4, 0x18, 0xcc, 0x46, 0x45, 0xc6, 0x67, 0x2a, 0x8f, 0xaf, 0x01, 0x3c, 0x23,
-	0x25, 0xef, 0x08, 0x25, 0xf1, 0x86, 0xc8, 0xa0, 0xe5, 0x03, 0x5e, 0x43, 0xcf, 0x27, 0xef, 0xc2,
-	0x80, 0x05, 0x55, 0xa0, 0x55, 0x03, 0x22, 0x0f, 0x9c, 0xdb, 0x64, 0x1b, 0xb0, 0x68, 0x5b, 0x44,
-	0x71, 0x7c, 0x6d, 0x73, 0x5f, 0x98, 0x53, 0xa1, 0xcd, 0xb5, 0x27, 0xd6, 0xd4, 0xf6, 0xb5, 0x8d,
-	0xcf, 0x60, 0xa4, 0x45, 0x91, 0xdf, 0x72, 0x92, 0x31, 0xfc, 0x1d, 0x8c, 0xcb, 0xa7, 0x2c, 0x4d,
-	0xe2, 0x8c, 0xfc, 0xaf, 0x92, 0xf0, 0x73, 0x18, 0xfd, 0xcc, 0x12, 0x4a, 0xae, 0x88, 0x8a, 0x79,
-	0x5c, 0x3c, 0xfe, 0xd3, 0x82, 0x71, 0xc9, 0x90, 0x29, 0x11, 0xb4, 0x79, 0xdd, 0x02, 0x3d, 0xf4,
-	0xc5, 0x6f, 0x34, 0x85, 0x1e, 0x2d, 0xda, 0x21, 0x8a, 0x1c, 0xcc, 0x4e, 0xa5, 0x0a, 0xd9, 0x24,
-	0x5f, 0xb9, 0xd1, 0x97, 0xd0, 0xdf, 0xca, 0x79, 0x64, 0xae, 0x3d, 0xb1, 0x0d, 0xc5, 0x6a, 0x4e,
-	0x7e, 0x89, 0x40, 0x0f, 0xa0, 0x43, 0x28, 0x4d, 0xa8, 0xdb, 0x16, 0xd9, 0x0a, 0x03, 0x3f, 0x91,
-	0x85, 0xbc, 0xc9, 0x75, 0x21, 0x0d, 0xaa, 0xb0, 0x2f, 0xd5, 0x0b, 0x98, 0x54, 0x6f, 0x28, 0xb5,
-	0x8e, 0x2b, 0xd5, 0xa9, 0x4f, 0xcc, 0xd4, 0x33, 0x40, 0x22, 0xe6, 0x82, 0x6c, 0x09, 0x23, 0xff,
-	0xad, 0x8d, 0x17, 0x70, 0xbf, 0xc2, 0x91, 0x52, 0x74, 0x02, 0xcb, 0x4c, 0xf0, 0xbb, 0x05, 0xed,
-	0xb7, 0x19, 0xa1, 0xbc, 0xa2, 0x38, 0xf8, 0xa0, 0xc2, 0x89, 0xdf, 0xe8, 0x33, 0x68, 0x87, 0x11,
-	0xcd, 0xdc, 0x93, 0x4a, 0xe3, 0xf4, 0xa8, 0x85, 0x13, 0x7d, 0x0e, 0xdd, 0x8c, 0xa7, 0xab, 0xf7,
-	0x57, 0xc3, 0xa4, 0x1b, 0x7d, 0x02, 0x90, 0xe6, 0xd7, 0xdb, 0x68, 0xb3, 0x7e, 0x4f, 0x76, 0xa2,
-	0xc3, 0x7d, 0xbf, 0x5f, 0xbc, 0xac, 0xc8, 0x0e, 0x3f, 0x87, 0xf1, 0x8a, 0xec, 0x5e, 0x27, 0xc9,
-	0xfb, 0x3c, 0x55, 0x85, 0x7e, 0x0c, 0xfd, 0x3c, 0x23, 0x74, 0x6d, 0x28, 0x73, 0xf8, 0xc3, 0x4f,
-	0xc1, 0x07, 0x82, 0x7f, 0x84, 0x33, 0x83, 0x20, 0xab, 0xfc, 0x14, 0xda, 0x1c, 0x20, 0xbb, 0x3d,
-	0x90, 0x5a, 0x78, 0x85, 0xbe, 0x70, 0x1c, 0xe8, 0xf3, 0x25, 0xdc, 0x5b, 0x91, 0x9d, 0x31, 0xe0,
-	0x7f, 0x8b, 0x83, 0x9f, 0xc2, 0xa9, 0x62, 0x1c, 0x6d, 0xf0, 0x4b, 0x80, 0x65, 0xcc, 0xe8, 0x6e,
-	0xc9, 0x2d, 0x81, 0xe1, 0x96, 0xc6, 0x70, 0xe3, 0x80, 0xa6, 0x6f, 0x61, 0xc8, 0x99, 0x11, 0xc9,
-	0x0a, 0xae, 0x0b, 0x3d, 0x52, 0xd8, 0xae, 0x35, 0xb1, 0xa7, 0x43, 0x5f, 0x99, 0x07, 0xf8, 0x4f,
-	0x61, 0xbc, 0x88, 0x68, 0xb5, 0xa1, 0x0d, 0x53, 0xc6, 0x4f, 0xe0, 0xde, 0x22, 0xa2, 0x46, 0xed,
-	0x8d, 0x22, 0xf1, 0x17, 0x70, 0xba, 0x88, 0xe8, 0xd5, 0x36, 0xb9, 0x56, 0x38, 0x17, 0x7a, 0x69,
-	0xc0, 0x18, 0xa1, 0xb1, 0x8c, 0xa7, 0x4c, 0x99, 0xba, 0xfa, 0xd1, 0x36, 0xa5, 0xbe, 0x80, 0xf3,
-	0x45, 0x44, 0x7f, 0xb9, 0x89, 0x36, 0x37, 0xf3, 0xcd, 0x86, 0x64, 0xd9, 0x31, 0xf0, 0x1c, 0x46,
-	0x1c, 0x1c, 0xb0, 0xcd, 0xcd, 0x11, 0x18, 0x3f, 0x73, 0x19, 0x77, 0xab, 0x43, 0x6a, 0xfb, 0xda,
-	0xc6, 0xbf, 0x42, 0x67, 0x79, 0x4b, 0xe2, 0x03, 0x25, 0x1e, 0xa3, 0xa2, 0x87, 0xd0, 0x0d, 0x45,
-	0x3d, 0xe2, 0x76, 0x3a, 0xbe, 0xb4, 0x9a, 0x4f, 0xc6, 0xec, 0x6f, 0x0b, 0x3a, 0x62, 0x09, 0xd1,
-	0x2b, 0xe3, 0xdf, 0xca, 0xc3, 0xfa, 0x6a, 0x14, 0x65, 0x78, 0x1f, 0xed, 0xbd, 0x17, 0x9f, 0x14,
-	0x6e, 0xa1, 0x97, 0x60, 0x5f, 0x91, 0x92, 0x59, 0x3b, 0xa8, 0x9a, 0x59, 0x3f, 0x9b, 0x05, 0xf3,
-	0x4d, 0x5e, 0x63, 0x96, 0x43, 0xae, 0x32, 0x8d, 0xcf, 0x18, 0xb7, 0xd0, 0x1c, 0xba, 0xc5, 0xe8,
-	0xd0, 0x23, 0x13, 0x54, 0x19, 0xa7, 0xe7, 0x35, 0xb9, 0x54, 0x88, 0xd9, 0x5f, 0x16, 0xd8, 0x2b,
-	0xb2, 0xbb, 0x6b, 0xf5, 0xaf, 0xa0, 0x5b, 0x7c, 0xbf, 0x48, 0x81, 0xea, 0x27, 0xc2, 0x73, 0xf7,
-	0x1d, 0x9a, 0xfe, 0xa2, 0x68, 0xc1, 0x83, 0x12, 0x62, 0x34, 0xe0, 0xbc, 0xf6, 0xaa, 0xb5, 0xff,
-	0x61, 0x83, 0xbd, 0x88, 0xe8, 0x5d, 0xb5, 0x7f, 0xb3, 0xa7, 0xbd, 0xbe, 0x8d, 0xde, 0x99, 0x66,
-	0xab, 0x03, 0x81, 0x5b, 0xe8, 0xb2, 0x2a, 0xba, 0xb2, 0x9a, 0xcd, 0x8c, 0x17, 0xd0, 0xe6, 0x6b,
-	0x89, 0xce, 0x4b, 0x8a, 0xb1, 0xa6, 0xde, 0x7d, 0x83, 0xa3, 0x8e, 0x49, 0xa1, 0x4f, 0x4e, 0xd9,
-	0xd0, 0x57, 0x9d, 0x71, 0x63, 0xb6, 0xef, 0x61, 0x60, 0x2c, 0x2c, 0x7a, 0x5c, 0x92, 0xf7, 0xf7,
-	0xb8, 0x39, 0xc2, 0x57, 0xd0, 0x11, 0x5b, 0xac, 0xbb, 0x5a, 0x5b, 0x6b, 0x6f, 0xa8, 0x58, 0x7c,
-	0x57, 0x71, 0xeb, 0xd2, 0xba, 0xee, 0x8a, 0x87, 0xaf, 0xff, 0x09, 0x00, 0x00, 0xff, 0xff, 0xe7,
-	0x95, 0x7f, 0xb4, 0xba, 0x09, 0x00, 0x00,
+	// 1190 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x5d, 0x6e, 0xdb, 0x46,
+	0x10, 0x16, 0x4d, 0x49, 0xa6, 0x46, 0x4e, 0xe4, 0xac, 0xff, 0x68, 0xc6, 0x6e, 0x8c, 0x2d, 0x92,
+	0x1a, 0x75, 0x93, 0xb8, 0x6a, 0x6a, 0x18, 0x05, 0xd2, 0xd6, 0x89, 0x0c, 0x17, 0x95, 0x11, 0x18,
+	0x0c, 0x82, 0xbc, 0xb4, 0x30, 0x68, 0x69, 0x13, 0x13, 0x91, 0x49, 0x75, 0xb9, 0x34, 0xaa, 0x13,
+	0x14, 0x3d, 0x40, 0x5f, 0x7b, 0x93, 0x9e, 0xa0, 0x97, 0x2a, 0xb8, 0x3f, 0xe4, 0x72, 0x4d, 0x2a,
+	0x2e, 0xf2, 0x24, 0xce, 0xee, 0x7c, 0x33, 0xdf, 0xfc, 0xec, 0x8c, 0x60, 0x29, 0x9d, 0x26, 0xd3,
+	0x30, 0x7a, 0x32, 0xa5, 0x31, 0x8b, 0x51, 0x8b, 0xff, 0xe0, 0x97, 0xe0, 0x1c, 0x47, 0xe3, 0x69,
+	0x1c, 0x46, 0x0c, 0x6d, 0x41, 0x87, 0xd1, 0x20, 0x4a, 0xa6, 0x31, 0x65, 0xae, 0xb5, 0x63, 0xed,
+	0xb6, 0xfc, 0xe2, 0x00, 0x6d, 0x82, 0x13, 0x11, 0x76, 0x1e, 0x8c, 0xc7, 0xd4, 0x5d, 0xd8, 0xb1,
+	0x76, 0x3b, 0xfe, 0x62, 0x44, 0xd8, 0xd1, 0x78, 0x4c, 0xf1, 0x1b, 0x70, 0x4e, 0xe3, 0x51, 0xc0,
+	0xc2, 0x38, 0x42, 0x7b, 0xe0, 0x10, 0x69, 0x90, 0xdb, 0xe8, 0xf6, 0x7b, 0xc2, 0xe3, 0x13, 0xe5,
+	0xc7, 0x77, 0x88, 0xe6, 0x91, 0x92, 0x77, 0x84, 0x92, 0x68, 0x44, 0xa4, 0xd1, 0xe2, 0x00, 0x9f,
+	0xc3, 0xa2, 0x4f, 0xde, 0x8d, 0x03, 0x16, 0x94, 0x15, 0x2d, 0x43, 0x11, 0x79, 0xe0, 0x5c, 0xc7,
+	0x93, 0x80, 0x85, 0x13, 0x61, 0xc5, 0xf1, 0x73, 0x39, 0xbb, 0x1b, 0xa7, 0x94, 0x73, 0x73, 0xed,
+	0x1d, 0x6b, 0xd7, 0xf6, 0x73, 0x19, 0xdf, 0x83, 0x5e, 0x4e, 0x8a, 0xfc, 0x96, 0x92, 0x84, 0xe1,
+	0x1f, 0x60, 0xb9, 0x38, 0x4a, 0xa6, 0x71, 0x94, 0x90, 0xff, 0x15, 0x12, 0x7e, 0x0a, 0xbd, 0xd7,
+	0x2c, 0xa6, 0xe4, 0x84, 0x28, 0x9b, 0xf3, 0xc9, 0xe3, 0xbf, 0x2c, 0x58, 0x2e, 0x10, 0xd2, 0x25,
+	0x82, 0x66, 0x16, 0x37, 0xd7, 0x5e, 0xf2, 0xf9, 0x37, 0xda, 0x85, 0x45, 0x2a, 0xd2, 0xc1, 0x83,
+	0xec, 0xf6, 0xef, 0x4a, 0x16, 0x32, 0x49, 0xbe, 0xba, 0x46, 0x8f, 0xa1, 0x33, 0x91, 0xf5, 0x48,
+	0x5c, 0x7b, 0xc7, 0xd6, 0x18, 0xab, 0x3a, 0xf9, 0x85, 0x06, 0x5a, 0x85, 0x16, 0xa1, 0x34, 0xa6,
+	0x6e, 0x93, 0x7b, 0x13, 0x02, 0x7e, 0x28, 0x03, 0x39, 0x4b, 0xf3, 0x40, 0x2a, 0x58, 0x61, 0x1f,
+	0x96, 0x0b, 0x35, 0xc9, 0x5e, 0x63, 0x6a, 0xcd, 0x67, 0x9a, 0xbb, 0x5e, 0xd0, 0x5d, 0xf7, 0x01,
+	0x71, 0x9b, 0x03, 0x32, 0x21, 0x8c, 0xdc, 0x2e, 0x8d, 0x7b, 0xb0, 0x52, 0xc2, 0x48, 0x2a, 0xb9,
+	0x03, 0xab, 0xca, 0xc1, 0xf1, 0xef, 0x61, 0xc2, 0x92, 0xdb, 0x39, 0x78, 0x09, 0x2b, 0x25, 0x8c,
+	0x74, 0xb0, 0x0e, 0x6d, 0xc2, 0x4f, 0x38, 0xc2, 0xf1, 0xa5, 0x54, 0x13, 0xd9, 0x01, 0xac, 0xaa,
+	0x5a, 0xbf, 0x08, 0xd8, 0xe8, 0x52, 0xb9, 0xfe, 0x0c, 0x20, 0xf7, 0x94, 0x59, 0xb2, 0x77, 0x3b,
+	0xbe, 0x76, 0x82, 0x5f, 0xc1, 0x9a, 0x81, 0x93, 0xee, 0xbf, 0xcd, 0x38, 0x8b, 0x6f, 0x81, 0xeb,
+	0xf6, 0x37, 0x64, 0xb2, 0xcd, 0xa6, 0xf2, 0x0b, 0x4d, 0xfc, 0x87, 0x05, 0xcd, 0x37, 0x09, 0xa1,
+	0x59, 0x49, 0xa3, 0xe0, 0x4a, 0x85, 0xcb, 0xbf, 0xd1, 0xe7, 0xd0, 0x1c, 0x87, 0x34, 0x71, 0x17,
+	0x76, 0xec, 0xaa, 0x5e, 0xe7, 0x97, 0xe8, 0x0b, 0x68, 0x27, 0x99, 0x03, 0xb3, 0xc1, 0x72, 0x35,
+	0x79, 0x8d, 0xb6, 0x01, 0xa6, 0xe9, 0xc5, 0x24, 0x1c, 0x9d, 0x7f, 0x20, 0x33, 0xde, 0x62, 0x1d,
+	0xbf, 0x23, 0x4e, 0x86, 0x64, 0x86, 0x9f, 0xc2, 0xf2, 0x90, 0xcc, 0x4e, 0xe3, 0xf8, 0x43, 0x3a,
+	0x55, 0xd9, 0xb8, 0x0f, 0x9d, 0x34, 0x21, 0xf4, 0x5c, 0x63, 0xe6, 0x64, 0x07, 0xaf, 0x82, 0x2b,
+	0x82, 0x7f, 0x86, 0x7b, 0x1a, 0x40, 0xa6, 0xe1, 0x01, 0x34, 0x33, 0x05, 0xd9, 0x6e, 0x5d, 0xc9,
+	0x25, 0x8b, 0xd0, 0xe7, 0x17, 0x35, 0xe5, 0xd8, 0x87, 0x3b, 0x43, 0x32, 0xd3, 0x3a, 0xfc, 0x63,
+	0x76, 0xf0, 0x23, 0xb8, 0xab, 0x10, 0x73, 0x3b, 0xec, 0x00, 0xd6, 0x72, 0x96, 0xa5, 0x4a, 0x6f,
+	0x03, 0xe4, 0xb1, 0xa9, 0x4a, 0x77, 0x54, 0x70, 0x09, 0x3e, 0x83, 0x75, 0x13, 0x27, 0xfd, 0x1c,
+	0xdc, 0xac, 0xb4, 0x2b, 0xf9, 0xdd, 0xc8, 0x87, 0x5e, 0xea, 0x94, 0x27, 0xd8, 0x8f, 0x59, 0xc0,
+	0xc8, 0x6d, 0xc3, 0x44, 0x0f, 0xa0, 0x9b, 0x84, 0xef, 0xa3, 0x80, 0xa5, 0x94, 0x9c, 0xab, 0xa4,
+	0x41, 0x7e, 0xe4, 0x97, 0x15, 0x12, 0xd7, 0x36, 0x14, 0x5e, 0xe3, 0xbf, 0x2d, 0xe8, 0x2a, 0xbf,
+	0xd9, 0x5e, 0xf0, 0xc0, 0x99, 0x52, 0x72, 0x1d, 0xc6, 0x69, 0xa2, 0x4a, 0xaa, 0x64, 0xa3, 0x45,
+	0x16, 0x8c, 0x16, 0x31, 0xc9, 0xd8, 0x1f, 0x23, 0xd3, 0x34, 0xc9, 0x64, 0x5d, 0xce, 0xc2, 0x2b,
+	0xe2, 0xb6, 0xf8, 0x02, 0xe0, 0xdf, 0xf8, 0x17, 0x40, 0x43, 0x32, 0xfb, 0x29, 0xcc, 0xda, 0x74,
+	0x96, 0x67, 0x79, 0x1f, 0x3a, 0x54, 0x52, 0x56, 0x59, 0x46, 0x45, 0x96, 0x55, 0x34, 0x7e, 0xa1,
+	0x54, 0xd3, 0x59, 0x8f, 0xa1, 0x37, 0x24, 0xb3, 0xb7, 0x7a, 0xe5, 0x3d, 0x70, 0x92, 0xec, 0x53,
+	0x4d, 0x17, 0xdb, 0xcf, 0x65, 0xfc, 0x2b, 0x38, 0x43, 0x32, 0x3b, 0xbe, 0x26, 0xd1, 0x2d, 0x8a,
+	0xa3, 0x1b, 0x5a, 0x28, 0x1b, 0x2a, 0xd8, 0xd8, 0x3a, 0x9b, 0x43, 0x80, 0xe3, 0x88, 0xd1, 0xd9,
+	0x71, 0x26, 0x71, 0x9d, 0x4c, 0xca, 0x3b, 0x36, 0x13, 0x6a, 0xe2, 0xf8, 0x1e, 0x96, 0x32, 0x64,
+	0x48, 0x12, 0x81, 0x75, 0x61, 0x91, 0x08, 0x99, 0x67, 0x67, 0xc9, 0x57, 0x62, 0x0d, 0xfe, 0x11,
+	0x2c, 0x0f, 0x42, 0x5a, 0x7e, 0xde, 0x15, 0x33, 0x07, 0x3f, 0x84, 0x3b, 0x83, 0x90, 0x6a, 0x2f,
+	0xb1, 0x92, 0x24, 0xfe, 0x12, 0xee, 0x0e, 0x42, 0x7a, 0x32, 0x89, 0x2f, 0x94, 0x9e, 0x0b, 0x8b,
+	0xd3, 0x80, 0x31, 0x42, 0x23, 0x69, 0x4f, 0x89, 0xd2, 0x75, 0x79, 0x87, 0x54, 0xb9, 0xde, 0x83,
+	0xb5, 0x41, 0x48, 0xdf, 0x5e, 0x86, 0xa3, 0xcb, 0xa3, 0xd1, 0x88, 0x24, 0xc9, 0x3c, 0xe5, 0xaf,
+	0xb8, 0xd1, 0xb3, 0x94, 0x1d, 0x4d, 0x26, 0x1a, 0x85, 0xea, 0x9c, 0xe0, 0x23, 0xe8, 0x65, 0xa6,
+	0xf5, 0x2e, 0xa8, 0x1a, 0xb8, 0x73, 0x0a, 0x8a, 0xdf, 0x43, 0x4b, 0xb4, 0x45, 0x75, 0xd5, 0xe6,
+	0xf5, 0xc2, 0x3a, 0xb4, 0xc7, 0x3c, 0x7a, 0xde, 0x0c, 0x8e, 0x2f, 0xa5, 0xea, 0x7d, 0xdf, 0xff,
+	0xd3, 0x86, 0x16, 0x5f, 0x19, 0xe8, 0xb9, 0xf6, 0x9f, 0x70, 0xdd, 0x1c, 0xeb, 0x22, 0x0c, 0x6f,
+	0xe3, 0xc6, 0xb9, 0x78, 0x40, 0xb8, 0x81, 0x0e, 0xc1, 0x3e, 0x21, 0x05, 0xd2, 0xf8, 0x37, 0xe4,
+	0xd5, 0xad, 0x27, 0x81, 0x3c, 0x4b, 0x0d, 0xe4, 0x59, 0x5a, 0x8d, 0xd4, 0x46, 0x30, 0x6e, 0xa0,
+	0x23, 0x68, 0x8b, 0x42, 0xa3, 0x4d, 0x5d, 0xa9, 0x54, 0x7c, 0xcf, 0xab, 0xba, 0xd2, 0x4d, 0x88,
+	0xd5, 0x5e, 0x36, 0x51, 0xfa, 0x8b, 0xe0, 0x79, 0x55, 0x57, 0xb9, 0x89, 0x13, 0x70, 0xd4, 0x82,
+	0x46, 0xf7, 0x8d, 0x30, 0xf5, 0x25, 0xe0, 0x6d, 0x55, 0x5f, 0x2a, 0x43, 0xfd, 0x7f, 0x6c, 0xb0,
+	0xb3, 0xc9, 0xf7, 0x89, 0x95, 0x78, 0x0e, 0x6d, 0xf1, 0xf2, 0xd0, 0xc6, 0xcd, 0x4d, 0x21, 0xd0,
+	0xb5, 0x2b, 0x04, 0x37, 0xd0, 0x33, 0x51, 0x8e, 0xd5, 0x42, 0x45, 0x2b, 0xc6, 0x9a, 0x71, 0x9a,
+	0xa3, 0x4e, 0xa1, 0xab, 0xad, 0x2f, 0xb4, 0x65, 0x3a, 0x28, 0x25, 0x62, 0xbb, 0xe6, 0x56, 0xe3,
+	0xd0, 0xe2, 0xcf, 0x27, 0x0f, 0xdf, 0x98, 0xaa, 0x5e, 0xaf, 0x38, 0xe7, 0xef, 0x04, 0x37, 0xf6,
+	0x2d, 0xf4, 0x1d, 0xb4, 0xc5, 0xc2, 0xd3, 0x03, 0x2f, 0xad, 0xc0, 0x7a, 0xfe, 0x2f, 0x00, 0x8a,
+	0xbd, 0x50, 0x9f, 0xb8, 0xcd, 0xe2, 0xc2, 0xd8, 0x21, 0xb8, 0xd1, 0xff, 0xd7, 0x06, 0x7b, 0x10,
+	0xd2, 0x4f, 0xad, 0xdf, 0xc1, 0x8d, 0xfa, 0x99, 0xb3, 0xd4, 0xbb, 0x97, 0xa3, 0xd5, 0x78, 0xc7,
+	0x0d, 0xb4, 0x5f, 0x2e, 0x5c, 0x69, 0xb0, 0x56, 0x23, 0x9e, 0x41, 0x33, 0x1b, 0xaa, 0x68, 0xad,
+	0x80, 0x68, 0x43, 0xd6, 0x5b, 0xd1, 0x30, 0x6a, 0x15, 0x08, 0x7e, 0xf2, 0xd5, 0x69, 0xfc, 0xca,
+	0x6f, 0xae, 0xd2, 0xdb, 0x8f, 0xd0, 0xd5, 0xc6, 0x6d, 0xde, 0x22, 0x95, 0x53, 0xb8, 0xda, 0xc2,
+	0xd7, 0x66, 0x5b, 0x18, 0x63, 0xd6, 0x5b, 0x52, 0xa8, 0xbc, 0x27, 0x0e, 0xa1, 0x2d, 0xc6, 0xb6,
+	0x4e, 0xb6, 0x34, 0xc8, 0x6b, 0xc2, 0xbc, 0x68, 0xf3, 0xd3, 0x6f, 0xfe, 0x1b, 0x00, 0x6c, 0xee,
+	0xc2, 0x7e, 0x41, 0x0f, 0x00, 0x00,
 }
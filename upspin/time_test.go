@@ -31,3 +31,60 @@ func TestTime(t *testing.T) {
 	}
 
 }
+
+func TestTimeRoundTrip(t *testing.T) {
+	for _, goTime := range []time.Time{
+		time.Unix(0, 0),
+		time.Date(2001, 3, 15, 17, 39, 12, 0, time.UTC),
+		time.Date(2017, 12, 31, 23, 59, 59, 0, time.FixedZone("EST", -5*3600)),
+		time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), // Before the epoch.
+	} {
+		theTime := TimeFromGo(goTime)
+		if back := theTime.Go(); !back.Equal(goTime) {
+			t.Errorf("TimeFromGo(%v).Go() = %v", goTime, back)
+		}
+		parsed, err := ParseTime(theTime.String())
+		if err != nil {
+			t.Errorf("ParseTime(%q): %v", theTime, err)
+			continue
+		}
+		if parsed != theTime {
+			t.Errorf("ParseTime(%q) = %d, want %d", theTime, parsed, theTime)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := TimeFromGo(time.Date(2001, 3, 15, 17, 39, 12, 0, time.UTC))
+	for _, s := range []string{
+		"2001-03-15T17:39:12Z",
+		"2001-03-15T17:39:12+00:00",
+		"2001-03-15T12:39:12-05:00",
+		"2001-03-16T02:39:12+09:00",
+		"2001-03-15T17:39:12.75Z", // Fractions are dropped.
+		"2001-03-15T17:39:12 UTC",
+	} {
+		got, err := ParseTime(s)
+		if err != nil {
+			t.Errorf("ParseTime(%q): %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseTime(%q) = %v, want %v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"yesterday",
+		"2001-03-15",
+		"2001-03-15 17:39:12",
+		"2001-03-15T17:39:12",
+		"2001-13-15T17:39:12Z",
+		"2001-03-15T17:39:12 PST",
+	} {
+		if got, err := ParseTime(s); err == nil {
+			t.Errorf("ParseTime(%q) = %v, want error", s, got)
+		}
+	}
+}
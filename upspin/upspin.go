@@ -7,6 +7,7 @@ package upspin // import "upspin.io/upspin"
 import (
 	"crypto/elliptic"
 	"errors"
+	"io"
 	"math/big"
 	"time"
 )
@@ -290,6 +291,10 @@ const (
 	// Packings from 2 through 19 are not for production use. This region
 	// is reserved for debugging and other temporary packing implementations.
 
+	// PlainSumPack is like PlainPack but stores a SHA-256 checksum of
+	// each block, verified when the block is unpacked. Nothing is signed.
+	PlainSumPack Packing = 2
+
 	// Packings from 20 and above (as well as PlainPack) are fixed in
 	// value and semantics and may be used in production.
 
@@ -346,6 +351,93 @@ type KeyServer interface {
 	Put(user *User) error
 }
 
+// KeyLookupBatcher is implemented by KeyServers that can look up several
+// users in one request. It is not part of the KeyServer interface; clients
+// discover whether a KeyServer supports it using a type assertion.
+type KeyLookupBatcher interface {
+	// LookupBatch is like calling Lookup for each of the names.
+	// The two returned slices have the same length as names; for each
+	// name, either the User or the error is non-nil. A failure to look up
+	// one name does not affect the others, but if the request as a whole
+	// fails, every element of the error slice holds that error.
+	LookupBatch(names []UserName) ([]*User, []error)
+}
+
+// KeyWatcher is implemented by KeyServers that can report changes to the
+// user records they hold, so that those caching the results of Lookup can
+// tell when a record has changed. It is not part of the KeyServer interface;
+// clients discover whether a KeyServer supports it using a type assertion.
+type KeyWatcher interface {
+	// Watch returns a channel of KeyEvents, one for each user record
+	// stored by a successful Put, in the order they were stored.
+	// Each record is numbered by a sequence that starts at 1 and
+	// increases by one with each record.
+	//
+	// If sequence is WatchStart, Watch sends every record the server
+	// has stored. If sequence is WatchNew, it sends only records stored
+	// after the call. Otherwise, sequence must be that of a record
+	// already received and Watch sends the records that follow it.
+	// Any other sequence is an error.
+	//
+	// Watch is not subject to access control, since user records are
+	// public.
+	//
+	// The channel is closed when done is closed. If an error occurs
+	// while waiting for records, it is sent as a KeyEvent with a non-nil
+	// Error field and the channel is closed.
+	Watch(sequence int64, done <-chan struct{}) (<-chan KeyEvent, error)
+}
+
+// KeyRotator is implemented by KeyServers that keep, for each user, a history
+// of the changes to the user's public key in which each new key is signed by
+// the key it replaces. It lets those relying on a key verify that it was
+// authorized by its predecessor rather than trusting the latest key blindly.
+// It is not part of the KeyServer interface; clients discover whether a
+// KeyServer supports it using a type assertion.
+type KeyRotator interface {
+	// Rotate is like Put for an existing user but also replaces the
+	// user's public key, recording the change in the user's key history.
+	// The signature must be made with the user's current private key over
+	// the hash computed by factotum.RotationHash from the user name, the
+	// current public key, and the new one, user.PublicKey.
+	Rotate(user *User, sig Signature) error
+
+	// KeyHistory returns, oldest first, the changes made to the named
+	// user's public key since the server began recording them.
+	KeyHistory(name UserName) ([]KeyRotation, error)
+}
+
+// KeyRotation records the replacement of a user's public key.
+type KeyRotation struct {
+	// Previous is the key that was replaced.
+	Previous PublicKey
+
+	// PublicKey is the key that replaced it.
+	PublicKey PublicKey
+
+	// Signature is the signature made with Previous that authorized the
+	// change. It is zero if the key was replaced not by the user but by
+	// an administrator, whose authority must be trusted instead.
+	Signature Signature
+
+	// Time is when the key was replaced.
+	Time Time
+}
+
+// KeyEvent reports a user record stored by a KeyServer.
+type KeyEvent struct {
+	// User is the user record that was stored.
+	User *User
+
+	// Sequence is the position of the record among those stored by
+	// the server. Records with the same sequence are the same record.
+	Sequence int64
+
+	// Error is non-nil if an error occurred while waiting for records.
+	// In that case, all other fields are zero.
+	Error error
+}
+
 // A PublicKey can be seen by anyone and is used for authenticating a user.
 type PublicKey string
 
@@ -548,6 +640,68 @@ type Event struct {
 	Error error
 }
 
+// DirHistorian is implemented by DirServers that can report the history of
+// changes to an item. It is not part of the DirServer interface; clients
+// discover whether a DirServer supports it using a type assertion.
+type DirHistorian interface {
+	// History returns, oldest first, the events that created, modified,
+	// or deleted the named item. For a deletion, the Entry is the
+	// DirEntry that was deleted. If the item was deleted and later
+	// recreated, the events for all its incarnations are returned.
+	// A server may limit the number of events it returns, in which
+	// case it drops the oldest.
+	//
+	// The caller must have one or more Upspin access rights to the
+	// named item. If the caller has rights but not Read, the entries
+	// are incomplete (see the description of AttrIncomplete).
+	//
+	// If this server does not support this method it returns
+	// ErrNotSupported.
+	History(name PathName) ([]Event, error)
+}
+
+// DirExister is implemented by DirServers that can report whether an item
+// exists more cheaply than Lookup. It is not part of the DirServer interface;
+// clients discover whether a DirServer supports it using a type assertion.
+type DirExister interface {
+	// Exists reports whether the named item exists. It does not
+	// follow a link at the final element of the name; if the name
+	// is a link, Exists reports true.
+	//
+	// As with Lookup, the caller must have one or more Upspin access
+	// rights to the named item for its existence to be revealed. If the
+	// caller has no rights, Exists reports false just as if the item did
+	// not exist.
+	//
+	// If an earlier element of the name is a link, Exists returns
+	// ErrFollowLink and the caller should use Lookup to discover the
+	// link.
+	//
+	// If this server does not support this method it returns
+	// ErrNotSupported.
+	Exists(name PathName) (bool, error)
+}
+
+// DirPutAller is implemented by DirServers that can put several entries as
+// a single transaction. It is not part of the DirServer interface; clients
+// discover whether a DirServer supports it using a type assertion.
+type DirPutAller interface {
+	// PutAll puts the entries, in order, so that either all of them
+	// are put or none is, as when writing a file together with the
+	// Access file that protects it. The entries must all be in the same
+	// user's tree and none may be a root. Each entry is checked as by
+	// Put, except that an entry may be put in a directory made by an
+	// earlier entry. As with Put, the returned entries are incomplete
+	// and hold only their new sequence numbers.
+	//
+	// If the returned error is ErrFollowLink, the only entry returned
+	// is that of the link found along the path of one of the entries.
+	//
+	// If this server does not support this method it returns
+	// ErrNotSupported.
+	PutAll(entries []*DirEntry) ([]*DirEntry, error)
+}
+
 // Time represents a timestamp in units of seconds since
 // the Unix epoch, Jan 1 1970 0:00 UTC.
 type Time int64
@@ -581,8 +735,12 @@ type DirEntry struct {
 // sync manually because the flags package cannot import this package.
 const BlockSize = 1024 * 1024
 
-// MaxBlockSize is the maximum size permitted for a block. The limit
+// MaxBlockSize is the maximum size permitted for a block, 1GB. The limit
 // guarantees that 32-bit machines can process the data without problems.
+// Packers refuse to pack larger blocks, and refuse to unpack entries
+// whose blocks claim to be larger, so a malicious DirEntry cannot force
+// a reader to allocate an arbitrarily large buffer. Clients that split
+// data into blocks must use a block size no larger than this.
 const MaxBlockSize = 1024 * 1024 * 1024
 
 // DirBlock describes a block of data representing a contiguous section of a file.
@@ -672,6 +830,56 @@ type StoreServer interface {
 	Delete(ref Reference) error
 }
 
+// StoreExister is implemented by StoreServers that can report whether they
+// hold the data for a reference without returning it. It is not part of the
+// StoreServer interface; clients discover whether a StoreServer supports it
+// using a type assertion.
+type StoreExister interface {
+	// Exists reports whether the data identified by the reference is
+	// held in this StoreServer. A clean report that the data is missing
+	// returns false and a nil error.
+	//
+	// If this server does not support this method it returns
+	// ErrNotSupported.
+	Exists(ref Reference) (bool, error)
+}
+
+// StoreGetBatcher is implemented by StoreServers that can return the data
+// for several references in one request. It is not part of the StoreServer
+// interface; clients discover whether a StoreServer supports it using a type
+// assertion.
+type StoreGetBatcher interface {
+	// GetBatch is like calling Get for each of the references, of which
+	// there may be at most MaxGetBatchRefs, but to bound the size of the
+	// reply it may return results for only the first few: it stops
+	// after the result that brings the total size of the data returned to
+	// MaxGetBatchBytes or more. It always returns at least one result
+	// unless refs is empty. A failure to get one reference does not
+	// affect the others, but if the request as a whole fails, there is a
+	// result for each reference and each holds that error.
+	//
+	// If this server does not support this method it returns
+	// ErrNotSupported in every result.
+	GetBatch(refs []Reference) []StoreGetResult
+}
+
+// StoreGetResult holds the values returned by StoreServer.Get for one
+// reference in a StoreGetBatcher.GetBatch request.
+type StoreGetResult struct {
+	Data      []byte
+	Refdata   *Refdata
+	Locations []Location
+	Error     error
+}
+
+// MaxGetBatchRefs is the maximum number of references in a single
+// StoreGetBatcher.GetBatch request.
+const MaxGetBatchRefs = 64
+
+// MaxGetBatchBytes is the size of the data after which
+// StoreGetBatcher.GetBatch returns no more results.
+const MaxGetBatchBytes = 16 * BlockSize
+
 // Client API.
 
 // The Client interface provides a higher-level API suitable for applications
@@ -808,6 +1016,43 @@ type Client interface {
 	DirServer(name PathName) (DirServer, error)
 }
 
+// ReaderGetter is implemented by Clients that can stream the contents of
+// a file rather than returning them all at once. It is not part of the
+// Client interface; callers discover whether a Client supports it using a
+// type assertion.
+type ReaderGetter interface {
+	// GetReader is like Get but returns a ReadCloser that fetches,
+	// unpacks, and verifies each block of the file only as it is read.
+	// An error in any block is returned by Read. The caller must call
+	// Close when done.
+	GetReader(name PathName) (io.ReadCloser, error)
+}
+
+// ReaderPutter is implemented by Clients that can store a file whose
+// contents are read from a stream rather than held in memory. It is not
+// part of the Client interface; callers discover whether a Client supports
+// it using a type assertion.
+type ReaderPutter interface {
+	// PutReader is like Put but reads the data from r, packing and
+	// storing each block before reading the next. The directory entry
+	// is written only once all the data has been stored.
+	PutReader(name PathName, r io.Reader) (*DirEntry, error)
+}
+
+// Copier is implemented by Clients that can copy a file without always
+// reading and rewriting its data. Like ReaderPutter, it is not part of the
+// Client interface.
+type Copier interface {
+	// Copy copies the file src, following any links, to dst, which must
+	// not exist, and returns the directory entry for dst. When both names
+	// are served by the same DirServer, the data is held by the Client's
+	// StoreServer, and, if the data is encrypted, the Access files of src
+	// and dst grant read rights to the same users, Copy duplicates the
+	// references to the data as PutDuplicate does. Otherwise it reads the
+	// data and packs it anew for dst.
+	Copy(src, dst PathName) (*DirEntry, error)
+}
+
 // The File interface has semantics and an API that parallels a subset
 // of Go's os.File. The main semantic difference, besides the limited
 // method set, is that a Read will only return once the entire contents
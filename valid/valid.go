@@ -115,8 +115,10 @@ func Endpoint(endpoint upspin.Endpoint) error {
 // Rules:
 // - present and valid Name and SignedName
 // - Name is equal to SignedName
+// - Attr is exactly one of AttrNone, AttrDirectory, or AttrLink
 // - blocks may be present only if Attr == AttrNone
-// - Link may be present only if Attr == AttrLink
+// - blocks start at offset zero, are valid, and have no holes or overlaps
+// - Link may be present only if Attr == AttrLink, and must then be valid
 // - Attr must not include AttrIncomplete
 // - Packing must be known
 // - Sequence must have a known special value or be non-negative
@@ -161,7 +163,7 @@ func DirEntry(entry *upspin.DirEntry) error {
 
 	// Packing must be valid.
 	switch entry.Packing {
-	case upspin.PlainPack, upspin.EEPack, upspin.EEIntegrityPack:
+	case upspin.PlainPack, upspin.PlainSumPack, upspin.EEPack, upspin.EEIntegrityPack:
 		// OK
 	case upspin.UnassignedPack:
 		if entry.IsDir() {
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"upspin.io/access"
+	"upspin.io/errors"
 	"upspin.io/upspin"
 )
 
@@ -226,6 +227,12 @@ func TestDirEntry(t *testing.T) {
 		t.Fatal("no error for bad name")
 	}
 	restore()
+	// Empty names.
+	entry.Name, entry.SignedName = "", ""
+	if err := DirEntry(&entry); !errors.Is(errors.Invalid, err) {
+		t.Fatalf("empty name: got %v, want Invalid error", err)
+	}
+	restore()
 	// Mismatched names.
 	entry.SignedName = "curly@stooges.com/nyuk"
 	if err := DirEntry(&entry); err == nil {
@@ -244,6 +251,17 @@ func TestDirEntry(t *testing.T) {
 		t.Fatal("no error for link with data")
 	}
 	restore()
+	// Link with no target.
+	entry.Attr = upspin.AttrLink
+	entry.Blocks = nil
+	if err := DirEntry(&entry); err == nil {
+		t.Fatal("no error for link with empty Link")
+	}
+	entry.Link = "moe@stooges.com/nyuk"
+	if err := DirEntry(&entry); err != nil {
+		t.Fatalf("valid link: expected no error, got %s", err)
+	}
+	restore()
 	// Data present for directory.
 	entry.Attr = upspin.AttrDirectory
 	if err := DirEntry(&entry); err == nil {
@@ -292,6 +310,12 @@ func TestDirEntry(t *testing.T) {
 		t.Fatal("no error for overlapping blocks")
 	}
 	restore()
+	// First block not at offset zero.
+	entry.Blocks = entry.Blocks[1:]
+	if err := DirEntry(&entry); err == nil {
+		t.Fatal("no error for first block at non-zero offset")
+	}
+	restore()
 	// Zero-length block.
 	entry.Blocks = append(entry.Blocks, upspin.DirBlock{})
 	if err := DirEntry(&entry); err == nil {